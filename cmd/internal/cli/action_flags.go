@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2024, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // Copyright (c) Contributors to the Apptainer project, established as
 //   Apptainer a Series of LF Projects LLC.
 // This software is licensed under a 3-clause BSD license. Please consult the
@@ -61,6 +61,7 @@ var (
 	noRocm          bool
 	noUmask         bool
 	disableCache    bool
+	lazyPull        bool
 
 	netNamespace   bool
 	netnsPath      string
@@ -820,6 +821,16 @@ var actionCdiDirs = cmdline.Flag{
 	Usage:        "comma-separated list of directories in which CDI should look for device definition JSON files. If omitted, default will be: /etc/cdi,/var/run/cdi",
 }
 
+// --lazy
+var actionLazyFlag = cmdline.Flag{
+	ID:           "actionLazyFlag",
+	Value:        &lazyPull,
+	DefaultValue: false,
+	Name:         "lazy",
+	Usage:        "fetch layers of docker:// images on demand, rather than pulling the full image before running (OCI mode, squashfs / erofs layers only; the first run of an image reads each layer in full to verify it)",
+	EnvKeys:      []string{"LAZY"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(ExecCmd)
@@ -921,5 +932,6 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDevice, actionsCmd...)
 		cmdManager.RegisterFlagForCmd(&actionCdiDirs, actionsCmd...)
+		cmdManager.RegisterFlagForCmd(&actionLazyFlag, actionsCmd...)
 	})
 }
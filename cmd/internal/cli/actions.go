@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/v4/docs"
 	"github.com/sylabs/singularity/v4/internal/pkg/cache"
@@ -23,6 +24,7 @@ import (
 	"github.com/sylabs/singularity/v4/internal/pkg/client/oras"
 	"github.com/sylabs/singularity/v4/internal/pkg/client/shub"
 	"github.com/sylabs/singularity/v4/internal/pkg/ociimage"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociimage/lazy"
 	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
 	"github.com/sylabs/singularity/v4/internal/pkg/remote/credential/ociauth"
 	"github.com/sylabs/singularity/v4/internal/pkg/runtime/launcher"
@@ -55,6 +57,7 @@ type contextKey string
 const (
 	keyOrigImageURI contextKey = "origImageURI"
 	keyPullTempDir  contextKey = "pullTempDir"
+	keyLazyImage    contextKey = "lazyImage"
 )
 
 // actionPreRun will:
@@ -84,6 +87,12 @@ func actionPreRun(cmd *cobra.Command, args []string) {
 		noEval = true
 	}
 
+	// --lazy fetches layers from the registry while the container runs, which
+	// is only implemented by the OCI launcher.
+	if lazyPull && !isOCI {
+		sylog.Fatalf("--lazy is only supported in OCI mode (--oci)")
+	}
+
 	// --hostname requires UTS namespace
 	if len(hostname) > 0 {
 		utsNamespace = true
@@ -96,11 +105,14 @@ func actionPreRun(cmd *cobra.Command, args []string) {
 
 	// Replace remote URI with a local image path, pulling to cache or a
 	// temporary directory as needed.
-	localImage, pullTempDir := uriToImage(cmd.Context(), cmd, origImageURI)
+	localImage, pullTempDir, lazyImg := uriToImage(cmd.Context(), cmd, origImageURI)
 	args[0] = localImage
 
 	// Track the pullTempDir (if set) in the context, so it can be cleaned up on container exit.
 	cmd.SetContext(context.WithValue(cmd.Context(), keyPullTempDir, &pullTempDir))
+	// Track the image opened for on-demand fetching (if any), so that it is
+	// not opened again by the launcher.
+	cmd.SetContext(context.WithValue(cmd.Context(), keyLazyImage, lazyImg))
 }
 
 func uriToCacheImage(ctx context.Context, refType string, cmd *cobra.Command, imgCache *cache.Handle, pullFrom string) (string, error) {
@@ -231,13 +243,14 @@ func handleOCI(ctx context.Context, cmd *cobra.Command, imgCache *cache.Handle,
 // uriToImage will pull a remote image to the cache, or a temporary directory if
 // the cache is disabled. It returns a path to the pulled image, and the
 // temporary directory that should be removed when the container exits, where
-// applicable.
-func uriToImage(ctx context.Context, cmd *cobra.Command, origImageURI string) (imagePath, tempDir string) {
+// applicable. With --lazy, a registry image that can be run with on-demand
+// fetching of layers is returned instead of a path.
+func uriToImage(ctx context.Context, cmd *cobra.Command, origImageURI string) (imagePath, tempDir string, lazyImg *lazy.Image) {
 	refType, _ := uri.Split(origImageURI)
 	// If joining an instance (instance://xxx), or we have a bare filename then
 	// no retrieval / conversion is required.
 	if refType == "instance" || refType == "" {
-		return origImageURI, ""
+		return origImageURI, "", nil
 	}
 
 	imgCache := getCacheHandle(cache.Config{Disable: disableCache})
@@ -245,6 +258,16 @@ func uriToImage(ctx context.Context, cmd *cobra.Command, origImageURI string) (i
 		sylog.Fatalf("failed to create a new image cache handle")
 	}
 
+	// With --lazy, a registry image with layers that can be read on demand is
+	// run directly, without pulling it to an OCI-SIF first.
+	if lazyPull {
+		if refType != "docker" {
+			sylog.Warningf("--lazy only applies to docker:// images, pulling %s in full", origImageURI)
+		} else if img, dir := lazyOpen(ctx, cmd, imgCache, origImageURI); img != nil {
+			return origImageURI, dir, img
+		}
+	}
+
 	// If the cache is disabled, then we pull to a temporary location, which
 	// will need to be removed on container exit. Otherwise, we pull to the
 	// cache and run directly from there.
@@ -266,14 +289,60 @@ func uriToImage(ctx context.Context, cmd *cobra.Command, origImageURI string) (i
 		}
 		sylog.Warningf("%v", err)
 		sylog.Warningf("OCI-SIF could not be created, falling back to unpacking OCI bundle in temporary sandbox dir")
-		return origImageURI, ""
+		return origImageURI, "", nil
 	}
 
 	if err != nil {
 		sylog.Fatalf("Unable to handle %s uri: %v", origImageURI, err)
 	}
 
-	return imagePath, tempDir
+	return imagePath, tempDir, nil
+}
+
+// lazyOpen opens the registry image imageURI for on-demand fetching of its
+// layers. If the layers cannot be fetched on demand, a nil image is returned.
+// Otherwise, the returned temporary directory holds fetched chunks if the
+// cache is disabled, and should be removed when the container exits.
+func lazyOpen(ctx context.Context, cmd *cobra.Command, imgCache *cache.Handle, imageURI string) (*lazy.Image, string) {
+	lazyDir, err := os.MkdirTemp(tmpDir, "singularity-lazy-")
+	if err != nil {
+		sylog.Fatalf("Unable to create temporary directory: %v", err)
+	}
+
+	ociAuth, err := makeOCICredentials(cmd)
+	if err != nil {
+		os.RemoveAll(lazyDir)
+		sylog.Fatalf("While creating Docker credentials: %v", err)
+	}
+
+	img, err := lazy.Open(ctx, getOCITransportOptions(ociAuth), imgCache, imageURI, lazyDir)
+	if err != nil {
+		os.RemoveAll(lazyDir)
+		if errors.Is(err, lazy.ErrNotSeekable) {
+			sylog.Infof("%v, pulling %s in full", err, imageURI)
+			return nil, ""
+		}
+		sylog.Fatalf("Unable to handle %s uri: %v", imageURI, err)
+	}
+	return img, lazyDir
+}
+
+// getOCITransportOptions returns the transport options for interaction with
+// OCI registries etc., as specified by CLI flags.
+func getOCITransportOptions(ociAuth *authn.AuthConfig) *ociimage.TransportOptions {
+	return &ociimage.TransportOptions{
+		Insecure:         noHTTPS,
+		AuthConfig:       ociAuth,
+		DockerDaemonHost: dockerHost,
+		AuthFilePath:     ociauth.ChooseAuthFile(reqAuthFile),
+		UserAgent:        useragent.Value(),
+		Platform:         getOCIPlatform(),
+	}
+}
+
+func lazyImageFromContext(ctx context.Context) *lazy.Image {
+	img, _ := ctx.Value(keyLazyImage).(*lazy.Image)
+	return img
 }
 
 func pullTempDirFromContext(ctx context.Context) string {
@@ -412,6 +481,11 @@ func launchContainer(cmd *cobra.Command, ep launcher.ExecParams) error {
 		return err
 	}
 
+	// If actionPreRun pulled the image, record where it was pulled from.
+	if origImageURI, ok := cmd.Context().Value(keyOrigImageURI).(*string); ok && *origImageURI != ep.Image {
+		ep.ImageSource = *origImageURI
+	}
+
 	opts := []launcher.Option{
 		launcher.OptWritable(isWritable),
 		launcher.OptWritableTmpfs(isWritableTmpfs),
@@ -471,6 +545,7 @@ func launchContainer(cmd *cobra.Command, ep launcher.ExecParams) error {
 		launcher.OptTmpSandbox(tmpSandbox),
 		launcher.OptNoTmpSandbox(noTmpSandbox),
 		launcher.OptPullTempDir(ep.PullTempDir),
+		launcher.OptLazyImage(lazyImageFromContext(cmd.Context())),
 	}
 
 	// Explicitly use the interface type here, as we will add alternative launchers later...
@@ -479,13 +554,13 @@ func launchContainer(cmd *cobra.Command, ep launcher.ExecParams) error {
 	if isOCI {
 		sylog.Debugf("Using OCI runtime launcher.")
 
-		tOpts := &ociimage.TransportOptions{
-			Insecure:         noHTTPS,
-			AuthConfig:       &authConfig,
-			DockerDaemonHost: dockerHost,
-			AuthFilePath:     ociauth.ChooseAuthFile(reqAuthFile),
-			UserAgent:        useragent.Value(),
-			Platform:         getOCIPlatform(),
+		tOpts := getOCITransportOptions(&authConfig)
+		if lazyPull {
+			// On-demand fetching of layers needs the same credentials as a
+			// pull, including those from the auth file.
+			if tOpts.AuthConfig, err = makeOCICredentials(cmd); err != nil {
+				return fmt.Errorf("while creating Docker credentials: %w", err)
+			}
 		}
 		opts = append(opts, launcher.OptTransportOptions(tOpts))
 
@@ -0,0 +1,163 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/internal/pkg/attest"
+	cosignsignature "github.com/sylabs/singularity/v4/internal/pkg/cosign"
+	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+	"github.com/sylabs/singularity/v4/pkg/image"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// loadAttestationSigner returns the signer of a provenance attestation, using
+// the private key at keyPath if specified, or else a PGP private key.
+func loadAttestationSigner(cmd *cobra.Command, keyPath string, keyIdx int) (attest.Signer, error) {
+	if keyPath != "" {
+		sylog.Infof("Signing provenance attestation with key material from '%v'", keyPath)
+		b, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key material: %w", err)
+		}
+
+		// cosign private keys are always encrypted, with their own format.
+		if p, _ := pem.Decode(b); p != nil && (p.Type == cosign.CosignPrivateKeyPemType || p.Type == cosign.SigstorePrivateKeyPemType) {
+			pass, err := cryptoutils.GetPasswordFromStdIn(false)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't read key password: %w", err)
+			}
+			sv, err := cosign.LoadPrivateKey(b, pass, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load key material: %w", err)
+			}
+			return attest.NewSigner(sv), nil
+		}
+
+		s, err := signature.LoadSignerFromPEMFile(keyPath, crypto.SHA256, cryptoutils.GetPasswordFromStdIn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key material: %w", err)
+		}
+		return attest.NewSigner(s), nil
+	}
+
+	sylog.Infof("Signing provenance attestation with PGP key material")
+	var f sypgp.EntitySelector
+	if cmd.Flag(buildAttestKeyIdxFlag.Name).Changed {
+		f = selectEntityAtIndex(keyIdx)
+	} else {
+		f = selectEntityInteractive()
+	}
+	e, err := sypgp.GetPrivateEntity(decryptSelectedEntityInteractive(f))
+	if err != nil {
+		return nil, err
+	}
+	return attest.NewPGPSigner(e), nil
+}
+
+// attestImage generates a provenance statement for the SIF or OCI-SIF image at
+// path, built as described by m, and stores it in the image, signed by s.
+func attestImage(ctx context.Context, path string, m attest.Materials, s attest.Signer) error {
+	ociSIF, err := image.IsOCISIF(path)
+	if err != nil {
+		return fmt.Errorf("while checking image: %w", err)
+	}
+
+	if ociSIF {
+		digest, err := cosignsignature.ImageDigest(ctx, path)
+		if err != nil {
+			return err
+		}
+		m.Subject = attest.ResourceDescriptor{
+			Name:   filepath.Base(path),
+			Digest: map[string]string{digest.Algorithm: digest.Hex},
+		}
+	} else if m.Subject, err = attest.SIFSubject(path); err != nil {
+		return err
+	}
+
+	e, err := attest.Sign(attest.NewStatement(m), s)
+	if err != nil {
+		return err
+	}
+
+	if ociSIF {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return cosignsignature.AttachAttestation(ctx, path, b)
+	}
+	return attest.AttachSIF(path, e)
+}
+
+var errNoValidAttestation = errors.New("no provenance attestation satisfies the policy")
+
+// verifyAttestation checks that the SIF or OCI-SIF image at path holds a
+// provenance attestation, signed by one of verifiers, that satisfies p. The
+// statements of valid attestations are returned.
+func verifyAttestation(ctx context.Context, path string, p attest.Policy, verifiers ...attest.Verifier) ([]*attest.Statement, error) {
+	ociSIF, err := image.IsOCISIF(path)
+	if err != nil {
+		return nil, fmt.Errorf("while checking image: %w", err)
+	}
+
+	var es []*attest.Envelope
+	var digest map[string]string
+	if ociSIF {
+		bs, d, err := cosignsignature.Attestations(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bs {
+			var e attest.Envelope
+			if err := json.Unmarshal(b, &e); err != nil {
+				sylog.Verbosef("Skipping attestation: %v", err)
+				continue
+			}
+			es = append(es, &e)
+		}
+		if len(es) == 0 {
+			return nil, attest.ErrNoAttestation
+		}
+		digest = map[string]string{d.Algorithm: d.Hex}
+	} else if es, digest, err = attest.SIFAttestations(path); err != nil {
+		return nil, err
+	}
+
+	var sts []*attest.Statement
+	for i, e := range es {
+		st, ids, err := e.Verify(verifiers...)
+		if err != nil {
+			sylog.Verbosef("Attestation %d invalid for provided key material: %v", i, err)
+			continue
+		}
+		if err := p.Check(st, digest); err != nil {
+			sylog.Verbosef("Attestation %d does not satisfy policy: %v", i, err)
+			continue
+		}
+		for _, id := range ids {
+			sylog.Infof("Provenance attestation %d signed by: %s", i, id)
+		}
+		sts = append(sts, st)
+	}
+	if len(sts) == 0 {
+		return nil, errNoValidAttestation
+	}
+	return sts, nil
+}
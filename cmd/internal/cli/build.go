@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2018-2023, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // Copyright (c) Contributors to the Apptainer project, established as
 //   Apptainer a Series of LF Projects LLC.
 // This software is licensed under a 3-clause BSD license. Please consult the
@@ -9,6 +9,7 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"runtime"
@@ -53,6 +54,21 @@ var buildArgs struct {
 	writableTmpfs   bool     // For test section only
 	buildVarArgs    []string // Variables passed to build procedure.
 	buildVarArgFile string   // Variables file passed to build procedure.
+	secrets         []string // Secrets available to post and test sections.
+	network         string   // Network for post and test sections.
+	cpus            string   // CPU limit for post and test sections.
+	memory          string   // Memory limit for post and test sections.
+	keepFailed      bool     // Keep the bundles of a failed build for resuming.
+	shellOnFailure  bool     // Run a shell in the failed stage on failure.
+	resume          string   // Failed build to resume.
+	contextDir      string   // Build context uploaded to a build server.
+	platforms       []string // Platforms for a multi-platform OCI build.
+	singleFile      bool     // Write a multi-platform OCI build to one OCI-SIF.
+	attest          bool     // Generate a signed provenance attestation.
+	attestKey       string   // Private key to sign the provenance attestation.
+	attestKeyIdx    int      // PGP private key to sign the provenance attestation.
+	attestBuilderID string   // Builder ID recorded in the provenance attestation.
+	recipients      []string // Public keys the encryption key is encrypted for.
 }
 
 // -s|--sandbox
@@ -129,6 +145,26 @@ var buildArchFlag = cmdline.Flag{
 	EnvKeys:      []string{"BUILD_ARCH"},
 }
 
+// --platform
+var buildPlatformFlag = cmdline.Flag{
+	ID:           "buildPlatformFlag",
+	Value:        &buildArgs.platforms,
+	DefaultValue: []string{},
+	Name:         "platform",
+	Usage:        "comma separated list of platforms (e.g. linux/amd64,linux/arm64) to build for, writing one OCI-SIF per platform (requires --oci)",
+	EnvKeys:      []string{"BUILD_PLATFORM"},
+}
+
+// --single-file
+var buildSingleFileFlag = cmdline.Flag{
+	ID:           "buildSingleFileFlag",
+	Value:        &buildArgs.singleFile,
+	DefaultValue: false,
+	Name:         "single-file",
+	Usage:        "write the images for all --platform platforms into a single multi-platform OCI-SIF",
+	EnvKeys:      []string{"BUILD_SINGLE_FILE"},
+}
+
 // -d|--detached
 var buildDetachedFlag = cmdline.Flag{
 	ID:           "buildDetachedFlag",
@@ -180,6 +216,46 @@ var buildNoCleanupFlag = cmdline.Flag{
 	EnvKeys:      []string{"NO_CLEANUP"},
 }
 
+// --keep-failed
+var buildKeepFailedFlag = cmdline.Flag{
+	ID:           "buildKeepFailedFlag",
+	Value:        &buildArgs.keepFailed,
+	DefaultValue: false,
+	Name:         "keep-failed",
+	Usage:        "keep the sandbox of the failed stage after a failed build, so that it can be inspected, and the build continued with --resume",
+	EnvKeys:      []string{"KEEP_FAILED"},
+}
+
+// --shell-on-failure
+var buildShellOnFailureFlag = cmdline.Flag{
+	ID:           "buildShellOnFailureFlag",
+	Value:        &buildArgs.shellOnFailure,
+	DefaultValue: false,
+	Name:         "shell-on-failure",
+	Usage:        "run an interactive shell in the sandbox of the failed stage after a failed build",
+}
+
+// --resume
+var buildResumeFlag = cmdline.Flag{
+	ID:           "buildResumeFlag",
+	Value:        &buildArgs.resume,
+	DefaultValue: "",
+	Name:         "resume",
+	Usage:        "resume a failed build kept with --keep-failed, from the section that failed, using the directory printed when the build failed",
+	Tag:          "<dir>",
+}
+
+// --context-dir
+var buildContextDirFlag = cmdline.Flag{
+	ID:           "buildContextDirFlag",
+	Value:        &buildArgs.contextDir,
+	DefaultValue: "",
+	Name:         "context-dir",
+	Usage:        "resolve %files sources from the host inside this directory only (used by build-server)",
+	Tag:          "<dir>",
+	Hidden:       true,
+}
+
 // --fakeroot
 var buildFakerootFlag = cmdline.Flag{
 	ID:           "buildFakerootFlag",
@@ -211,6 +287,16 @@ var buildEncryptFlag = cmdline.Flag{
 	Usage:        "build an image with an encrypted file system",
 }
 
+// --recipient
+var buildRecipientFlag = cmdline.Flag{
+	ID:           "buildRecipientFlag",
+	Value:        &buildArgs.recipients,
+	DefaultValue: []string{},
+	Name:         "recipient",
+	Usage:        "path to a PEM formatted RSA or ASCII armored PGP public key to encrypt the image for (can be specified multiple times)",
+	EnvKeys:      []string{"ENCRYPTION_RECIPIENT"},
+}
+
 // TODO: Deprecate at 3.6, remove at 3.8
 // --fix-perms
 var buildFixPermsFlag = cmdline.Flag{
@@ -309,21 +395,108 @@ var buildVarArgFileFlag = cmdline.Flag{
 	Usage:        "specifies a file containing variable=value lines to replace '{{ variable }}' with value in build definition files",
 }
 
+// --secret
+var buildSecretFlag = cmdline.Flag{
+	ID:           "buildSecretFlag",
+	Value:        &buildArgs.secrets,
+	DefaultValue: []string{},
+	Name:         "secret",
+	Usage:        "expose a secret to the %post and %test sections at /run/secrets/<id>, without storing it in the image, in id=<id>,src=<path> or id=<id>,env=<variable> format (not supported with remote build)",
+	Tag:          "<spec>",
+	StringArray:  true,
+}
+
+// --network
+var buildNetworkFlag = cmdline.Flag{
+	ID:           "buildNetworkFlag",
+	Value:        &buildArgs.network,
+	DefaultValue: "default",
+	Name:         "network",
+	Usage:        "network for the %post and %test sections: 'default' uses the host network, 'none' runs them in an isolated network namespace (not supported with remote build)",
+	EnvKeys:      []string{"BUILD_NETWORK"},
+	Tag:          "<none|default>",
+}
+
+// --cpus
+var buildCPUsFlag = cmdline.Flag{
+	ID:           "buildCPUsFlag",
+	Value:        &buildArgs.cpus,
+	DefaultValue: "",
+	Name:         "cpus",
+	Usage:        "number of CPUs available to the %post and %test sections (not supported with remote build)",
+	EnvKeys:      []string{"BUILD_CPUS"},
+}
+
+// --memory
+var buildMemoryFlag = cmdline.Flag{
+	ID:           "buildMemoryFlag",
+	Value:        &buildArgs.memory,
+	DefaultValue: "",
+	Name:         "memory",
+	Usage:        "memory limit in bytes, or with a unit suffix (e.g. 4G), for the %post and %test sections (not supported with remote build)",
+	EnvKeys:      []string{"BUILD_MEMORY"},
+}
+
+// --attest
+var buildAttestFlag = cmdline.Flag{
+	ID:           "buildAttestFlag",
+	Value:        &buildArgs.attest,
+	DefaultValue: false,
+	Name:         "attest",
+	Usage:        "add a signed SLSA provenance attestation to the built SIF or OCI-SIF image",
+}
+
+// --attest-key
+var buildAttestKeyFlag = cmdline.Flag{
+	ID:           "buildAttestKeyFlag",
+	Value:        &buildArgs.attestKey,
+	DefaultValue: "",
+	Name:         "attest-key",
+	Usage:        "path to the private key, or cosign private key, used to sign the provenance attestation (default: PGP key)",
+	EnvKeys:      []string{"ATTEST_KEY"},
+}
+
+// --attest-keyidx
+var buildAttestKeyIdxFlag = cmdline.Flag{
+	ID:           "buildAttestKeyIdxFlag",
+	Value:        &buildArgs.attestKeyIdx,
+	DefaultValue: 0,
+	Name:         "attest-keyidx",
+	Usage:        "PGP private key used to sign the provenance attestation (index from 'key list --secret')",
+}
+
+// --attest-builder-id
+var buildAttestBuilderIDFlag = cmdline.Flag{
+	ID:           "buildAttestBuilderIDFlag",
+	Value:        &buildArgs.attestBuilderID,
+	DefaultValue: "",
+	Name:         "attest-builder-id",
+	Usage:        "builder ID recorded in the provenance attestation (default: singularity://<hostname>)",
+	EnvKeys:      []string{"ATTEST_BUILDER_ID"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(buildCmd)
 
 		cmdManager.RegisterFlagForCmd(&buildArchFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildPlatformFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildSingleFileFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildBuilderFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildDetachedFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildDisableCacheFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildEncryptFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildRecipientFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildFakerootFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoSetgroupsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildFixPermsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildJSONFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildLibraryFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoCleanupFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildKeepFailedFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildShellOnFailureFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildResumeFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildContextDirFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoTestFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildRemoteFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildSandboxFlag, buildCmd)
@@ -349,12 +522,21 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&buildWritableTmpfsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildVarArgsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildVarArgFileFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildSecretFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNetworkFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildCPUsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildMemoryFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildAttestFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildAttestKeyFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildAttestKeyIdxFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildAttestBuilderIDFlag, buildCmd)
 
 		cmdManager.RegisterFlagForCmd(&commonOCIFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonNoOCIFlag, buildCmd)
 
 		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonKeepLayersFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&commonLayerFormatFlag, buildCmd)
 	})
 }
 
@@ -483,15 +665,13 @@ func definitionFromSpec(spec string) (types.Definition, error) {
 	if isValid {
 		sylog.Debugf("Found valid definition: %s\n", spec)
 		// File exists and contains valid definition
-		var defFile *os.File
-		defFile, err = os.Open(spec)
+		var raw []byte
+		raw, err = parser.ReadDefinitionFile(spec)
 		if err != nil {
 			return types.Definition{}, err
 		}
 
-		defer defFile.Close()
-
-		return parser.ParseDefinitionFile(defFile)
+		return parser.ParseDefinitionFile(bytes.NewReader(raw))
 	}
 
 	// File exists and does NOT contain a valid definition
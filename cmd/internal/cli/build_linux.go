@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2018-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // Copyright (c) Contributors to the Apptainer project, established as
 //   Apptainer a Series of LF Projects LLC.
 // This software is licensed under a 3-clause BSD license. Please consult the
@@ -10,18 +10,26 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	osExec "os/exec"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/ccoveille/go-safecast/v2"
+	"github.com/docker/go-units"
 	"github.com/google/go-containerregistry/pkg/authn"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 	keyclient "github.com/sylabs/scs-key-client/client"
+	"github.com/sylabs/singularity/v4/internal/pkg/attest"
 	"github.com/sylabs/singularity/v4/internal/pkg/build"
 	"github.com/sylabs/singularity/v4/internal/pkg/build/args"
 	bkclient "github.com/sylabs/singularity/v4/internal/pkg/build/buildkit/client"
@@ -29,6 +37,7 @@ import (
 	"github.com/sylabs/singularity/v4/internal/pkg/buildcfg"
 	"github.com/sylabs/singularity/v4/internal/pkg/cache"
 	"github.com/sylabs/singularity/v4/internal/pkg/ociplatform"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
 	"github.com/sylabs/singularity/v4/internal/pkg/remote/endpoint"
 	fakerootConfig "github.com/sylabs/singularity/v4/internal/pkg/runtime/engine/fakeroot/config"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/bin"
@@ -42,6 +51,7 @@ import (
 	"github.com/sylabs/singularity/v4/pkg/runtime/engine/config"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
 	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
+	"golang.org/x/sys/unix"
 )
 
 func fakerootExec() {
@@ -172,10 +182,82 @@ func runBuild(cmd *cobra.Command, args []string) {
 		os.Setenv("SINGULARITY_WRITABLE_TMPFS", "1")
 	}
 
+	if len(buildArgs.secrets) > 0 {
+		if buildArgs.remote {
+			sylog.Fatalf("--secret option is not supported for remote build")
+		}
+		if isOCI {
+			sylog.Fatalf("--secret option is not supported for OCI builds from Dockerfiles")
+		}
+	}
+
+	if buildArgs.network != "default" || buildArgs.cpus != "" || buildArgs.memory != "" {
+		if buildArgs.remote {
+			sylog.Fatalf("--network, --cpus and --memory options are not supported for remote build")
+		}
+		if isOCI {
+			sylog.Fatalf("--network, --cpus and --memory options are not supported for OCI builds from Dockerfiles")
+		}
+		if err := checkBuildIsolation(); err != nil {
+			sylog.Fatalf("%v", err)
+		}
+	}
+
+	if buildArgs.keepFailed || buildArgs.shellOnFailure || buildArgs.resume != "" {
+		if buildArgs.remote {
+			sylog.Fatalf("--keep-failed, --shell-on-failure and --resume options are not supported for remote build")
+		}
+		if isOCI {
+			sylog.Fatalf("--keep-failed, --shell-on-failure and --resume options are not supported for OCI builds from Dockerfiles")
+		}
+		if buildArgs.resume != "" && buildArgs.update {
+			sylog.Fatalf("--resume option cannot be used with --update")
+		}
+	}
+
 	if cmd.Flags().Lookup("authfile").Changed && buildArgs.remote {
 		sylog.Fatalf("Custom authfile is not supported for remote build")
 	}
 
+	if len(buildArgs.platforms) > 0 {
+		if !isOCI {
+			sylog.Fatalf("--platform option is only supported for OCI builds from Dockerfiles")
+		}
+		if cmd.Flags().Lookup("arch").Changed {
+			sylog.Fatalf("--platform option cannot be used with --arch")
+		}
+	}
+	platforms := make([]ggcrv1.Platform, 0, len(buildArgs.platforms))
+	for _, p := range buildArgs.platforms {
+		pp, err := ociplatform.PlatformFromString(p)
+		if err != nil {
+			sylog.Fatalf("Invalid platform %q: %v", p, err)
+		}
+		platforms = append(platforms, *pp)
+	}
+	checkLayerFormat(cmd)
+
+	if buildArgs.singleFile && len(buildArgs.platforms) == 0 {
+		sylog.Fatalf("--single-file option requires --platform")
+	}
+	if buildArgs.singleFile && encryptionRequested(cmd) {
+		sylog.Fatalf("--single-file option is not supported for encrypted builds")
+	}
+
+	if buildArgs.attest {
+		if buildArgs.remote {
+			sylog.Fatalf("--attest option is not supported for remote build")
+		}
+		if buildArgs.sandbox {
+			sylog.Fatalf("--attest option requires a SIF or OCI-SIF image, not a sandbox")
+		}
+		if len(buildArgs.platforms) > 1 {
+			sylog.Fatalf("--attest option is not supported for multi-platform builds")
+		}
+	} else if buildArgs.attestKey != "" || cmd.Flags().Lookup("attest-keyidx").Changed || buildArgs.attestBuilderID != "" {
+		sylog.Warningf("Attestation options specified, but --attest was not. NOT adding a provenance attestation.")
+	}
+
 	if buildArgs.arch != runtime.GOARCH && !buildArgs.remote && !isOCI {
 		sylog.Fatalf("Requested architecture (%s) does not match host (%s). Cannot build locally.", buildArgs.arch, runtime.GOARCH)
 	}
@@ -199,12 +281,32 @@ func runBuild(cmd *cobra.Command, args []string) {
 	if err := checkBuildTarget(dest); err != nil {
 		sylog.Fatalf("While checking build target: %s", err)
 	}
+	if !buildArgs.singleFile {
+		for _, p := range platforms {
+			if err := checkBuildTarget(ocisif.PlatformImagePath(dest, p)); err != nil {
+				sylog.Fatalf("While checking build target: %s", err)
+			}
+		}
+	}
 
 	if buildArgs.remote {
 		runBuildRemote(cmd.Context(), cmd, dest, spec)
 		return
 	}
 
+	// Load the attestation key before the build, as it may prompt for a
+	// passphrase.
+	var attestSigner attest.Signer
+	if buildArgs.attest {
+		s, err := loadAttestationSigner(cmd, buildArgs.attestKey, buildArgs.attestKeyIdx)
+		if err != nil {
+			sylog.Fatalf("While loading attestation key: %v", err)
+		}
+		attestSigner = s
+	}
+	startedOn := time.Now()
+	var sources []attest.ResourceDescriptor
+
 	authConf, err := makeOCICredentials(cmd)
 	if err != nil {
 		sylog.Fatalf("While creating Docker credentials: %v", err)
@@ -224,26 +326,141 @@ func runBuild(cmd *cobra.Command, args []string) {
 			sylog.Fatalf("While trying to determine current dir: %v", err)
 		}
 
+		// Encrypted OCI-SIF images are written by the current user, so root
+		// is not required.
+		keyInfo := buildEncryptionMaterial(cmd)
+		if encryptionRequested(cmd) && keyInfo == nil {
+			sylog.Fatalf("Encryption was requested, but no key material was provided. Use --passphrase or --pem-path.")
+		}
+
 		bkOpts := &bkclient.Opts{
 			AuthConf:        authConf,
 			ReqAuthFile:     reqAuthFile,
 			BuildVarArgs:    buildArgs.buildVarArgs,
 			BuildVarArgFile: buildArgs.buildVarArgFile,
 			ReqArch:         reqArch,
+			ReqPlatforms:    buildArgs.platforms,
+			SingleFile:      buildArgs.singleFile,
 			KeepLayers:      keepLayers,
+			LayerFormat:     layerFormat,
 			ContextDir:      wd,
 			DisableCache:    disableCache,
 		}
-		if err := bkclient.Run(cmd.Context(), bkOpts, dest, spec); err != nil {
+		if keyInfo == nil {
+			if err := bkclient.Run(cmd.Context(), bkOpts, dest, spec); err != nil {
+				sylog.Fatalf("%v", err)
+			}
+		} else if err := buildEncryptedOCI(cmd.Context(), bkOpts, dest, spec, platforms, *keyInfo); err != nil {
 			sylog.Fatalf("%v", err)
 		}
 	} else {
-		runBuildLocal(cmd.Context(), authConf, cmd, dest, spec)
+		sources = runBuildLocal(cmd.Context(), authConf, cmd, dest, spec)
+	}
+
+	if attestSigner != nil {
+		if err := attestBuild(cmd.Context(), dest, spec, sources, startedOn, attestSigner); err != nil {
+			sylog.Fatalf("While adding provenance attestation: %v", err)
+		}
+		sylog.Infof("Provenance attestation added to image '%v'", dest)
 	}
 
 	sylog.Infof("Build complete: %s", dest)
 }
 
+// buildEncryptedOCI builds the Dockerfile spec as with bkclient.Run, into a
+// temporary directory, and encrypts the layers of the resulting OCI-SIF images
+// with the key material in ki before they are moved to dest. Plaintext images
+// are therefore never written to dest, and are removed if encryption fails.
+func buildEncryptedOCI(ctx context.Context, bkOpts *bkclient.Opts, dest, spec string, platforms []ggcrv1.Platform, ki cryptkey.KeyInfo) error {
+	stageDir, err := os.MkdirTemp(tmpDir, "build-encrypt-")
+	if err != nil {
+		return fmt.Errorf("while creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	stageDest := filepath.Join(stageDir, filepath.Base(dest))
+	if err := bkclient.Run(ctx, bkOpts, stageDest, spec); err != nil {
+		return err
+	}
+
+	paths := map[string]string{stageDest: dest}
+	if len(platforms) > 0 {
+		clear(paths)
+		for _, p := range platforms {
+			paths[ocisif.PlatformImagePath(stageDest, p)] = ocisif.PlatformImagePath(dest, p)
+		}
+	}
+
+	for from := range paths {
+		sylog.Infof("Encrypting image layers of %s", filepath.Base(from))
+		if err := ocisif.EncryptLayers(from, ki, stageDir); err != nil {
+			return fmt.Errorf("while encrypting image: %w", err)
+		}
+	}
+	for from, to := range paths {
+		if err := moveFile(from, to); err != nil {
+			return fmt.Errorf("while writing encrypted image: %w", err)
+		}
+	}
+	return nil
+}
+
+// moveFile moves the file at from to to, copying it if they are on different
+// filesystems.
+func moveFile(from, to string) error {
+	err := os.Rename(from, to)
+	if !errors.Is(err, unix.EXDEV) {
+		return err
+	}
+	fi, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	return fs.CopyFileAtomic(from, to, fi.Mode().Perm())
+}
+
+// attestBuild adds a provenance attestation, signed by s, to the image at dest,
+// built from spec using the bootstrap sources.
+func attestBuild(ctx context.Context, dest, spec string, sources []attest.ResourceDescriptor, startedOn time.Time, s attest.Signer) error {
+	buildArgsMap, err := args.ReadBuildArgs(buildArgs.buildVarArgs, buildArgs.buildVarArgFile)
+	if err != nil {
+		return err
+	}
+
+	builderID := buildArgs.attestBuilderID
+	if builderID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		builderID = "singularity://" + hostname
+	}
+
+	m := attest.Materials{
+		Definition:     attest.ResourceDescriptor{URI: spec},
+		Sources:        sources,
+		BuildArgs:      buildArgsMap,
+		BuilderID:      builderID,
+		BuilderVersion: buildcfg.PACKAGE_VERSION,
+		StartedOn:      startedOn,
+		FinishedOn:     time.Now(),
+	}
+
+	// A definition file, or Dockerfile, is recorded with its digest.
+	if fs.IsFile(spec) && !isImage(spec) {
+		abs, err := filepath.Abs(spec)
+		if err != nil {
+			return err
+		}
+		if m.Definition.Digest, err = attest.FileDigest(abs); err != nil {
+			return err
+		}
+		m.Definition.URI = "file://" + abs
+	}
+
+	return attestImage(ctx, dest, m, s)
+}
+
 func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 	// building encrypted containers on the remote builder is not currently supported
 	if buildArgs.encrypt {
@@ -281,8 +498,13 @@ func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 		buildArgs.webURL = webURL
 	}
 
+	// A self-hosted build server may use its own token, or none at all.
+	if token, ok := os.LookupEnv("SINGULARITY_BUILDER_TOKEN"); ok {
+		authToken = token
+	}
+
 	// submitting a remote build requires a valid authToken
-	if authToken == "" {
+	if authToken == "" && !cmd.Flag("builder").Changed {
 		sylog.Fatalf("Unable to submit build job: %v", remoteWarning)
 	}
 
@@ -368,25 +590,13 @@ func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 	}
 }
 
-func runBuildLocal(ctx context.Context, authConf *authn.AuthConfig, cmd *cobra.Command, dst, spec string) {
-	var keyInfo *cryptkey.KeyInfo
-	if buildArgs.encrypt || promptForPassphrase || cmd.Flags().Lookup("pem-path").Changed {
-		if os.Getuid() != 0 {
-			sylog.Fatalf("You must be root to build an encrypted container")
-		}
-
-		k, err := getEncryptionMaterial(cmd)
-		if err != nil {
-			sylog.Fatalf("While handling encryption material: %v", err)
-		}
-		keyInfo = k
-	} else {
-		_, passphraseEnvOK := os.LookupEnv("SINGULARITY_ENCRYPTION_PASSPHRASE")
-		_, pemPathEnvOK := os.LookupEnv("SINGULARITY_ENCRYPTION_PEM_PATH")
-		if passphraseEnvOK || pemPathEnvOK {
-			sylog.Warningf("Encryption related env vars found, but --encrypt was not specified. NOT encrypting container.")
-		}
+// runBuildLocal performs a local build from spec to dst, returning the
+// bootstrap sources of the build.
+func runBuildLocal(ctx context.Context, authConf *authn.AuthConfig, cmd *cobra.Command, dst, spec string) []attest.ResourceDescriptor {
+	if encryptionRequested(cmd) && os.Getuid() != 0 {
+		sylog.Fatalf("You must be root to build an encrypted container")
 	}
+	keyInfo := buildEncryptionMaterial(cmd)
 
 	imgCache := getCacheHandle(cache.Config{Disable: disableCache})
 	if imgCache == nil {
@@ -408,6 +618,25 @@ func runBuildLocal(ctx context.Context, authConf *authn.AuthConfig, cmd *cobra.C
 		sylog.Fatalf("Unable to build from %s: %v", spec, err)
 	}
 
+	secrets := make([]types.Secret, 0, len(buildArgs.secrets))
+	for _, spec := range buildArgs.secrets {
+		sec, err := types.ParseSecret(spec)
+		if err != nil {
+			sylog.Fatalf("While processing build secrets: %v", err)
+		}
+		if slices.ContainsFunc(secrets, func(s types.Secret) bool { return s.ID == sec.ID }) {
+			sylog.Fatalf("While processing build secrets: duplicate secret id %q", sec.ID)
+		}
+		secrets = append(secrets, sec)
+	}
+
+	contextDir := buildArgs.contextDir
+	if contextDir != "" {
+		if contextDir, err = filepath.Abs(contextDir); err != nil {
+			sylog.Fatalf("While resolving build context directory: %v", err)
+		}
+	}
+
 	authToken := ""
 	hasLibrary := false
 	hasSIF := false
@@ -463,9 +692,12 @@ func runBuildLocal(ctx context.Context, authConf *authn.AuthConfig, cmd *cobra.C
 	b, err := build.New(
 		defs,
 		build.Config{
-			Dest:      dst,
-			Format:    buildFormat,
-			NoCleanUp: buildArgs.noCleanUp,
+			Dest:           dst,
+			Format:         buildFormat,
+			NoCleanUp:      buildArgs.noCleanUp,
+			KeepFailed:     buildArgs.keepFailed,
+			ShellOnFailure: buildArgs.shellOnFailure,
+			Resume:         buildArgs.resume,
 			Opts: types.Options{
 				ImgCache:          imgCache,
 				TmpDir:            tmpDir,
@@ -484,6 +716,11 @@ func runBuildLocal(ctx context.Context, authConf *authn.AuthConfig, cmd *cobra.C
 				EncryptionKeyInfo: keyInfo,
 				FixPerms:          buildArgs.fixPerms,
 				SandboxTarget:     sandboxTarget,
+				Secrets:           secrets,
+				Network:           buildArgs.network,
+				CPUs:              buildArgs.cpus,
+				Memory:            buildArgs.memory,
+				ContextDir:        contextDir,
 				// Only perform a build with the host DefaultPlatform at present.
 				// TODO: rework --arch handling for remote builds so that local builds can specify --arch and --platform.
 				Platform: *dp,
@@ -496,6 +733,7 @@ func runBuildLocal(ctx context.Context, authConf *authn.AuthConfig, cmd *cobra.C
 	if err = b.Full(ctx); err != nil {
 		sylog.Fatalf("While performing build: %v", err)
 	}
+	return b.Sources()
 }
 
 func checkSections() error {
@@ -527,6 +765,30 @@ func isImage(spec string) bool {
 	return err == nil
 }
 
+// encryptionRequested returns whether the build output should be encrypted.
+func encryptionRequested(cmd *cobra.Command) bool {
+	return buildArgs.encrypt || promptForPassphrase || cmd.Flags().Lookup("pem-path").Changed || len(buildArgs.recipients) > 0
+}
+
+// buildEncryptionMaterial returns the key material to encrypt the build output
+// with, or nil if encryption was not requested.
+func buildEncryptionMaterial(cmd *cobra.Command) *cryptkey.KeyInfo {
+	if !encryptionRequested(cmd) {
+		_, passphraseEnvOK := os.LookupEnv("SINGULARITY_ENCRYPTION_PASSPHRASE")
+		_, pemPathEnvOK := os.LookupEnv("SINGULARITY_ENCRYPTION_PEM_PATH")
+		if passphraseEnvOK || pemPathEnvOK {
+			sylog.Warningf("Encryption related env vars found, but --encrypt was not specified. NOT encrypting container.")
+		}
+		return nil
+	}
+
+	k, err := getEncryptionMaterial(cmd)
+	if err != nil {
+		sylog.Fatalf("While handling encryption material: %v", err)
+	}
+	return k
+}
+
 // getEncryptionMaterial handles the setting of encryption environment and flag parameters to eventually be
 // passed to the crypt package for handling.
 // This handles the SINGULARITY_ENCRYPTION_PASSPHRASE/PEM_PATH envvars outside of cobra in order to
@@ -537,11 +799,28 @@ func getEncryptionMaterial(cmd *cobra.Command) (*cryptkey.KeyInfo, error) {
 	passphraseEnv, passphraseEnvOK := os.LookupEnv("SINGULARITY_ENCRYPTION_PASSPHRASE")
 	pemPathEnv, pemPathEnvOK := os.LookupEnv("SINGULARITY_ENCRYPTION_PEM_PATH")
 
+	var recipients []string
+	if cmd.Name() == "build" {
+		recipients = buildArgs.recipients
+	}
+
 	// checks for no flags/envvars being set
-	if !PEMFlag.Changed && !pemPathEnvOK && !passphraseFlag.Changed && !passphraseEnvOK {
+	if !PEMFlag.Changed && !pemPathEnvOK && !passphraseFlag.Changed && !passphraseEnvOK && len(recipients) == 0 {
 		return nil, nil
 	}
 
+	// Additional recipients are only supported with PEM key material.
+	if len(recipients) > 0 {
+		if passphraseFlag.Changed || (passphraseEnvOK && !PEMFlag.Changed && !pemPathEnvOK) {
+			sylog.Fatalf("Cannot encrypt container with a passphrase for additional recipients")
+		}
+		for _, r := range recipients {
+			if _, err := loadEncryptionKey(cmd, r); err != nil {
+				sylog.Fatalf("Invalid encryption public key: %v", err)
+			}
+		}
+	}
+
 	// order of precedence:
 	// 1. PEM flag
 	// 2. Passphrase flag
@@ -560,19 +839,17 @@ func getEncryptionMaterial(cmd *cobra.Command) (*cryptkey.KeyInfo, error) {
 
 		sylog.Verbosef("Using pem path flag for encrypted container")
 
-		// Check it's a valid PEM public key we can load, before starting the build (#4173)
-		if cmd.Name() == "build" {
-			if _, err := cryptkey.LoadPEMPublicKey(encryptionPEMPath); err != nil {
+		// Check it's a valid key we can load, before starting the build (#4173),
+		// or before launching the engine for actions on a container (#5221)
+		passphrase, err := loadEncryptionKey(cmd, encryptionPEMPath)
+		if err != nil {
+			if cmd.Name() == "build" {
 				sylog.Fatalf("Invalid encryption public key: %v", err)
 			}
-			// or a valid private key before launching the engine for actions on a container (#5221)
-		} else {
-			if _, err := cryptkey.LoadPEMPrivateKey(encryptionPEMPath); err != nil {
-				sylog.Fatalf("Invalid encryption private key: %v", err)
-			}
+			sylog.Fatalf("Invalid encryption private key: %v", err)
 		}
 
-		return &cryptkey.KeyInfo{Format: cryptkey.PEM, Path: encryptionPEMPath}, nil
+		return &cryptkey.KeyInfo{Format: cryptkey.PEM, Path: encryptionPEMPath, Material: passphrase, Recipients: recipients}, nil
 	}
 
 	if passphraseFlag.Changed {
@@ -598,7 +875,19 @@ func getEncryptionMaterial(cmd *cobra.Command) (*cryptkey.KeyInfo, error) {
 		}
 
 		sylog.Verbosef("Using pem path environment variable for encrypted container")
-		return &cryptkey.KeyInfo{Format: cryptkey.PEM, Path: pemPathEnv}, nil
+
+		var passphrase string
+		if cmd.Name() != "build" {
+			if passphrase, err = pgpKeyPassphrase(pemPathEnv); err != nil {
+				sylog.Fatalf("Invalid encryption private key: %v", err)
+			}
+		}
+		return &cryptkey.KeyInfo{Format: cryptkey.PEM, Path: pemPathEnv, Material: passphrase, Recipients: recipients}, nil
+	}
+
+	if len(recipients) > 0 {
+		sylog.Verbosef("Using recipient public keys for encrypted container")
+		return &cryptkey.KeyInfo{Format: cryptkey.PEM, Recipients: recipients}, nil
 	}
 
 	if passphraseEnvOK {
@@ -608,3 +897,72 @@ func getEncryptionMaterial(cmd *cobra.Command) (*cryptkey.KeyInfo, error) {
 
 	return nil, nil
 }
+
+// loadEncryptionKey checks the key at path can be loaded. When building, it
+// must be a PEM formatted RSA public key, or an ASCII armored PGP public key.
+// Otherwise, it must be the matching private key, and the passphrase needed to
+// decrypt a PGP private key is returned.
+func loadEncryptionKey(cmd *cobra.Command, path string) (string, error) {
+	if cmd.Name() != "build" {
+		return pgpKeyPassphrase(path)
+	}
+
+	if _, err := cryptkey.LoadPGPKey(path); err == nil || !errors.Is(err, cryptkey.ErrNoPGPData) {
+		return "", err
+	}
+	_, err := cryptkey.LoadPEMPublicKey(path)
+	return "", err
+}
+
+// pgpKeyPassphrase checks the private key at path can be loaded. If it is an
+// encrypted PGP private key, the user is prompted for its passphrase, which
+// is returned.
+func pgpKeyPassphrase(path string) (string, error) {
+	e, err := cryptkey.LoadPGPKey(path)
+	if errors.Is(err, cryptkey.ErrNoPGPData) {
+		_, err := cryptkey.LoadPEMPrivateKey(path)
+		return "", err
+	} else if err != nil {
+		return "", err
+	}
+
+	if e.PrivateKey == nil {
+		return "", fmt.Errorf("%s holds a PGP public key", path)
+	}
+	if !e.PrivateKey.Encrypted {
+		return "", nil
+	}
+
+	passphrase, err := interactive.AskQuestionNoEcho("Enter PGP key passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if err := e.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+		return "", fmt.Errorf("could not decrypt PGP private key: %v", err)
+	}
+	return passphrase, nil
+}
+
+// checkBuildIsolation validates the --network, --cpus and --memory options.
+func checkBuildIsolation() error {
+	switch buildArgs.network {
+	case "default", "none":
+	default:
+		return fmt.Errorf("invalid --network value %q: must be 'none' or 'default'", buildArgs.network)
+	}
+	if buildArgs.cpus != "" {
+		c, err := decimal.NewFromString(buildArgs.cpus)
+		if err != nil {
+			return fmt.Errorf("invalid --cpus value: %w", err)
+		}
+		if !c.IsPositive() {
+			return fmt.Errorf("invalid --cpus value %q: must be greater than 0", buildArgs.cpus)
+		}
+	}
+	if buildArgs.memory != "" {
+		if _, err := units.RAMInBytes(buildArgs.memory); err != nil {
+			return fmt.Errorf("invalid --memory value: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/pkg/build/buildserver"
+	"github.com/sylabs/singularity/v4/pkg/cmdline"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var buildServerArgs struct {
+	listen     string
+	workDir    string
+	tokenFile  string
+	maxBuilds  int
+	retention  int
+	maxContext int
+	tlsCert    string
+	tlsKey     string
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(BuildServerCmd)
+		cmdManager.RegisterFlagForCmd(&buildServerListenFlag, BuildServerCmd)
+		cmdManager.RegisterFlagForCmd(&buildServerWorkDirFlag, BuildServerCmd)
+		cmdManager.RegisterFlagForCmd(&buildServerTokenFileFlag, BuildServerCmd)
+		cmdManager.RegisterFlagForCmd(&buildServerMaxBuildsFlag, BuildServerCmd)
+		cmdManager.RegisterFlagForCmd(&buildServerRetentionFlag, BuildServerCmd)
+		cmdManager.RegisterFlagForCmd(&buildServerMaxContextFlag, BuildServerCmd)
+		cmdManager.RegisterFlagForCmd(&buildServerTLSCertFlag, BuildServerCmd)
+		cmdManager.RegisterFlagForCmd(&buildServerTLSKeyFlag, BuildServerCmd)
+	})
+}
+
+// --listen
+var buildServerListenFlag = cmdline.Flag{
+	ID:           "buildServerListenFlag",
+	Value:        &buildServerArgs.listen,
+	DefaultValue: "localhost:8080",
+	Name:         "listen",
+	Usage:        "address to listen on",
+	Tag:          "<address>",
+}
+
+// --work-dir
+var buildServerWorkDirFlag = cmdline.Flag{
+	ID:           "buildServerWorkDirFlag",
+	Value:        &buildServerArgs.workDir,
+	DefaultValue: filepath.Join(os.TempDir(), "singularity-build-server"),
+	Name:         "work-dir",
+	Usage:        "directory holding build contexts and builds",
+	Tag:          "<dir>",
+}
+
+// --token-file
+var buildServerTokenFileFlag = cmdline.Flag{
+	ID:           "buildServerTokenFileFlag",
+	Value:        &buildServerArgs.tokenFile,
+	DefaultValue: "",
+	Name:         "token-file",
+	Usage:        "file holding the token that clients must send (required)",
+	Tag:          "<path>",
+}
+
+// --max-builds
+var buildServerMaxBuildsFlag = cmdline.Flag{
+	ID:           "buildServerMaxBuildsFlag",
+	Value:        &buildServerArgs.maxBuilds,
+	DefaultValue: 1,
+	Name:         "max-builds",
+	Usage:        "number of builds that may run at the same time, further builds are queued",
+}
+
+// --retention
+var buildServerRetentionFlag = cmdline.Flag{
+	ID:           "buildServerRetentionFlag",
+	Value:        &buildServerArgs.retention,
+	DefaultValue: 60,
+	Name:         "retention",
+	Usage:        "minutes a completed build, and its image, are kept on the server",
+}
+
+// --max-context-size
+var buildServerMaxContextFlag = cmdline.Flag{
+	ID:           "buildServerMaxContextFlag",
+	Value:        &buildServerArgs.maxContext,
+	DefaultValue: 1024,
+	Name:         "max-context-size",
+	Usage:        "maximum size, in MiB, of a build context uploaded by a client",
+}
+
+// --tls-cert
+var buildServerTLSCertFlag = cmdline.Flag{
+	ID:           "buildServerTLSCertFlag",
+	Value:        &buildServerArgs.tlsCert,
+	DefaultValue: "",
+	Name:         "tls-cert",
+	Usage:        "serve over HTTPS, with the certificate in this PEM file",
+	Tag:          "<path>",
+}
+
+// --tls-key
+var buildServerTLSKeyFlag = cmdline.Flag{
+	ID:           "buildServerTLSKeyFlag",
+	Value:        &buildServerArgs.tlsKey,
+	DefaultValue: "",
+	Name:         "tls-key",
+	Usage:        "private key, in PEM format, of the --tls-cert certificate",
+	Tag:          "<path>",
+}
+
+// BuildServerCmd is the 'build-server' command that runs a remote build server.
+var BuildServerCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, _ []string) {
+		if (buildServerArgs.tlsCert == "") != (buildServerArgs.tlsKey == "") {
+			sylog.Fatalf("--tls-cert and --tls-key must be used together")
+		}
+
+		if buildServerArgs.tokenFile == "" {
+			sylog.Fatalf("A --token-file is required, so that only clients holding the token may submit builds")
+		}
+		b, err := os.ReadFile(buildServerArgs.tokenFile)
+		if err != nil {
+			sylog.Fatalf("While reading token: %v", err)
+		}
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			sylog.Fatalf("Token file %s is empty", buildServerArgs.tokenFile)
+		}
+		if buildServerArgs.maxContext <= 0 {
+			sylog.Fatalf("--max-context-size must be greater than 0")
+		}
+
+		s, err := buildserver.New(buildserver.Config{
+			WorkDir:        buildServerArgs.workDir,
+			AuthToken:      token,
+			MaxBuilds:      buildServerArgs.maxBuilds,
+			Retention:      time.Duration(buildServerArgs.retention) * time.Minute,
+			MaxContextSize: int64(buildServerArgs.maxContext) << 20,
+		})
+		if err != nil {
+			sylog.Fatalf("Unable to create build server: %v", err)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGTERM)
+		defer stop()
+
+		sylog.Infof("Build server listening on %s", buildServerArgs.listen)
+		if err := s.ListenAndServe(ctx, buildServerArgs.listen, buildServerArgs.tlsCert, buildServerArgs.tlsKey); err != nil {
+			sylog.Fatalf("Build server failed: %v", err)
+		}
+	},
+
+	Use:     docs.BuildServerUse,
+	Short:   docs.BuildServerShort,
+	Long:    docs.BuildServerLong,
+	Example: docs.BuildServerExample,
+}
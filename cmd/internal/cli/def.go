@@ -0,0 +1,35 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/pkg/cmdline"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(DefCmd)
+		cmdManager.RegisterSubCmd(DefCmd, DefFromDockerfileCmd)
+		cmdManager.RegisterSubCmd(DefCmd, DefToDockerfileCmd)
+	})
+}
+
+// DefCmd is the 'def' command that provides utilities for definition files.
+var DefCmd = &cobra.Command{
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return errors.New("invalid command")
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.DefUse,
+	Short:   docs.DefShort,
+	Long:    docs.DefLong,
+	Example: docs.DefExample,
+}
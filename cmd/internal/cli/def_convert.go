@@ -0,0 +1,57 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/app/singularity"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// DefFromDockerfileCmd is the 'def from-dockerfile' command that converts a
+// Dockerfile into a definition file.
+var DefFromDockerfileCmd = &cobra.Command{
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		dst := ""
+		if len(args) > 1 {
+			dst = args[1]
+		}
+		if err := singularity.DefFromDockerfile(args[0], dst); err != nil {
+			sylog.Fatalf("%v", err.Error())
+		}
+		return nil
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.DefFromDockerfileUse,
+	Short:   docs.DefFromDockerfileShort,
+	Long:    docs.DefFromDockerfileLong,
+	Example: docs.DefFromDockerfileExample,
+}
+
+// DefToDockerfileCmd is the 'def to-dockerfile' command that converts a
+// definition file into a Dockerfile.
+var DefToDockerfileCmd = &cobra.Command{
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		dst := ""
+		if len(args) > 1 {
+			dst = args[1]
+		}
+		if err := singularity.DefToDockerfile(args[0], dst); err != nil {
+			sylog.Fatalf("%v", err.Error())
+		}
+		return nil
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.DefToDockerfileUse,
+	Short:   docs.DefToDockerfileShort,
+	Long:    docs.DefToDockerfileLong,
+	Example: docs.DefToDockerfileExample,
+}
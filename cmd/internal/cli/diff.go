@@ -0,0 +1,76 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/app/singularity"
+	"github.com/sylabs/singularity/v4/internal/pkg/cache"
+	"github.com/sylabs/singularity/v4/pkg/cmdline"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var diffJSON bool
+
+// -j|--json
+var diffJSONFlag = cmdline.Flag{
+	ID:           "diffJSONFlag",
+	Value:        &diffJSON,
+	DefaultValue: false,
+	Name:         "json",
+	ShortHand:    "j",
+	Usage:        "print the differences in JSON format",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(DiffCmd)
+
+		cmdManager.RegisterFlagForCmd(&diffJSONFlag, DiffCmd)
+		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, DiffCmd)
+		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, DiffCmd)
+
+		cmdManager.RegisterFlagForCmd(&dockerHostFlag, DiffCmd)
+		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, DiffCmd)
+		cmdManager.RegisterFlagForCmd(&dockerPasswordFlag, DiffCmd)
+		cmdManager.RegisterFlagForCmd(&dockerLoginFlag, DiffCmd)
+
+		cmdManager.RegisterFlagForCmd(&commonArchFlag, DiffCmd)
+		cmdManager.RegisterFlagForCmd(&commonPlatformFlag, DiffCmd)
+
+		cmdManager.RegisterFlagForCmd(&commonAuthFileFlag, DiffCmd)
+	})
+}
+
+// DiffCmd is the 'diff' command that shows the differences between two images.
+var DiffCmd = &cobra.Command{
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ociAuth, err := makeOCICredentials(cmd)
+		if err != nil {
+			sylog.Fatalf("While creating Docker credentials: %v", err)
+		}
+
+		opts := singularity.DiffOptions{
+			TransportOptions: getOCITransportOptions(ociAuth),
+			Cache:            getCacheHandle(cache.Config{}),
+			TmpDir:           tmpDir,
+			JSON:             diffJSON,
+		}
+		if err := singularity.Diff(cmd.Context(), args[0], args[1], opts, os.Stdout); err != nil {
+			sylog.Fatalf("While comparing images: %v", err)
+		}
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.DiffUse,
+	Short:   docs.DiffShort,
+	Long:    docs.DiffLong,
+	Example: docs.DiffExample,
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/pkg/cmdline"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(ImageCmd)
+
+		cmdManager.RegisterSubCmd(ImageCmd, ImageConvertCmd)
+		cmdManager.RegisterFlagForCmd(&imageConvertLayerFormatFlag, ImageConvertCmd)
+
+		cmdManager.RegisterSubCmd(ImageCmd, ImageSquashCmd)
+
+		cmdManager.RegisterSubCmd(ImageCmd, ImageRekeyCmd)
+		cmdManager.RegisterFlagForCmd(&imageRekeyAddFlag, ImageRekeyCmd)
+		cmdManager.RegisterFlagForCmd(&imageRekeyRemoveFlag, ImageRekeyCmd)
+		cmdManager.RegisterFlagForCmd(&commonPromptForPassphraseFlag, ImageRekeyCmd)
+		cmdManager.RegisterFlagForCmd(&commonPEMFlag, ImageRekeyCmd)
+
+		for _, cmd := range []*cobra.Command{ImageCpCmd, ImageLsCmd} {
+			cmdManager.RegisterSubCmd(ImageCmd, cmd)
+			cmdManager.RegisterFlagForCmd(&commonPromptForPassphraseFlag, cmd)
+			cmdManager.RegisterFlagForCmd(&commonPEMFlag, cmd)
+			cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, cmd)
+			cmdManager.RegisterFlagForCmd(&commonArchFlag, cmd)
+			cmdManager.RegisterFlagForCmd(&commonPlatformFlag, cmd)
+		}
+	})
+}
+
+// ImageCmd is the 'image' command that allows management of image content.
+var ImageCmd = &cobra.Command{
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return errors.New("invalid command")
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.ImageUse,
+	Short:   docs.ImageShort,
+	Long:    docs.ImageLong,
+	Example: docs.ImageExample,
+}
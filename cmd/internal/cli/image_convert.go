@@ -0,0 +1,48 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+	"github.com/sylabs/singularity/v4/pkg/cmdline"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var imageConvertLayerFormat string
+
+// --layer-format
+var imageConvertLayerFormatFlag = cmdline.Flag{
+	ID:           "imageConvertLayerFormatFlag",
+	Value:        &imageConvertLayerFormat,
+	DefaultValue: ocisif.SquashfsLayerFormat,
+	Name:         "layer-format",
+	Usage:        "format to convert layers to - squashfs, squashfs-zstd or erofs",
+}
+
+// ImageConvertCmd is the 'image convert' command that rewrites the layers of
+// an OCI-SIF image in another filesystem format.
+var ImageConvertCmd = &cobra.Command{
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		if _, err := ocisif.LayerFormatMediaType(imageConvertLayerFormat); err != nil {
+			sylog.Fatalf("%v", err)
+		}
+		tmpEnv := os.Getenv("SINGULARITY_TMPDIR")
+		if err := ocisif.ConvertLayers(args[0], imageConvertLayerFormat, tmpEnv); err != nil {
+			sylog.Fatalf("While converting layers: %v", err)
+		}
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.ImageConvertUse,
+	Short:   docs.ImageConvertShort,
+	Long:    docs.ImageConvertLong,
+	Example: docs.ImageConvertExample,
+}
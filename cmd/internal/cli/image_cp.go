@@ -0,0 +1,64 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/app/singularity"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// splitImagePath splits an <image>:<path> argument at the first ':' that is
+// followed by an absolute path. If there is no such ':', the path is empty.
+func splitImagePath(arg string) (image, path string) {
+	if i := strings.Index(arg, ":/"); i > 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, ""
+}
+
+// imageFilesOptions returns the options used to read files from an image,
+// from the flags of cmd.
+func imageFilesOptions(cmd *cobra.Command) singularity.ImageFilesOptions {
+	ki, err := getEncryptionMaterial(cmd)
+	if err != nil {
+		sylog.Fatalf("While handling encryption material: %v", err)
+	}
+	opts := singularity.ImageFilesOptions{
+		KeyInfo: ki,
+		TmpDir:  tmpDir,
+	}
+	// Without --platform, a single image is read whatever its platform.
+	if platform != "" {
+		p := getOCIPlatform()
+		opts.Platform = &p
+	}
+	return opts
+}
+
+// ImageCpCmd is the 'image cp' command that copies a file or directory out of
+// an image.
+var ImageCpCmd = &cobra.Command{
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		image, path := splitImagePath(args[0])
+		if path == "" {
+			sylog.Fatalf("Source must be specified as <image>:<path>")
+		}
+		if err := singularity.ImageCp(image, path, args[1], imageFilesOptions(cmd)); err != nil {
+			sylog.Fatalf("While copying from image: %v", err)
+		}
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.ImageCpUse,
+	Short:   docs.ImageCpShort,
+	Long:    docs.ImageCpLong,
+	Example: docs.ImageCpExample,
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/app/singularity"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// ImageLsCmd is the 'image ls' command that lists the content of a directory
+// in an image.
+var ImageLsCmd = &cobra.Command{
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		image, path := splitImagePath(args[0])
+		if path == "" {
+			path = "/"
+		}
+		if err := singularity.ImageLs(image, path, imageFilesOptions(cmd), os.Stdout); err != nil {
+			sylog.Fatalf("While listing image content: %v", err)
+		}
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.ImageLsUse,
+	Short:   docs.ImageLsShort,
+	Long:    docs.ImageLsLong,
+	Example: docs.ImageLsExample,
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+	"github.com/sylabs/singularity/v4/pkg/cmdline"
+	"github.com/sylabs/singularity/v4/pkg/image"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
+)
+
+var (
+	imageRekeyAdd    []string
+	imageRekeyRemove []string
+)
+
+// --add-recipient
+var imageRekeyAddFlag = cmdline.Flag{
+	ID:           "imageRekeyAddFlag",
+	Value:        &imageRekeyAdd,
+	DefaultValue: []string{},
+	Name:         "add-recipient",
+	Usage:        "path to a PEM formatted RSA or ASCII armored PGP public key to add as a recipient (can be specified multiple times)",
+}
+
+// --remove-recipient
+var imageRekeyRemoveFlag = cmdline.Flag{
+	ID:           "imageRekeyRemoveFlag",
+	Value:        &imageRekeyRemove,
+	DefaultValue: []string{},
+	Name:         "remove-recipient",
+	Usage:        "ID, or path to the public key, of a recipient to remove (can be specified multiple times)",
+}
+
+// ImageRekeyCmd is the 'image rekey' command that updates the recipients of an
+// encrypted SIF image.
+var ImageRekeyCmd = &cobra.Command{
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(imageRekeyAdd) == 0 && len(imageRekeyRemove) == 0 {
+			ids, err := cryptkey.Recipients(args[0])
+			if err != nil {
+				sylog.Fatalf("While reading recipients: %v", err)
+			}
+			printRecipients(ids)
+			return
+		}
+
+		k, err := getEncryptionMaterial(cmd)
+		if err != nil {
+			sylog.Fatalf("While handling encryption material: %v", err)
+		}
+		if k == nil || k.Format != cryptkey.PEM {
+			sylog.Fatalf("Updating recipients requires the private key of a recipient, specified with --pem-path")
+		}
+
+		// Removing a recipient changes the key of the image. The layers of
+		// an OCI-SIF image are rewritten, while the encrypted partition of a
+		// native SIF image is changed in place.
+		var rotate cryptkey.RotateFunc
+		if len(imageRekeyRemove) > 0 {
+			ociSIF, err := image.IsOCISIF(args[0])
+			if err != nil {
+				sylog.Fatalf("While checking image: %v", err)
+			}
+			if ociSIF {
+				rotate = func(path string, key, newKey, msg []byte) error {
+					return ocisif.RotateLayerKey(path, key, newKey, msg, "")
+				}
+			} else if signed, err := hasSignatures(args[0]); err != nil {
+				sylog.Fatalf("While checking image signatures: %v", err)
+			} else if signed {
+				sylog.Warningf("The key of the image is changed, so its existing signatures will no longer verify, and it must be signed again")
+			}
+		}
+
+		ids, err := cryptkey.Rekey(args[0], k, imageRekeyAdd, imageRekeyRemove, rotate)
+		if err != nil {
+			sylog.Fatalf("While updating recipients: %v", err)
+		}
+		printRecipients(ids)
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.ImageRekeyUse,
+	Short:   docs.ImageRekeyShort,
+	Long:    docs.ImageRekeyLong,
+	Example: docs.ImageRekeyExample,
+}
+
+// hasSignatures returns whether the native SIF image at path holds any
+// signatures.
+func hasSignatures(path string) (bool, error) {
+	fi, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return false, err
+	}
+	defer fi.UnloadContainer()
+
+	ds, err := fi.GetDescriptors(sif.WithDataType(sif.DataSignature))
+	return len(ds) > 0, err
+}
+
+// printRecipients prints the IDs of the recipients of an encrypted image.
+func printRecipients(ids []string) {
+	fmt.Println("Recipients:")
+	for _, id := range ids {
+		if id == "" {
+			id = "(unknown)"
+		}
+		fmt.Printf("  %s\n", id)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// ImageSquashCmd is the 'image squash' command that squashes the layers of an
+// OCI-SIF image into a single layer.
+var ImageSquashCmd = &cobra.Command{
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		tmpEnv := os.Getenv("SINGULARITY_TMPDIR")
+		if err := ocisif.SquashImage(args[0], tmpEnv); err != nil {
+			sylog.Fatalf("While squashing image: %v", err)
+		}
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.ImageSquashUse,
+	Short:   docs.ImageSquashShort,
+	Long:    docs.ImageSquashLong,
+	Example: docs.ImageSquashExample,
+}
@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2017-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2017-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -84,8 +84,11 @@ func init() {
 		cmdManager.RegisterSubCmd(KeyCmd, KeyImportCmd)
 		cmdManager.RegisterSubCmd(KeyCmd, KeyRemoveCmd)
 		cmdManager.RegisterSubCmd(KeyCmd, KeyExportCmd)
+		cmdManager.RegisterSubCmd(KeyCmd, KeyEditCmd)
+		cmdManager.RegisterSubCmd(KeyCmd, KeyAddSubkeyCmd)
+		cmdManager.RegisterSubCmd(KeyCmd, KeyRevokeCmd)
 
-		cmdManager.RegisterFlagForCmd(&keyServerURIFlag, KeySearchCmd, KeyPushCmd, KeyPullCmd)
+		cmdManager.RegisterFlagForCmd(&keyServerURIFlag, KeySearchCmd, KeyPushCmd, KeyPullCmd, KeyRevokeCmd)
 		cmdManager.RegisterFlagForCmd(&keySearchLongListFlag, KeySearchCmd)
 		cmdManager.RegisterFlagForCmd(&keyNewpairBitLengthFlag, KeyNewPairCmd)
 		cmdManager.RegisterFlagForCmd(&keyImportWithNewPasswordFlag, KeyImportCmd)
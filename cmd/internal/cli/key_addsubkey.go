@@ -0,0 +1,45 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// KeyAddSubkeyCmd is `singularity key addsubkey <fingerprint>' command
+var KeyAddSubkeyCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		var expiry time.Time
+		if cmd.Flags().Changed(keyExpireFlag.Name) {
+			var err error
+			if expiry, err = parseKeyExpiry(keyExpire, time.Now()); err != nil {
+				sylog.Fatalf("Invalid key expiry: %s", err)
+			}
+		}
+
+		keyring := sypgp.NewHandle("")
+		e, err := keyring.AddSigningSubkey(args[0], expiry, askKeyPassphrase)
+		if err != nil {
+			sylog.Fatalf("Unable to add subkey: %s", err)
+		}
+
+		sk := e.Subkeys[len(e.Subkeys)-1]
+		fmt.Printf("Signing subkey %016X added to key with fingerprint %X\n", sk.PublicKey.KeyId, e.PrimaryKey.Fingerprint)
+	},
+
+	Use:     docs.KeyAddSubkeyUse,
+	Short:   docs.KeyAddSubkeyShort,
+	Long:    docs.KeyAddSubkeyLong,
+	Example: docs.KeyAddSubkeyExample,
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/interactive"
+	"github.com/sylabs/singularity/v4/pkg/cmdline"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var keyExpire string
+
+// -e|--expire
+var keyExpireFlag = cmdline.Flag{
+	ID:           "keyExpireFlag",
+	Value:        &keyExpire,
+	DefaultValue: "",
+	Name:         "expire",
+	ShortHand:    "e",
+	Usage:        "expiry of the key, as a date (2027-06-30), a duration from now (90d, 12w, 2y), or 'never'",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterFlagForCmd(&keyExpireFlag, KeyEditCmd, KeyAddSubkeyCmd)
+	})
+}
+
+// KeyEditCmd is `singularity key edit --expire <expiry> <fingerprint>' command
+var KeyEditCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flags().Changed(keyExpireFlag.Name) {
+			sylog.Fatalf("The --expire flag is required")
+		}
+
+		expiry, err := parseKeyExpiry(keyExpire, time.Now())
+		if err != nil {
+			sylog.Fatalf("Invalid key expiry: %s", err)
+		}
+
+		keyring := sypgp.NewHandle("")
+		e, err := keyring.SetKeyExpiry(args[0], expiry, askKeyPassphrase)
+		if err != nil {
+			sylog.Fatalf("Unable to change key expiry: %s", err)
+		}
+
+		if expiry.IsZero() {
+			fmt.Printf("Key with fingerprint %X set to never expire\n", e.PrimaryKey.Fingerprint)
+		} else {
+			fmt.Printf("Key with fingerprint %X set to expire on %s\n", e.PrimaryKey.Fingerprint, expiry.Format(time.RFC3339))
+		}
+	},
+
+	Use:     docs.KeyEditUse,
+	Short:   docs.KeyEditShort,
+	Long:    docs.KeyEditLong,
+	Example: docs.KeyEditExample,
+}
+
+// parseKeyExpiry returns the expiry described by s, relative to now. The
+// expiry may be 'never' (or '0'), a date in YYYY-MM-DD format, a number of
+// days, weeks or years followed by 'd', 'w' or 'y', or a duration accepted
+// by time.ParseDuration. A zero time indicates a key that does not expire.
+func parseKeyExpiry(s string, now time.Time) (time.Time, error) {
+	switch s = strings.TrimSpace(s); s {
+	case "never", "0":
+		return time.Time{}, nil
+	case "":
+		return time.Time{}, fmt.Errorf("expiry must not be empty")
+	}
+
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n > 0 {
+		switch s[len(s)-1] {
+		case 'd':
+			return now.AddDate(0, 0, n), nil
+		case 'w':
+			return now.AddDate(0, 0, 7*n), nil
+		case 'y':
+			return now.AddDate(n, 0, 0), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date, or a duration", s)
+	}
+	if d <= 0 {
+		return time.Time{}, fmt.Errorf("%q is not a positive duration", s)
+	}
+	return now.Add(d), nil
+}
+
+// askKeyPassphrase prompts the user for the passphrase of a private key.
+func askKeyPassphrase() (string, error) {
+	return interactive.AskQuestionNoEcho("Enter key passphrase : ")
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseKeyExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		{s: "never", want: time.Time{}},
+		{s: "0", want: time.Time{}},
+		{s: "2027-06-30", want: time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)},
+		{s: "90d", want: now.AddDate(0, 0, 90)},
+		{s: "12w", want: now.AddDate(0, 0, 84)},
+		{s: "2y", want: now.AddDate(2, 0, 0)},
+		{s: "36h", want: now.Add(36 * time.Hour)},
+		{s: "", wantErr: true},
+		{s: "-1h", wantErr: true},
+		{s: "0y", wantErr: true},
+		{s: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseKeyExpiry(tt.s, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
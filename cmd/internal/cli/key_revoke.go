@@ -0,0 +1,145 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/pkg/remote/endpoint"
+	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+	"github.com/sylabs/singularity/v4/pkg/cmdline"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var (
+	keyRevokeReason  string
+	keyRevokeMessage string
+	keyRevokeSubkey  string
+	keyRevokePush    bool
+	keyRevokeOutput  string
+)
+
+// --reason
+var keyRevokeReasonFlag = cmdline.Flag{
+	ID:           "keyRevokeReasonFlag",
+	Value:        &keyRevokeReason,
+	DefaultValue: "unspecified",
+	Name:         "reason",
+	Usage:        "reason for the revocation (unspecified, superseded, retired, compromised)",
+}
+
+// -m|--message
+var keyRevokeMessageFlag = cmdline.Flag{
+	ID:           "keyRevokeMessageFlag",
+	Value:        &keyRevokeMessage,
+	DefaultValue: "",
+	Name:         "message",
+	ShortHand:    "m",
+	Usage:        "explanation of the revocation",
+}
+
+// --subkey
+var keyRevokeSubkeyFlag = cmdline.Flag{
+	ID:           "keyRevokeSubkeyFlag",
+	Value:        &keyRevokeSubkey,
+	DefaultValue: "",
+	Name:         "subkey",
+	Usage:        "revoke only the subkey with this key ID or fingerprint",
+}
+
+// -U|--push
+var keyRevokePushFlag = cmdline.Flag{
+	ID:           "keyRevokePushFlag",
+	Value:        &keyRevokePush,
+	DefaultValue: false,
+	Name:         "push",
+	ShortHand:    "U",
+	Usage:        "push the revoked public key to the key server",
+}
+
+// -o|--output
+var keyRevokeOutputFlag = cmdline.Flag{
+	ID:           "keyRevokeOutputFlag",
+	Value:        &keyRevokeOutput,
+	DefaultValue: "",
+	Name:         "output",
+	ShortHand:    "o",
+	Usage:        "write a revocation certificate to this file, without revoking the key in the keyring",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterFlagForCmd(&keyRevokeReasonFlag, KeyRevokeCmd)
+		cmdManager.RegisterFlagForCmd(&keyRevokeMessageFlag, KeyRevokeCmd)
+		cmdManager.RegisterFlagForCmd(&keyRevokeSubkeyFlag, KeyRevokeCmd)
+		cmdManager.RegisterFlagForCmd(&keyRevokePushFlag, KeyRevokeCmd)
+		cmdManager.RegisterFlagForCmd(&keyRevokeOutputFlag, KeyRevokeCmd)
+	})
+}
+
+// KeyRevokeCmd is `singularity key revoke <fingerprint>' command
+var KeyRevokeCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		reason, err := sypgp.ParseRevocationReason(keyRevokeReason)
+		if err != nil {
+			sylog.Fatalf("%s", err)
+		}
+
+		keyring := sypgp.NewHandle("")
+
+		if keyRevokeOutput != "" {
+			if keyRevokeSubkey != "" || keyRevokePush {
+				sylog.Fatalf("--subkey / --push not supported with --output: a revocation certificate revokes the primary key when imported")
+			}
+			b, err := keyring.RevocationCertificate(args[0], reason, keyRevokeMessage, askKeyPassphrase)
+			if err != nil {
+				sylog.Fatalf("Unable to create revocation certificate: %s", err)
+			}
+			if err := os.WriteFile(keyRevokeOutput, b, 0o600); err != nil {
+				sylog.Fatalf("Unable to write revocation certificate: %s", err)
+			}
+			fmt.Printf("Revocation certificate written to %s\n", keyRevokeOutput)
+			return
+		}
+
+		e, err := keyring.RevokeKey(args[0], keyRevokeSubkey, reason, keyRevokeMessage, askKeyPassphrase)
+		if err != nil {
+			sylog.Fatalf("Unable to revoke key: %s", err)
+		}
+
+		if keyRevokeSubkey != "" {
+			fmt.Printf("Subkey %s of key with fingerprint %X revoked\n", keyRevokeSubkey, e.PrimaryKey.Fingerprint)
+		} else {
+			fmt.Printf("Key with fingerprint %X revoked\n", e.PrimaryKey.Fingerprint)
+		}
+
+		if !keyRevokePush {
+			fmt.Println("NOT pushing revoked key to keystore")
+			return
+		}
+
+		co, err := getKeyserverClientOpts(keyServerURI, endpoint.KeyserverPushOp)
+		if err != nil {
+			sylog.Fatalf("Keyserver client failed: %s", err)
+		}
+
+		if err := sypgp.PushPubkey(cmd.Context(), e, co...); err != nil {
+			sylog.Errorf("push failed: %s", err)
+			os.Exit(2)
+		}
+		fmt.Println("Revoked key successfully pushed to keystore")
+	},
+
+	Use:     docs.KeyRevokeUse,
+	Short:   docs.KeyRevokeShort,
+	Long:    docs.KeyRevokeLong,
+	Example: docs.KeyRevokeExample,
+}
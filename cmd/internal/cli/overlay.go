@@ -16,6 +16,9 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&overlaySizeFlag, OverlayCreateCmd)
 		cmdManager.RegisterFlagForCmd(&overlayCreateDirFlag, OverlayCreateCmd)
 		cmdManager.RegisterFlagForCmd(&overlaySparseFlag, OverlayCreateCmd)
+		cmdManager.RegisterFlagForCmd(&overlayEncryptFlag, OverlayCreateCmd)
+		cmdManager.RegisterFlagForCmd(&commonPromptForPassphraseFlag, OverlayCreateCmd)
+		cmdManager.RegisterFlagForCmd(&commonPEMFlag, OverlayCreateCmd)
 
 		cmdManager.RegisterSubCmd(OverlayCmd, OverlaySyncCmd)
 
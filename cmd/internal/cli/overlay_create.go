@@ -6,12 +6,14 @@ import (
 	"github.com/sylabs/singularity/v4/internal/app/singularity"
 	"github.com/sylabs/singularity/v4/pkg/cmdline"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
 )
 
 var (
-	overlaySize   int
-	overlayDirs   []string
-	overlaySparse bool
+	overlaySize    int
+	overlayDirs    []string
+	overlaySparse  bool
+	overlayEncrypt bool
 )
 
 // -s|--size
@@ -44,11 +46,32 @@ var overlayCreateDirFlag = cmdline.Flag{
 	Usage:        "directory to create as part of the overlay layout",
 }
 
+// --encrypt
+var overlayEncryptFlag = cmdline.Flag{
+	ID:           "overlayEncryptFlag",
+	Value:        &overlayEncrypt,
+	DefaultValue: false,
+	Name:         "encrypt",
+	Usage:        "add an encrypted overlay to an OCI-SIF image",
+}
+
 // OverlayCreateCmd is the 'overlay create' command that allows to create writable overlay.
 var OverlayCreateCmd = &cobra.Command{
 	Args: cobra.ExactArgs(1),
-	RunE: func(_ *cobra.Command, args []string) error {
-		if err := singularity.OverlayCreate(args[0], overlaySize, overlaySparse, overlayDirs...); err != nil {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ki *cryptkey.KeyInfo
+		if overlayEncrypt {
+			k, err := getEncryptionMaterial(cmd)
+			if err != nil {
+				sylog.Fatalf("While handling encryption material: %v", err)
+			}
+			if k == nil {
+				sylog.Fatalf("Encryption was requested, but no key material was provided. Use --passphrase or --pem-path.")
+			}
+			ki = k
+		}
+
+		if err := singularity.OverlayCreate(args[0], overlaySize, overlaySparse, ki, overlayDirs...); err != nil {
 			sylog.Fatalf("%v", err.Error())
 		}
 		return nil
@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2020-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2020-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
 // distributed with the sources of this project regarding your rights to use or distribute this
 // software.
@@ -96,14 +96,14 @@ func decryptSelectedEntityInteractive(f sypgp.EntitySelector) sypgp.EntitySelect
 	}
 }
 
-// decryptPrivateKeyInteractive decrypts the private key in e, prompting the user for a passphrase.
+// decryptPrivateKeyInteractive decrypts the private keys in e, prompting the user for a passphrase.
 func decryptPrivateKeyInteractive(e *openpgp.Entity) error {
 	passphrase, err := interactive.AskQuestionNoEcho("Enter key passphrase : ")
 	if err != nil {
 		return err
 	}
 
-	return e.PrivateKey.Decrypt([]byte(passphrase))
+	return e.DecryptPrivateKeys([]byte(passphrase))
 }
 
 // primaryIdentity returns the Identity marked as primary, or the first identity if none are so
@@ -145,7 +145,7 @@ func isGlobal(e *openpgp.Entity) bool {
 }
 
 // outputVerify outputs a textual representation of r to stdout.
-func outputVerify(_ *sif.FileImage, r integrity.VerifyResult) bool {
+func outputVerify(_ *sif.FileImage, r sifsignature.VerifyResult) bool {
 	e := r.Entity()
 
 	// Print signing entity info.
@@ -167,6 +167,11 @@ func outputVerify(_ *sif.FileImage, r integrity.VerifyResult) bool {
 
 		// Always print fingerprint.
 		fmt.Printf("%-18v Fingerprint: %X\n", prefix, e.PrimaryKey.Fingerprint)
+
+		// Print key status, if the key is no longer valid.
+		if s := r.KeyStatus(); s != sifsignature.KeyStatusValid && s != sifsignature.KeyStatusUnknown {
+			fmt.Printf("%-18v Key status: %v\n", prefix, s)
+		}
 	}
 
 	// Print table of signed objects.
@@ -212,19 +217,53 @@ type keyEntity struct {
 	KeyLocal    bool
 	KeyCheck    bool
 	DataCheck   bool
+	KeyStatus   string
 }
 
 // keyList is a list of one or more keys.
 type keyList struct {
 	Signatures int
 	SignerKeys []*key
+	Threshold  *thresholdReport `json:",omitempty"`
+}
+
+// objectSigners lists the entities that signed an object, used for json output.
+type objectSigners struct {
+	ID        uint32
+	Partition string
+	Signers   []string
+}
+
+// thresholdReport describes the result of a threshold verification, used for json output.
+type thresholdReport struct {
+	Required int
+	Signers  []string
+	Objects  []objectSigners
+}
+
+// newThresholdReport returns the json output describing res.
+func newThresholdReport(res sifsignature.ThresholdResult) *thresholdReport {
+	r := thresholdReport{
+		Required: res.Threshold,
+		Signers:  append([]string{}, res.Signers...),
+		Objects:  make([]objectSigners, 0, len(res.Objects)),
+	}
+	for _, o := range res.Objects {
+		r.Objects = append(r.Objects, objectSigners{
+			ID:        o.ID,
+			Partition: o.DataType.String(),
+			Signers:   o.Signers,
+		})
+	}
+	return &r
 }
 
 // getJSONCallback returns a signature.VerifyCallback that appends to kl.
 func getJSONCallback(kl *keyList) sifsignature.VerifyCallback {
-	return func(f *sif.FileImage, r integrity.VerifyResult) bool {
+	return func(f *sif.FileImage, r sifsignature.VerifyResult) bool {
 		name, fp := "unknown", ""
 		var keyLocal, keyCheck bool
+		status := r.KeyStatus()
 
 		// Increment signature count.
 		kl.Signatures++
@@ -236,7 +275,7 @@ func getJSONCallback(kl *keyList) sifsignature.VerifyCallback {
 			}
 			fp = hex.EncodeToString(e.PrimaryKey.Fingerprint[:])
 			keyLocal = isLocal(e)
-			keyCheck = true
+			keyCheck = status != sifsignature.KeyStatusRevoked && status != sifsignature.KeyStatusExpired
 		}
 
 		// For each verified object, append an entry to the list.
@@ -248,10 +287,39 @@ func getJSONCallback(kl *keyList) sifsignature.VerifyCallback {
 				KeyLocal:    keyLocal,
 				KeyCheck:    keyCheck,
 				DataCheck:   true,
+				KeyStatus:   string(status),
 			}
 			kl.SignerKeys = append(kl.SignerKeys, &key{ke})
 		}
 
+		// A signature rejected due to the state of the signing key verifies no objects, so note
+		// each of the objects it covers.
+		if !keyCheck && len(r.Verified()) == 0 && status != sifsignature.KeyStatusUnknown {
+			id, isGroup := r.Signature().LinkedID()
+			ods, err := f.GetDescriptors(sif.WithID(id))
+			if isGroup {
+				ods, err = f.GetDescriptors(sif.WithGroupID(id))
+			}
+			if err != nil {
+				sylog.Errorf("failed to get descriptors: %v", err)
+				return false
+			}
+
+			for _, od := range ods {
+				ke := keyEntity{
+					Partition:   od.DataType().String(),
+					Name:        name,
+					Fingerprint: fp,
+					KeyLocal:    keyLocal,
+					KeyCheck:    false,
+					DataCheck:   false,
+					KeyStatus:   string(status),
+				}
+				kl.SignerKeys = append(kl.SignerKeys, &key{ke})
+			}
+			return false
+		}
+
 		var integrityError *integrity.ObjectIntegrityError
 		if errors.As(r.Error(), &integrityError) {
 			od, err := f.GetDescriptor(sif.WithID(integrityError.ID))
@@ -267,6 +335,7 @@ func getJSONCallback(kl *keyList) sifsignature.VerifyCallback {
 				KeyLocal:    keyLocal,
 				KeyCheck:    keyCheck,
 				DataCheck:   false,
+				KeyStatus:   string(status),
 			}
 			kl.SignerKeys = append(kl.SignerKeys, &key{ke})
 		}
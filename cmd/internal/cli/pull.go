@@ -154,6 +154,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&commonOCIFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&commonNoOCIFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&commonKeepLayersFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&commonLayerFormatFlag, PullCmd)
 
 		cmdManager.RegisterFlagForCmd(&commonArchFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&commonPlatformFlag, PullCmd)
@@ -183,6 +184,8 @@ func pullRun(cmd *cobra.Command, args []string) {
 		sylog.Fatalf("Failed to create an image cache handle")
 	}
 
+	checkLayerFormat(cmd)
+
 	pullFrom := args[len(args)-1]
 	transport, ref := uri.Split(pullFrom)
 	if ref == "" {
@@ -322,6 +325,7 @@ func pullRun(cmd *cobra.Command, args []string) {
 			NoCleanUp:   buildArgs.noCleanUp,
 			OciSif:      isOCI,
 			KeepLayers:  keepLayers,
+			LayerFormat: layerFormat,
 			Platform:    getOCIPlatform(),
 			ReqAuthFile: reqAuthFile,
 			WithCosign:  pullWithCosign,
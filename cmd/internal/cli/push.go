@@ -39,6 +39,10 @@ var (
 
 	// pushWithCosign sets whether cosign signatures are pushed when pushing OCI images.
 	pushWithCosign bool
+
+	// pushPlatformImages sets whether the per-platform images of a multi-platform
+	// build are pushed as an OCI image index.
+	pushPlatformImages bool
 )
 
 // --library
@@ -92,6 +96,16 @@ var pushWithCosignFlag = cmdline.Flag{
 	EnvKeys:      []string{"WITH_COSIGN"},
 }
 
+// --platform-images
+var pushPlatformImagesFlag = cmdline.Flag{
+	ID:           "pushPlatformImagesFlag",
+	Value:        &pushPlatformImages,
+	DefaultValue: false,
+	Name:         "platform-images",
+	Usage:        "push the per-platform OCI-SIF images written by a multi-platform build as an OCI image index",
+	EnvKeys:      []string{"PLATFORM_IMAGES"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(PushCmd)
@@ -109,6 +123,7 @@ func init() {
 
 		cmdManager.RegisterFlagForCmd(&pushLayerFormatFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&pushWithCosignFlag, PushCmd)
+		cmdManager.RegisterFlagForCmd(&pushPlatformImagesFlag, PushCmd)
 	})
 }
 
@@ -123,6 +138,9 @@ var PushCmd = &cobra.Command{
 		if transport == "" {
 			sylog.Fatalf("bad uri %s", dest)
 		}
+		if pushPlatformImages && transport != DockerProtocol {
+			sylog.Fatalf("--%s is only supported for push to docker / OCI registries", pushPlatformImagesFlag.Name)
+		}
 
 		switch transport {
 		case LibraryProtocol, "": // Handle pushing to a library
@@ -217,10 +235,11 @@ var PushCmd = &cobra.Command{
 				sylog.Fatalf("Unable to make docker oci credentials: %s", err)
 			}
 			opts := oci.PushOptions{
-				Auth:        ociAuth,
-				AuthFile:    reqAuthFile,
-				LayerFormat: pushLayerFormat,
-				WithCosign:  pushWithCosign,
+				Auth:           ociAuth,
+				AuthFile:       reqAuthFile,
+				LayerFormat:    pushLayerFormat,
+				WithCosign:     pushWithCosign,
+				PlatformImages: pushPlatformImages,
 			}
 			if err := oci.Push(cmd.Context(), file, ref, opts); err != nil {
 				sylog.Fatalf("Unable to push image to oci registry: %v", err)
@@ -1,4 +1,4 @@
-// Copyright (c) 2017-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2017-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -17,8 +17,10 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/v4/docs"
 	cosignsignature "github.com/sylabs/singularity/v4/internal/pkg/cosign"
+	"github.com/sylabs/singularity/v4/internal/pkg/pkcs11key"
 	sifsignature "github.com/sylabs/singularity/v4/internal/pkg/signature"
 	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/interactive"
 	"github.com/sylabs/singularity/v4/pkg/cmdline"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
 )
@@ -28,6 +30,11 @@ var (
 	priKeyIdx  int
 	signAll    bool
 	useCosign  bool
+	keyless    bool
+	fulcioURL  string
+	rekorURL   string
+	idToken    string
+	pkcs11URI  string
 )
 
 // -g|--group-id
@@ -111,6 +118,55 @@ var cosignFlag = cmdline.Flag{
 	Usage:        "sign an OCI-SIF with a cosign-compatible sigstore signature",
 }
 
+// --keyless
+var signKeylessFlag = cmdline.Flag{
+	ID:           "signKeylessFlag",
+	Value:        &keyless,
+	DefaultValue: false,
+	Name:         "keyless",
+	Usage:        "sign an OCI-SIF using an ephemeral key, certified with an OIDC identity token (requires --cosign)",
+}
+
+// --fulcio-url
+var signFulcioURLFlag = cmdline.Flag{
+	ID:           "signFulcioURLFlag",
+	Value:        &fulcioURL,
+	DefaultValue: cosignsignature.DefaultFulcioURL,
+	Name:         "fulcio-url",
+	Usage:        "URL of the Fulcio compatible certificate authority for --keyless signing",
+	EnvKeys:      []string{"FULCIO_URL"},
+}
+
+// --rekor-url
+var signRekorURLFlag = cmdline.Flag{
+	ID:           "signRekorURLFlag",
+	Value:        &rekorURL,
+	DefaultValue: cosignsignature.DefaultRekorURL,
+	Name:         "rekor-url",
+	Usage:        "URL of the Rekor compatible transparency log for --keyless signing",
+	EnvKeys:      []string{"REKOR_URL"},
+}
+
+// --identity-token
+var signIdentityTokenFlag = cmdline.Flag{
+	ID:           "signIdentityTokenFlag",
+	Value:        &idToken,
+	DefaultValue: "",
+	Name:         "identity-token",
+	Usage:        "OIDC identity token presented to the certificate authority for --keyless signing",
+	EnvKeys:      []string{"IDENTITY_TOKEN"},
+}
+
+// --pkcs11-uri
+var signPKCS11URIFlag = cmdline.Flag{
+	ID:           "signPKCS11URIFlag",
+	Value:        &pkcs11URI,
+	DefaultValue: "",
+	Name:         "pkcs11-uri",
+	Usage:        "PKCS#11 URI of a private key held in an HSM or security token",
+	EnvKeys:      []string{"SIGN_PKCS11_URI"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(SignCmd)
@@ -123,6 +179,11 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&signKeyIdxFlag, SignCmd)
 		cmdManager.RegisterFlagForCmd(&signAllFlag, SignCmd)
 		cmdManager.RegisterFlagForCmd(&cosignFlag, SignCmd)
+		cmdManager.RegisterFlagForCmd(&signKeylessFlag, SignCmd)
+		cmdManager.RegisterFlagForCmd(&signFulcioURLFlag, SignCmd)
+		cmdManager.RegisterFlagForCmd(&signRekorURLFlag, SignCmd)
+		cmdManager.RegisterFlagForCmd(&signIdentityTokenFlag, SignCmd)
+		cmdManager.RegisterFlagForCmd(&signPKCS11URIFlag, SignCmd)
 	})
 }
 
@@ -143,9 +204,39 @@ var SignCmd = &cobra.Command{
 }
 
 func doSignCmd(cmd *cobra.Command, cpath string) {
+	if keyless && !useCosign {
+		sylog.Fatalf("--keyless signatures require --cosign")
+	}
+
+	if pkcs11URI != "" && (priKeyPath != "" || priKeyIdx != 0) {
+		sylog.Fatalf("--key / --keyidx not supported: --pkcs11-uri signatures use a key held in a PKCS#11 token")
+	}
+
+	if useCosign && keyless {
+		if priKeyPath != "" || priKeyIdx != 0 || pkcs11URI != "" {
+			sylog.Fatalf("--key / --keyidx / --pkcs11-uri not supported: --keyless signatures use an ephemeral key")
+		}
+		if signAll || sifGroupID != 0 || sifDescID != 0 {
+			sylog.Fatalf("--cosign signatures sign an OCI image, specifying SIF descriptors / groups is not supported")
+		}
+		if idToken == "" {
+			sylog.Fatalf("--keyless signatures require an OIDC --identity-token")
+		}
+		err := cosignsignature.SignOCISIFKeyless(cmd.Context(), cpath, cosignsignature.KeylessSignOptions{
+			FulcioURL:     fulcioURL,
+			RekorURL:      rekorURL,
+			IdentityToken: idToken,
+		})
+		if err != nil {
+			sylog.Fatalf("%v", err)
+		}
+		sylog.Infof("Signature created and applied to image '%v'", cpath)
+		return
+	}
+
 	if useCosign {
-		if priKeyPath == "" {
-			sylog.Fatalf("--cosign signatures require a private --key to be specified")
+		if priKeyPath == "" && pkcs11URI == "" {
+			sylog.Fatalf("--cosign signatures require a private --key or --pkcs11-uri to be specified")
 		}
 		if priKeyIdx != 0 {
 			sylog.Fatalf("--keyidx not supported: --cosign signatures use a private --key, not the PGP keyring")
@@ -153,7 +244,12 @@ func doSignCmd(cmd *cobra.Command, cpath string) {
 		if signAll || sifGroupID != 0 || sifDescID != 0 {
 			sylog.Fatalf("--cosign signatures sign an OCI image, specifying SIF descriptors / groups is not supported")
 		}
-		err := signCosign(cmd.Context(), cpath, priKeyPath)
+		var err error
+		if pkcs11URI != "" {
+			err = signCosignPKCS11(cmd.Context(), cpath, pkcs11URI)
+		} else {
+			err = signCosign(cmd.Context(), cpath, priKeyPath)
+		}
 		if err != nil {
 			sylog.Fatalf("%v", err)
 		}
@@ -171,6 +267,17 @@ func signSIF(cmd *cobra.Command, cpath string) error {
 
 	// Set key material.
 	switch {
+	case pkcs11URI != "":
+		sylog.Infof("Signing image with key material from PKCS#11 token")
+
+		k, err := openPKCS11Key(pkcs11URI)
+		if err != nil {
+			return fmt.Errorf("failed to load key material: %v", err)
+		}
+		defer k.Close()
+
+		opts = append(opts, sifsignature.OptSignWithSigner(k.Signer()))
+
 	case cmd.Flag(signPrivateKeyFlag.Name).Changed:
 		sylog.Infof("Signing image with key material from '%v'", priKeyPath)
 
@@ -231,3 +338,32 @@ func signCosign(ctx context.Context, sifPath, keyPath string) error {
 
 	return cosignsignature.SignOCISIF(ctx, sifPath, sv)
 }
+
+func signCosignPKCS11(ctx context.Context, sifPath, uri string) error {
+	sylog.Infof("Sigstore/cosign compatible signature, using key material from PKCS#11 token")
+
+	k, err := openPKCS11Key(uri)
+	if err != nil {
+		return fmt.Errorf("failed to load key material: %w", err)
+	}
+	defer k.Close()
+
+	return cosignsignature.SignOCISIF(ctx, sifPath, k.Signer())
+}
+
+// openPKCS11Key opens the private key held in a PKCS#11 token, identified by
+// uri. If uri does not hold the user PIN of the token, it is prompted for.
+func openPKCS11Key(uri string) (*pkcs11key.Key, error) {
+	u, err := pkcs11key.ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.PIN == "" {
+		if u.PIN, err = interactive.AskQuestionNoEcho("Enter PKCS#11 token PIN: "); err != nil {
+			return nil, fmt.Errorf("couldn't read token PIN: %w", err)
+		}
+	}
+
+	return pkcs11key.Open(u)
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/sylabs/singularity/v4/docs"
 	"github.com/sylabs/singularity/v4/internal/pkg/buildcfg"
 	"github.com/sylabs/singularity/v4/internal/pkg/ociplatform"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
 	"github.com/sylabs/singularity/v4/internal/pkg/plugin"
 	"github.com/sylabs/singularity/v4/internal/pkg/remote"
 	"github.com/sylabs/singularity/v4/internal/pkg/remote/endpoint"
@@ -95,6 +96,9 @@ var (
 	// Keep individual layers when creating / pulling an OCI-SIF?
 	keepLayers bool
 
+	// Filesystem format of layers when creating / pulling an OCI-SIF.
+	layerFormat string
+
 	// Platform for retrieving images
 	arch     string
 	platform string
@@ -252,7 +256,7 @@ var commonPEMFlag = cmdline.Flag{
 	Value:        &encryptionPEMPath,
 	DefaultValue: "",
 	Name:         "pem-path",
-	Usage:        "enter an path to a PEM formatted RSA key for an encrypted container",
+	Usage:        "enter a path to a PEM formatted RSA key, or ASCII armored PGP key, for an encrypted container",
 }
 
 // -F|--force
@@ -307,6 +311,16 @@ var commonKeepLayersFlag = cmdline.Flag{
 	EnvKeys:      []string{"KEEP_LAYERS"},
 }
 
+// --layer-format
+var commonLayerFormatFlag = cmdline.Flag{
+	ID:           "layerFormat",
+	Value:        &layerFormat,
+	DefaultValue: ocisif.SquashfsLayerFormat,
+	Name:         "layer-format",
+	Usage:        "Filesystem format of layers when creating an OCI-SIF - squashfs, squashfs-zstd or erofs.",
+	EnvKeys:      []string{"OCISIF_LAYER_FORMAT"},
+}
+
 // --tmp-sandbox
 var actionTmpSandbox = cmdline.Flag{
 	ID:           "actionTmpSandbox",
@@ -877,3 +891,14 @@ func getOCIPlatform() ggcrv1.Platform {
 	}
 	return *p
 }
+
+// checkLayerFormat ensures that `--layer-format` is valid, and only set when an
+// OCI-SIF is being created.
+func checkLayerFormat(cmd *cobra.Command) {
+	if cmd.Flags().Lookup("layer-format").Changed && !isOCI {
+		sylog.Fatalf("--layer-format is only supported when creating an OCI-SIF with --oci")
+	}
+	if _, err := ocisif.LayerFormatMediaType(layerFormat); err != nil {
+		sylog.Fatalf("%v", err)
+	}
+}
@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2017-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2017-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -9,32 +9,59 @@ package cli
 import (
 	"context"
 	"crypto"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/sigstore/sigstore/pkg/signature"
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/v4/docs"
+	"github.com/sylabs/singularity/v4/internal/pkg/attest"
+	"github.com/sylabs/singularity/v4/internal/pkg/buildcfg"
 	cosignsignature "github.com/sylabs/singularity/v4/internal/pkg/cosign"
 	"github.com/sylabs/singularity/v4/internal/pkg/remote/endpoint"
 	sifsignature "github.com/sylabs/singularity/v4/internal/pkg/signature"
+	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/v4/internal/pkg/verifypolicy"
 	"github.com/sylabs/singularity/v4/pkg/cmdline"
 	"github.com/sylabs/singularity/v4/pkg/image"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
 )
 
 var (
-	sifGroupID                   uint32 // -g groupid specification
-	sifDescID                    uint32 // -i id specification
-	certificatePath              string // --certificate flag
-	certificateIntermediatesPath string // --certificate-intermediates flag
-	certificateRootsPath         string // --certificate-roots flag
-	ocspVerify                   bool   // --ocsp-verify flag
-	pubKeyPath                   string // --key flag
-	localVerify                  bool   // -l flag
-	jsonVerify                   bool   // -j flag
+	sifGroupID                   uint32   // -g groupid specification
+	sifDescID                    uint32   // -i id specification
+	certificatePaths             []string // --certificate flag
+	certificateIntermediatesPath string   // --certificate-intermediates flag
+	certificateRootsPath         string   // --certificate-roots flag
+	ocspVerify                   bool     // --ocsp-verify flag
+	ocspResponsesPath            string   // --ocsp-responses flag
+	crlPaths                     []string // --crl flag
+	crlFetch                     bool     // --crl-fetch flag
+	revocationMaxAge             string   // --revocation-max-age flag
+	pubKeyPaths                  []string // --key flag
+	localVerify                  bool     // -l flag
+	jsonVerify                   bool     // -j flag
 	verifyAll                    bool
 	verifyLegacy                 bool
+	certificateIdentity          string   // --certificate-identity flag
+	certificateOIDCIssuer        string   // --certificate-oidc-issuer flag
+	rekorPubKeyPath              string   // --rekor-public-key flag
+	trustedRootPath              string   // --trusted-root flag
+	attestationVerify            bool     // --attestation flag
+	attestationBuilderIDs        []string // --attestation-builder-id flag
+	attestationSources           []string // --attestation-source flag
+	attestationRequireDigests    bool     // --attestation-require-digests flag
+	policyVerify                 bool     // --policy flag
+	policyFilePath               string   // --policy-file flag
+	policySource                 string   // --policy-source flag
+	verifyKeyFingerprints        []string // --key-fingerprints flag
+	verifyThreshold              int      // --threshold flag
 )
 
 // -u|--url
@@ -91,10 +118,10 @@ var verifySifDescIDFlag = cmdline.Flag{
 // --certificate
 var verifyCertificateFlag = cmdline.Flag{
 	ID:           "certificateFlag",
-	Value:        &certificatePath,
-	DefaultValue: "",
+	Value:        &certificatePaths,
+	DefaultValue: []string{},
 	Name:         "certificate",
-	Usage:        "path to the certificate",
+	Usage:        "path to the certificate (may be specified multiple times)",
 	EnvKeys:      []string{"VERIFY_CERTIFICATE"},
 }
 
@@ -128,13 +155,53 @@ var verifyOCSPFlag = cmdline.Flag{
 	EnvKeys:      []string{"VERIFY_OCSP"},
 }
 
+// --ocsp-responses
+var verifyOCSPResponsesFlag = cmdline.Flag{
+	ID:           "verifyOCSPResponsesFlag",
+	Value:        &ocspResponsesPath,
+	DefaultValue: "",
+	Name:         "ocsp-responses",
+	Usage:        "path to a file or directory of pre-fetched OCSP responses for certificates",
+	EnvKeys:      []string{"VERIFY_OCSP_RESPONSES"},
+}
+
+// --crl
+var verifyCRLFlag = cmdline.Flag{
+	ID:           "verifyCRLFlag",
+	Value:        &crlPaths,
+	DefaultValue: []string{},
+	Name:         "crl",
+	Usage:        "path to a certificate revocation list for certificates (may be specified multiple times)",
+	EnvKeys:      []string{"VERIFY_CRL"},
+}
+
+// --crl-fetch
+var verifyCRLFetchFlag = cmdline.Flag{
+	ID:           "verifyCRLFetchFlag",
+	Value:        &crlFetch,
+	DefaultValue: false,
+	Name:         "crl-fetch",
+	Usage:        "enable revocation check for certificates with CRLs fetched from their distribution points",
+	EnvKeys:      []string{"VERIFY_CRL_FETCH"},
+}
+
+// --revocation-max-age
+var verifyRevocationMaxAgeFlag = cmdline.Flag{
+	ID:           "verifyRevocationMaxAgeFlag",
+	Value:        &revocationMaxAge,
+	DefaultValue: "",
+	Name:         "revocation-max-age",
+	Usage:        "maximum age of OCSP responses and CRLs used for revocation checks (e.g. 72h)",
+	EnvKeys:      []string{"VERIFY_REVOCATION_MAX_AGE"},
+}
+
 // --key
 var verifyPublicKeyFlag = cmdline.Flag{
 	ID:           "publicKeyFlag",
-	Value:        &pubKeyPath,
-	DefaultValue: "",
+	Value:        &pubKeyPaths,
+	DefaultValue: []string{},
 	Name:         "key",
-	Usage:        "path to the public key file",
+	Usage:        "path to the public key file (may be specified multiple times)",
 	EnvKeys:      []string{"VERIFY_KEY"},
 }
 
@@ -188,6 +255,140 @@ var verifyCosignFlag = cmdline.Flag{
 	Usage:        "verify an OCI-SIF with a cosign-compatible sigstore signature",
 }
 
+// --certificate-identity
+var verifyCertificateIdentityFlag = cmdline.Flag{
+	ID:           "verifyCertificateIdentityFlag",
+	Value:        &certificateIdentity,
+	DefaultValue: "",
+	Name:         "certificate-identity",
+	Usage:        "identity (email or URI) required in the certificate of a keyless --cosign signature",
+	EnvKeys:      []string{"VERIFY_CERTIFICATE_IDENTITY"},
+}
+
+// --certificate-oidc-issuer
+var verifyCertificateOIDCIssuerFlag = cmdline.Flag{
+	ID:           "verifyCertificateOIDCIssuerFlag",
+	Value:        &certificateOIDCIssuer,
+	DefaultValue: "",
+	Name:         "certificate-oidc-issuer",
+	Usage:        "OIDC issuer required in the certificate of a keyless --cosign signature",
+	EnvKeys:      []string{"VERIFY_CERTIFICATE_OIDC_ISSUER"},
+}
+
+// --rekor-url
+var verifyRekorURLFlag = cmdline.Flag{
+	ID:           "verifyRekorURLFlag",
+	Value:        &rekorURL,
+	DefaultValue: cosignsignature.DefaultRekorURL,
+	Name:         "rekor-url",
+	Usage:        "URL of the Rekor compatible transparency log for keyless --cosign signatures",
+	EnvKeys:      []string{"REKOR_URL"},
+}
+
+// --rekor-public-key
+var verifyRekorPublicKeyFlag = cmdline.Flag{
+	ID:           "verifyRekorPublicKeyFlag",
+	Value:        &rekorPubKeyPath,
+	DefaultValue: "",
+	Name:         "rekor-public-key",
+	Usage:        "path to the trusted public key of the transparency log for keyless --cosign signatures",
+	EnvKeys:      []string{"REKOR_PUBLIC_KEY"},
+}
+
+// --trusted-root
+var verifyTrustedRootFlag = cmdline.Flag{
+	ID:           "verifyTrustedRootFlag",
+	Value:        &trustedRootPath,
+	DefaultValue: "",
+	Name:         "trusted-root",
+	Usage:        "path to a sigstore trusted_root.json, holding the transparency log keys and CA certificates for keyless --cosign signatures",
+	EnvKeys:      []string{"TRUSTED_ROOT"},
+}
+
+// --attestation
+var verifyAttestationFlag = cmdline.Flag{
+	ID:           "verifyAttestationFlag",
+	Value:        &attestationVerify,
+	DefaultValue: false,
+	Name:         "attestation",
+	Usage:        "verify the signed SLSA provenance attestation of the image, instead of its signatures",
+}
+
+// --attestation-builder-id
+var verifyAttestationBuilderIDFlag = cmdline.Flag{
+	ID:           "verifyAttestationBuilderIDFlag",
+	Value:        &attestationBuilderIDs,
+	DefaultValue: []string{},
+	Name:         "attestation-builder-id",
+	Usage:        "require the image to be built by a builder with the specified ID (may be specified multiple times)",
+}
+
+// --attestation-source
+var verifyAttestationSourceFlag = cmdline.Flag{
+	ID:           "verifyAttestationSourceFlag",
+	Value:        &attestationSources,
+	DefaultValue: []string{},
+	Name:         "attestation-source",
+	Usage:        "require the image to record bootstrap sources, each starting with the specified URI prefix (may be specified multiple times)",
+}
+
+// --attestation-require-digests
+var verifyAttestationRequireDigestsFlag = cmdline.Flag{
+	ID:           "verifyAttestationRequireDigestsFlag",
+	Value:        &attestationRequireDigests,
+	DefaultValue: false,
+	Name:         "attestation-require-digests",
+	Usage:        "require the definition, and each bootstrap source, of the image to be recorded with a digest",
+}
+
+// --policy
+var verifyPolicyFlag = cmdline.Flag{
+	ID:           "verifyPolicyFlag",
+	Value:        &policyVerify,
+	DefaultValue: false,
+	Name:         "policy",
+	Usage:        "verify the image against the user, or else the system-wide, verification policy",
+}
+
+// --policy-file
+var verifyPolicyFileFlag = cmdline.Flag{
+	ID:           "verifyPolicyFileFlag",
+	Value:        &policyFilePath,
+	DefaultValue: "",
+	Name:         "policy-file",
+	Usage:        "verify the image against the verification policy in the specified file (implies --policy)",
+	EnvKeys:      []string{"VERIFY_POLICY_FILE"},
+}
+
+// --policy-source
+var verifyPolicySourceFlag = cmdline.Flag{
+	ID:           "verifyPolicySourceFlag",
+	Value:        &policySource,
+	DefaultValue: "",
+	Name:         "policy-source",
+	Usage:        "URI the image was retrieved from, used to select a registry scope of the verification policy",
+}
+
+// --key-fingerprints
+var verifyKeyFingerprintsFlag = cmdline.Flag{
+	ID:           "verifyKeyFingerprintsFlag",
+	Value:        &verifyKeyFingerprints,
+	DefaultValue: []string{},
+	Name:         "key-fingerprints",
+	Usage:        "require the image to be signed by the entities with the specified PGP or key fingerprints",
+	EnvKeys:      []string{"VERIFY_KEY_FINGERPRINTS"},
+}
+
+// --threshold
+var verifyThresholdFlag = cmdline.Flag{
+	ID:           "verifyThresholdFlag",
+	Value:        &verifyThreshold,
+	DefaultValue: 0,
+	Name:         "threshold",
+	Usage:        "number of the --key-fingerprints entities that must sign the image (default: all)",
+	EnvKeys:      []string{"VERIFY_THRESHOLD"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(VerifyCmd)
@@ -201,12 +402,30 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&verifyCertificateIntermediatesFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyCertificateRootsFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyOCSPFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyOCSPResponsesFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyCRLFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyCRLFetchFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyRevocationMaxAgeFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyPublicKeyFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyLocalFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyJSONFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyAllFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyLegacyFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyCosignFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyCertificateIdentityFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyCertificateOIDCIssuerFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyRekorURLFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyRekorPublicKeyFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyTrustedRootFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyAttestationFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyAttestationBuilderIDFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyAttestationSourceFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyAttestationRequireDigestsFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyPolicyFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyPolicyFileFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyPolicySourceFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyKeyFingerprintsFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyThresholdFlag, VerifyCmd)
 	})
 }
 
@@ -226,12 +445,108 @@ var VerifyCmd = &cobra.Command{
 	Example: docs.VerifyExample,
 }
 
+// revocationCheck returns true if a revocation check of certificates was
+// requested.
+func revocationCheck() bool {
+	return ocspVerify || ocspResponsesPath != "" || len(crlPaths) > 0 || crlFetch
+}
+
 func doVerifyCmd(cmd *cobra.Command, cpath string) {
+	if revocationMaxAge != "" && !revocationCheck() {
+		sylog.Fatalf("--revocation-max-age requires --ocsp-verify, --ocsp-responses, --crl or --crl-fetch")
+	}
+	if revocationMaxAge != "" {
+		if d, err := time.ParseDuration(revocationMaxAge); err != nil || d < 0 {
+			sylog.Fatalf("--revocation-max-age must be a duration that is not negative, e.g. 72h")
+		}
+	}
+
+	if verifyThreshold != 0 && len(verifyKeyFingerprints) == 0 {
+		sylog.Fatalf("--threshold requires --key-fingerprints")
+	}
+	if len(verifyKeyFingerprints) > 0 && (policyVerify || policyFilePath != "" || attestationVerify) {
+		sylog.Fatalf("--key-fingerprints / --threshold not supported with --policy or --attestation")
+	}
+
+	if policyVerify || policyFilePath != "" {
+		if attestationVerify || useCosign || certificateIdentity != "" || certificateOIDCIssuer != "" {
+			sylog.Fatalf("--attestation / --cosign / keyless options not supported: --policy verifies signatures as described by the policy")
+		}
+		if len(pubKeyPaths) > 0 || len(certificatePaths) > 0 || certificateIntermediatesPath != "" || certificateRootsPath != "" || revocationCheck() {
+			sylog.Fatalf("--key / certificate options not supported: --policy uses the key material of the policy")
+		}
+		if signAll || sifGroupID != 0 || sifDescID != 0 || verifyAll || verifyLegacy {
+			sylog.Fatalf("--policy applies to the image, specifying SIF descriptors / groups / legacy signatures is not supported")
+		}
+		if err := verifyImagePolicy(cmd.Context(), cpath); err != nil {
+			sylog.Fatalf("%v", err)
+		}
+		return
+	}
+	if policySource != "" {
+		sylog.Fatalf("--policy-source requires --policy")
+	}
+
+	if attestationVerify {
+		if useCosign || certificateIdentity != "" || certificateOIDCIssuer != "" {
+			sylog.Fatalf("--cosign / keyless options not supported: attestations of SIF and OCI-SIF images are verified with --key, --certificate or PGP keys")
+		}
+		if signAll || sifGroupID != 0 || sifDescID != 0 || verifyAll || verifyLegacy {
+			sylog.Fatalf("--attestation applies to the image, specifying SIF descriptors / groups is not supported")
+		}
+		if len(pubKeyPaths)+len(certificatePaths) > 1 {
+			sylog.Fatalf("--attestation is verified with a single --key or --certificate")
+		}
+		if err := verifyImageAttestation(cmd, cpath); err != nil {
+			sylog.Fatalf("%v", err)
+		}
+		return
+	}
+	if len(attestationBuilderIDs) > 0 || len(attestationSources) > 0 || attestationRequireDigests {
+		sylog.Fatalf("--attestation-builder-id / --attestation-source / --attestation-require-digests require --attestation")
+	}
+
+	keylessVerify := certificateIdentity != "" || certificateOIDCIssuer != ""
+	if keylessVerify && !useCosign {
+		sylog.Fatalf("--certificate-identity / --certificate-oidc-issuer require --cosign")
+	}
+
+	if useCosign && keylessVerify {
+		if certificateIdentity == "" || certificateOIDCIssuer == "" {
+			sylog.Fatalf("keyless --cosign verification requires both --certificate-identity and --certificate-oidc-issuer")
+		}
+		if len(pubKeyPaths) > 0 || len(certificatePaths) > 0 || revocationCheck() {
+			sylog.Fatalf("--key / --certificate / revocation options not supported: keyless --cosign verification uses the certificate in the signature")
+		}
+		if len(verifyKeyFingerprints) > 0 {
+			sylog.Fatalf("--key-fingerprints / --threshold not supported: keyless --cosign verification checks a single identity")
+		}
+		if certificateRootsPath == "" && trustedRootPath == "" {
+			sylog.Fatalf("keyless --cosign verification requires the CA --certificate-roots, or a --trusted-root")
+		}
+		if rekorPubKeyPath == "" && trustedRootPath == "" {
+			sylog.Fatalf("keyless --cosign verification requires the transparency log --rekor-public-key, or a --trusted-root")
+		}
+		if localVerify || keyServerURI != "" {
+			sylog.Fatalf("--local / key server not supported: not applicable to keyless --cosign verification")
+		}
+		if signAll || sifGroupID != 0 || sifDescID != 0 {
+			sylog.Fatalf("--cosign signatures apply to an OCI image, specifying SIF descriptors / groups is not supported")
+		}
+		if verifyLegacy {
+			sylog.Fatalf("--legacy-insecure not supported: not applicable to --cosign verification")
+		}
+		if err := verifyCosignKeyless(cmd.Context(), cpath); err != nil {
+			sylog.Fatalf("%v", err)
+		}
+		return
+	}
+
 	if useCosign {
-		if pubKeyPath == "" {
+		if len(pubKeyPaths) == 0 {
 			sylog.Fatalf("--cosign verification requires a public --key to be specified")
 		}
-		if certificatePath != "" || certificateIntermediatesPath != "" || certificateRootsPath != "" || ocspVerify {
+		if len(certificatePaths) > 0 || certificateIntermediatesPath != "" || certificateRootsPath != "" || revocationCheck() {
 			sylog.Fatalf("certificate not supported: --cosign verification uses a public --key")
 		}
 		if localVerify {
@@ -246,7 +561,12 @@ func doVerifyCmd(cmd *cobra.Command, cpath string) {
 		if verifyLegacy {
 			sylog.Fatalf("--legacy-insecure not supported: not applicable to --cosign verification")
 		}
-		err := verifyCosign(cmd.Context(), cpath, pubKeyPath)
+		var err error
+		if len(verifyKeyFingerprints) > 0 || len(pubKeyPaths) > 1 {
+			err = verifyCosignThreshold(cmd.Context(), cpath)
+		} else {
+			err = verifyCosign(cmd.Context(), cpath, pubKeyPaths[0])
+		}
 		if err != nil {
 			sylog.Fatalf("%v", err)
 		}
@@ -267,16 +587,17 @@ func verifySIF(cmd *cobra.Command, cpath string) error {
 		sylog.Infof("Image is an OCI-SIF, use `--cosign` to verify cosign compatible signatures.")
 	}
 
-	switch {
-	case cmd.Flag(verifyCertificateFlag.Name).Changed:
-		sylog.Infof("Verifying image with key material from certificate '%v'", certificatePath)
+	for _, path := range certificatePaths {
+		sylog.Infof("Verifying image with key material from certificate '%v'", path)
 
-		c, err := loadCertificate(certificatePath)
+		c, err := loadCertificate(path)
 		if err != nil {
 			return fmt.Errorf("failed to load certificate: %w", err)
 		}
 		opts = append(opts, sifsignature.OptVerifyWithCertificate(c))
+	}
 
+	if len(certificatePaths) > 0 {
 		if cmd.Flag(verifyCertificateIntermediatesFlag.Name).Changed {
 			p, err := loadCertificatePool(certificateIntermediatesPath)
 			if err != nil {
@@ -297,28 +618,32 @@ func verifySIF(cmd *cobra.Command, cpath string) error {
 			opts = append(opts, sifsignature.OptVerifyWithOCSP())
 		}
 
-	case cmd.Flag(verifyPublicKeyFlag.Name).Changed:
-		sylog.Infof("Verifying image with key material from '%v'", pubKeyPath)
+		ro, err := getRevocationOpts(cpath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, ro...)
+	}
+
+	for _, path := range pubKeyPaths {
+		sylog.Infof("Verifying image with key material from '%v'", path)
 
-		v, err := signature.LoadVerifierFromPEMFile(pubKeyPath, crypto.SHA256)
+		v, err := signature.LoadVerifierFromPEMFile(path, crypto.SHA256)
 		if err != nil {
 			return fmt.Errorf("failed to load key material: %w", err)
 		}
 		opts = append(opts, sifsignature.OptVerifyWithVerifier(v))
+	}
 
-	default:
+	// Signers identified by PGP fingerprint are counted alongside the certificates or keys.
+	if len(opts) == 0 || len(verifyKeyFingerprints) > 0 {
 		sylog.Infof("Verifying image with PGP key material")
 
-		// Set keyserver option, if applicable.
-		if localVerify {
-			opts = append(opts, sifsignature.OptVerifyWithPGP())
-		} else {
-			co, err := getKeyserverClientOpts(keyServerURI, endpoint.KeyserverVerifyOp)
-			if err != nil {
-				return fmt.Errorf("error while getting keyserver client config: %w", err)
-			}
-			opts = append(opts, sifsignature.OptVerifyWithPGP(co...))
+		o, err := getPGPVerifyOpt()
+		if err != nil {
+			return err
 		}
+		opts = append(opts, o)
 	}
 
 	// Set group option, if applicable.
@@ -341,6 +666,10 @@ func verifySIF(cmd *cobra.Command, cpath string) error {
 		opts = append(opts, sifsignature.OptVerifyLegacy())
 	}
 
+	if len(verifyKeyFingerprints) > 0 {
+		return verifyImageThreshold(cmd.Context(), cpath, verifyKeyFingerprints, opts)
+	}
+
 	// Set callback option.
 	if jsonVerify {
 		var kl keyList
@@ -369,6 +698,110 @@ func verifySIF(cmd *cobra.Command, cpath string) error {
 	return nil
 }
 
+// getPGPVerifyOpt returns the option that configures verification with PGP key material, from
+// the local keyring, or else the key server.
+func getPGPVerifyOpt() (sifsignature.VerifyOpt, error) {
+	if localVerify {
+		return sifsignature.OptVerifyWithPGP(), nil
+	}
+
+	co, err := getKeyserverClientOpts(keyServerURI, endpoint.KeyserverVerifyOp)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting keyserver client config: %w", err)
+	}
+	return sifsignature.OptVerifyWithPGP(co...), nil
+}
+
+// verifyImageThreshold verifies the image at cpath, according to opts, and checks that it was
+// signed by at least --threshold of the entities identified by fingerprints.
+func verifyImageThreshold(ctx context.Context, cpath string, fingerprints []string, opts []sifsignature.VerifyOpt) error {
+	if jsonVerify {
+		var kl keyList
+
+		opts = append(opts, sifsignature.OptVerifyCallback(getJSONCallback(&kl)))
+
+		res, verifyErr := sifsignature.VerifyThreshold(ctx, cpath, fingerprints, verifyThreshold, opts...)
+		kl.Threshold = newThresholdReport(res)
+
+		// Always output JSON.
+		if err := outputJSON(os.Stdout, kl); err != nil {
+			return fmt.Errorf("failed to output JSON: %v", err)
+		}
+
+		if verifyErr != nil {
+			return fmt.Errorf("failed to verify container: %v", verifyErr)
+		}
+		return nil
+	}
+
+	opts = append(opts, sifsignature.OptVerifyCallback(outputVerify))
+
+	res, err := sifsignature.VerifyThreshold(ctx, cpath, fingerprints, verifyThreshold, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to verify container: %v", err)
+	}
+
+	for _, fp := range res.Signers {
+		fmt.Printf("Signed by required entity: %s\n", strings.ToUpper(fp))
+	}
+	sylog.Infof("Verified signature(s) from image '%v', by %d of the required entities (%d required)", cpath, len(res.Signers), res.Threshold)
+	return nil
+}
+
+// stapledOCSPSuffix is appended to the path of an image to form the path of a
+// file holding OCSP responses stapled to the image.
+const stapledOCSPSuffix = ".ocsp"
+
+// getRevocationOpts returns the options that configure the offline revocation
+// checks of certificates used to verify the image at cpath. If any revocation
+// check is requested, OCSP responses stapled to the image are used.
+func getRevocationOpts(cpath string) ([]sifsignature.VerifyOpt, error) {
+	if !revocationCheck() {
+		return nil, nil
+	}
+
+	var opts []sifsignature.VerifyOpt
+
+	if ocspResponsesPath != "" {
+		rs, err := sifsignature.LoadOCSPResponses(ocspResponsesPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load OCSP responses: %w", err)
+		}
+		opts = append(opts, sifsignature.OptVerifyWithOCSPResponses(rs...))
+	}
+
+	if stapled := cpath + stapledOCSPSuffix; fs.IsFile(stapled) {
+		sylog.Infof("Using OCSP responses stapled to image from '%v'", stapled)
+		rs, err := sifsignature.LoadOCSPResponses(stapled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load OCSP responses: %w", err)
+		}
+		opts = append(opts, sifsignature.OptVerifyWithOCSPResponses(rs...))
+	}
+
+	for _, path := range crlPaths {
+		crls, err := sifsignature.LoadCRLs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CRL: %w", err)
+		}
+		opts = append(opts, sifsignature.OptVerifyWithCRLs(crls...))
+	}
+
+	if crlFetch {
+		opts = append(opts, sifsignature.OptVerifyWithCRLFetch())
+	}
+
+	if revocationMaxAge != "" {
+		d, err := time.ParseDuration(revocationMaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --revocation-max-age: %w", err)
+		}
+		opts = append(opts, sifsignature.OptVerifyWithRevocationMaxAge(d))
+	}
+
+	return opts, nil
+}
+
 func verifyCosign(ctx context.Context, sifPath, keyPath string) error {
 	sylog.Infof("Verifying image with sigstore/cosign signature, using key material from '%v'", keyPath)
 
@@ -384,3 +817,203 @@ func verifyCosign(ctx context.Context, sifPath, keyPath string) error {
 	fmt.Println(string(payloads))
 	return nil
 }
+
+// verifyCosignThreshold verifies the cosign signatures of the OCI-SIF image at cpath with each
+// --key, and checks that it was signed by at least --threshold of the --key-fingerprints
+// entities, or by each --key if no fingerprints are specified.
+func verifyCosignThreshold(ctx context.Context, cpath string) error {
+	fps := verifyKeyFingerprints
+
+	opts := make([]sifsignature.VerifyOpt, 0, len(pubKeyPaths))
+	for _, path := range pubKeyPaths {
+		sylog.Infof("Verifying image with sigstore/cosign signature, using key material from '%v'", path)
+
+		v, err := signature.LoadVerifierFromPEMFile(path, crypto.SHA256)
+		if err != nil {
+			return fmt.Errorf("failed to load key material: %w", err)
+		}
+		opts = append(opts, sifsignature.OptVerifyWithVerifier(v))
+
+		if len(verifyKeyFingerprints) == 0 {
+			pub, err := v.PublicKey()
+			if err != nil {
+				return fmt.Errorf("failed to load key material: %w", err)
+			}
+			fp, err := sifsignature.KeyFingerprint(pub)
+			if err != nil {
+				return fmt.Errorf("failed to load key material: %w", err)
+			}
+			fps = append(fps, fp)
+		}
+	}
+
+	return verifyImageThreshold(ctx, cpath, fps, opts)
+}
+
+func verifyCosignKeyless(ctx context.Context, sifPath string) error {
+	sylog.Infof("Verifying image with keyless sigstore/cosign signature, for identity '%v' from issuer '%v'", certificateIdentity, certificateOIDCIssuer)
+
+	opts := cosignsignature.KeylessVerifyOptions{
+		Identity: certificateIdentity,
+		Issuer:   certificateOIDCIssuer,
+		RekorURL: rekorURL,
+	}
+
+	var tr *cosignsignature.TrustedRoot
+	if trustedRootPath != "" {
+		var err error
+		if tr, err = cosignsignature.LoadTrustedRoot(trustedRootPath); err != nil {
+			return fmt.Errorf("failed to load trusted root: %w", err)
+		}
+	}
+
+	if certificateRootsPath != "" {
+		p, err := loadCertificatePool(certificateRootsPath)
+		if err != nil {
+			return fmt.Errorf("failed to load root certificates: %w", err)
+		}
+		opts.Roots = p
+	} else {
+		roots, intermediates, err := tr.CertificatePools()
+		if err != nil {
+			return fmt.Errorf("failed to load trusted root: %w", err)
+		}
+		opts.Roots, opts.Intermediates = roots, intermediates
+	}
+
+	if certificateIntermediatesPath != "" {
+		p, err := loadCertificatePool(certificateIntermediatesPath)
+		if err != nil {
+			return fmt.Errorf("failed to load intermediate certificates: %w", err)
+		}
+		opts.Intermediates = p
+	}
+
+	if rekorPubKeyPath != "" {
+		b, err := os.ReadFile(rekorPubKeyPath)
+		if err != nil {
+			return fmt.Errorf("failed to load transparency log public key: %w", err)
+		}
+		if opts.RekorPublicKey, err = cryptoutils.UnmarshalPEMToPublicKey(b); err != nil {
+			return fmt.Errorf("failed to load transparency log public key: %w", err)
+		}
+	} else {
+		pub, err := tr.RekorPublicKey(rekorURL)
+		if err != nil {
+			return fmt.Errorf("failed to load trusted root: %w", err)
+		}
+		opts.RekorPublicKey = pub
+	}
+
+	payloads, err := cosignsignature.VerifyOCISIFKeyless(ctx, sifPath, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(payloads))
+	return nil
+}
+
+func verifyImageAttestation(cmd *cobra.Command, cpath string) error {
+	var v attest.Verifier
+
+	switch {
+	case len(certificatePaths) > 0:
+		sylog.Infof("Verifying provenance attestation with key material from certificate '%v'", certificatePaths[0])
+
+		c, err := loadCertificate(certificatePaths[0])
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+
+		var intermediates, roots *x509.CertPool
+		if cmd.Flag(verifyCertificateIntermediatesFlag.Name).Changed {
+			if intermediates, err = loadCertificatePool(certificateIntermediatesPath); err != nil {
+				return fmt.Errorf("failed to load intermediate certificates: %w", err)
+			}
+		}
+		if cmd.Flag(verifyCertificateRootsFlag.Name).Changed {
+			if roots, err = loadCertificatePool(certificateRootsPath); err != nil {
+				return fmt.Errorf("failed to load root certificates: %w", err)
+			}
+		}
+		if v, err = attest.NewCertificateVerifier(c, intermediates, roots); err != nil {
+			return fmt.Errorf("failed to load key material: %w", err)
+		}
+
+	case len(pubKeyPaths) > 0:
+		sylog.Infof("Verifying provenance attestation with key material from '%v'", pubKeyPaths[0])
+
+		sv, err := signature.LoadVerifierFromPEMFile(pubKeyPaths[0], crypto.SHA256)
+		if err != nil {
+			return fmt.Errorf("failed to load key material: %w", err)
+		}
+		v = attest.NewVerifier(sv)
+
+	default:
+		sylog.Infof("Verifying provenance attestation with PGP key material from the local keyring")
+
+		kr, err := sypgp.NewHandle("").LoadPubKeyring()
+		if err != nil {
+			return fmt.Errorf("failed to load keyring: %w", err)
+		}
+		v = attest.NewPGPVerifier(kr)
+	}
+
+	p := attest.Policy{
+		BuilderIDs:     attestationBuilderIDs,
+		Sources:        attestationSources,
+		RequireDigests: attestationRequireDigests,
+	}
+	sts, err := verifyAttestation(cmd.Context(), cpath, p, v)
+	if err != nil {
+		return fmt.Errorf("failed to verify provenance attestation: %w", err)
+	}
+
+	b, err := json.Marshal(sts)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func verifyImagePolicy(ctx context.Context, cpath string) error {
+	path := policyFilePath
+	if path == "" {
+		path = buildcfg.VERIFY_POLICY_FILE
+		if _, err := os.Stat(verifypolicy.UserPath()); err == nil {
+			path = verifypolicy.UserPath()
+		}
+	}
+	sylog.Infof("Verifying image against verification policy '%v'", path)
+
+	p, err := verifypolicy.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load verification policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("failed to validate verification policy: %w", err)
+	}
+
+	// PGP keys are looked up in the global keyring, then in the user keyring.
+	global := sypgp.NewHandle(buildcfg.SINGULARITY_CONFDIR, sypgp.GlobalHandleOpt())
+	gkr, err := global.LoadPubKeyring()
+	if err != nil {
+		return fmt.Errorf("failed to load keyring: %w", err)
+	}
+	ukr, err := sypgp.PublicKeyRing()
+	if err != nil {
+		return fmt.Errorf("failed to load keyring: %w", err)
+	}
+
+	s, err := p.Check(ctx, cpath, policySource, sypgp.NewMultiKeyRing(gkr, ukr))
+	if err != nil {
+		if s != nil {
+			return fmt.Errorf("image does not satisfy verification policy scope '%v': %w", s.Name, err)
+		}
+		return fmt.Errorf("failed to verify container: %w", err)
+	}
+
+	sylog.Infof("Image '%v' satisfies verification policy scope '%v'", cpath, s.Name)
+	return nil
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2017-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2017-2026, Sylabs Inc. All rights reserved.
 // Copyright (c) Contributors to the Apptainer project, established as
 //   Apptainer a Series of LF Projects LLC.
 // This software is licensed under a 3-clause BSD license. Please consult the
@@ -66,7 +66,17 @@ Enterprise Performance Computing (EPC)`
       oras://     an OCI registry that holds SIF files using ORAS
 
   When run with the --oci flag, the spec must be a valid Dockerfile, and output
-  is always an OCI-SIF image.`
+  is always an OCI-SIF image.
+
+  PROVENANCE:
+
+  With --attest, a SLSA provenance statement is added to the built SIF or
+  OCI-SIF image, as a signed in-toto attestation. It records the digest of the
+  definition file, the bootstrap source and its digest, where resolved, the
+  build arguments, the builder ID, and the digest of the image. The
+  attestation is signed with the private key, or cosign private key, specified
+  by --attest-key, or else a PGP key. It can be checked with
+  'singularity verify --attestation'.`
 
 	BuildExample string = `
 
@@ -166,7 +176,24 @@ Enterprise Performance Computing (EPC)`
           $ singularity build /tmp/debian2.sif /tmp/debian
 
       Build an OCI-SIF image from a Dockerfile:
-          $ singularity build --oci /tmp/myimage.oci.sif /path/to/Dockerfile`
+          $ singularity build --oci /tmp/myimage.oci.sif /path/to/Dockerfile
+
+      Build OCI-SIF images for two platforms, and push them as an image index:
+          $ singularity build --oci --platform linux/amd64,linux/arm64 /tmp/myimage.oci.sif /path/to/Dockerfile
+          $ singularity push --platform-images /tmp/myimage.oci.sif docker://registry.example.com/myimage:latest
+
+      Build a single OCI-SIF holding images for two platforms:
+          $ singularity build --oci --platform linux/amd64,linux/arm64 --single-file /tmp/myimage.oci.sif /path/to/Dockerfile
+
+      Build a sif image with a provenance attestation, signed with a private key:
+          $ singularity build --attest --attest-key private.pem /tmp/debian0.sif /path/to/debian.def
+
+      Build an encrypted sif image that two users can decrypt, with an RSA and a PGP public key:
+          $ sudo singularity build --recipient alice.pem --recipient bob.asc /tmp/debian3.sif /path/to/debian.def
+
+      Build an encrypted OCI-SIF image, without root, then run it with the private key:
+          $ singularity build --oci --encrypt --pem-path public.pem /tmp/myimage.oci.sif docker://alpine
+          $ singularity run --oci --pem-path private.pem /tmp/myimage.oci.sif`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Cache
@@ -192,7 +219,11 @@ Enterprise Performance Computing (EPC)`
   SINGULARITY_CACHEDIR is not set). By default the entire cache is cleaned, use
   --days and --type flags to override this behavior. Note: if you use Singularity
   as root, cache will be stored in '/root/.singularity/.cache', to clean that
-  cache, you will need to run 'cache clean' as root, or with 'sudo'.`
+  cache, you will need to run 'cache clean' as root, or with 'sudo'.
+
+  With --days, objects of the 'cas' content store are removed if they have not
+  been shared with a new file for that many days. Files that already share
+  their content keep it.`
 	CacheCleanExample string = `
   All group commands have their own help output:
 
@@ -207,7 +238,14 @@ Enterprise Performance Computing (EPC)`
 	CacheListShort string = `List your local Singularity cache`
 	CacheListLong  string = `
   This will list your local cache (stored at $HOME/.singularity/cache if
-  SINGULARITY_CACHEDIR is not set).`
+  SINGULARITY_CACHEDIR is not set).
+
+  If SINGULARITY_CACHE_DEDUPE is set, files in sandboxes built with
+  'singularity build --sandbox' are deduplicated through a content store in
+  the 'cas' cache. Identical files are reflinked to a single copy where the
+  filesystem supports it (XFS, btrfs). Only filesystems that support reflinks
+  are deduplicated, as sandboxes may be modified in place. The space saved by
+  deduplication is included in the listing.`
 	CacheListExample string = `
   All group commands have their own help output:
 
@@ -346,6 +384,63 @@ Enterprise Performance Computing (EPC)`
 	KeyRemoveExample string = `
   $ singularity key remove D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934`
 
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// key edit
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	KeyEditUse   string = `edit --expire <expiry> <fingerprint>`
+	KeyEditShort string = `Change the expiry of a private key in your local keyring`
+	KeyEditLong  string = `
+  The 'key edit' command changes the expiry of a private key in your local
+  keyring, and of the corresponding public key. Subkeys are set to expire with
+  the primary key. The expiry may be given as a date (2027-06-30), as a
+  duration from now in days (90d), weeks (12w) or years (2y), or as 'never'.
+  Use 'key push' to publish the updated key.`
+	KeyEditExample string = `
+  $ singularity key edit --expire 1y D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934
+  $ singularity key edit --expire never D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// key addsubkey
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	KeyAddSubkeyUse   string = `addsubkey [addsubkey options...] <fingerprint>`
+	KeyAddSubkeyShort string = `Add a signing subkey to a private key in your local keyring`
+	KeyAddSubkeyLong  string = `
+  The 'key addsubkey' command generates a new signing subkey for a private key
+  in your local keyring. The newest valid signing subkey is used by 'sign', so
+  adding a subkey, then revoking the previous one with 'key revoke --subkey',
+  rotates the signing key while keeping the fingerprint of the key that
+  signatures are verified against. Use 'key push' to publish the updated key.`
+	KeyAddSubkeyExample string = `
+  $ singularity key addsubkey D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934
+  $ singularity key addsubkey --expire 1y D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// key revoke
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	KeyRevokeUse   string = `revoke [revoke options...] <fingerprint>`
+	KeyRevokeShort string = `Revoke a private key, or one of its subkeys, in your local keyring`
+	KeyRevokeLong  string = `
+  The 'key revoke' command adds a revocation signature to a private key in your
+  local keyring, and to the corresponding public key. With --subkey, only the
+  subkey with the given key ID or fingerprint is revoked. With --push, the
+  revoked public key is uploaded to the key server.
+
+  With --output, the key is not revoked. Instead, a revocation certificate is
+  written to the given file, to be stored safely. If the private key is later
+  lost or compromised, 'singularity key import' of the certificate revokes the
+  key in the keyring, which can then be pushed to the key server.
+
+  The --reason flag controls how existing signatures are treated by 'verify':
+    superseded, retired: signatures created before the revocation remain valid,
+                         and are reported as 'revoked-after-signing'.
+    compromised, unspecified (default): all signatures made with the key are
+                         rejected.`
+	KeyRevokeExample string = `
+  $ singularity key revoke --reason superseded --push D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934
+  $ singularity key revoke --reason compromised --message "laptop stolen" D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934
+  $ singularity key revoke --subkey 0F2F6E2A5D1B9C04 --reason retired D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934
+  $ singularity key revoke --reason compromised --output revoke.asc D87FE3AF5C1F063FCBCC9B02F812842B5EEE5934`
+
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// key new-cosign-pair
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -723,7 +818,10 @@ Enterprise Performance Computing (EPC)`
   $ singularity push /home/user/my.sif library://user/collection/my.sif:latest
 
   To supported OCI registry
-  $ singularity push /home/user/my.sif oras://registry/namespace/image:tag`
+  $ singularity push /home/user/my.sif oras://registry/namespace/image:tag
+
+  The per-platform images of a multi-platform build, as an OCI image index
+  $ singularity push --platform-images /home/user/my.oci.sif docker://registry/namespace/image:tag`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// search
@@ -811,19 +909,42 @@ Enterprise Performance Computing (EPC)`
 
   Key material can be provided via PEM-encoded file, or an entity in the PGP
   keyring. To manage the PGP keyring, see 'singularity help key'.
+
+  A private key held in an HSM or security token can be used through its
+  PKCS#11 module, by specifying a PKCS#11 URI with --pkcs11-uri. The URI must
+  identify the token and key, and include the module-path of the module. The
+  token PIN is prompted for, unless the URI has a pin-value or pin-source.
   
   --cosign mode supports signing an OCI image within an OCI-SIF file with a
   cosign-compatible signature. A private key must be provided with the --key
-  flag.`
+  or --pkcs11-uri flag, unless --keyless is specified.
+
+  --keyless signing uses an ephemeral key, certified by a Fulcio compatible
+  certificate authority in exchange for an OIDC --identity-token. The signature
+  is recorded in a Rekor compatible transparency log. Sites running their own
+  sigstore services can set --fulcio-url and --rekor-url.`
 	SignExample string = `
   Sign with a private key:
   $ singularity sign --key private.pem container.sif
 
   Sign with PGP:
   $ singularity sign container.sif
+
+  Sign with a private key held in a PKCS#11 token:
+  $ singularity sign --pkcs11-uri \
+      "pkcs11:token=signing;object=key?module-path=/usr/lib/softhsm/libsofthsm2.so" \
+      container.sif
   
   Sign an image within an OCI-SIF with a cosign compatible signature:
-  $ singularity sign --cosign --key cosign.key container.oci.sif`
+  $ singularity sign --cosign --key cosign.key container.oci.sif
+
+  Sign an image within an OCI-SIF with a key held in a PKCS#11 token:
+  $ singularity sign --cosign --pkcs11-uri \
+      "pkcs11:token=signing;object=key?module-path=/usr/lib/softhsm/libsofthsm2.so" \
+      container.oci.sif
+
+  Sign an image within an OCI-SIF with a keyless cosign signature:
+  $ singularity sign --cosign --keyless --identity-token "$TOKEN" container.oci.sif`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// verify
@@ -836,20 +957,105 @@ Enterprise Performance Computing (EPC)`
 
   Key material can be provided via PEM-encoded file, or via the PGP keyring. To
   manage the PGP keyring, see 'singularity help key'.
+
+  Signatures made with a PGP key that was later retired, superseded or expired
+  remain valid, with a warning, and the key status is reported as
+  'revoked-after-signing' or 'expired-after-signing', including in the
+  KeyStatus field of --json output. Signatures made with a key revoked as
+  compromised, or without a reason, are rejected. A revoked user ID does not
+  revoke the key.
+
+  --key-fingerprints requires the image to be signed by the listed entities.
+  PGP keys are identified by their fingerprint, and the key of a --certificate
+  or --key by the hex encoded SHA-256 digest of its DER encoded public key.
+  With --threshold, only that number of the listed entities must sign the
+  image, e.g. 2 of 3 maintainers. --certificate and --key may be specified
+  multiple times, and signers identified by PGP fingerprint are counted
+  alongside them. Signatures by other entities are ignored. The --json output
+  lists the entities that signed each object. With --cosign, the cosign
+  signatures of an OCI-SIF image are counted, and multiple --key options
+  without --key-fingerprints require a signature by each key.
+
+  Certificates can be subject to revocation checks. --ocsp-verify queries OCSP
+  responders online. Checks can also be performed offline, with pre-fetched
+  OCSP responses in the file or directory given by --ocsp-responses, and with
+  CRLs given by --crl. OCSP responses stapled to an image, in a file named
+  after the image with an added '.ocsp' extension, are used whenever a
+  revocation check is requested. CRLs are fetched from the distribution points
+  of certificates with --crl-fetch. --revocation-max-age sets the maximum age
+  of the responses and CRLs used. Responses and CRLs that are out of date, or
+  older than the maximum age, are skipped in favour of other sources.
   
   
   --cosign mode supports verifying an OCI image within an OCI-SIF file that has
   a cosign-compatible signature. A public key must be provided with the --key
-  flag.`
+  flag.
+
+  Keyless cosign signatures are verified by specifying the required
+  --certificate-identity and --certificate-oidc-issuer, in place of --key. The
+  signing certificate must chain to the CA --certificate-roots, and the
+  signature must be included in the transparency log at --rekor-url, signed
+  with the trusted --rekor-public-key. Alternatively, the CA certificates and
+  log keys can be read from a sigstore trusted_root.json with --trusted-root.
+  The log public key is never retrieved from the log itself.
+
+  --attestation mode verifies the SLSA provenance attestation added to a SIF or
+  OCI-SIF image by 'singularity build --attest', instead of its signatures.
+  The attestation must be signed by the --key, --certificate, or a PGP key in
+  the local keyring, and must describe the image. It may also be required to
+  record a trusted --attestation-builder-id, one or more bootstrap sources, all
+  starting with an --attestation-source prefix that ends at a '/', ':' or '@',
+  or the end of the source URI, and, with
+  --attestation-require-digests, the digests of the definition file and
+  bootstrap sources. The verified
+  statements are output as JSON.
+
+  --policy mode verifies a SIF or OCI-SIF image against a verification policy
+  file, which lists the trusted PGP fingerprints, x509 certificates and cosign
+  keys for images in given paths, or retrieved from given registries. The
+  policy in ~/.singularity/verify-policy.toml is used if present, otherwise the
+  system-wide verify-policy.toml. A policy file may be specified with
+  --policy-file, and the URI that the image was retrieved from with
+  --policy-source. The system-wide policy is checked before running any
+  container when 'enforce verify policy' is set in singularity.conf.`
 	VerifyExample string = `
   Verify with a public key:
   $ singularity verify --key public.pem container.sif
 
   Verify with PGP:
   $ singularity verify container.sif
+
+  Verify with a certificate, checking revocation offline with a CRL and
+  OCSP responses no older than 3 days:
+  $ singularity verify --certificate leaf.pem \
+      --certificate-intermediates intermediate.pem \
+      --certificate-roots root.pem --crl root.crl \
+      --ocsp-responses ocsp/ --revocation-max-age 72h container.sif
   
+  Verify an image is signed by at least 2 of 3 PGP keys:
+  $ singularity verify --threshold 2 \
+      --key-fingerprints 5994BE54C31CF1B5E1994F987C52CF6D055F072B,7064B1D6EFF01B1262FED3F03581D99FE87EAFD1,F34371D0ACD5D09EB9BD853A80600A5FA11BBD29 \
+      container.sif
+
   Verify an image within an OCI-SIF with a cosign compatible signature:
-  $ singularity verify --cosign --key cosign.pub container.oci.sif`
+  $ singularity verify --cosign --key cosign.pub container.oci.sif
+
+  Verify an image within an OCI-SIF with a keyless cosign signature:
+  $ singularity verify --cosign --certificate-roots fulcio-root.pem \
+      --rekor-public-key rekor.pub \
+      --certificate-identity user@example.com \
+      --certificate-oidc-issuer https://accounts.example.com container.oci.sif
+
+  Verify the provenance attestation of an image built from a library source:
+  $ singularity verify --attestation --key public.pem \
+      --attestation-source library:// container.sif
+
+  Verify an image against the verification policy:
+  $ singularity verify --policy container.sif
+
+  Verify an image pulled from a registry against a specific policy file:
+  $ singularity verify --policy-file policy.toml \
+      --policy-source docker://registry.example.com/app:1 app.oci.sif`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Run-help
@@ -944,6 +1150,33 @@ Enterprise Performance Computing (EPC)`
 
   $ singularity inspect --app <appname> ubuntu.sif`
 
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// Diff
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	DiffUse   string = `diff [diff options...] <image1> <image2>`
+	DiffShort string = `Show the differences between two images`
+	DiffLong  string = `
+  The diff command compares two images, and lists the files that were added,
+  removed, or modified in the second image, with their permissions. Changes to
+  the labels, environment and runscript of the images are also shown, together
+  with packages that were added, removed, or changed version in the dpkg or rpm
+  package databases. Listing rpm packages requires the rpm command on the host.
+
+  Images may be SIF, OCI-SIF or sandbox images, or OCI images specified with a
+  docker://, docker-archive:, docker-daemon:, oci: or oci-archive: URI. Images
+  are read directly, without being mounted or run. SIF images must have a
+  squashfs root filesystem.
+
+  The environment of a native image is compared per environment file, as shown
+  by 'singularity inspect'. The environment of an OCI or OCI-SIF image is
+  compared per variable, and its runscript is the command formed by the
+  ENTRYPOINT and CMD of the image.`
+	DiffExample string = `
+  $ singularity diff old.sif new.sif
+
+  To compare an OCI-SIF image with the image it was pulled from, in JSON format:
+  $ singularity diff --json alpine_latest.oci.sif docker://alpine:latest`
+
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Test
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -1165,7 +1398,12 @@ Enterprise Performance Computing (EPC)`
 	OverlayCreateShort string = `Create EXT3 writable overlay image`
 	OverlayCreateLong  string = `
   The overlay create command allows to create EXT3 writable overlay image either
-  as a single EXT3 image or by adding it automatically to an existing SIF image.`
+  as a single EXT3 image or by adding it automatically to an existing SIF image.
+
+  With --encrypt, the overlay is added to an existing OCI-SIF image as an
+  encrypted layer. If the image is already encrypted, the overlay uses the same
+  key, and the --pem-path private key or --passphrase for the image must be
+  provided. The same key material is needed to run the image with the overlay.`
 	OverlayCreateExample string = `
   To create and add a writable overlay to an existing SIF image:
   $ singularity overlay create --size 1024 /tmp/image.sif
@@ -1174,7 +1412,10 @@ Enterprise Performance Computing (EPC)`
   $ singularity overlay create --size 1024 /tmp/my_overlay.img
 
   To create a sparse overlay when creating a new ext3 file system image:
-  $ singularity overlay create --size 1024 --sparse /tmp/ext3_overlay.img`
+  $ singularity overlay create --size 1024 --sparse /tmp/ext3_overlay.img
+
+  To add an encrypted writable overlay to an encrypted OCI-SIF image:
+  $ singularity overlay create --size 1024 --encrypt --pem-path private.pem /tmp/image.oci.sif`
 
 	OverlaySyncUse   string = `sync oci-sif`
 	OverlaySyncShort string = `Sync OCI-SIF manifest & config with overlay content`
@@ -1195,6 +1436,118 @@ Enterprise Performance Computing (EPC)`
   To seal an OCI-SIF image containing an overlay:
   $ singularity overlay seal /tmp/overlay.oci.sif`
 
+	ImageUse   string = `image`
+	ImageShort string = `Manage the content of container images`
+	ImageLong  string = `
+  The image command allows management of the content of container images.`
+	ImageExample string = `
+  All image commands have their own help output:
+
+  $ singularity help image convert
+  $ singularity image convert --help`
+
+	ImageConvertUse   string = `convert <options> oci-sif`
+	ImageConvertShort string = `Convert the layers of an OCI-SIF image to another format`
+	ImageConvertLong  string = `
+  The image convert command rewrites the layers of an OCI-SIF image, in place,
+  in another filesystem format. Supported formats are squashfs (the default),
+  squashfs-zstd (squashfs with zstd compression) and erofs. erofs layers offer
+  better random read performance, but require erofsfuse, or the kernel erofs
+  driver when running as root, to run the image, or to convert its layers back
+  to another format.
+
+  In an OCI-SIF holding one image per platform, the image for each platform is
+  converted. A writable overlay is left as-is. Converting layers removes any
+  cosign signatures of the image, and invalidates any other signatures.`
+	ImageConvertExample string = `
+  To convert the layers of an OCI-SIF image to erofs:
+  $ singularity image convert --layer-format erofs /tmp/image.oci.sif`
+
+	ImageSquashUse   string = `squash oci-sif`
+	ImageSquashShort string = `Squash the layers of an OCI-SIF image into a single layer`
+	ImageSquashLong  string = `
+  The image squash command rewrites an OCI-SIF image, in place, so that all of
+  its layers are squashed into a single layer, as when an image is built or
+  pulled in OCI mode without '--keep-layers'. The layer is written in the
+  format of the original layers (squashfs, squashfs-zstd or erofs), or squashfs
+  if they are of mixed formats.
+
+  The image config, labels, and any writable overlay are preserved. Squashing
+  changes the digest of the image, so any cosign signatures of the image no
+  longer match and are removed.`
+	ImageSquashExample string = `
+  To squash the layers of an OCI-SIF image:
+  $ singularity image squash /tmp/image.oci.sif`
+
+	ImageCpUse   string = `cp <options> <image>:<path> <destination>`
+	ImageCpShort string = `Copy a file or directory out of an image`
+	ImageCpLong  string = `
+  The image cp command copies a file or directory out of a SIF, OCI-SIF,
+  squashfs, ext3 or sandbox image, without running the image. The image is read
+  directly, so no mounts or privileges are required.
+
+  Symbolic links in <path> are resolved within the image. Symbolic links
+  beneath a copied directory are recreated as-is. File permissions and
+  modification times are preserved, but setuid, setgid and sticky bits are not.
+  If <destination> is an existing directory, the file or directory is copied
+  into it. Existing files are never overwritten.
+
+  Encrypted images can be read by providing the key with --passphrase or
+  --pem-path.`
+	ImageCpExample string = `
+  To copy a file out of an image:
+  $ singularity image cp image.sif:/etc/os-release .
+
+  To copy a directory out of an encrypted image:
+  $ singularity image cp --pem-path private.pem image.sif:/opt/app app`
+
+	ImageLsUse   string = `ls <options> <image>[:<path>]`
+	ImageLsShort string = `List the content of a directory in an image`
+	ImageLsLong  string = `
+  The image ls command lists the content of a directory in a SIF, OCI-SIF,
+  squashfs, ext3 or sandbox image, without running the image. The root
+  directory of the image is listed if no <path> is specified. The image is read
+  directly, so no mounts or privileges are required.
+
+  Encrypted images can be read by providing the key with --passphrase or
+  --pem-path.`
+	ImageLsExample string = `
+  To list the /etc directory of an image:
+  $ singularity image ls image.sif:/etc`
+
+	ImageRekeyUse   string = `rekey <options> sif`
+	ImageRekeyShort string = `List or update the recipients of an encrypted SIF image`
+	ImageRekeyLong  string = `
+  The image rekey command lists or updates, in place, the recipients that the
+  key of an encrypted SIF image is encrypted for, without re-encrypting the
+  image file system. Recipients are identified by the SHA-256 digest of their
+  RSA public key, or the fingerprint of their PGP key. The recipients are listed
+  if no recipients are added or removed.
+
+  Recipients are added with --add-recipient, which takes a PEM formatted RSA or
+  ASCII armored PGP public key. Recipients are removed with --remove-recipient,
+  which takes the ID or public key of the recipient. Updating recipients
+  requires the private key of an existing recipient, provided with --pem-path.
+
+  Removing a recipient changes the key of the image, so that a removed
+  recipient that kept a copy of the old key is not able to decrypt the image.
+  The new key is encrypted for each remaining recipient, so the public keys of
+  the remaining recipients, other than the key provided with --pem-path, must
+  be provided with --add-recipient. The encrypted layers of an OCI-SIF image are
+  rewritten with the new key, which changes their digests. The encrypted
+  partition of a native SIF image is changed in place, so existing signatures
+  of the image must be renewed.`
+	ImageRekeyExample string = `
+  To list the recipients of an encrypted image:
+  $ singularity image rekey image.sif
+
+  To add a PGP key as a recipient, using an RSA private key of a recipient:
+  $ singularity image rekey --pem-path private.pem --add-recipient alice.asc image.sif
+
+  To remove a recipient, encrypting the new key for the remaining recipients:
+  $ singularity image rekey --pem-path private.pem --add-recipient alice.asc \
+      --remove-recipient bob.pem image.sif`
+
 	DataUse   string = `data`
 	DataShort string = `Manage an OCI-SIF data container`
 	DataLong  string = `
@@ -1217,6 +1570,96 @@ Enterprise Performance Computing (EPC)`
 
   To create a data container that package a single file:
   $ singularity data package mydir/myfile data.oci.sif`
+
+	DefUse   string = `def`
+	DefShort string = `Work with definition files`
+	DefLong  string = `
+  The def command provides utilities that operate on definition files.`
+	DefExample string = `
+  All def commands have their own help output:
+
+  $ singularity help def from-dockerfile
+  $ singularity def to-dockerfile --help`
+
+	DefFromDockerfileUse   string = `from-dockerfile <Dockerfile> [<definition file>]`
+	DefFromDockerfileShort string = `Convert a Dockerfile into a definition file`
+	DefFromDockerfileLong  string = `
+  The def from-dockerfile command converts a Dockerfile into a definition file.
+  Each Dockerfile stage becomes a build stage of the definition file:
+
+    FROM                 Bootstrap / From / Stage headers
+    ARG                  %arguments
+    RUN                  %post
+    ENV                  %environment, and %post
+    WORKDIR              %post, and %runscript
+    LABEL / MAINTAINER   %labels
+    COPY / ADD           %files
+    COPY --from          %files from <stage>
+    ENTRYPOINT / CMD     %runscript
+
+  Instructions and options that have no equivalent in a definition file are
+  skipped with a warning. Note that %files are always copied before %post
+  runs, so a Dockerfile that interleaves COPY and RUN may need to be adjusted.
+  Global ARGs used in FROM must have a default value.
+
+  If no definition file path is given, the definition is written to stdout.`
+	DefFromDockerfileExample string = `
+  $ singularity def from-dockerfile Dockerfile
+  $ singularity def from-dockerfile Dockerfile myimage.def`
+
+	DefToDockerfileUse   string = `to-dockerfile <definition file> [<Dockerfile>]`
+	DefToDockerfileShort string = `Convert a definition file into a Dockerfile`
+	DefToDockerfileLong  string = `
+  The def to-dockerfile command performs a best-effort conversion of a definition
+  file into a Dockerfile. The %post section becomes a RUN instruction using a
+  here-document, and the %runscript becomes the ENTRYPOINT.
+
+  Bootstrap agents other than docker and scratch, and sections such as %setup,
+  %test and %startscript, have no Dockerfile equivalent. They are skipped with a
+  warning.
+
+  If no Dockerfile path is given, the Dockerfile is written to stdout.`
+	DefToDockerfileExample string = `
+  $ singularity def to-dockerfile myimage.def
+  $ singularity def to-dockerfile myimage.def Dockerfile`
+)
+
+// Documentation for build-server command.
+const (
+	BuildServerUse   string = `build-server [build-server options...]`
+	BuildServerShort string = `Run a remote build server`
+	BuildServerLong  string = `
+  The build-server command runs a server that builds images on behalf of
+  clients, using 'singularity build --builder <URL>'. It implements the
+  protocol of the Sylabs remote Build Service, so no third-party service is
+  required to offer remote builds on a dedicated node.
+
+  Definitions are built natively on the server, with --fakeroot. Local files
+  referenced in the %files sections of a definition are uploaded by the client,
+  and the build cannot read any other file from the server through %files.
+  Definitions with %pre or %setup sections, which would run on the server host,
+  are refused, as are the localimage, oci, oci-archive, docker-archive and
+  docker-daemon bootstrap agents, which read their source from the server.
+  Definitions are checked once their templates are rendered, and may not hold
+  %include directives, which are resolved by the client. build-server should still be run as a dedicated unprivileged user, that has
+  been configured for fakeroot builds.
+
+  Uploaded build contexts are limited to --max-context-size, and are removed
+  once they have not been used for the retention period.
+
+  Images are not pushed to a library: the client downloads the built image
+  from the server. Builds and their images are removed from the server once
+  the retention period has passed.
+
+  A --token-file is required. Clients must send the token it holds, which they
+  read from the SINGULARITY_BUILDER_TOKEN environment variable.`
+	BuildServerExample string = `
+  On the build node:
+  $ singularity build-server --listen :8080 --work-dir /var/tmp/build-server --token-file ~/.build-token
+
+  On a client:
+  $ export SINGULARITY_BUILDER_TOKEN=$(cat ~/.build-token)
+  $ singularity build --builder http://buildnode:8080 image.sif image.def`
 )
 
 // Documentation for sif/siftool command.
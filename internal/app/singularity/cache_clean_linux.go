@@ -22,6 +22,10 @@ func cleanCache(imgCache *cache.Handle, cacheType string, dryRun bool, days int)
 	if imgCache == nil {
 		return fmt.Errorf("invalid image cache handle")
 	}
+	// Content store objects age from when they were last shared with a file.
+	if cacheType == cache.ContentCacheType && days > 0 {
+		return imgCache.CleanContentStore(dryRun, days)
+	}
 	return imgCache.CleanCache(cacheType, dryRun, days)
 }
 
@@ -37,7 +41,7 @@ func CleanSingularityCache(imgCache *cache.Handle, dryRun bool, cacheCleanTypes
 	}
 
 	// Default is all caches
-	cachesToClean := append(cache.OciCacheTypes, cache.FileCacheTypes...)
+	cachesToClean := slices.Concat(cache.OciCacheTypes, cache.FileCacheTypes, cache.ContentCacheTypes)
 
 	// If specified caches, and we don't have 'all' specified then clean the specified
 	// ones only.
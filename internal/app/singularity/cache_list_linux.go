@@ -14,6 +14,7 @@ import (
 
 	"github.com/sylabs/singularity/v4/internal/pkg/cache"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/dedupe"
 )
 
 // listTypeCache will list a cache type with given name (cacheType). The options are 'library', and 'oci'.
@@ -121,6 +122,20 @@ func ListSingularityCache(imgCache *cache.Handle, cacheListTypes []string, cache
 		containersShown = true
 	}
 
+	var (
+		contentShown bool
+		contentUsage dedupe.Usage
+	)
+	if len(cacheListTypes) == 0 || slices.Contains(cacheListTypes, cache.ContentCacheType) {
+		u, err := imgCache.ContentStoreUsage()
+		if err != nil {
+			return err
+		}
+		contentUsage = u
+		totalSpace += u.Size
+		contentShown = true
+	}
+
 	if cacheListVerbose {
 		fmt.Print("\n")
 	}
@@ -139,6 +154,10 @@ func ListSingularityCache(imgCache *cache.Handle, cacheListTypes []string, cache
 	out.WriteString(" of space\n")
 
 	fmt.Print(out.String())
+	if contentShown && contentUsage.Objects > 0 {
+		fmt.Printf("There are %d deduplicated file object(s) using %s, saving %s of space in sandboxes\n",
+			contentUsage.Objects, fs.FindSize(contentUsage.Size), fs.FindSize(contentUsage.Saved))
+	}
 	fmt.Printf("Total space used: %s\n", fs.FindSize(totalSpace))
 
 	return nil
@@ -0,0 +1,93 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/build/dockerfile"
+	"github.com/sylabs/singularity/v4/pkg/build/types/parser"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// DefFromDockerfile converts the Dockerfile at src into a definition file,
+// which is written to dst. If dst is empty, the definition is written to
+// stdout.
+func DefFromDockerfile(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("unable to open Dockerfile: %w", err)
+	}
+	defer f.Close()
+
+	defs, warnings, err := dockerfile.FromDockerfile(f)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		sylog.Warningf("%s", w)
+	}
+
+	var buf bytes.Buffer
+	for i := range defs {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		if err := defs[i].WriteRaw(&buf); err != nil {
+			return fmt.Errorf("while writing definition: %w", err)
+		}
+	}
+
+	return writeConverted(dst, buf.Bytes())
+}
+
+// DefToDockerfile converts the definition file at src into a Dockerfile, which
+// is written to dst. If dst is empty, the Dockerfile is written to stdout.
+func DefToDockerfile(src, dst string) error {
+	raw, err := parser.ReadDefinitionFile(src)
+	if err != nil {
+		return fmt.Errorf("unable to read definition file: %w", err)
+	}
+
+	defs, err := parser.All(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("while parsing definition file: %w", err)
+	}
+
+	df, warnings, err := dockerfile.ToDockerfile(defs)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		sylog.Warningf("%s", w)
+	}
+
+	return writeConverted(dst, df)
+}
+
+// writeConverted writes content to dst, which must not already exist, or to
+// stdout if dst is empty.
+func writeConverted(dst string, content []byte) error {
+	if dst == "" {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists - will not overwrite", dst)
+		}
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
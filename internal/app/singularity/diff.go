@@ -0,0 +1,63 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/cache"
+	"github.com/sylabs/singularity/v4/internal/pkg/image/diff"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociimage"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// DiffOptions holds the options for a comparison of two images.
+type DiffOptions struct {
+	// TransportOptions are used to fetch OCI images.
+	TransportOptions *ociimage.TransportOptions
+	// Cache is used to store fetched OCI images.
+	Cache *cache.Handle
+	// TmpDir is the parent directory of temporary files.
+	TmpDir string
+	// JSON selects JSON output.
+	JSON bool
+}
+
+// Diff writes the differences between images src1 and src2 to w.
+func Diff(ctx context.Context, src1, src2 string, opts DiffOptions, w io.Writer) error {
+	tmpDir, err := os.MkdirTemp(opts.TmpDir, "diff-")
+	if err != nil {
+		return fmt.Errorf("while creating temporary directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			sylog.Errorf("while removing temporary directory: %v", err)
+		}
+	}()
+
+	images := make([]*diff.Image, 2)
+	for i, src := range []string{src1, src2} {
+		sylog.Infof("Reading %s", src)
+		img, err := diff.Load(ctx, src, opts.TransportOptions, opts.Cache, tmpDir)
+		if err != nil {
+			return fmt.Errorf("while reading %s: %w", src, err)
+		}
+		images[i] = img
+	}
+
+	result := diff.Compare(images[0], images[1])
+	if opts.JSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(result)
+	}
+	result.WriteText(w)
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/singularity/v4/internal/pkg/image/rootfs"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
+)
+
+// ImageFilesOptions holds the options used to read files from an image.
+type ImageFilesOptions struct {
+	// KeyInfo holds the key used to decrypt an encrypted image.
+	KeyInfo *cryptkey.KeyInfo
+	// Platform, if set, selects the image read from a multi-platform OCI-SIF,
+	// and is required of the image read from any other OCI-SIF.
+	Platform *ggcrv1.Platform
+	// TmpDir is the parent directory of temporary files.
+	TmpDir string
+}
+
+// openImageRootfs opens the root filesystem of the image at src for reading.
+func openImageRootfs(src string, opts ImageFilesOptions) (*rootfs.FS, error) {
+	var ropts []rootfs.Option
+	if opts.Platform != nil {
+		ropts = append(ropts, rootfs.OptPlatform(*opts.Platform))
+	}
+	if opts.KeyInfo != nil {
+		ropts = append(ropts, rootfs.OptKeyInfo(opts.KeyInfo))
+	}
+	if opts.TmpDir != "" {
+		ropts = append(ropts, rootfs.OptTmpDir(opts.TmpDir))
+	}
+	fsys, err := rootfs.Open(src, ropts...)
+	if err != nil {
+		return nil, fmt.Errorf("while opening %s: %w", src, err)
+	}
+	return fsys, nil
+}
+
+// rootfsName converts the absolute path p, in an image, to a name in its root
+// filesystem.
+func rootfsName(p string) string {
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// ImageCp copies the file or directory at srcPath, in the image at src, to
+// dst on the host. If dst is an existing directory, the file is copied into
+// it. Existing files are never overwritten. Permissions and modification times
+// are preserved, except for setuid, setgid and sticky bits.
+func ImageCp(src, srcPath, dst string, opts ImageFilesOptions) error {
+	fsys, err := openImageRootfs(src, opts)
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	name := rootfsName(srcPath)
+	fi, err := fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	if di, err := os.Stat(dst); err == nil && di.IsDir() && name != "." {
+		dst = filepath.Join(dst, path.Base(name))
+	}
+	if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+		return fmt.Errorf("%s already exists - will not overwrite", dst)
+	}
+	return copyFromImage(fsys, name, fi, dst)
+}
+
+// copyFromImage copies name, described by fi, from fsys to dst.
+func copyFromImage(fsys *rootfs.FS, name string, fi fs.FileInfo, dst string) error {
+	switch mode := fi.Mode(); {
+	case mode.IsDir():
+		if err := os.Mkdir(dst, 0o700); err != nil {
+			return err
+		}
+		entries, err := fsys.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			child := path.Join(name, e.Name())
+			cfi, err := fsys.Lstat(child)
+			if err != nil {
+				return err
+			}
+			if err := copyFromImage(fsys, child, cfi, filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+	case mode.IsRegular():
+		if err := copyFileFromImage(fsys, name, dst); err != nil {
+			return err
+		}
+	case mode&fs.ModeSymlink != 0:
+		target, err := fsys.ReadLink(name)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	default:
+		sylog.Warningf("Skipping /%s: unsupported file type %s", name, mode.Type())
+		return nil
+	}
+
+	if err := os.Chmod(dst, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
+
+// copyFileFromImage copies the content of the regular file name, from fsys,
+// to a new file dst.
+func copyFileFromImage(fsys *rootfs.FS, name, dst string) error {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("while copying /%s: %w", name, err)
+	}
+	return w.Close()
+}
+
+// ImageLs writes a listing of the directory at dir, in the image at src, to w.
+// If dir is not a directory, it is listed alone.
+func ImageLs(src, dir string, opts ImageFilesOptions, w io.Writer) error {
+	fsys, err := openImageRootfs(src, opts)
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	name := rootfsName(dir)
+	fi, err := fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		name string
+		fi   fs.FileInfo
+	}
+	var entries []entry
+	if fi.IsDir() {
+		des, err := fsys.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		for _, de := range des {
+			efi, err := fsys.Lstat(path.Join(name, de.Name()))
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry{name: de.Name(), fi: efi})
+		}
+	} else {
+		lfi, err := fsys.Lstat(name)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{name: "/" + name, fi: lfi})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, e := range entries {
+		display := e.name
+		if e.fi.Mode()&fs.ModeSymlink != 0 {
+			p := path.Join(name, e.name)
+			if !fi.IsDir() {
+				p = name
+			}
+			target, err := fsys.ReadLink(p)
+			if err != nil {
+				return err
+			}
+			display += " -> " + target
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", e.fi.Mode(), e.fi.Size(), e.fi.ModTime().Format("2006-01-02 15:04"), display)
+	}
+	return tw.Flush()
+}
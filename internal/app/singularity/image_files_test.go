@@ -0,0 +1,101 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeImageFilesSandbox(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "usr/lib/app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "usr/lib/os-release"), []byte("ID=test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "usr/lib/app/run"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../usr/lib/os-release", filepath.Join(dir, "etc/os-release")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("run", filepath.Join(dir, "usr/lib/app/link")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestImageCp(t *testing.T) {
+	img := writeImageFilesSandbox(t)
+	dst := t.TempDir()
+
+	// A symbolic link in the source path is followed.
+	if err := ImageCp(img, "/etc/os-release", dst, ImageFilesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dst, "os-release"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "ID=test\n" {
+		t.Errorf("got content %q", b)
+	}
+
+	if err := ImageCp(img, "/etc/os-release", dst, ImageFilesOptions{}); err == nil {
+		t.Errorf("unexpected success overwriting existing file")
+	}
+
+	// A directory is copied recursively, recreating symbolic links.
+	app := filepath.Join(dst, "app")
+	if err := ImageCp(img, "/usr/lib/app", app, ImageFilesOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(app, "run"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o755 {
+		t.Errorf("got mode %v, want %v", fi.Mode().Perm(), os.FileMode(0o755))
+	}
+	target, err := os.Readlink(filepath.Join(app, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "run" {
+		t.Errorf("got link target %q, want %q", target, "run")
+	}
+}
+
+func TestImageLs(t *testing.T) {
+	img := writeImageFilesSandbox(t)
+
+	var buf bytes.Buffer
+	if err := ImageLs(img, "/etc", ImageFilesOptions{}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "os-release -> ../usr/lib/os-release") {
+		t.Errorf("unexpected listing %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := ImageLs(img, "/usr/lib/app", ImageFilesOptions{}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "link -> run") || !strings.HasSuffix(lines[1], "run") {
+		t.Errorf("unexpected listing %q", buf.String())
+	}
+}
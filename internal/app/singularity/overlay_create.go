@@ -1,4 +1,4 @@
-// Copyright (c) 2021-2024, Sylabs Inc. All rights reserved.
+// Copyright (c) 2021-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.package singularity
@@ -21,6 +21,7 @@ import (
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/v4/pkg/image"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
 	"golang.org/x/sys/unix"
 )
 
@@ -36,6 +37,8 @@ var (
 	errOverlayExists    = errors.New("writable overlay already exists in image")
 	errOverlayEXT3      = errors.New("image is an EXT3 filesystem that can be used as an overlay directly")
 	errOverlayNotSIF    = errors.New("cannot add an overlay to a non-SIF image")
+	errOverlayNotOCISIF = errors.New("an encrypted overlay can only be added to an OCI-SIF image")
+	errOverlayPlaintext = errors.New("cannot add an unencrypted overlay to an encrypted image, use --encrypt")
 )
 
 // sifIsSigned returns true if the SIF in rw contains one or more signature objects.
@@ -260,8 +263,10 @@ func createOverlayFile(path string, size int, sparse bool, overlayDirs ...string
 
 // OverlayCreate creates an overlay at imgPath, or adds an overlay to imgPath if
 // it is a SIF file. The overlay will have specified size (MiB) and sparseness.
-// Any directories listed in overlayDirs will be created in the overlay fs.
-func OverlayCreate(imgPath string, size int, sparse bool, overlayDirs ...string) error {
+// Any directories listed in overlayDirs will be created in the overlay fs. If
+// ki is not nil, the overlay is added to the OCI-SIF at imgPath encrypted, with
+// the key of the image, or a new key if the image is not encrypted.
+func OverlayCreate(imgPath string, size int, sparse bool, ki *cryptkey.KeyInfo, overlayDirs ...string) error {
 	if size < 64 {
 		return fmt.Errorf("image size must be equal or greater than 64 MiB")
 	}
@@ -279,6 +284,19 @@ func OverlayCreate(imgPath string, size int, sparse bool, overlayDirs ...string)
 		}
 	}
 
+	if ki != nil && (img == nil || img.Type != image.OCISIF) {
+		return errOverlayNotOCISIF
+	}
+	if ki == nil && img != nil && img.Type == image.OCISIF {
+		encrypted, err := ocisif.IsEncrypted(imgPath)
+		if err != nil {
+			return fmt.Errorf("while checking for encryption: %s", err)
+		}
+		if encrypted {
+			return errOverlayPlaintext
+		}
+	}
+
 	// Create the overlay in a separate file.
 	tmpFile := imgPath + ".ext3"
 	defer os.Remove(tmpFile)
@@ -294,6 +312,9 @@ func OverlayCreate(imgPath string, size int, sparse bool, overlayDirs ...string)
 		return nil
 	}
 	// Add to OCI-SIF
+	if img.Type == image.OCISIF && ki != nil {
+		return ocisif.AddEncryptedOverlay(imgPath, tmpFile, *ki, filepath.Dir(imgPath))
+	}
 	if img.Type == image.OCISIF {
 		return ocisif.AddOverlay(imgPath, tmpFile)
 	}
@@ -0,0 +1,271 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package attest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// PayloadType is the DSSE payload type of an in-toto statement.
+const PayloadType = "application/vnd.in-toto+json"
+
+var (
+	errNoSigners          = errors.New("no signers specified")
+	errNoVerifiers        = errors.New("no verifiers specified")
+	errPayloadType        = errors.New("unexpected payload type")
+	errNoValidSignatures  = errors.New("attestation has no signature that can be verified with the provided key material")
+	errUnexpectedIdentity = errors.New("signed by unexpected entity")
+)
+
+// Envelope is a DSSE envelope, holding a signed in-toto statement.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     []byte      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a signature in a DSSE envelope.
+type Signature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   []byte `json:"sig"`
+}
+
+// pae returns the DSSE pre-authentication encoding of payload, which is the
+// message that is signed.
+func pae(payloadType string, payload []byte) []byte {
+	return fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+}
+
+// Signer signs DSSE envelopes.
+type Signer interface {
+	// Sign returns the ID of the signing key, and the signature of msg.
+	Sign(msg []byte) (keyID string, sig []byte, err error)
+}
+
+// Verifier verifies signatures in DSSE envelopes.
+type Verifier interface {
+	// Verify checks that sig is a valid signature of msg, returning the
+	// identity of the signer.
+	Verify(keyID string, msg, sig []byte) (string, error)
+}
+
+// Sign returns a DSSE envelope holding st, signed by each of signers.
+func Sign(st *Statement, signers ...Signer) (*Envelope, error) {
+	if len(signers) == 0 {
+		return nil, errNoSigners
+	}
+
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Envelope{
+		PayloadType: PayloadType,
+		Payload:     payload,
+	}
+	msg := pae(e.PayloadType, e.Payload)
+	for _, s := range signers {
+		keyID, sig, err := s.Sign(msg)
+		if err != nil {
+			return nil, fmt.Errorf("while signing attestation: %w", err)
+		}
+		e.Signatures = append(e.Signatures, Signature{KeyID: keyID, Sig: sig})
+	}
+	return e, nil
+}
+
+// Verify checks the signatures of e with verifiers, returning the statement
+// held by e, and the identities of the signers whose signature is valid. An
+// error is returned if no signature is valid.
+func (e *Envelope) Verify(verifiers ...Verifier) (*Statement, []string, error) {
+	if len(verifiers) == 0 {
+		return nil, nil, errNoVerifiers
+	}
+	if e.PayloadType != PayloadType {
+		return nil, nil, fmt.Errorf("%w: %q", errPayloadType, e.PayloadType)
+	}
+
+	msg := pae(e.PayloadType, e.Payload)
+
+	var ids []string
+	for _, s := range e.Signatures {
+		for _, v := range verifiers {
+			if id, err := v.Verify(s.KeyID, msg, s.Sig); err == nil {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil, errNoValidSignatures
+	}
+
+	var st Statement
+	if err := json.Unmarshal(e.Payload, &st); err != nil {
+		return nil, nil, fmt.Errorf("while decoding statement: %w", err)
+	}
+	return &st, ids, nil
+}
+
+// keyID returns the hex encoded SHA-256 digest of the DER encoding of pub.
+func keyID(pub crypto.PublicKey) (string, error) {
+	der, err := cryptoutils.MarshalPublicKeyToDER(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type keySigner struct {
+	s signature.Signer
+}
+
+// NewSigner returns a Signer that signs with the private key held by s, which
+// may be loaded from a PEM file or a cosign private key.
+func NewSigner(s signature.Signer) Signer {
+	return keySigner{s: s}
+}
+
+func (ks keySigner) Sign(msg []byte) (string, []byte, error) {
+	pub, err := ks.s.PublicKey()
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := keyID(pub)
+	if err != nil {
+		return "", nil, err
+	}
+	sig, err := ks.s.SignMessage(bytes.NewReader(msg))
+	if err != nil {
+		return "", nil, err
+	}
+	return id, sig, nil
+}
+
+type keyVerifier struct {
+	v signature.Verifier
+}
+
+// NewVerifier returns a Verifier that verifies signatures with the public key
+// held by v. The identity of the signer is the key ID.
+func NewVerifier(v signature.Verifier) Verifier {
+	return keyVerifier{v: v}
+}
+
+func (kv keyVerifier) Verify(_ string, msg, sig []byte) (string, error) {
+	pub, err := kv.v.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	id, err := keyID(pub)
+	if err != nil {
+		return "", err
+	}
+	if err := kv.v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(msg)); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+type certVerifier struct {
+	c             *x509.Certificate
+	intermediates *x509.CertPool
+	roots         *x509.CertPool
+	v             signature.Verifier
+}
+
+// NewCertificateVerifier returns a Verifier that verifies signatures with the
+// public key of certificate c. The certificate must chain to roots, or the
+// system roots if roots is nil, via intermediates, and be valid for code
+// signing. The identity of the signer is the subject of c.
+func NewCertificateVerifier(c *x509.Certificate, intermediates, roots *x509.CertPool) (Verifier, error) {
+	v, err := signature.LoadVerifier(c.PublicKey, crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return certVerifier{
+		c:             c,
+		intermediates: intermediates,
+		roots:         roots,
+		v:             v,
+	}, nil
+}
+
+func (cv certVerifier) Verify(_ string, msg, sig []byte) (string, error) {
+	opts := x509.VerifyOptions{
+		Intermediates: cv.intermediates,
+		Roots:         cv.roots,
+		CurrentTime:   time.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	if _, err := cv.c.Verify(opts); err != nil {
+		return "", err
+	}
+	if err := cv.v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(msg)); err != nil {
+		return "", err
+	}
+	return cv.c.Subject.String(), nil
+}
+
+type pgpSigner struct {
+	e *openpgp.Entity
+}
+
+// NewPGPSigner returns a Signer that generates detached OpenPGP signatures
+// with the decrypted private key of e.
+func NewPGPSigner(e *openpgp.Entity) Signer {
+	return pgpSigner{e: e}
+}
+
+func (ps pgpSigner) Sign(msg []byte) (string, []byte, error) {
+	var b bytes.Buffer
+	if err := openpgp.DetachSign(&b, ps.e, bytes.NewReader(msg), nil); err != nil {
+		return "", nil, err
+	}
+	return fingerprint(ps.e), b.Bytes(), nil
+}
+
+type pgpVerifier struct {
+	kr openpgp.KeyRing
+}
+
+// NewPGPVerifier returns a Verifier that verifies detached OpenPGP signatures
+// with the keys in kr. The identity of the signer is the fingerprint of the
+// primary key.
+func NewPGPVerifier(kr openpgp.KeyRing) Verifier {
+	return pgpVerifier{kr: kr}
+}
+
+func (pv pgpVerifier) Verify(keyID string, msg, sig []byte) (string, error) {
+	e, err := openpgp.CheckDetachedSignature(pv.kr, bytes.NewReader(msg), bytes.NewReader(sig), nil)
+	if err != nil {
+		return "", err
+	}
+	fp := fingerprint(e)
+	if keyID != "" && !strings.EqualFold(keyID, fp) {
+		return "", fmt.Errorf("%w: %s", errUnexpectedIdentity, fp)
+	}
+	return fp, nil
+}
+
+func fingerprint(e *openpgp.Entity) string {
+	return strings.ToUpper(hex.EncodeToString(e.PrimaryKey.Fingerprint))
+}
@@ -0,0 +1,329 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package attest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// getTestSigner returns a fixed test Signer.
+func getTestSigner(t *testing.T, file string) signature.Signer {
+	t.Helper()
+
+	path := filepath.Join("..", "..", "..", "test", "keys", file)
+
+	sv, err := signature.LoadSignerFromPEMFile(path, crypto.SHA256, cryptoutils.SkipPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sv
+}
+
+// getTestVerifier returns a fixed test Verifier.
+func getTestVerifier(t *testing.T, file string) signature.Verifier {
+	t.Helper()
+
+	path := filepath.Join("..", "..", "..", "test", "keys", file)
+
+	sv, err := signature.LoadVerifierFromPEMFile(path, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sv
+}
+
+// getTestEntity returns a fixed test PGP entity.
+func getTestEntity(t *testing.T) *openpgp.Entity {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("..", "..", "..", "test", "keys", "pgp-private.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	el, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(el), 1; got != want {
+		t.Fatalf("got %v entities, want %v", got, want)
+	}
+	return el[0]
+}
+
+// getCertificate returns the certificate read from the specified file.
+func getCertificate(t *testing.T, file string) *x509.Certificate {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("..", "..", "..", "test", "certs", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := pem.Decode(b)
+	if p == nil {
+		t.Fatal("failed to decode PEM")
+	}
+
+	c, err := x509.ParseCertificate(p.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// getCertificatePool returns a pool holding the certificate read from the
+// specified file.
+func getCertificatePool(t *testing.T, file string) *x509.CertPool {
+	t.Helper()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(getCertificate(t, file))
+	return pool
+}
+
+func getTestStatement() *Statement {
+	return NewStatement(Materials{
+		Subject: ResourceDescriptor{
+			Name:   "image.sif",
+			Digest: map[string]string{"sha256": "0123456789abcdef"},
+		},
+		Definition: ResourceDescriptor{
+			URI:    "file:///src/image.def",
+			Digest: map[string]string{"sha256": "fedcba9876543210"},
+		},
+		Sources: []ResourceDescriptor{
+			{URI: "docker://alpine:3", Digest: map[string]string{"sha256": "00112233"}},
+		},
+		BuildArgs:      map[string]string{"VERSION": "1.0"},
+		BuilderID:      "singularity://builder",
+		BuilderVersion: "4.5.1",
+		StartedOn:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		FinishedOn:     time.Date(2026, 1, 2, 3, 14, 5, 0, time.UTC),
+	})
+}
+
+func TestNewStatement(t *testing.T) {
+	st := getTestStatement()
+
+	if got, want := st.Type, StatementType; got != want {
+		t.Errorf("got type %v, want %v", got, want)
+	}
+	if got, want := st.PredicateType, PredicateSLSAProvenance; got != want {
+		t.Errorf("got predicate type %v, want %v", got, want)
+	}
+	if got, want := st.Predicate.BuildDefinition.ExternalParameters.Definition, "file:///src/image.def"; got != want {
+		t.Errorf("got definition %v, want %v", got, want)
+	}
+
+	want := []ResourceDescriptor{
+		{Name: NameDefinition, URI: "file:///src/image.def", Digest: map[string]string{"sha256": "fedcba9876543210"}},
+		{Name: NameBootstrap, URI: "docker://alpine:3", Digest: map[string]string{"sha256": "00112233"}},
+	}
+	if got := st.Predicate.BuildDefinition.ResolvedDependencies; !reflect.DeepEqual(got, want) {
+		t.Errorf("got dependencies %v, want %v", got, want)
+	}
+
+	if got, want := st.Predicate.RunDetails.Builder.Version["singularity"], "4.5.1"; got != want {
+		t.Errorf("got builder version %v, want %v", got, want)
+	}
+
+	b, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(`"_type":"https://in-toto.io/Statement/v1"`)) {
+		t.Errorf("unexpected statement encoding: %s", b)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	e := getTestEntity(t)
+
+	pgpKeyRing := openpgp.EntityList{e}
+
+	certVerifier, err := NewCertificateVerifier(
+		getCertificate(t, "leaf.pem"),
+		getCertificatePool(t, "intermediate.pem"),
+		getCertificatePool(t, "root.pem"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	untrustedVerifier, err := NewCertificateVerifier(
+		getCertificate(t, "leaf.pem"),
+		nil,
+		getCertificatePool(t, "root.pem"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		signers   []Signer
+		verifiers []Verifier
+		wantIDs   int
+		wantErr   error
+	}{
+		{
+			name:      "NoSigners",
+			verifiers: []Verifier{NewVerifier(getTestVerifier(t, "ed25519-public.pem"))},
+			wantErr:   errNoSigners,
+		},
+		{
+			name:    "NoVerifiers",
+			signers: []Signer{NewSigner(getTestSigner(t, "ed25519-private.pem"))},
+			wantErr: errNoVerifiers,
+		},
+		{
+			name:      "ED25519",
+			signers:   []Signer{NewSigner(getTestSigner(t, "ed25519-private.pem"))},
+			verifiers: []Verifier{NewVerifier(getTestVerifier(t, "ed25519-public.pem"))},
+			wantIDs:   1,
+		},
+		{
+			name:      "ECDSA",
+			signers:   []Signer{NewSigner(getTestSigner(t, "ecdsa-private.pem"))},
+			verifiers: []Verifier{NewVerifier(getTestVerifier(t, "ecdsa-public.pem"))},
+			wantIDs:   1,
+		},
+		{
+			name:      "WrongKey",
+			signers:   []Signer{NewSigner(getTestSigner(t, "ed25519-private.pem"))},
+			verifiers: []Verifier{NewVerifier(getTestVerifier(t, "rsa-public.pem"))},
+			wantErr:   errNoValidSignatures,
+		},
+		{
+			name:      "Certificate",
+			signers:   []Signer{NewSigner(getTestSigner(t, "rsa-private.pem"))},
+			verifiers: []Verifier{certVerifier},
+			wantIDs:   1,
+		},
+		{
+			name:      "CertificateUntrusted",
+			signers:   []Signer{NewSigner(getTestSigner(t, "rsa-private.pem"))},
+			verifiers: []Verifier{untrustedVerifier},
+			wantErr:   errNoValidSignatures,
+		},
+		{
+			name:      "PGP",
+			signers:   []Signer{NewPGPSigner(e)},
+			verifiers: []Verifier{NewPGPVerifier(pgpKeyRing)},
+			wantIDs:   1,
+		},
+		{
+			name:      "PGPEmptyKeyRing",
+			signers:   []Signer{NewPGPSigner(e)},
+			verifiers: []Verifier{NewPGPVerifier(openpgp.EntityList{})},
+			wantErr:   errNoValidSignatures,
+		},
+		{
+			name: "Multiple",
+			signers: []Signer{
+				NewSigner(getTestSigner(t, "ed25519-private.pem")),
+				NewPGPSigner(e),
+			},
+			verifiers: []Verifier{
+				NewVerifier(getTestVerifier(t, "ed25519-public.pem")),
+				NewPGPVerifier(pgpKeyRing),
+			},
+			wantIDs: 2,
+		},
+		{
+			name: "MultiplePartial",
+			signers: []Signer{
+				NewSigner(getTestSigner(t, "ed25519-private.pem")),
+				NewPGPSigner(e),
+			},
+			verifiers: []Verifier{NewPGPVerifier(pgpKeyRing)},
+			wantIDs:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := getTestStatement()
+
+			env, err := Sign(st, tt.signers...)
+			if err != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			// Round trip the envelope through its encoding.
+			b, err := json.Marshal(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got Envelope
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			gotSt, ids, err := got.Verify(tt.verifiers...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got, want := len(ids), tt.wantIDs; got != want {
+				t.Errorf("got %v signers, want %v", got, want)
+			}
+			if !reflect.DeepEqual(gotSt, st) {
+				t.Errorf("got statement %+v, want %+v", gotSt, st)
+			}
+		})
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	env, err := Sign(getTestStatement(), NewSigner(getTestSigner(t, "ed25519-private.pem")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(getTestVerifier(t, "ed25519-public.pem"))
+
+	t.Run("Payload", func(t *testing.T) {
+		e := *env
+		e.Payload = bytes.Replace(e.Payload, []byte("alpine"), []byte("ubuntu"), 1)
+		if _, _, err := e.Verify(v); !errors.Is(err, errNoValidSignatures) {
+			t.Errorf("got error %v, want %v", err, errNoValidSignatures)
+		}
+	})
+
+	t.Run("PayloadType", func(t *testing.T) {
+		e := *env
+		e.PayloadType = "application/json"
+		if _, _, err := e.Verify(v); !errors.Is(err, errPayloadType) {
+			t.Errorf("got error %v, want %v", err, errPayloadType)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package attest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	errStatementType   = errors.New("not an in-toto v1 statement")
+	errPredicateType   = errors.New("not a SLSA v1 provenance statement")
+	errSubjectMismatch = errors.New("statement subject does not match image")
+	errUntrustedBuild  = errors.New("image not built by a trusted builder")
+	errUntrustedSource = errors.New("image built from an untrusted source")
+	errUnpinnedSource  = errors.New("image built from a source without a digest")
+	errNoSource        = errors.New("image provenance does not record a bootstrap source")
+)
+
+// Policy constrains the provenance of an image. The zero value only requires
+// a SLSA provenance statement for the image.
+type Policy struct {
+	// BuilderIDs, if set, are the IDs of the builders trusted to build the
+	// image.
+	BuilderIDs []string
+	// Sources, if set, are the URI prefixes of the bootstrap sources the
+	// image may be built from. A prefix must end at the end of the URI, or at
+	// a '/', ':' or '@'. The provenance of the image must then record a
+	// bootstrap source.
+	Sources []string
+	// RequireDigests requires the definition, and each bootstrap source, of
+	// the build to be recorded with a digest.
+	RequireDigests bool
+}
+
+// Check checks that st holds SLSA provenance for the image with the specified
+// digest, which satisfies p.
+func (p Policy) Check(st *Statement, digest map[string]string) error {
+	if st.Type != StatementType {
+		return fmt.Errorf("%w: %q", errStatementType, st.Type)
+	}
+	if st.PredicateType != PredicateSLSAProvenance {
+		return fmt.Errorf("%w: %q", errPredicateType, st.PredicateType)
+	}
+
+	if !slices.ContainsFunc(st.Subject, func(s ResourceDescriptor) bool {
+		return matchDigest(s.Digest, digest)
+	}) {
+		return errSubjectMismatch
+	}
+
+	if builder := st.Predicate.RunDetails.Builder.ID; len(p.BuilderIDs) > 0 && !slices.Contains(p.BuilderIDs, builder) {
+		return fmt.Errorf("%w: %q", errUntrustedBuild, builder)
+	}
+
+	hasDefinition, hasSource := false, false
+	for _, d := range st.Predicate.BuildDefinition.ResolvedDependencies {
+		switch d.Name {
+		case NameDefinition:
+			hasDefinition = true
+		case NameBootstrap:
+			hasSource = true
+			if len(p.Sources) > 0 && !slices.ContainsFunc(p.Sources, func(prefix string) bool {
+				return hasSourcePrefix(d.URI, prefix)
+			}) {
+				return fmt.Errorf("%w: %q", errUntrustedSource, d.URI)
+			}
+		default:
+			continue
+		}
+		if p.RequireDigests && len(d.Digest) == 0 {
+			return fmt.Errorf("%w: %q", errUnpinnedSource, d.URI)
+		}
+	}
+	if len(p.Sources) > 0 && !hasSource {
+		return errNoSource
+	}
+	if p.RequireDigests && !hasDefinition {
+		return fmt.Errorf("%w: %q", errUnpinnedSource, st.Predicate.BuildDefinition.ExternalParameters.Definition)
+	}
+	return nil
+}
+
+// hasSourcePrefix returns true if uri starts with prefix, and the prefix ends
+// at the end of uri, or at a separator, so that "docker://registry.example.com"
+// does not match "docker://registry.example.com.evil.io/image".
+func hasSourcePrefix(uri, prefix string) bool {
+	if prefix == "" || !strings.HasPrefix(uri, prefix) {
+		return false
+	}
+	if len(uri) == len(prefix) || strings.ContainsAny(prefix[len(prefix)-1:], "/:@") {
+		return true
+	}
+	return strings.ContainsAny(uri[len(prefix):len(prefix)+1], "/:@")
+}
+
+// matchDigest returns true if a and b hold the same value for at least one
+// algorithm, and do not disagree on the value of any other.
+func matchDigest(a, b map[string]string) bool {
+	match := false
+	for alg, v := range a {
+		if w, ok := b[alg]; ok {
+			if !strings.EqualFold(v, w) {
+				return false
+			}
+			match = true
+		}
+	}
+	return match
+}
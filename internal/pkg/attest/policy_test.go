@@ -0,0 +1,181 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package attest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	digest := map[string]string{"sha256": "0123456789abcdef"}
+
+	unpinned := getTestStatement()
+	unpinned.Predicate.BuildDefinition.ResolvedDependencies[1].Digest = nil
+
+	noDefinition := getTestStatement()
+	noDefinition.Predicate.BuildDefinition.ResolvedDependencies = noDefinition.Predicate.BuildDefinition.ResolvedDependencies[1:]
+
+	noSource := getTestStatement()
+	noSource.Predicate.BuildDefinition.ResolvedDependencies = noSource.Predicate.BuildDefinition.ResolvedDependencies[:1]
+
+	wrongType := getTestStatement()
+	wrongType.Type = "https://in-toto.io/Statement/v0.1"
+
+	wrongPredicate := getTestStatement()
+	wrongPredicate.PredicateType = "https://spdx.dev/Document"
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		st      *Statement
+		digest  map[string]string
+		wantErr error
+	}{
+		{
+			name:   "Default",
+			st:     getTestStatement(),
+			digest: digest,
+		},
+		{
+			name:   "DigestCase",
+			st:     getTestStatement(),
+			digest: map[string]string{"sha256": "0123456789ABCDEF"},
+		},
+		{
+			name:    "SubjectMismatch",
+			st:      getTestStatement(),
+			digest:  map[string]string{"sha256": "ffffffffffffffff"},
+			wantErr: errSubjectMismatch,
+		},
+		{
+			name:    "SubjectAlgorithm",
+			st:      getTestStatement(),
+			digest:  map[string]string{"sha512": "0123456789abcdef"},
+			wantErr: errSubjectMismatch,
+		},
+		{
+			name:    "StatementType",
+			st:      wrongType,
+			digest:  digest,
+			wantErr: errStatementType,
+		},
+		{
+			name:    "PredicateType",
+			st:      wrongPredicate,
+			digest:  digest,
+			wantErr: errPredicateType,
+		},
+		{
+			name:   "Builder",
+			policy: Policy{BuilderIDs: []string{"singularity://other", "singularity://builder"}},
+			st:     getTestStatement(),
+			digest: digest,
+		},
+		{
+			name:    "BuilderUntrusted",
+			policy:  Policy{BuilderIDs: []string{"singularity://other"}},
+			st:      getTestStatement(),
+			digest:  digest,
+			wantErr: errUntrustedBuild,
+		},
+		{
+			name:   "Source",
+			policy: Policy{Sources: []string{"library://", "docker://alpine"}},
+			st:     getTestStatement(),
+			digest: digest,
+		},
+		{
+			name:    "SourceUntrusted",
+			policy:  Policy{Sources: []string{"library://"}},
+			st:      getTestStatement(),
+			digest:  digest,
+			wantErr: errUntrustedSource,
+		},
+		{
+			name:    "SourceBoundary",
+			policy:  Policy{Sources: []string{"docker://alp", "docker://alpine:3.1"}},
+			st:      getTestStatement(),
+			digest:  digest,
+			wantErr: errUntrustedSource,
+		},
+		{
+			name:   "SourceExact",
+			policy: Policy{Sources: []string{"docker://alpine:3"}},
+			st:     getTestStatement(),
+			digest: digest,
+		},
+		{
+			name:    "SourceMissing",
+			policy:  Policy{Sources: []string{"library://", "docker://alpine"}},
+			st:      noSource,
+			digest:  digest,
+			wantErr: errNoSource,
+		},
+		{
+			name:   "NoSource",
+			st:     noSource,
+			digest: digest,
+		},
+		{
+			name:   "RequireDigests",
+			policy: Policy{RequireDigests: true},
+			st:     getTestStatement(),
+			digest: digest,
+		},
+		{
+			name:   "Unpinned",
+			st:     unpinned,
+			digest: digest,
+		},
+		{
+			name:    "RequireDigestsUnpinned",
+			policy:  Policy{RequireDigests: true},
+			st:      unpinned,
+			digest:  digest,
+			wantErr: errUnpinnedSource,
+		},
+		{
+			name:    "RequireDigestsNoDefinition",
+			policy:  Policy{RequireDigests: true},
+			st:      noDefinition,
+			digest:  digest,
+			wantErr: errUnpinnedSource,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Check(tt.st, tt.digest); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasSourcePrefix(t *testing.T) {
+	tests := []struct {
+		uri    string
+		prefix string
+		want   bool
+	}{
+		{"docker://registry.example.com/image:1", "docker://registry.example.com", true},
+		{"docker://registry.example.com:5000/image", "docker://registry.example.com", true},
+		{"docker://registry.example.com/image@sha256:00", "docker://registry.example.com/image", true},
+		{"docker://registry.example.com", "docker://registry.example.com", true},
+		{"docker://registry.example.com.evil.io/image", "docker://registry.example.com", false},
+		{"docker://registry.example.com/image2", "docker://registry.example.com/image", false},
+		{"library://user/image", "library://", true},
+		{"oras://registry.example.com/image", "docker://", false},
+		{"docker://alpine", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := hasSourcePrefix(tt.uri, tt.prefix); got != tt.want {
+			t.Errorf("hasSourcePrefix(%q, %q) = %v, want %v", tt.uri, tt.prefix, got, tt.want)
+		}
+	}
+}
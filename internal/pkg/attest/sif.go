@@ -0,0 +1,131 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package attest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// SIFObjectName is the name of the SIF data object holding a provenance
+// attestation.
+const SIFObjectName = "provenance.intoto.json"
+
+// ErrNoAttestation is returned when an image holds no provenance attestation.
+var ErrNoAttestation = errors.New("image has no provenance attestation")
+
+// primaryPartition returns the descriptor of the primary system partition of f.
+func primaryPartition(f *sif.FileImage) (sif.Descriptor, error) {
+	d, err := f.GetDescriptor(sif.WithPartitionType(sif.PartPrimSys))
+	if err != nil {
+		return sif.Descriptor{}, fmt.Errorf("while locating primary partition: %w", err)
+	}
+	return d, nil
+}
+
+// SIFSubject returns the subject of a provenance statement for the SIF image
+// at path, which is identified by the digest of its primary partition.
+func SIFSubject(path string) (ResourceDescriptor, error) {
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return ResourceDescriptor{}, fmt.Errorf("while loading SIF: %w", err)
+	}
+	defer f.UnloadContainer()
+
+	d, err := primaryPartition(f)
+	if err != nil {
+		return ResourceDescriptor{}, err
+	}
+	digest, err := Digest(d.GetReader())
+	if err != nil {
+		return ResourceDescriptor{}, fmt.Errorf("while computing digest of primary partition: %w", err)
+	}
+	return ResourceDescriptor{Name: filepath.Base(path), Digest: digest}, nil
+}
+
+// AttachSIF adds e to the SIF image at path. The attestation is added to the
+// object group of the primary partition, so that it is covered by signatures
+// subsequently applied to the group.
+func AttachSIF(path string, e *Envelope) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := sif.LoadContainerFromPath(path)
+	if err != nil {
+		return fmt.Errorf("while loading SIF: %w", err)
+	}
+	defer f.UnloadContainer()
+
+	d, err := primaryPartition(f)
+	if err != nil {
+		return err
+	}
+
+	di, err := sif.NewDescriptorInput(sif.DataGenericJSON, bytes.NewReader(b),
+		sif.OptObjectName(SIFObjectName),
+		sif.OptGroupID(d.GroupID()),
+		sif.OptLinkedID(d.ID()),
+	)
+	if err != nil {
+		return err
+	}
+	if err := f.AddObject(di); err != nil {
+		return fmt.Errorf("while adding attestation: %w", err)
+	}
+	return nil
+}
+
+// SIFAttestations returns the provenance attestations held by the SIF image at
+// path, along with the digest of its primary partition.
+func SIFAttestations(path string) ([]*Envelope, map[string]string, error) {
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, nil, fmt.Errorf("while loading SIF: %w", err)
+	}
+	defer f.UnloadContainer()
+
+	d, err := primaryPartition(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	digest, err := Digest(d.GetReader())
+	if err != nil {
+		return nil, nil, fmt.Errorf("while computing digest of primary partition: %w", err)
+	}
+
+	ds, err := f.GetDescriptors(
+		sif.WithDataType(sif.DataGenericJSON),
+		func(od sif.Descriptor) (bool, error) { return od.Name() == SIFObjectName, nil },
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ds) == 0 {
+		return nil, nil, ErrNoAttestation
+	}
+
+	es := make([]*Envelope, 0, len(ds))
+	for _, od := range ds {
+		b, err := od.GetData()
+		if err != nil {
+			return nil, nil, err
+		}
+		var e Envelope
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, nil, fmt.Errorf("while decoding attestation: %w", err)
+		}
+		es = append(es, &e)
+	}
+	return es, digest, nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package attest
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// tempFileFrom copies the file at path to a temporary file, and returns its
+// path.
+func tempFileFrom(t *testing.T, path string) string {
+	t.Helper()
+
+	src, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(t.TempDir(), "*.sif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	return dst.Name()
+}
+
+func TestAttachSIF(t *testing.T) {
+	path := tempFileFrom(t, filepath.Join("..", "..", "..", "test", "images", "one-group.sif"))
+
+	if _, _, err := SIFAttestations(path); !errors.Is(err, ErrNoAttestation) {
+		t.Fatalf("got error %v, want %v", err, ErrNoAttestation)
+	}
+
+	subject, err := SIFSubject(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := subject.Name, filepath.Base(path); got != want {
+		t.Errorf("got subject name %v, want %v", got, want)
+	}
+
+	st := getTestStatement()
+	st.Subject = []ResourceDescriptor{subject}
+	env, err := Sign(st, NewSigner(getTestSigner(t, "ed25519-private.pem")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AttachSIF(path, env); err != nil {
+		t.Fatal(err)
+	}
+
+	es, digest, err := SIFAttestations(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(es), 1; got != want {
+		t.Fatalf("got %v attestations, want %v", got, want)
+	}
+	if !reflect.DeepEqual(digest, subject.Digest) {
+		t.Errorf("got digest %v, want %v", digest, subject.Digest)
+	}
+
+	got, _, err := es[0].Verify(NewVerifier(getTestVerifier(t, "ed25519-public.pem")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (Policy{}).Check(got, digest); err != nil {
+		t.Errorf("unexpected policy error: %v", err)
+	}
+
+	// The attestation is in the object group of the primary partition, so
+	// that it is covered by a signature of the group.
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.UnloadContainer()
+
+	d, err := f.GetDescriptor(sif.WithDataType(sif.DataGenericJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := f.GetDescriptor(sif.WithPartitionType(sif.PartPrimSys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.GroupID(), p.GroupID(); got != want {
+		t.Errorf("got group %v, want %v", got, want)
+	}
+	if got, want := d.Name(), SIFObjectName; got != want {
+		t.Errorf("got name %v, want %v", got, want)
+	}
+}
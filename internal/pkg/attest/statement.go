@@ -0,0 +1,183 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package attest generates, signs, stores and checks in-toto statements
+// holding SLSA provenance of container images built by singularity.
+package attest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// StatementType is the type of an in-toto v1 statement.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateSLSAProvenance is the type of a SLSA v1 provenance predicate.
+	PredicateSLSAProvenance = "https://slsa.dev/provenance/v1"
+	// BuildType identifies the build process, and the form of the parameters,
+	// recorded in provenance generated by singularity build.
+	BuildType = "https://sylabs.io/singularity/build/v1"
+)
+
+const (
+	// NameDefinition is the name of the resolved dependency that records the
+	// definition file, or Dockerfile, of a build.
+	NameDefinition = "definition"
+	// NameBootstrap is the name of a resolved dependency that records the
+	// bootstrap source of a build stage.
+	NameBootstrap = "bootstrap"
+)
+
+// ResourceDescriptor identifies an artifact consumed or produced by a build.
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// Statement is an in-toto v1 statement, holding SLSA v1 provenance.
+type Statement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Provenance           `json:"predicate"`
+}
+
+// Provenance is a SLSA v1 provenance predicate.
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition records the inputs of a build.
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   Parameters           `json:"externalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// Parameters are the external parameters of a build, under the control of the
+// user who requested it.
+type Parameters struct {
+	Definition string            `json:"definition"`
+	BuildArgs  map[string]string `json:"buildArgs,omitempty"`
+}
+
+// RunDetails records the builder, and the execution, of a build.
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// Builder identifies the platform that performed a build.
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// Metadata records the execution of a build.
+type Metadata struct {
+	StartedOn  *time.Time `json:"startedOn,omitempty"`
+	FinishedOn *time.Time `json:"finishedOn,omitempty"`
+}
+
+// Materials describes a completed build, from which provenance is generated.
+type Materials struct {
+	// Subject is the image produced by the build.
+	Subject ResourceDescriptor
+	// Definition is the definition file, or Dockerfile, of the build. The
+	// URI of a build from a source URI, with no definition file, has no
+	// digest.
+	Definition ResourceDescriptor
+	// Sources are the bootstrap sources of the stages of the build.
+	Sources []ResourceDescriptor
+	// BuildArgs are the values of build arguments supplied by the user.
+	BuildArgs map[string]string
+	// BuilderID identifies the builder.
+	BuilderID string
+	// BuilderVersion is the version of singularity that performed the build.
+	BuilderVersion string
+	// StartedOn and FinishedOn are the start and end times of the build.
+	StartedOn  time.Time
+	FinishedOn time.Time
+}
+
+// NewStatement returns a provenance statement for the build described by m.
+func NewStatement(m Materials) *Statement {
+	var deps []ResourceDescriptor
+	if len(m.Definition.Digest) > 0 {
+		def := m.Definition
+		def.Name = NameDefinition
+		deps = append(deps, def)
+	}
+	for _, s := range m.Sources {
+		s.Name = NameBootstrap
+		deps = append(deps, s)
+	}
+
+	st := &Statement{
+		Type:          StatementType,
+		Subject:       []ResourceDescriptor{m.Subject},
+		PredicateType: PredicateSLSAProvenance,
+		Predicate: Provenance{
+			BuildDefinition: BuildDefinition{
+				BuildType: BuildType,
+				ExternalParameters: Parameters{
+					Definition: m.Definition.URI,
+					BuildArgs:  m.BuildArgs,
+				},
+				ResolvedDependencies: deps,
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{
+					ID: m.BuilderID,
+				},
+			},
+		},
+	}
+	if m.BuilderVersion != "" {
+		st.Predicate.RunDetails.Builder.Version = map[string]string{"singularity": m.BuilderVersion}
+	}
+	if !m.StartedOn.IsZero() {
+		t := m.StartedOn.UTC()
+		st.Predicate.RunDetails.Metadata.StartedOn = &t
+	}
+	if !m.FinishedOn.IsZero() {
+		t := m.FinishedOn.UTC()
+		st.Predicate.RunDetails.Metadata.FinishedOn = &t
+	}
+	return st
+}
+
+// Digest returns the SHA-256 digest of the content of r, in the form used by
+// a ResourceDescriptor.
+func Digest(r io.Reader) (map[string]string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return map[string]string{"sha256": hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// FileDigest returns the SHA-256 digest of the file at path, in the form used
+// by a ResourceDescriptor.
+func FileDigest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := Digest(f)
+	if err != nil {
+		return nil, fmt.Errorf("while computing digest of %s: %w", path, err)
+	}
+	return d, nil
+}
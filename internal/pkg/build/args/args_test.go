@@ -250,3 +250,137 @@ func TestReadDefaults(t *testing.T) {
 		"HOME":        "/root",
 	})
 }
+
+func TestReaderTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		output       string
+		argsMap      map[string]string
+		consumedArgs []string
+		err          string
+	}{
+		{
+			name:         "if true",
+			input:        "a\n{{ if .gpu }}\ngpu\n{{ else }}\ncpu\n{{ end }}\nb\n",
+			output:       "a\ngpu\nb\n",
+			argsMap:      map[string]string{"gpu": "yes"},
+			consumedArgs: []string{"gpu"},
+		},
+		{
+			name:         "if false",
+			input:        "a\n{{ if .gpu }}\ngpu {{ CUDA }}\n{{ else }}\ncpu\n{{ end }}\nb\n",
+			output:       "a\ncpu\nb\n",
+			argsMap:      map[string]string{"gpu": "false"},
+			consumedArgs: []string{"CUDA", "gpu"},
+		},
+		{
+			name:         "if undefined",
+			input:        "{{ if gpu }}gpu{{ end }}",
+			output:       "",
+			consumedArgs: []string{"gpu"},
+		},
+		{
+			name:         "else if",
+			input:        `{{ if eq .OS "alpine" }}apk{{ else if ne .OS "fedora" }}apt{{ else }}dnf{{ end }}`,
+			output:       "apt",
+			argsMap:      map[string]string{"OS": "ubuntu"},
+			consumedArgs: []string{"OS"},
+		},
+		{
+			name:         "not",
+			input:        "{{ if not .minimal }}docs{{ end }}",
+			output:       "docs",
+			argsMap:      map[string]string{"minimal": "0"},
+			consumedArgs: []string{"minimal"},
+		},
+		{
+			name:         "default",
+			input:        `{{ VER | default "1.0" }} {{ OS | default alpine }}`,
+			output:       "1.0 ubuntu",
+			argsMap:      map[string]string{"OS": "ubuntu"},
+			consumedArgs: []string{"OS", "VER"},
+		},
+		{
+			name:         "range",
+			input:        "%post\n{{ range .PKGS }}\n    install {{ . }}\n{{ end }}\n",
+			output:       "%post\n    install a\n    install b\n",
+			argsMap:      map[string]string{"PKGS": "a, b,"},
+			consumedArgs: []string{"PKGS"},
+		},
+		{
+			name:   "foreign actions",
+			input:  `docker inspect --format '{{.Id}} {{ json .Config }} {{ . }}'`,
+			output: `docker inspect --format '{{.Id}} {{ json .Config }} {{ . }}'`,
+		},
+		{
+			name:  "missing end",
+			input: "{{ if .gpu }}gpu",
+			err:   "missing {{ end }}",
+		},
+		{
+			name:  "unexpected end",
+			input: "gpu{{ end }}",
+			err:   "unexpected {{ end }}",
+		},
+		{
+			name:  "else after else",
+			input: "{{ if .a }}a{{ else }}b{{ else }}c{{ end }}",
+			err:   "after {{ else }}",
+		},
+		{
+			name:   "range undefined",
+			input:  "{{ range .PKGS }}{{ . }}{{ end }}",
+			output: "{{ range .PKGS }}{{ . }}{{ end }}",
+		},
+		{
+			name:   "foreign with",
+			input:  "{{ with .Config }}{{ .Env }}{{ end }}",
+			output: "{{ with .Config }}{{ .Env }}{{ end }}",
+		},
+		{
+			name:   "foreign if",
+			input:  "{{ if .status.ready }}y{{ else }}n{{ end }}",
+			output: "{{ if .status.ready }}y{{ else }}n{{ end }}",
+		},
+		{
+			name:   "foreign range",
+			input:  "{{ range .Items }}{{ .Name }}{{ end }}",
+			output: "{{ range .Items }}{{ .Name }}{{ end }}",
+		},
+		{
+			name:         "foreign block lines",
+			input:        "%post\n{{- range .Items }}\n{{ if .gpu }}\n{{ VER }}\n{{ end }}\n{{ end }}\n",
+			output:       "%post\n{{- range .Items }}\n1.0\n{{ end }}\n",
+			argsMap:      map[string]string{"gpu": "yes", "VER": "1.0"},
+			consumedArgs: []string{"gpu", "VER"},
+		},
+		{
+			name:  "foreign else if",
+			input: "{{ if .a }}a{{ else if .b.c }}b{{ end }}",
+			err:   "invalid {{ else if .b.c }}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var consumedArgs []string
+			reader, err := NewReader(
+				bytes.NewReader([]byte(test.input)),
+				test.argsMap,
+				map[string]string{},
+				&consumedArgs,
+			)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+
+			output, err := io.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, test.output, string(output))
+			assert.ElementsMatch(t, test.consumedArgs, consumedArgs)
+		})
+	}
+}
@@ -0,0 +1,440 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package args
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The template language understood in definition files is deliberately small.
+// Any {{ ... }} action that does not match one of the forms below is left
+// untouched, so that e.g. Go templates passed to tools in %post still work. An
+// if with another condition, a with, or a range over a name that is not a
+// build-arg, opens a block that is left untouched up to its {{ end }}, though
+// build-args in its body are still replaced.
+//
+//	{{ NAME }}                      value of build-arg NAME
+//	{{ NAME | default "value" }}    value of NAME, or "value" if NAME is unset
+//	{{ if COND }}                   conditional block, where COND is one of
+//	{{ else if COND }}                .NAME, not .NAME, eq .NAME "value",
+//	{{ else }}                        ne .NAME "value"
+//	{{ end }}
+//	{{ range .NAME }}               repeat block for each comma-separated
+//	{{ . }}                           element of NAME, available as {{ . }}
+//	{{ end }}
+var (
+	actionRegexp  = regexp.MustCompile(`{{([^{}]*)}}`)
+	varRegexp     = regexp.MustCompile(`^(\w+)$`)
+	defaultRegexp = regexp.MustCompile(`^(\w+)\s*\|\s*default\s+("(?:[^"\\]|\\.)*"|\S+)$`)
+	ifRegexp      = regexp.MustCompile(`^(else\s+)?if\s+(.+)$`)
+	rangeRegexp   = regexp.MustCompile(`^range\s+\.?(\w+)$`)
+	condRegexp    = regexp.MustCompile(`^(?:(not)\s+\.?(\w+)|(eq|ne)\s+\.?(\w+)\s+("(?:[^"\\]|\\.)*"|\S+)|\.?(\w+))$`)
+	// blockRegexp matches Go template actions that open a block closed by
+	// {{ end }}, with optional trim markers.
+	blockRegexp = regexp.MustCompile(`^(?:-\s+)?(if|range|with|block|define)\b`)
+	// elseRegexp and endRegexp match Go template else and end actions, with
+	// optional trim markers.
+	elseRegexp = regexp.MustCompile(`^(?:-\s+)?else\b`)
+	endRegexp  = regexp.MustCompile(`^(?:-\s+)?end(?:\s+-)?$`)
+)
+
+type tokenKind int
+
+const (
+	tokText tokenKind = iota
+	tokVar
+	tokDot
+	tokIf
+	tokElseIf
+	tokElse
+	tokEnd
+	tokRange
+	// tokBlock opens a block of another template language, that is kept
+	// as is.
+	tokBlock
+)
+
+// token is a lexical element of a definition file template.
+type token struct {
+	kind tokenKind
+	// text is the literal text of a tokText, or the source of an action.
+	text string
+	// raw is the source consumed by an action, which may be its whole line.
+	raw string
+	// foreign is set for an else action that is not part of the template
+	// language, which may only appear in a tokBlock.
+	foreign bool
+	name    string
+	// def is the default value of a tokVar, if any.
+	def  *string
+	cond *condition
+}
+
+// condition is the condition of an if or else if action.
+type condition struct {
+	op    string
+	name  string
+	value string
+}
+
+// node is an element of the parsed template tree.
+type node struct {
+	tok token
+	// branches holds the conditions and bodies of an if node, and the
+	// body of a range node.
+	branches []branch
+}
+
+type branch struct {
+	cond *condition
+	body []node
+}
+
+// templateArgs resolves build-arg values for template evaluation.
+type templateArgs struct {
+	buildArgsMap   map[string]string
+	defaultArgsMap map[string]string
+	consumed       map[string]bool
+}
+
+// defined returns whether name is a build-arg, without consuming it.
+func (a *templateArgs) defined(name string) bool {
+	_, ok := a.buildArgsMap[name]
+	if !ok {
+		_, ok = a.defaultArgsMap[name]
+	}
+	return ok
+}
+
+func (a *templateArgs) lookup(name string) (string, bool) {
+	a.consumed[name] = true
+	if val, ok := a.buildArgsMap[name]; ok {
+		return val, true
+	}
+	val, ok := a.defaultArgsMap[name]
+	return val, ok
+}
+
+// renderTemplate evaluates the definition file template src.
+func renderTemplate(src []byte, a *templateArgs) ([]byte, error) {
+	toks, err := lexTemplate(src, a)
+	if err != nil {
+		return nil, err
+	}
+	nodes, rest, err := parseTemplate(toks)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected {{ %s }} in definition file", keyword(rest[0].kind))
+	}
+
+	// Record every argument referenced in the template as consumed, even in
+	// branches that are not taken, so that they are not reported as unused.
+	markConsumed(nodes, a)
+
+	var buf bytes.Buffer
+	if err := execTemplate(&buf, nodes, a, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// lexTemplate splits src into text and action tokens. An if, else, end or
+// range action that is alone on its line consumes the whole line, so that it
+// does not leave a blank line behind. A range is part of the template language
+// only if its name is a build-arg in a.
+func lexTemplate(src []byte, a *templateArgs) ([]token, error) {
+	var toks []token
+	i := 0
+	for _, m := range actionRegexp.FindAllSubmatchIndex(src, -1) {
+		tok, ok, err := parseAction(string(bytes.TrimSpace(src[m[2]:m[3]])), a)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		tok.text = string(src[m[0]:m[1]])
+
+		start, end := m[0], m[1]
+		if tok.kind != tokVar && tok.kind != tokDot && tok.kind != tokBlock && !tok.foreign {
+			lineStart := bytes.LastIndexByte(src[:start], '\n') + 1
+			lineEnd := bytes.IndexByte(src[end:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(src)
+			} else {
+				lineEnd += end + 1
+			}
+			if i <= lineStart && isBlank(src[lineStart:start]) && isBlank(src[end:lineEnd]) {
+				start, end = lineStart, lineEnd
+			}
+		}
+
+		if start > i {
+			toks = append(toks, token{kind: tokText, text: string(src[i:start])})
+		}
+		tok.raw = string(src[start:end])
+		toks = append(toks, tok)
+		i = end
+	}
+	if i < len(src) {
+		toks = append(toks, token{kind: tokText, text: string(src[i:])})
+	}
+	return toks, nil
+}
+
+// parseAction parses the content of a {{ ... }} action. It returns false if
+// the action is not part of the template language, and must be kept as is.
+func parseAction(action string, a *templateArgs) (token, bool, error) {
+	switch action {
+	case ".":
+		return token{kind: tokDot}, true, nil
+	case "else":
+		return token{kind: tokElse}, true, nil
+	case "end":
+		return token{kind: tokEnd}, true, nil
+	}
+
+	if m := varRegexp.FindStringSubmatch(action); m != nil {
+		return token{kind: tokVar, name: m[1]}, true, nil
+	}
+	if m := defaultRegexp.FindStringSubmatch(action); m != nil {
+		def, err := unquote(m[2])
+		if err != nil {
+			return token{}, false, err
+		}
+		return token{kind: tokVar, name: m[1], def: &def}, true, nil
+	}
+	if m := rangeRegexp.FindStringSubmatch(action); m != nil && a.defined(m[1]) {
+		return token{kind: tokRange, name: m[1]}, true, nil
+	}
+	if m := ifRegexp.FindStringSubmatch(action); m != nil && condRegexp.MatchString(m[2]) {
+		c := condRegexp.FindStringSubmatch(m[2])
+		kind := tokIf
+		if m[1] != "" {
+			kind = tokElseIf
+		}
+		cond := &condition{op: "", name: c[6]}
+		switch {
+		case c[1] != "":
+			cond = &condition{op: "not", name: c[2]}
+		case c[3] != "":
+			value, err := unquote(c[5])
+			if err != nil {
+				return token{}, false, err
+			}
+			cond = &condition{op: c[3], name: c[4], value: value}
+		}
+		return token{kind: kind, cond: cond}, true, nil
+	}
+
+	// Blocks of other template languages are kept as is, with their else
+	// and end actions.
+	switch {
+	case blockRegexp.MatchString(action):
+		return token{kind: tokBlock}, true, nil
+	case elseRegexp.MatchString(action):
+		return token{kind: tokElse, foreign: true}, true, nil
+	case endRegexp.MatchString(action):
+		return token{kind: tokEnd}, true, nil
+	}
+	return token{}, false, nil
+}
+
+// parseTemplate builds the template tree from toks. It stops at an else,
+// else if or end token, which is returned with the remaining tokens.
+func parseTemplate(toks []token) ([]node, []token, error) {
+	var nodes []node
+	for len(toks) > 0 {
+		tok := toks[0]
+		switch tok.kind {
+		case tokElseIf, tokElse, tokEnd:
+			return nodes, toks, nil
+		case tokIf:
+			n := node{tok: tok}
+			cond := tok.cond
+			rest := toks[1:]
+			for {
+				body, r, err := parseTemplate(rest)
+				if err != nil {
+					return nil, nil, err
+				}
+				n.branches = append(n.branches, branch{cond: cond, body: body})
+				if len(r) == 0 {
+					return nil, nil, fmt.Errorf("missing {{ end }} for {{ if }} in definition file")
+				}
+				rest = r[1:]
+				if r[0].kind == tokEnd {
+					break
+				}
+				if r[0].foreign {
+					return nil, nil, fmt.Errorf("invalid {{ %s }} in {{ if }} in definition file", strings.TrimSpace(r[0].text[2:len(r[0].text)-2]))
+				}
+				if cond == nil {
+					return nil, nil, fmt.Errorf("unexpected {{ %s }} after {{ else }} in definition file", keyword(r[0].kind))
+				}
+				cond = r[0].cond
+			}
+			nodes = append(nodes, n)
+			toks = rest
+		case tokRange:
+			body, r, err := parseTemplate(toks[1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(r) == 0 || r[0].kind != tokEnd {
+				return nil, nil, fmt.Errorf("missing {{ end }} for {{ range }} in definition file")
+			}
+			nodes = append(nodes, node{tok: tok, branches: []branch{{body: body}}})
+			toks = r[1:]
+		case tokBlock:
+			// The actions delimiting the block are kept as text, around
+			// the evaluation of the body.
+			nodes = append(nodes, node{tok: token{kind: tokText, text: tok.raw}})
+			rest := toks[1:]
+			for {
+				body, r, err := parseTemplate(rest)
+				if err != nil {
+					return nil, nil, err
+				}
+				nodes = append(nodes, body...)
+				if len(r) == 0 {
+					return nil, nil, fmt.Errorf("missing {{ end }} for %s in definition file", tok.text)
+				}
+				nodes = append(nodes, node{tok: token{kind: tokText, text: r[0].raw}})
+				rest = r[1:]
+				if r[0].kind == tokEnd {
+					break
+				}
+			}
+			toks = rest
+		default:
+			nodes = append(nodes, node{tok: tok})
+			toks = toks[1:]
+		}
+	}
+	return nodes, nil, nil
+}
+
+func markConsumed(nodes []node, a *templateArgs) {
+	for _, n := range nodes {
+		if n.tok.kind == tokVar || n.tok.kind == tokRange {
+			a.consumed[n.tok.name] = true
+		}
+		for _, b := range n.branches {
+			if b.cond != nil {
+				a.consumed[b.cond.name] = true
+			}
+			markConsumed(b.body, a)
+		}
+	}
+}
+
+// execTemplate writes the evaluation of nodes to buf. dot holds the current
+// elements of the enclosing range actions, innermost last.
+func execTemplate(buf *bytes.Buffer, nodes []node, a *templateArgs, dot []string) error {
+	for _, n := range nodes {
+		switch n.tok.kind {
+		case tokText:
+			buf.WriteString(n.tok.text)
+		case tokDot:
+			if len(dot) == 0 {
+				// Outside of a range, {{ . }} is not ours to evaluate.
+				buf.WriteString(n.tok.text)
+				continue
+			}
+			buf.WriteString(dot[len(dot)-1])
+		case tokVar:
+			val, ok := a.lookup(n.tok.name)
+			if !ok && n.tok.def != nil {
+				val, ok = *n.tok.def, true
+			}
+			if !ok {
+				return fmt.Errorf("build var %s is not defined through either --build-arg (--build-arg-file) or 'arguments' section", n.tok.name)
+			}
+			buf.WriteString(val)
+		case tokIf:
+			for _, b := range n.branches {
+				if b.cond == nil || b.cond.eval(a) {
+					if err := execTemplate(buf, b.body, a, dot); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		case tokRange:
+			val, ok := a.lookup(n.tok.name)
+			if !ok {
+				return fmt.Errorf("build var %s is not defined through either --build-arg (--build-arg-file) or 'arguments' section", n.tok.name)
+			}
+			for _, elem := range strings.Split(val, ",") {
+				if elem = strings.TrimSpace(elem); elem == "" {
+					continue
+				}
+				if err := execTemplate(buf, n.branches[0].body, a, append(dot, elem)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// eval evaluates the condition. An undefined argument is false, as is an
+// argument set to an empty string, 0, false, no or off.
+func (c *condition) eval(a *templateArgs) bool {
+	val, ok := a.lookup(c.name)
+	switch c.op {
+	case "eq":
+		return ok && val == c.value
+	case "ne":
+		return !ok || val != c.value
+	case "not":
+		return !ok || !isTrue(val)
+	default:
+		return ok && isTrue(val)
+	}
+}
+
+func isTrue(val string) bool {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "", "0", "false", "no", "off":
+		return false
+	}
+	return true
+}
+
+func isBlank(b []byte) bool {
+	return len(bytes.TrimSpace(b)) == 0
+}
+
+func unquote(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	v, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s in definition file: %w", s, err)
+	}
+	return v, nil
+}
+
+func keyword(k tokenKind) string {
+	switch k {
+	case tokElseIf:
+		return "else if"
+	case tokElse:
+		return "else"
+	case tokEnd:
+		return "end"
+	}
+	return ""
+}
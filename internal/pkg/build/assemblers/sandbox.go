@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2023, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,9 +6,12 @@
 package assemblers
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/dedupe"
 	"github.com/sylabs/singularity/v4/pkg/build/types"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
 	"github.com/sylabs/singularity/v4/pkg/util/archive"
@@ -17,6 +20,9 @@ import (
 // SandboxAssembler assembles a sandbox image.
 type SandboxAssembler struct {
 	Copy bool
+	// Store, if set, is the content store that files in the sandbox are
+	// deduplicated through.
+	Store *dedupe.Store
 }
 
 // Assemble creates a Sandbox image from a Bundle.
@@ -43,5 +49,19 @@ func (a *SandboxAssembler) Assemble(b *types.Bundle, path string) (err error) {
 		}
 	}
 
+	if a.Store != nil {
+		sylog.Infof("Deduplicating sandbox files...")
+		stats, err := a.Store.Dedupe(path)
+		if errors.Is(err, dedupe.ErrReflinkUnsupported) {
+			sylog.Infof("Sandbox files not deduplicated, as the filesystem does not support reflinks")
+			return nil
+		}
+		if err != nil {
+			sylog.Warningf("Could not deduplicate sandbox: %v", err)
+			return nil
+		}
+		sylog.Infof("Deduplicated %d files, saving %s", stats.Files, fs.FindSize(stats.Saved))
+	}
+
 	return nil
 }
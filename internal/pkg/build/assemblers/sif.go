@@ -101,9 +101,13 @@ func createSIF(path string, b *types.Bundle, squashfile string, encOpts *encrypt
 			if err != nil {
 				return err
 			}
+			mt, err := cryptkey.MessageType(data)
+			if err != nil {
+				return err
+			}
 			part, err := sif.NewDescriptorInput(sif.DataCryptoMessage, bytes.NewReader(data),
 				sif.OptLinkedID(syspartID),
-				sif.OptCryptoMessageMetadata(sif.FormatPEM, sif.MessageRSAOAEP),
+				sif.OptCryptoMessageMetadata(sif.FormatPEM, mt),
 			)
 			if err != nil {
 				return err
@@ -23,6 +23,7 @@ import (
 	"github.com/sylabs/singularity/v4/pkg/util/fs/proc"
 	"github.com/sylabs/singularity/v4/pkg/util/singularityconf"
 
+	"github.com/sylabs/singularity/v4/internal/pkg/attest"
 	"github.com/sylabs/singularity/v4/internal/pkg/build/apps"
 	"github.com/sylabs/singularity/v4/internal/pkg/build/args"
 	"github.com/sylabs/singularity/v4/internal/pkg/build/assemblers"
@@ -46,6 +47,14 @@ type Build struct {
 	stages []stage
 	// Conf contains cross stage build configuration.
 	Conf Config
+	// failedStage and failedStep record the progress of the build, so that
+	// they identify where a failed build stopped.
+	failedStage int
+	failedStep  string
+	// kept is the number of stages whose bundles are kept after a failure.
+	// The bundles of a resumed build are kept until it succeeds, so that it
+	// can be resumed again.
+	kept int
 }
 
 // Config defines how build is executed, including things like where final image is written.
@@ -59,6 +68,15 @@ type Config struct {
 	NoCleanUp bool
 	// Opts for bundles.
 	Opts types.Options
+	// KeepFailed keeps the bundles of a failed build, so that it can be
+	// inspected, and resumed.
+	KeepFailed bool
+	// ShellOnFailure runs an interactive shell in the rootfs of the failed
+	// stage when the build fails.
+	ShellOnFailure bool
+	// Resume is the directory holding a failed build, kept with KeepFailed,
+	// to resume.
+	Resume string
 }
 
 // NewBuild creates a new Build struct from a spec (URI, definition file, etc...).
@@ -105,6 +123,21 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 
 	lastStageIndex := len(defs) - 1
 
+	// re-open the bundles of a failed build that is resumed
+	var resumed []*types.Bundle
+	var resumeFrom []int
+	if conf.Resume != "" {
+		state, err := readResumeState(conf.Resume)
+		if err != nil {
+			return nil, err
+		}
+		resumed, resumeFrom, err = resumeBundles(state, defs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resume build: %v", err)
+		}
+		sylog.Infof("Resuming build from the %s step of stage %d", state.Step, state.Stage+1)
+	}
+
 	// create stages
 	for i, d := range defs {
 		// verify every definition has a header if there are multiple stages
@@ -116,23 +149,41 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 		if conf.Format == "sandbox" {
 			rootfsParent = filepath.Dir(conf.Dest)
 		}
-		parentPath, err := os.MkdirTemp(rootfsParent, "build-temp-")
-		if err != nil {
-			return nil, fmt.Errorf("failed to create build parent dir: %w", err)
-		}
 
 		var s stage
-		if conf.Opts.EncryptionKeyInfo != nil {
-			s.b, err = types.NewEncryptedBundle(parentPath, conf.Opts.TmpDir, conf.Opts.EncryptionKeyInfo)
+		var parentPath string
+		if i < len(resumed) {
+			s.b = resumed[i]
+			s.resumeFrom = resumeFrom[i]
+			parentPath = filepath.Dir(s.b.RootfsPath)
 		} else {
-			s.b, err = types.NewBundle(parentPath, conf.Opts.TmpDir)
-		}
-		if err != nil {
-			return nil, err
+			parentPath, err = os.MkdirTemp(rootfsParent, "build-temp-")
+			if err != nil {
+				return nil, fmt.Errorf("failed to create build parent dir: %w", err)
+			}
+
+			if conf.Opts.EncryptionKeyInfo != nil {
+				s.b, err = types.NewEncryptedBundle(parentPath, conf.Opts.TmpDir, conf.Opts.EncryptionKeyInfo)
+			} else {
+				s.b, err = types.NewBundle(parentPath, conf.Opts.TmpDir)
+			}
+			if err != nil {
+				return nil, err
+			}
 		}
 		s.name = d.Header["stage"]
 		s.b.Recipe = d
 
+		// ensure that secret values never reach the definition stored in the image
+		if len(conf.Opts.Secrets) > 0 {
+			if s.b.Recipe.Raw, err = redactSecrets(d.Raw, conf.Opts.Secrets); err != nil {
+				return nil, err
+			}
+			if s.b.Recipe.FullRaw, err = redactSecrets(d.FullRaw, conf.Opts.Secrets); err != nil {
+				return nil, err
+			}
+		}
+
 		if conf.Format == "sandbox" && lastStageIndex == i {
 			// rootfs path changed during bundle creation it means that chown
 			// is not possible within the temporary rootfs, we will switch to
@@ -183,10 +234,16 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 		b.stages = append(b.stages, s)
 	}
 
+	b.kept = len(resumed)
+
 	// only need an assembler for last stage
 	switch conf.Format {
 	case "sandbox":
-		b.stages[lastStageIndex].a = &assemblers.SandboxAssembler{Copy: sandboxCopy}
+		store, err := conf.Opts.ImgCache.ContentStore()
+		if err != nil {
+			return nil, err
+		}
+		b.stages[lastStageIndex].a = &assemblers.SandboxAssembler{Copy: sandboxCopy, Store: store}
 	case "sif":
 		b.stages[lastStageIndex].a = &assemblers.SIFAssembler{}
 	default:
@@ -207,7 +264,7 @@ func (b Build) cleanUp() {
 		return
 	}
 
-	for _, s := range b.stages {
+	for _, s := range b.stages[b.kept:] {
 		sylog.Debugf("Cleaning up %q and %q", s.b.RootfsPath, s.b.TmpDir)
 		err := s.b.Remove()
 		if err != nil {
@@ -217,7 +274,7 @@ func (b Build) cleanUp() {
 }
 
 // Full runs a standard build from start to finish.
-func (b *Build) Full(ctx context.Context) error {
+func (b *Build) Full(ctx context.Context) (err error) {
 	sylog.Infof("Starting build...")
 
 	// monitor build for termination signal and clean up
@@ -228,8 +285,14 @@ func (b *Build) Full(ctx context.Context) error {
 		b.cleanUp()
 		os.Exit(1)
 	}()
-	// clean up build normally
-	defer b.cleanUp()
+	// clean up build normally, keeping what is needed to inspect or resume
+	// a failed build if requested
+	defer func() {
+		if err != nil && (b.Conf.KeepFailed || b.Conf.ShellOnFailure) {
+			b.keepFailed()
+		}
+		b.cleanUp()
+	}()
 
 	oldumask := syscall.Umask(0o002)
 
@@ -265,37 +328,10 @@ func (b *Build) Full(ctx context.Context) error {
 
 	// build each stage one after the other
 	for i, stage := range b.stages {
-		if err := stage.runHostScript("pre", stage.b.Recipe.BuildData.Pre); err != nil {
-			return err
-		}
-
-		// only update last stage if specified
-		update := stage.b.Opts.Update && !stage.b.Opts.Force && i == len(b.stages)-1
-		if update {
-			// updating, extract dest container to bundle
-			sylog.Infof("Building into existing container: %s", b.Conf.Dest)
-			p, err := sources.GetLocalPacker(ctx, b.Conf.Dest, stage.b)
-			if err != nil {
-				return err
-			}
-
-			_, err = p.Pack(ctx)
-			if err != nil {
+		if b.progress(i, stepBootstrap) {
+			if err := b.bootstrap(ctx, i); err != nil {
 				return err
 			}
-		} else {
-			// regular build or force, start build from scratch
-			if b.Conf.Opts.ImgCache == nil {
-				return fmt.Errorf("undefined image cache")
-			}
-			if err := stage.c.Get(ctx, stage.b); err != nil {
-				return fmt.Errorf("conveyor failed to get: %v", err)
-			}
-
-			_, err := stage.c.Pack(ctx)
-			if err != nil {
-				return fmt.Errorf("packer failed to pack: %v", err)
-			}
 		}
 
 		// create apps in bundle
@@ -304,31 +340,34 @@ func (b *Build) Full(ctx context.Context) error {
 			a.HandleSection(k, v)
 		}
 
-		a.HandleBundle(stage.b)
-		appPost, err := a.HandlePost(stage.b)
-		if err != nil {
-			return fmt.Errorf("unable to get app post information: %v", err)
-		}
-		stage.b.Recipe.BuildData.Post.Script += appPost
+		if b.progress(i, stepFiles) {
+			a.HandleBundle(stage.b)
 
-		// copy potential files from previous stage
-		if stage.b.RunSection("files") {
-			if err := stage.copyFilesFrom(b); err != nil {
-				return fmt.Errorf("unable to copy files from stage to container fs: %v", err)
+			// copy potential files from previous stage
+			if stage.b.RunSection("files") {
+				if err := stage.copyFilesFrom(b); err != nil {
+					return fmt.Errorf("unable to copy files from stage to container fs: %v", err)
+				}
 			}
-		}
 
-		if err := stage.runHostScript("setup", stage.b.Recipe.BuildData.Setup); err != nil {
-			return err
-		}
+			if err := stage.runHostScript("setup", stage.b.Recipe.BuildData.Setup); err != nil {
+				return err
+			}
 
-		// copy files from host
-		if stage.b.RunSection("files") {
-			if err := stage.copyFiles(); err != nil {
-				return fmt.Errorf("unable to copy files from host to container fs: %v", err)
+			// copy files from host
+			if stage.b.RunSection("files") {
+				if err := stage.copyFiles(); err != nil {
+					return fmt.Errorf("unable to copy files from host to container fs: %v", err)
+				}
 			}
 		}
 
+		appPost, err := a.HandlePost(stage.b)
+		if err != nil {
+			return fmt.Errorf("unable to get app post information: %v", err)
+		}
+		stage.b.Recipe.BuildData.Post.Script += appPost
+
 		// create stage file for /etc/resolv.conf and /etc/hosts
 		sessionResolv, err := createStageFile("/etc/resolv.conf", stage.b, "Name resolution could fail")
 		if err != nil {
@@ -351,29 +390,46 @@ func (b *Build) Full(ctx context.Context) error {
 		}
 		defer os.Remove(configFile)
 
-		if stage.b.Recipe.BuildData.Post.Script != "" {
-			if err := stage.runPostScript(configFile, sessionResolv, sessionHosts); err != nil {
+		// stage build secrets, available to %post and %test sections only
+		secretsDir, cleanupSecrets, err := stage.stageSecrets()
+		if err != nil {
+			return fmt.Errorf("while staging build secrets: %v", err)
+		}
+		defer cleanupSecrets()
+
+		if b.progress(i, stepPost) && stage.b.Recipe.BuildData.Post.Script != "" {
+			if err := stage.runPostScript(configFile, sessionResolv, sessionHosts, secretsDir); err != nil {
 				return fmt.Errorf("while running engine: %v", err)
 			}
 		}
 
-		sylog.Debugf("Inserting Metadata")
-		if err := stage.insertMetadata(); err != nil {
-			return fmt.Errorf("while inserting metadata to bundle: %v", err)
-		}
+		if b.progress(i, stepTest) {
+			sylog.Debugf("Inserting Metadata")
+			if err := stage.insertMetadata(); err != nil {
+				return fmt.Errorf("while inserting metadata to bundle: %v", err)
+			}
 
-		if err := stage.runTestScript(configFile, sessionResolv, sessionHosts); err != nil {
-			return fmt.Errorf("failed to execute %%test script: %v", err)
+			if err := stage.runTestScript(configFile, sessionResolv, sessionHosts, secretsDir); err != nil {
+				return fmt.Errorf("failed to execute %%test script: %v", err)
+			}
 		}
+
+		// remove secrets before the stage can be copied from, or assembled
+		cleanupSecrets()
 	}
 
 	syscall.Umask(oldumask)
 
+	b.progress(len(b.stages)-1, stepAssemble)
 	sylog.Debugf("Calling assembler")
 	if err := b.stages[len(b.stages)-1].Assemble(b.Conf.Dest); err != nil {
 		return err
 	}
 
+	// the resumed build is complete, so its state and bundles are obsolete
+	b.kept = 0
+	b.removeResumeState("")
+
 	sylog.Verbosef("Build complete: %s", b.Conf.Dest)
 	return nil
 }
@@ -391,13 +447,12 @@ func makeDef(spec string) (types.Definition, error) {
 	}
 
 	// default to reading file as definition
-	defFile, err := os.Open(spec)
+	raw, err := parser.ReadDefinitionFile(spec)
 	if err != nil {
-		return types.Definition{}, fmt.Errorf("unable to open file %s: %v", spec, err)
+		return types.Definition{}, fmt.Errorf("unable to read file %s: %v", spec, err)
 	}
-	defer defFile.Close()
 
-	d, err := parser.ParseDefinitionFile(defFile)
+	d, err := parser.ParseDefinitionFile(bytes.NewReader(raw))
 	if err != nil {
 		return types.Definition{}, fmt.Errorf("while parsing definition: %s: %v", spec, err)
 	}
@@ -420,18 +475,29 @@ func MakeAllDefs(spec string, buildArgsMap map[string]string) ([]types.Definitio
 		return []types.Definition{d}, err
 	}
 
-	// default to reading file as definition
-	defFile, err := os.Open(spec)
+	// default to reading file as definition, with any %include directives
+	// resolved so that fragments are subject to build-arg substitution.
+	raw, err := parser.ReadDefinitionFile(spec)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open file %s: %w", spec, err)
+		return nil, fmt.Errorf("unable to read file %s: %w", spec, err)
 	}
-	defer defFile.Close()
 
-	defsPreBuildArgs, err := parser.All(defFile)
-	nDefs := len(defsPreBuildArgs)
+	defs, err := RenderDefs(raw, buildArgsMap)
 	if err != nil {
 		return nil, fmt.Errorf("while parsing definition: %s: %w", spec, err)
 	}
+	return defs, nil
+}
+
+// RenderDefs parses the definition raw, whose %include directives have been
+// resolved, and renders the templates of each of its stages with the build
+// args in buildArgsMap, and the defaults of the stage %arguments section.
+func RenderDefs(raw []byte, buildArgsMap map[string]string) ([]types.Definition, error) {
+	defsPreBuildArgs, err := parser.All(bytes.NewReader(raw))
+	nDefs := len(defsPreBuildArgs)
+	if err != nil {
+		return nil, err
+	}
 
 	revisedDefs := make([]types.Definition, 0, nDefs)
 	var overallConsumedArgs []string
@@ -477,6 +543,42 @@ func MakeAllDefs(spec string, buildArgsMap map[string]string) ([]types.Definitio
 	return revisedDefs, nil
 }
 
+// bootstrap runs the %pre section of stage i, then bootstraps its rootfs, or
+// extracts the existing container into it when updating the last stage.
+func (b *Build) bootstrap(ctx context.Context, i int) error {
+	stage := b.stages[i]
+	if err := stage.runHostScript("pre", stage.b.Recipe.BuildData.Pre); err != nil {
+		return err
+	}
+
+	// only update last stage if specified
+	update := stage.b.Opts.Update && !stage.b.Opts.Force && i == len(b.stages)-1
+	if update {
+		// updating, extract dest container to bundle
+		sylog.Infof("Building into existing container: %s", b.Conf.Dest)
+		p, err := sources.GetLocalPacker(ctx, b.Conf.Dest, stage.b)
+		if err != nil {
+			return err
+		}
+
+		_, err = p.Pack(ctx)
+		return err
+	}
+
+	// regular build or force, start build from scratch
+	if b.Conf.Opts.ImgCache == nil {
+		return fmt.Errorf("undefined image cache")
+	}
+	if err := stage.c.Get(ctx, stage.b); err != nil {
+		return fmt.Errorf("conveyor failed to get: %v", err)
+	}
+
+	if _, err := stage.c.Pack(ctx); err != nil {
+		return fmt.Errorf("packer failed to pack: %v", err)
+	}
+	return nil
+}
+
 func (b *Build) findStageIndex(name string) (int, error) {
 	for i, s := range b.stages {
 		if name == s.name {
@@ -486,3 +588,38 @@ func (b *Build) findStageIndex(name string) (int, error) {
 
 	return -1, fmt.Errorf("stage %s was not found", name)
 }
+
+// Sources returns the bootstrap sources of the stages of the build, for
+// provenance. The digest of a source is recorded where it was resolved by the
+// conveyor.
+func (b *Build) Sources() []attest.ResourceDescriptor {
+	rds := make([]attest.ResourceDescriptor, 0, len(b.stages))
+	for _, s := range b.stages {
+		rd := attest.ResourceDescriptor{URI: sourceURI(s.b.Recipe.Header)}
+		if alg, hex, ok := strings.Cut(s.b.SourceDigest, ":"); ok {
+			rd.Digest = map[string]string{alg: hex}
+		}
+		rds = append(rds, rd)
+	}
+	return rds
+}
+
+// sourceURI returns the URI of the bootstrap source specified by the header of
+// a definition.
+func sourceURI(h map[string]string) string {
+	from := h["from"]
+	// Package manager bootstraps are identified by their mirror.
+	if from == "" && h["mirrorurl"] != "" {
+		return h["mirrorurl"]
+	}
+	switch h["bootstrap"] {
+	case "docker", "docker-daemon", "docker-archive", "oci", "oci-archive":
+		if h["namespace"] != "" {
+			from = h["namespace"] + "/" + from
+		}
+		if h["registry"] != "" {
+			from = h["registry"] + "/" + from
+		}
+	}
+	return h["bootstrap"] + "://" + from
+}
@@ -44,3 +44,73 @@ func TestProcessDefsMultipleDef(t *testing.T) {
 	rt = strings.Contains(d[1].BuildData.Files[0].Files[0].Src, "/root/hello")
 	assert.Equal(t, rt, true)
 }
+
+func TestProcessDefsInclude(t *testing.T) {
+	d, err := MakeAllDefs(
+		filepath.Join("..", "..", "..", "test", "build-args", "include-unit-test.def"),
+		map[string]string{
+			"OS_VER": "1",
+			"PROXY":  "http://proxy:3128",
+		},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, d[0].Header["from"], "alpine:1")
+	assert.Equal(t, strings.TrimSpace(d[0].Environment.Script), "export OS_VER=1")
+	rt := strings.Contains(d[0].BuildData.Post.Script, "export http_proxy=http://proxy:3128")
+	assert.Equal(t, rt, true)
+	rt = strings.Contains(string(d[0].FullRaw), "%include")
+	assert.Equal(t, rt, false)
+}
+
+func TestProcessDefsTemplate(t *testing.T) {
+	defFile := filepath.Join("..", "..", "..", "test", "build-args", "template-unit-test.def")
+
+	d, err := MakeAllDefs(defFile, map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, d[0].Header["from"], "ubuntu:22.04")
+	assert.Equal(t, strings.TrimSpace(d[0].BuildData.Post.Script), "apt-get install -y curl\n    apt-get install -y git")
+
+	d, err = MakeAllDefs(defFile, map[string]string{
+		"GPU":  "true",
+		"PKGS": "python3",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, d[0].Header["from"], "nvidia/cuda:12.4.1-runtime-ubuntu22.04")
+	assert.Equal(t, strings.TrimSpace(d[0].BuildData.Post.Script), "apt-get install -y python3")
+}
+
+func TestSourceURI(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   string
+	}{
+		{
+			name:   "Docker",
+			header: map[string]string{"bootstrap": "docker", "from": "alpine:3"},
+			want:   "docker://alpine:3",
+		},
+		{
+			name:   "DockerRegistry",
+			header: map[string]string{"bootstrap": "docker", "from": "alpine:3", "registry": "quay.io", "namespace": "sylabs"},
+			want:   "docker://quay.io/sylabs/alpine:3",
+		},
+		{
+			name:   "Library",
+			header: map[string]string{"bootstrap": "library", "from": "alpine:3", "registry": "ignored"},
+			want:   "library://alpine:3",
+		},
+		{
+			name:   "Yum",
+			header: map[string]string{"bootstrap": "yum", "mirrorurl": "http://mirror.example.com/%{OSVERSION}"},
+			want:   "http://mirror.example.com/%{OSVERSION}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sourceURI(tt.header))
+		})
+	}
+}
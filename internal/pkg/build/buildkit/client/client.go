@@ -35,6 +35,8 @@ import (
 
 	"github.com/blang/semver/v4"
 	"github.com/google/go-containerregistry/pkg/authn"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	moby_buildkit_v1 "github.com/moby/buildkit/api/services/control"
 	"github.com/moby/buildkit/client"
 	dockerfile "github.com/moby/buildkit/frontend/dockerfile/builder"
@@ -45,6 +47,7 @@ import (
 	bkauth "github.com/sylabs/singularity/v4/internal/pkg/build/buildkit/auth"
 	"github.com/sylabs/singularity/v4/internal/pkg/client/ocisif"
 	"github.com/sylabs/singularity/v4/internal/pkg/ociplatform"
+	ocisifimg "github.com/sylabs/singularity/v4/internal/pkg/ocisif"
 	"github.com/sylabs/singularity/v4/internal/pkg/remote/credential/ociauth"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/bin"
 	fsoverlay "github.com/sylabs/singularity/v4/internal/pkg/util/fs/overlay"
@@ -76,8 +79,15 @@ type Opts struct {
 	BuildVarArgFile string
 	// Requested build architecture
 	ReqArch string
+	// Requested build platforms, for a multi-platform build writing one
+	// OCI-SIF per platform.
+	ReqPlatforms []string
+	// Write all ReqPlatforms images into a single multi-platform OCI-SIF?
+	SingleFile bool
 	// Keep individual layers when creating OCI-SIF?
 	KeepLayers bool
+	// Filesystem format of layers when creating OCI-SIF.
+	LayerFormat string
 	// Context dir in which to perform build (relevant for ADD statements, etc.)
 	ContextDir string
 	// Disable buildkitd's internal caching mechanism
@@ -99,6 +109,147 @@ func Run(ctx context.Context, opts *Opts, dest, spec string) error {
 		defer bkCleanup()
 	}
 
+	if len(opts.ReqPlatforms) > 0 {
+		return buildPlatforms(ctx, opts, listenSocket, dest, spec)
+	}
+
+	pullOpts := ocisif.PullOptions{
+		KeepLayers:  opts.KeepLayers,
+		LayerFormat: opts.LayerFormat,
+	}
+	if opts.ReqArch != "" {
+		platform, err := ociplatform.PlatformFromArch(opts.ReqArch)
+		if err != nil {
+			return fmt.Errorf("could not determine OCI platform from architecture %q: %w", opts.ReqArch, err)
+		}
+		pullOpts.Platform = *platform
+	}
+	return buildOCISIF(ctx, opts, listenSocket, dest, spec, "", pullOpts)
+}
+
+// buildPlatforms runs one build per requested platform, writing the image for
+// each platform to the OCI-SIF file returned by ocisifimg.PlatformImagePath.
+func buildPlatforms(ctx context.Context, opts *Opts, listenSocket, dest, spec string) error {
+	platforms := make([]ggcrv1.Platform, 0, len(opts.ReqPlatforms))
+	for _, ps := range opts.ReqPlatforms {
+		p, err := ociplatform.PlatformFromString(ps)
+		if err != nil {
+			return fmt.Errorf("invalid platform %q: %w", ps, err)
+		}
+		platforms = append(platforms, *p)
+	}
+
+	if err := checkWorkerPlatforms(ctx, listenSocket, platforms); err != nil {
+		return err
+	}
+
+	if opts.SingleFile {
+		return buildPlatformsSIF(ctx, opts, listenSocket, dest, spec, platforms)
+	}
+
+	for _, p := range platforms {
+		platformDest := ocisifimg.PlatformImagePath(dest, p)
+		sylog.Infof("Building %s image %s", p.String(), platformDest)
+		pullOpts := ocisif.PullOptions{
+			KeepLayers:  opts.KeepLayers,
+			LayerFormat: opts.LayerFormat,
+			Platform:    p,
+		}
+		if err := buildOCISIF(ctx, opts, listenSocket, platformDest, spec, p.String(), pullOpts); err != nil {
+			return fmt.Errorf("while building %s image: %w", p.String(), err)
+		}
+	}
+	return nil
+}
+
+// buildPlatformsSIF runs one build per platform, and writes the images for all
+// platforms into the single multi-platform OCI-SIF file dest.
+func buildPlatformsSIF(ctx context.Context, opts *Opts, listenSocket, dest, spec string, platforms []ggcrv1.Platform) error {
+	workDir, err := os.MkdirTemp("", "singularity-buildkit-")
+	if err != nil {
+		return fmt.Errorf("while creating temporary directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			sylog.Errorf("While trying to remove temporary directory (%s): %v", workDir, err)
+		}
+	}()
+
+	imgs := make([]ggcrv1.Image, 0, len(platforms))
+	for i, p := range platforms {
+		sylog.Infof("Building %s image", p.String())
+		tarFile, err := os.Create(filepath.Join(workDir, fmt.Sprintf("image-%d.tar", i)))
+		if err != nil {
+			return fmt.Errorf("while creating tar file: %w", err)
+		}
+		err = buildImage(ctx, opts, tarFile, listenSocket, spec, p.String(), false)
+		tarFile.Close()
+		if err != nil {
+			return fmt.Errorf("while building %s image from dockerfile: %w", p.String(), err)
+		}
+
+		img, err := tarball.ImageFromPath(tarFile.Name(), nil)
+		if err != nil {
+			return fmt.Errorf("while reading %s image: %w", p.String(), err)
+		}
+		if err := ociplatform.CheckImagePlatform(p, img); err != nil {
+			return err
+		}
+		imgs = append(imgs, img)
+	}
+
+	iwOpts := []ocisifimg.ImageWriterOpt{
+		ocisifimg.WithSquashFSLayers(true),
+		ocisifimg.WithLayerFormat(opts.LayerFormat),
+		ocisifimg.WithPlatformImages(imgs[1:]...),
+	}
+	if !opts.KeepLayers {
+		iwOpts = append(iwOpts, ocisifimg.WithSquash(true))
+	}
+	w, err := ocisifimg.NewImageWriter(imgs[0], dest, workDir, iwOpts...)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(); err != nil {
+		return fmt.Errorf("while writing multi-platform OCI-SIF: %w", err)
+	}
+	return nil
+}
+
+// checkWorkerPlatforms returns an error if any of platforms is not supported by
+// the workers of the buildkitd daemon at listenSocket.
+func checkWorkerPlatforms(ctx context.Context, listenSocket string, platforms []ggcrv1.Platform) error {
+	c, err := client.New(ctx, listenSocket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	workers, err := c.ListWorkers(ctx)
+	if err != nil {
+		return fmt.Errorf("while listing buildkit workers: %w", err)
+	}
+
+	for _, p := range platforms {
+		supported := false
+		for _, w := range workers {
+			for _, wp := range w.Platforms {
+				wp := ggcrv1.Platform{OS: wp.OS, Architecture: wp.Architecture, Variant: wp.Variant}
+				if wp.Satisfies(p) {
+					supported = true
+				}
+			}
+		}
+		if !supported {
+			return fmt.Errorf("platform %s is not supported by buildkitd (configure QEMU binfmt_misc emulation to build for foreign architectures)", p.String())
+		}
+	}
+	return nil
+}
+
+// buildOCISIF builds spec for platform (the daemon's default platform if
+// empty), and writes the resulting image to the OCI-SIF file dest.
+func buildOCISIF(ctx context.Context, opts *Opts, listenSocket, dest, spec, platform string, pullOpts ocisif.PullOptions) error {
 	tarFile, err := os.CreateTemp("", "singularity-buildkit-tar-")
 	if err != nil {
 		return fmt.Errorf("while creating temporary tar file: %w", err)
@@ -111,22 +262,12 @@ func Run(ctx context.Context, opts *Opts, dest, spec string) error {
 		}
 	}()
 
-	if err := buildImage(ctx, opts, tarFile, listenSocket, spec, false); err != nil {
+	if err := buildImage(ctx, opts, tarFile, listenSocket, spec, platform, false); err != nil {
 		return fmt.Errorf("while building from dockerfile: %w", err)
 	}
 	sylog.Debugf("Saved OCI image as tar: %s", tarFile.Name())
 	tarFile.Close()
 
-	pullOpts := ocisif.PullOptions{
-		KeepLayers: opts.KeepLayers,
-	}
-	if opts.ReqArch != "" {
-		platform, err := ociplatform.PlatformFromArch(opts.ReqArch)
-		if err != nil {
-			return fmt.Errorf("could not determine OCI platform from architecture %q: %w", opts.ReqArch, err)
-		}
-		pullOpts.Platform = *platform
-	}
 	if _, err := ocisif.PullOCISIF(ctx, nil, dest, "docker-archive:"+tarFile.Name(), pullOpts); err != nil {
 		return fmt.Errorf("while converting OCI tar image to OCI-SIF: %w", err)
 	}
@@ -277,7 +418,7 @@ func isBuildkitdRunning(ctx context.Context, bkSocket, reqVersion string) (bool,
 	return true, nil
 }
 
-func buildImage(ctx context.Context, opts *Opts, tarFile *os.File, listenSocket, spec string, clientsideFrontend bool) error {
+func buildImage(ctx context.Context, opts *Opts, tarFile *os.File, listenSocket, spec, platform string, clientsideFrontend bool) error {
 	sylog.SyncLogrusLevel()
 
 	c, err := client.New(ctx, listenSocket)
@@ -286,7 +427,7 @@ func buildImage(ctx context.Context, opts *Opts, tarFile *os.File, listenSocket,
 	}
 
 	pipeR, pipeW := io.Pipe()
-	solveOpt, err := newSolveOpt(ctx, opts, pipeW, spec, clientsideFrontend)
+	solveOpt, err := newSolveOpt(ctx, opts, pipeW, spec, platform, clientsideFrontend)
 	if err != nil {
 		return err
 	}
@@ -342,7 +483,7 @@ func buildImage(ctx context.Context, opts *Opts, tarFile *os.File, listenSocket,
 	return eg.Wait()
 }
 
-func newSolveOpt(_ context.Context, opts *Opts, w io.WriteCloser, spec string, clientsideFrontend bool) (*client.SolveOpt, error) {
+func newSolveOpt(_ context.Context, opts *Opts, w io.WriteCloser, spec, platform string, clientsideFrontend bool) (*client.SolveOpt, error) {
 	switch opts.ContextDir {
 	case "":
 		return nil, fmt.Errorf("please specify build context (e.g. \".\" for the current directory)")
@@ -372,6 +513,10 @@ func newSolveOpt(_ context.Context, opts *Opts, w io.WriteCloser, spec string, c
 		frontendAttrs["no-cache"] = ""
 	}
 
+	if platform != "" {
+		frontendAttrs["platform"] = platform
+	}
+
 	attachable := []session.Attachable{bkauth.NewAuthProvider(opts.AuthConf, ociauth.ChooseAuthFile(opts.ReqAuthFile))}
 
 	buildArgsMap, err := args.ReadBuildArgs(opts.BuildVarArgs, opts.BuildVarArgFile)
@@ -0,0 +1,197 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package buildserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/env"
+)
+
+// ImageRefPrefix prefixes the libraryRef of the status of a build run by a
+// build server, telling the client to download the image from the server.
+const ImageRefPrefix = "build-server://"
+
+// build is a build submitted to the server.
+type build struct {
+	id  string
+	dir string
+	// contextDir holds the extracted build context, and workDir is the
+	// working directory of the client, inside contextDir.
+	contextDir string
+	workDir    string
+	libraryURL string
+	// ctx is canceled to cancel the build.
+	ctx    context.Context
+	cancel context.CancelFunc
+	log    *logBuffer
+
+	mu            sync.Mutex
+	complete      bool
+	imageSize     int64
+	imageChecksum string
+}
+
+// rawBuildInfo is the status of a build, as returned to the client.
+type rawBuildInfo struct {
+	ID            string `json:"id"`
+	IsComplete    bool   `json:"isComplete"`
+	ImageSize     int64  `json:"imageSize,omitempty"`
+	ImageChecksum string `json:"imageChecksum,omitempty"`
+	LibraryRef    string `json:"libraryRef"`
+	LibraryURL    string `json:"libraryURL"`
+}
+
+func (b *build) defPath() string {
+	return filepath.Join(b.dir, "build.def")
+}
+
+func (b *build) imagePath() string {
+	return filepath.Join(b.dir, "image.sif")
+}
+
+func (b *build) status() rawBuildInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return rawBuildInfo{
+		ID:            b.id,
+		IsComplete:    b.complete,
+		ImageSize:     b.imageSize,
+		ImageChecksum: b.imageChecksum,
+		LibraryRef:    ImageRefPrefix + b.id,
+	}
+}
+
+// finish records the end of the build, with the image it produced if err is
+// nil.
+func (b *build) finish(err error) {
+	var size int64
+	var checksum string
+	if err == nil {
+		size, checksum, err = fileDigest(b.imagePath())
+	}
+	if err != nil {
+		fmt.Fprintf(b.log, "Build failed: %v\n", err)
+		size, checksum = 0, ""
+	}
+	b.log.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.complete = true
+	b.imageSize = size
+	b.imageChecksum = checksum
+}
+
+func fileDigest(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, "sha256." + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildCommand returns the command running the native build of b, with
+// --fakeroot. Host files referenced by the definition are resolved in the
+// build context only.
+func buildCommand(ctx context.Context, b *build) *exec.Cmd {
+	args := []string{"build", "--fakeroot", "--context-dir", b.contextDir}
+	if b.libraryURL != "" {
+		args = append(args, "--library", b.libraryURL)
+	}
+	args = append(args, b.imagePath(), b.defPath())
+
+	cmd := exec.CommandContext(ctx, filepath.Join(buildcfg.BINDIR, "singularity"), args...)
+	cmd.Dir = b.workDir
+	cmd.Env = buildEnv()
+	// Cancel the whole build, not only the singularity process.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	return cmd
+}
+
+// buildEnv returns the environment of the server, without any variable that
+// would alter the behavior of the build.
+func buildEnv() []string {
+	envs := make([]string, 0)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, env.SingularityPrefix) {
+			envs = append(envs, e)
+		}
+	}
+	return envs
+}
+
+// logBuffer holds the output of a build, and lets readers follow it.
+type logBuffer struct {
+	mu      sync.Mutex
+	buf     []byte
+	closed  bool
+	changed chan struct{}
+}
+
+func newLogBuffer() *logBuffer {
+	return &logBuffer{changed: make(chan struct{})}
+}
+
+func (l *logBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, os.ErrClosed
+	}
+	l.buf = append(l.buf, p...)
+	l.notify()
+	return len(p), nil
+}
+
+// Close marks the end of the output.
+func (l *logBuffer) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.closed {
+		l.closed = true
+		l.notify()
+	}
+	return nil
+}
+
+func (l *logBuffer) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// next returns the output from offset off, whether the output is complete,
+// and a channel that is closed when more output is available.
+func (l *logBuffer) next(off int) ([]byte, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.buf[off:], l.closed, l.changed
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package buildserver
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var digestRegexp = regexp.MustCompile(`^sha256\.[0-9a-f]{64}$`)
+
+// buildContext is an archive of local files uploaded by a client.
+type buildContext struct {
+	digest string
+	size   int64
+	path   string
+	// uploaded is set once the archive has been uploaded, and verified.
+	uploaded bool
+	// expiry abandons the upload, or removes the uploaded archive, once it
+	// has expired.
+	expiry *time.Timer
+}
+
+// receive writes the archive read from r to the path of the context, checking
+// that it matches the announced size and digest.
+func (c *buildContext) receive(r io.Reader) error {
+	f, err := os.CreateTemp(filepath.Dir(c.path), "upload-")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(r, c.size+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("while receiving build context: %w", err)
+	}
+
+	if n != c.size {
+		return fmt.Errorf("build context size is %d bytes, expected %d", n, c.size)
+	}
+	if digest := "sha256." + hex.EncodeToString(h.Sum(nil)); digest != c.digest {
+		return fmt.Errorf("build context digest is %s, expected %s", digest, c.digest)
+	}
+
+	return os.Rename(tmp, c.path)
+}
+
+// extractContext extracts the build context archive at src into the directory
+// dst. Only regular files and directories are extracted, and every path must
+// be local to dst.
+func extractContext(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("while reading build context: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("while reading build context: %w", err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(h.Name))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("build context entry %q is not a local path", h.Name)
+		}
+		path := filepath.Join(dst, name)
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			if err := extractFile(tr, path, os.FileMode(h.Mode)&0o755|0o600); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("build context entry %q has unsupported type %q", h.Name, h.Typeflag)
+		}
+	}
+}
+
+func extractFile(r io.Reader, path string, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|unix.O_NOFOLLOW, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("while extracting %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+/*
+Package buildserver implements a self-hostable remote build server, run with
+'singularity build-server'. It speaks the protocol of the Sylabs remote Build
+Service, as implemented by the scs-build-client module, so that an unmodified
+'singularity build --builder <URL>' may use it. Definitions are built natively
+on the server, with --fakeroot.
+
+All requests, except uploads of build contexts, must carry an
+'Authorization: Bearer <token>' header, with the token the server is
+configured with. Errors are returned as JSON, in the format of the json-resp module.
+
+Build contexts hold the local files referenced in the %files sections of a
+definition:
+
+	POST   /v1/build-context                 {"size": <bytes>, "digest": "sha256.<hex>"}
+	PUT    <Location>                        gzip compressed tar archive
+	DELETE /v1/build-context/<digest>
+
+A POST returns a Location header, relative to the base URL, where the archive
+is to be uploaded. If the context is already present on the server, no
+Location is returned, and the upload is skipped. The archive holds the files at
+their absolute path on the client, without the leading slash. Only regular
+files and directories are allowed. A context larger than the configured limit
+is refused. An upload Location expires, and an uploaded context is removed,
+once the context has not been announced with a POST for the retention period.
+
+Builds are submitted, followed and downloaded with:
+
+	POST   /v1/build                         build request
+	GET    /v1/build/<id>                    build status
+	GET    /v1/build-ws/<id>                 websocket, build output
+	PUT    /v1/build/<id>/_cancel
+	GET    /v1/build/<id>/image              built SIF image
+
+A build request is a JSON object:
+
+	{
+	  "definitionRaw": "<base64 encoded definition>",
+	  "libraryRef": "",
+	  "libraryURL": "<library used to pull library:// sources>",
+	  "builderRequirements": {"arch": "<GOARCH>"},
+	  "contextDigest": "<digest of an uploaded build context>",
+	  "workingDir": "<working directory of the client>"
+	}
+
+The server does not push images to a library, so libraryRef must be empty. The
+build is run with the working directory set to workingDir, inside the
+extracted build context, and %files sources are resolved inside the build
+context only. The 'localimage', 'oci', 'oci-archive', 'docker-archive' and
+'docker-daemon' bootstraps are rejected, as they would refer to the server
+filesystem. %setup and %pre sections are rejected, as they would run on the
+server host. These checks apply to the definition once its templates are
+rendered, as by the build. %include directives, which the client resolves, are
+rejected, as they would read from the server filesystem.
+
+A POST, and a GET of the build status, return a JSON object:
+
+	{
+	  "id": "<build ID>",
+	  "isComplete": <whether the build has ended>,
+	  "imageSize": <size of the built image, 0 if the build failed>,
+	  "imageChecksum": "sha256.<hex>",
+	  "libraryRef": "build-server://<build ID>",
+	  "libraryURL": ""
+	}
+
+The libraryRef prefix tells the client to download the image from the server,
+instead of a library. Build output is sent over the websocket as text
+messages, starting from the beginning of the build, and the websocket is
+closed with a normal closure once the build has ended. Completed builds, and
+their image, are removed after a retention period.
+*/
+package buildserver
@@ -0,0 +1,607 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package buildserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	jsonresp "github.com/sylabs/json-resp"
+	nativebuild "github.com/sylabs/singularity/v4/internal/pkg/build"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// Config is the configuration of a build server.
+type Config struct {
+	// WorkDir holds the uploaded build contexts, and the builds.
+	WorkDir string
+	// AuthToken must be sent by clients as a bearer token.
+	AuthToken string
+	// MaxBuilds is the number of builds that may run at the same time.
+	// Further builds are queued.
+	MaxBuilds int
+	// Retention is how long a completed build, and its image, are kept. It is
+	// also how long a build context is kept after it was last announced.
+	Retention time.Duration
+	// MaxContextSize is the maximum size of an uploaded build context, in
+	// bytes.
+	MaxContextSize int64
+}
+
+// defaultMaxContextSize is the default maximum size of a build context.
+const defaultMaxContextSize = 1 << 30
+
+// refusedBootstraps are the bootstrap agents that read their source from the
+// server, rather than from a remote registry or library.
+var refusedBootstraps = map[string]bool{
+	"localimage":     true,
+	"oci":            true,
+	"oci-archive":    true,
+	"docker-archive": true,
+	"docker-daemon":  true,
+}
+
+// Server is a remote build server.
+type Server struct {
+	conf Config
+	mux  *http.ServeMux
+	// slots limits the number of running builds.
+	slots chan struct{}
+	// command returns the command running build b.
+	command func(ctx context.Context, b *build) *exec.Cmd
+
+	mu       sync.Mutex
+	contexts map[string]*buildContext
+	uploads  map[string]*buildContext
+	builds   map[string]*build
+}
+
+// buildRequest is a build submitted by a client.
+type buildRequest struct {
+	DefinitionRaw       []byte            `json:"definitionRaw"`
+	LibraryRef          string            `json:"libraryRef"`
+	LibraryURL          string            `json:"libraryURL,omitempty"`
+	BuilderRequirements map[string]string `json:"builderRequirements,omitempty"`
+	ContextDigest       string            `json:"contextDigest,omitempty"`
+	WorkingDir          string            `json:"workingDir,omitempty"`
+}
+
+// New returns a build server, storing build contexts and builds in the
+// WorkDir of conf.
+func New(conf Config) (*Server, error) {
+	if conf.WorkDir == "" {
+		return nil, fmt.Errorf("a work directory is required")
+	}
+	if conf.AuthToken == "" {
+		return nil, fmt.Errorf("an authentication token is required")
+	}
+	if conf.MaxBuilds < 1 {
+		conf.MaxBuilds = 1
+	}
+	if conf.Retention <= 0 {
+		conf.Retention = time.Hour
+	}
+	if conf.MaxContextSize <= 0 {
+		conf.MaxContextSize = defaultMaxContextSize
+	}
+
+	for _, dir := range []string{"contexts", "builds"} {
+		if err := os.MkdirAll(filepath.Join(conf.WorkDir, dir), 0o700); err != nil {
+			return nil, fmt.Errorf("while creating work directory: %w", err)
+		}
+	}
+
+	s := &Server{
+		conf:     conf,
+		mux:      http.NewServeMux(),
+		slots:    make(chan struct{}, conf.MaxBuilds),
+		command:  buildCommand,
+		contexts: make(map[string]*buildContext),
+		uploads:  make(map[string]*buildContext),
+		builds:   make(map[string]*build),
+	}
+
+	s.mux.HandleFunc("POST /v1/build-context", s.authorized(s.handleContextCreate))
+	s.mux.HandleFunc("PUT /v1/build-context/_upload/{token}", s.handleContextUpload)
+	s.mux.HandleFunc("DELETE /v1/build-context/{digest}", s.authorized(s.handleContextDelete))
+	s.mux.HandleFunc("POST /v1/build", s.authorized(s.handleBuildSubmit))
+	s.mux.HandleFunc("GET /v1/build/{id}", s.authorized(s.handleBuildStatus))
+	s.mux.HandleFunc("PUT /v1/build/{id}/_cancel", s.authorized(s.handleBuildCancel))
+	s.mux.HandleFunc("GET /v1/build/{id}/image", s.authorized(s.handleBuildImage))
+	s.mux.HandleFunc("GET /v1/build-ws/{id}", s.authorized(s.handleBuildOutput))
+
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sylog.Debugf("%s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the build server at addr, over TLS if certFile and
+// keyFile are set, until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context, addr, certFile, keyFile string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if certFile != "" {
+			errCh <- srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			errCh <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	for _, b := range s.builds {
+		b.cancel()
+	}
+	s.mu.Unlock()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// authorized wraps h, checking the bearer token of requests.
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "bearer") || subtle.ConstantTimeCompare([]byte(token), []byte(s.conf.AuthToken)) != 1 {
+			writeError(w, "invalid authentication token", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) handleContextCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Size   int64  `json:"size"`
+		Digest string `json:"digest"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !digestRegexp.MatchString(req.Digest) || req.Size <= 0 {
+		writeError(w, "invalid build context size or digest", http.StatusBadRequest)
+		return
+	}
+	if req.Size > s.conf.MaxContextSize {
+		writeError(w, fmt.Sprintf("build context is larger than the limit of %d bytes", s.conf.MaxContextSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.contexts[req.Digest]; ok && c.uploaded {
+		c.expiry.Reset(s.conf.Retention)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	token := randomID()
+	c := &buildContext{
+		digest: req.Digest,
+		size:   req.Size,
+		path:   filepath.Join(s.conf.WorkDir, "contexts", req.Digest+".tar.gz"),
+	}
+	s.uploads[token] = c
+	// An upload that is not made within the retention period is abandoned.
+	c.expiry = time.AfterFunc(s.conf.Retention, func() {
+		s.mu.Lock()
+		delete(s.uploads, token)
+		s.mu.Unlock()
+	})
+
+	w.Header().Set("Location", "v1/build-context/_upload/"+token)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleContextUpload(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+
+	s.mu.Lock()
+	c, ok := s.uploads[token]
+	delete(s.uploads, token)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, "invalid upload location", http.StatusNotFound)
+		return
+	}
+	c.expiry.Stop()
+
+	if err := c.receive(r.Body); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	c.uploaded = true
+	if old, ok := s.contexts[c.digest]; ok {
+		old.expiry.Stop()
+	}
+	s.contexts[c.digest] = c
+	// The context is removed once it has not been announced by a client for
+	// the retention period.
+	c.expiry = time.AfterFunc(s.conf.Retention, func() { s.removeContext(c) })
+	s.mu.Unlock()
+
+	sylog.Infof("Received build context %s", c.digest)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleContextDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c, ok := s.contexts[r.PathValue("digest")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, "build context not found", http.StatusNotFound)
+		return
+	}
+
+	c.expiry.Stop()
+	s.removeContext(c)
+	w.WriteHeader(http.StatusOK)
+}
+
+// removeContext removes build context c, unless it has been replaced by a new
+// upload of the same digest.
+func (s *Server) removeContext(c *buildContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.contexts[c.digest] != c {
+		return
+	}
+	delete(s.contexts, c.digest)
+
+	if err := os.Remove(c.path); err != nil {
+		sylog.Warningf("While removing build context %s: %v", c.digest, err)
+	}
+}
+
+func (s *Server) handleBuildSubmit(w http.ResponseWriter, r *http.Request) {
+	var req buildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := checkRequest(req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := s.newBuild(req)
+	if err != nil {
+		var reqErr requestError
+		if errors.As(err, &reqErr) {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sylog.Errorf("While creating build: %v", err)
+		writeError(w, "could not create build", http.StatusInternalServerError)
+		return
+	}
+
+	sylog.Infof("Build %s submitted", b.id)
+	go s.run(b)
+
+	writeResponse(w, b.status())
+}
+
+// requestError is an error caused by an invalid build request.
+type requestError struct {
+	error
+}
+
+// checkRequest checks that req can be built by the server.
+func checkRequest(req buildRequest) error {
+	if req.LibraryRef != "" {
+		return fmt.Errorf("this build server cannot push images to a library, build to a local file instead")
+	}
+	if arch := req.BuilderRequirements["arch"]; arch != "" && arch != runtime.GOARCH {
+		return fmt.Errorf("this build server cannot build for architecture %s, only for %s", arch, runtime.GOARCH)
+	}
+	if req.WorkingDir != "" && !filepath.IsAbs(req.WorkingDir) {
+		return fmt.Errorf("working directory %q is not an absolute path", req.WorkingDir)
+	}
+
+	// %include directives are resolved by the client. The build would resolve
+	// any that remain against the server host.
+	for _, line := range strings.Split(string(req.DefinitionRaw), "\n") {
+		if f := strings.Fields(line); len(f) > 0 && strings.EqualFold(f[0], "%include") {
+			return fmt.Errorf("a %%include directive is not supported by this build server")
+		}
+	}
+	// The definition is checked once rendered, as by the build, so that
+	// templates cannot hide the bootstrap agent or sections.
+	defs, err := nativebuild.RenderDefs(req.DefinitionRaw, nil)
+	if err != nil {
+		return fmt.Errorf("invalid definition: %w", err)
+	}
+	for _, d := range defs {
+		if b := strings.ToLower(strings.TrimSpace(d.Header["bootstrap"])); refusedBootstraps[b] {
+			return fmt.Errorf("building from a %q source is not supported by this build server", b)
+		}
+		// %setup and %pre run on the server host, outside of the fakeroot
+		// build container.
+		if strings.TrimSpace(d.BuildData.Setup.Script) != "" {
+			return fmt.Errorf("a %%setup section is not supported by this build server")
+		}
+		if strings.TrimSpace(d.BuildData.Pre.Script) != "" {
+			return fmt.Errorf("a %%pre section is not supported by this build server")
+		}
+	}
+	return nil
+}
+
+// newBuild creates the directory of a build of req, holding the definition and
+// the extracted build context.
+func (s *Server) newBuild(req buildRequest) (b *build, err error) {
+	var c *buildContext
+	if req.ContextDigest != "" {
+		s.mu.Lock()
+		c = s.contexts[req.ContextDigest]
+		s.mu.Unlock()
+		if c == nil {
+			return nil, requestError{fmt.Errorf("unknown build context %s", req.ContextDigest)}
+		}
+	}
+
+	id := randomID()
+	dir := filepath.Join(s.conf.WorkDir, "builds", id)
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b = &build{
+		id:         id,
+		dir:        dir,
+		contextDir: filepath.Join(dir, "context"),
+		libraryURL: req.LibraryURL,
+		ctx:        ctx,
+		cancel:     cancel,
+		log:        newLogBuffer(),
+	}
+	b.workDir = filepath.Join(b.contextDir, filepath.Clean("/"+req.WorkingDir))
+
+	if err := os.WriteFile(b.defPath(), req.DefinitionRaw, 0o600); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := os.Mkdir(b.contextDir, 0o755); err != nil {
+		cancel()
+		return nil, err
+	}
+	if c != nil {
+		if err := extractContext(c.path, b.contextDir); err != nil {
+			cancel()
+			return nil, requestError{err}
+		}
+	}
+	if err := os.MkdirAll(b.workDir, 0o755); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.builds[id] = b
+	s.mu.Unlock()
+
+	return b, nil
+}
+
+// run runs build b once a build slot is free, and removes it once the
+// retention period has passed.
+func (s *Server) run(b *build) {
+	defer time.AfterFunc(s.conf.Retention, func() { s.remove(b) })
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-b.ctx.Done():
+		b.finish(fmt.Errorf("build canceled"))
+		return
+	}
+
+	sylog.Infof("Build %s started", b.id)
+	cmd := s.command(b.ctx, b)
+	cmd.Stdout = b.log
+	cmd.Stderr = b.log
+	err := cmd.Run()
+	if b.ctx.Err() != nil {
+		err = fmt.Errorf("build canceled")
+	}
+	b.finish(err)
+	b.cancel()
+
+	if err != nil {
+		sylog.Infof("Build %s failed: %v", b.id, err)
+	} else {
+		sylog.Infof("Build %s complete", b.id)
+	}
+}
+
+// remove removes build b, and its image.
+func (s *Server) remove(b *build) {
+	s.mu.Lock()
+	delete(s.builds, b.id)
+	s.mu.Unlock()
+
+	if err := os.RemoveAll(b.dir); err != nil {
+		sylog.Warningf("While removing build %s: %v", b.id, err)
+	}
+}
+
+// build returns the build named in the request, or writes an error.
+func (s *Server) build(w http.ResponseWriter, r *http.Request) *build {
+	s.mu.Lock()
+	b, ok := s.builds[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, "build not found", http.StatusNotFound)
+		return nil
+	}
+	return b
+}
+
+func (s *Server) handleBuildStatus(w http.ResponseWriter, r *http.Request) {
+	if b := s.build(w, r); b != nil {
+		writeResponse(w, b.status())
+	}
+}
+
+func (s *Server) handleBuildCancel(w http.ResponseWriter, r *http.Request) {
+	if b := s.build(w, r); b != nil {
+		sylog.Infof("Canceling build %s", b.id)
+		b.cancel()
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *Server) handleBuildImage(w http.ResponseWriter, r *http.Request) {
+	b := s.build(w, r)
+	if b == nil {
+		return
+	}
+	if st := b.status(); !st.IsComplete || st.ImageSize == 0 {
+		writeError(w, "build has no image", http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(b.imagePath())
+	if err != nil {
+		writeError(w, "build has no image", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "image.sif", fi.ModTime(), f)
+}
+
+var upgrader = websocket.Upgrader{}
+
+// handleBuildOutput streams the output of a build over a websocket, from the
+// beginning of the build until it has ended.
+func (s *Server) handleBuildOutput(w http.ResponseWriter, r *http.Request) {
+	b := s.build(w, r)
+	if b == nil {
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		sylog.Debugf("While upgrading to websocket: %v", err)
+		return
+	}
+	defer ws.Close()
+
+	// Detect the client going away, by reading until an error occurs.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := ws.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	off := 0
+	for {
+		data, done, changed := b.log.next(off)
+		if len(data) > 0 {
+			if err := ws.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+			off += len(data)
+			continue
+		}
+		if done {
+			break
+		}
+		select {
+		case <-changed:
+		case <-gone:
+			return
+		}
+	}
+
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(5*time.Second)); err != nil {
+		return
+	}
+	// Give the client a chance to close the connection first.
+	select {
+	case <-gone:
+	case <-time.After(5 * time.Second):
+	}
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeResponse(w http.ResponseWriter, v any) {
+	if err := jsonresp.WriteResponse(w, v, http.StatusOK); err != nil {
+		sylog.Debugf("While writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, message string, code int) {
+	if err := jsonresp.WriteError(w, message, code); err != nil {
+		sylog.Debugf("While writing error: %v", err)
+	}
+}
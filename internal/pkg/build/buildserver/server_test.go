@@ -0,0 +1,326 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package buildserver
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	buildclient "github.com/sylabs/scs-build-client/client"
+)
+
+const testDef = `Bootstrap: docker
+From: alpine
+
+%files
+    input.txt /input.txt
+`
+
+// newTestServer returns a build server, whose builds copy the definition, and
+// the input.txt file of the build context, into the image.
+func newTestServer(t *testing.T, token string) (*Server, *buildclient.Client) {
+	t.Helper()
+
+	s, err := New(Config{WorkDir: t.TempDir(), AuthToken: token})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	s.command = func(ctx context.Context, b *build) *exec.Cmd {
+		script := `echo building; cat "$1" input.txt > "$2"`
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", script, "sh", b.defPath(), b.imagePath())
+		cmd.Dir = b.workDir
+		return cmd
+	}
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	c, err := buildclient.NewClient(
+		buildclient.OptBaseURL(srv.URL),
+		buildclient.OptBearerToken(token),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return s, c
+}
+
+func TestBuild(t *testing.T) {
+	s, c := newTestServer(t, "secret")
+	ctx := context.Background()
+
+	wd := t.TempDir()
+	input := filepath.Join(wd, "input.txt")
+	if err := os.WriteFile(input, []byte("input\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := c.UploadBuildContext(ctx, []string{strings.TrimPrefix(input, "/")})
+	if err != nil {
+		t.Fatalf("failed to upload build context: %v", err)
+	}
+
+	bi, err := c.Submit(ctx, strings.NewReader(testDef),
+		buildclient.OptBuildContext(digest),
+		buildclient.OptBuildWorkingDirectory(wd),
+	)
+	if err != nil {
+		t.Fatalf("failed to submit build: %v", err)
+	}
+	if !strings.HasPrefix(bi.LibraryRef(), ImageRefPrefix) {
+		t.Errorf("unexpected library ref %q", bi.LibraryRef())
+	}
+
+	var out bytes.Buffer
+	if err := c.GetOutput(ctx, bi.ID(), &out); err != nil {
+		t.Fatalf("failed to get output: %v", err)
+	}
+	if got := out.String(); got != "building\n" {
+		t.Errorf("got output %q, want %q", got, "building\n")
+	}
+
+	bi, err = c.GetStatus(ctx, bi.ID())
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	want := testDef + "input\n"
+	if !bi.IsComplete() || bi.ImageSize() != int64(len(want)) {
+		t.Errorf("unexpected status: complete %v, size %d", bi.IsComplete(), bi.ImageSize())
+	}
+
+	if err := c.DeleteBuildContext(ctx, digest); err != nil {
+		t.Errorf("failed to delete build context: %v", err)
+	}
+
+	b := s.builds[bi.ID()]
+	image, err := os.ReadFile(b.imagePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(image) != want {
+		t.Errorf("got image %q, want %q", image, want)
+	}
+}
+
+func TestSubmitErrors(t *testing.T) {
+	_, c := newTestServer(t, "secret")
+
+	tests := []struct {
+		name string
+		def  string
+		opts []buildclient.BuildOption
+	}{
+		{
+			name: "LibraryRef",
+			def:  testDef,
+			opts: []buildclient.BuildOption{buildclient.OptBuildLibraryRef("library://user/default/image:latest")},
+		},
+		{
+			name: "Arch",
+			def:  testDef,
+			opts: []buildclient.BuildOption{buildclient.OptBuildArchitecture("invalid")},
+		},
+		{
+			name: "LocalImage",
+			def:  "Bootstrap: localimage\nFrom: /etc/image.sif\n",
+		},
+		{
+			name: "OCIArchive",
+			def:  "Bootstrap: oci-archive\nFrom: /etc/image.tar\n",
+		},
+		{
+			name: "DockerDaemon",
+			def:  "Bootstrap: docker-daemon\nFrom: alpine:latest\n",
+		},
+		{
+			name: "TemplatedBootstrap",
+			def:  "Bootstrap: {{ B | default \"localimage\" }}\nFrom: /etc\n",
+		},
+		{
+			name: "TemplatedSetup",
+			def:  testDef + "\n{{ if eq B \"1\" }}\n%setup\n    touch /tmp/file\n{{ end }}\n\n%arguments\n    B=1\n",
+		},
+		{
+			name: "Include",
+			def:  testDef + "\n%include /etc/fragment.def\n",
+		},
+		{
+			name: "Setup",
+			def:  testDef + "\n%setup\n    touch /tmp/file\n",
+		},
+		{
+			name: "Pre",
+			def:  testDef + "\n%pre\n    touch /tmp/file\n",
+		},
+		{
+			name: "StagePre",
+			def:  "Bootstrap: docker\nFrom: alpine\nStage: one\n\n%pre\n    touch /tmp/file\n\n" + "Bootstrap: docker\nFrom: alpine\nStage: two\n",
+		},
+		{
+			name: "UnknownContext",
+			def:  testDef,
+			opts: []buildclient.BuildOption{buildclient.OptBuildContext("sha256." + strings.Repeat("0", 64))},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Submit(context.Background(), strings.NewReader(tt.def), tt.opts...); err == nil {
+				t.Errorf("unexpected success")
+			}
+		})
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	s, _ := newTestServer(t, "secret")
+
+	for _, auth := range []string{"", "Bearer wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/build/0", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%q: got status %d, want %d", auth, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestNoToken(t *testing.T) {
+	if _, err := New(Config{WorkDir: t.TempDir()}); err == nil {
+		t.Errorf("unexpected success")
+	}
+}
+
+func TestContextLimits(t *testing.T) {
+	s, err := New(Config{WorkDir: t.TempDir(), AuthToken: "secret", Retention: 50 * time.Millisecond, MaxContextSize: 1024})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	create := func(size int) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"size": %d, "digest": "sha256.%s"}`, size, strings.Repeat("0", 64))
+		req := httptest.NewRequest(http.MethodPost, "/v1/build-context", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := create(1025); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	rec := create(1024)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Header().Get("Location") == "" {
+		t.Fatalf("no upload location")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		n := len(s.uploads)
+		s.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("upload location did not expire")
+}
+
+func TestCancel(t *testing.T) {
+	s, c := newTestServer(t, "secret")
+	s.command = func(ctx context.Context, _ *build) *exec.Cmd {
+		return exec.CommandContext(ctx, "/bin/sleep", "60")
+	}
+	ctx := context.Background()
+
+	bi, err := c.Submit(ctx, strings.NewReader(testDef))
+	if err != nil {
+		t.Fatalf("failed to submit build: %v", err)
+	}
+	if err := c.Cancel(ctx, bi.ID()); err != nil {
+		t.Fatalf("failed to cancel build: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !bi.IsComplete() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if bi, err = c.GetStatus(ctx, bi.ID()); err != nil {
+			t.Fatalf("failed to get status: %v", err)
+		}
+	}
+	if !bi.IsComplete() || bi.ImageSize() != 0 {
+		t.Errorf("unexpected status: complete %v, size %d", bi.IsComplete(), bi.ImageSize())
+	}
+}
+
+func TestExtractContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		hdr     tar.Header
+		wantErr bool
+	}{
+		{
+			name: "File",
+			hdr:  tar.Header{Name: "home/user/file", Typeflag: tar.TypeReg, Mode: 0o644},
+		},
+		{
+			name: "Dir",
+			hdr:  tar.Header{Name: "home/user/", Typeflag: tar.TypeDir, Mode: 0o755},
+		},
+		{
+			name:    "Escape",
+			hdr:     tar.Header{Name: "../file", Typeflag: tar.TypeReg, Mode: 0o644},
+			wantErr: true,
+		},
+		{
+			name:    "Symlink",
+			hdr:     tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gw := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gw)
+			if err := tw.WriteHeader(&tt.hdr); err != nil {
+				t.Fatal(err)
+			}
+			tw.Close()
+			gw.Close()
+
+			src := filepath.Join(t.TempDir(), "context.tar.gz")
+			if err := os.WriteFile(src, buf.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := extractContext(src, t.TempDir())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package dockerfile provides best-effort conversion between Dockerfiles and
+// Singularity definition files.
+package dockerfile
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/linter"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	dfshell "github.com/moby/buildkit/frontend/dockerfile/shell"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/shell"
+	"github.com/sylabs/singularity/v4/pkg/build/types"
+)
+
+// dockerVarRegexp matches $VAR and ${VAR} references in a Dockerfile FROM line.
+var dockerVarRegexp = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)`)
+
+// stageState holds the definition for a Dockerfile stage while its
+// instructions are being converted.
+type stageState struct {
+	lex        *dfshell.Lex
+	name       string
+	header     map[string]string
+	labels     map[string]string
+	files      []types.Files
+	arguments  []string
+	post       []string
+	env        []string
+	workdir    string
+	hasRun     bool
+	entrypoint *instructions.ShellDependantCmdLine
+	cmd        *instructions.ShellDependantCmdLine
+}
+
+// clone returns a deep copy of the stage state, so that a stage can be used as
+// the base for a later stage.
+func (s *stageState) clone() *stageState {
+	c := *s
+	c.header = maps.Clone(s.header)
+	c.labels = maps.Clone(s.labels)
+	c.files = make([]types.Files, len(s.files))
+	for i, f := range s.files {
+		c.files[i] = types.Files{Args: f.Args, Files: append([]types.FileTransport{}, f.Files...)}
+	}
+	c.arguments = append([]string{}, s.arguments...)
+	c.post = append([]string{}, s.post...)
+	c.env = append([]string{}, s.env...)
+	return &c
+}
+
+// unquote removes Dockerfile quoting from v. If v cannot be processed, it is
+// returned unmodified.
+func (s *stageState) unquote(v string) string {
+	u, _, err := s.lex.ProcessWord(v, dfshell.EnvsFromSlice(nil))
+	if err != nil {
+		return v
+	}
+	return u
+}
+
+// addFile records a file transfer in the %files section with the given args.
+func (s *stageState) addFile(args string, ft types.FileTransport) {
+	for i := range s.files {
+		if s.files[i].Args == args {
+			s.files[i].Files = append(s.files[i].Files, ft)
+			return
+		}
+	}
+	s.files = append(s.files, types.Files{Args: args, Files: []types.FileTransport{ft}})
+}
+
+// definition returns the types.Definition equivalent of the stage state.
+func (s *stageState) definition() types.Definition {
+	d := types.Definition{
+		Header: s.header,
+		ImageData: types.ImageData{
+			Labels: s.labels,
+		},
+		BuildData: types.Data{
+			Files: s.files,
+		},
+	}
+	if len(s.post) > 0 {
+		d.BuildData.Post.Script = strings.Join(s.post, "\n")
+	}
+	if len(s.env) > 0 {
+		d.Environment.Script = strings.Join(s.env, "\n")
+	}
+	if len(s.arguments) > 0 {
+		d.BuildData.Arguments.Script = strings.Join(s.arguments, "\n")
+	}
+	d.Runscript.Script = runscript(s.entrypoint, s.cmd, s.workdir)
+	return d
+}
+
+// FromDockerfile converts the Dockerfile read from r into one definition per
+// build stage, in the order the stages appear in the Dockerfile. Instructions,
+// or instruction options, that have no equivalent in a definition file are
+// skipped and described in the returned warnings.
+func FromDockerfile(r io.Reader) ([]types.Definition, []string, error) {
+	res, err := parser.Parse(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("while parsing Dockerfile: %w", err)
+	}
+	dockerStages, metaArgs, err := instructions.Parse(res.AST, linter.New(&linter.Config{SkipAll: true}))
+	if err != nil {
+		return nil, nil, fmt.Errorf("while parsing Dockerfile: %w", err)
+	}
+	if len(dockerStages) == 0 {
+		return nil, nil, fmt.Errorf("no FROM instruction found in Dockerfile")
+	}
+
+	var warnings []string
+	warnf := func(format string, a ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, a...))
+	}
+
+	// The lexer is used to remove Dockerfile quoting from values. Variable
+	// references are left in place, to be expanded by the shell.
+	lex := dfshell.NewLex(res.EscapeToken)
+	lex.SkipUnsetEnv = true
+
+	// Global ARGs, declared before the first FROM, may only be used in FROM.
+	metaDefaults := map[string]string{}
+	for _, a := range metaArgs {
+		for _, kv := range a.Args {
+			metaDefaults[kv.Key] = kv.ValueString()
+		}
+	}
+
+	// Stages can be referenced by name, or by index. Unnamed stages in a
+	// multi-stage Dockerfile are given a name, as definition files can only
+	// reference stages by name.
+	stageNames := make([]string, len(dockerStages))
+	for i, ds := range dockerStages {
+		stageNames[i] = ds.Name
+		if stageNames[i] == "" && len(dockerStages) > 1 {
+			stageNames[i] = "stage-" + strconv.Itoa(i)
+		}
+	}
+	findStage := func(ref string) (int, bool) {
+		if i, err := strconv.Atoi(ref); err == nil && i >= 0 && i < len(stageNames) {
+			return i, true
+		}
+		for i, n := range stageNames {
+			if n != "" && strings.EqualFold(n, ref) {
+				return i, true
+			}
+		}
+		return -1, false
+	}
+
+	states := make([]*stageState, 0, len(dockerStages))
+	for i, ds := range dockerStages {
+		var s *stageState
+		if base, ok := findStage(ds.BaseName); ok && base < i {
+			warnf("stage %q is based on stage %q: instructions from %q are repeated in the definition", stageNames[i], stageNames[base], stageNames[base])
+			s = states[base].clone()
+		} else {
+			s = &stageState{
+				lex:    lex,
+				header: map[string]string{},
+				labels: map[string]string{},
+			}
+			if strings.EqualFold(ds.BaseName, "scratch") {
+				s.header["bootstrap"] = "scratch"
+			} else {
+				s.header["bootstrap"] = "docker"
+				if s.header["from"], err = fromWithArgs(ds.BaseName, metaDefaults, s); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		s.name = stageNames[i]
+		if s.name != "" {
+			s.header["stage"] = s.name
+		}
+		if ds.Platform != "" {
+			warnf("FROM --platform=%s is ignored: use 'singularity build --arch' instead", ds.Platform)
+		}
+
+		for _, c := range ds.Commands {
+			convertCommand(c, s, stageNames, findStage, warnf)
+		}
+		if s.workdir != "" && s.entrypoint == nil && s.cmd == nil {
+			warnf("WORKDIR %s applies to %%post only: the runscript of the base image does not change to it", s.workdir)
+		}
+		states = append(states, s)
+	}
+
+	defs := make([]types.Definition, 0, len(states))
+	for _, s := range states {
+		defs = append(defs, s.definition())
+	}
+	return defs, warnings, nil
+}
+
+// fromWithArgs replaces references to global Dockerfile ARGs in a FROM value
+// with build-arg placeholders, adding the ARG defaults to the %arguments
+// section of the stage. A global ARG without a default cannot be converted, as
+// %arguments entries must have a value.
+func fromWithArgs(from string, defaults map[string]string, s *stageState) (string, error) {
+	var err error
+	from = dockerVarRegexp.ReplaceAllStringFunc(from, func(m string) string {
+		sm := dockerVarRegexp.FindStringSubmatch(m)
+		name := sm[1] + sm[2]
+		v, ok := defaults[name]
+		if !ok {
+			return m
+		}
+		if v == "" {
+			err = fmt.Errorf("global ARG %s used in FROM %s has no default value: set a default in the Dockerfile", name, from)
+			return m
+		}
+		if arg := name + "=" + v; !slices.Contains(s.arguments, arg) {
+			s.arguments = append(s.arguments, arg)
+		}
+		return "{{ " + name + " }}"
+	})
+	return from, err
+}
+
+// convertCommand applies the Dockerfile instruction c to the stage state s.
+func convertCommand(c instructions.Command, s *stageState, stageNames []string, findStage func(string) (int, bool), warnf func(string, ...any)) {
+	switch c := c.(type) {
+	case *instructions.RunCommand:
+		if len(c.FlagsUsed) > 0 {
+			warnf("RUN options are ignored: --%s", strings.Join(c.FlagsUsed, ", --"))
+		}
+		s.post = append(s.post, runLine(c.ShellDependantCmdLine))
+		s.hasRun = true
+
+	case *instructions.EnvCommand:
+		for _, kv := range c.Env {
+			line := fmt.Sprintf("export %s=\"%s\"", kv.Key, shell.EscapeDoubleQuotes(s.unquote(kv.Value)))
+			s.env = append(s.env, line)
+			// %environment is not sourced during %post, so the variable must
+			// also be set for any following RUN instructions.
+			s.post = append(s.post, line)
+		}
+
+	case *instructions.ArgCommand:
+		for _, kv := range c.Args {
+			if kv.Value != nil && *kv.Value != "" {
+				s.arguments = append(s.arguments, kv.Key+"="+*kv.Value)
+			} else {
+				warnf("ARG %s has no default value: it must be provided with --build-arg", kv.Key)
+			}
+			s.post = append(s.post, fmt.Sprintf("export %s=\"{{ %s }}\"", kv.Key, kv.Key))
+		}
+
+	case *instructions.LabelCommand:
+		for _, kv := range c.Labels {
+			s.labels[s.unquote(kv.Key)] = s.unquote(kv.Value)
+		}
+
+	case *instructions.MaintainerCommand:
+		s.labels["maintainer"] = c.Maintainer
+
+	case *instructions.WorkdirCommand:
+		s.workdir = resolvePath(s.workdir, c.Path)
+		q := "'" + shell.EscapeSingleQuotes(s.workdir) + "'"
+		s.post = append(s.post, "mkdir -p "+q, "cd "+q)
+
+	case *instructions.CopyCommand:
+		args := ""
+		if c.From != "" {
+			i, ok := findStage(c.From)
+			if !ok {
+				warnf("COPY --from=%s is not a build stage: only copies from build stages are supported", c.From)
+				return
+			}
+			args = "from " + stageNames[i]
+		}
+		if c.Chown != "" || c.Chmod != "" || c.Link || c.Parents || len(c.ExcludePatterns) > 0 {
+			warnf("COPY options --chown, --chmod, --link, --parents and --exclude are ignored")
+		}
+		copyFiles(s, args, c.SourcesAndDest, "COPY", warnf)
+
+	case *instructions.AddCommand:
+		var local []string
+		for _, src := range c.SourcePaths {
+			if strings.Contains(src, "://") || strings.HasPrefix(src, "git@") {
+				warnf("ADD of remote source %q has no definition file equivalent: download it in %%post instead", src)
+				continue
+			}
+			local = append(local, src)
+		}
+		if len(local) == 0 {
+			return
+		}
+		if slices.ContainsFunc(local, isArchive) {
+			warnf("ADD is converted as a plain copy: local archives are not extracted")
+		}
+		copyFiles(s, "", instructions.SourcesAndDest{DestPath: c.DestPath, SourcePaths: local}, "ADD", warnf)
+
+	case *instructions.EntrypointCommand:
+		cl := c.ShellDependantCmdLine
+		s.entrypoint = &cl
+
+	case *instructions.CmdCommand:
+		cl := c.ShellDependantCmdLine
+		s.cmd = &cl
+
+	case *instructions.UserCommand:
+		warnf("USER %s has no definition file equivalent: containers run as the calling user", c.User)
+
+	default:
+		warnf("%s has no definition file equivalent and is ignored", strings.ToUpper(c.Name()))
+	}
+}
+
+// copyFiles adds the sources of a COPY or ADD instruction to the %files
+// section identified by args.
+func copyFiles(s *stageState, args string, sd instructions.SourcesAndDest, name string, warnf func(string, ...any)) {
+	if len(sd.SourceContents) > 0 {
+		warnf("%s with here-documents has no definition file equivalent and is ignored", name)
+	}
+	if s.hasRun {
+		warnf("%s after RUN: %%files are copied before %%post runs, so the order of operations will differ", name)
+	}
+	dst := resolvePath(s.workdir, sd.DestPath)
+	if strings.HasSuffix(sd.DestPath, "/") && !strings.HasSuffix(dst, "/") {
+		dst += "/"
+	}
+	for _, src := range sd.SourcePaths {
+		s.addFile(args, types.FileTransport{Src: src, Dst: dst})
+	}
+}
+
+// isArchive returns true if src looks like an archive that ADD would extract.
+func isArchive(src string) bool {
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz", ".tar.zst"} {
+		if strings.HasSuffix(src, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath resolves p relative to the working directory wd, as Docker
+// does for WORKDIR and COPY destinations.
+func resolvePath(wd, p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	if wd == "" {
+		wd = "/"
+	}
+	return path.Join(wd, p)
+}
+
+// runLine returns the shell script equivalent of a RUN instruction.
+func runLine(cl instructions.ShellDependantCmdLine) string {
+	if !cl.PrependShell {
+		return quoteArgs(cl.CmdLine)
+	}
+	line := strings.Join(cl.CmdLine, " ")
+	// A bare here-document is run as a script by Docker.
+	if len(cl.Files) == 1 && strings.HasPrefix(strings.TrimSpace(line), "<<") && len(strings.Fields(line)) == 1 {
+		return strings.TrimSuffix(cl.Files[0].Data, "\n")
+	}
+	// Otherwise here-documents are standard shell syntax, and can be
+	// reconstructed around the command.
+	for _, f := range cl.Files {
+		line += "\n" + f.Data + f.Name
+	}
+	return line
+}
+
+// quoteArgs single quotes each argument, so that it is passed verbatim by the
+// shell.
+func quoteArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, a := range args {
+		quoted = append(quoted, "'"+shell.EscapeSingleQuotes(a)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// execArgs returns the argument vector that Docker executes for an
+// ENTRYPOINT or CMD instruction.
+func execArgs(cl *instructions.ShellDependantCmdLine) []string {
+	if cl.PrependShell {
+		return []string{"/bin/sh", "-c", strings.Join(cl.CmdLine, " ")}
+	}
+	return cl.CmdLine
+}
+
+// runscript returns a %runscript reproducing the Docker ENTRYPOINT / CMD
+// semantics. Arguments passed to the container replace CMD, and are appended
+// to an exec form ENTRYPOINT. The runscript changes to the WORKDIR, if any, as
+// the working directory of a Singularity container is not set by the image.
+func runscript(entrypoint, cmd *instructions.ShellDependantCmdLine, workdir string) string {
+	if entrypoint == nil && cmd == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	if workdir != "" {
+		fmt.Fprintf(&b, "cd %s\n", quoteArgs([]string{workdir}))
+	}
+
+	// A shell form ENTRYPOINT ignores CMD and any arguments.
+	if entrypoint != nil && entrypoint.PrependShell {
+		b.WriteString("exec " + quoteArgs(execArgs(entrypoint)))
+		return b.String()
+	}
+
+	if cmd != nil && len(cmd.CmdLine) > 0 {
+		b.WriteString("if [ $# -eq 0 ]; then\n")
+		fmt.Fprintf(&b, "    set -- %s\n", quoteArgs(execArgs(cmd)))
+		b.WriteString("fi\n")
+	}
+	if entrypoint != nil && len(entrypoint.CmdLine) > 0 {
+		fmt.Fprintf(&b, "exec %s \"$@\"", quoteArgs(entrypoint.CmdLine))
+	} else {
+		b.WriteString("exec \"$@\"")
+	}
+	return b.String()
+}
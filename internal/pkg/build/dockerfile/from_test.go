@@ -0,0 +1,235 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package dockerfile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sylabs/singularity/v4/pkg/build/types/parser"
+)
+
+func TestFromDockerfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		dockerfile   string
+		wantDef      string
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:       "NoFrom",
+			dockerfile: "ARG A=1\n",
+			wantErr:    true,
+		},
+		{
+			name: "Simple",
+			dockerfile: `FROM alpine:3.20
+LABEL org.opencontainers.image.title="My App" maintainer=me
+ENV PATH=/opt/bin:$PATH A="b c"
+WORKDIR /opt
+COPY app.sh bin/
+RUN ["chmod", "755", "/opt/bin/app.sh"]
+CMD ["/opt/bin/app.sh"]
+`,
+			wantDef: `bootstrap: docker
+from: alpine:3.20
+
+%labels
+	maintainer me
+	org.opencontainers.image.title My App
+
+%files
+	app.sh	/opt/bin/
+
+%environment
+export PATH="/opt/bin:$PATH"
+export A="b c"
+
+%runscript
+cd '/opt'
+if [ $# -eq 0 ]; then
+    set -- '/opt/bin/app.sh'
+fi
+exec "$@"
+
+%post
+export PATH="/opt/bin:$PATH"
+export A="b c"
+mkdir -p '/opt'
+cd '/opt'
+'chmod' '755' '/opt/bin/app.sh'
+
+`,
+		},
+		{
+			name:       "GlobalArgNoDefault",
+			dockerfile: "ARG BASE\nFROM ${BASE}\n",
+			wantErr:    true,
+		},
+		{
+			name: "MultiStage",
+			dockerfile: `ARG BASE=golang:1.22
+FROM ${BASE} AS builder
+RUN go build -o /out/app .
+
+FROM scratch
+COPY --from=builder /out/app /app
+ENTRYPOINT ["/app"]
+`,
+			wantDef: `bootstrap: docker
+from: {{ BASE }}
+stage: builder
+
+%post
+go build -o /out/app .
+
+%arguments
+BASE=golang:1.22
+
+
+bootstrap: scratch
+stage: stage-1
+
+%files from builder
+	/out/app	/app
+
+%runscript
+exec '/app' "$@"
+
+`,
+		},
+		{
+			name: "StageFromStage",
+			dockerfile: `FROM alpine AS base
+RUN apk add curl
+FROM base
+RUN curl --version
+`,
+			wantDef: `bootstrap: docker
+from: alpine
+stage: base
+
+%post
+apk add curl
+
+
+bootstrap: docker
+from: alpine
+stage: stage-1
+
+%post
+apk add curl
+curl --version
+
+`,
+			wantWarnings: 1,
+		},
+		{
+			name: "Unsupported",
+			dockerfile: `FROM alpine
+RUN --network=none echo hello
+USER nobody
+EXPOSE 8080
+COPY --from=docker.io/library/busybox /bin/busybox /bin/
+ADD https://example.com/file.txt /
+ENTRYPOINT echo hello
+`,
+			wantDef: `bootstrap: docker
+from: alpine
+
+%runscript
+exec '/bin/sh' '-c' 'echo hello'
+
+%post
+echo hello
+
+`,
+			wantWarnings: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs, warnings, err := FromDockerfile(strings.NewReader(tt.dockerfile))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var buf bytes.Buffer
+			for i := range defs {
+				if i > 0 {
+					buf.WriteString("\n")
+				}
+				if err := defs[i].WriteRaw(&buf); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(tt.wantDef, buf.String()); diff != "" {
+				t.Errorf("unexpected definition (-want +got):\n%s", diff)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("got %d warnings, want %d: %v", len(warnings), tt.wantWarnings, warnings)
+			}
+
+			// The generated definition must be parsable.
+			if _, err := parser.All(&buf); err != nil {
+				t.Errorf("generated definition could not be parsed: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunscript(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		want       string
+	}{
+		{
+			name:       "None",
+			dockerfile: "FROM alpine\n",
+			want:       "",
+		},
+		{
+			name:       "ShellCmd",
+			dockerfile: "FROM alpine\nCMD echo $HOME\n",
+			want:       "if [ $# -eq 0 ]; then\n    set -- '/bin/sh' '-c' 'echo $HOME'\nfi\nexec \"$@\"",
+		},
+		{
+			name:       "EntrypointAndCmd",
+			dockerfile: "FROM alpine\nENTRYPOINT [\"/bin/echo\", \"it's\"]\nCMD [\"a\", \"b\"]\n",
+			want:       "if [ $# -eq 0 ]; then\n    set -- 'a' 'b'\nfi\nexec '/bin/echo' 'it'\"'\"'s' \"$@\"",
+		},
+		{
+			name:       "ShellEntrypointIgnoresCmd",
+			dockerfile: "FROM alpine\nENTRYPOINT exec top -b\nCMD [\"a\"]\n",
+			want:       "exec '/bin/sh' '-c' 'exec top -b'",
+		},
+		{
+			name:       "Workdir",
+			dockerfile: "FROM alpine\nWORKDIR /srv\nWORKDIR app\nENTRYPOINT [\"./run\"]\n",
+			want:       "cd '/srv/app'\nexec './run' \"$@\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs, _, err := FromDockerfile(strings.NewReader(tt.dockerfile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := defs[0].Runscript.Script; got != tt.want {
+				t.Errorf("got runscript %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
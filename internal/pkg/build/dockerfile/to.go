@@ -0,0 +1,234 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package dockerfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sylabs/singularity/v4/pkg/build/types"
+)
+
+var (
+	// buildArgRegexp matches build-arg placeholders in a definition file.
+	buildArgRegexp = regexp.MustCompile(`{{\s*(\w+)\s*}}`)
+	// envRegexp matches a simple variable assignment in an %environment section.
+	envRegexp = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_]\w*)=(.*)$`)
+)
+
+// ToDockerfile converts a set of definition stages, as returned by
+// parser.All, into a Dockerfile. The conversion is best-effort. Sections and
+// bootstrap agents that have no Dockerfile equivalent are skipped and
+// described in the returned warnings.
+func ToDockerfile(defs []types.Definition) ([]byte, []string, error) {
+	if len(defs) == 0 {
+		return nil, nil, fmt.Errorf("no build stages found in definition")
+	}
+
+	var warnings []string
+	warnf := func(format string, a ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, a...))
+	}
+
+	var buf bytes.Buffer
+
+	// Build-args used in a From header must be declared as global ARGs,
+	// before the first FROM instruction.
+	globalArgs := map[string]string{}
+	for _, d := range defs {
+		defaults := argumentDefaults(d)
+		for _, m := range buildArgRegexp.FindAllStringSubmatch(d.Header["from"], -1) {
+			if _, ok := globalArgs[m[1]]; !ok {
+				globalArgs[m[1]] = defaults[m[1]]
+			}
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(globalArgs)) {
+		buf.WriteString(argInstruction(k, globalArgs[k]))
+	}
+	if len(globalArgs) > 0 {
+		buf.WriteString("\n")
+	}
+
+	for i, d := range defs {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		writeStage(&buf, d, warnf)
+	}
+
+	return buf.Bytes(), warnings, nil
+}
+
+// writeStage writes the Dockerfile instructions for the build stage d to buf.
+func writeStage(buf *bytes.Buffer, d types.Definition, warnf func(string, ...any)) {
+	stage := d.Header["stage"]
+	if stage == "" {
+		stage = "final"
+	}
+
+	from := buildArgRegexp.ReplaceAllString(d.Header["from"], "$${$1}")
+	switch bootstrap := strings.ToLower(d.Header["bootstrap"]); bootstrap {
+	case "docker", "docker-daemon":
+	case "scratch":
+		from = "scratch"
+	default:
+		warnf("stage %s: 'Bootstrap: %s' has no Dockerfile equivalent: using 'FROM scratch'", stage, bootstrap)
+		from = "scratch"
+	}
+	fmt.Fprintf(buf, "FROM %s", from)
+	if name := d.Header["stage"]; name != "" {
+		fmt.Fprintf(buf, " AS %s", strings.ToLower(name))
+	}
+	buf.WriteString("\n")
+
+	// Build-args must be declared in each stage that uses them.
+	defaults := argumentDefaults(d)
+	for _, m := range buildArgRegexp.FindAllStringSubmatch(d.BuildData.Post.Script, -1) {
+		if _, ok := defaults[m[1]]; !ok {
+			defaults[m[1]] = ""
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(defaults)) {
+		buf.WriteString(argInstruction(k, defaults[k]))
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(d.Labels)) {
+		fmt.Fprintf(buf, "LABEL %s=%s\n", strconv.Quote(k), strconv.Quote(d.Labels[k]))
+	}
+
+	for _, f := range d.BuildData.Files {
+		from := ""
+		if s := f.Stage(); s != "" {
+			from = "--from=" + strings.ToLower(s) + " "
+		}
+		for _, ft := range f.Files {
+			if ft.Src == "" {
+				continue
+			}
+			dst := ft.Dst
+			if dst == "" {
+				dst = ft.Src
+			}
+			if from == "" && path.IsAbs(ft.Src) {
+				warnf("stage %s: %%files source %s is interpreted relative to the build context", stage, ft.Src)
+			}
+			fmt.Fprintf(buf, "COPY %s%s\n", from, jsonArgs(ft.Src, dst))
+		}
+	}
+
+	if post := d.BuildData.Post; post.Script != "" {
+		if strings.Contains(post.Script, "SINGULARITY_ENVIRONMENT") {
+			warnf("stage %s: variables written to $SINGULARITY_ENVIRONMENT in %%post are not persisted", stage)
+		}
+		script := buildArgRegexp.ReplaceAllString(post.Script, "$${$1}")
+		writeRunHeredoc(buf, script, post.Args)
+	}
+
+	writeEnvironment(buf, d.Environment.Script, stage, warnf)
+
+	if rs := strings.TrimSpace(d.Runscript.Script); rs != "" {
+		fmt.Fprintf(buf, "ENTRYPOINT %s\n", jsonArgs("/bin/sh", "-c", rs, "runscript"))
+	}
+
+	for _, s := range []struct {
+		name   string
+		script types.Script
+	}{
+		{"setup", d.BuildData.Setup},
+		{"pre", d.BuildData.Pre},
+		{"test", d.BuildData.Test},
+		{"startscript", d.Startscript},
+		{"help", d.Help},
+	} {
+		if strings.TrimSpace(s.script.Script) != "" {
+			warnf("stage %s: %%%s section has no Dockerfile equivalent and is ignored", stage, s.name)
+		}
+	}
+	if len(d.AppOrder) > 0 {
+		warnf("stage %s: SCIF app sections have no Dockerfile equivalent and are ignored", stage)
+	}
+}
+
+// writeRunHeredoc writes script as a RUN instruction using a here-document.
+// The -c option of the section arguments selects the interpreter, as for
+// %post in a definition file.
+func writeRunHeredoc(buf *bytes.Buffer, script, sectionArgs string) {
+	delim := "EOF"
+	for strings.Contains("\n"+script+"\n", "\n"+delim+"\n") {
+		delim += "_"
+	}
+
+	fmt.Fprintf(buf, "RUN <<\"%s\"\n", delim)
+	params := strings.Fields(strings.Split(sectionArgs, "#")[0])
+	if i := slices.Index(params, "-c"); i >= 0 && i+1 < len(params) {
+		fmt.Fprintf(buf, "#!%s\n", strings.Join(params[i+1:], " "))
+	} else {
+		buf.WriteString("set -ex\n")
+	}
+	buf.WriteString(strings.TrimSpace(script))
+	fmt.Fprintf(buf, "\n%s\n", delim)
+}
+
+// writeEnvironment writes an ENV instruction for each simple variable
+// assignment in the %environment script.
+func writeEnvironment(buf *bytes.Buffer, script, stage string, warnf func(string, ...any)) {
+	s := bufio.NewScanner(strings.NewReader(script))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := envRegexp.FindStringSubmatch(line)
+		if m == nil {
+			warnf("stage %s: %%environment line %q has no Dockerfile equivalent and is ignored", stage, line)
+			continue
+		}
+		fmt.Fprintf(buf, "ENV %s=%s\n", m[1], m[2])
+	}
+}
+
+// argumentDefaults returns the default values from the %arguments section of
+// definition d.
+func argumentDefaults(d types.Definition) map[string]string {
+	defaults := map[string]string{}
+	s := bufio.NewScanner(strings.NewReader(d.BuildData.Arguments.Script))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			defaults[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return defaults
+}
+
+func argInstruction(name, value string) string {
+	if value == "" {
+		return fmt.Sprintf("ARG %s\n", name)
+	}
+	return fmt.Sprintf("ARG %s=%s\n", name, strconv.Quote(value))
+}
+
+// jsonArgs returns args in the JSON array form accepted by Dockerfile
+// instructions.
+func jsonArgs(args ...string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(args)
+	return strings.ReplaceAll(strings.TrimSpace(b.String()), `","`, `", "`)
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package dockerfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sylabs/singularity/v4/pkg/build/types/parser"
+)
+
+func TestToDockerfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		def            string
+		wantDockerfile string
+		wantWarnings   int
+	}{
+		{
+			name: "Simple",
+			def: `Bootstrap: docker
+From: alpine:{{ VERSION }}
+
+%arguments
+	VERSION=3.20
+
+%labels
+	Author me
+
+%files
+	app.sh /usr/local/bin/app.sh
+
+%environment
+	export LC_ALL=C
+	. /opt/env.sh
+
+%post
+	apk add --no-cache {{ PKG }}
+
+%runscript
+	exec /usr/local/bin/app.sh "$@"
+`,
+			wantDockerfile: `ARG VERSION="3.20"
+
+FROM alpine:${VERSION}
+ARG PKG
+ARG VERSION="3.20"
+LABEL "Author"="me"
+COPY ["app.sh", "/usr/local/bin/app.sh"]
+RUN <<"EOF"
+set -ex
+apk add --no-cache ${PKG}
+EOF
+ENV LC_ALL=C
+ENTRYPOINT ["/bin/sh", "-c", "exec /usr/local/bin/app.sh \"$@\"", "runscript"]
+`,
+			wantWarnings: 1,
+		},
+		{
+			name: "MultiStage",
+			def: `Bootstrap: docker
+From: golang
+Stage: build
+
+%post -c /bin/bash
+	go build -o /out/app .
+
+Bootstrap: library
+From: alpine
+Stage: final
+
+%files from build
+	/out/app /app
+
+%test
+	/app --version
+`,
+			wantDockerfile: `FROM golang AS build
+RUN <<"EOF"
+#!/bin/bash
+go build -o /out/app .
+EOF
+
+FROM scratch AS final
+COPY --from=build ["/out/app", "/app"]
+`,
+			wantWarnings: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs, err := parser.All(strings.NewReader(tt.def))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, warnings, err := ToDockerfile(defs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantDockerfile, string(got)); diff != "" {
+				t.Errorf("unexpected Dockerfile (-want +got):\n%s", diff)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("got %d warnings, want %d: %v", len(warnings), tt.wantWarnings, warnings)
+			}
+
+			// The generated Dockerfile must convert back without error.
+			if _, _, err := FromDockerfile(strings.NewReader(string(got))); err != nil {
+				t.Errorf("generated Dockerfile could not be parsed: %v", err)
+			}
+		})
+	}
+}
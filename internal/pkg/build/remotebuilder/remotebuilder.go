@@ -15,13 +15,17 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"strings"
 
 	golog "github.com/go-log/log"
+	jsonresp "github.com/sylabs/json-resp"
 	buildclient "github.com/sylabs/scs-build-client/client"
 	client "github.com/sylabs/scs-library-client/client"
+	"github.com/sylabs/singularity/v4/internal/pkg/build/buildserver"
 	"github.com/sylabs/singularity/v4/internal/pkg/client/library"
 	"github.com/sylabs/singularity/v4/pkg/build/types"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
@@ -54,8 +58,17 @@ func New(imagePath, libraryURL string, d types.Definition, isDetached, force boo
 		return nil, err
 	}
 
+	builderURL, err := url.Parse(builderAddr)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(builderURL.Path, "/") {
+		builderURL.Path += "/"
+	}
+
 	return &RemoteBuilder{
 		BuildClient: bc,
+		BuilderURL:  builderURL,
 		ImagePath:   imagePath,
 		Force:       force,
 		LibraryURL:  libraryURL,
@@ -146,7 +159,15 @@ func (rb *RemoteBuilder) Build(ctx context.Context) (err error) {
 	}
 	sylog.Debugf("Build response - id: %s, libref: %s", bi.ID(), bi.LibraryRef())
 
+	// A build server keeps the image itself, rather than pushing it to a library.
+	fromBuildServer := strings.HasPrefix(bi.LibraryRef(), buildserver.ImageRefPrefix)
+
 	// If we're doing an detached build, print help on how to download the image
+	if rb.IsDetached && fromBuildServer {
+		fmt.Printf("Build submitted! Once it is complete, the image can be downloaded from:\n")
+		fmt.Printf("\t%s\n", rb.imageURL(bi.ID()))
+		return nil
+	}
 	libraryRefRaw := strings.TrimPrefix(bi.LibraryRef(), "library://")
 	if rb.IsDetached {
 		fmt.Printf("Build submitted! Once it is complete, the image can be retrieved by running:\n")
@@ -184,6 +205,13 @@ func (rb *RemoteBuilder) Build(ctx context.Context) (err error) {
 		}
 	}
 
+	if fromBuildServer {
+		if err := rb.downloadImage(ctx, bi.ID()); err != nil {
+			return fmt.Errorf("failed to download image file: %w", err)
+		}
+		return nil
+	}
+
 	// If image destination is local file, pull image.
 	if !strings.HasPrefix(rb.ImagePath, "library://") {
 		f, err := os.OpenFile(rb.ImagePath, os.O_CREATE|os.O_TRUNC|os.O_RDWR|unix.O_NOFOLLOW, 0o777)
@@ -213,3 +241,44 @@ func (rb *RemoteBuilder) Build(ctx context.Context) (err error) {
 
 	return nil
 }
+
+// imageURL returns the URL of the image of a build run by a build server.
+func (rb *RemoteBuilder) imageURL(buildID string) string {
+	return rb.BuilderURL.ResolveReference(&url.URL{Path: "v1/build/" + buildID + "/image"}).String()
+}
+
+// downloadImage downloads the image of a build run by a build server to the
+// image path.
+func (rb *RemoteBuilder) downloadImage(ctx context.Context, buildID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rb.imageURL(buildID), nil)
+	if err != nil {
+		return err
+	}
+	if rb.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+rb.AuthToken)
+	}
+	req.Header.Set("User-Agent", useragent.Value())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		if err := jsonresp.ReadError(res.Body); err != nil {
+			return fmt.Errorf("%s: %w", res.Status, err)
+		}
+		return fmt.Errorf("%s", res.Status)
+	}
+
+	f, err := os.OpenFile(rb.ImagePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|unix.O_NOFOLLOW, 0o777)
+	if err != nil {
+		return fmt.Errorf("unable to open file %s for writing: %w", rb.ImagePath, err)
+	}
+	if _, err := io.Copy(f, res.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/v4/pkg/build/types"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// Steps of the build of a stage, in order. A resumed build skips the steps
+// that completed before the failure.
+const (
+	// stepBootstrap runs %pre, and bootstraps the stage rootfs.
+	stepBootstrap = "bootstrap"
+	// stepFiles creates apps, runs %setup, and copies %files.
+	stepFiles = "files"
+	// stepPost runs %post.
+	stepPost = "post"
+	// stepTest inserts metadata, and runs %test.
+	stepTest = "test"
+	// stepAssemble assembles the final image from the last stage.
+	stepAssemble = "assemble"
+)
+
+var buildSteps = []string{stepBootstrap, stepFiles, stepPost, stepTest, stepAssemble}
+
+// resumeFile is the name of the file recording the state of a failed build,
+// in the parent directory of the rootfs of the failed stage.
+const resumeFile = "resume.json"
+
+// resumeState records the state of a failed build, so that it can be resumed.
+type resumeState struct {
+	// Stage is the index of the stage that failed.
+	Stage int `json:"stage"`
+	// Step is the step of the stage that failed.
+	Step string `json:"step"`
+	// Stages holds the bundles of the stages up to, and including, the
+	// failed stage.
+	Stages []resumeStage `json:"stages"`
+}
+
+// resumeStage records the bundle of a stage of a failed build.
+type resumeStage struct {
+	Name         string            `json:"name"`
+	RootfsPath   string            `json:"rootfsPath"`
+	TmpDir       string            `json:"tmpPath"`
+	JSONObjects  map[string][]byte `json:"jsonObjects"`
+	SourceDigest string            `json:"sourceDigest,omitempty"`
+}
+
+// readResumeState reads the state of a failed build, kept in dir.
+func readResumeState(dir string) (*resumeState, error) {
+	data, err := os.ReadFile(filepath.Join(dir, resumeFile))
+	if err != nil {
+		return nil, fmt.Errorf("%s does not hold a failed build: %w", dir, err)
+	}
+	var state resumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("while reading state of failed build: %w", err)
+	}
+	if state.Stage < 0 || state.Stage >= len(state.Stages) || !slices.Contains(buildSteps, state.Step) {
+		return nil, fmt.Errorf("invalid state of failed build in %s", dir)
+	}
+	return &state, nil
+}
+
+// resumeBundles re-opens the bundles of the failed build recorded in state,
+// for the stages of defs up to the failed stage. The returned slice holds, for
+// each of these stages, the index of the first build step to run.
+func resumeBundles(state *resumeState, defs []types.Definition) ([]*types.Bundle, []int, error) {
+	if len(defs) <= state.Stage {
+		return nil, nil, fmt.Errorf("definition has %d stage(s), but the failed build stopped in stage %d", len(defs), state.Stage+1)
+	}
+
+	bundles := make([]*types.Bundle, 0, state.Stage+1)
+	resumeFrom := make([]int, 0, state.Stage+1)
+	for i, rs := range state.Stages[:state.Stage+1] {
+		if name := defs[i].Header["stage"]; name != rs.Name {
+			return nil, nil, fmt.Errorf("stage %d is named %q in the definition, but %q in the failed build", i+1, name, rs.Name)
+		}
+		b, err := types.OpenBundle(rs.RootfsPath, rs.TmpDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rs.JSONObjects != nil {
+			b.JSONObjects = rs.JSONObjects
+		}
+		b.SourceDigest = rs.SourceDigest
+		bundles = append(bundles, b)
+
+		// Stages before the failed stage are complete.
+		from := slices.Index(buildSteps, stepAssemble)
+		if i == state.Stage {
+			from = slices.Index(buildSteps, state.Step)
+		}
+		resumeFrom = append(resumeFrom, from)
+	}
+
+	return bundles, resumeFrom, nil
+}
+
+// progress records that the build has reached step of stage i, and returns
+// whether the step must be run, i.e. that it was not completed by the failed
+// build that is being resumed.
+func (b *Build) progress(i int, step string) bool {
+	b.failedStage, b.failedStep = i, step
+	return slices.Index(buildSteps, step) >= b.stages[i].resumeFrom
+}
+
+// removeResumeState removes the state of the failed build that is resumed,
+// unless it is kept in dir, where it was replaced by the state of a new
+// failure.
+func (b *Build) removeResumeState(dir string) {
+	if b.Conf.Resume == "" || filepath.Clean(b.Conf.Resume) == filepath.Clean(dir) {
+		return
+	}
+	if err := os.Remove(filepath.Join(b.Conf.Resume, resumeFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		sylog.Warningf("Could not remove state of resumed build: %v", err)
+	}
+}
+
+// keepFailed is called when the build fails. With --keep-failed, it keeps the
+// bundles of the stages up to the failed stage, and records the state needed
+// to resume the build. With --shell-on-failure, it runs an interactive shell
+// in the rootfs of the failed stage. Nothing new is kept if the build failed
+// before the first step of a stage was reached.
+func (b *Build) keepFailed() {
+	if b.failedStep == "" {
+		if b.Conf.Resume != "" {
+			sylog.Warningf("Build failed before any stage was started, it can be resumed again with --resume %s", b.Conf.Resume)
+		} else {
+			sylog.Warningf("Build failed before any stage was started, no sandbox kept")
+		}
+		return
+	}
+
+	failed := b.stages[b.failedStage]
+	dir := filepath.Dir(failed.b.RootfsPath)
+
+	if b.Conf.KeepFailed {
+		state := resumeState{
+			Stage: b.failedStage,
+			Step:  b.failedStep,
+		}
+		for _, s := range b.stages[:b.failedStage+1] {
+			state.Stages = append(state.Stages, resumeStage{
+				Name:         s.name,
+				RootfsPath:   s.b.RootfsPath,
+				TmpDir:       s.b.TmpDir,
+				JSONObjects:  s.b.JSONObjects,
+				SourceDigest: s.b.SourceDigest,
+			})
+		}
+
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err == nil {
+			err = fs.WriteFileNoFollow(filepath.Join(dir, resumeFile), data, 0o600)
+		}
+		if err != nil {
+			sylog.Errorf("Could not record state of failed build: %v", err)
+		} else {
+			b.removeResumeState(dir)
+			b.kept = b.failedStage + 1
+			sylog.Infof("Failed stage sandbox kept at: %s", failed.b.RootfsPath)
+			sylog.Infof("Fix the definition file, then continue the build from the %s step with --resume %s", b.failedStep, dir)
+		}
+	}
+
+	if b.Conf.ShellOnFailure {
+		sylog.Infof("Starting a shell in the failed stage sandbox. Exit the shell to end the build.")
+		exe := filepath.Join(buildcfg.BINDIR, "singularity")
+		cmd := exec.Command(exe, "shell", "--writable", failed.b.RootfsPath)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Dir = "/"
+		cmd.Env = currentEnvNoSingularity([]string{"DEBUG"})
+		if err := cmd.Run(); err != nil {
+			sylog.Warningf("Shell exited with error: %v", err)
+		}
+	}
+}
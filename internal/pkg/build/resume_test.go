@@ -0,0 +1,163 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sylabs/singularity/v4/pkg/build/types"
+)
+
+func writeResumeState(t *testing.T, dir string, state resumeState) {
+	t.Helper()
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, resumeFile), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	tmpDir := t.TempDir()
+	var stages []resumeStage
+	for _, name := range []string{"build", "final"} {
+		parent := t.TempDir()
+		rootfs := filepath.Join(parent, "rootfs")
+		if err := os.Mkdir(rootfs, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		bundleTmp, err := os.MkdirTemp(tmpDir, "bundle-temp-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		stages = append(stages, resumeStage{
+			Name:        name,
+			RootfsPath:  rootfs,
+			TmpDir:      bundleTmp,
+			JSONObjects: map[string][]byte{types.OCIConfigJSON: []byte("{}")},
+		})
+	}
+	dir := filepath.Dir(stages[1].RootfsPath)
+
+	defs := []types.Definition{
+		{Header: map[string]string{"stage": "build"}},
+		{Header: map[string]string{"stage": "final"}},
+	}
+
+	t.Run("NoState", func(t *testing.T) {
+		_, err := readResumeState(t.TempDir())
+		assert.ErrorContains(t, err, "does not hold a failed build")
+	})
+
+	t.Run("InvalidStep", func(t *testing.T) {
+		writeResumeState(t, dir, resumeState{Stage: 1, Step: "unknown", Stages: stages})
+		_, err := readResumeState(dir)
+		assert.ErrorContains(t, err, "invalid state")
+	})
+
+	t.Run("StageMismatch", func(t *testing.T) {
+		writeResumeState(t, dir, resumeState{Stage: 1, Step: stepPost, Stages: stages})
+		state, err := readResumeState(dir)
+		assert.NoError(t, err)
+		renamed := []types.Definition{defs[0], {Header: map[string]string{"stage": "runtime"}}}
+		_, _, err = resumeBundles(state, renamed)
+		assert.ErrorContains(t, err, `named "runtime"`)
+		_, _, err = resumeBundles(state, defs[:1])
+		assert.ErrorContains(t, err, "has 1 stage(s)")
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		writeResumeState(t, dir, resumeState{Stage: 1, Step: stepPost, Stages: stages})
+		state, err := readResumeState(dir)
+		assert.NoError(t, err)
+		bundles, resumeFrom, err := resumeBundles(state, defs)
+		assert.NoError(t, err)
+		assert.Len(t, bundles, 2)
+		assert.Equal(t, []int{4, 2}, resumeFrom)
+		assert.Equal(t, stages[1].RootfsPath, bundles[1].RootfsPath)
+		assert.Equal(t, []byte("{}"), bundles[1].JSONObjects[types.OCIConfigJSON])
+
+		b := &Build{stages: []stage{
+			{b: bundles[0], resumeFrom: resumeFrom[0]},
+			{b: bundles[1], resumeFrom: resumeFrom[1]},
+		}}
+		assert.False(t, b.progress(0, stepTest))
+		assert.False(t, b.progress(1, stepFiles))
+		assert.True(t, b.progress(1, stepPost))
+		assert.Equal(t, 1, b.failedStage)
+		assert.Equal(t, stepPost, b.failedStep)
+	})
+}
+
+func TestKeepFailedBeforeStage(t *testing.T) {
+	rootfs := filepath.Join(t.TempDir(), "rootfs")
+	if err := os.Mkdir(rootfs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b := &Build{
+		stages: []stage{{b: &types.Bundle{RootfsPath: rootfs}}},
+		Conf:   Config{KeepFailed: true},
+	}
+	b.keepFailed()
+	assert.Equal(t, 0, b.kept)
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(rootfs), resumeFile))
+}
+
+func TestKeepFailedResumed(t *testing.T) {
+	var bundles []*types.Bundle
+	for range 2 {
+		rootfs := filepath.Join(t.TempDir(), "rootfs")
+		if err := os.Mkdir(rootfs, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		bundles = append(bundles, &types.Bundle{RootfsPath: rootfs})
+	}
+	resumeDir := filepath.Dir(bundles[0].RootfsPath)
+	newDir := filepath.Dir(bundles[1].RootfsPath)
+
+	newBuild := func() *Build {
+		writeResumeState(t, resumeDir, resumeState{Stage: 0, Step: stepPost, Stages: []resumeStage{{RootfsPath: bundles[0].RootfsPath}}})
+		return &Build{
+			stages: []stage{{b: bundles[0]}, {b: bundles[1]}},
+			Conf:   Config{KeepFailed: true, Resume: resumeDir},
+			kept:   1,
+		}
+	}
+
+	t.Run("BeforeStage", func(t *testing.T) {
+		b := newBuild()
+		b.keepFailed()
+		assert.Equal(t, 1, b.kept)
+		assert.FileExists(t, filepath.Join(resumeDir, resumeFile))
+	})
+
+	t.Run("SameStage", func(t *testing.T) {
+		b := newBuild()
+		b.progress(0, stepTest)
+		b.keepFailed()
+		assert.Equal(t, 1, b.kept)
+		state, err := readResumeState(resumeDir)
+		assert.NoError(t, err)
+		assert.Equal(t, stepTest, state.Step)
+	})
+
+	t.Run("LaterStage", func(t *testing.T) {
+		b := newBuild()
+		b.progress(1, stepFiles)
+		b.keepFailed()
+		assert.Equal(t, 2, b.kept)
+		assert.NoFileExists(t, filepath.Join(resumeDir, resumeFile))
+		state, err := readResumeState(newDir)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, state.Stage)
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sylabs/singularity/v4/pkg/build/types"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// secretsPath is the location of build secrets in the container.
+	secretsPath = "/run/secrets"
+	// secretsTmpfs is where build secrets are staged on the host.
+	secretsTmpfs = "/dev/shm"
+	// redactedSecret replaces secret values found in the stored definition.
+	redactedSecret = "<redacted>"
+)
+
+// stageSecrets writes the build secrets to a private directory on a tmpfs,
+// and creates the /run/secrets mount point in the stage rootfs. It returns the
+// staging directory, to be bound onto /run/secrets, and a cleanup function
+// that removes both the staged secrets and any mount point it created, and
+// may safely be called more than once.
+func (s *stage) stageSecrets() (string, func(), error) {
+	if len(s.b.Opts.Secrets) == 0 {
+		return "", func() {}, nil
+	}
+
+	parent := secretsTmpfs
+	var st unix.Statfs_t
+	if err := unix.Statfs(parent, &st); err != nil || st.Type != unix.TMPFS_MAGIC {
+		sylog.Warningf("%s is not a tmpfs: build secrets will be staged in %s", secretsTmpfs, s.b.TmpDir)
+		parent = s.b.TmpDir
+	}
+
+	dir, err := os.MkdirTemp(parent, "build-secrets-")
+	if err != nil {
+		return "", nil, fmt.Errorf("while creating secrets directory: %w", err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			sylog.Errorf("While removing build secrets from %s: %v", dir, err)
+		}
+	}
+
+	for _, sec := range s.b.Opts.Secrets {
+		v, err := sec.Value()
+		if err != nil {
+			cleanup()
+			return "", nil, err
+		}
+		path := filepath.Join(dir, sec.ID)
+		if err := os.WriteFile(path, v, 0o400); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("while staging secret %s: %w", sec.ID, err)
+		}
+	}
+
+	removeMountPoint, err := s.createSecretsMountPoint()
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return dir, sync.OnceFunc(func() {
+		removeMountPoint()
+		cleanup()
+	}), nil
+}
+
+// createSecretsMountPoint creates the /run/secrets directory in the stage
+// rootfs if it does not exist, and returns a function removing any directory
+// that was created, so that the mount point does not persist in the image.
+func (s *stage) createSecretsMountPoint() (func(), error) {
+	var created []string
+	for _, dir := range []string{"run", "run/secrets"} {
+		_, err := s.b.Rootfs.Lstat(dir)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("while checking %s in rootfs: %w", dir, err)
+		}
+		if err := s.b.Rootfs.Mkdir(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("while creating %s in rootfs: %w", dir, err)
+		}
+		created = append(created, dir)
+	}
+
+	return func() {
+		for i := len(created) - 1; i >= 0; i-- {
+			if err := s.b.Rootfs.Remove(created[i]); err != nil {
+				sylog.Warningf("While removing %s from rootfs: %v", created[i], err)
+			}
+		}
+	}, nil
+}
+
+// redactSecrets replaces any occurrence of the value of a build secret in the
+// raw definition, so that it is not stored in the image.
+func redactSecrets(raw []byte, secrets []types.Secret) ([]byte, error) {
+	for _, sec := range secrets {
+		v, err := sec.Value()
+		if err != nil {
+			return nil, err
+		}
+		v = bytes.TrimSpace(v)
+		if len(v) == 0 || !bytes.Contains(raw, v) {
+			continue
+		}
+		sylog.Warningf("Value of secret %s found in definition: it will be redacted from the stored definition", sec.ID)
+		raw = bytes.ReplaceAll(raw, v, []byte(redactedSecret))
+	}
+	return raw, nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sylabs/singularity/v4/pkg/build/types"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(src, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	secrets := []types.Secret{{ID: "token", Src: src}}
+
+	raw := []byte("%post\n    git clone https://s3cr3t@example.com/repo.git\n")
+	got, err := redactSecrets(raw, secrets)
+	assert.NoError(t, err)
+	assert.Equal(t, "%post\n    git clone https://<redacted>@example.com/repo.git\n", string(got))
+
+	raw = []byte("%post\n    git clone https://$(cat /run/secrets/token)@example.com/repo.git\n")
+	got, err = redactSecrets(raw, secrets)
+	assert.NoError(t, err)
+	assert.Equal(t, string(raw), string(got))
+}
+
+func TestStageSecrets(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(src, []byte("s3cr3t"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := types.NewBundle(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Remove()
+	b.Opts.Secrets = []types.Secret{{ID: "token", Src: src}}
+	s := &stage{b: b}
+
+	dir, cleanup, err := s.stageSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := os.ReadFile(filepath.Join(dir, "token"))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", string(v))
+	assert.DirExists(t, filepath.Join(b.RootfsPath, secretsPath))
+
+	cleanup()
+	cleanup()
+	assert.NoDirExists(t, dir)
+	assert.NoDirExists(t, filepath.Join(b.RootfsPath, "run"))
+}
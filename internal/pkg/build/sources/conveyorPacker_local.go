@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2024, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -8,9 +8,11 @@ package sources
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/sylabs/singularity/v4/pkg/build/types"
 	"github.com/sylabs/singularity/v4/pkg/image"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
@@ -33,6 +35,15 @@ func GetLocalPacker(ctx context.Context, src string, b *types.Bundle) (LocalPack
 		return nil, err
 	}
 
+	// Record the digest of an image file, as the source of the build.
+	if imageObject.Type != image.SANDBOX {
+		digest, err := fileDigest(src)
+		if err != nil {
+			return nil, fmt.Errorf("while computing digest of %s: %w", src, err)
+		}
+		b.SourceDigest = digest.String()
+	}
+
 	switch imageObject.Type {
 	case image.SIF:
 		sylog.Debugf("Packing from SIF")
@@ -96,6 +107,18 @@ func GetLocalPacker(ctx context.Context, src string, b *types.Bundle) (LocalPack
 	}
 }
 
+// fileDigest returns the SHA-256 digest of the file at path.
+func fileDigest(path string) (v1.Hash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	defer f.Close()
+
+	h, _, err := v1.SHA256(f)
+	return h, err
+}
+
 // Get just stores the source.
 func (cp *LocalConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err error) {
 	src := filepath.Clean(b.Recipe.Header["from"])
@@ -172,6 +172,12 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		return err
 	}
 
+	digest, err := cp.srcImg.Digest()
+	if err != nil {
+		return err
+	}
+	cp.b.SourceDigest = digest.String()
+
 	cf, err := cp.srcImg.ConfigFile()
 	if err != nil {
 		return err
@@ -229,6 +235,8 @@ func (cp *OCIConveyorPacker) insertOCIConfig() error {
 }
 
 func (cp *OCIConveyorPacker) unpackRootfs(ctx context.Context) error {
+	// The rootfs is modified by later build steps, so deduplication is left to
+	// the sandbox assembler.
 	if err := ociimage.UnpackRootfs(ctx, cp.srcImg, cp.b.RootfsPath); err != nil {
 		return err
 	}
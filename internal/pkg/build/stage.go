@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2023, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -28,6 +28,9 @@ type stage struct {
 	a Assembler
 	// b is an intermediate structure that encapsulates all information for the container, e.g., metadata, filesystems.
 	b *types.Bundle
+	// resumeFrom is the index in buildSteps of the first step to run, when
+	// resuming a failed build.
+	resumeFrom int
 }
 
 const (
@@ -76,7 +79,7 @@ func (s *stage) runHostScript(name string, script types.Script) error {
 	return nil
 }
 
-func (s *stage) runPostScript(configFile, sessionResolv, sessionHosts string) error {
+func (s *stage) runPostScript(configFile, sessionResolv, sessionHosts, secretsDir string) error {
 	if s.b.Recipe.BuildData.Post.Script != "" {
 		useBuildConfig := os.Geteuid() == 0 || buildcfg.SINGULARITY_SUID_INSTALL == 0
 
@@ -100,6 +103,10 @@ func (s *stage) runPostScript(configFile, sessionResolv, sessionHosts string) er
 		if sessionHosts != "" {
 			cmdArgs = append(cmdArgs, "-B", sessionHosts+":/etc/hosts")
 		}
+		if secretsDir != "" {
+			cmdArgs = append(cmdArgs, "-B", secretsDir+":"+secretsPath+":ro")
+		}
+		cmdArgs = append(cmdArgs, s.isolationArgs()...)
 
 		script := s.b.Recipe.BuildData.Post
 		scriptPath := filepath.Join(s.b.RootfsPath, ".post.script")
@@ -134,7 +141,7 @@ func (s *stage) runPostScript(configFile, sessionResolv, sessionHosts string) er
 	return nil
 }
 
-func (s *stage) runTestScript(configFile, sessionResolv, sessionHosts string) error {
+func (s *stage) runTestScript(configFile, sessionResolv, sessionHosts, secretsDir string) error {
 	if !s.b.Opts.NoTest && s.b.Recipe.BuildData.Test.Script != "" {
 		useBuildConfig := os.Geteuid() == 0 || buildcfg.SINGULARITY_SUID_INSTALL == 0
 
@@ -157,6 +164,10 @@ func (s *stage) runTestScript(configFile, sessionResolv, sessionHosts string) er
 		if sessionHosts != "" {
 			cmdArgs = append(cmdArgs, "-B", sessionHosts+":/etc/hosts")
 		}
+		if secretsDir != "" {
+			cmdArgs = append(cmdArgs, "-B", secretsDir+":"+secretsPath+":ro")
+		}
+		cmdArgs = append(cmdArgs, s.isolationArgs()...)
 
 		cmdArgs = append(cmdArgs, s.b.RootfsPath)
 
@@ -173,6 +184,24 @@ func (s *stage) runTestScript(configFile, sessionResolv, sessionHosts string) er
 	return nil
 }
 
+// isolationArgs returns the arguments applying the network and resource
+// limits requested for the build to the %post and %test sections. Resource
+// limits are applied to a cgroup by the runtime, as for --cpus and --memory
+// with singularity exec.
+func (s *stage) isolationArgs() []string {
+	var args []string
+	if s.b.Opts.Network == "none" {
+		args = append(args, "--net", "--network", "none")
+	}
+	if s.b.Opts.CPUs != "" {
+		args = append(args, "--cpus", s.b.Opts.CPUs)
+	}
+	if s.b.Opts.Memory != "" {
+		args = append(args, "--memory", s.b.Opts.Memory)
+	}
+	return args
+}
+
 func (s *stage) copyFilesFrom(b *Build) error {
 	def := s.b.Recipe
 	for _, f := range def.BuildData.Files {
@@ -226,12 +255,39 @@ func (s *stage) copyFiles() error {
 			sylog.Warningf("Attempt to copy file with no name, skipping.")
 			continue
 		}
+		src := transfer.Src
+		if s.b.Opts.ContextDir != "" {
+			var err error
+			if src, err = contextPath(s.b.Opts.ContextDir, src); err != nil {
+				return err
+			}
+		}
 		// copy each file into bundle rootfs
 		sylog.Infof("Copying %v to %v", transfer.Src, transfer.Dst)
-		if err := files.CopyFromHost(transfer.Src, transfer.Dst, s.b.RootfsPath); err != nil {
+		if err := files.CopyFromHost(src, transfer.Dst, s.b.RootfsPath); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// contextPath resolves src, the source of a file copied from the host, inside
+// the build context directory dir. An absolute src is taken relative to dir,
+// and a relative src to the current directory, which must be inside dir.
+func contextPath(dir, src string) (string, error) {
+	path := filepath.Join(dir, filepath.Clean("/"+src))
+	if !filepath.IsAbs(src) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(wd, src)
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return "", fmt.Errorf("%s is outside of the build context", src)
+	}
+	return path, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sylabs/singularity/v4/pkg/build/types"
+)
+
+func TestIsolationArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts types.Options
+		want []string
+	}{
+		{
+			name: "Default",
+			opts: types.Options{Network: "default"},
+			want: nil,
+		},
+		{
+			name: "NoNetwork",
+			opts: types.Options{Network: "none"},
+			want: []string{"--net", "--network", "none"},
+		},
+		{
+			name: "Limits",
+			opts: types.Options{CPUs: "2.5", Memory: "4G"},
+			want: []string{"--cpus", "2.5", "--memory", "4G"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &stage{b: &types.Bundle{Opts: tt.opts}}
+			assert.Equal(t, tt.want, s.isolationArgs())
+		})
+	}
+}
+
+func TestContextPath(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Absolute",
+			src:  "/home/user/file.txt",
+			want: filepath.Join(dir, "home/user/file.txt"),
+		},
+		{
+			name: "Relative",
+			src:  "data/*.csv",
+			want: filepath.Join(dir, "data/*.csv"),
+		},
+		{
+			name: "Root",
+			src:  "/",
+			want: dir,
+		},
+		{
+			name: "AbsoluteEscape",
+			src:  "/../../etc/shadow",
+			want: filepath.Join(dir, "etc/shadow"),
+		},
+		{
+			name:    "RelativeEscape",
+			src:     "../etc/shadow",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := contextPath(dir, tt.src)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
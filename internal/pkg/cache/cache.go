@@ -36,6 +36,9 @@ const (
 	DirEnv = "SINGULARITY_CACHEDIR"
 	// DisableEnv specifies whether the image should be used
 	DisableEnv = "SINGULARITY_DISABLE_CACHE"
+	// DedupeEnv specifies whether unpacked rootfs files should be deduplicated
+	// through the content store
+	DedupeEnv = "SINGULARITY_CACHE_DEDUPE"
 	// SubDirName specifies the name of the directory relative to the
 	// ParentDir specified when the cache is created.
 	// By default the cache will be placed at "~/.singularity/cache" which
@@ -57,6 +60,10 @@ const (
 
 	// OciBlobCacheType specifies the cache holds OCI blobs (layers) pulled from OCI sources
 	OciBlobCacheType = "blob"
+
+	// ContentCacheType specifies the cache holds the content store, used to
+	// deduplicate files in unpacked root filesystems
+	ContentCacheType = "cas"
 )
 
 var (
@@ -73,8 +80,13 @@ var (
 	OciCacheTypes = []string{
 		OciBlobCacheType,
 	}
-	// AllCacheTypes lists both file and OCI layout cache types.
-	AllCacheTypes = append(FileCacheTypes, OciCacheTypes...)
+	// ContentCacheTypes lists the content store cache types, that hold objects
+	// shared with files in unpacked root filesystems.
+	ContentCacheTypes = []string{
+		ContentCacheType,
+	}
+	// AllCacheTypes lists file, OCI layout, and content store cache types.
+	AllCacheTypes = slices.Concat(FileCacheTypes, OciCacheTypes, ContentCacheTypes)
 )
 
 // Config describes the requested configuration requested when a new handle is created,
@@ -84,6 +96,9 @@ type Config struct {
 	ParentDir string
 	// Disable specifies whether the user request the cache to be disabled by default.
 	Disable bool
+	// Dedupe specifies whether the user requests deduplication of unpacked
+	// rootfs files through the content store.
+	Dedupe bool
 }
 
 // Handle is an structure representing the image cache, it's location and subdirectories
@@ -98,6 +113,8 @@ type Handle struct {
 	rootDir string
 	// If the cache is disabled
 	disabled bool
+	// If rootfs files are deduplicated through the content store
+	dedupe bool
 }
 
 func (h *Handle) GetFileCacheDir(cacheType string) (cacheDir string, err error) {
@@ -283,6 +300,12 @@ func New(cfg Config) (h *Handle, err error) {
 	if cacheDisabled || cfg.Disable {
 		h.disabled = true
 	}
+	dedupe, err := parseBoolEnv(DedupeEnv)
+	if err != nil {
+		return nil, err
+	}
+	h.dedupe = dedupe || cfg.Dedupe
+
 	// If the cache is disabled, we stop here. Basically we return a valid handle that is not fully initialized
 	// since it would create the directories required by an enabled cache.
 	if h.disabled {
@@ -335,6 +358,20 @@ func New(cfg Config) (h *Handle, err error) {
 	return h, nil
 }
 
+// parseBoolEnv parses the boolean value of environment variable env, which is
+// false if unset.
+func parseBoolEnv(env string) (bool, error) {
+	v := os.Getenv(env)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse environment variable %s: %s", env, err)
+	}
+	return b, nil
+}
+
 // getCacheParentDir figures out where the parent directory of the cache is.
 //
 // Singularity makes the following assumptions:
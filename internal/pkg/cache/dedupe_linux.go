@@ -0,0 +1,53 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/dedupe"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// ContentStore returns the content store used to deduplicate files in unpacked
+// root filesystems. If the cache is disabled, or deduplication was not
+// requested, nil is returned.
+func (h *Handle) ContentStore() (*dedupe.Store, error) {
+	if h == nil || h.disabled || !h.dedupe {
+		return nil, nil
+	}
+	return dedupe.New(h.getCacheTypeDir(ContentCacheType))
+}
+
+// ContentStoreUsage returns the usage of the content store, whether or not
+// deduplication was requested.
+func (h *Handle) ContentStoreUsage() (dedupe.Usage, error) {
+	if h.disabled {
+		return dedupe.Usage{}, errCacheDisabled
+	}
+	store, err := dedupe.New(h.getCacheTypeDir(ContentCacheType))
+	if err != nil {
+		return dedupe.Usage{}, err
+	}
+	return store.Usage()
+}
+
+// CleanContentStore removes the objects of the content store that have not
+// been shared with a file for the specified number of days.
+func (h *Handle) CleanContentStore(dryRun bool, days int) error {
+	if h.disabled {
+		return errCacheDisabled
+	}
+	store, err := dedupe.New(h.getCacheTypeDir(ContentCacheType))
+	if err != nil {
+		return err
+	}
+	n, size, err := store.Clean(days, dryRun)
+	if err != nil {
+		return err
+	}
+	sylog.Infof("Removing %d content store objects (%s)", n, fs.FindSize(size))
+	return nil
+}
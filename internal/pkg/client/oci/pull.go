@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2018-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -29,6 +29,7 @@ type PullOptions struct {
 	NoCleanUp   bool
 	OciSif      bool
 	KeepLayers  bool
+	LayerFormat string
 	WithCosign  bool
 	Platform    gccrv1.Platform
 	ReqAuthFile string
@@ -63,6 +64,7 @@ func PullToCache(ctx context.Context, imgCache *cache.Handle, pullFrom string, o
 			Platform:    opts.Platform,
 			ReqAuthFile: opts.ReqAuthFile,
 			KeepLayers:  opts.KeepLayers,
+			LayerFormat: opts.LayerFormat,
 			WithCosign:  opts.WithCosign,
 		}
 		return ocisif.PullOCISIF(ctx, imgCache, "", pullFrom, ocisifOpts)
@@ -93,6 +95,7 @@ func PullToFile(ctx context.Context, imgCache *cache.Handle, pullTo, pullFrom st
 			Platform:    opts.Platform,
 			ReqAuthFile: opts.ReqAuthFile,
 			KeepLayers:  opts.KeepLayers,
+			LayerFormat: opts.LayerFormat,
 			WithCosign:  opts.WithCosign,
 		}
 		src, err = ocisif.PullOCISIF(ctx, imgCache, directTo, pullFrom, ocisifOpts)
@@ -1,4 +1,4 @@
-// Copyright (c) 2023-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2023-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -7,10 +7,14 @@ package oci
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/sylabs/singularity/v4/internal/pkg/client/ocisif"
+	ocisifimg "github.com/sylabs/singularity/v4/internal/pkg/ocisif"
 	"github.com/sylabs/singularity/v4/pkg/image"
 )
 
@@ -31,11 +35,40 @@ type PushOptions struct {
 	// WithCosign sets whether to push any associated cosign signatures when
 	// pushing an OCI-SIF to a registry.
 	WithCosign bool
+	// PlatformImages sets whether to push the per-platform OCI-SIF images
+	// written in place of the source file by a multi-platform build, as an OCI
+	// image index.
+	PlatformImages bool
 }
 
 // Push pushes an image into an OCI registry, as an OCI image (not an ORAS artifact).
-// At present, only OCI-SIF images can be pushed in this manner.
+// At present, only OCI-SIF images can be pushed in this manner. If
+// opts.PlatformImages is set, the per-platform OCI-SIF images written in place
+// of sourceFile by a multi-platform build are pushed together as an OCI image
+// index.
 func Push(ctx context.Context, sourceFile string, destRef string, opts PushOptions) error {
+	ocisifOpts := ocisif.PushOptions{
+		Auth:        opts.Auth,
+		AuthFile:    opts.AuthFile,
+		LayerFormat: opts.LayerFormat,
+		TmpDir:      opts.TmpDir,
+		WithCosign:  opts.WithCosign,
+	}
+
+	if opts.PlatformImages {
+		if _, err := os.Stat(sourceFile); !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s exists: per-platform images are written in place of the requested path by a multi-platform build", sourceFile)
+		}
+		images, err := ocisifimg.PlatformImages(sourceFile)
+		if err != nil {
+			return err
+		}
+		if len(images) == 0 {
+			return fmt.Errorf("no per-platform images of %s found", sourceFile)
+		}
+		return ocisif.PushOCISIFIndex(ctx, images, destRef, ocisifOpts)
+	}
+
 	img, err := image.Init(sourceFile, false)
 	if err != nil {
 		return err
@@ -44,13 +77,6 @@ func Push(ctx context.Context, sourceFile string, destRef string, opts PushOptio
 
 	switch img.Type {
 	case image.OCISIF:
-		ocisifOpts := ocisif.PushOptions{
-			Auth:        opts.Auth,
-			AuthFile:    opts.AuthFile,
-			LayerFormat: opts.LayerFormat,
-			TmpDir:      opts.TmpDir,
-			WithCosign:  opts.WithCosign,
-		}
 		return ocisif.PushOCISIF(ctx, sourceFile, destRef, ocisifOpts)
 	case image.SIF:
 		return fmt.Errorf("non OCI SIF images can only be pushed to OCI registries via oras://")
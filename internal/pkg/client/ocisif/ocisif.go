@@ -1,4 +1,4 @@
-// Copyright (c) 2023-2025 Sylabs Inc. All rights reserved.
+// Copyright (c) 2023-2026 Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -16,9 +16,12 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	cosignremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
 	ocimutate "github.com/sylabs/oci-tools/pkg/mutate"
 	ocitsif "github.com/sylabs/oci-tools/pkg/sif"
@@ -49,6 +52,9 @@ type PullOptions struct {
 	Platform    ggcrv1.Platform
 	ReqAuthFile string
 	KeepLayers  bool
+	// LayerFormat is the filesystem format of layers - one of the
+	// ocisif.xxxLayerFormat constants. Squashfs is used if unset.
+	LayerFormat string
 	WithCosign  bool
 }
 
@@ -85,6 +91,9 @@ func PullOCISIF(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom
 		if opts.KeepLayers {
 			cacheSuffix = cacheSuffixMultiLayer
 		}
+		if opts.LayerFormat != "" && opts.LayerFormat != ocisif.SquashfsLayerFormat {
+			cacheSuffix += "." + opts.LayerFormat
+		}
 		cacheEntry, err := imgCache.GetEntry(cache.OciSifCacheType, hash.String()+cacheSuffix)
 		if err != nil {
 			return "", fmt.Errorf("unable to check if %v exists in cache: %v", hash, err)
@@ -144,7 +153,7 @@ func createOciSif(ctx context.Context, tOpts *ociimage.TransportOptions, imgCach
 		return fmt.Errorf("while fetching OCI image: %w", err)
 	}
 
-	iwOpts := []ocisif.ImageWriterOpt{ocisif.WithSquashFSLayers(true)}
+	iwOpts := []ocisif.ImageWriterOpt{ocisif.WithSquashFSLayers(true), ocisif.WithLayerFormat(opts.LayerFormat)}
 	if !opts.KeepLayers {
 		iwOpts = append(iwOpts, ocisif.WithSquash(true))
 	}
@@ -157,7 +166,7 @@ func createOciSif(ctx context.Context, tOpts *ociimage.TransportOptions, imgCach
 	}
 
 	if opts.WithCosign {
-		if err := canPullSignatures(img, opts.KeepLayers); err != nil {
+		if err := canPullSignatures(img, opts.KeepLayers, opts.LayerFormat); err != nil {
 			sylog.Warningf("Not fetching cosign signatures: %v", err)
 			return nil
 		}
@@ -167,7 +176,7 @@ func createOciSif(ctx context.Context, tOpts *ociimage.TransportOptions, imgCach
 	return nil
 }
 
-func canPullSignatures(img ggcrv1.Image, keepLayers bool) error {
+func canPullSignatures(img ggcrv1.Image, keepLayers bool, layerFormat string) error {
 	layers, err := img.Layers()
 	if err != nil {
 		return err
@@ -175,6 +184,9 @@ func canPullSignatures(img ggcrv1.Image, keepLayers bool) error {
 	if len(layers) > 1 && !keepLayers {
 		return fmt.Errorf("pulling a multiple layer image without --keep-layers invalidates signatures")
 	}
+	if layerFormat != "" && layerFormat != ocisif.SquashfsLayerFormat {
+		return fmt.Errorf("converting layers to %s invalidates signatures", layerFormat)
+	}
 	for _, l := range layers {
 		mt, err := l.MediaType()
 		if err != nil {
@@ -265,6 +277,10 @@ func PushOCISIF(ctx context.Context, sourceFile, destRef string, opts PushOption
 		return fmt.Errorf("invalid reference %q: %w", destRef, err)
 	}
 
+	if err := ocisif.CheckSingleImage(sourceFile); err != nil {
+		return err
+	}
+
 	if err := handleOverlay(sourceFile, opts); err != nil {
 		return err
 	}
@@ -287,46 +303,82 @@ func PushOCISIF(ctx context.Context, sourceFile, destRef string, opts PushOption
 		return err
 	}
 
-	remoteOpts := []remote.Option{
-		ociauth.AuthOptn(opts.Auth, opts.AuthFile),
-		remote.WithUserAgent(useragent.Value()),
-		remote.WithContext(ctx),
+	if err := remote.Write(ir, image, remoteOptions(ctx, opts)...); err != nil {
+		return err
 	}
-	if term.IsTerminal(2) {
-		pb := &progress.DownloadBar{}
-		progChan := make(chan ggcrv1.Update, 1)
-		go func() {
-			var total int64
-			soFar := int64(0)
-			for {
-				// The following is concurrency-safe because this is the only
-				// goroutine that's going to be reading progChan updates.
-				update := <-progChan
-				if update.Error != nil {
-					pb.Abort(false)
-					return
-				}
-				if update.Total != total {
-					pb.Init(update.Total)
-					total = update.Total
-				}
-				pb.IncrBy(int(update.Complete - soFar))
-				soFar = update.Complete
-				if soFar >= total {
-					pb.Wait()
-					return
-				}
-			}
-		}()
-		remoteOpts = append(remoteOpts, remote.WithProgress(progChan))
+
+	if opts.WithCosign {
+		return pushSignatures(ctx, ir, d, opts)
+	}
+
+	return nil
+}
+
+// PushOCISIFIndex pushes the single images of the OCI-SIF sourceFiles, each
+// built for a different platform, to the OCI registry destRef, as an OCI image
+// index holding one manifest per platform.
+func PushOCISIFIndex(ctx context.Context, sourceFiles []string, destRef string, opts PushOptions) error {
+	destRef = strings.TrimPrefix(destRef, "docker://")
+	destRef = strings.TrimPrefix(destRef, "//")
+	ir, err := name.ParseReference(destRef)
+	if err != nil {
+		return fmt.Errorf("invalid reference %q: %w", destRef, err)
 	}
 
-	if err := remote.Write(ir, image, remoteOpts...); err != nil {
+	idx := mutate.IndexMediaType(empty.Index, types.OCIImageIndex)
+	descriptors := make([]sourcesink.Descriptor, 0, len(sourceFiles))
+	for _, sourceFile := range sourceFiles {
+		if err := ocisif.CheckSingleImage(sourceFile); err != nil {
+			return err
+		}
+		if err := handleOverlay(sourceFile, opts); err != nil {
+			return err
+		}
+
+		ss, err := sourcesink.SIFFromPath(sourceFile)
+		if err != nil {
+			return fmt.Errorf("failed to open OCI-SIF: %w", err)
+		}
+		d, err := ss.Get(ctx)
+		if err != nil {
+			return fmt.Errorf("while fetching image from OCI-SIF %s: %v", sourceFile, err)
+		}
+		image, err := d.Image()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve image: %w", err)
+		}
+		image, err = transformLayers(image, opts)
+		if err != nil {
+			return err
+		}
+
+		cf, err := image.ConfigFile()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve image config: %w", err)
+		}
+		p := cf.Platform()
+		if p == nil {
+			return fmt.Errorf("image in %s does not declare a platform", sourceFile)
+		}
+		sylog.Infof("Adding %s image from %s", p.String(), sourceFile)
+
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add:        image,
+			Descriptor: ggcrv1.Descriptor{Platform: p},
+		})
+		descriptors = append(descriptors, d)
+	}
+
+	if err := remote.WriteIndex(ir, idx, remoteOptions(ctx, opts)...); err != nil {
 		return err
 	}
 
 	if opts.WithCosign {
-		return pushSignatures(ctx, ir, d, opts)
+		for _, d := range descriptors {
+			if err := pushSignatures(ctx, ir, d, opts); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -346,6 +398,10 @@ func transformLayers(base ggcrv1.Image, opts PushOptions) (ggcrv1.Image, error)
 			return nil, err
 		}
 
+		if opts.LayerFormat != DefaultLayerFormat && ocisif.IsEncryptedLayer(mt) {
+			return nil, fmt.Errorf("encrypted layers cannot be pushed with layer format %q", opts.LayerFormat)
+		}
+
 		switch opts.LayerFormat {
 		case DefaultLayerFormat:
 			continue
@@ -354,6 +410,9 @@ func transformLayers(base ggcrv1.Image, opts PushOptions) (ggcrv1.Image, error)
 				return nil, fmt.Errorf("unexpected layer mediaType: %v", mt)
 			}
 		case TarLayerFormat:
+			if mt == ocisif.ErofsLayerMediaType {
+				return nil, fmt.Errorf("erofs layers cannot be pushed with layer format %q", opts.LayerFormat)
+			}
 			opener, err := ocimutate.TarFromSquashfsLayer(l, ocimutate.OptTarTempDir(opts.TmpDir))
 			if err != nil {
 				return nil, err
@@ -434,3 +493,42 @@ func pushSignatures(ctx context.Context, ir name.Reference, d sourcesink.Descrip
 	}
 	return remote.Write(csRef, sigImg, remoteOpts...)
 }
+
+// remoteOptions returns the options used to write images to a registry,
+// with a progress bar if stderr is a terminal.
+func remoteOptions(ctx context.Context, opts PushOptions) []remote.Option {
+	remoteOpts := []remote.Option{
+		ociauth.AuthOptn(opts.Auth, opts.AuthFile),
+		remote.WithUserAgent(useragent.Value()),
+		remote.WithContext(ctx),
+	}
+	if term.IsTerminal(2) {
+		pb := &progress.DownloadBar{}
+		progChan := make(chan ggcrv1.Update, 1)
+		go func() {
+			var total int64
+			soFar := int64(0)
+			for {
+				// The following is concurrency-safe because this is the only
+				// goroutine that's going to be reading progChan updates.
+				update := <-progChan
+				if update.Error != nil {
+					pb.Abort(false)
+					return
+				}
+				if update.Total != total {
+					pb.Init(update.Total)
+					total = update.Total
+				}
+				pb.IncrBy(int(update.Complete - soFar))
+				soFar = update.Complete
+				if soFar >= total {
+					pb.Wait()
+					return
+				}
+			}
+		}()
+		remoteOpts = append(remoteOpts, remote.WithProgress(progChan))
+	}
+	return remoteOpts
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cosign
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	cosignremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+)
+
+// ImageDigest returns the manifest digest of the single OCI container image in
+// the OCI-SIF at sifPath.
+func ImageDigest(ctx context.Context, sifPath string) (v1.Hash, error) {
+	si, err := signedImage(ctx, sifPath)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	return si.Digest()
+}
+
+// AttachAttestation adds the DSSE envelope env to the OCI-SIF at sifPath, as a
+// cosign compatible attestation of the single OCI container image it holds.
+func AttachAttestation(ctx context.Context, sifPath string, env []byte) error {
+	si, err := signedImage(ctx, sifPath)
+	if err != nil {
+		return err
+	}
+	digest, err := si.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve digest: %w", err)
+	}
+
+	att, err := static.NewAttestation(env)
+	if err != nil {
+		return err
+	}
+	si, err = mutate.AttachAttestationToImage(si, att)
+	if err != nil {
+		return err
+	}
+	atts, err := si.Attestations()
+	if err != nil {
+		return err
+	}
+	return writeCosignImage(sifPath, digest, atts, cosignremote.AttestationTagSuffix)
+}
+
+// Attestations returns the DSSE envelopes of the cosign compatible
+// attestations of the single OCI container image in the OCI-SIF at sifPath,
+// along with the manifest digest of the image.
+func Attestations(ctx context.Context, sifPath string) ([][]byte, v1.Hash, error) {
+	si, err := signedImage(ctx, sifPath)
+	if err != nil {
+		return nil, v1.Hash{}, err
+	}
+	digest, err := si.Digest()
+	if err != nil {
+		return nil, v1.Hash{}, fmt.Errorf("failed to retrieve digest: %w", err)
+	}
+
+	atts, err := si.Attestations()
+	if err != nil {
+		return nil, v1.Hash{}, fmt.Errorf("failed to retrieve attestations: %w", err)
+	}
+	as, err := atts.Get()
+	if err != nil {
+		return nil, v1.Hash{}, fmt.Errorf("failed to retrieve attestations: %w", err)
+	}
+
+	envs := make([][]byte, 0, len(as))
+	for _, a := range as {
+		b, err := a.Payload()
+		if err != nil {
+			return nil, v1.Hash{}, fmt.Errorf("failed to retrieve attestation: %w", err)
+		}
+		envs = append(envs, b)
+	}
+	return envs, digest, nil
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	useragent "github.com/sylabs/singularity/v4/pkg/util/user-agent"
+)
+
+// DefaultFulcioURL is the certificate authority used when no other is
+// specified.
+const DefaultFulcioURL = "https://fulcio.sigstore.dev"
+
+var (
+	// oidIssuer is the deprecated Fulcio extension holding the OIDC issuer as
+	// a raw string.
+	oidIssuer = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	// oidIssuerV2 is the Fulcio extension holding the OIDC issuer as a DER
+	// encoded UTF8String.
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+
+	errNoCertificates = errors.New("certificate authority returned no certificates")
+)
+
+type fulcioRequest struct {
+	Credentials struct {
+		OIDCIdentityToken string `json:"oidcIdentityToken"`
+	} `json:"credentials"`
+	PublicKeyRequest struct {
+		PublicKey struct {
+			Algorithm string `json:"algorithm"`
+			Content   string `json:"content"`
+		} `json:"publicKey"`
+		ProofOfPossession []byte `json:"proofOfPossession"`
+	} `json:"publicKeyRequest"`
+}
+
+type fulcioChain struct {
+	Chain struct {
+		Certificates []string `json:"certificates"`
+	} `json:"chain"`
+}
+
+type fulcioResponse struct {
+	SignedCertificateEmbeddedSct *fulcioChain `json:"signedCertificateEmbeddedSct,omitempty"`
+	SignedCertificateDetachedSct *fulcioChain `json:"signedCertificateDetachedSct,omitempty"`
+}
+
+// tokenSubject returns the subject of the OIDC identity token, which Fulcio
+// requires to be signed as proof of possession of the private key. The token
+// signature is not verified; that is the responsibility of the CA.
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("identity token is not a JWT")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("while decoding identity token: %w", err)
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return "", fmt.Errorf("while decoding identity token: %w", err)
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	if claims.Subject == "" {
+		return "", errors.New("identity token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// fulcioCertificate requests a signing certificate for the public key of sv
+// from the Fulcio compatible CA at fulcioURL, authenticating with the OIDC
+// identity token. The PEM encoded leaf certificate, and the remainder of the
+// chain, are returned.
+func fulcioCertificate(ctx context.Context, client *http.Client, fulcioURL, token string, sv signature.SignerVerifier) (certPEM, chainPEM []byte, err error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	subject, err := tokenSubject(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof, err := sv.SignMessage(strings.NewReader(subject))
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, err := sv.PublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	pubPEM, err := cryptoutils.MarshalPublicKeyToPEM(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fr fulcioRequest
+	fr.Credentials.OIDCIdentityToken = token
+	fr.PublicKeyRequest.PublicKey.Algorithm = "ECDSA"
+	fr.PublicKeyRequest.PublicKey.Content = string(pubPEM)
+	fr.PublicKeyRequest.ProofOfPossession = proof
+	body, err := json.Marshal(fr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(fulcioURL, "/")+"/api/v2/signingCert", bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", useragent.Value())
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("certificate authority: %s: %s", res.Status, bytes.TrimSpace(b))
+	}
+
+	var r fulcioResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, nil, err
+	}
+	chain := r.SignedCertificateEmbeddedSct
+	if chain == nil {
+		chain = r.SignedCertificateDetachedSct
+	}
+	if chain == nil || len(chain.Chain.Certificates) == 0 {
+		return nil, nil, errNoCertificates
+	}
+
+	certs := chain.Chain.Certificates
+	return []byte(certs[0]), []byte(strings.Join(certs[1:], "")), nil
+}
+
+// certificateIssuer returns the OIDC issuer recorded in a Fulcio certificate.
+func certificateIssuer(c *x509.Certificate) (string, error) {
+	for _, ext := range c.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err != nil {
+				return "", err
+			}
+			return issuer, nil
+		case ext.Id.Equal(oidIssuer):
+			return string(ext.Value), nil
+		}
+	}
+	return "", errors.New("certificate has no OIDC issuer extension")
+}
+
+// certificateIdentities returns the email and URI subject alternative names
+// of c, which identify the signer in a Fulcio certificate.
+func certificateIdentities(c *x509.Certificate) []string {
+	ids := append([]string{}, c.EmailAddresses...)
+	for _, u := range c.URIs {
+		ids = append(ids, u.String())
+	}
+	return ids
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	signatureoptions "github.com/sigstore/sigstore/pkg/signature/options"
+	sigPayload "github.com/sigstore/sigstore/pkg/signature/payload"
+	"github.com/sylabs/singularity/v4/pkg/image"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var (
+	errIdentityMismatch = errors.New("certificate identity does not match")
+	errIssuerMismatch   = errors.New("certificate OIDC issuer does not match")
+)
+
+// KeylessSignOptions configures keyless signing, with a short-lived
+// certificate issued by a Fulcio compatible CA, and a signature recorded in a
+// Rekor compatible transparency log.
+type KeylessSignOptions struct {
+	// FulcioURL is the base URL of the certificate authority.
+	FulcioURL string
+	// RekorURL is the base URL of the transparency log.
+	RekorURL string
+	// IdentityToken is the OIDC identity token presented to the CA.
+	IdentityToken string
+	// HTTPClient is used for requests, or http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// SignOCISIFKeyless signs the single OCI container image in the OCI-SIF at
+// sifPath with an ephemeral key. The key is certified by the CA, using the OIDC
+// identity token, and the signature is uploaded to the transparency log. The
+// certificate chain and log entry are stored with the cosign compatible
+// signature added to the OCI-SIF.
+func SignOCISIFKeyless(ctx context.Context, sifPath string, opts KeylessSignOptions) error {
+	sign, err := keylessSigner(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return signImage(ctx, sifPath, sign)
+}
+
+// keylessSigner returns a function that signs a payload with an ephemeral key,
+// certified by the CA, recording the signature in the transparency log.
+func keylessSigner(ctx context.Context, opts KeylessSignOptions) (func(payload []byte) (oci.Signature, error), error) {
+	if opts.IdentityToken == "" {
+		return nil, errors.New("keyless signing requires an OIDC identity token")
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	sylog.Infof("Requesting signing certificate from %s", opts.FulcioURL)
+	certPEM, chainPEM, err := fulcioCertificate(ctx, opts.HTTPClient, opts.FulcioURL, opts.IdentityToken, sv)
+	if err != nil {
+		return nil, fmt.Errorf("while obtaining signing certificate: %w", err)
+	}
+
+	return func(payload []byte) (oci.Signature, error) {
+		sig, err := sv.SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		sylog.Infof("Uploading signature to transparency log %s", opts.RekorURL)
+		rc := newRekorClient(opts.RekorURL, opts.HTTPClient)
+		e, err := rc.upload(ctx, newHashedRekord(payload, sig, certPEM))
+		if err != nil {
+			return nil, fmt.Errorf("while uploading to transparency log: %w", err)
+		}
+		sylog.Infof("Transparency log entry created with index %d", e.LogIndex)
+
+		b64sig := base64.StdEncoding.EncodeToString(sig)
+		sylog.Debugf("Generated cosign signature: %v", b64sig)
+		return static.NewSignature(payload, b64sig,
+			static.WithCertChain(certPEM, chainPEM),
+			static.WithBundle(entryBundle(e)),
+		)
+	}, nil
+}
+
+// KeylessVerifyOptions configures verification of keyless signatures.
+type KeylessVerifyOptions struct {
+	// Roots holds the root certificates of the CA.
+	Roots *x509.CertPool
+	// Intermediates holds additional intermediate certificates of the CA.
+	Intermediates *x509.CertPool
+	// Identity is the required email or URI subject alternative name.
+	Identity string
+	// Issuer is the required OIDC issuer.
+	Issuer string
+	// RekorURL is the base URL of the transparency log.
+	RekorURL string
+	// RekorPublicKey is the trusted public key of the transparency log, which
+	// must be set. It is not retrieved from the log, which could substitute
+	// its own key.
+	RekorPublicKey crypto.PublicKey
+	// HTTPClient is used for requests, or http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// VerifyOCISIFKeyless checks that a single OCI container image, contained in
+// the OCI-SIF file at sifPath, has at least 1 keyless cosign signature. A valid
+// signature has a certificate that chains to opts.Roots, with the required
+// identity and issuer, at the time it was recorded in the transparency log. The
+// log entry must be signed by the log, and its inclusion in the log is checked
+// with a proof fetched from the log. Returns a JSON representation of valid
+// payloads.
+func VerifyOCISIFKeyless(ctx context.Context, sifPath string, opts KeylessVerifyOptions) ([]byte, error) {
+	ok, err := image.IsOCISIF(sifPath)
+	if err != nil {
+		return nil, fmt.Errorf("while checking OCI-SIF: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("not an OCI-SIF: %q", sifPath)
+	}
+	if opts.Roots == nil {
+		return nil, errors.New("keyless verification requires root certificates")
+	}
+	if opts.RekorPublicKey == nil {
+		return nil, errors.New("keyless verification requires the transparency log public key")
+	}
+
+	rc := newRekorClient(opts.RekorURL, opts.HTTPClient)
+	lv, err := signature.LoadVerifier(opts.RekorPublicKey, crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyOCIBlobDigests(sifPath); err != nil {
+		return nil, err
+	}
+
+	verify := func(ctx context.Context, s oci.Signature) (*sigPayload.SimpleContainerImage, error) {
+		return opts.verifySignature(ctx, s, rc, lv)
+	}
+	payloads, err := checkSignatures(ctx, sifPath, verify)
+	if err != nil {
+		return nil, err
+	}
+	if len(payloads) == 0 {
+		return nil, ErrNoValidSignatures
+	}
+
+	return json.Marshal(payloads)
+}
+
+// verifySignature verifies the keyless signature s, using the transparency log
+// client rc and log verifier lv.
+func (opts KeylessVerifyOptions) verifySignature(ctx context.Context, s oci.Signature, rc *rekorClient, lv signature.Verifier) (*sigPayload.SimpleContainerImage, error) {
+	cert, err := s.Cert()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve certificate: %w", err)
+	}
+	if cert == nil {
+		return nil, errors.New("signature has no certificate")
+	}
+	sig, err := s.Signature()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve signature: %w", err)
+	}
+	payload, err := s.Payload()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve payload: %w", err)
+	}
+	certPEM, err := cryptoutils.MarshalCertificateToPEM(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transparency log entry establishes when the signature was made, so
+	// must be checked before the short-lived certificate.
+	b, err := s.Bundle()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve transparency log bundle: %w", err)
+	}
+	if err := verifyBundle(b, lv, payload, sig, certPEM); err != nil {
+		return nil, err
+	}
+	if err := rc.verifyInclusion(ctx, b, lv); err != nil {
+		return nil, err
+	}
+
+	intermediates := x509.NewCertPool()
+	if opts.Intermediates != nil {
+		intermediates = opts.Intermediates.Clone()
+	}
+	chain, err := s.Chain()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve certificate chain: %w", err)
+	}
+	for _, c := range chain {
+		if !c.Equal(cert) {
+			intermediates.AddCert(c)
+		}
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         opts.Roots,
+		CurrentTime:   time.Unix(b.Payload.IntegratedTime, 0),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, fmt.Errorf("certificate not valid: %w", err)
+	}
+
+	if ids := certificateIdentities(cert); !slices.Contains(ids, opts.Identity) {
+		return nil, fmt.Errorf("%w: %v", errIdentityMismatch, ids)
+	}
+	issuer, err := certificateIssuer(cert)
+	if err != nil {
+		return nil, err
+	}
+	if issuer != opts.Issuer {
+		return nil, fmt.Errorf("%w: %s", errIssuerMismatch, issuer)
+	}
+
+	verifier, err := signature.LoadVerifier(cert.PublicKey, crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return verifySignature(s, verifier)
+}
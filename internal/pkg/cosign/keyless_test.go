@@ -0,0 +1,574 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	ocisif "github.com/sylabs/oci-tools/pkg/sif"
+	useragent "github.com/sylabs/singularity/v4/pkg/util/user-agent"
+)
+
+const (
+	testIdentity = "signer@example.com"
+	testIssuer   = "https://oidc.example.com"
+)
+
+// testToken returns an unsigned OIDC identity token for the test identity.
+func testToken(t *testing.T) string {
+	t.Helper()
+
+	claims, err := json.Marshal(map[string]string{
+		"iss":   testIssuer,
+		"sub":   "1234",
+		"email": testIdentity,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"none"}`)) + "." + enc(claims) + "." + enc([]byte("sig"))
+}
+
+// standInCA is a minimal Fulcio compatible certificate authority, which
+// trusts the claims of any identity token presented to it.
+type standInCA struct {
+	root    *x509.Certificate
+	rootKey *ecdsa.PrivateKey
+}
+
+func newStandInCA(t *testing.T) *standInCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "stand-in root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &standInCA{root: root, rootKey: key}
+}
+
+func (ca *standInCA) roots() *x509.CertPool {
+	p := x509.NewCertPool()
+	p.AddCert(ca.root)
+	return p
+}
+
+func (ca *standInCA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/api/v2/signingCert" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req fulcioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.Split(req.Credentials.OIDCIdentityToken, ".")[1])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+		Email  string `json:"email"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey([]byte(req.PublicKeyRequest.PublicKey.Content))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	v, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	proof := bytes.NewReader(req.PublicKeyRequest.ProofOfPossession)
+	if err := v.VerifySignature(proof, strings.NewReader(claims.Email)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	issuer, err := asn1.MarshalWithParams(claims.Issuer, "utf8")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(time.Now().UnixNano()),
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{claims.Email},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.root, pub, ca.rootKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	leafPEM, _ := cryptoutils.MarshalCertificateToPEM(&x509.Certificate{Raw: der})
+	rootPEM, _ := cryptoutils.MarshalCertificateToPEM(ca.root)
+	var res fulcioResponse
+	res.SignedCertificateEmbeddedSct = &fulcioChain{}
+	res.SignedCertificateEmbeddedSct.Chain.Certificates = []string{string(leafPEM), string(rootPEM)}
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// standInLog is a minimal Rekor compatible transparency log, holding entries
+// in memory.
+type standInLog struct {
+	mu      sync.Mutex
+	sv      signature.SignerVerifier
+	logID   string
+	entries []rekorEntry
+}
+
+func newStandInLog(t *testing.T) *standInLog {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logID, err := rekorLogID(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &standInLog{sv: sv, logID: logID}
+}
+
+// testTreeHash returns the RFC 6962 hash of the tree with the specified leaf
+// hashes.
+func testTreeHash(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	return nodeHash(testTreeHash(leaves[:k]), testTreeHash(leaves[k:]))
+}
+
+// testTreePath returns the RFC 6962 inclusion proof of leaf m in the tree with
+// the specified leaf hashes.
+func testTreePath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	if m < k {
+		return append(testTreePath(m, leaves[:k]), testTreeHash(leaves[k:]))
+	}
+	return append(testTreePath(m-k, leaves[k:]), testTreeHash(leaves[:k]))
+}
+
+// entry returns the entry at index, with an inclusion proof for the current
+// tree. Must be called with l.mu held.
+func (l *standInLog) entry(index int) (rekorEntry, error) {
+	leaves := make([][]byte, 0, len(l.entries))
+	for _, e := range l.entries {
+		body, err := base64.StdEncoding.DecodeString(e.Body)
+		if err != nil {
+			return rekorEntry{}, err
+		}
+		leaves = append(leaves, leafHash(body))
+	}
+	root := testTreeHash(leaves)
+
+	e := l.entries[index]
+	p := &rekorInclusionProof{
+		LogIndex: int64(index),
+		RootHash: hex.EncodeToString(root),
+		TreeSize: int64(len(leaves)),
+	}
+	for _, h := range testTreePath(index, leaves) {
+		p.Hashes = append(p.Hashes, hex.EncodeToString(h))
+	}
+
+	text := fmt.Sprintf("stand-in - 1\n%d\n%s\n", len(leaves), base64.StdEncoding.EncodeToString(root))
+	sig, err := l.sv.SignMessage(strings.NewReader(text))
+	if err != nil {
+		return rekorEntry{}, err
+	}
+	hint := []byte{0, 0, 0, 0}
+	p.Checkpoint = text + "\n— stand-in " + base64.StdEncoding.EncodeToString(append(hint, sig...)) + "\n"
+
+	e.Verification.InclusionProof = p
+	return e, nil
+}
+
+func (l *standInLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/log/entries":
+		index, err := strconv.Atoi(r.URL.Query().Get("logIndex"))
+		if err != nil || index < 0 || index >= len(l.entries) {
+			http.NotFound(w, r)
+			return
+		}
+		e, err := l.entry(index)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]rekorEntry{strconv.Itoa(index): e})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/log/entries":
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e := rekorEntry{
+			Body:           base64.StdEncoding.EncodeToString(body.Bytes()),
+			IntegratedTime: time.Now().Unix(),
+			LogID:          l.logID,
+			LogIndex:       int64(len(l.entries)),
+		}
+		set, _ := json.Marshal(rekorSETPayload{
+			Body:           e.Body,
+			IntegratedTime: e.IntegratedTime,
+			LogID:          e.LogID,
+			LogIndex:       e.LogIndex,
+		})
+		sig, err := l.sv.SignMessage(bytes.NewReader(set))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		e.Verification.SignedEntryTimestamp = sig
+		l.entries = append(l.entries, e)
+
+		e, err = l.entry(len(l.entries) - 1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]rekorEntry{strconv.Itoa(int(e.LogIndex)): e})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestVerifyInclusionProof(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		var leaves [][]byte
+		for i := range size {
+			leaves = append(leaves, leafHash([]byte{byte(i)}))
+		}
+		root := testTreeHash(leaves)
+
+		for i := range size {
+			proof := testTreePath(i, leaves)
+			if err := verifyInclusionProof(uint64(i), uint64(size), leaves[i], proof, root); err != nil {
+				t.Errorf("size %d index %d: %v", size, i, err)
+			}
+			if err := verifyInclusionProof(uint64(i), uint64(size), leafHash([]byte("bad")), proof, root); !errors.Is(err, errInclusionProof) {
+				t.Errorf("size %d index %d: got error %v for wrong leaf, want %v", size, i, err, errInclusionProof)
+			}
+		}
+	}
+}
+
+func TestKeylessSignature(t *testing.T) {
+	useragent.InitValue(t.Name(), "0.0")
+
+	ca := newStandInCA(t)
+	caSrv := httptest.NewServer(ca)
+	defer caSrv.Close()
+	log := newStandInLog(t)
+	logSrv := httptest.NewServer(log)
+	defer logSrv.Close()
+
+	sign, err := keylessSigner(t.Context(), KeylessSignOptions{
+		FulcioURL:     caSrv.URL,
+		RekorURL:      logSrv.URL,
+		IdentityToken: testToken(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("a", 64)}
+	payload, err := cosignPayload(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a further entry, so that the inclusion proof is checked against a
+	// larger tree than at signing time.
+	if _, err := sign(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := KeylessVerifyOptions{
+		Roots:    ca.roots(),
+		Identity: testIdentity,
+		Issuer:   testIssuer,
+	}
+	rc := newRekorClient(logSrv.URL, nil)
+	pub, err := log.sv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lv, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := valid.verifySignature(t.Context(), sig, rc, lv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Critical.Image.DockerManifestDigest != digest.String() {
+		t.Errorf("got digest %v, want %v", got.Critical.Image.DockerManifestDigest, digest)
+	}
+
+	wrongIdentity := valid
+	wrongIdentity.Identity = "other@example.com"
+	if _, err := wrongIdentity.verifySignature(t.Context(), sig, rc, lv); !errors.Is(err, errIdentityMismatch) {
+		t.Errorf("got error %v, want %v", err, errIdentityMismatch)
+	}
+
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "https://other.example.com"
+	if _, err := wrongIssuer.verifySignature(t.Context(), sig, rc, lv); !errors.Is(err, errIssuerMismatch) {
+		t.Errorf("got error %v, want %v", err, errIssuerMismatch)
+	}
+
+	wrongRoots := valid
+	wrongRoots.Roots = newStandInCA(t).roots()
+	if _, err := wrongRoots.verifySignature(t.Context(), sig, rc, lv); err == nil {
+		t.Errorf("unexpected success with wrong roots")
+	}
+
+	// A signature over a different payload must not be accepted with the
+	// bundle of the original.
+	b64sig, err := sig.Base64Signature()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := sig.Cert()
+	certPEM, _ := cryptoutils.MarshalCertificateToPEM(cert)
+	b, _ := sig.Bundle()
+	other, err := cosignPayload(v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("b", 64)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered, err := static.NewSignature(other, b64sig, static.WithCertChain(certPEM, nil), static.WithBundle(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := valid.verifySignature(t.Context(), tampered, rc, lv); !errors.Is(err, errRekorEntryMismatch) {
+		t.Errorf("got error %v, want %v", err, errRekorEntryMismatch)
+	}
+
+	// The log signature on the entry must be from the expected log.
+	otherLog := newStandInLog(t)
+	if _, err := valid.verifySignature(t.Context(), sig, rc, otherLog.sv); !errors.Is(err, errRekorEntryMismatch) {
+		t.Errorf("got error %v, want %v", err, errRekorEntryMismatch)
+	}
+}
+
+func TestSignVerifyOCISIFKeyless(t *testing.T) {
+	useragent.InitValue(t.Name(), "0.0")
+
+	ca := newStandInCA(t)
+	caSrv := httptest.NewServer(ca)
+	defer caSrv.Close()
+	log := newStandInLog(t)
+	logSrv := httptest.NewServer(log)
+	defer logSrv.Close()
+	logPub, err := log.sv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("while generating test image: %v", err)
+	}
+	ii := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{Add: img})
+	testSIF := filepath.Join(t.TempDir(), "test.sif")
+	if err := ocisif.Write(testSIF, ii, ocisif.OptWriteWithSpareDescriptorCapacity(16)); err != nil {
+		t.Fatalf("while writing test image: %v", err)
+	}
+
+	err = SignOCISIFKeyless(t.Context(), testSIF, KeylessSignOptions{
+		FulcioURL:     caSrv.URL,
+		RekorURL:      logSrv.URL,
+		IdentityToken: testToken(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := KeylessVerifyOptions{
+		Roots:          ca.roots(),
+		Identity:       testIdentity,
+		Issuer:         testIssuer,
+		RekorURL:       logSrv.URL,
+		RekorPublicKey: logPub,
+	}
+	if _, err := VerifyOCISIFKeyless(t.Context(), testSIF, opts); err != nil {
+		t.Error(err)
+	}
+
+	// The log public key must be trusted, not retrieved from the log.
+	noKey := opts
+	noKey.RekorPublicKey = nil
+	if _, err := VerifyOCISIFKeyless(t.Context(), testSIF, noKey); err == nil {
+		t.Errorf("unexpected success without transparency log public key")
+	}
+
+	opts.Identity = "other@example.com"
+	if _, err := VerifyOCISIFKeyless(t.Context(), testSIF, opts); !errors.Is(err, ErrNoValidSignatures) {
+		t.Errorf("got error %v, want %v", err, ErrNoValidSignatures)
+	}
+}
+
+func TestTrustedRoot(t *testing.T) {
+	ca := newStandInCA(t)
+	current, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der := func(k *ecdsa.PrivateKey) string {
+		b, err := x509.MarshalPKIXPublicKey(k.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	root := `{
+  "tlogs": [
+    {
+      "baseUrl": "https://rekor.example.com",
+      "publicKey": {
+        "rawBytes": "` + der(old) + `",
+        "validFor": {"start": "2021-01-01T00:00:00Z", "end": "2022-01-01T00:00:00Z"}
+      }
+    },
+    {
+      "baseUrl": "https://rekor.example.com",
+      "publicKey": {
+        "rawBytes": "` + der(current) + `",
+        "validFor": {"start": "2022-01-01T00:00:00Z"}
+      }
+    }
+  ],
+  "certificateAuthorities": [
+    {
+      "certChain": {"certificates": [{"rawBytes": "` + base64.StdEncoding.EncodeToString(ca.root.Raw) + `"}]}
+    }
+  ]
+}`
+	path := filepath.Join(t.TempDir(), "trusted_root.json")
+	if err := os.WriteFile(path, []byte(root), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := LoadTrustedRoot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := tr.RekorPublicKey("https://rekor.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.PublicKey.Equal(pub) {
+		t.Errorf("got expired or unexpected transparency log key")
+	}
+
+	if _, err := tr.RekorPublicKey("https://other.example.com"); !errors.Is(err, ErrNoRekorKey) {
+		t.Errorf("got error %v, want %v", err, ErrNoRekorKey)
+	}
+
+	roots, _, err := tr.CertificatePools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !roots.Equal(ca.roots()) {
+		t.Errorf("unexpected root certificates")
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/sigstore/pkg/signature"
+	useragent "github.com/sylabs/singularity/v4/pkg/util/user-agent"
+)
+
+// DefaultRekorURL is the transparency log used when no other is specified.
+const DefaultRekorURL = "https://rekor.sigstore.dev"
+
+var (
+	errRekorEntryMismatch = errors.New("transparency log entry does not match signature")
+	errInclusionProof     = errors.New("transparency log inclusion proof not valid")
+	errCheckpoint         = errors.New("transparency log checkpoint not valid")
+)
+
+// hashedRekord is a Rekor hashedrekord v0.0.1 entry, which records a signature
+// over the SHA256 digest of an artifact.
+type hashedRekord struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Spec       hashedRekordSpec `json:"spec"`
+}
+
+type hashedRekordSpec struct {
+	Signature struct {
+		Content   []byte `json:"content"`
+		PublicKey struct {
+			Content []byte `json:"content"`
+		} `json:"publicKey"`
+	} `json:"signature"`
+	Data struct {
+		Hash struct {
+			Algorithm string `json:"algorithm"`
+			Value     string `json:"value"`
+		} `json:"hash"`
+	} `json:"data"`
+}
+
+// newHashedRekord returns a hashedrekord entry recording sig, made by the key
+// of the PEM encoded certificate certPEM, over payload.
+func newHashedRekord(payload, sig, certPEM []byte) hashedRekord {
+	digest := sha256.Sum256(payload)
+
+	e := hashedRekord{APIVersion: "0.0.1", Kind: "hashedrekord"}
+	e.Spec.Signature.Content = sig
+	e.Spec.Signature.PublicKey.Content = certPEM
+	e.Spec.Data.Hash.Algorithm = "sha256"
+	e.Spec.Data.Hash.Value = hex.EncodeToString(digest[:])
+	return e
+}
+
+// rekorEntry is a transparency log entry, as returned by the Rekor API.
+type rekorEntry struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	Verification   struct {
+		InclusionProof       *rekorInclusionProof `json:"inclusionProof,omitempty"`
+		SignedEntryTimestamp []byte               `json:"signedEntryTimestamp,omitempty"`
+	} `json:"verification"`
+}
+
+type rekorInclusionProof struct {
+	Checkpoint string   `json:"checkpoint"`
+	Hashes     []string `json:"hashes"`
+	LogIndex   int64    `json:"logIndex"`
+	RootHash   string   `json:"rootHash"`
+	TreeSize   int64    `json:"treeSize"`
+}
+
+// rekorSETPayload is the content signed by the log in a signed entry
+// timestamp. Fields are in the lexical order required by canonical JSON.
+type rekorSETPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// rekorClient is a client for the REST API of a Rekor compatible transparency
+// log.
+type rekorClient struct {
+	url    string
+	client *http.Client
+}
+
+func newRekorClient(rekorURL string, client *http.Client) *rekorClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &rekorClient{url: strings.TrimSuffix(rekorURL, "/"), client: client}
+}
+
+// do performs a request to the API at path, decoding a JSON response into v,
+// or returning the raw response if v is nil.
+func (c *rekorClient) do(ctx context.Context, method, path string, body any, v any) ([]byte, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", useragent.Value())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("transparency log %s %s: %s: %s", method, path, res.Status, bytes.TrimSpace(b))
+	}
+	if v == nil {
+		return b, nil
+	}
+	return nil, json.Unmarshal(b, v)
+}
+
+// firstEntry returns the single entry of a Rekor API response, which is keyed
+// by entry UUID.
+func firstEntry(entries map[string]rekorEntry) (*rekorEntry, error) {
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("expected 1 transparency log entry, got %d", len(entries))
+	}
+	for _, e := range entries {
+		return &e, nil
+	}
+	return nil, nil
+}
+
+// upload adds the hashedrekord entry e to the log, returning the log entry.
+func (c *rekorClient) upload(ctx context.Context, e hashedRekord) (*rekorEntry, error) {
+	var entries map[string]rekorEntry
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/log/entries", e, &entries); err != nil {
+		return nil, err
+	}
+	return firstEntry(entries)
+}
+
+// entryByIndex returns the log entry at index, including its inclusion proof.
+func (c *rekorClient) entryByIndex(ctx context.Context, index int64) (*rekorEntry, error) {
+	var entries map[string]rekorEntry
+	path := "/api/v1/log/entries?logIndex=" + url.QueryEscape(strconv.FormatInt(index, 10))
+	if _, err := c.do(ctx, http.MethodGet, path, nil, &entries); err != nil {
+		return nil, err
+	}
+	return firstEntry(entries)
+}
+
+// rekorLogID returns the log ID of a log with public key pub, which is the
+// hex encoded SHA256 digest of the DER encoded key.
+func rekorLogID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(der)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// entryBundle returns the cosign bundle recording e.
+func entryBundle(e *rekorEntry) *bundle.RekorBundle {
+	return &bundle.RekorBundle{
+		SignedEntryTimestamp: e.Verification.SignedEntryTimestamp,
+		Payload: bundle.RekorPayload{
+			Body:           e.Body,
+			IntegratedTime: e.IntegratedTime,
+			LogIndex:       e.LogIndex,
+			LogID:          e.LogID,
+		},
+	}
+}
+
+// verifyBundle checks that the signed entry timestamp in b was made by the
+// log with verifier lv, and that the entry records sig, made with the key of
+// certPEM, over payload.
+func verifyBundle(b *bundle.RekorBundle, lv signature.Verifier, payload, sig, certPEM []byte) error {
+	if b == nil {
+		return errors.New("signature has no transparency log bundle")
+	}
+	body, ok := b.Payload.Body.(string)
+	if !ok {
+		return fmt.Errorf("%w: unexpected body type %T", errRekorEntryMismatch, b.Payload.Body)
+	}
+
+	pub, err := lv.PublicKey()
+	if err != nil {
+		return err
+	}
+	logID, err := rekorLogID(pub)
+	if err != nil {
+		return err
+	}
+	if b.Payload.LogID != logID {
+		return fmt.Errorf("%w: entry is from log %s, not %s", errRekorEntryMismatch, b.Payload.LogID, logID)
+	}
+
+	set, err := json.Marshal(rekorSETPayload{
+		Body:           body,
+		IntegratedTime: b.Payload.IntegratedTime,
+		LogID:          b.Payload.LogID,
+		LogIndex:       b.Payload.LogIndex,
+	})
+	if err != nil {
+		return err
+	}
+	if err := lv.VerifySignature(bytes.NewReader(b.SignedEntryTimestamp), bytes.NewReader(set)); err != nil {
+		return fmt.Errorf("signed entry timestamp not valid: %w", err)
+	}
+
+	return checkEntryBody(body, payload, sig, certPEM)
+}
+
+// checkEntryBody checks that the base64 encoded hashedrekord body records
+// sig, made with the key of certPEM, over payload.
+func checkEntryBody(body string, payload, sig, certPEM []byte) error {
+	b, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errRekorEntryMismatch, err)
+	}
+	var e hashedRekord
+	if err := json.Unmarshal(b, &e); err != nil {
+		return fmt.Errorf("%w: %w", errRekorEntryMismatch, err)
+	}
+
+	want := newHashedRekord(payload, sig, certPEM)
+	if e.Kind != want.Kind ||
+		e.Spec.Data.Hash != want.Spec.Data.Hash ||
+		!bytes.Equal(e.Spec.Signature.Content, want.Spec.Signature.Content) ||
+		!bytes.Equal(bytes.TrimSpace(e.Spec.Signature.PublicKey.Content), bytes.TrimSpace(certPEM)) {
+		return errRekorEntryMismatch
+	}
+	return nil
+}
+
+// verifyInclusion fetches the log entry recorded by b from the log, and checks
+// its inclusion proof against a checkpoint signed by the log with verifier lv.
+func (c *rekorClient) verifyInclusion(ctx context.Context, b *bundle.RekorBundle, lv signature.Verifier) error {
+	e, err := c.entryByIndex(ctx, b.Payload.LogIndex)
+	if err != nil {
+		return fmt.Errorf("while fetching transparency log entry: %w", err)
+	}
+	if e.Body != b.Payload.Body || e.IntegratedTime != b.Payload.IntegratedTime {
+		return errRekorEntryMismatch
+	}
+	p := e.Verification.InclusionProof
+	if p == nil {
+		return fmt.Errorf("%w: no proof returned by log", errInclusionProof)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(e.Body)
+	if err != nil {
+		return err
+	}
+	root, err := hex.DecodeString(p.RootHash)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInclusionProof, err)
+	}
+	proof := make([][]byte, 0, len(p.Hashes))
+	for _, h := range p.Hashes {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInclusionProof, err)
+		}
+		proof = append(proof, b)
+	}
+	if p.LogIndex < 0 || p.TreeSize < 0 {
+		return errInclusionProof
+	}
+	if err := verifyInclusionProof(uint64(p.LogIndex), uint64(p.TreeSize), leafHash(body), proof, root); err != nil {
+		return err
+	}
+
+	return verifyCheckpoint(p.Checkpoint, p.TreeSize, root, lv)
+}
+
+// leafHash returns the RFC 6962 hash of a log leaf.
+func leafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+// nodeHash returns the RFC 6962 hash of an interior node of a log.
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// verifyInclusionProof checks that the leaf with hash leaf, at index in a
+// tree of size entries, is included in the tree with the specified root hash,
+// following the algorithm in RFC 9162 section 2.1.3.2.
+func verifyInclusionProof(index, size uint64, leaf []byte, proof [][]byte, root []byte) error {
+	if index >= size {
+		return fmt.Errorf("%w: index %d outside tree of size %d", errInclusionProof, index, size)
+	}
+
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return fmt.Errorf("%w: proof too long", errInclusionProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			if fn&1 == 0 {
+				for fn&1 == 0 && fn != 0 {
+					fn >>= 1
+					sn >>= 1
+				}
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 {
+		return fmt.Errorf("%w: proof too short", errInclusionProof)
+	}
+	if !bytes.Equal(r, root) {
+		return fmt.Errorf("%w: root hash mismatch", errInclusionProof)
+	}
+	return nil
+}
+
+// verifyCheckpoint checks that the signed note checkpoint commits to a tree of
+// the specified size and root hash, and is signed by the log with verifier lv.
+func verifyCheckpoint(checkpoint string, size int64, root []byte, lv signature.Verifier) error {
+	text, sigs, ok := strings.Cut(checkpoint, "\n\n")
+	if !ok {
+		return fmt.Errorf("%w: malformed note", errCheckpoint)
+	}
+	text += "\n"
+
+	lines := strings.Split(text, "\n")
+	if len(lines) < 3 {
+		return fmt.Errorf("%w: malformed note", errCheckpoint)
+	}
+	if lines[1] != strconv.FormatInt(size, 10) {
+		return fmt.Errorf("%w: tree size %s, expected %d", errCheckpoint, lines[1], size)
+	}
+	if lines[2] != base64.StdEncoding.EncodeToString(root) {
+		return fmt.Errorf("%w: root hash mismatch", errCheckpoint)
+	}
+
+	// Signature lines are of the form "— <name> <base64(key hint || signature)>".
+	for _, line := range strings.Split(strings.TrimSpace(sigs), "\n") {
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if len(fields) != 2 {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(b) <= 4 {
+			continue
+		}
+		if err := lv.VerifySignature(bytes.NewReader(b[4:]), strings.NewReader(text)); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no valid signature from log", errCheckpoint)
+}
@@ -15,6 +15,7 @@ import (
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/sigstore/cosign/v2/pkg/oci"
 	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
 	cosignremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
 	"github.com/sigstore/cosign/v2/pkg/oci/static"
@@ -24,6 +25,7 @@ import (
 	ocisif "github.com/sylabs/oci-tools/pkg/sif"
 	"github.com/sylabs/oci-tools/pkg/sourcesink"
 	"github.com/sylabs/sif/v2/pkg/sif"
+	ocisifimg "github.com/sylabs/singularity/v4/internal/pkg/ocisif"
 	"github.com/sylabs/singularity/v4/pkg/image"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
 	useragent "github.com/sylabs/singularity/v4/pkg/util/user-agent"
@@ -49,30 +51,58 @@ func cosignPayload(digest v1.Hash) ([]byte, error) {
 	return json.Marshal(&payload)
 }
 
+// SignOCISIF signs the single OCI container image in the OCI-SIF at sifPath
+// with signer, adding a cosign compatible signature to the OCI-SIF.
 func SignOCISIF(ctx context.Context, sifPath string, signer signature.Signer) error {
+	return signImage(ctx, sifPath, func(payload []byte) (oci.Signature, error) {
+		sig, err := signer.SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		b64sig := base64.StdEncoding.EncodeToString(sig)
+		sylog.Debugf("Generated cosign signature: %v", b64sig)
+		return static.NewSignature(payload, b64sig)
+	})
+}
+
+// signedImage returns the single OCI container image in the OCI-SIF at sifPath.
+func signedImage(ctx context.Context, sifPath string) (oci.SignedImage, error) {
 	ok, err := image.IsOCISIF(sifPath)
 	if err != nil {
-		return fmt.Errorf("while checking OCI-SIF: %w", err)
+		return nil, fmt.Errorf("while checking OCI-SIF: %w", err)
 	}
 	if !ok {
-		return fmt.Errorf("image is not an OCI-SIF: %q", sifPath)
+		return nil, fmt.Errorf("image is not an OCI-SIF: %q", sifPath)
+	}
+	if err := ocisifimg.CheckSingleImage(sifPath); err != nil {
+		return nil, err
 	}
 
 	ss, err := sourcesink.SIFFromPath(sifPath)
 	if err != nil {
-		return fmt.Errorf("failed to open OCI-SIF: %w", err)
+		return nil, fmt.Errorf("failed to open OCI-SIF: %w", err)
 	}
 	d, err := ss.Get(ctx)
 	if err != nil {
-		return fmt.Errorf("while fetching image from OCI-SIF: %v", err)
+		return nil, fmt.Errorf("while fetching image from OCI-SIF: %v", err)
 	}
 	sd, ok := d.(sourcesink.SignedDescriptor)
 	if !ok {
-		return fmt.Errorf("failed to upgrade Descriptor to SignedDescriptor")
+		return nil, fmt.Errorf("failed to upgrade Descriptor to SignedDescriptor")
 	}
 	si, err := sd.SignedImage(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve image: %w", err)
+		return nil, fmt.Errorf("failed to retrieve image: %w", err)
+	}
+	return si, nil
+}
+
+// signImage adds the signature returned by sign, for the cosign payload of the
+// single OCI container image in the OCI-SIF at sifPath, to the OCI-SIF.
+func signImage(ctx context.Context, sifPath string, sign func(payload []byte) (oci.Signature, error)) error {
+	si, err := signedImage(ctx, sifPath)
+	if err != nil {
+		return err
 	}
 	digest, err := si.Digest()
 	if err != nil {
@@ -83,17 +113,10 @@ func SignOCISIF(ctx context.Context, sifPath string, signer signature.Signer) er
 	if err != nil {
 		return fmt.Errorf("while generating signature payload: %w", err)
 	}
-	sOpts := []signature.SignOption{signatureoptions.WithContext(ctx)}
-	sig, err := signer.SignMessage(bytes.NewReader(payload), sOpts...)
-	if err != nil {
-		return err
-	}
-	b64sig := base64.StdEncoding.EncodeToString(sig)
-	ociSig, err := static.NewSignature(payload, b64sig)
+	ociSig, err := sign(payload)
 	if err != nil {
 		return err
 	}
-	sylog.Debugf("Generated cosign signature: %v", b64sig)
 
 	si, err = mutate.AttachSignatureToImage(si, ociSig)
 	if err != nil {
@@ -103,8 +126,14 @@ func SignOCISIF(ctx context.Context, sifPath string, signer signature.Signer) er
 	if err != nil {
 		return err
 	}
+	return writeCosignImage(sifPath, digest, sigs, cosignremote.SignatureTagSuffix)
+}
 
-	csRef, err := sourcesink.CosignRef(digest, nil, cosignremote.SignatureTagSuffix)
+// writeCosignImage writes img, holding the signatures or attestations of the
+// image with the specified digest, to the OCI-SIF at sifPath, replacing any
+// existing image with the cosign reference formed from suffix.
+func writeCosignImage(sifPath string, digest v1.Hash, img v1.Image, suffix string) error {
+	csRef, err := sourcesink.CosignRef(digest, nil, suffix)
 	if err != nil {
 		return err
 	}
@@ -118,5 +147,5 @@ func SignOCISIF(ctx context.Context, sifPath string, signer signature.Signer) er
 	if err != nil {
 		return fmt.Errorf("while loading SIF: %w", err)
 	}
-	return ofi.ReplaceImage(sigs, match.Name(csRef.Name()), ocisif.OptAppendReference(csRef))
+	return ofi.ReplaceImage(img, match.Name(csRef.Name()), ocisif.OptAppendReference(csRef))
 }
@@ -0,0 +1,118 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cosign
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNoRekorKey is returned when a trusted root holds no current public key
+// for a transparency log.
+var ErrNoRekorKey = errors.New("no public key for transparency log in trusted root")
+
+// validity is the period for which a key of a trusted root is valid. An unset
+// End means it is still valid.
+type validity struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// current reports whether the validity period includes t.
+func (v validity) current(t time.Time) bool {
+	return !t.Before(v.Start) && (v.End.IsZero() || t.Before(v.End))
+}
+
+// TrustedRoot holds the transparency log keys and certificate authorities of
+// a sigstore trusted root, as distributed in a trusted_root.json file.
+type TrustedRoot struct {
+	TLogs []struct {
+		BaseURL   string `json:"baseUrl"`
+		PublicKey struct {
+			RawBytes []byte   `json:"rawBytes"`
+			ValidFor validity `json:"validFor"`
+		} `json:"publicKey"`
+	} `json:"tlogs"`
+	CertificateAuthorities []struct {
+		CertChain struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"certChain"`
+	} `json:"certificateAuthorities"`
+}
+
+// LoadTrustedRoot reads a sigstore trusted root from the JSON file at path.
+func LoadTrustedRoot(path string) (*TrustedRoot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tr TrustedRoot
+	if err := json.Unmarshal(b, &tr); err != nil {
+		return nil, fmt.Errorf("while parsing trusted root: %w", err)
+	}
+	return &tr, nil
+}
+
+// RekorPublicKey returns the current public key of the transparency log at
+// rekorURL. If the log has more than one current key, the most recent is
+// returned.
+func (tr *TrustedRoot) RekorPublicKey(rekorURL string) (crypto.PublicKey, error) {
+	now := time.Now()
+
+	var (
+		raw   []byte
+		start time.Time
+	)
+	for _, l := range tr.TLogs {
+		if strings.TrimSuffix(l.BaseURL, "/") != strings.TrimSuffix(rekorURL, "/") {
+			continue
+		}
+		if !l.PublicKey.ValidFor.current(now) || l.PublicKey.ValidFor.Start.Before(start) {
+			continue
+		}
+		raw, start = l.PublicKey.RawBytes, l.PublicKey.ValidFor.Start
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoRekorKey, rekorURL)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("while parsing transparency log public key: %w", err)
+	}
+	return pub, nil
+}
+
+// CertificatePools returns the root and intermediate certificates of the
+// certificate authorities of the trusted root. The final certificate of each
+// chain is a root. Authorities that no longer issue certificates are included,
+// as signing certificates are checked at the time they were logged.
+func (tr *TrustedRoot) CertificatePools() (roots, intermediates *x509.CertPool, err error) {
+	roots, intermediates = x509.NewCertPool(), x509.NewCertPool()
+	for _, ca := range tr.CertificateAuthorities {
+		certs := ca.CertChain.Certificates
+		for i, c := range certs {
+			cert, err := x509.ParseCertificate(c.RawBytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("while parsing certificate authority: %w", err)
+			}
+			if i == len(certs)-1 {
+				roots.AddCert(cert)
+			} else {
+				intermediates.AddCert(cert)
+			}
+		}
+	}
+	return roots, intermediates, nil
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2025-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -42,7 +42,7 @@ func VerifyOCISIF(ctx context.Context, sifPath string, verifier signature.Verifi
 		return nil, err
 	}
 
-	payloads, err := checkSignatures(ctx, sifPath, verifier)
+	payloads, err := checkSignatures(ctx, sifPath, keyVerifier(verifier))
 	if err != nil {
 		return nil, err
 	}
@@ -53,6 +53,35 @@ func VerifyOCISIF(ctx context.Context, sifPath string, verifier signature.Verifi
 	return json.Marshal(payloads)
 }
 
+// SignedBy checks whether a single OCI container image, contained in the
+// OCI-SIF file at sifPath, has at least 1 cosign signature that can be verified
+// with each of the provided verifiers. The digests of the OCI blobs stored in
+// sifPath are checked once, before any signature. The returned slice holds the
+// result for each verifier, in order.
+func SignedBy(ctx context.Context, sifPath string, verifiers []signature.Verifier) ([]bool, error) {
+	ok, err := image.IsOCISIF(sifPath)
+	if err != nil {
+		return nil, fmt.Errorf("while checking OCI-SIF: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("not an OCI-SIF: %q", sifPath)
+	}
+
+	if err := verifyOCIBlobDigests(sifPath); err != nil {
+		return nil, err
+	}
+
+	signed := make([]bool, len(verifiers))
+	for i, v := range verifiers {
+		payloads, err := checkSignatures(ctx, sifPath, keyVerifier(v))
+		if err != nil {
+			return nil, err
+		}
+		signed[i] = len(payloads) > 0
+	}
+	return signed, nil
+}
+
 var ErrOCIBlobMismatch = errors.New("OCI blob digest mismatch")
 
 // verifyOCIBlobDigest checks that the OCIBlobDigest stored for each OCI blob
@@ -89,11 +118,21 @@ func verifyOCIBlobDigests(sifPath string) error {
 	return nil
 }
 
+// sigVerifier verifies a cosign signature, returning its payload.
+type sigVerifier func(ctx context.Context, s oci.Signature) (*sigPayload.SimpleContainerImage, error)
+
+// keyVerifier returns a sigVerifier that verifies signatures with verifier.
+func keyVerifier(verifier signature.Verifier) sigVerifier {
+	return func(_ context.Context, s oci.Signature) (*sigPayload.SimpleContainerImage, error) {
+		return verifySignature(s, verifier)
+	}
+}
+
 // checkSignatures retrieves each signature associated with a single OCI
 // container image in SIFPath, verifies the signature using the provided
-// verifier, and checks the payload manifest digest is a match for the image.
-// The payloads of valid signatures are returned.
-func checkSignatures(ctx context.Context, sifPath string, verifier signature.Verifier) ([]sigPayload.SimpleContainerImage, error) {
+// verify function, and checks the payload manifest digest is a match for the
+// image. The payloads of valid signatures are returned.
+func checkSignatures(ctx context.Context, sifPath string, verify sigVerifier) ([]sigPayload.SimpleContainerImage, error) {
 	ss, err := sourcesink.SIFFromPath(sifPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open OCI-SIF: %w", err)
@@ -129,7 +168,7 @@ func checkSignatures(ctx context.Context, sifPath string, verifier signature.Ver
 
 	validPayloads := []sigPayload.SimpleContainerImage{}
 	for i, s := range sigs {
-		payload, err := verifySignature(s, verifier)
+		payload, err := verify(ctx, s)
 		if err != nil {
 			sylog.Verbosef("signature %d invalid for provided key material: %v", i, err)
 			continue
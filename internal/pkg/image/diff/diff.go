@@ -0,0 +1,246 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package diff compares the root filesystem, metadata and installed packages
+// of two container images.
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/cache"
+	imagerootfs "github.com/sylabs/singularity/v4/internal/pkg/image/rootfs"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociimage"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/uri"
+	"github.com/sylabs/singularity/v4/pkg/image"
+	"github.com/sylabs/singularity/v4/pkg/inspect"
+)
+
+// Image holds the content of an image that is compared.
+type Image struct {
+	// Files holds the files of the root filesystem, keyed by absolute path.
+	Files map[string]Entry
+	// Metadata holds the labels, environment and runscript of the image.
+	Metadata *inspect.Metadata
+	// Packages holds the version of each installed package, keyed by name.
+	Packages map[string]string
+}
+
+// Load reads the image at src, which may be a SIF, OCI-SIF, squashfs, ext3 or
+// sandbox image, or an OCI image URI. Remote OCI images are fetched to imgCache, or tmpDir if
+// the cache is disabled. Temporary files are written to tmpDir.
+func Load(ctx context.Context, src string, tOpts *ociimage.TransportOptions, imgCache *cache.Handle, tmpDir string) (*Image, error) {
+	if transport, _ := uri.Split(src); transport != "" {
+		img, err := ociimage.LocalImage(ctx, tOpts, imgCache, src, tmpDir)
+		if err != nil {
+			return nil, err
+		}
+		return loadOCI(img, tmpDir)
+	}
+
+	img, err := image.Init(src, false)
+	if err != nil {
+		return nil, err
+	}
+	img.File.Close()
+
+	if img.Type != image.OCISIF {
+		fsys, err := imagerootfs.Open(img.Path, imagerootfs.OptTmpDir(tmpDir))
+		if err != nil {
+			return nil, err
+		}
+		defer fsys.Close()
+		return loadFS(fsys, tmpDir)
+	}
+
+	fi, err := sif.LoadContainerFromPath(img.Path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF: %w", err)
+	}
+	defer fi.UnloadContainer()
+	oi, err := ocisif.GetPlatformImage(fi, tOpts.Platform)
+	if err != nil {
+		return nil, err
+	}
+	return loadOCI(oi, tmpDir)
+}
+
+// loadFS reads a native Singularity image, with root filesystem fsys.
+func loadFS(fsys fs.FS, tmpDir string) (*Image, error) {
+	r, err := readFS(fsys)
+	if err != nil {
+		return nil, err
+	}
+	md, err := nativeMetadata(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Image{
+		Files:    r.tree,
+		Metadata: md,
+		Packages: packages(r, tmpDir),
+	}, nil
+}
+
+// loadOCI reads an OCI image, applying each of its layers in turn.
+func loadOCI(img ggcrv1.Image, tmpDir string) (*Image, error) {
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("while retrieving image config: %w", err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("while retrieving image layers: %w", err)
+	}
+
+	r := newRootfs()
+	for i, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		opener, err := ocisif.LayerTarOpener(l, mt, tmpDir)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+		rc, err := opener()
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+		err = r.applyLayer(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+	}
+
+	return &Image{
+		Files:    r.tree,
+		Metadata: ociMetadata(cf),
+		Packages: packages(r, tmpDir),
+	}, nil
+}
+
+// Item is a named value present in only one of the compared images.
+type Item[T any] struct {
+	Name  string `json:"name"`
+	Value T      `json:"value"`
+}
+
+// Modification is a named value that differs between the compared images.
+type Modification[T any] struct {
+	Name string `json:"name"`
+	Old  T      `json:"old"`
+	New  T      `json:"new"`
+}
+
+// Changes lists the named values that were added, removed or modified in the
+// second image, relative to the first.
+type Changes[T any] struct {
+	Added    []Item[T]         `json:"added"`
+	Removed  []Item[T]         `json:"removed"`
+	Modified []Modification[T] `json:"modified"`
+}
+
+// Empty returns whether there are no changes.
+func (c Changes[T]) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// compare returns the changes between a and b, sorted by name.
+func compare[T comparable](a, b map[string]T) Changes[T] {
+	c := Changes[T]{
+		Added:    []Item[T]{},
+		Removed:  []Item[T]{},
+		Modified: []Modification[T]{},
+	}
+	for _, k := range slices.Sorted(maps.Keys(a)) {
+		bv, ok := b[k]
+		switch {
+		case !ok:
+			c.Removed = append(c.Removed, Item[T]{Name: k, Value: a[k]})
+		case bv != a[k]:
+			c.Modified = append(c.Modified, Modification[T]{Name: k, Old: a[k], New: bv})
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(b)) {
+		if _, ok := a[k]; !ok {
+			c.Added = append(c.Added, Item[T]{Name: k, Value: b[k]})
+		}
+	}
+	return c
+}
+
+// Result holds the differences between two images.
+type Result struct {
+	Files       Changes[Entry]  `json:"files"`
+	Labels      Changes[string] `json:"labels"`
+	Environment Changes[string] `json:"environment"`
+	Runscript   Changes[string] `json:"runscript"`
+	Packages    Changes[string] `json:"packages"`
+}
+
+// Compare returns the differences between images a and b.
+func Compare(a, b *Image) *Result {
+	runscript := func(img *Image) map[string]string {
+		if img.Metadata.Attributes.Runscript == "" {
+			return nil
+		}
+		return map[string]string{"runscript": img.Metadata.Attributes.Runscript}
+	}
+	return &Result{
+		Files:       compare(a.Files, b.Files),
+		Labels:      compare(a.Metadata.Attributes.Labels, b.Metadata.Attributes.Labels),
+		Environment: compare(a.Metadata.Attributes.Environment, b.Metadata.Attributes.Environment),
+		Runscript:   compare(runscript(a), runscript(b)),
+		Packages:    compare(a.Packages, b.Packages),
+	}
+}
+
+// Empty returns whether the compared images do not differ.
+func (r *Result) Empty() bool {
+	return r.Files.Empty() && r.Labels.Empty() && r.Environment.Empty() &&
+		r.Runscript.Empty() && r.Packages.Empty()
+}
+
+// writeChanges writes a section of the text form of a result to w.
+func writeChanges[T any](w io.Writer, title string, c Changes[T], format func(T) string) {
+	if c.Empty() {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, i := range c.Added {
+		fmt.Fprintf(w, "  + %s  %s\n", i.Name, format(i.Value))
+	}
+	for _, i := range c.Removed {
+		fmt.Fprintf(w, "  - %s  %s\n", i.Name, format(i.Value))
+	}
+	for _, m := range c.Modified {
+		fmt.Fprintf(w, "  M %s  %s -> %s\n", m.Name, format(m.Old), format(m.New))
+	}
+}
+
+// WriteText writes the result to w, in a form for display.
+func (r *Result) WriteText(w io.Writer) {
+	if r.Empty() {
+		fmt.Fprintln(w, "No differences found")
+		return
+	}
+	quote := func(s string) string { return fmt.Sprintf("%q", s) }
+	writeChanges(w, "Files", r.Files, Entry.String)
+	writeChanges(w, "Labels", r.Labels, quote)
+	writeChanges(w, "Environment", r.Environment, quote)
+	writeChanges(w, "Runscript", r.Runscript, quote)
+	writeChanges(w, "Packages", r.Packages, func(s string) string { return s })
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package diff
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func writeSandbox(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func names[T any](items []Item[T]) []string {
+	n := []string{}
+	for _, i := range items {
+		n = append(n, i.Name)
+	}
+	return n
+}
+
+func modifiedNames[T any](mods []Modification[T]) []string {
+	n := []string{}
+	for _, m := range mods {
+		n = append(n, m.Name)
+	}
+	return n
+}
+
+func TestCompareSandbox(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	dirA := filepath.Join(tmpDir, "a")
+	writeSandbox(t, dirA, map[string]string{
+		".singularity.d/labels.json":        `{"version": "1", "removed": "x"}`,
+		".singularity.d/runscript":          "#!/bin/sh\necho a\n",
+		".singularity.d/env/90-environment": "export A=1\n",
+		"etc/same":                          "same",
+		"etc/changed":                       "old",
+		"etc/removed":                       "removed",
+		"var/lib/dpkg/status":               "Package: bash\nStatus: install ok installed\nVersion: 5.1\n\nPackage: old\nStatus: install ok installed\nVersion: 1\n",
+	})
+	if err := os.Symlink("same", filepath.Join(dirA, "etc/link")); err != nil {
+		t.Fatal(err)
+	}
+
+	dirB := filepath.Join(tmpDir, "b")
+	writeSandbox(t, dirB, map[string]string{
+		".singularity.d/labels.json":        `{"version": "2", "added": "y"}`,
+		".singularity.d/runscript":          "#!/bin/sh\necho b\n",
+		".singularity.d/env/90-environment": "export A=1\n",
+		"etc/same":                          "same",
+		"etc/changed":                       "new",
+		"etc/added":                         "added",
+		"var/lib/dpkg/status":               "Package: bash\nStatus: install ok installed\nVersion: 5.2\n\nPackage: new\nStatus: install ok installed\nVersion: 1\n",
+	})
+	if err := os.Symlink("changed", filepath.Join(dirB, "etc/link")); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Load(context.Background(), dirA, nil, nil, tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(context.Background(), dirB, nil, nil, tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Compare(a, b)
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"added files", names(r.Files.Added), []string{"/etc/added"}},
+		{"removed files", names(r.Files.Removed), []string{"/etc/removed"}},
+		{"modified files", modifiedNames(r.Files.Modified), []string{"/.singularity.d/labels.json", "/.singularity.d/runscript", "/etc/changed", "/etc/link", "/var/lib/dpkg/status"}},
+		{"added labels", names(r.Labels.Added), []string{"added"}},
+		{"removed labels", names(r.Labels.Removed), []string{"removed"}},
+		{"modified labels", modifiedNames(r.Labels.Modified), []string{"version"}},
+		{"modified environment", modifiedNames(r.Environment.Modified), []string{}},
+		{"modified runscript", modifiedNames(r.Runscript.Modified), []string{"runscript"}},
+		{"added packages", names(r.Packages.Added), []string{"dpkg:new"}},
+		{"removed packages", names(r.Packages.Removed), []string{"dpkg:old"}},
+		{"modified packages", modifiedNames(r.Packages.Modified), []string{"dpkg:bash"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if same := Compare(a, a); !same.Empty() {
+		t.Errorf("comparison of an image with itself is not empty: %+v", same)
+	}
+}
+
+type tarFile struct {
+	name     string
+	content  string
+	typeflag byte
+	linkname string
+}
+
+func tarLayer(t *testing.T, files []tarFile) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		h := &tar.Header{
+			Name:     f.name,
+			Typeflag: f.typeflag,
+			Linkname: f.linkname,
+			Mode:     0o644,
+			Size:     int64(len(f.content)),
+		}
+		if f.typeflag == tar.TypeDir {
+			h.Mode = 0o755
+		}
+		if f.typeflag != tar.TypeReg {
+			h.Size = 0
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil && h.Size > 0 {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadOCI(t *testing.T) {
+	layers := [][]tarFile{
+		{
+			{name: "etc/", typeflag: tar.TypeDir},
+			{name: "etc/keep", content: "keep", typeflag: tar.TypeReg},
+			{name: "etc/whiteout", content: "x", typeflag: tar.TypeReg},
+			{name: "opt/", typeflag: tar.TypeDir},
+			{name: "opt/old", content: "old", typeflag: tar.TypeReg},
+		},
+		{
+			{name: "etc/.wh.whiteout", typeflag: tar.TypeReg},
+			{name: "etc/hardlink", typeflag: tar.TypeLink, linkname: "etc/keep"},
+			{name: "opt/", typeflag: tar.TypeDir},
+			{name: "opt/.wh..wh..opq", typeflag: tar.TypeReg},
+			{name: "opt/new", content: "new", typeflag: tar.TypeReg},
+		},
+	}
+
+	img := empty.Image
+	for _, files := range layers {
+		b := tarLayer(t, files)
+		l, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img, err = mutate.AppendLayers(img, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cf, err := img.ConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf.Config.Entrypoint = []string{"/bin/echo"}
+	cf.Config.Cmd = []string{"hello world"}
+	cf.Config.Env = []string{"PATH=/bin", "A=1"}
+	cf.Config.Labels = map[string]string{"l": "v"}
+	if img, err = mutate.ConfigFile(img, cf); err != nil {
+		t.Fatal(err)
+	}
+
+	oi, err := loadOCI(img, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := slices.Sorted(maps.Keys(oi.Files))
+	want := []string{"/etc", "/etc/hardlink", "/etc/keep", "/opt", "/opt/new"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got files %v, want %v", got, want)
+	}
+	if oi.Files["/etc/hardlink"] != oi.Files["/etc/keep"] {
+		t.Errorf("hard link entry %v differs from target %v", oi.Files["/etc/hardlink"], oi.Files["/etc/keep"])
+	}
+
+	attr := oi.Metadata.Attributes
+	if want := `"/bin/echo" "hello world"`; attr.Runscript != want {
+		t.Errorf("got runscript %q, want %q", attr.Runscript, want)
+	}
+	if want := map[string]string{"PATH": "/bin", "A": "1"}; !reflect.DeepEqual(attr.Environment, want) {
+		t.Errorf("got environment %v, want %v", attr.Environment, want)
+	}
+	if want := map[string]string{"l": "v"}; !reflect.DeepEqual(attr.Labels, want) {
+		t.Errorf("got labels %v, want %v", attr.Labels, want)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/shell"
+	"github.com/sylabs/singularity/v4/pkg/inspect"
+)
+
+const (
+	labelsFile    = "/.singularity.d/labels.json"
+	runscriptFile = "/.singularity.d/runscript"
+	envDir        = "/.singularity.d/env"
+)
+
+// nativeMetadata returns the labels, environment and runscript of a native
+// Singularity image, from the files in its /.singularity.d directory. The
+// environment is keyed by file, as with 'singularity inspect'.
+func nativeMetadata(r *rootfs) (*inspect.Metadata, error) {
+	md := inspect.NewMetadata()
+
+	if b, ok := r.files[labelsFile]; ok {
+		if err := json.Unmarshal(b, &md.Attributes.Labels); err != nil {
+			return nil, fmt.Errorf("while parsing %s: %w", labelsFile, err)
+		}
+	}
+	md.Attributes.Runscript = string(r.files[runscriptFile])
+	for name, b := range r.files {
+		if path.Dir(name) == envDir {
+			md.Attributes.Environment[name] = string(b)
+		}
+	}
+	return md, nil
+}
+
+// ociMetadata returns the labels, environment and runscript of an OCI image,
+// from its config. The environment is keyed by variable name, and the
+// runscript is the command formed by the ENTRYPOINT and CMD of the image.
+func ociMetadata(cf *ggcrv1.ConfigFile) *inspect.Metadata {
+	md := inspect.NewMetadata()
+
+	maps.Copy(md.Attributes.Labels, cf.Config.Labels)
+	for _, e := range cf.Config.Env {
+		k, v, _ := strings.Cut(e, "=")
+		md.Attributes.Environment[k] = v
+	}
+	md.Attributes.Runscript = shell.ArgsQuoted(slices.Concat(cf.Config.Entrypoint, cf.Config.Cmd))
+	return md
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/util/rpm"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+const dpkgStatusFile = "/var/lib/dpkg/status"
+
+// rpmDBDirs are the locations of the rpm database, in current and older
+// distributions.
+var rpmDBDirs = []string{"/usr/lib/sysimage/rpm", "/var/lib/rpm"}
+
+// isRPMDBFile returns whether name is a file of an rpm database.
+func isRPMDBFile(name string) bool {
+	return slices.Contains(rpmDBDirs, path.Dir(name))
+}
+
+// dpkgPackages returns the version of each installed package listed in the
+// content of a dpkg status file.
+func dpkgPackages(status []byte) map[string]string {
+	pkgs := make(map[string]string)
+
+	var name, version string
+	installed := false
+	add := func() {
+		if name != "" && installed {
+			pkgs[name] = version
+		}
+		name, version, installed = "", "", false
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(status))
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			add()
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch k {
+		case "Package":
+			name = v
+		case "Version":
+			version = v
+		case "Status":
+			installed = strings.HasSuffix(v, " installed")
+		}
+	}
+	add()
+	return pkgs
+}
+
+// rpmPackages returns the version of each package in the rpm database held in
+// r, using the host rpm command. The database is written to a directory under
+// tmpDir so that it can be queried.
+func rpmPackages(r *rootfs, tmpDir string) (map[string]string, error) {
+	for _, d := range rpmDBDirs {
+		var names []string
+		for name := range r.files {
+			if path.Dir(name) == d {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		dbDir, err := os.MkdirTemp(tmpDir, "rpmdb-")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(dbDir, path.Base(name)), r.files[name], 0o600); err != nil {
+				return nil, err
+			}
+		}
+		return rpm.Packages(dbDir)
+	}
+	return nil, nil
+}
+
+// packages returns the version of each package installed in the root
+// filesystem, from dpkg and rpm databases. Package names are prefixed by the
+// package manager. If an rpm database is present, but cannot be queried, a
+// warning is issued and its packages are omitted.
+func packages(r *rootfs, tmpDir string) map[string]string {
+	pkgs := make(map[string]string)
+
+	if status, ok := r.files[dpkgStatusFile]; ok {
+		for name, version := range dpkgPackages(status) {
+			pkgs["dpkg:"+name] = version
+		}
+	}
+
+	rpms, err := rpmPackages(r, tmpDir)
+	if err != nil {
+		sylog.Warningf("Unable to list rpm packages: %v", err)
+	}
+	for name, version := range rpms {
+		pkgs["rpm:"+name] = version
+	}
+
+	if len(pkgs) == 0 {
+		sylog.Debugf("No package database found")
+	}
+	return pkgs
+}
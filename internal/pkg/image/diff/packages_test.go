@@ -0,0 +1,37 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDpkgPackages(t *testing.T) {
+	status := `Package: bash
+Status: install ok installed
+Priority: required
+Version: 5.2.15-2+b2
+Description: GNU Bourne Again SHell
+ Bash is an sh-compatible command language interpreter.
+
+Package: removed
+Status: deinstall ok config-files
+Version: 1.0
+
+Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.36-9
+`
+	want := map[string]string{
+		"bash":  "5.2.15-2+b2",
+		"libc6": "2.36-9",
+	}
+	if got := dpkgPackages([]byte(status)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
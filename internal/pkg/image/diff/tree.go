@@ -0,0 +1,269 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package diff
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// Entry describes a file in the root filesystem of an image.
+type Entry struct {
+	Mode fs.FileMode
+	// Size is the size of a regular file.
+	Size int64
+	// Link is the target of a symbolic link.
+	Link string
+	// Digest is the digest of the content of a regular file.
+	Digest string
+}
+
+// MarshalJSON encodes the entry with its mode in string form.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Mode   string `json:"mode"`
+		Size   int64  `json:"size,omitempty"`
+		Link   string `json:"link,omitempty"`
+		Digest string `json:"digest,omitempty"`
+	}{
+		Mode:   e.Mode.String(),
+		Size:   e.Size,
+		Link:   e.Link,
+		Digest: e.Digest,
+	})
+}
+
+// String returns a short description of the entry.
+func (e Entry) String() string {
+	switch {
+	case e.Mode.IsRegular():
+		return fmt.Sprintf("%s %d", e.Mode, e.Size)
+	case e.Mode&fs.ModeSymlink != 0:
+		return fmt.Sprintf("%s -> %s", e.Mode, e.Link)
+	default:
+		return e.Mode.String()
+	}
+}
+
+// rootfs holds the files of the root filesystem of an image, keyed by absolute
+// path, and the content of those files that are read to obtain metadata and
+// package lists.
+type rootfs struct {
+	tree  map[string]Entry
+	files map[string][]byte
+}
+
+func newRootfs() *rootfs {
+	return &rootfs{
+		tree:  make(map[string]Entry),
+		files: make(map[string][]byte),
+	}
+}
+
+// isContentFile returns whether the content of the file at absolute path name
+// is required to compare image metadata or packages.
+func isContentFile(name string) bool {
+	switch {
+	case name == labelsFile, name == runscriptFile, name == dpkgStatusFile:
+		return true
+	case path.Dir(name) == envDir:
+		return true
+	}
+	return isRPMDBFile(name)
+}
+
+// normMode converts unix setuid, setgid and sticky bits, which are set by some
+// fs.FS implementations, to their fs.FileMode equivalents.
+func normMode(m fs.FileMode) fs.FileMode {
+	bits := []struct {
+		unix fs.FileMode
+		mode fs.FileMode
+	}{
+		{0o4000, fs.ModeSetuid},
+		{0o2000, fs.ModeSetgid},
+		{0o1000, fs.ModeSticky},
+	}
+	for _, b := range bits {
+		if m&b.unix != 0 {
+			m = m&^b.unix | b.mode
+		}
+	}
+	return m
+}
+
+// digest returns the digest of the content read from r. If keep is set, the
+// content is also returned.
+func digest(r io.Reader, keep bool) (string, []byte, error) {
+	var buf *bytes.Buffer
+	if keep {
+		buf = new(bytes.Buffer)
+		r = io.TeeReader(r, buf)
+	}
+	h, _, err := ggcrv1.SHA256(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if buf != nil {
+		return h.String(), buf.Bytes(), nil
+	}
+	return h.String(), nil, nil
+}
+
+// readFS reads the root filesystem held in fsys.
+func readFS(fsys fs.FS) (*rootfs, error) {
+	r := newRootfs()
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		abs := "/" + name
+		e := Entry{Mode: normMode(fi.Mode())}
+		switch {
+		case e.Mode.IsRegular():
+			e.Size = fi.Size()
+			f, err := fsys.Open(name)
+			if errors.Is(err, fs.ErrPermission) {
+				sylog.Warningf("Unable to read %s, comparing size only: %v", abs, err)
+				break
+			}
+			if err != nil {
+				return err
+			}
+			var content []byte
+			e.Digest, content, err = digest(f, isContentFile(abs))
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("while reading %s: %w", abs, err)
+			}
+			if content != nil {
+				r.files[abs] = content
+			}
+		case e.Mode&fs.ModeSymlink != 0:
+			if e.Link, err = fs.ReadLink(fsys, name); err != nil {
+				return err
+			}
+		}
+		r.tree[abs] = e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// remove removes name, and anything beneath it, from the root filesystem. If
+// childrenOnly is set, name itself is retained.
+func (r *rootfs) remove(name string, childrenOnly bool) {
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	for k := range r.tree {
+		if strings.HasPrefix(k, prefix) || (!childrenOnly && k == name) {
+			delete(r.tree, k)
+			delete(r.files, k)
+		}
+	}
+}
+
+// applyLayer applies the changes held in the tar stream rd, with OCI
+// whiteouts, to the root filesystem.
+func (r *rootfs) applyLayer(rd io.Reader) error {
+	const (
+		whiteoutPrefix = ".wh."
+		opaqueWhiteout = ".wh..wh..opq"
+	)
+
+	added := make(map[string]Entry)
+	files := make(map[string][]byte)
+	var whiteouts, opaques []string
+
+	tr := tar.NewReader(rd)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean("/" + h.Name)
+		if name == "/" {
+			continue
+		}
+		dir, base := path.Split(name)
+		if base == opaqueWhiteout {
+			opaques = append(opaques, path.Clean(dir))
+			continue
+		}
+		if target, ok := strings.CutPrefix(base, whiteoutPrefix); ok {
+			whiteouts = append(whiteouts, path.Join(dir, target))
+			continue
+		}
+
+		e := Entry{Mode: h.FileInfo().Mode()}
+		switch h.Typeflag {
+		case tar.TypeReg:
+			e.Size = h.Size
+			var content []byte
+			e.Digest, content, err = digest(tr, isContentFile(name))
+			if err != nil {
+				return fmt.Errorf("while reading %s: %w", name, err)
+			}
+			if content != nil {
+				files[name] = content
+			}
+		case tar.TypeSymlink:
+			e.Link = h.Linkname
+		case tar.TypeLink:
+			target := path.Clean("/" + h.Linkname)
+			t, ok := added[target]
+			if !ok {
+				if t, ok = r.tree[target]; !ok {
+					return fmt.Errorf("hard link %s to missing file %s", name, target)
+				}
+			}
+			e = t
+			if content, ok := files[target]; ok {
+				files[name] = content
+			} else if content, ok := r.files[target]; ok {
+				files[name] = content
+			}
+		}
+		added[name] = e
+	}
+
+	for _, d := range opaques {
+		r.remove(d, true)
+	}
+	for _, w := range whiteouts {
+		r.remove(w, false)
+	}
+	for name, e := range added {
+		r.tree[name] = e
+	}
+	for name, content := range files {
+		r.files[name] = content
+	}
+	return nil
+}
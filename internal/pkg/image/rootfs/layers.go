@@ -0,0 +1,414 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package rootfs
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// layerNode is a file in the root filesystem assembled from image layers.
+type layerNode struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	link    string
+	// layer is the index of the layer that holds the content of the file, and
+	// index is the position of the file in the tar stream of that layer.
+	layer int
+	index int
+	// children holds the entries of a directory.
+	children map[string]*layerNode
+}
+
+// layerAdd is a node added to the index by the layer being applied.
+type layerAdd struct {
+	name string
+	node *layerNode
+}
+
+// layerCursor is a tar stream of a layer, positioned after the entry at
+// index, which is reused to read files in stream order without reading the
+// layer from its start for each file.
+type layerCursor struct {
+	rc    io.ReadCloser
+	tr    *tar.Reader
+	index int
+	inUse bool
+}
+
+// layerFS is a read-only view of the root filesystem assembled from the
+// layers of an OCI image. File content is streamed from the layer that holds
+// it when the file is opened.
+type layerFS struct {
+	root    *layerNode
+	openers []func() (io.ReadCloser, error)
+
+	mu      sync.Mutex
+	cursors map[int]*layerCursor
+}
+
+var _ fs.ReadLinkFS = (*layerFS)(nil)
+
+// newLayerFS indexes the files of the layers of img. Temporary files that may
+// be needed to read layers are created in tmpDir.
+func newLayerFS(img ggcrv1.Image, tmpDir string) (*layerFS, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("while retrieving image layers: %w", err)
+	}
+
+	l := &layerFS{
+		root:    &layerNode{name: ".", mode: fs.ModeDir | 0o755, children: map[string]*layerNode{}},
+		cursors: map[int]*layerCursor{},
+	}
+	for i, layer := range layers {
+		mt, err := layer.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		opener, err := ocisif.LayerTarOpener(layer, mt, tmpDir)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+		l.openers = append(l.openers, opener)
+		if err := l.applyLayer(i); err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+	}
+	return l, nil
+}
+
+// applyLayer adds the files of layer i to the index, applying its whiteouts.
+func (l *layerFS) applyLayer(i int) error {
+	rc, err := l.openers[i]()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var adds []layerAdd
+	var whiteouts, opaques []string
+
+	tr := tar.NewReader(rc)
+	for index := 0; ; index++ {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(strings.TrimPrefix(path.Clean("/"+h.Name), "/"))
+		if name == "" || name == "." {
+			continue
+		}
+		dir, base := path.Split(name)
+		if base == opaqueWhiteout {
+			opaques = append(opaques, path.Clean(dir))
+			continue
+		}
+		if target, ok := strings.CutPrefix(base, whiteoutPrefix); ok {
+			whiteouts = append(whiteouts, path.Join(dir, target))
+			continue
+		}
+
+		n := &layerNode{
+			name:    base,
+			mode:    h.FileInfo().Mode(),
+			size:    h.Size,
+			modTime: h.ModTime,
+			link:    h.Linkname,
+			layer:   i,
+			index:   index,
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			n.size = 0
+		case tar.TypeLink:
+			target := path.Clean(strings.TrimPrefix(path.Clean("/"+h.Linkname), "/"))
+			t := l.lookupAdded(target, adds)
+			if t == nil {
+				return fmt.Errorf("hard link %s to missing file %s", name, target)
+			}
+			*n = *t
+			n.name = base
+		case tar.TypeReg:
+		default:
+			n.size = 0
+		}
+		if h.Typeflag != tar.TypeSymlink {
+			n.link = ""
+		}
+		adds = append(adds, layerAdd{name: name, node: n})
+	}
+
+	for _, d := range opaques {
+		if n := l.lookup(d); n != nil && n.children != nil {
+			clear(n.children)
+		}
+	}
+	for _, w := range whiteouts {
+		if parent := l.lookup(path.Dir(w)); parent != nil && parent.children != nil {
+			delete(parent.children, path.Base(w))
+		}
+	}
+	for _, a := range adds {
+		l.insert(a.name, a.node)
+	}
+	return nil
+}
+
+// lookupAdded returns the node at name, among the nodes added by the layer
+// being applied, or the nodes of lower layers.
+func (l *layerFS) lookupAdded(name string, adds []layerAdd) *layerNode {
+	for i := len(adds) - 1; i >= 0; i-- {
+		if adds[i].name == name {
+			return adds[i].node
+		}
+	}
+	return l.lookup(name)
+}
+
+// lookup returns the node at the clean, relative path name, or nil if there is
+// no such node. Symbolic links are not followed.
+func (l *layerFS) lookup(name string) *layerNode {
+	n := l.root
+	if name == "." {
+		return n
+	}
+	for elem := range strings.SplitSeq(name, "/") {
+		if n.children == nil {
+			return nil
+		}
+		if n = n.children[elem]; n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// insert places node n at the clean, relative path name, creating any missing
+// parent directories. An existing directory retains its entries.
+func (l *layerFS) insert(name string, n *layerNode) {
+	parent := l.root
+	dir, base := path.Split(name)
+	if dir != "" {
+		for elem := range strings.SplitSeq(strings.TrimSuffix(dir, "/"), "/") {
+			child := parent.children[elem]
+			if child == nil || child.children == nil {
+				child = &layerNode{name: elem, mode: fs.ModeDir | 0o755, children: map[string]*layerNode{}}
+				parent.children[elem] = child
+			}
+			parent = child
+		}
+	}
+
+	if n.mode.IsDir() {
+		n.children = map[string]*layerNode{}
+		if old := parent.children[base]; old != nil && old.children != nil {
+			n.children = old.children
+		}
+	}
+	parent.children[base] = n
+}
+
+func (l *layerFS) node(op, name string) (*layerNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	n := l.lookup(name)
+	if n == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return n, nil
+}
+
+// Open opens the named file. A symbolic link is opened as itself.
+func (l *layerFS) Open(name string) (fs.File, error) {
+	n, err := l.node("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f := &layerFile{fs: l, node: n}
+	if n.children != nil {
+		for _, k := range slices.Sorted(maps.Keys(n.children)) {
+			f.entries = append(f.entries, layerDirEntry{n.children[k]})
+		}
+	}
+	return f, nil
+}
+
+// Lstat returns a FileInfo describing the named file.
+func (l *layerFS) Lstat(name string) (fs.FileInfo, error) {
+	n, err := l.node("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return layerFileInfo{n}, nil
+}
+
+// ReadLink returns the destination of the named symbolic link.
+func (l *layerFS) ReadLink(name string) (string, error) {
+	n, err := l.node("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if n.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return n.link, nil
+}
+
+// content returns a reader for the content of regular file n. If the cursor of
+// the layer holding n is free, and positioned before n, it is used. Otherwise,
+// the layer is read from its start.
+func (l *layerFS) content(n *layerNode) (io.Reader, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.cursors[n.layer]
+	switch {
+	case c != nil && c.inUse:
+		// Another file of the layer is being read, use a separate stream.
+		c = &layerCursor{index: -1}
+	case c == nil || c.index >= n.index:
+		if c != nil {
+			c.rc.Close()
+		}
+		c = &layerCursor{index: -1}
+		l.cursors[n.layer] = c
+	}
+	if c.rc == nil {
+		rc, err := l.openers[n.layer]()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.rc = rc
+		c.tr = tar.NewReader(rc)
+	}
+
+	for c.index < n.index {
+		if _, err := c.tr.Next(); err != nil {
+			c.rc.Close()
+			delete(l.cursors, n.layer)
+			return nil, nil, fmt.Errorf("while reading layer %d: %w", n.layer, err)
+		}
+		c.index++
+	}
+
+	c.inUse = true
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		c.inUse = false
+		if l.cursors[n.layer] != c {
+			c.rc.Close()
+		}
+	}
+	return io.LimitReader(c.tr, n.size), release, nil
+}
+
+// Close releases the layer streams held open to read files.
+func (l *layerFS) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, c := range l.cursors {
+		c.rc.Close()
+		delete(l.cursors, i)
+	}
+	return nil
+}
+
+type layerFileInfo struct {
+	n *layerNode
+}
+
+func (fi layerFileInfo) Name() string       { return fi.n.name }
+func (fi layerFileInfo) Size() int64        { return fi.n.size }
+func (fi layerFileInfo) Mode() fs.FileMode  { return fi.n.mode }
+func (fi layerFileInfo) ModTime() time.Time { return fi.n.modTime }
+func (fi layerFileInfo) IsDir() bool        { return fi.n.mode.IsDir() }
+func (fi layerFileInfo) Sys() any           { return nil }
+
+type layerDirEntry struct {
+	n *layerNode
+}
+
+func (d layerDirEntry) Name() string               { return d.n.name }
+func (d layerDirEntry) IsDir() bool                { return d.n.mode.IsDir() }
+func (d layerDirEntry) Type() fs.FileMode          { return d.n.mode.Type() }
+func (d layerDirEntry) Info() (fs.FileInfo, error) { return layerFileInfo(d), nil }
+
+// layerFile is an open file or directory of a layerFS.
+type layerFile struct {
+	fs      *layerFS
+	node    *layerNode
+	r       io.Reader
+	release func()
+	entries []fs.DirEntry
+}
+
+func (f *layerFile) Stat() (fs.FileInfo, error) { return layerFileInfo{f.node}, nil }
+
+func (f *layerFile) Read(b []byte) (int, error) {
+	if !f.node.mode.IsRegular() {
+		return 0, &fs.PathError{Op: "read", Path: f.node.name, Err: errors.New("not a regular file")}
+	}
+	if f.r == nil {
+		r, release, err := f.fs.content(f.node)
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.node.name, Err: err}
+		}
+		f.r, f.release = r, release
+	}
+	return f.r.Read(b)
+}
+
+func (f *layerFile) Close() error {
+	if f.release != nil {
+		f.release()
+		f.release = nil
+	}
+	return nil
+}
+
+func (f *layerFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.node.name, Err: errors.New("not a directory")}
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package rootfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+type tarFile struct {
+	name     string
+	content  string
+	typeflag byte
+	linkname string
+}
+
+func tarLayer(t *testing.T, files []tarFile) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		h := &tar.Header{
+			Name:     f.name,
+			Typeflag: f.typeflag,
+			Linkname: f.linkname,
+			Mode:     0o644,
+			Size:     int64(len(f.content)),
+		}
+		if f.typeflag == tar.TypeDir {
+			h.Mode = 0o755
+		}
+		if f.typeflag != tar.TypeReg {
+			h.Size = 0
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil && h.Size > 0 {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLayerFS(t *testing.T) {
+	layers := [][]tarFile{
+		{
+			{name: "etc/", typeflag: tar.TypeDir},
+			{name: "etc/a", content: "a", typeflag: tar.TypeReg},
+			{name: "etc/b", content: "b", typeflag: tar.TypeReg},
+			{name: "etc/whiteout", content: "x", typeflag: tar.TypeReg},
+			{name: "opt/", typeflag: tar.TypeDir},
+			{name: "opt/old", content: "old", typeflag: tar.TypeReg},
+			{name: "usr/lib/os-release", content: "ID=test\n", typeflag: tar.TypeReg},
+		},
+		{
+			{name: "etc/.wh.whiteout", typeflag: tar.TypeReg},
+			{name: "etc/hardlink", typeflag: tar.TypeLink, linkname: "etc/a"},
+			{name: "etc/os-release", typeflag: tar.TypeSymlink, linkname: "../usr/lib/os-release"},
+			{name: "opt/", typeflag: tar.TypeDir},
+			{name: "opt/.wh..wh..opq", typeflag: tar.TypeReg},
+			{name: "opt/new", content: "new", typeflag: tar.TypeReg},
+		},
+	}
+
+	img := empty.Image
+	for _, files := range layers {
+		b := tarLayer(t, files)
+		l, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img, err = mutate.AppendLayers(img, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base, err := newLayerFS(img, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := &FS{base: base, closers: []io.Closer{base}}
+	defer fsys.Close()
+
+	var names []string
+	err = fs.WalkDir(fsys, ".", func(name string, _ fs.DirEntry, err error) error {
+		names = append(names, name)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		".", "etc", "etc/a", "etc/b", "etc/hardlink", "etc/os-release",
+		"opt", "opt/new", "usr", "usr/lib", "usr/lib/os-release",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got files %v, want %v", names, want)
+	}
+
+	// Read files in and out of layer order, and with two files of a layer
+	// open at once.
+	for name, want := range map[string]string{
+		"etc/b":          "b",
+		"etc/a":          "a",
+		"etc/hardlink":   "a",
+		"etc/os-release": "ID=test\n",
+		"opt/new":        "new",
+	} {
+		got, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got content %q, want %q", name, got, want)
+		}
+	}
+	fa, err := fsys.Open("etc/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fa.Close()
+	if n, err := fa.Read(make([]byte, 1)); n != 1 {
+		t.Fatalf("got %d, %v reading etc/a", n, err)
+	}
+	if got, err := fs.ReadFile(fsys, "etc/b"); err != nil || string(got) != "b" {
+		t.Errorf("got %q, %v reading etc/b while etc/a is open", got, err)
+	}
+
+	if _, err := fsys.Stat("etc/whiteout"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v for whiteout file, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package rootfs provides read-only access to the root filesystem of a
+// container image, in-process, without mounting the image.
+package rootfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/ccoveille/go-safecast/v2"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociplatform"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/crypt"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/ext3"
+	"github.com/sylabs/singularity/v4/pkg/image"
+	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
+)
+
+// maxSymlinks is the maximum number of symbolic links followed while
+// resolving a path.
+const maxSymlinks = 40
+
+// ErrNoKey is returned when an encrypted image is opened without a key.
+var ErrNoKey = errors.New("image is encrypted, a key is required")
+
+type options struct {
+	keyInfo  *cryptkey.KeyInfo
+	platform *ggcrv1.Platform
+	tmpDir   string
+}
+
+// Option is a functional option for Open.
+type Option func(o *options) error
+
+// OptKeyInfo sets the key used to decrypt an encrypted image.
+func OptKeyInfo(ki *cryptkey.KeyInfo) Option {
+	return func(o *options) error {
+		o.keyInfo = ki
+		return nil
+	}
+}
+
+// OptPlatform sets the platform of the image that is read from a multi-platform
+// OCI-SIF, which the image read from any other OCI-SIF must also satisfy. By
+// default, the host platform is used for a multi-platform OCI-SIF.
+func OptPlatform(p ggcrv1.Platform) Option {
+	return func(o *options) error {
+		o.platform = &p
+		return nil
+	}
+}
+
+// OptTmpDir sets the directory in which temporary files are created.
+func OptTmpDir(dir string) Option {
+	return func(o *options) error {
+		o.tmpDir = dir
+		return nil
+	}
+}
+
+// FS is a read-only view of the root filesystem of an image. It implements
+// fs.FS, fs.ReadDirFS, fs.StatFS and fs.ReadLinkFS. Symbolic links in paths
+// are resolved relative to the root of the image, never the host.
+type FS struct {
+	base    fs.ReadLinkFS
+	closers []io.Closer
+}
+
+var (
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadLinkFS = (*FS)(nil)
+)
+
+// Open opens the root filesystem of the image at src, which may be a
+// sandbox, SIF, squashfs, ext3 or OCI-SIF image. The squashfs and ext3
+// filesystems of native images are read directly from the image file. The
+// root filesystem of an OCI-SIF image is assembled from its layers.
+func Open(src string, opts ...Option) (*FS, error) {
+	o := options{tmpDir: os.TempDir()}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	img, err := image.Init(src, false)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FS{}
+	switch img.Type {
+	case image.SANDBOX:
+		img.File.Close()
+		root, err := os.OpenRoot(img.Path)
+		if err != nil {
+			return nil, err
+		}
+		f.base = root.FS().(fs.ReadLinkFS)
+		f.closers = append(f.closers, root)
+	case image.OCISIF:
+		img.File.Close()
+		base, err := openOCISIF(img.Path, o)
+		if err != nil {
+			return nil, err
+		}
+		f.base = base
+		f.closers = append(f.closers, base)
+	default:
+		f.closers = append(f.closers, img.File)
+		if f.base, err = openPartition(img, o); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// openPartition returns a view of the root filesystem partition of a native
+// image.
+func openPartition(img *image.Image, o options) (fs.ReadLinkFS, error) {
+	part, err := img.GetRootFsPartition()
+	if err != nil {
+		return nil, err
+	}
+	offset, err := safecast.Convert[int64](part.Offset)
+	if err != nil {
+		return nil, err
+	}
+	size, err := safecast.Convert[int64](part.Size)
+	if err != nil {
+		return nil, err
+	}
+	var r io.ReaderAt = io.NewSectionReader(img.File, offset, size)
+
+	switch part.Type {
+	case image.SQUASHFS:
+		return newSquashfsFS(r)
+	case image.EXT3:
+		return ext3.New(r)
+	case image.ENCRYPTSQUASHFS:
+		if o.keyInfo == nil {
+			return nil, ErrNoKey
+		}
+		key, err := cryptkey.PlaintextKey(*o.keyInfo, img.Path)
+		if err != nil {
+			return nil, fmt.Errorf("while retrieving image key: %w", err)
+		}
+		l, err := crypt.NewLUKS2Reader(r, size, key)
+		if err != nil {
+			return nil, fmt.Errorf("while decrypting root filesystem: %w", err)
+		}
+		return newSquashfsFS(l)
+	default:
+		return nil, fmt.Errorf("unsupported root filesystem partition type %d", part.Type)
+	}
+}
+
+// openOCISIF returns a view of the root filesystem of the image, for the
+// requested platform, in the OCI-SIF at src. Without a requested platform, a
+// single image is read whatever its platform, and the image for the host
+// platform is read from a multi-platform OCI-SIF.
+func openOCISIF(src string, o options) (*layerFS, error) {
+	fi, err := sif.LoadContainerFromPath(src, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF: %w", err)
+	}
+	defer fi.UnloadContainer()
+
+	if o.platform == nil {
+		img, err := ocisif.GetSingleImage(fi)
+		if err == nil {
+			return newLayerFS(img, o.tmpDir)
+		}
+		if !errors.Is(err, ocisif.ErrMultiplePlatforms) {
+			return nil, err
+		}
+		if o.platform, err = ociplatform.DefaultPlatform(); err != nil {
+			return nil, err
+		}
+	}
+
+	img, err := ocisif.GetPlatformImage(fi, *o.platform)
+	if err != nil {
+		return nil, err
+	}
+	return newLayerFS(img, o.tmpDir)
+}
+
+// Close releases the resources held by the view of the root filesystem.
+func (f *FS) Close() error {
+	var errs []error
+	for _, c := range f.closers {
+		errs = append(errs, c.Close())
+	}
+	f.closers = nil
+	return errors.Join(errs...)
+}
+
+// resolve returns the path of name, with all symbolic links resolved relative
+// to the root of the filesystem. If follow is not set, a symbolic link in the
+// final element of name is not resolved.
+func (f *FS) resolve(op, name string, follow bool) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	var resolved []string
+	pending := strings.Split(name, "/")
+	links := 0
+	for len(pending) > 0 {
+		elem := pending[0]
+		pending = pending[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+			continue
+		}
+
+		p := path.Join(append(resolved, elem)...)
+		if len(pending) == 0 && !follow {
+			resolved = append(resolved, elem)
+			break
+		}
+		fi, err := f.base.Lstat(p)
+		if err != nil {
+			return "", &fs.PathError{Op: op, Path: name, Err: unwrapPathError(err)}
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			resolved = append(resolved, elem)
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return "", &fs.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+		}
+		target, err := f.base.ReadLink(p)
+		if err != nil {
+			return "", &fs.PathError{Op: op, Path: name, Err: unwrapPathError(err)}
+		}
+		if strings.HasPrefix(target, "/") {
+			resolved = nil
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	if len(resolved) == 0 {
+		return ".", nil
+	}
+	return path.Join(resolved...), nil
+}
+
+// unwrapPathError returns the underlying error of a *fs.PathError, so that it
+// can be reported against the path requested by the caller.
+func unwrapPathError(err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return pe.Err
+	}
+	return err
+}
+
+// Open opens the named file, following symbolic links.
+func (f *FS) Open(name string) (fs.File, error) {
+	p, err := f.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return f.base.Open(p)
+}
+
+// Stat returns a FileInfo describing the named file, following symbolic
+// links.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	p, err := f.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return f.base.Lstat(p)
+}
+
+// Lstat returns a FileInfo describing the named file. If the file is a
+// symbolic link, it is described as itself.
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := f.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return f.base.Lstat(p)
+}
+
+// ReadLink returns the destination of the named symbolic link.
+func (f *FS) ReadLink(name string) (string, error) {
+	p, err := f.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	return f.base.ReadLink(p)
+}
+
+// ReadDir reads the named directory, following symbolic links, and returns its
+// entries sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := f.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(f.base, p)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package rootfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// writeTree populates dir with files and symbolic links used to test path
+// resolution.
+func writeTree(t *testing.T, dir string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Join(dir, "usr/lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "usr/lib/os-release"), []byte("ID=test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"lib":            "usr/lib",
+		"etc/os-release": "../usr/lib/os-release",
+		"etc/absolute":   "/usr/lib/os-release",
+		"etc/escape":     "../../../../usr/lib",
+		"etc/loop":       "loop",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testResolve(t *testing.T, fsys *FS) {
+	t.Helper()
+
+	for _, name := range []string{"etc/os-release", "etc/absolute", "lib/os-release", "etc/escape/os-release"} {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(b) != "ID=test\n" {
+			t.Errorf("%s: got content %q", name, b)
+		}
+	}
+
+	fi, err := fsys.Lstat("etc/os-release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat followed symbolic link")
+	}
+	target, err := fsys.ReadLink("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "usr/lib" {
+		t.Errorf("got link target %q, want %q", target, "usr/lib")
+	}
+
+	entries, err := fsys.ReadDir("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "os-release" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+
+	if _, err := fsys.Stat("etc/loop"); !errors.Is(err, syscall.ELOOP) {
+		t.Errorf("got error %v for symbolic link loop, want %v", err, syscall.ELOOP)
+	}
+	if _, err := fsys.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v for missing file, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestOpenSandbox(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir)
+
+	fsys, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	testResolve(t, fsys)
+}
+
+func TestOpenExt3(t *testing.T) {
+	mkfs, err := exec.LookPath("mkfs.ext3")
+	if err != nil {
+		t.Skip("mkfs.ext3 not found")
+	}
+
+	src := t.TempDir()
+	writeTree(t, src)
+	img := filepath.Join(t.TempDir(), "image.img")
+	if out, err := exec.Command(mkfs, "-q", "-F", "-d", src, img, "8M").CombinedOutput(); err != nil {
+		t.Fatalf("mkfs.ext3 failed: %v: %s", err, out)
+	}
+
+	fsys, err := Open(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	testResolve(t, fsys)
+}
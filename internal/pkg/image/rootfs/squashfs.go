@@ -0,0 +1,47 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package rootfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/sylabs/squashfs"
+)
+
+// squashfsFS adds fs.ReadLinkFS to a squashfs filesystem, which never follows
+// symbolic links.
+type squashfsFS struct {
+	*squashfs.Reader
+}
+
+func newSquashfsFS(r io.ReaderAt) (*squashfsFS, error) {
+	sr, err := squashfs.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("while reading squashfs filesystem: %w", err)
+	}
+	return &squashfsFS{sr}, nil
+}
+
+// Lstat returns a FileInfo describing the named file.
+func (s *squashfsFS) Lstat(name string) (fs.FileInfo, error) {
+	return s.Stat(name)
+}
+
+// ReadLink returns the destination of the named symbolic link.
+func (s *squashfsFS) ReadLink(name string) (string, error) {
+	f, err := s.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sf, ok := f.(*squashfs.File)
+	if !ok || !sf.IsSymlink() {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return sf.SymlinkPath(), nil
+}
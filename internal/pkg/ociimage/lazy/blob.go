@@ -0,0 +1,325 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package lazy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultChunkSize is the size of the chunks that are fetched from the
+// registry, and stored in the chunk cache.
+const DefaultChunkSize = 1 << 20
+
+// indexFile is the name of the file, in the chunk directory of a blob, that
+// records the digests of its chunks.
+const indexFile = "index"
+
+var (
+	// errNoRangeSupport is returned when a registry (or the storage it
+	// redirects to) ignores a range request, and returns a complete blob.
+	errNoRangeSupport = errors.New("registry does not support range requests")
+	// errNotVerified is returned when a chunk is read from a blob that has not
+	// been verified against its digest.
+	errNotVerified = errors.New("blob has not been verified")
+	// errDigestMismatch is returned when content from the registry does not
+	// match the expected digest.
+	errDigestMismatch = errors.New("digest mismatch")
+)
+
+// chunkIndex holds the digests of the chunks of a blob, which has been
+// verified against its digest.
+type chunkIndex struct {
+	ChunkSize int64    `json:"chunkSize"`
+	Digests   []string `json:"digests"`
+}
+
+// Blob provides random access to a layer blob held in a remote registry. Data
+// is fetched in fixed size chunks, using HTTP range requests, on first access.
+// Fetched chunks are stored in a chunk directory, so that they are only
+// retrieved from the registry once.
+//
+// Chunks are checked against an index of chunk digests, which is recorded when
+// the complete blob is verified against its digest by Verify.
+type Blob struct {
+	url       string
+	client    *http.Client
+	digest    ggcrv1.Hash
+	size      int64
+	chunkSize int64
+	// chunkDir holds chunks that have been fetched from the registry.
+	chunkDir string
+	// fullPath, if set, is a complete local copy of the blob that is used in
+	// preference to the registry.
+	fullPath string
+	// index holds the digests of the chunks of the blob, once verified.
+	index *chunkIndex
+	// fetches ensures that a chunk is only fetched once when it is read
+	// concurrently, without serializing fetches of distinct chunks.
+	fetches singleflight.Group
+}
+
+func newBlob(url string, client *http.Client, digest ggcrv1.Hash, size int64, chunkDir string) *Blob {
+	return &Blob{
+		url:       url,
+		client:    client,
+		digest:    digest,
+		size:      size,
+		chunkSize: DefaultChunkSize,
+		chunkDir:  chunkDir,
+	}
+}
+
+// Digest returns the digest of the blob.
+func (b *Blob) Digest() ggcrv1.Hash {
+	return b.digest
+}
+
+// Size returns the size of the blob in bytes.
+func (b *Blob) Size() int64 {
+	return b.size
+}
+
+// Verify ensures that the content of the blob matches its digest, before it is
+// read. If the chunk directory holds an index of chunk digests, recorded by an
+// earlier verification, it is used. Otherwise, the complete blob is streamed
+// from the registry, without being stored, to check its digest and record the
+// digests of its chunks. Subsequent reads fetch chunks on demand, and check
+// each against the index.
+func (b *Blob) Verify(ctx context.Context) error {
+	if b.fullPath != "" || b.index != nil {
+		return nil
+	}
+	if b.digest.Algorithm != "sha256" {
+		return fmt.Errorf("unsupported digest algorithm %q", b.digest.Algorithm)
+	}
+
+	indexPath := filepath.Join(b.chunkDir, indexFile)
+	if ci, err := b.readIndex(indexPath); err == nil {
+		b.index = ci
+		return nil
+	}
+
+	sylog.Infof("Verifying layer %s, reading %d bytes from the registry", b.digest, b.size)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from registry: %s", resp.Status)
+	}
+
+	ci := chunkIndex{ChunkSize: b.chunkSize}
+	blobHash := sha256.New()
+	r := io.TeeReader(resp.Body, blobHash)
+	for idx := int64(0); idx*b.chunkSize < b.size; idx++ {
+		chunkHash := sha256.New()
+		if _, err := io.CopyN(chunkHash, r, b.chunkLen(idx)); err != nil {
+			return fmt.Errorf("while reading %s: %w", b.digest, err)
+		}
+		ci.Digests = append(ci.Digests, hex.EncodeToString(chunkHash.Sum(nil)))
+	}
+	if n, _ := io.Copy(io.Discard, r); n > 0 {
+		return fmt.Errorf("%s is larger than %d bytes", b.digest, b.size)
+	}
+	if got := hex.EncodeToString(blobHash.Sum(nil)); got != b.digest.Hex {
+		return fmt.Errorf("%w: %s has digest sha256:%s", errDigestMismatch, b.digest, got)
+	}
+
+	b.index = &ci
+	if err := writeIndex(indexPath, &ci); err != nil {
+		sylog.Warningf("Could not store chunk index of %s: %v", b.digest, err)
+	}
+	return nil
+}
+
+// readIndex reads an index of chunk digests from path, checking that it
+// describes the chunks of b.
+func (b *Blob) readIndex(path string) (*chunkIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ci chunkIndex
+	if err := json.Unmarshal(data, &ci); err != nil {
+		return nil, err
+	}
+	if ci.ChunkSize != b.chunkSize || int64(len(ci.Digests)) != (b.size+b.chunkSize-1)/b.chunkSize {
+		return nil, fmt.Errorf("index does not match blob")
+	}
+	return &ci, nil
+}
+
+// ReadAt implements io.ReaderAt, fetching any chunks covering the requested
+// range that are not yet held locally. Unless a complete local copy of the blob
+// is used, Verify must have been called first.
+func (b *Blob) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off >= b.size {
+		return 0, io.EOF
+	}
+
+	if b.fullPath != "" {
+		f, err := os.Open(b.fullPath)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		return f.ReadAt(p, off)
+	}
+
+	n := 0
+	for n < len(p) && off < b.size {
+		idx := off / b.chunkSize
+		chunk, err := b.chunk(idx)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], chunk[off-idx*b.chunkSize:])
+		n += c
+		off += int64(c)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// chunk returns the content of chunk idx, from the chunk directory if present
+// and intact, or else from the registry. Content is checked against the digest
+// of the chunk in the index.
+func (b *Blob) chunk(idx int64) ([]byte, error) {
+	if b.index == nil {
+		return nil, fmt.Errorf("%w: %s", errNotVerified, b.digest)
+	}
+
+	path := filepath.Join(b.chunkDir, strconv.FormatInt(idx, 10))
+	if data, err := os.ReadFile(path); err == nil && b.chunkValid(idx, data) {
+		return data, nil
+	}
+
+	v, err, _ := b.fetches.Do(strconv.FormatInt(idx, 10), func() (any, error) {
+		// Chunk may have been fetched while we were waiting.
+		if data, err := os.ReadFile(path); err == nil && b.chunkValid(idx, data) {
+			return data, nil
+		}
+
+		data, err := b.fetch(idx*b.chunkSize, b.chunkLen(idx))
+		if err != nil {
+			return nil, fmt.Errorf("while fetching %s chunk %d: %w", b.digest, idx, err)
+		}
+		if !b.chunkValid(idx, data) {
+			return nil, fmt.Errorf("while fetching %s chunk %d: %w", b.digest, idx, errDigestMismatch)
+		}
+
+		if err := writeChunk(path, data); err != nil {
+			sylog.Warningf("Could not store %s chunk %d: %v", b.digest, idx, err)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// chunkValid reports whether data is the content of chunk idx, according to
+// the index.
+func (b *Blob) chunkValid(idx int64, data []byte) bool {
+	if int64(len(data)) != b.chunkLen(idx) {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) == b.index.Digests[idx]
+}
+
+// chunkLen returns the length of chunk idx, which is shorter than chunkSize
+// for the final chunk of the blob.
+func (b *Blob) chunkLen(idx int64) int64 {
+	return min(b.chunkSize, b.size-idx*b.chunkSize)
+}
+
+// fetch retrieves length bytes of the blob, starting at off, from the registry.
+func (b *Blob) fetch(off, length int64) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, b.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+length-1))
+
+	sylog.Debugf("Fetching %d bytes at offset %d from %s", length, off, b.url)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		return nil, errNoRangeSupport
+	default:
+		return nil, fmt.Errorf("unexpected response from registry: %s", resp.Status)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(resp.Body, data); err != nil {
+		return nil, fmt.Errorf("while reading response: %w", err)
+	}
+	return data, nil
+}
+
+// writeIndex writes an index of chunk digests to path.
+func writeIndex(path string, ci *chunkIndex) error {
+	data, err := json.Marshal(ci)
+	if err != nil {
+		return err
+	}
+	return writeChunk(path, data)
+}
+
+// writeChunk atomically writes a chunk to path, so that concurrent readers
+// never see a partial chunk.
+func writeChunk(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".chunk-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
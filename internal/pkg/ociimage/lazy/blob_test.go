@@ -0,0 +1,252 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package lazy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func testContent(size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// blobServer serves content, counting requests, optionally ignoring Range
+// headers.
+func blobServer(t *testing.T, content []byte, ignoreRange bool, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if ignoreRange {
+			r.Header.Del("Range")
+		}
+		http.ServeContent(w, r, "blob", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// testBlob returns a Blob for content served by s, with 1KiB chunks.
+func testBlob(t *testing.T, s *httptest.Server, content []byte) *Blob {
+	t.Helper()
+
+	sum := sha256.Sum256(content)
+	digest := ggcrv1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+	b := newBlob(s.URL, s.Client(), digest, int64(len(content)), t.TempDir())
+	b.chunkSize = 1024
+	return b
+}
+
+func TestBlobReadAt(t *testing.T) {
+	content := testContent(10*1024 + 123)
+
+	tests := []struct {
+		name   string
+		off    int64
+		length int
+	}{
+		{name: "Start", off: 0, length: 10},
+		{name: "WithinChunk", off: 1500, length: 100},
+		{name: "AcrossChunks", off: 1000, length: 3000},
+		{name: "FinalChunk", off: 10 * 1024, length: 123},
+		{name: "PastEnd", off: 10*1024 + 100, length: 100},
+		{name: "Whole", off: 0, length: len(content)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			s := blobServer(t, content, false, &requests)
+			b := testBlob(t, s, content)
+			if err := b.Verify(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			p := make([]byte, tt.length)
+			n, err := b.ReadAt(p, tt.off)
+
+			want := content[tt.off:min(tt.off+int64(tt.length), int64(len(content)))]
+			if n != len(want) {
+				t.Fatalf("read %d bytes, want %d", n, len(want))
+			}
+			if n < tt.length && !errors.Is(err, io.EOF) {
+				t.Errorf("got error %v, want %v", err, io.EOF)
+			}
+			if n == tt.length && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(p[:n], want) {
+				t.Errorf("read content does not match")
+			}
+
+			// A second read must be satisfied from the chunk directory.
+			before := requests.Load()
+			if _, err := b.ReadAt(p, tt.off); err != nil && !errors.Is(err, io.EOF) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if after := requests.Load(); after != before {
+				t.Errorf("%d requests made for cached chunks", after-before)
+			}
+		})
+	}
+}
+
+func TestBlobNoRangeSupport(t *testing.T) {
+	content := testContent(4096)
+
+	var requests atomic.Int32
+	s := blobServer(t, content, true, &requests)
+	b := testBlob(t, s, content)
+
+	if _, err := b.fetch(0, 10); !errors.Is(err, errNoRangeSupport) {
+		t.Errorf("got error %v, want %v", err, errNoRangeSupport)
+	}
+}
+
+func TestBlobVerify(t *testing.T) {
+	content := testContent(4096 + 10)
+
+	t.Run("Mismatch", func(t *testing.T) {
+		var requests atomic.Int32
+		s := blobServer(t, content, false, &requests)
+		b := testBlob(t, s, content)
+		b.digest.Hex = strings.Repeat("0", 64)
+
+		if err := b.Verify(context.Background()); !errors.Is(err, errDigestMismatch) {
+			t.Errorf("got error %v, want %v", err, errDigestMismatch)
+		}
+		if _, err := b.ReadAt(make([]byte, 10), 0); !errors.Is(err, errNotVerified) {
+			t.Errorf("got error %v, want %v", err, errNotVerified)
+		}
+	})
+
+	t.Run("Unverified", func(t *testing.T) {
+		var requests atomic.Int32
+		s := blobServer(t, content, false, &requests)
+		b := testBlob(t, s, content)
+
+		// A chunk without a digest in an index must not be used.
+		if err := writeChunk(filepath.Join(b.chunkDir, "0"), content[:1024]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.ReadAt(make([]byte, 10), 0); !errors.Is(err, errNotVerified) {
+			t.Errorf("got error %v, want %v", err, errNotVerified)
+		}
+	})
+
+	t.Run("StoredIndex", func(t *testing.T) {
+		var requests atomic.Int32
+		s := blobServer(t, content, false, &requests)
+		b := testBlob(t, s, content)
+		if err := b.Verify(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// A second verification must use the index in the chunk directory.
+		b2 := newBlob(b.url, b.client, b.digest, b.size, b.chunkDir)
+		b2.chunkSize = b.chunkSize
+		before := requests.Load()
+		if err := b2.Verify(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if after := requests.Load(); after != before {
+			t.Errorf("%d requests made with stored index", after-before)
+		}
+	})
+
+	t.Run("CorruptChunk", func(t *testing.T) {
+		var requests atomic.Int32
+		s := blobServer(t, content, false, &requests)
+		b := testBlob(t, s, content)
+		if err := b.Verify(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// A cached chunk that does not match its digest is fetched again.
+		corrupt := bytes.Clone(content[:1024])
+		corrupt[0]++
+		if err := writeChunk(filepath.Join(b.chunkDir, "0"), corrupt); err != nil {
+			t.Fatal(err)
+		}
+		p := make([]byte, 1024)
+		if _, err := b.ReadAt(p, 0); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, content[:1024]) {
+			t.Errorf("read content does not match")
+		}
+	})
+
+	t.Run("ModifiedRemote", func(t *testing.T) {
+		var requests atomic.Int32
+		s := blobServer(t, content, false, &requests)
+		b := testBlob(t, s, content)
+		if err := b.Verify(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// Content served after verification must match the chunk digests.
+		modified := bytes.Clone(content)
+		modified[2000]++
+		s2 := blobServer(t, modified, false, &requests)
+		b.url = s2.URL
+		if _, err := b.ReadAt(make([]byte, 10), 1024); !errors.Is(err, errDigestMismatch) {
+			t.Errorf("got error %v, want %v", err, errDigestMismatch)
+		}
+	})
+}
+
+func TestBlobConcurrentReads(t *testing.T) {
+	content := testContent(4096)
+
+	var requests atomic.Int32
+	s := blobServer(t, content, false, &requests)
+	b := testBlob(t, s, content)
+	if err := b.Verify(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	before := requests.Load()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := make([]byte, len(content))
+			if _, err := b.ReadAt(p, 0); err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(p, content) {
+				t.Error("read content does not match")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := requests.Load() - before; got > 4 {
+		t.Errorf("%d requests made for 4 chunks", got)
+	}
+}
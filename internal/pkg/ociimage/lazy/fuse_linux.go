@@ -0,0 +1,95 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"syscall"
+
+	"github.com/ccoveille/go-safecast/v2"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// blobRoot is the root directory of a FUSE filesystem exposing layer blobs.
+type blobRoot struct {
+	fs.Inode
+	blobs []*Blob
+}
+
+var _ fs.NodeOnAdder = (*blobRoot)(nil)
+
+// OnAdd populates the root directory with a file for each blob, named by its
+// index.
+func (r *blobRoot) OnAdd(ctx context.Context) {
+	for i, b := range r.blobs {
+		ch := r.NewPersistentInode(ctx, &blobFile{blob: b}, fs.StableAttr{Mode: syscall.S_IFREG})
+		r.AddChild(strconv.Itoa(i), ch, false)
+	}
+}
+
+// blobFile is a read-only file, with content read on demand from a Blob.
+type blobFile struct {
+	fs.Inode
+	blob *Blob
+}
+
+var (
+	_ fs.NodeGetattrer = (*blobFile)(nil)
+	_ fs.NodeOpener    = (*blobFile)(nil)
+	_ fs.NodeReader    = (*blobFile)(nil)
+)
+
+func (f *blobFile) Getattr(_ context.Context, _ fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	size, err := safecast.Convert[uint64](f.blob.Size())
+	if err != nil {
+		return syscall.EIO
+	}
+	out.Mode = 0o444
+	out.Size = size
+	return 0
+}
+
+func (f *blobFile) Open(_ context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	// Blob content never changes, so the kernel page cache can be retained.
+	return nil, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (f *blobFile) Read(_ context.Context, _ fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	n, err := f.blob.ReadAt(dest, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		sylog.Errorf("While reading %s: %v", f.blob.Digest(), err)
+		return nil, syscall.EIO
+	}
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+// Mount serves the layer blobs of the image, as read-only files named by layer
+// index, from a FUSE filesystem mounted at dir. The returned server must be
+// unmounted once the filesystem is no longer required.
+func (i *Image) Mount(dir string) (*fuse.Server, error) {
+	root := &blobRoot{blobs: i.Blobs}
+	server, err := fs.Mount(dir, root, &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName: "singularity-lazy",
+			Name:   "lazy",
+			// Mount directly where permitted, falling back to fusermount.
+			DirectMount: true,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while mounting layer blobs: %w", err)
+	}
+	return server, nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package lazy implements on-demand access to the layers of OCI images held in
+// a registry. Layers are read with HTTP range requests as they are accessed,
+// rather than being pulled in full before a container is started. Only layers
+// in a random access format (squashfs, erofs) can be read on demand.
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sylabs/singularity/v4/internal/pkg/cache"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociimage"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+	"github.com/sylabs/singularity/v4/internal/pkg/remote/credential/ociauth"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// ErrNotSeekable is returned when an image cannot be accessed on demand,
+// because of the format of its layers, or the capabilities of the registry.
+var ErrNotSeekable = errors.New("image layers cannot be read on demand")
+
+// Image is an OCI image in a registry, with layers that are read on demand.
+type Image struct {
+	ggcrv1.Image
+	// Blobs provide access to the content of each layer, in image order.
+	Blobs []*Blob
+	// MediaTypes are the media types of each layer, in image order.
+	MediaTypes []types.MediaType
+}
+
+// Open resolves a docker:// imageURI to an image in a registry, and returns an
+// Image that reads its layers on demand. Fetched chunks are stored in the OCI
+// blob cache of imgCache. If the cache is disabled, they are stored under
+// tmpDir. If any layer is not in a random access format, or the registry does
+// not support range requests, an error wrapping ErrNotSeekable is returned.
+//
+// Each layer is verified against its digest before Open returns, as described
+// for Blob.Verify. Layers that have been verified before, with the same chunk
+// directory, are not fetched again. Otherwise, Open reads every layer in full
+// from the registry, so the first use of an image is not faster than a pull.
+func Open(ctx context.Context, tOpts *ociimage.TransportOptions, imgCache *cache.Handle, imageURI, tmpDir string) (*Image, error) {
+	if tOpts == nil {
+		tOpts = &ociimage.TransportOptions{}
+	}
+
+	srcType, srcRef, err := ociimage.URItoSourceSinkRef(imageURI)
+	if err != nil {
+		return nil, err
+	}
+	if srcType != ociimage.RegistrySourceSink {
+		return nil, fmt.Errorf("%w: only docker:// images are supported", ErrNotSeekable)
+	}
+
+	ref, ok := srcType.Reference(srcRef, tOpts)
+	if !ok {
+		return nil, fmt.Errorf("invalid image reference: %s", srcRef)
+	}
+
+	img, err := srcType.Image(ctx, srcRef, tOpts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("while fetching image manifest: %w", err)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("while obtaining layers: %w", err)
+	}
+
+	client, err := registryClient(ctx, tOpts, ref.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	blobDir, chunkDir := tmpDir, filepath.Join(tmpDir, "chunks")
+	if imgCache != nil && !imgCache.IsDisabled() {
+		blobDir, err = imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+		if err != nil {
+			return nil, err
+		}
+		chunkDir = filepath.Join(blobDir, "chunks")
+	}
+
+	li := Image{Image: img}
+	for i, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			return nil, fmt.Errorf("while checking layer %d: %w", i, err)
+		}
+		if !ocisif.IsSquashfsLayer(mt) && mt != ocisif.ErofsLayerMediaType {
+			return nil, fmt.Errorf("%w: layer %d has media type %q", ErrNotSeekable, i, mt)
+		}
+		digest, err := l.Digest()
+		if err != nil {
+			return nil, err
+		}
+		size, err := l.Size()
+		if err != nil {
+			return nil, err
+		}
+
+		url := fmt.Sprintf("%s://%s/v2/%s/blobs/%s",
+			ref.Context().Scheme(), ref.Context().RegistryStr(), ref.Context().RepositoryStr(), digest)
+		b := newBlob(url, client, digest, size, filepath.Join(chunkDir, digest.Algorithm, digest.Hex))
+
+		// Use a complete copy of the blob, if it is already in the cache.
+		fullPath := filepath.Join(blobDir, "blobs", digest.Algorithm, digest.Hex)
+		if fs.IsFile(fullPath) {
+			sylog.Debugf("Using cached blob %s", fullPath)
+			b.fullPath = fullPath
+		}
+
+		li.Blobs = append(li.Blobs, b)
+		li.MediaTypes = append(li.MediaTypes, mt)
+	}
+
+	// Check that range requests are honored, by fetching the start of the
+	// first layer that is not held in full locally.
+	for _, b := range li.Blobs {
+		if b.fullPath != "" || b.size == 0 {
+			continue
+		}
+		if _, err := b.fetch(0, 1); err != nil {
+			if errors.Is(err, errNoRangeSupport) {
+				return nil, fmt.Errorf("%w: %v", ErrNotSeekable, err)
+			}
+			return nil, err
+		}
+		break
+	}
+
+	for _, b := range li.Blobs {
+		if err := b.Verify(ctx); err != nil {
+			return nil, fmt.Errorf("while verifying layer %s: %w", b.Digest(), err)
+		}
+	}
+
+	return &li, nil
+}
+
+// registryClient returns an HTTP client that authenticates against the
+// registry hosting repo, for pulling blobs.
+func registryClient(ctx context.Context, tOpts *ociimage.TransportOptions, repo name.Repository) (*http.Client, error) {
+	auth, err := ociauth.Authenticator(tOpts.AuthConfig, tOpts.AuthFilePath, repo)
+	if err != nil {
+		return nil, fmt.Errorf("while resolving registry credentials: %w", err)
+	}
+
+	var rt http.RoundTripper = remote.DefaultTransport
+	if tOpts.UserAgent != "" {
+		rt = transport.NewUserAgent(rt, tOpts.UserAgent)
+	}
+	rt, err = transport.NewWithContext(ctx, repo.Registry, auth, rt, []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, fmt.Errorf("while authenticating to registry: %w", err)
+	}
+	return &http.Client{Transport: rt}, nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package lazy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociimage"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+)
+
+func pushTestImage(t *testing.T, host, tag string, mt types.MediaType, content []byte) string {
+	t.Helper()
+
+	img, err := mutate.AppendLayers(empty.Image, static.NewLayer(content, mt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(host+"/test/image:"+tag, name.Insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	return "docker://" + ref.String()
+}
+
+func TestOpen(t *testing.T) {
+	s := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(s.Close)
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := testContent(3 * DefaultChunkSize / 2)
+	squashURI := pushTestImage(t, u.Host, "squashfs", ocisif.SquashfsLayerMediaType, content)
+	tarURI := pushTestImage(t, u.Host, "tar", types.OCILayer, content)
+
+	tOpts := &ociimage.TransportOptions{
+		Insecure: true,
+		Platform: ggcrv1.Platform{OS: "linux", Architecture: "amd64"},
+	}
+
+	t.Run("Squashfs", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		img, err := Open(context.Background(), tOpts, nil, squashURI, tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(img.Blobs) != 1 || len(img.MediaTypes) != 1 {
+			t.Fatalf("got %d blobs, %d media types, want 1", len(img.Blobs), len(img.MediaTypes))
+		}
+		if img.MediaTypes[0] != ocisif.SquashfsLayerMediaType {
+			t.Errorf("got media type %q, want %q", img.MediaTypes[0], ocisif.SquashfsLayerMediaType)
+		}
+
+		got, err := io.ReadAll(io.NewSectionReader(img.Blobs[0], 0, img.Blobs[0].Size()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("blob content does not match")
+		}
+
+		// Chunks must be held under tmpDir when there is no cache.
+		d := img.Blobs[0].Digest()
+		chunks, err := os.ReadDir(filepath.Join(tmpDir, "chunks", d.Algorithm, d.Hex))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Two chunks, and the index of chunk digests.
+		if len(chunks) != 3 {
+			t.Errorf("got %d entries, want 3", len(chunks))
+		}
+	})
+
+	t.Run("Tar", func(t *testing.T) {
+		_, err := Open(context.Background(), tOpts, nil, tarURI, t.TempDir())
+		if !errors.Is(err, ErrNotSeekable) {
+			t.Errorf("got error %v, want %v", err, ErrNotSeekable)
+		}
+	})
+
+	t.Run("NotRegistry", func(t *testing.T) {
+		_, err := Open(context.Background(), tOpts, nil, "oci:"+strings.TrimPrefix(squashURI, "docker://"), t.TempDir())
+		if !errors.Is(err, ErrNotSeekable) {
+			t.Errorf("got error %v, want %v", err, ErrNotSeekable)
+		}
+	})
+}
@@ -0,0 +1,511 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ocisif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ocitmutate "github.com/sylabs/oci-tools/pkg/mutate"
+	ocitsif "github.com/sylabs/oci-tools/pkg/sif"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/crypt"
+	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
+)
+
+// encryptedMediaTypeSuffix is appended to the mediaType of a layer that is
+// held in a LUKS2 volume.
+const encryptedMediaTypeSuffix = "+luks"
+
+var (
+	// ErrImageEncrypted is returned when encrypting an image that already has
+	// encrypted layers.
+	ErrImageEncrypted = errors.New("image is already encrypted")
+	// ErrImageNotEncrypted is returned when changing the key of an image that
+	// has no encrypted layers.
+	ErrImageNotEncrypted = errors.New("image is not encrypted")
+	// ErrKeyFormat is returned when the key material does not match the
+	// format that the layers of an image are encrypted with.
+	ErrKeyFormat = errors.New("key material does not match image encryption")
+)
+
+// EncryptedMediaType returns the mediaType of a layer of mediaType mt, once
+// encrypted.
+func EncryptedMediaType(mt types.MediaType) types.MediaType {
+	return mt + encryptedMediaTypeSuffix
+}
+
+// IsEncryptedLayer returns whether mt is the mediaType of an encrypted layer.
+func IsEncryptedLayer(mt types.MediaType) bool {
+	return strings.HasSuffix(string(mt), encryptedMediaTypeSuffix)
+}
+
+// DecryptedMediaType returns the mediaType of the decrypted content of a layer
+// of mediaType mt.
+func DecryptedMediaType(mt types.MediaType) types.MediaType {
+	return types.MediaType(strings.TrimSuffix(string(mt), encryptedMediaTypeSuffix))
+}
+
+// IsEncrypted returns whether the single image in the OCI-SIF at imagePath has
+// any encrypted layers.
+func IsEncrypted(imagePath string) (bool, error) {
+	fi, err := sif.LoadContainerFromPath(imagePath,
+		sif.OptLoadWithFlag(os.O_RDONLY),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer fi.UnloadContainer()
+
+	img, err := GetSingleImage(fi)
+	if err != nil {
+		return false, fmt.Errorf("while getting image: %w", err)
+	}
+	return HasEncryptedLayers(img)
+}
+
+// HasEncryptedLayers returns whether img has any encrypted layers.
+func HasEncryptedLayers(img v1.Image) (bool, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return false, fmt.Errorf("while getting image layers: %w", err)
+	}
+	for _, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			return false, fmt.Errorf("while getting layer mediatype: %w", err)
+		}
+		if IsEncryptedLayer(mt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hasCryptoMessage returns whether fi holds an encrypted key, which is the
+// case for images encrypted with PEM key material, rather than a passphrase.
+func hasCryptoMessage(fi *sif.FileImage) (bool, error) {
+	ds, err := fi.GetDescriptors(sif.WithDataType(sif.DataCryptoMessage))
+	return len(ds) > 0, err
+}
+
+// addCryptoMessage adds key, encrypted with the PEM key material in ki, to fi.
+func addCryptoMessage(fi *sif.FileImage, ki cryptkey.KeyInfo, key []byte) error {
+	if ki.Format != cryptkey.PEM {
+		return nil
+	}
+	msg, err := cryptkey.EncryptKey(ki, key)
+	if err != nil {
+		return err
+	}
+	return addKeyMessage(fi, msg)
+}
+
+// addKeyMessage adds the encrypted key msg, as returned by cryptkey.EncryptKey,
+// to fi.
+func addKeyMessage(fi *sif.FileImage, msg []byte) error {
+	mt, err := cryptkey.MessageType(msg)
+	if err != nil {
+		return err
+	}
+	di, err := sif.NewDescriptorInput(sif.DataCryptoMessage, bytes.NewReader(msg),
+		sif.OptCryptoMessageMetadata(sif.FormatPEM, mt),
+	)
+	if err != nil {
+		return err
+	}
+	return fi.AddObject(di)
+}
+
+// encryptLayer writes the content of l to a LUKS2 volume at path, encrypted
+// with key, and returns it as a layer of mediaType EncryptedMediaType(mt).
+func encryptLayer(l io.Reader, mt types.MediaType, path string, key []byte) (v1.Layer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := crypt.EncryptLUKS2(f, l, key); err != nil {
+		return nil, fmt.Errorf("while encrypting layer: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return encryptedLayerFromOpener(func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}, EncryptedMediaType(mt))
+}
+
+// encryptedLayerFromOpener returns a layer of mediaType mt, holding the LUKS2
+// volume read from opener.
+func encryptedLayerFromOpener(opener imageOpener, mt types.MediaType) (v1.Layer, error) {
+	rc, err := opener()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	b := make([]byte, hdrBuffSize)
+	if _, err := io.ReadFull(rc, b); err != nil {
+		return nil, fmt.Errorf("while reading encrypted layer header: %w", err)
+	}
+	if !crypt.IsLUKS2(b) {
+		return nil, fmt.Errorf("encrypted layer is not a LUKS2 volume")
+	}
+
+	// Re-open rather than seek, so we can use the SIF GetReader API which
+	// returns an io.Reader only.
+	if err := rc.Close(); err != nil {
+		return nil, err
+	}
+	rc, err = opener()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	digest, size, err := v1.SHA256(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &imageLayer{
+		mediaType: mt,
+		opener:    opener,
+		digest:    digest,
+		diffID:    digest, // no compression - diffID = digest
+		size:      size,
+	}, nil
+}
+
+// EncryptLayers encrypts each squashfs or erofs layer of the single image in
+// the OCI-SIF at imagePath in a LUKS2 volume, with a new key created from the
+// key material in ki. With PEM key material, the key is stored in the image,
+// encrypted for the public key(s) in ki. Temporary files are created in
+// tmpDir, or the location returned by os.TempDir if tmpDir is the empty
+// string.
+//
+// The encrypted image is written to a new OCI-SIF, which replaces the file at
+// imagePath, so that no plaintext layer content remains in it. The plaintext
+// file is unlinked, but its content is not overwritten, so imagePath should be
+// in a temporary location.
+func EncryptLayers(imagePath string, ki cryptkey.KeyInfo, tmpDir string) error {
+	key, err := cryptkey.NewPlaintextKey(ki)
+	if err != nil {
+		return fmt.Errorf("while creating encryption key: %w", err)
+	}
+
+	fi, err := sif.LoadContainerFromPath(imagePath, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return err
+	}
+	defer fi.UnloadContainer()
+
+	img, err := GetSingleImage(fi)
+	if err != nil {
+		return fmt.Errorf("while getting image: %w", err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return fmt.Errorf("while getting image layers: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp(tmpDir, "encrypt-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	ms := make([]ocitmutate.Mutation, 0, len(layers))
+	for i, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			return fmt.Errorf("while getting layer mediatype: %w", err)
+		}
+		if IsEncryptedLayer(mt) {
+			return ErrImageEncrypted
+		}
+		if !IsSquashfsLayer(mt) && mt != ErofsLayerMediaType {
+			return fmt.Errorf("cannot encrypt layer %d with mediaType %q", i, mt)
+		}
+
+		rc, err := l.Compressed()
+		if err != nil {
+			return err
+		}
+		el, err := encryptLayer(rc, mt, filepath.Join(workDir, strconv.Itoa(i)), key)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		ms = append(ms, ocitmutate.SetLayer(i, el))
+	}
+
+	img, err = ocitmutate.Apply(img, ms...)
+	if err != nil {
+		return err
+	}
+	return replaceImageFile(imagePath, img, func(fi *sif.FileImage) error {
+		return addCryptoMessage(fi, ki, key)
+	})
+}
+
+// replaceImageFile writes img to a new OCI-SIF, to which addMessage adds the
+// encrypted key of the image, and renames it over the file at imagePath.
+func replaceImageFile(imagePath string, img v1.Image, addMessage func(*sif.FileImage) error) error {
+	// Write the image to a new file alongside imagePath, so that it can be
+	// renamed into place.
+	tmp, err := os.CreateTemp(filepath.Dir(imagePath), ".encrypt-*.sif")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	ii := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{Add: img})
+	if err := ocitsif.Write(tmpPath, ii, ocitsif.OptWriteWithSpareDescriptorCapacity(spareDescriptorCapacity)); err != nil {
+		return fmt.Errorf("while writing encrypted image: %w", err)
+	}
+
+	efi, err := sif.LoadContainerFromPath(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := addMessage(efi); err != nil {
+		efi.UnloadContainer()
+		return err
+	}
+	if err := efi.UnloadContainer(); err != nil {
+		return err
+	}
+
+	st, err := os.Stat(imagePath)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, st.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, imagePath)
+}
+
+// RotateLayerKey changes the key of each encrypted layer of the single image
+// in the OCI-SIF at imagePath from key to newKey, and replaces the encrypted
+// key of the image with msg, as returned by cryptkey.EncryptKey. The content
+// of the layers is not re-encrypted. As the digests of the encrypted layers
+// change, the image is written to a new OCI-SIF, which replaces the file at
+// imagePath. Temporary files are created in tmpDir, or the location returned
+// by os.TempDir if tmpDir is the empty string.
+func RotateLayerKey(imagePath string, key, newKey, msg []byte, tmpDir string) error {
+	fi, err := sif.LoadContainerFromPath(imagePath, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return err
+	}
+	defer fi.UnloadContainer()
+
+	img, err := GetSingleImage(fi)
+	if err != nil {
+		return fmt.Errorf("while getting image: %w", err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return fmt.Errorf("while getting image layers: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp(tmpDir, "rekey-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	ms := make([]ocitmutate.Mutation, 0, len(layers))
+	for i, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			return fmt.Errorf("while getting layer mediatype: %w", err)
+		}
+		if !IsEncryptedLayer(mt) {
+			continue
+		}
+		path := filepath.Join(workDir, strconv.Itoa(i))
+		if err := rotateLayerKey(l, path, key, newKey); err != nil {
+			return fmt.Errorf("while changing key of layer %d: %w", i, err)
+		}
+		el, err := encryptedLayerFromOpener(func() (io.ReadCloser, error) {
+			return os.Open(path)
+		}, mt)
+		if err != nil {
+			return err
+		}
+		ms = append(ms, ocitmutate.SetLayer(i, el))
+	}
+	if len(ms) == 0 {
+		return ErrImageNotEncrypted
+	}
+
+	img, err = ocitmutate.Apply(img, ms...)
+	if err != nil {
+		return err
+	}
+	return replaceImageFile(imagePath, img, func(fi *sif.FileImage) error {
+		return addKeyMessage(fi, msg)
+	})
+}
+
+// rotateLayerKey copies the LUKS2 volume of encrypted layer l to path, and
+// changes its key from key to newKey.
+func rotateLayerKey(l v1.Layer, path string, key, newKey []byte) error {
+	rc, err := l.Compressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	size, err := io.Copy(f, rc)
+	if err != nil {
+		return err
+	}
+	if err := crypt.AddLUKS2Key(f, 0, size, key, newKey); err != nil {
+		return err
+	}
+	if err := crypt.RemoveLUKS2Key(f, 0, size, key); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// AddEncryptedOverlay adds the provided ext3 overlay file at overlayPath to the
+// OCI-SIF at imagePath, as a new image layer encrypted in a LUKS2 volume. If
+// the image is already encrypted, the overlay is encrypted with the key of the
+// image, which must be accessible with the key material in ki. Otherwise, a new
+// key is created from ki, as by EncryptLayers. Temporary files are created in
+// tmpDir, or the location returned by os.TempDir if tmpDir is the empty string.
+func AddEncryptedOverlay(imagePath, overlayPath string, ki cryptkey.KeyInfo, tmpDir string) error {
+	fi, err := sif.LoadContainerFromPath(imagePath)
+	if err != nil {
+		return err
+	}
+	defer fi.UnloadContainer()
+
+	img, err := GetSingleImage(fi)
+	if err != nil {
+		return fmt.Errorf("while getting image: %w", err)
+	}
+	encrypted, err := HasEncryptedLayers(img)
+	if err != nil {
+		return err
+	}
+	hasMessage, err := hasCryptoMessage(fi)
+	if err != nil {
+		return err
+	}
+
+	var key []byte
+	if encrypted {
+		if hasMessage != (ki.Format == cryptkey.PEM) {
+			if hasMessage {
+				return fmt.Errorf("%w: image is encrypted with a PEM key", ErrKeyFormat)
+			}
+			return fmt.Errorf("%w: image is encrypted with a passphrase", ErrKeyFormat)
+		}
+		if key, err = cryptkey.PlaintextKey(ki, imagePath); err != nil {
+			return fmt.Errorf("while getting image key: %w", err)
+		}
+		if err := checkLayerKey(imagePath, fi, img, key); err != nil {
+			return err
+		}
+	} else if key, err = cryptkey.NewPlaintextKey(ki); err != nil {
+		return fmt.Errorf("while creating encryption key: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp(tmpDir, "encrypt-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	f, err := os.Open(overlayPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ol, err := encryptLayer(f, Ext3LayerMediaType, filepath.Join(workDir, "overlay"), key)
+	if err != nil {
+		return err
+	}
+
+	img, err = mutate.AppendLayers(img, ol)
+	if err != nil {
+		return err
+	}
+
+	ofi, err := ocitsif.FromFileImage(fi)
+	if err != nil {
+		return err
+	}
+	if err := ofi.ReplaceImage(img, nil, ocitsif.OptUpdateTempDir(workDir)); err != nil {
+		return err
+	}
+	if encrypted {
+		return nil
+	}
+	return addCryptoMessage(fi, ki, key)
+}
+
+// checkLayerKey verifies that key unlocks the first encrypted layer of img,
+// held in fi, loaded from imagePath.
+func checkLayerKey(imagePath string, fi *sif.FileImage, img v1.Image, key []byte) error {
+	layers, err := img.Layers()
+	if err != nil {
+		return fmt.Errorf("while getting image layers: %w", err)
+	}
+	for _, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			return fmt.Errorf("while getting layer mediatype: %w", err)
+		}
+		if !IsEncryptedLayer(mt) {
+			continue
+		}
+		d, err := l.Digest()
+		if err != nil {
+			return fmt.Errorf("while getting layer digest: %w", err)
+		}
+		desc, err := fi.GetDescriptor(sif.WithOCIBlobDigest(d))
+		if err != nil {
+			return fmt.Errorf("while getting layer descriptor: %w", err)
+		}
+
+		f, err := os.Open(imagePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := crypt.NewLUKS2Reader(io.NewSectionReader(f, desc.Offset(), desc.Size()), desc.Size(), key); err != nil {
+			return fmt.Errorf("while unlocking encrypted layer: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ocisif
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/erofs"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+// erofsTarOpener returns an opener for the content of the erofs layer l, as a
+// tar stream with OCI whiteouts. On each call of the opener, the layer is
+// copied to a temporary directory in workDir, and mounted with the kernel
+// erofs driver or erofsfuse, until the returned stream is closed.
+func erofsTarOpener(l ggcrv1.Layer, workDir string) (func() (io.ReadCloser, error), error) {
+	return func() (io.ReadCloser, error) {
+		dir, err := os.MkdirTemp(workDir, "erofs-")
+		if err != nil {
+			return nil, err
+		}
+		r := &erofsTarReader{dir: dir, done: make(chan struct{})}
+		if err := r.mount(l); err != nil {
+			r.cleanup()
+			return nil, err
+		}
+
+		pr, pw := io.Pipe()
+		r.PipeReader = pr
+		go func() {
+			defer close(r.done)
+			pw.CloseWithError(dirToTar(r.mnt, pw))
+		}()
+		return r, nil
+	}, nil
+}
+
+// erofsTarReader reads the tar stream written from an erofs layer mounted in
+// dir.
+type erofsTarReader struct {
+	*io.PipeReader
+	dir     string
+	mnt     string
+	mounted bool
+	kernel  bool
+	done    chan struct{}
+}
+
+// mount copies the layer l to a file, as it may not be held in a file that
+// can be mounted, and mounts it.
+func (r *erofsTarReader) mount(l ggcrv1.Layer) error {
+	imgPath := filepath.Join(r.dir, "layer.erofs")
+	if err := writeLayerFile(l, imgPath); err != nil {
+		return err
+	}
+	r.mnt = filepath.Join(r.dir, "mnt")
+	if err := os.Mkdir(r.mnt, 0o700); err != nil {
+		return err
+	}
+	kernel, err := erofs.Mount(context.Background(), 0, 0, imgPath, r.mnt)
+	if err != nil {
+		return fmt.Errorf("while mounting erofs layer: %w", err)
+	}
+	r.mounted, r.kernel = true, kernel
+	return nil
+}
+
+// cleanup unmounts the layer, and removes the temporary directory.
+func (r *erofsTarReader) cleanup() {
+	if r.mounted {
+		if err := erofs.Unmount(context.Background(), r.mnt, r.kernel); err != nil {
+			sylog.Warningf("While unmounting erofs layer: %v", err)
+			return
+		}
+	}
+	if err := os.RemoveAll(r.dir); err != nil {
+		sylog.Warningf("While removing %s: %v", r.dir, err)
+	}
+}
+
+// Close closes the stream, and cleans up once the tar stream is no longer
+// being written.
+func (r *erofsTarReader) Close() error {
+	err := r.PipeReader.Close()
+	<-r.done
+	r.cleanup()
+	return err
+}
+
+// writeLayerFile writes the uncompressed content of l to a new file at path.
+func writeLayerFile(l ggcrv1.Layer, path string) error {
+	rc, err := l.Uncompressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// dirToTar writes the content of the directory tree at root, holding an
+// overlayfs layer, to w as a tar stream. Overlayfs whiteouts, 0:0 character
+// devices, and opaque directories, marked with the trusted.overlay.opaque or
+// user.overlay.opaque xattr, are converted to OCI whiteouts.
+func dirToTar(root string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	// Paths of files that have already been written, by device and inode, so
+	// that further links to them are written as hard links.
+	type inode struct{ dev, ino uint64 }
+	links := map[inode]string{}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("cannot stat %s", name)
+		}
+
+		if fi.Mode()&fs.ModeCharDevice != 0 && st.Rdev == 0 {
+			return tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     path.Join(path.Dir(name), whiteoutPrefix+path.Base(name)),
+				Mode:     0o600,
+				ModTime:  fi.ModTime(),
+			})
+		}
+
+		var link string
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		h, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		h.Name = name
+		if fi.IsDir() {
+			h.Name += "/"
+		}
+		h.Uname, h.Gname = "", ""
+
+		opaque, err := copyXattrs(p, h)
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode().IsRegular() && st.Nlink > 1 {
+			// cast to uint64 as st.Dev is uint32 on MIPS
+			key := inode{uint64(st.Dev), st.Ino}
+			if target, ok := links[key]; ok {
+				h.Typeflag = tar.TypeLink
+				h.Linkname = target
+				h.Size = 0
+				return tw.WriteHeader(h)
+			}
+			links[key] = name
+		}
+
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if opaque {
+			if err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     path.Join(name, whiteoutOpaque),
+				Mode:     0o600,
+				ModTime:  fi.ModTime(),
+			}); err != nil {
+				return err
+			}
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		rf, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer rf.Close()
+		_, err = io.Copy(tw, rf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// copyXattrs adds the xattrs of the file at path to the PAX records of h, and
+// returns whether they mark an opaque directory. Overlayfs xattrs are not
+// copied.
+func copyXattrs(path string, h *tar.Header) (opaque bool, err error) {
+	names, err := listXattrs(path)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range names {
+		value, err := getXattr(path, name)
+		if errors.Is(err, unix.ENODATA) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("while reading xattr %s of %s: %w", name, path, err)
+		}
+		if strings.HasPrefix(name, "trusted.overlay.") || strings.HasPrefix(name, "user.overlay.") {
+			if strings.HasSuffix(name, ".overlay.opaque") && string(value) == "y" {
+				opaque = true
+			}
+			continue
+		}
+		if h.PAXRecords == nil {
+			h.PAXRecords = map[string]string{}
+		}
+		h.PAXRecords["SCHILY.xattr."+name] = string(value)
+		h.Format = tar.FormatPAX
+	}
+	return opaque, nil
+}
+
+// listXattrs returns the names of the xattrs of the file at path.
+func listXattrs(path string) ([]string, error) {
+	size, err := unix.Llistxattr(path, nil)
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Llistxattr(path, buf); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// getXattr returns the value of the xattr name of the file at path.
+func getXattr(path, name string) ([]byte, error) {
+	size, err := unix.Lgetxattr(path, name, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Lgetxattr(path, name, buf); err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
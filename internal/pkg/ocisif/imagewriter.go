@@ -44,6 +44,8 @@ type ImageWriter struct {
 	squashFSLayers bool
 	artifactType   string
 	workDir        string
+	platformImages []ggcrv1.Image
+	layerFormat    string
 }
 
 type ImageWriterOpt func(*ImageWriter) error
@@ -64,6 +66,19 @@ func WithSquashFSLayers(v bool) ImageWriterOpt {
 	}
 }
 
+// WithLayerFormat sets the format that layers are converted to by
+// WithSquashFSLayers - one of the xxxLayerFormat constants. Squashfs is used
+// if unset.
+func WithLayerFormat(f string) ImageWriterOpt {
+	return func(w *ImageWriter) error {
+		if _, err := LayerFormatMediaType(f); err != nil {
+			return err
+		}
+		w.layerFormat = f
+		return nil
+	}
+}
+
 // WithArtifactType says the image should be written with the artifactType field defined in the OCI
 // v1.1.0 specification to v.
 func WithArtifactType(v string) ImageWriterOpt {
@@ -73,6 +88,18 @@ func WithArtifactType(v string) ImageWriterOpt {
 	}
 }
 
+// WithPlatformImages adds images for further platforms, to be written alongside
+// the source image in a multi-platform OCI-SIF. The root index of the OCI-SIF
+// then holds one manifest per platform, with the platform recorded in its
+// descriptor. Each image, including the source image, must declare a distinct
+// platform in its config.
+func WithPlatformImages(imgs ...ggcrv1.Image) ImageWriterOpt {
+	return func(w *ImageWriter) error {
+		w.platformImages = append(w.platformImages, imgs...)
+		return nil
+	}
+}
+
 var (
 	errNoDestProvided    = errors.New("no destination file provided")
 	errNoWorkDirProvided = errors.New("no workDir for intermediate files provided")
@@ -120,68 +147,142 @@ func NewImageWriter(src ggcrv1.Image, dest, workDir string, opts ...ImageWriterO
 // Write will write an image to an OCI-SIF file, applying relevant mutations set
 // via options on the ImageWriter.
 func (w *ImageWriter) Write() error {
-	var err error
-	img := w.src
+	// Layers converted from the same source layer are shared between platform
+	// images, so that they are only written to the OCI-SIF once.
+	converted := map[convertedKey]ggcrv1.Layer{}
 
-	numLayers := len(w.srcManifest.Layers)
-	if numLayers < 1 {
-		return fmt.Errorf("image has no layers")
+	if len(w.platformImages) == 0 {
+		img, err := w.mutate(w.src, w.srcManifest, w.srcDigest, converted)
+		if err != nil {
+			return err
+		}
+		ii := ggcrmutate.AppendManifests(empty.Index, ggcrmutate.IndexAddendum{
+			Add: img,
+		})
+		return ocitsif.Write(w.dest, ii, ocitsif.OptWriteWithSpareDescriptorCapacity(spareDescriptorCapacity))
+	}
+
+	var ii ggcrv1.ImageIndex = empty.Index
+	platforms := map[string]bool{}
+	for _, src := range append([]ggcrv1.Image{w.src}, w.platformImages...) {
+		cf, err := src.ConfigFile()
+		if err != nil {
+			return fmt.Errorf("while retrieving image config: %w", err)
+		}
+		p := cf.Platform()
+		if p == nil {
+			return fmt.Errorf("image does not declare a platform")
+		}
+		if platforms[p.String()] {
+			return fmt.Errorf("more than one image for platform %s", p.String())
+		}
+		platforms[p.String()] = true
+
+		digest, err := src.Digest()
+		if err != nil {
+			return err
+		}
+		mf, err := src.Manifest()
+		if err != nil {
+			return err
+		}
+
+		img, err := w.mutate(src, mf, digest, converted)
+		if err != nil {
+			return fmt.Errorf("while preparing %s image: %w", p.String(), err)
+		}
+		ii = ggcrmutate.AppendManifests(ii, ggcrmutate.IndexAddendum{
+			Add:        img,
+			Descriptor: ggcrv1.Descriptor{Platform: p},
+		})
+	}
+
+	return ocitsif.Write(w.dest, ii, ocitsif.OptWriteWithSpareDescriptorCapacity(spareDescriptorCapacity))
+}
+
+// mutate applies the mutations set via options on the ImageWriter to img.
+func (w *ImageWriter) mutate(img ggcrv1.Image, mf *ggcrv1.Manifest, digest ggcrv1.Hash, converted map[convertedKey]ggcrv1.Layer) (ggcrv1.Image, error) {
+	hasOverlay, canSquash, err := squashable(mf)
+	if err != nil {
+		return nil, err
 	}
-	hasOverlay := w.srcManifest.Layers[numLayers-1].MediaType == Ext3LayerMediaType
-	canSquash := (hasOverlay && numLayers > 2) || (!hasOverlay && numLayers > 1)
 
 	if w.squashLayers && canSquash {
 		if hasOverlay {
 			img, err = squashWithOverlay(img, w.workDir)
 			if err != nil {
-				return fmt.Errorf("while squashing image with overlay: %w", err)
+				return nil, fmt.Errorf("while squashing image with overlay: %w", err)
 			}
 		} else {
 			img, err = ocitmutate.Squash(img)
 			if err != nil {
-				return fmt.Errorf("while squashing image: %w", err)
+				return nil, fmt.Errorf("while squashing image: %w", err)
 			}
 		}
 	}
 
 	if w.squashFSLayers {
-		img, err = imgLayersToSquashfs(img, w.srcDigest, w.workDir)
+		img, err = imgLayersToFormat(img, digest, w.workDir, w.layerFormat, converted)
 		if err != nil {
-			return fmt.Errorf("while converting layers: %w", err)
+			return nil, fmt.Errorf("while converting layers: %w", err)
 		}
 	}
 
 	if w.artifactType != "" {
 		img, err = ocitmutate.Apply(img, ocitmutate.SetArtifactType(w.artifactType))
 		if err != nil {
-			return fmt.Errorf("while setting artifact type: %w", err)
+			return nil, fmt.Errorf("while setting artifact type: %w", err)
 		}
 	}
 
-	ii := ggcrmutate.AppendManifests(empty.Index, ggcrmutate.IndexAddendum{
-		Add: img,
-	})
+	return img, nil
+}
 
-	return ocitsif.Write(w.dest, ii, ocitsif.OptWriteWithSpareDescriptorCapacity(spareDescriptorCapacity))
+// squashable returns whether the image with manifest mf has a writable
+// overlay, and whether it has more than one layer, other than the overlay, that
+// can be squashed.
+func squashable(mf *ggcrv1.Manifest) (hasOverlay, canSquash bool, err error) {
+	numLayers := len(mf.Layers)
+	if numLayers < 1 {
+		return false, false, fmt.Errorf("image has no layers")
+	}
+	hasOverlay = mf.Layers[numLayers-1].MediaType == Ext3LayerMediaType
+	canSquash = (hasOverlay && numLayers > 2) || (!hasOverlay && numLayers > 1)
+	return hasOverlay, canSquash, nil
 }
 
 func squashWithOverlay(base ggcrv1.Image, workDir string) (ggcrv1.Image, error) {
+	img, err := fsLayersToTar(base, workDir)
+	if err != nil {
+		return nil, err
+	}
+	ls, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("while getting layers: %w", err)
+	}
+
+	// Squash all except final ext3 overlay.
+	return ocitmutate.SquashSubset(img, 0, len(ls)-1)
+}
+
+// fsLayersToTar converts any squashfs or erofs layers of base to tar layers, as
+// oci-tools can only squash tar layers at present.
+func fsLayersToTar(base ggcrv1.Image, workDir string) (ggcrv1.Image, error) {
 	ms := []ocitmutate.Mutation{}
 	ls, err := base.Layers()
 	if err != nil {
 		return nil, fmt.Errorf("while getting layers: %w", err)
 	}
 
-	// At present, oci-tools can only squash tar layers.
 	for i, l := range ls {
 		mt, err := l.MediaType()
 		if err != nil {
 			return nil, fmt.Errorf("while getting mediaType: %w", err)
 		}
-		if mt == SquashfsLayerMediaType {
-			opener, err := ocitmutate.TarFromSquashfsLayer(l, ocitmutate.OptTarTempDir(workDir))
+		if IsSquashfsLayer(mt) || mt == ErofsLayerMediaType {
+			opener, err := LayerTarOpener(l, mt, workDir)
 			if err != nil {
-				return nil, fmt.Errorf("while getting tarball from squashfs: %w", err)
+				return nil, err
 			}
 			tarLayer, err := tarball.LayerFromOpener(opener)
 			if err != nil {
@@ -194,12 +295,19 @@ func squashWithOverlay(base ggcrv1.Image, workDir string) (ggcrv1.Image, error)
 	if err != nil {
 		return nil, fmt.Errorf("while converting layers to tar: %w", err)
 	}
+	return img, nil
+}
 
-	// Squash all except final ext3 overlay.
-	return ocitmutate.SquashSubset(img, 0, len(ls)-1)
+// convertedKey identifies the conversion of a source layer to a layer format.
+type convertedKey struct {
+	digest        ggcrv1.Hash
+	format        string
+	skipWhiteouts bool
 }
 
-func imgLayersToSquashfs(img ggcrv1.Image, digest ggcrv1.Hash, workDir string) (sqfsImage ggcrv1.Image, err error) {
+// imgLayersToSquashfs converts the layers of img to SquashFS. Conversions are
+// recorded in converted, and reused for identical source layers.
+func imgLayersToSquashfs(img ggcrv1.Image, digest ggcrv1.Hash, workDir string, converted map[convertedKey]ggcrv1.Layer) (sqfsImage ggcrv1.Image, err error) {
 	ms := []ocitmutate.Mutation{}
 
 	layers, err := img.Layers()
@@ -242,9 +350,28 @@ func imgLayersToSquashfs(img ggcrv1.Image, digest ggcrv1.Hash, workDir string) (
 			continue
 		}
 
-		squashfsLayer, err := ocitmutate.SquashfsLayer(l, workDir, sqOpts...)
+		ld, err := l.Digest()
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrFailedSquashfsConversion, err)
+			return nil, err
+		}
+		key := convertedKey{digest: ld, format: SquashfsLayerFormat, skipWhiteouts: len(sqOpts) > 0}
+		squashfsLayer, ok := converted[key]
+		if !ok {
+			// Layers in another filesystem format are converted through tar.
+			if mt == SquashfsZstdLayerMediaType || mt == ErofsLayerMediaType {
+				opener, err := LayerTarOpener(l, mt, workDir)
+				if err != nil {
+					return nil, err
+				}
+				if l, err = tarball.LayerFromOpener(opener); err != nil {
+					return nil, err
+				}
+			}
+			squashfsLayer, err = ocitmutate.SquashfsLayer(l, workDir, sqOpts...)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrFailedSquashfsConversion, err)
+			}
+			converted[key] = squashfsLayer
 		}
 		ms = append(ms, ocitmutate.SetLayer(i, squashfsLayer))
 	}
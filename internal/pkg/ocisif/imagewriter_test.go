@@ -7,7 +7,9 @@ package ocisif
 import (
 	"archive/tar"
 	"bytes"
+	"errors"
 	"io"
+	"os"
 	"path/filepath"
 	"strconv"
 	"testing"
@@ -16,6 +18,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/sylabs/sif/v2/pkg/sif"
 	useragent "github.com/sylabs/singularity/v4/pkg/util/user-agent"
 )
 
@@ -178,3 +181,130 @@ func TestWrite(t *testing.T) {
 		})
 	}
 }
+
+func platformImage(t *testing.T, img ggcrv1.Image, p ggcrv1.Platform) ggcrv1.Image {
+	cf, err := img.ConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf = cf.DeepCopy()
+	cf.OS, cf.Architecture, cf.Variant = p.OS, p.Architecture, p.Variant
+	img, err = mutate.ConfigFile(img, cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestWritePlatformImages(t *testing.T) {
+	useragent.InitValue("TestWritePlatformImages", "0.0.0")
+	tmpDir := t.TempDir()
+	tImg := testImage(t)
+
+	amd64 := ggcrv1.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := ggcrv1.Platform{OS: "linux", Architecture: "arm64"}
+
+	tests := []struct {
+		name      string
+		srcImg    ggcrv1.Image
+		platImgs  []ggcrv1.Image
+		opts      []ImageWriterOpt
+		wantError bool
+	}{
+		{
+			name:     "TwoPlatforms",
+			srcImg:   platformImage(t, tImg, amd64),
+			platImgs: []ggcrv1.Image{platformImage(t, tImg, arm64)},
+		},
+		{
+			name:     "TwoPlatformsSquashFS",
+			srcImg:   platformImage(t, tImg, amd64),
+			platImgs: []ggcrv1.Image{platformImage(t, tImg, arm64)},
+			opts:     []ImageWriterOpt{WithSquashFSLayers(true)},
+		},
+		{
+			name:      "DuplicatePlatform",
+			srcImg:    platformImage(t, tImg, amd64),
+			platImgs:  []ggcrv1.Image{platformImage(t, tImg, amd64)},
+			wantError: true,
+		},
+		{
+			name:      "NoPlatform",
+			srcImg:    tImg,
+			platImgs:  []ggcrv1.Image{platformImage(t, tImg, arm64)},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(tmpDir, tt.name+".oci.sif")
+			opts := append(tt.opts, WithPlatformImages(tt.platImgs...))
+			w, err := NewImageWriter(tt.srcImg, dest, tmpDir, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = w.Write()
+			if (err != nil) != tt.wantError {
+				t.Fatalf("got error %v, want error %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+
+			fi, err := sif.LoadContainerFromPath(dest, sif.OptLoadWithFlag(os.O_RDONLY))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fi.UnloadContainer()
+
+			for _, p := range []ggcrv1.Platform{amd64, arm64} {
+				img, err := GetPlatformImage(fi, p)
+				if err != nil {
+					t.Fatalf("while getting %s image: %v", p.String(), err)
+				}
+				cf, err := img.ConfigFile()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cf.Architecture != p.Architecture {
+					t.Errorf("got %s image, want %s", cf.Architecture, p.Architecture)
+				}
+			}
+
+			if _, err := GetSingleImage(fi); !errors.Is(err, ErrMultiplePlatforms) {
+				t.Errorf("got error %v, want %v", err, ErrMultiplePlatforms)
+			}
+		})
+	}
+}
+
+func TestGetPlatformImageSingle(t *testing.T) {
+	useragent.InitValue("TestGetPlatformImageSingle", "0.0.0")
+	tmpDir := t.TempDir()
+
+	amd64 := ggcrv1.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := ggcrv1.Platform{OS: "linux", Architecture: "arm64"}
+
+	dest := filepath.Join(tmpDir, "image.oci.sif")
+	w, err := NewImageWriter(platformImage(t, testImage(t), amd64), dest, tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := sif.LoadContainerFromPath(dest, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.UnloadContainer()
+
+	if _, err := GetPlatformImage(fi, amd64); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := GetPlatformImage(fi, arm64); err == nil {
+		t.Errorf("unexpected success for %s image", amd64.String())
+	}
+}
@@ -0,0 +1,519 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ocisif
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	ggcrmutate "github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ocitmutate "github.com/sylabs/oci-tools/pkg/mutate"
+	ocitsif "github.com/sylabs/oci-tools/pkg/sif"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/bin"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	useragent "github.com/sylabs/singularity/v4/pkg/util/user-agent"
+)
+
+const (
+	// SquashfsZstdLayerMediaType is the mediaType of squashfs layers that are
+	// compressed with zstd.
+	SquashfsZstdLayerMediaType types.MediaType = "application/vnd.sylabs.image.layer.v1.squashfs+zstd"
+	// ErofsLayerMediaType is the mediaType of erofs layers.
+	ErofsLayerMediaType types.MediaType = "application/vnd.sylabs.image.layer.v1.erofs"
+)
+
+const (
+	// SquashfsLayerFormat writes layers as gzip compressed squashfs. This is
+	// the default.
+	SquashfsLayerFormat = "squashfs"
+	// SquashfsZstdLayerFormat writes layers as zstd compressed squashfs.
+	SquashfsZstdLayerFormat = "squashfs-zstd"
+	// ErofsLayerFormat writes layers as lz4hc compressed erofs.
+	ErofsLayerFormat = "erofs"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = whiteoutPrefix + whiteoutPrefix + ".opq"
+	opaqueXattr    = "SCHILY.xattr.trusted.overlay.opaque"
+	// userOpaqueXattr is also set on the opaque directories of erofs layers,
+	// as it can be read without CAP_SYS_ADMIN when they are converted to
+	// another format.
+	userOpaqueXattr = "SCHILY.xattr.user.overlay.opaque"
+)
+
+// LayerFormats returns the layer formats that an OCI-SIF can be written with.
+func LayerFormats() []string {
+	return []string{SquashfsLayerFormat, SquashfsZstdLayerFormat, ErofsLayerFormat}
+}
+
+// LayerFormatMediaType returns the layer mediaType for the layer format.
+func LayerFormatMediaType(format string) (types.MediaType, error) {
+	switch format {
+	case "", SquashfsLayerFormat:
+		return SquashfsLayerMediaType, nil
+	case SquashfsZstdLayerFormat:
+		return SquashfsZstdLayerMediaType, nil
+	case ErofsLayerFormat:
+		return ErofsLayerMediaType, nil
+	default:
+		return "", fmt.Errorf("unsupported layer format %q (supported: %s)", format, strings.Join(LayerFormats(), ", "))
+	}
+}
+
+// IsSquashfsLayer returns whether mt is the mediaType of a squashfs layer,
+// with any compression.
+func IsSquashfsLayer(mt types.MediaType) bool {
+	return mt == SquashfsLayerMediaType || mt == SquashfsZstdLayerMediaType
+}
+
+// ConvertLayers rewrites the layers of the image(s) in the OCI-SIF at imagePath
+// in the layer format. An OCI-SIF holding one image per platform has the image
+// for each platform converted. Any writable overlay is left as-is. Cosign
+// signatures and attestations of the original image(s) are removed, as they do
+// not match the converted image(s). Temporary files are created in tmpDir, or
+// the location returned by os.TempDir if tmpDir is the empty string.
+func ConvertLayers(imagePath, format, tmpDir string) error {
+	fi, err := sif.LoadContainerFromPath(imagePath)
+	if err != nil {
+		return err
+	}
+	defer fi.UnloadContainer()
+
+	ofi, err := ocitsif.FromFileImage(fi)
+	if err != nil {
+		return err
+	}
+	ri, err := ofi.RootIndex()
+	if err != nil {
+		return err
+	}
+	im, err := ri.IndexManifest()
+	if err != nil {
+		return err
+	}
+	var digests []ggcrv1.Hash
+	for _, d := range im.Manifests {
+		if SkipCosignMatcher(d) && d.MediaType.IsImage() {
+			digests = append(digests, d.Digest)
+		}
+	}
+	if len(digests) == 0 {
+		return errors.New("no image found in OCI-SIF")
+	}
+
+	workDir, err := os.MkdirTemp(tmpDir, "layer-format-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	converted := map[convertedKey]ggcrv1.Layer{}
+	replaced := map[ggcrv1.Hash]ggcrv1.Image{}
+	for _, digest := range digests {
+		img, err := ri.Image(digest)
+		if err != nil {
+			return fmt.Errorf("while getting image: %w", err)
+		}
+		newImg, err := imgLayersToFormat(img, digest, workDir, format, converted)
+		if err != nil {
+			return err
+		}
+		newDigest, err := newImg.Digest()
+		if err != nil {
+			return err
+		}
+		if newDigest != digest {
+			replaced[digest] = newImg
+		}
+	}
+	if len(replaced) == 0 {
+		sylog.Infof("Layers are already in %s format, no conversion required.", format)
+		return nil
+	}
+
+	removed := 0
+	for digest := range replaced {
+		n, err := removeCosignImages(ofi, digest)
+		if err != nil {
+			return fmt.Errorf("while removing signatures: %w", err)
+		}
+		removed += n
+	}
+
+	if len(digests) == 1 {
+		for _, newImg := range replaced {
+			err = ofi.ReplaceImage(newImg, nil, ocitsif.OptUpdateTempDir(workDir))
+		}
+	} else {
+		err = replacePlatformImages(imagePath, ofi, replaced)
+	}
+	if err != nil {
+		return err
+	}
+
+	if removed > 0 {
+		sylog.Warningf("Removed %d cosign signature / attestation image(s) that do not match the converted image.", removed)
+	}
+	sylog.Warningf("Any signatures of the image no longer match its converted layers.")
+	return nil
+}
+
+// replacePlatformImages writes the root index of ofi, read from the OCI-SIF at
+// imagePath, to a new OCI-SIF, with the images in replaced substituted for
+// the images of the same digest. The platform and annotations of the
+// descriptor of each image are kept. The new OCI-SIF is renamed over the file
+// at imagePath.
+func replacePlatformImages(imagePath string, ofi *ocitsif.OCIFileImage, replaced map[ggcrv1.Hash]ggcrv1.Image) error {
+	ri, err := ofi.RootIndex()
+	if err != nil {
+		return err
+	}
+	im, err := ri.IndexManifest()
+	if err != nil {
+		return err
+	}
+
+	var ii ggcrv1.ImageIndex = empty.Index
+	for _, d := range im.Manifests {
+		var add ggcrmutate.Appendable
+		switch {
+		case replaced[d.Digest] != nil:
+			add = replaced[d.Digest]
+		case d.MediaType.IsImage():
+			add, err = ri.Image(d.Digest)
+		case d.MediaType.IsIndex():
+			add, err = ri.ImageIndex(d.Digest)
+		default:
+			err = fmt.Errorf("unsupported manifest mediaType %q", d.MediaType)
+		}
+		if err != nil {
+			return err
+		}
+		ii = ggcrmutate.AppendManifests(ii, ggcrmutate.IndexAddendum{
+			Add: add,
+			Descriptor: ggcrv1.Descriptor{
+				Platform:    d.Platform,
+				Annotations: d.Annotations,
+			},
+		})
+	}
+
+	// Write the index to a new file alongside imagePath, so that it can be
+	// renamed into place.
+	tmp, err := os.CreateTemp(filepath.Dir(imagePath), ".convert-*.sif")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	if err := ocitsif.Write(tmpPath, ii, ocitsif.OptWriteWithSpareDescriptorCapacity(spareDescriptorCapacity)); err != nil {
+		return fmt.Errorf("while writing converted image: %w", err)
+	}
+	st, err := os.Stat(imagePath)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, st.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, imagePath)
+}
+
+// imgLayersToFormat converts the layers of img to the layer format, other than
+// squashfs, which is handled by imgLayersToSquashfs. Conversions are recorded
+// in converted, and reused for identical source layers.
+func imgLayersToFormat(img ggcrv1.Image, digest ggcrv1.Hash, workDir, format string, converted map[convertedKey]ggcrv1.Layer) (ggcrv1.Image, error) {
+	if format == "" || format == SquashfsLayerFormat {
+		return imgLayersToSquashfs(img, digest, workDir, converted)
+	}
+	targetType, err := LayerFormatMediaType(format)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("while retrieving layers: %w", err)
+	}
+
+	ms := []ocitmutate.Mutation{}
+	for i, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		if mt == targetType {
+			continue
+		}
+		// If the last layer is ext3 then it's an overlay, and we don't convert it.
+		if i == len(layers)-1 && mt == Ext3LayerMediaType {
+			sylog.Infof("Image contains a writable overlay - use 'singularity overlay seal' to convert to r/o.")
+			continue
+		}
+
+		ld, err := l.Digest()
+		if err != nil {
+			return nil, err
+		}
+		key := convertedKey{digest: ld, format: format}
+		newLayer, ok := converted[key]
+		if !ok {
+			opener, err := LayerTarOpener(l, mt, workDir)
+			if err != nil {
+				return nil, err
+			}
+			sylog.Infof("Converting layer %d to %s", i, format)
+			newLayer, err = tarToLayerFormat(opener, format, workDir)
+			if err != nil {
+				return nil, fmt.Errorf("while converting layer %d to %s: %w", i, format, err)
+			}
+			converted[key] = newLayer
+		}
+		ms = append(ms, ocitmutate.SetLayer(i, newLayer))
+	}
+	if len(ms) == 0 {
+		return img, nil
+	}
+
+	ms = append(ms,
+		ocitmutate.SetHistory(ggcrv1.History{
+			Created:    ggcrv1.Time{Time: time.Now()},
+			CreatedBy:  useragent.Value(),
+			Comment:    "oci-sif created from " + digest.Hex,
+			EmptyLayer: false,
+		}))
+
+	return ocitmutate.Apply(img, ms...)
+}
+
+// LayerTarOpener returns an opener for the content of layer l, of mediaType
+// mt, as a tar stream with OCI whiteouts.
+func LayerTarOpener(l ggcrv1.Layer, mt types.MediaType, workDir string) (func() (io.ReadCloser, error), error) {
+	switch {
+	case IsSquashfsLayer(mt):
+		opener, err := ocitmutate.TarFromSquashfsLayer(l, ocitmutate.OptTarTempDir(workDir))
+		if err != nil {
+			return nil, fmt.Errorf("while getting tarball from squashfs: %w", err)
+		}
+		return opener, nil
+	case mt == ErofsLayerMediaType:
+		return erofsTarOpener(l, workDir)
+	case mt.IsLayer():
+		return l.Uncompressed, nil
+	default:
+		return nil, fmt.Errorf("unsupported layer mediaType %q", mt)
+	}
+}
+
+// tarToLayerFormat creates a filesystem image, in the layer format, from the
+// tar stream returned by opener. OCI whiteouts in the tar stream are converted
+// to overlayfs whiteouts.
+func tarToLayerFormat(opener func() (io.ReadCloser, error), format, workDir string) (ggcrv1.Layer, error) {
+	mt, err := LayerFormatMediaType(format)
+	if err != nil {
+		return nil, err
+	}
+
+	// A first pass finds opaque directories, as a directory may precede its
+	// opaque whiteout in the tar stream.
+	rc, err := opener()
+	if err != nil {
+		return nil, err
+	}
+	opaque, err := opaqueDirs(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp(workDir, "layer-")
+	if err != nil {
+		return nil, err
+	}
+	dest := filepath.Join(dir, "layer."+format)
+
+	var cmd *exec.Cmd
+	switch format {
+	case SquashfsZstdLayerFormat:
+		sqfstar, err := bin.FindBin("sqfstar")
+		if err != nil {
+			return nil, err
+		}
+		cmd = exec.Command(sqfstar, "-comp", "zstd", "-quiet", dest)
+	case ErofsLayerFormat:
+		mkfs, err := bin.FindBin("mkfs.erofs")
+		if err != nil {
+			return nil, err
+		}
+		cmd = exec.Command(mkfs, "--quiet", "--tar=f", "-zlz4hc", dest)
+	default:
+		return nil, fmt.Errorf("unsupported layer format %q", format)
+	}
+
+	pr, pw := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		rc, err := opener()
+		if err != nil {
+			pw.CloseWithError(err)
+			errCh <- err
+			return
+		}
+		defer rc.Close()
+		err = overlayWhiteouts(rc, pw, opaque, format == ErofsLayerFormat)
+		pw.CloseWithError(err)
+		errCh <- err
+	}()
+
+	cmd.Stdin = pr
+	out, err := cmd.CombinedOutput()
+	pr.Close()
+	if werr := <-errCh; werr != nil && err == nil {
+		return nil, fmt.Errorf("while reading layer: %w", werr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", filepath.Base(cmd.Path), err, out)
+	}
+
+	return formatLayerFromFile(dest, mt)
+}
+
+// opaqueDirs returns the directories of the tar stream r that contain an
+// opaque whiteout.
+func opaqueDirs(r io.Reader) (map[string]bool, error) {
+	opaque := map[string]bool{}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return opaque, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := cleanTarName(h.Name)
+		if path.Base(name) == whiteoutOpaque {
+			opaque[path.Dir(name)] = true
+		}
+	}
+}
+
+// overlayWhiteouts copies the tar stream r to w, converting OCI whiteouts to
+// overlayfs whiteouts. Whiteout files become 0:0 character devices, and the
+// directories in opaque are marked with the trusted.overlay.opaque xattr, and
+// also the user.overlay.opaque xattr if userXattr is set.
+func overlayWhiteouts(r io.Reader, w io.Writer, opaque map[string]bool, userXattr bool) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	seen := map[string]bool{}
+
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := cleanTarName(h.Name)
+		base := path.Base(name)
+		switch {
+		case base == whiteoutOpaque:
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			h = &tar.Header{
+				Typeflag: tar.TypeChar,
+				Name:     path.Join(path.Dir(name), strings.TrimPrefix(base, whiteoutPrefix)),
+				ModTime:  h.ModTime,
+			}
+		case h.Typeflag == tar.TypeDir && opaque[name]:
+			setOpaque(h, userXattr)
+			seen[name] = true
+		}
+
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+
+	// Opaque directories without their own entry in the tar stream.
+	for dir := range opaque {
+		if seen[dir] || dir == "." {
+			continue
+		}
+		h := &tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     dir + "/",
+			Mode:     0o755,
+		}
+		setOpaque(h, userXattr)
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func setOpaque(h *tar.Header, userXattr bool) {
+	if h.PAXRecords == nil {
+		h.PAXRecords = map[string]string{}
+	}
+	h.PAXRecords[opaqueXattr] = "y"
+	if userXattr {
+		h.PAXRecords[userOpaqueXattr] = "y"
+	}
+	h.Format = tar.FormatPAX
+}
+
+// cleanTarName returns name without leading "/" or "./", or trailing "/".
+func cleanTarName(name string) string {
+	return path.Clean(strings.TrimPrefix(path.Clean("/"+name), "/"))
+}
+
+// formatLayerFromFile returns a layer, of mediaType mt, with the filesystem
+// image at path as its uncompressed content.
+func formatLayerFromFile(path string, mt types.MediaType) (ggcrv1.Layer, error) {
+	opener := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	rc, err := opener()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	digest, size, err := ggcrv1.SHA256(rc)
+	if err != nil {
+		return nil, err
+	}
+	return &imageLayer{
+		mediaType: mt,
+		opener:    opener,
+		digest:    digest,
+		diffID:    digest, // no compression - diffID = digest
+		size:      size,
+	}, nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ocisif
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"golang.org/x/sys/unix"
+)
+
+func TestLayerFormatMediaType(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    types.MediaType
+		wantErr bool
+	}{
+		{format: "", want: SquashfsLayerMediaType},
+		{format: SquashfsLayerFormat, want: SquashfsLayerMediaType},
+		{format: SquashfsZstdLayerFormat, want: SquashfsZstdLayerMediaType},
+		{format: ErofsLayerFormat, want: ErofsLayerMediaType},
+		{format: "tar", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := LayerFormatMediaType(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	opaque   bool
+}
+
+func writeTestTar(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		h := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0o644}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readTestTar(t *testing.T, b []byte) []tarEntry {
+	t.Helper()
+
+	var entries []tarEntry
+	tr := tar.NewReader(bytes.NewReader(b))
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, tarEntry{
+			name:     h.Name,
+			typeflag: h.Typeflag,
+			opaque:   h.PAXRecords[opaqueXattr] == "y",
+		})
+	}
+}
+
+func TestOverlayWhiteouts(t *testing.T) {
+	in := writeTestTar(t, []tarEntry{
+		{name: "./etc/", typeflag: tar.TypeDir},
+		{name: "./etc/.wh..wh..opq", typeflag: tar.TypeReg},
+		{name: "./etc/hosts", typeflag: tar.TypeReg},
+		{name: "usr/.wh.bin", typeflag: tar.TypeReg},
+		{name: "var/lib/.wh..wh..opq", typeflag: tar.TypeReg},
+	})
+
+	opaque, err := opaqueDirs(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"etc": true, "var/lib": true}; !reflect.DeepEqual(opaque, want) {
+		t.Errorf("got opaque dirs %v, want %v", opaque, want)
+	}
+
+	var out bytes.Buffer
+	if err := overlayWhiteouts(bytes.NewReader(in), &out, opaque, false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []tarEntry{
+		{name: "./etc/", typeflag: tar.TypeDir, opaque: true},
+		{name: "./etc/hosts", typeflag: tar.TypeReg},
+		{name: "usr/bin", typeflag: tar.TypeChar},
+		{name: "var/lib/", typeflag: tar.TypeDir, opaque: true},
+	}
+	if got := readTestTar(t, out.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDirToTar(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "file"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(root, "file"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("file", filepath.Join(root, "symlink")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "opaque"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Setxattr(filepath.Join(root, "opaque"), "user.overlay.opaque", []byte("y"), 0); err != nil {
+		t.Skipf("user xattrs are not supported: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := dirToTar(root, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []tarEntry{
+		{name: "file", typeflag: tar.TypeReg},
+		{name: "link", typeflag: tar.TypeLink},
+		{name: "opaque/", typeflag: tar.TypeDir},
+		{name: "opaque/.wh..wh..opq", typeflag: tar.TypeReg},
+		{name: "symlink", typeflag: tar.TypeSymlink},
+	}
+	if got := readTestTar(t, b.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2024-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2024-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -27,48 +27,76 @@ import (
 
 var Ext3LayerMediaType types.MediaType = "application/vnd.sylabs.image.layer.v1.ext3"
 
-// HasOverlay returns whether the OCI-SIF at imgPath has an ext3 writable final
-// layer - an 'overlay'. If present, the offset of the overlay data in the
-// OCI-SIF file is also returned.
-func HasOverlay(imagePath string) (bool, int64, error) {
+// Overlay describes an ext3 writable final layer of an OCI-SIF - an 'overlay'.
+type Overlay struct {
+	// Offset is the offset of the overlay data in the OCI-SIF file.
+	Offset int64
+	// Size is the size of the overlay data in the OCI-SIF file.
+	Size int64
+	// Encrypted is set if the overlay is held in a LUKS2 volume.
+	Encrypted bool
+}
+
+// GetOverlay returns the ext3 writable final layer of the OCI-SIF at
+// imagePath, or nil if it has no overlay.
+func GetOverlay(imagePath string) (*Overlay, error) {
 	fi, err := sif.LoadContainerFromPath(imagePath,
 		sif.OptLoadWithFlag(os.O_RDONLY),
 	)
 	if err != nil {
-		return false, 0, err
+		return nil, err
 	}
 	defer fi.UnloadContainer()
 
 	img, err := GetSingleImage(fi)
+	// An overlay cannot be added to an OCI-SIF holding one image per platform.
+	if errors.Is(err, ErrMultiplePlatforms) {
+		return nil, nil
+	}
 	if err != nil {
-		return false, 0, fmt.Errorf("while getting image: %w", err)
+		return nil, fmt.Errorf("while getting image: %w", err)
 	}
 	layers, err := img.Layers()
 	if err != nil {
-		return false, 0, fmt.Errorf("while getting image layers: %w", err)
+		return nil, fmt.Errorf("while getting image layers: %w", err)
 	}
 	if len(layers) < 1 {
-		return false, 0, fmt.Errorf("image has no layers")
+		return nil, fmt.Errorf("image has no layers")
 	}
 	mt, err := layers[len(layers)-1].MediaType()
 	if err != nil {
-		return false, 0, fmt.Errorf("while getting layer mediatype: %w", err)
+		return nil, fmt.Errorf("while getting layer mediatype: %w", err)
 	}
 	// Not an overlay as last layer
-	if mt != Ext3LayerMediaType {
-		return false, 0, nil
+	if DecryptedMediaType(mt) != Ext3LayerMediaType {
+		return nil, nil
 	}
 
 	// Overlay as last layer, get offset
 	ld, err := layers[len(layers)-1].Digest()
 	if err != nil {
-		return false, 0, fmt.Errorf("while getting layer digest: %w", err)
+		return nil, fmt.Errorf("while getting layer digest: %w", err)
 	}
 	desc, err := fi.GetDescriptor(sif.WithOCIBlobDigest(ld))
 	if err != nil {
-		return false, 0, fmt.Errorf("while getting layer descriptor: %w", err)
+		return nil, fmt.Errorf("while getting layer descriptor: %w", err)
 	}
-	return true, desc.Offset(), nil
+	return &Overlay{
+		Offset:    desc.Offset(),
+		Size:      desc.Size(),
+		Encrypted: IsEncryptedLayer(mt),
+	}, nil
+}
+
+// HasOverlay returns whether the OCI-SIF at imgPath has an ext3 writable final
+// layer - an 'overlay'. If present, the offset of the overlay data in the
+// OCI-SIF file is also returned. The overlay may be encrypted, see GetOverlay.
+func HasOverlay(imagePath string) (bool, int64, error) {
+	o, err := GetOverlay(imagePath)
+	if err != nil || o == nil {
+		return false, 0, err
+	}
+	return true, o.Offset, nil
 }
 
 // AddOverlay adds the provided ext3 overlay file at overlayPath to the OCI-SIF
@@ -128,7 +156,7 @@ func SyncOverlay(imagePath string) error {
 	if err != nil {
 		return fmt.Errorf("while getting layer mediatype: %w", err)
 	}
-	if mt != Ext3LayerMediaType {
+	if DecryptedMediaType(mt) != Ext3LayerMediaType {
 		return fmt.Errorf("image does not contain a writable overlay")
 	}
 
@@ -145,7 +173,12 @@ func SyncOverlay(imagePath string) error {
 	o := func() (io.ReadCloser, error) {
 		return io.NopCloser(desc.GetReader()), nil
 	}
-	newLayer, err := imageLayerFromOpener(o, image.EXT3)
+	var newLayer v1.Layer
+	if IsEncryptedLayer(mt) {
+		newLayer, err = encryptedLayerFromOpener(o, mt)
+	} else {
+		newLayer, err = imageLayerFromOpener(o, image.EXT3)
+	}
 	if err != nil {
 		return err
 	}
@@ -211,6 +244,9 @@ func SealOverlay(imagePath, tmpDir string) error {
 	if err != nil {
 		return fmt.Errorf("while getting layer mediatype: %w", err)
 	}
+	if mt == EncryptedMediaType(Ext3LayerMediaType) {
+		return fmt.Errorf("cannot seal an encrypted overlay")
+	}
 	if mt != Ext3LayerMediaType {
 		return fmt.Errorf("image does not contain a writable overlay")
 	}
@@ -282,7 +318,8 @@ func SealOverlay(imagePath, tmpDir string) error {
 type imageOpener func() (io.ReadCloser, error)
 
 type imageLayer struct {
-	imageType int // image.EXT3 and image.SQUASHFS are currently implemented
+	imageType int             // image.EXT3 and image.SQUASHFS are currently implemented
+	mediaType types.MediaType // overrides the mediaType implied by imageType
 	opener    imageOpener
 	digest    v1.Hash
 	diffID    v1.Hash
@@ -331,6 +368,9 @@ func (l *imageLayer) Size() (int64, error) {
 
 // MediaType returns the media type of the Layer.
 func (l *imageLayer) MediaType() (types.MediaType, error) {
+	if l.mediaType != "" {
+		return l.mediaType, nil
+	}
 	switch l.imageType {
 	case image.EXT3:
 		return Ext3LayerMediaType, nil
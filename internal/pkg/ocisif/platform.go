@@ -0,0 +1,121 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ocisif
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// PlatformImagePath returns the path of the OCI-SIF image for platform p, in a
+// multi-platform build that was requested to write path. The platform is
+// inserted before the extension, so that image.oci.sif becomes, e.g.,
+// image_linux_arm64.oci.sif or image_linux_arm_v7.oci.sif.
+func PlatformImagePath(path string, p v1.Platform) string {
+	base, ext := splitExt(path)
+	parts := []string{base, p.OS, p.Architecture}
+	if p.Variant != "" {
+		parts = append(parts, p.Variant)
+	}
+	return strings.Join(parts, "_") + ext
+}
+
+// PlatformImages returns the paths of the files named as the per-platform
+// OCI-SIF images written in place of path by a multi-platform build. As
+// unrelated files may be named alike, it should only be used where the
+// per-platform images were requested explicitly.
+func PlatformImages(path string) ([]string, error) {
+	base, ext := splitExt(path)
+	pattern := escapeGlob(base) + "_*_*" + escapeGlob(ext)
+	return filepath.Glob(pattern)
+}
+
+// SelectPlatformImage returns path if it exists. Otherwise, if path was
+// written as per-platform images by a multi-platform build, it returns the
+// image named after platform p, or after p without its variant, which must
+// declare a platform that satisfies p. If there is no such image, path is
+// returned.
+func SelectPlatformImage(path string, p v1.Platform) (string, error) {
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return path, nil
+	}
+
+	platforms := []v1.Platform{p}
+	if p.Variant != "" {
+		np := p
+		np.Variant = ""
+		platforms = append(platforms, np)
+	}
+
+	for _, cp := range platforms {
+		c := PlatformImagePath(path, cp)
+		if _, err := os.Stat(c); err != nil {
+			continue
+		}
+
+		ip, err := imagePlatform(c)
+		if err != nil {
+			return "", fmt.Errorf("while reading platform of %s: %w", c, err)
+		}
+		if !ip.Satisfies(cp) {
+			return "", fmt.Errorf("%s holds an image for platform %s, not %s", c, ip.String(), cp.String())
+		}
+		sylog.Verbosef("Using %s, matching platform %s", c, p.String())
+		return c, nil
+	}
+
+	return path, nil
+}
+
+// imagePlatform returns the platform declared by the single image in the
+// OCI-SIF at path.
+func imagePlatform(path string) (*v1.Platform, error) {
+	fi, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, err
+	}
+	defer fi.UnloadContainer()
+
+	img, err := GetSingleImage(fi)
+	if err != nil {
+		return nil, err
+	}
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if cf.Platform() == nil {
+		return nil, fmt.Errorf("image does not declare a platform")
+	}
+	return cf.Platform(), nil
+}
+
+// splitExt splits path into its base and extension, treating .oci.sif as a
+// single extension, and defaulting the extension to .sif.
+func splitExt(path string) (base, ext string) {
+	switch {
+	case strings.HasSuffix(path, ".oci.sif"):
+		ext = ".oci.sif"
+	case filepath.Ext(path) != "":
+		ext = filepath.Ext(path)
+	default:
+		return path, ".sif"
+	}
+	return strings.TrimSuffix(path, ext), ext
+}
+
+func escapeGlob(s string) string {
+	r := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`)
+	return r.Replace(s)
+}
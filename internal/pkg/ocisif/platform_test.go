@@ -0,0 +1,145 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ocisif
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestPlatformImagePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		platform ggcrv1.Platform
+		want     string
+	}{
+		{
+			name:     "SIF",
+			path:     "/tmp/image.sif",
+			platform: ggcrv1.Platform{OS: "linux", Architecture: "amd64"},
+			want:     "/tmp/image_linux_amd64.sif",
+		},
+		{
+			name:     "OCISIF",
+			path:     "image.oci.sif",
+			platform: ggcrv1.Platform{OS: "linux", Architecture: "arm64"},
+			want:     "image_linux_arm64.oci.sif",
+		},
+		{
+			name:     "Variant",
+			path:     "image.sif",
+			platform: ggcrv1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+			want:     "image_linux_arm_v7.sif",
+		},
+		{
+			name:     "NoExtension",
+			path:     "image",
+			platform: ggcrv1.Platform{OS: "linux", Architecture: "arm64"},
+			want:     "image_linux_arm64.sif",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlatformImagePath(tt.path, tt.platform); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectPlatformImage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "image.sif")
+
+	platforms := []ggcrv1.Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm64"},
+	}
+	for _, p := range platforms {
+		im, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf, err := im.ConfigFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cf.OS, cf.Architecture = p.OS, p.Architecture
+		if im, err = mutate.ConfigFile(im, cf); err != nil {
+			t.Fatal(err)
+		}
+		iw, err := NewImageWriter(im, PlatformImagePath(path, p), t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := iw.Write(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	images, err := PlatformImages(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != len(platforms) {
+		t.Errorf("got %d per-platform images, want %d", len(images), len(platforms))
+	}
+
+	for _, p := range platforms {
+		got, err := SelectPlatformImage(path, p)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := PlatformImagePath(path, p); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	// An image named after a variant-less platform is used for a variant.
+	arm64v8 := ggcrv1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}
+	got, err := SelectPlatformImage(path, arm64v8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := PlatformImagePath(path, platforms[1]); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Without an image for the platform, the requested path is returned.
+	ppc64le := ggcrv1.Platform{OS: "linux", Architecture: "ppc64le"}
+	got, err = SelectPlatformImage(path, ppc64le)
+	if err != nil || got != path {
+		t.Errorf("got %q, %v, want %q", got, err, path)
+	}
+
+	// An image named after a platform it does not hold is refused.
+	b, err := os.ReadFile(PlatformImagePath(path, platforms[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(PlatformImagePath(path, ppc64le), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SelectPlatformImage(path, ppc64le); err == nil {
+		t.Errorf("unexpected success for mismatched platform")
+	}
+
+	// An existing image is used as is.
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = SelectPlatformImage(path, platforms[0])
+	if err != nil || got != path {
+		t.Errorf("got %q, %v, want %q", got, err, path)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ocisif
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	imagespec "github.com/opencontainers/image-spec/specs-go/v1"
+	ocitmutate "github.com/sylabs/oci-tools/pkg/mutate"
+	ocitsif "github.com/sylabs/oci-tools/pkg/sif"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// SquashImage squashes the layers of the single image in the OCI-SIF at
+// imagePath into a single layer, in the format of the original layers, or
+// squashfs if they are of mixed formats. Any writable overlay is retained as a
+// separate layer, and the image config is preserved. Cosign signatures and
+// attestations of the original image are removed, as they do not match the
+// squashed image. Temporary files are created in tmpDir, or the location
+// returned by os.TempDir if tmpDir is the empty string.
+func SquashImage(imagePath, tmpDir string) error {
+	fi, err := sif.LoadContainerFromPath(imagePath)
+	if err != nil {
+		return err
+	}
+	defer fi.UnloadContainer()
+
+	ofi, err := ocitsif.FromFileImage(fi)
+	if err != nil {
+		return err
+	}
+
+	img, err := ofi.Image(SkipCosignMatcher)
+	if err != nil {
+		return fmt.Errorf("while getting image: %w", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return err
+	}
+	mf, err := img.Manifest()
+	if err != nil {
+		return err
+	}
+	hasOverlay, canSquash, err := squashable(mf)
+	if err != nil {
+		return err
+	}
+	if !canSquash {
+		sylog.Infof("Image has a single layer, no squash required.")
+		return nil
+	}
+
+	format := manifestLayerFormat(mf)
+
+	workDir, err := os.MkdirTemp(tmpDir, "squash-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	sylog.Infof("Squashing %d layers to a %s layer", len(mf.Layers), format)
+	if hasOverlay {
+		img, err = squashWithOverlay(img, workDir)
+	} else {
+		img, err = fsLayersToTar(img, workDir)
+		if err == nil {
+			img, err = ocitmutate.Squash(img)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("while squashing image: %w", err)
+	}
+	img, err = imgLayersToFormat(img, digest, workDir, format, map[convertedKey]ggcrv1.Layer{})
+	if err != nil {
+		return fmt.Errorf("while converting layers: %w", err)
+	}
+
+	removed, err := removeCosignImages(ofi, digest)
+	if err != nil {
+		return fmt.Errorf("while removing signatures: %w", err)
+	}
+	if err := ofi.ReplaceImage(img, nil, ocitsif.OptUpdateTempDir(workDir)); err != nil {
+		return err
+	}
+	if removed > 0 {
+		sylog.Warningf("Removed %d cosign signature / attestation image(s) that do not match the squashed image.", removed)
+	}
+	return nil
+}
+
+// manifestLayerFormat returns the layer format of the layers of mf, other than
+// a writable overlay, or SquashfsLayerFormat if they are of mixed, or other,
+// formats.
+func manifestLayerFormat(mf *ggcrv1.Manifest) string {
+	format := ""
+	for i, l := range mf.Layers {
+		if i == len(mf.Layers)-1 && l.MediaType == Ext3LayerMediaType {
+			break
+		}
+		var f string
+		switch l.MediaType {
+		case SquashfsZstdLayerMediaType:
+			f = SquashfsZstdLayerFormat
+		case ErofsLayerMediaType:
+			f = ErofsLayerFormat
+		default:
+			f = SquashfsLayerFormat
+		}
+		if format != "" && f != format {
+			return SquashfsLayerFormat
+		}
+		format = f
+	}
+	if format == "" {
+		return SquashfsLayerFormat
+	}
+	return format
+}
+
+// removeCosignImages removes the cosign signature and attestation images
+// associated with the image digest from ofi, returning the number of images
+// removed.
+func removeCosignImages(ofi *ocitsif.OCIFileImage, digest ggcrv1.Hash) (int, error) {
+	tag := digest.Algorithm + "-" + digest.Hex
+	matcher := func(d ggcrv1.Descriptor) bool {
+		return !SkipCosignMatcher(d) && strings.Contains(d.Annotations[imagespec.AnnotationRefName], tag)
+	}
+
+	ri, err := ofi.RootIndex()
+	if err != nil {
+		return 0, err
+	}
+	im, err := ri.IndexManifest()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, d := range im.Manifests {
+		if matcher(d) {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, ofi.RemoveManifests(matcher)
+}
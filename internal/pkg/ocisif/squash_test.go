@@ -0,0 +1,121 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ocisif
+
+import (
+	"testing"
+
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+func TestSquashImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		layers      int
+		overlay     bool
+		wantLayers  int
+		wantOverlay bool
+	}{
+		{name: "SingleLayer", layers: 1, wantLayers: 1},
+		{name: "MultiLayer", layers: 3, wantLayers: 1},
+		{name: "MultiLayerOverlay", layers: 3, overlay: true, wantLayers: 2, wantOverlay: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imgFile := randomImage(t, 1024, tt.layers)
+			if tt.overlay {
+				if err := AddOverlay(imgFile, extfsOverlayPath); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := SquashImage(imgFile, t.TempDir()); err != nil {
+				t.Fatalf("Unexpected error squashing image: %v", err)
+			}
+
+			hasOverlay, _, err := HasOverlay(imgFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hasOverlay != tt.wantOverlay {
+				t.Errorf("Overlay present is %v, expected %v", hasOverlay, tt.wantOverlay)
+			}
+
+			fi, err := sif.LoadContainerFromPath(imgFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fi.UnloadContainer()
+			img, err := GetSingleImage(fi)
+			if err != nil {
+				t.Fatal(err)
+			}
+			layers, err := img.Layers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(layers) != tt.wantLayers {
+				t.Errorf("Expected %d layers, found %d", tt.wantLayers, len(layers))
+			}
+			mt, err := layers[0].MediaType()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mt != SquashfsLayerMediaType {
+				t.Errorf("Layer 0 is %s. Expected %s.", mt, SquashfsLayerMediaType)
+			}
+		})
+	}
+}
+
+func TestManifestLayerFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		mediaTypes []types.MediaType
+		want       string
+	}{
+		{
+			name:       "Squashfs",
+			mediaTypes: []types.MediaType{SquashfsLayerMediaType, SquashfsLayerMediaType},
+			want:       SquashfsLayerFormat,
+		},
+		{
+			name:       "SquashfsZstd",
+			mediaTypes: []types.MediaType{SquashfsZstdLayerMediaType, SquashfsZstdLayerMediaType},
+			want:       SquashfsZstdLayerFormat,
+		},
+		{
+			name:       "ErofsOverlay",
+			mediaTypes: []types.MediaType{ErofsLayerMediaType, ErofsLayerMediaType, Ext3LayerMediaType},
+			want:       ErofsLayerFormat,
+		},
+		{
+			name:       "Mixed",
+			mediaTypes: []types.MediaType{ErofsLayerMediaType, SquashfsZstdLayerMediaType},
+			want:       SquashfsLayerFormat,
+		},
+		{
+			name:       "Tar",
+			mediaTypes: []types.MediaType{types.OCILayer, types.OCILayer},
+			want:       SquashfsLayerFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mf := &ggcrv1.Manifest{}
+			for _, mt := range tt.mediaTypes {
+				mf.Layers = append(mf.Layers, ggcrv1.Descriptor{MediaType: mt})
+			}
+			if got := manifestLayerFormat(mf); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2024, Sylabs Inc. All rights reserved.
+// Copyright (c) 2024-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,6 +6,9 @@
 package ocisif
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"strings"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -13,17 +16,93 @@ import (
 	ocitsif "github.com/sylabs/oci-tools/pkg/sif"
 	"github.com/sylabs/oci-tools/pkg/sourcesink"
 	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociplatform"
 )
 
+// ErrMultiplePlatforms is returned when an OCI-SIF holding one image per
+// platform is used where a single image is required.
+var ErrMultiplePlatforms = errors.New("OCI-SIF holds one image per platform")
+
 // GetSingleImage returns a v1.Image from an OCI-SIF, that must contain a single
-// image. It ignores any cosign images that may be present in the OCI-SIF.
+// image. It ignores any cosign images that may be present in the OCI-SIF. If
+// the OCI-SIF contains one image per platform, an error wrapping
+// ErrMultiplePlatforms is returned, so that the caller can select a platform.
 func GetSingleImage(fi *sif.FileImage) (v1.Image, error) {
 	ofi, err := ocitsif.FromFileImage(fi)
 	if err != nil {
 		return nil, err
 	}
 
-	return ofi.Image(SkipCosignMatcher)
+	img, err := ofi.Image(SkipCosignMatcher)
+	if err != nil {
+		if ps, perr := imagePlatforms(ofi); perr == nil && len(ps) > 1 {
+			return nil, fmt.Errorf("%w (%s), but a single image is required", ErrMultiplePlatforms, strings.Join(ps, ", "))
+		}
+		return nil, err
+	}
+	return img, nil
+}
+
+// CheckSingleImage returns an error if the OCI-SIF at path does not contain a
+// single image, as with GetSingleImage.
+func CheckSingleImage(path string) error {
+	fi, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return fmt.Errorf("while loading SIF: %w", err)
+	}
+	defer fi.UnloadContainer()
+
+	_, err = GetSingleImage(fi)
+	return err
+}
+
+// GetPlatformImage returns a v1.Image from an OCI-SIF, that must satisfy
+// platform. If the OCI-SIF contains a single image, it is returned as with
+// GetSingleImage. If it contains one image per platform, with the platform
+// recorded in the descriptor of each image, the image that satisfies platform
+// is returned. Any cosign images that may be present in the OCI-SIF are
+// ignored.
+func GetPlatformImage(fi *sif.FileImage, platform v1.Platform) (v1.Image, error) {
+	ofi, err := ocitsif.FromFileImage(fi)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := ofi.Image(SkipCosignMatcher)
+	if err != nil {
+		img, err = ofi.Image(func(d v1.Descriptor) bool {
+			return SkipCosignMatcher(d) && d.Platform != nil && d.Platform.Satisfies(platform)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("while selecting image for platform %s: %w", platform.String(), err)
+		}
+	}
+
+	if err := ociplatform.CheckImagePlatform(platform, img); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// imagePlatforms returns the platforms recorded in the root index of an
+// OCI-SIF, for images other than cosign images.
+func imagePlatforms(ofi *ocitsif.OCIFileImage) ([]string, error) {
+	ri, err := ofi.RootIndex()
+	if err != nil {
+		return nil, err
+	}
+	im, err := ri.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var ps []string
+	for _, d := range im.Manifests {
+		if SkipCosignMatcher(d) && d.Platform != nil {
+			ps = append(ps, d.Platform.String())
+		}
+	}
+	return ps, nil
 }
 
 // SkipCosignMatcher matches all images / indices, except those that are related
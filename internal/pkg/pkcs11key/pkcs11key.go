@@ -0,0 +1,113 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package pkcs11key implements signers backed by private keys held in PKCS#11
+// tokens, such as HSMs or smart cards. Keys are identified by PKCS#11 URIs,
+// and never leave the token.
+package pkcs11key
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+var errKeyNotFound = errors.New("private key not found in PKCS#11 token")
+
+// Key is a private key held in a PKCS#11 token.
+type Key struct {
+	ctx    *crypto11.Context
+	signer crypto.Signer
+}
+
+// Open opens a session to the token holding the private key identified by u,
+// logging in with the PIN of u. The key must be closed when no longer needed.
+func Open(u *URI) (*Key, error) {
+	ctx, err := crypto11.Configure(&crypto11.Config{
+		Path:        u.ModulePath,
+		TokenLabel:  u.Token,
+		TokenSerial: u.Serial,
+		SlotNumber:  u.Slot,
+		Pin:         u.PIN,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while opening PKCS#11 token: %w", err)
+	}
+
+	var label []byte
+	if u.Object != "" {
+		label = []byte(u.Object)
+	}
+
+	s, err := ctx.FindKeyPair(u.ID, label)
+	if err != nil {
+		ctx.Close()
+		return nil, fmt.Errorf("while finding PKCS#11 key: %w", err)
+	}
+	if s == nil {
+		ctx.Close()
+		return nil, errKeyNotFound
+	}
+
+	return &Key{ctx: ctx, signer: s}, nil
+}
+
+// Close closes the session to the token holding k.
+func (k *Key) Close() error {
+	return k.ctx.Close()
+}
+
+// Public returns the public key corresponding to k.
+func (k *Key) Public() crypto.PublicKey {
+	return k.signer.Public()
+}
+
+// Signer returns a signer that produces signatures with k.
+func (k *Key) Signer() signature.Signer {
+	return NewSigner(k.signer)
+}
+
+// Signer produces signatures of messages with a crypto.Signer, whose private
+// key may not be accessible. ECDSA and RSA signatures are of the SHA-256
+// digest of messages, ASN.1 encoded and using PKCS #1 v1.5 respectively, and
+// Ed25519 signatures are of messages themselves, as expected by the verifiers
+// returned by signature.LoadVerifier. The type satisfies the signature.Signer
+// interface.
+type Signer struct {
+	s crypto.Signer
+}
+
+// NewSigner returns a Signer that produces signatures with s.
+func NewSigner(s crypto.Signer) *Signer {
+	return &Signer{s: s}
+}
+
+// PublicKey returns the public key used to verify signatures produced by s.
+func (s *Signer) PublicKey(...signature.PublicKeyOption) (crypto.PublicKey, error) {
+	return s.s.Public(), nil
+}
+
+// SignMessage signs message.
+func (s *Signer) SignMessage(message io.Reader, _ ...signature.SignOption) ([]byte, error) {
+	if _, ok := s.s.Public().(ed25519.PublicKey); ok {
+		b, err := io.ReadAll(message)
+		if err != nil {
+			return nil, err
+		}
+		return s.s.Sign(rand.Reader, b, crypto.Hash(0))
+	}
+
+	h := crypto.SHA256.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, err
+	}
+	return s.s.Sign(rand.Reader, h.Sum(nil), crypto.SHA256)
+}
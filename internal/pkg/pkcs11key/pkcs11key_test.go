@@ -0,0 +1,172 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package pkcs11key
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+func TestSigner(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{"ECDSA", ecKey},
+		{"RSA", rsaKey},
+		{"ED25519", edKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSignVerify(t, NewSigner(tt.key))
+		})
+	}
+}
+
+// testSignVerify checks that signatures produced by s verify with the verifier
+// loaded from its public key.
+func testSignVerify(t *testing.T, s signature.Signer) {
+	t.Helper()
+
+	pub, err := s.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("message")
+	sig, err := s.SignMessage(bytes.NewReader(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(message)); err != nil {
+		t.Errorf("failed to verify signature: %v", err)
+	}
+	if err := v.VerifySignature(bytes.NewReader(sig), bytes.NewReader([]byte("other"))); err == nil {
+		t.Errorf("unexpected success verifying signature of other message")
+	}
+}
+
+// softHSMModules are the usual locations of the SoftHSM PKCS#11 module.
+var softHSMModules = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib64/pkcs11/libsofthsm2.so",
+	"/usr/lib64/softhsm/libsofthsm.so",
+}
+
+// initSoftHSM initializes a SoftHSM token with the specified label and PIN,
+// in a temporary directory, and returns the path of the SoftHSM module. The
+// test is skipped if SoftHSM is not available.
+func initSoftHSM(t *testing.T, label, pin string) string {
+	t.Helper()
+
+	var module string
+	for _, m := range softHSMModules {
+		if _, err := os.Stat(m); err == nil {
+			module = m
+			break
+		}
+	}
+	util, err := exec.LookPath("softhsm2-util")
+	if module == "" || err != nil {
+		t.Skip("SoftHSM not available")
+	}
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "softhsm2.conf")
+	tokens := filepath.Join(dir, "tokens")
+	if err := os.Mkdir(tokens, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(conf, fmt.Appendf(nil, "directories.tokendir = %s\n", tokens), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	cmd := exec.Command(util, "--init-token", "--free", "--label", label, "--pin", pin, "--so-pin", pin)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to initialize token: %v: %s", err, out)
+	}
+	return module
+}
+
+func TestOpen(t *testing.T) {
+	module := initSoftHSM(t, "test", "1234")
+
+	ctx, err := crypto11.Configure(&crypto11.Config{Path: module, TokenLabel: "test", Pin: "1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctx.GenerateECDSAKeyPairWithLabel([]byte{1}, []byte("ecdsa"), elliptic.P256()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctx.GenerateRSAKeyPairWithLabel([]byte{2}, []byte("rsa"), 2048); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Close()
+
+	tests := []struct {
+		name    string
+		uri     string
+		wantErr bool
+	}{
+		{name: "ECDSA", uri: "pkcs11:token=test;object=ecdsa"},
+		{name: "RSA", uri: "pkcs11:token=test;id=%02"},
+		{name: "NotFound", uri: "pkcs11:token=test;object=missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := ParseURI(tt.uri + "?module-path=" + module + "&pin-value=1234")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			k, err := Open(u)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer k.Close()
+
+			testSignVerify(t, k.Signer())
+		})
+	}
+}
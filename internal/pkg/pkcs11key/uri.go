@@ -0,0 +1,141 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package pkcs11key
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// uriScheme is the scheme of PKCS#11 URIs.
+const uriScheme = "pkcs11:"
+
+var (
+	errBadScheme    = errors.New("PKCS#11 URI must start with 'pkcs11:'")
+	errNoModule     = errors.New("PKCS#11 URI must specify a module-path")
+	errNoToken      = errors.New("PKCS#11 URI must specify exactly one of token, serial, slot-id")
+	errNoObject     = errors.New("PKCS#11 URI must specify an object or id")
+	errBothPINs     = errors.New("PKCS#11 URI must not specify both pin-value and pin-source")
+	errBadAttribute = errors.New("invalid PKCS#11 URI attribute")
+)
+
+// URI identifies a private key held in a PKCS#11 token, as described by
+// RFC 7512. Only the attributes needed to locate a signing key are supported:
+//
+//	ModulePath: path of the PKCS#11 module (module-path query attribute)
+//	Token: label of the token (token path attribute)
+//	Serial: serial number of the token (serial path attribute)
+//	Slot: slot containing the token (slot-id path attribute)
+//	Object: label of the key (object path attribute)
+//	ID: identifier of the key (id path attribute)
+//	PIN: user PIN of the token (pin-value, or content of pin-source file)
+type URI struct {
+	ModulePath string
+	Token      string
+	Serial     string
+	Slot       *int
+	Object     string
+	ID         []byte
+	PIN        string
+}
+
+// parseAttributes returns the attributes of s, separated by sep.
+func parseAttributes(s, sep string) (map[string]string, error) {
+	attrs := map[string]string{}
+	if s == "" {
+		return attrs, nil
+	}
+
+	for _, attr := range strings.Split(s, sep) {
+		k, v, ok := strings.Cut(attr, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%w: %q", errBadAttribute, attr)
+		}
+		v, err := url.PathUnescape(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", errBadAttribute, attr, err)
+		}
+		if _, ok := attrs[k]; ok {
+			return nil, fmt.Errorf("%w: %q specified more than once", errBadAttribute, k)
+		}
+		attrs[k] = v
+	}
+	return attrs, nil
+}
+
+// ParseURI parses the PKCS#11 URI s. If the URI holds a pin-source
+// attribute, the PIN is read from the file it refers to.
+func ParseURI(s string) (*URI, error) {
+	rest, ok := strings.CutPrefix(s, uriScheme)
+	if !ok {
+		return nil, errBadScheme
+	}
+	path, query, _ := strings.Cut(rest, "?")
+
+	pattrs, err := parseAttributes(path, ";")
+	if err != nil {
+		return nil, err
+	}
+	qattrs, err := parseAttributes(query, "&")
+	if err != nil {
+		return nil, err
+	}
+
+	u := URI{
+		ModulePath: qattrs["module-path"],
+		Token:      pattrs["token"],
+		Serial:     pattrs["serial"],
+		Object:     pattrs["object"],
+		PIN:        qattrs["pin-value"],
+	}
+
+	if v, ok := pattrs["slot-id"]; ok {
+		slot, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: slot-id: %v", errBadAttribute, err)
+		}
+		u.Slot = &slot
+	}
+
+	if v, ok := pattrs["id"]; ok {
+		u.ID = []byte(v)
+	}
+
+	if v, ok := qattrs["pin-source"]; ok {
+		if u.PIN != "" {
+			return nil, errBothPINs
+		}
+		b, err := os.ReadFile(strings.TrimPrefix(v, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("while reading pin-source: %w", err)
+		}
+		u.PIN = strings.TrimRight(string(b), "\r\n")
+	}
+
+	if u.ModulePath == "" {
+		return nil, errNoModule
+	}
+
+	n := 0
+	for _, set := range []bool{u.Token != "", u.Serial != "", u.Slot != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errNoToken
+	}
+
+	if u.Object == "" && len(u.ID) == 0 {
+		return nil, errNoObject
+	}
+
+	return &u, nil
+}
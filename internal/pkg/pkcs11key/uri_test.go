@@ -0,0 +1,117 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package pkcs11key
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseURI(t *testing.T) {
+	pinSource := filepath.Join(t.TempDir(), "pin")
+	if err := os.WriteFile(pinSource, []byte("5678\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	slot := 1
+
+	tests := []struct {
+		name    string
+		uri     string
+		wantURI *URI
+		wantErr error
+	}{
+		{
+			name: "TokenObject",
+			uri:  "pkcs11:token=my%20token;object=signing-key?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-value=1234",
+			wantURI: &URI{
+				ModulePath: "/usr/lib/softhsm/libsofthsm2.so",
+				Token:      "my token",
+				Object:     "signing-key",
+				PIN:        "1234",
+			},
+		},
+		{
+			name: "SlotID",
+			uri:  "pkcs11:slot-id=1;id=%01%02?module-path=/lib/p11.so",
+			wantURI: &URI{
+				ModulePath: "/lib/p11.so",
+				Slot:       &slot,
+				ID:         []byte{1, 2},
+			},
+		},
+		{
+			name: "PINSource",
+			uri:  "pkcs11:serial=abc;object=key?module-path=/lib/p11.so&pin-source=file://" + pinSource,
+			wantURI: &URI{
+				ModulePath: "/lib/p11.so",
+				Serial:     "abc",
+				Object:     "key",
+				PIN:        "5678",
+			},
+		},
+		{
+			name:    "BadScheme",
+			uri:     "file:token=t;object=key?module-path=/lib/p11.so",
+			wantErr: errBadScheme,
+		},
+		{
+			name:    "NoModule",
+			uri:     "pkcs11:token=t;object=key",
+			wantErr: errNoModule,
+		},
+		{
+			name:    "NoToken",
+			uri:     "pkcs11:object=key?module-path=/lib/p11.so",
+			wantErr: errNoToken,
+		},
+		{
+			name:    "TwoTokens",
+			uri:     "pkcs11:token=t;slot-id=1;object=key?module-path=/lib/p11.so",
+			wantErr: errNoToken,
+		},
+		{
+			name:    "NoObject",
+			uri:     "pkcs11:token=t?module-path=/lib/p11.so",
+			wantErr: errNoObject,
+		},
+		{
+			name:    "BothPINs",
+			uri:     "pkcs11:token=t;object=key?module-path=/lib/p11.so&pin-value=1&pin-source=" + pinSource,
+			wantErr: errBothPINs,
+		},
+		{
+			name:    "BadSlot",
+			uri:     "pkcs11:slot-id=one;object=key?module-path=/lib/p11.so",
+			wantErr: errBadAttribute,
+		},
+		{
+			name:    "DuplicateAttribute",
+			uri:     "pkcs11:token=t;token=u;object=key?module-path=/lib/p11.so",
+			wantErr: errBadAttribute,
+		},
+		{
+			name:    "MissingPINSource",
+			uri:     "pkcs11:token=t;object=key?module-path=/lib/p11.so&pin-source=/missing",
+			wantErr: os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := ParseURI(tt.uri)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+			if got, want := u, tt.wantURI; !reflect.DeepEqual(got, want) {
+				t.Errorf("got URI %+v, want %+v", got, want)
+			}
+		})
+	}
+}
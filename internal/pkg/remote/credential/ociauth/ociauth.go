@@ -1,4 +1,4 @@
-// Copyright (c) 2023 Sylabs Inc. All rights reserved.
+// Copyright (c) 2023-2026 Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -119,6 +119,18 @@ func AuthOptn(ociAuth *authn.AuthConfig, reqAuthFile string) remote.Option {
 	return remote.WithAuthFromKeychain(&singularityKeychain{reqAuthFile: reqAuthFile})
 }
 
+// Authenticator returns an authenticator for target, using explicit
+// credentials in ociAuth if set, or else those found in reqAuthFile / the
+// default auth file, as for AuthOptn.
+func Authenticator(ociAuth *authn.AuthConfig, reqAuthFile string, target authn.Resource) (authn.Authenticator, error) {
+	if ociAuth != nil {
+		return authn.FromConfig(*ociAuth), nil
+	}
+
+	sk := &singularityKeychain{reqAuthFile: reqAuthFile}
+	return sk.Resolve(target)
+}
+
 func getCredsFile(reqAuthFile string) (*configfile.ConfigFile, error) {
 	authFileToUse := ChooseAuthFile(reqAuthFile)
 	cf, err := ConfigFileFromPath(authFileToUse)
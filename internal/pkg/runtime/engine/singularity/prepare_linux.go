@@ -37,6 +37,7 @@ import (
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/overlay"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/mainthread"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/user"
+	"github.com/sylabs/singularity/v4/internal/pkg/verifypolicy"
 	"github.com/sylabs/singularity/v4/pkg/image"
 	"github.com/sylabs/singularity/v4/pkg/runtime/engine/config"
 	singularityConfig "github.com/sylabs/singularity/v4/pkg/runtime/engine/singularity/config"
@@ -1258,6 +1259,12 @@ func (e *EngineOperations) loadImages(starterConfig *starter.Config) error {
 		}
 	}
 
+	if e.EngineConfig.File.EnforceVerifyPolicy {
+		if err := e.checkVerifyPolicy(img, starterConfig.GetIsSUID()); err != nil {
+			return err
+		}
+	}
+
 	switch e.EngineConfig.GetSessionLayer() {
 	case singularityConfig.OverlayLayer:
 		overlayImages, err := e.loadOverlayImages(starterConfig, writableOverlayPath)
@@ -1408,6 +1415,58 @@ func (e *EngineOperations) handleSIFImage(img *image.Image, rootFs *image.Sectio
 	return writableOverlayPath, nil
 }
 
+// checkVerifyPolicy checks img against the system-wide verification policy.
+func (e *EngineOperations) checkVerifyPolicy(img *image.Image, suid bool) error {
+	var f *os.File
+	var err error
+	if suid {
+		f, err = fs.OpenTrustedFile(buildcfg.VERIFY_POLICY_FILE, 0)
+	} else {
+		f, err = os.OpenFile(buildcfg.VERIFY_POLICY_FILE, os.O_RDONLY|unix.O_NOFOLLOW, 0)
+	}
+	if err != nil {
+		return fmt.Errorf("while loading verification policy: %s", err)
+	}
+	defer f.Close()
+
+	p, err := verifypolicy.LoadFile(f)
+	if err != nil {
+		return fmt.Errorf("while loading verification policy: %s", err)
+	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("while validating verification policy: %s", err)
+	}
+
+	// The image source is set by the user, so it cannot select the scope of a
+	// setuid launch. The scope is chosen from the opened image, rather than
+	// its path, which may since have been replaced.
+	source := e.EngineConfig.GetImageSource()
+	if suid {
+		source = ""
+	}
+	s, err := p.ScopeForFd(img.Fd, source)
+	if err != nil {
+		return err
+	}
+
+	keyring := sypgp.NewHandle(buildcfg.SINGULARITY_CONFDIR, sypgp.GlobalHandleOpt())
+	kr, err := keyring.LoadPubKeyring()
+	if err != nil {
+		return fmt.Errorf("while obtaining keyring for verification policy: %s", err)
+	}
+
+	// Read the image through the file descriptor that was checked, rather than
+	// its path, which may since have been replaced.
+	path := ""
+	if img.Type == image.SIF {
+		path = fmt.Sprintf("/proc/self/fd/%d", img.Fd)
+	}
+	if err := s.Check(context.TODO(), path, kr); err != nil {
+		return fmt.Errorf("image prohibited by verification policy scope %s: %s", s.Name, err)
+	}
+	return nil
+}
+
 // loadOverlayImages loads overlay images.
 func (e *EngineOperations) loadOverlayImages(starterConfig *starter.Config, writableOverlayPath string) ([]image.Image, error) {
 	images := make([]image.Image, 0)
@@ -28,6 +28,9 @@ type Launcher interface {
 type ExecParams struct {
 	// Image is the container image to execute, as a bare path, or <transport>:<path>.
 	Image string
+	// ImageSource is the URI that Image was pulled from by the action command,
+	// if any.
+	ImageSource string
 	// PullTempDir is a temporary directory used to store an image that was
 	// implicitly pulled with cache disabled, and which must be cleaned up on
 	// container exit.
@@ -368,6 +368,10 @@ func (l *Launcher) Exec(ctx context.Context, ep launcher.ExecParams) error {
 		l.engineConfig.SetDeletePullTempDir(ep.PullTempDir)
 	}
 
+	// Record where the image was pulled from, to select a scope of the
+	// verification policy.
+	l.engineConfig.SetImageSource(ep.ImageSource)
+
 	// Call the starter binary using our prepared config.
 	if l.engineConfig.GetInstance() {
 		err = l.starterInstance(ep.Instance, useSuid)
@@ -0,0 +1,59 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sylabs/singularity/v4/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/v4/internal/pkg/syecl"
+	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// checkECL applies the execution control list to the image, if it is a SIF or
+// OCI-SIF image. The ECL cannot be applied to other images, such as docker://
+// images run with --lazy, or unpacked to a sandbox when an OCI-SIF could not be
+// created, so a warning is emitted. As the OCI launcher runs unprivileged, this
+// check is advisory only.
+func checkECL(ctx context.Context, image string) error {
+	ecl, err := syecl.LoadConfig(buildcfg.ECL_FILE)
+	if err != nil {
+		return fmt.Errorf("while loading ECL configuration: %w", err)
+	}
+	if err := ecl.ValidateConfig(); err != nil {
+		return fmt.Errorf("while validating ECL configuration: %w", err)
+	}
+	if !ecl.Activated {
+		return nil
+	}
+
+	path, ok := strings.CutPrefix(image, "oci-sif:")
+	if !ok {
+		path, ok = strings.CutPrefix(image, "sif:")
+	}
+	if !ok {
+		sylog.Warningf("ECL not applied to %s, which is not a SIF or OCI-SIF image", image)
+		return nil
+	}
+
+	keyring := sypgp.NewHandle(buildcfg.SINGULARITY_CONFDIR, sypgp.GlobalHandleOpt())
+	var kr openpgp.KeyRing
+	if kr, err = keyring.LoadPubKeyring(); err != nil {
+		return fmt.Errorf("while obtaining keyring for ECL: %w", err)
+	}
+
+	if ok, err := ecl.ShouldRun(ctx, path, kr); err != nil {
+		return fmt.Errorf("while checking container image with ECL: %w", err)
+	} else if !ok {
+		return errors.New("image prohibited by ECL")
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"syscall"
 
 	"github.com/ccoveille/go-safecast/v2"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/uuid"
 	lccgroups "github.com/opencontainers/cgroups"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -31,6 +32,7 @@ import (
 	"github.com/sylabs/singularity/v4/internal/pkg/cache"
 	"github.com/sylabs/singularity/v4/internal/pkg/cgroups"
 	"github.com/sylabs/singularity/v4/internal/pkg/ociimage"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
 	"github.com/sylabs/singularity/v4/internal/pkg/runtime/launcher"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/env"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
@@ -40,6 +42,7 @@ import (
 	"github.com/sylabs/singularity/v4/internal/pkg/util/shell"
 	imgutil "github.com/sylabs/singularity/v4/pkg/image"
 	"github.com/sylabs/singularity/v4/pkg/ocibundle"
+	lazybundle "github.com/sylabs/singularity/v4/pkg/ocibundle/lazy"
 	"github.com/sylabs/singularity/v4/pkg/ocibundle/native"
 	ocisifbundle "github.com/sylabs/singularity/v4/pkg/ocibundle/ocisif"
 	sifbundle "github.com/sylabs/singularity/v4/pkg/ocibundle/sif"
@@ -207,10 +210,6 @@ func checkOpts(lo launcher.Options) error {
 		badOpt = append(badOpt, "ContainAll")
 	}
 
-	if lo.KeyInfo != nil {
-		badOpt = append(badOpt, "KeyInfo")
-	}
-
 	if lo.SIFFUSE {
 		badOpt = append(badOpt, "SIFFUSE")
 	}
@@ -698,12 +697,22 @@ func (l *Launcher) Exec(ctx context.Context, ep launcher.ExecParams) error {
 	}
 
 	// Handle bare image paths and check image file format.
-	image, err := normalizeImageRef(ep.Image)
+	image, err := normalizeImageRef(ep.Image, l.cfg.TransportOptions.Platform)
 	if err != nil {
 		return err
 	}
 	l.image = image
 
+	if err := checkECL(ctx, image); err != nil {
+		return err
+	}
+
+	if l.singularityConf.EnforceVerifyPolicy {
+		if err := checkVerifyPolicy(ctx, image, ep.ImageSource); err != nil {
+			return err
+		}
+	}
+
 	if err := l.mountSessionTmpfs(); err != nil {
 		return err
 	}
@@ -744,6 +753,16 @@ func (l *Launcher) Exec(ctx context.Context, ep launcher.ExecParams) error {
 		b, err = ocisifbundle.New(
 			ocisifbundle.OptBundlePath(bundleDir),
 			ocisifbundle.OptImageRef(image),
+			ocisifbundle.OptPlatform(l.cfg.TransportOptions.Platform),
+			ocisifbundle.OptKeyInfo(l.cfg.KeyInfo),
+		)
+	case l.cfg.LazyImage != nil && strings.HasPrefix(image, "docker://"):
+		b, err = lazybundle.New(
+			lazybundle.OptBundlePath(bundleDir),
+			lazybundle.OptImageRef(image),
+			lazybundle.OptImage(l.cfg.LazyImage),
+			lazybundle.OptTransportOptions(l.cfg.TransportOptions),
+			lazybundle.OptImgCache(imgCache),
 		)
 	case strings.HasPrefix(image, "sif:"):
 		sylog.Infof("Running a non-OCI SIF in OCI mode. See user guide for compatibility information.")
@@ -955,8 +974,10 @@ func CrunNestCgroup() error {
 }
 
 // normalizeImageRef transforms a bare image path to an oci-sif: or sif: prefixed path,
-// after checking the image is an oci-sif or native (non-oci) sif.
-func normalizeImageRef(imageRef string) (string, error) {
+// after checking the image is an oci-sif or native (non-oci) sif. If the path
+// was written as per-platform OCI-SIF images by a multi-platform build, the
+// image satisfying platform is selected.
+func normalizeImageRef(imageRef string, platform ggcrv1.Platform) (string, error) {
 	imageRef = strings.TrimPrefix(imageRef, "oci-sif:")
 
 	// We can't just look for a `<transport>:<path>` pair as bare filenames can contain colons.
@@ -966,6 +987,11 @@ func normalizeImageRef(imageRef string) (string, error) {
 		return imageRef, nil
 	}
 
+	imageRef, err := ocisif.SelectPlatformImage(imageRef, platform)
+	if err != nil {
+		return "", err
+	}
+
 	// oci-sif or bare image path, check it's an image we can run.
 	img, err := imgutil.Init(imageRef, false)
 	if err != nil {
@@ -1,4 +1,4 @@
-// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// Copyright (c) 2022-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -8,8 +8,10 @@ package oci
 import (
 	"os/user"
 	"reflect"
+	"runtime"
 	"testing"
 
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	lccgroups "github.com/opencontainers/cgroups"
 	"github.com/sylabs/singularity/v4/internal/pkg/cgroups"
 	"github.com/sylabs/singularity/v4/internal/pkg/runtime/launcher"
@@ -198,7 +200,7 @@ func Test_normalizeImageRef(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := normalizeImageRef(tt.imageRef)
+			got, err := normalizeImageRef(tt.imageRef, ggcrv1.Platform{OS: "linux", Architecture: runtime.GOARCH})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("normalizeImageRef() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -8,15 +8,18 @@ package oci
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/ccoveille/go-safecast/v2"
 	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/crypt"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/overlay"
 	"github.com/sylabs/singularity/v4/pkg/image"
 	"github.com/sylabs/singularity/v4/pkg/ocibundle/tools"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
 	"github.com/sylabs/singularity/v4/pkg/util/singularityconf"
 )
 
@@ -62,43 +65,103 @@ func cleanupWritableTmpfs(ctx context.Context, bundleDir, overlayDir string) err
 
 // imageOverlaySet returns an overlay.Set that includes the correct r/o or
 // writable overlay item for an ext3 overlay layer in an OCI-SIF image file, if
-// applicable.
-func (l *Launcher) imageOverlaySet(bundleDir string) (*overlay.Set, error) {
+// applicable. If the overlay is encrypted, its decrypted content is served from
+// a FUSE filesystem in bundleDir, and a function that unmounts it is also
+// returned.
+func (l *Launcher) imageOverlaySet(bundleDir string) (*overlay.Set, func() error, error) {
 	if !strings.HasPrefix(l.image, "oci-sif:") {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	imgAbs, err := filepath.Abs(strings.TrimPrefix(l.image, "oci-sif:"))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	sifOverlay, sifOffset, err := ocisif.HasOverlay(imgAbs)
+	sifOverlay, err := ocisif.GetOverlay(imgAbs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if !sifOverlay {
-		return nil, nil
+	if sifOverlay == nil {
+		return nil, nil, nil
 	}
 
 	item := &overlay.Item{
 		Type:         image.EXT3,
 		Readonly:     !l.cfg.Writable,
 		SourcePath:   imgAbs,
-		SourceOffset: sifOffset,
+		SourceOffset: sifOverlay.Offset,
 	}
 	item.SetParentDir(bundleDir)
 
+	var cleanup func() error
+	if sifOverlay.Encrypted {
+		item.SourcePath, cleanup, err = l.mountEncryptedOverlay(bundleDir, imgAbs, sifOverlay)
+		if err != nil {
+			return nil, nil, err
+		}
+		item.SourceOffset = 0
+	}
+
 	if l.cfg.Writable {
 		return &overlay.Set{
 			WritableOverlay: item,
-		}, nil
+		}, cleanup, nil
 	}
 
 	return &overlay.Set{
 		ReadonlyOverlays: []*overlay.Item{item},
-	}, nil
+	}, cleanup, nil
+}
+
+// mountEncryptedOverlay serves the decrypted content of the encrypted overlay
+// o, in the OCI-SIF at imgPath, from a FUSE filesystem in bundleDir. The path
+// of the decrypted overlay, and a function that unmounts it, are returned.
+func (l *Launcher) mountEncryptedOverlay(bundleDir, imgPath string, o *ocisif.Overlay) (string, func() error, error) {
+	sylog.Debugf("Encrypted overlay detected")
+
+	if l.cfg.KeyInfo == nil {
+		return "", nil, fmt.Errorf("no key was provided, cannot access encrypted overlay")
+	}
+	key, err := cryptkey.PlaintextKey(*l.cfg.KeyInfo, imgPath)
+	if err != nil {
+		sylog.Errorf("Please check you are providing the correct key for decryption")
+		return "", nil, fmt.Errorf("cannot decrypt %s: %w", imgPath, err)
+	}
+
+	flag := os.O_RDONLY
+	if l.cfg.Writable {
+		flag = os.O_RDWR
+	}
+	f, err := os.OpenFile(imgPath, flag, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	v, err := crypt.NewLUKS2ReadWriter(f, o.Offset, o.Size, key)
+	if err != nil {
+		f.Close()
+		return "", nil, fmt.Errorf("while unlocking encrypted overlay: %w", err)
+	}
+
+	dir := filepath.Join(bundleDir, "overlay-luks")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		f.Close()
+		return "", nil, err
+	}
+	sylog.Debugf("Mounting decrypted overlay to %q", dir)
+	server, err := crypt.MountLUKS2(dir, []*crypt.LUKS2Reader{v}, l.cfg.Writable)
+	if err != nil {
+		f.Close()
+		return "", nil, err
+	}
+
+	cleanup := func() error {
+		defer f.Close()
+		sylog.Debugf("Unmounting decrypted overlay from %q", dir)
+		return server.Unmount()
+	}
+	return filepath.Join(dir, "0"), cleanup, nil
 }
 
 // WrapWithOverlays runs a function wrapped with prep / cleanup steps for the
@@ -108,10 +171,17 @@ func (l *Launcher) imageOverlaySet(bundleDir string) (*overlay.Set, error) {
 // Whether an ephemeral overlay is writable from inside the container is
 // controlled by the runtime config.
 func (l *Launcher) WrapWithOverlays(ctx context.Context, f func() error, bundleDir string) error {
-	s, err := l.imageOverlaySet(bundleDir)
+	s, cleanupImageOverlay, err := l.imageOverlaySet(bundleDir)
 	if err != nil {
 		return err
 	}
+	if cleanupImageOverlay != nil {
+		defer func() {
+			if cleanupErr := cleanupImageOverlay(); cleanupErr != nil {
+				sylog.Errorf("While unmounting encrypted overlay: %v", cleanupErr)
+			}
+		}()
+	}
 
 	hasSifOverlay := s != nil
 	hasUserOverlay := len(l.cfg.OverlayPaths) > 0
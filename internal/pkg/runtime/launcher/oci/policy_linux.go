@@ -0,0 +1,55 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+	"github.com/sylabs/singularity/v4/internal/pkg/verifypolicy"
+)
+
+// checkVerifyPolicy checks the normalized image reference image, pulled from
+// source if not empty, against the system-wide verification policy. Images
+// that are not SIF or OCI-SIF files are matched by reference only, and are
+// considered not to be signed. As the OCI launcher runs unprivileged, this
+// check is advisory only.
+func checkVerifyPolicy(ctx context.Context, image, source string) error {
+	p, err := verifypolicy.Load(buildcfg.VERIFY_POLICY_FILE)
+	if err != nil {
+		return fmt.Errorf("while loading verification policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("while validating verification policy: %w", err)
+	}
+
+	path := ""
+	for _, prefix := range []string{"oci-sif:", "sif:"} {
+		if v, ok := strings.CutPrefix(image, prefix); ok {
+			path = v
+		}
+	}
+	if path == "" && source == "" {
+		source = image
+	}
+
+	keyring := sypgp.NewHandle(buildcfg.SINGULARITY_CONFDIR, sypgp.GlobalHandleOpt())
+	kr, err := keyring.LoadPubKeyring()
+	if err != nil {
+		return fmt.Errorf("while obtaining keyring for verification policy: %w", err)
+	}
+
+	if s, err := p.Check(ctx, path, source, kr); err != nil {
+		if s != nil {
+			return fmt.Errorf("image prohibited by verification policy scope %s: %w", s.Name, err)
+		}
+		return err
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"github.com/sylabs/singularity/v4/internal/pkg/ociimage"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociimage/lazy"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/overlay"
 	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
 )
@@ -166,6 +167,11 @@ type Options struct {
 	// NoTmpSandbox prohibits unpacking of images into temporary sandbox dirs.
 	NoTmpSandbox bool
 
+	// LazyImage is a registry image, opened by the CLI, with layers that are
+	// mounted with on-demand fetching, rather than pulled in full. Effective
+	// for the OCI launcher only.
+	LazyImage *lazy.Image
+
 	// Devices contains the list of device mappings (if any), e.g. CDI mappings.
 	Devices []string
 
@@ -565,6 +571,15 @@ func OptNoTmpSandbox(b bool) Option {
 	}
 }
 
+// OptLazyImage mounts the layers of a registry image, that has been opened
+// with lazy.Open, with on-demand fetching.
+func OptLazyImage(img *lazy.Image) Option {
+	return func(lo *Options) error {
+		lo.LazyImage = img
+		return nil
+	}
+}
+
 // OptCacheDisabled indicates caching of images was disabled in the CLI.
 func OptCacheDisabled(b bool) Option {
 	return func(lo *Options) error {
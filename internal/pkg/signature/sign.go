@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2023, Sylabs Inc. All rights reserved.
+// Copyright (c) 2020-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
 // distributed with the sources of this project regarding your rights to use or distribute this
 // software.
@@ -30,7 +30,8 @@ func OptSignWithSigner(ss signature.Signer) SignOpt {
 }
 
 // OptSignEntitySelector specifies f be used to select (and decrypt, if necessary) the entity to
-// use to generate signature(s).
+// use to generate signature(s). Signatures are generated with the newest valid signing subkey of
+// the entity, if there is one.
 func OptSignEntitySelector(f sypgp.EntitySelector) SignOpt {
 	return func(s *signer) error {
 		e, err := sypgp.GetPrivateEntity(f)
@@ -38,7 +39,7 @@ func OptSignEntitySelector(f sypgp.EntitySelector) SignOpt {
 			return err
 		}
 
-		s.opts = append(s.opts, integrity.OptSignWithEntity(e))
+		s.opts = append(s.opts, integrity.OptSignWithEntity(sypgp.SigningEntity(e)))
 
 		return nil
 	}
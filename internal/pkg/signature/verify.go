@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/sigstore/sigstore/pkg/signature"
@@ -29,16 +30,19 @@ import (
 // TODO - error overlaps with ECL - should probably become part of a common errors package at some point.
 var errNotSignedByRequired = errors.New("image not signed by required entities")
 
-type VerifyCallback func(*sif.FileImage, integrity.VerifyResult) bool
+// VerifyCallback is called with the result of each signature validation. If it returns true, the
+// error in the result is ignored.
+type VerifyCallback func(*sif.FileImage, VerifyResult) bool
 
 type verifier struct {
 	certs         []*x509.Certificate
 	intermediates *x509.CertPool
 	roots         *x509.CertPool
-	ocsp          bool
+	revocation    RevocationOptions
 	svs           []signature.Verifier
 	pgp           bool
 	pgpOpts       []client.Option
+	kr            openpgp.KeyRing
 	groupIDs      []uint32
 	objectIDs     []uint32
 	all           bool
@@ -93,12 +97,61 @@ func OptVerifyWithPGP(opts ...client.Option) VerifyOpt {
 	}
 }
 
+// OptVerifyWithKeyRing specifies kr as the source of PGP key material to verify signatures,
+// instead of the local and global public keyrings.
+func OptVerifyWithKeyRing(kr openpgp.KeyRing) VerifyOpt {
+	return func(v *verifier) error {
+		v.kr = kr
+		return nil
+	}
+}
+
 // OptVerifyWithOCSP subjects the x509 certificate chains to online revocation checks,
 // before the leaf certificate is deemed as trusted for validating the signature.
 func OptVerifyWithOCSP() VerifyOpt {
 	return func(v *verifier) error {
-		v.ocsp = true
+		v.revocation.OCSP = true
+
+		return nil
+	}
+}
+
+// OptVerifyWithOCSPResponses subjects the x509 certificate chains to revocation checks against the
+// DER encoded OCSP responses rs, that were pre-fetched or stapled to the image. This may be called
+// multiple times to supply more responses.
+func OptVerifyWithOCSPResponses(rs ...[]byte) VerifyOpt {
+	return func(v *verifier) error {
+		v.revocation.OCSPResponses = append(v.revocation.OCSPResponses, rs...)
+		return nil
+	}
+}
+
+// OptVerifyWithCRLs subjects the x509 certificate chains to revocation checks against crls. This
+// may be called multiple times to supply more CRLs.
+func OptVerifyWithCRLs(crls ...*x509.RevocationList) VerifyOpt {
+	return func(v *verifier) error {
+		v.revocation.CRLs = append(v.revocation.CRLs, crls...)
+		return nil
+	}
+}
+
+// OptVerifyWithCRLFetch subjects the x509 certificate chains to revocation checks against the CRLs
+// published at the distribution points of the certificates.
+func OptVerifyWithCRLFetch() VerifyOpt {
+	return func(v *verifier) error {
+		v.revocation.FetchCRLs = true
+		return nil
+	}
+}
 
+// OptVerifyWithRevocationMaxAge specifies d as the maximum age of the OCSP responses and CRLs used
+// for revocation checks. If d is zero, any response or CRL that is current is used.
+func OptVerifyWithRevocationMaxAge(d time.Duration) VerifyOpt {
+	return func(v *verifier) error {
+		if d < 0 {
+			return fmt.Errorf("invalid revocation max age: %v", d)
+		}
+		v.revocation.MaxAge = d
 		return nil
 	}
 }
@@ -188,18 +241,22 @@ func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.Ve
 		}
 
 		// Verify that the certificate is issued by a trustworthy CA (i.e the certificate chain is not revoked or expired).
-		if v.ocsp {
+		if v.revocation.Enabled() {
 			if len(chain) != 1 {
 				return nil, fmt.Errorf("unhandled OCSP condition, chain length %d != 1", len(chain))
 			}
 
-			ocspErr := OCSPVerify(chain[0]...)
-			if ocspErr != nil {
+			if v.revocation.onlineOnly() {
+				err = OCSPVerify(chain[0]...)
+			} else {
+				err = VerifyRevocation(chain[0], v.revocation)
+			}
+			if err != nil {
 				// TODO: We need to decide whether this should be strict or permissive.
-				return nil, ocspErr
+				return nil, err
 			}
 
-			sylog.Debugf("OCSP validation has passed")
+			sylog.Debugf("Revocation check has passed")
 		}
 
 		// verify the signature by using the certificate.
@@ -217,8 +274,10 @@ func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.Ve
 	}
 
 	// Add PGP key material, if applicable.
-	if v.pgp {
-		var kr openpgp.KeyRing
+	var kr openpgp.KeyRing
+	if v.kr != nil {
+		kr = v.kr
+	} else if v.pgp {
 		if v.pgpOpts != nil {
 			hkr, err := sypgp.NewHybridKeyRing(ctx, v.pgpOpts...)
 			if err != nil {
@@ -240,8 +299,16 @@ func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.Ve
 			return nil, err
 		}
 		kr = sypgp.NewMultiKeyRing(gkr, kr)
+	}
 
-		iopts = append(iopts, integrity.OptVerifyWithKeyRing(kr))
+	// Accept PGP keys retired, superseded or expired after signing.
+	var skr *signingTimeKeyRing
+	if kr != nil {
+		var err error
+		if skr, err = newSigningTimeKeyRing(kr, f); err != nil {
+			return nil, err
+		}
+		iopts = append(iopts, integrity.OptVerifyWithKeyRing(skr))
 	}
 
 	// Add group IDs, if applicable.
@@ -275,7 +342,7 @@ func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.Ve
 	// Add callback, if applicable.
 	if v.cb != nil {
 		fn := func(r integrity.VerifyResult) bool {
-			return v.cb(f, r)
+			return v.cb(f, VerifyResult{r, skr.status(r)})
 		}
 		iopts = append(iopts, integrity.OptVerifyCallback(fn))
 	}
@@ -0,0 +1,212 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package signature
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// maxCRLSize is the maximum size of a CRL fetched from a distribution point.
+const maxCRLSize = 32 << 20
+
+// crlFetchTimeout is the timeout applied when fetching a CRL from a
+// distribution point.
+const crlFetchTimeout = 30 * time.Second
+
+var (
+	errFailedToDecodeCRL = errors.New("failed to decode CRL")
+	errUnreliableCRL     = errors.New("unreliable CRL")
+	errCRLTooOld         = errors.New("CRL older than maximum age")
+	errCRLScope          = errors.New("CRL does not cover certificate")
+)
+
+// oidIssuingDistributionPoint is the OID of the issuing distribution point
+// extension of a CRL.
+var oidIssuingDistributionPoint = asn1.ObjectIdentifier{2, 5, 29, 28}
+
+// LoadCRLs returns the CRLs read from the file at path, which holds either a
+// single DER encoded CRL, or PEM encoded CRLs. A symbolic link at path is not
+// followed.
+func LoadCRLs(path string) ([]*x509.RevocationList, error) {
+	b, err := fs.ReadFileNoFollow(path)
+	if err != nil {
+		return nil, err
+	}
+
+	crls, err := decodeCRLs(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return crls, nil
+}
+
+// decodeCRLs returns the CRLs in b, which holds either a single DER encoded
+// CRL, or PEM encoded CRLs.
+func decodeCRLs(b []byte) ([]*x509.RevocationList, error) {
+	var crls []*x509.RevocationList
+	for rest := bytes.TrimSpace(b); len(rest) > 0; rest = bytes.TrimSpace(rest) {
+		var p *pem.Block
+		if p, rest = pem.Decode(rest); p == nil {
+			break
+		}
+		if p.Type != "X509 CRL" {
+			return nil, fmt.Errorf("%w: unexpected PEM type %q", errFailedToDecodeCRL, p.Type)
+		}
+		crl, err := x509.ParseRevocationList(p.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errFailedToDecodeCRL, err)
+		}
+		crls = append(crls, crl)
+	}
+	if len(crls) > 0 {
+		return crls, nil
+	}
+
+	crl, err := x509.ParseRevocationList(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToDecodeCRL, err)
+	}
+	return []*x509.RevocationList{crl}, nil
+}
+
+// fetchCRLs retrieves the CRLs published at the distribution point url.
+func fetchCRLs(url string) ([]*x509.RevocationList, error) {
+	httpClient := &http.Client{Timeout: crlFetchTimeout}
+	httpResponse, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", httpResponse.Status)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(httpResponse.Body, maxCRLSize))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body. err: %w", err)
+	}
+
+	return decodeCRLs(b)
+}
+
+// checkCRLScope checks that crl covers cert, according to the issuing
+// distribution point extension of crl, if present (RFC 5280, 5.2.5). CRLs
+// that only cover some revocation reasons, and indirect CRLs, are not
+// supported, and cover no certificate.
+func checkCRLScope(crl *x509.RevocationList, cert *x509.Certificate) error {
+	i := slices.IndexFunc(crl.Extensions, func(e pkix.Extension) bool {
+		return e.Id.Equal(oidIssuingDistributionPoint)
+	})
+	if i < 0 {
+		return nil
+	}
+
+	var idp cryptobyte.String
+	in := cryptobyte.String(crl.Extensions[i].Value)
+	if !in.ReadASN1(&idp, cryptobyte_asn1.SEQUENCE) || !in.Empty() {
+		return fmt.Errorf("%w: malformed issuing distribution point", errCRLScope)
+	}
+
+	// distributionPoint [0] DistributionPointName, of which only the
+	// fullName [0] GeneralNames choice is supported.
+	var dp cryptobyte.String
+	var hasDP bool
+	if !idp.ReadOptionalASN1(&dp, &hasDP, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+		return fmt.Errorf("%w: malformed distribution point", errCRLScope)
+	}
+	if hasDP {
+		var names cryptobyte.String
+		if !dp.ReadASN1(&names, cryptobyte_asn1.Tag(0).Constructed().ContextSpecific()) {
+			return fmt.Errorf("%w: unsupported distribution point name", errCRLScope)
+		}
+		matched := false
+		for !names.Empty() {
+			var name cryptobyte.String
+			var tag cryptobyte_asn1.Tag
+			if !names.ReadAnyASN1(&name, &tag) {
+				return fmt.Errorf("%w: malformed distribution point name", errCRLScope)
+			}
+			if tag == cryptobyte_asn1.Tag(6).ContextSpecific() && slices.Contains(cert.CRLDistributionPoints, string(name)) {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%w: distribution point does not match", errCRLScope)
+		}
+	}
+
+	// onlyContainsUserCerts [1], onlyContainsCACerts [2], onlySomeReasons [3],
+	// indirectCRL [4], onlyContainsAttributeCerts [5].
+	for tag := cryptobyte_asn1.Tag(1); tag <= 5; tag++ {
+		var v cryptobyte.String
+		var present bool
+		if !idp.ReadOptionalASN1(&v, &present, tag.ContextSpecific()) {
+			return fmt.Errorf("%w: malformed issuing distribution point", errCRLScope)
+		}
+		if !present || (tag != 3 && !slices.Equal(v, []byte{0xff})) {
+			continue
+		}
+
+		switch tag {
+		case 1:
+			if cert.IsCA {
+				return fmt.Errorf("%w: only user certificates", errCRLScope)
+			}
+		case 2:
+			if !cert.IsCA {
+				return fmt.Errorf("%w: only CA certificates", errCRLScope)
+			}
+		case 3:
+			return fmt.Errorf("%w: only some reasons", errCRLScope)
+		case 4:
+			return fmt.Errorf("%w: indirect CRL", errCRLScope)
+		case 5:
+			return fmt.Errorf("%w: only attribute certificates", errCRLScope)
+		}
+	}
+	if !idp.Empty() {
+		return fmt.Errorf("%w: malformed issuing distribution point", errCRLScope)
+	}
+	return nil
+}
+
+// checkCRL checks that crl, which must have been verified to be signed by the
+// issuer of cert, is current, and does not list cert as revoked.
+func (o RevocationOptions) checkCRL(crl *x509.RevocationList, cert *x509.Certificate) error {
+	now := time.Now()
+	if crl.ThisUpdate.After(now) {
+		return errUnreliableCRL
+	}
+	if !crl.NextUpdate.IsZero() && crl.NextUpdate.Before(now) {
+		return fmt.Errorf("%w: expired at %s", errUnreliableCRL, crl.NextUpdate)
+	}
+	if o.MaxAge > 0 && now.Sub(crl.ThisUpdate) > o.MaxAge {
+		return fmt.Errorf("%w: issued at %s", errCRLTooOld, crl.ThisUpdate)
+	}
+
+	for _, rc := range crl.RevokedCertificateEntries {
+		if rc.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return fmt.Errorf("certificate revoked at '%s'. Revocation reason code: '%d'",
+				rc.RevocationTime, rc.ReasonCode)
+		}
+	}
+	return nil
+}
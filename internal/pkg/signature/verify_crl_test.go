@@ -0,0 +1,193 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package signature
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+func TestLoadCRLs(t *testing.T) {
+	_, intermediate, root := newTestChain(t)
+
+	c1 := newTestCRL(t, intermediate, time.Now())
+	c2 := newTestCRL(t, root, time.Now())
+
+	der := filepath.Join(t.TempDir(), "crl.der")
+	if err := os.WriteFile(der, c1.Raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pemPath := filepath.Join(t.TempDir(), "crl.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: c1.Raw})
+	b = append(b, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: c2.Raw})...)
+	if err := os.WriteFile(pemPath, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	badPEM := filepath.Join(t.TempDir(), "bad.pem")
+	if err := os.WriteFile(badPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c1.Raw}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	symlink := filepath.Join(t.TempDir(), "crl.der")
+	if err := os.Symlink(der, symlink); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCount int
+		wantErr   error
+	}{
+		{name: "DER", path: der, wantCount: 1},
+		{name: "Symlink", path: symlink, wantErr: syscall.ELOOP},
+		{name: "PEM", path: pemPath, wantCount: 2},
+		{name: "BadPEM", path: badPEM, wantErr: errFailedToDecodeCRL},
+		{name: "Missing", path: filepath.Join(t.TempDir(), "missing"), wantErr: os.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crls, err := LoadCRLs(tt.path)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+			if got, want := len(crls), tt.wantCount; got != want {
+				t.Errorf("got %v CRLs, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFetchCRL(t *testing.T) {
+	_, intermediate, _ := newTestChain(t)
+
+	crl := newTestCRL(t, intermediate, time.Now())
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/crl" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(crl.Raw) //nolint:errcheck
+	}))
+	defer s.Close()
+
+	got, err := fetchCRLs(s.URL + "/crl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(got), 1; got != want {
+		t.Fatalf("got %v CRLs, want %v", got, want)
+	}
+	if got, want := got[0].Number, crl.Number; got.Cmp(want) != 0 {
+		t.Errorf("got CRL number %v, want %v", got, want)
+	}
+
+	if _, err := fetchCRLs(s.URL + "/missing"); err == nil {
+		t.Errorf("unexpected success")
+	}
+}
+
+// newTestIDP returns an issuing distribution point CRL extension, with the
+// distribution point uri, if not empty, and the boolean fields with the
+// specified tags set.
+func newTestIDP(t *testing.T, uri string, tags ...cryptobyte_asn1.Tag) pkix.Extension {
+	t.Helper()
+
+	var b cryptobyte.Builder
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		if uri != "" {
+			b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+					b.AddASN1(cryptobyte_asn1.Tag(6).ContextSpecific(), func(b *cryptobyte.Builder) {
+						b.AddBytes([]byte(uri))
+					})
+				})
+			})
+		}
+		for _, tag := range tags {
+			b.AddASN1(tag.ContextSpecific(), func(b *cryptobyte.Builder) {
+				if tag == 3 {
+					b.AddBytes([]byte{0x07, 0x80}) // keyCompromise
+				} else {
+					b.AddUint8(0xff)
+				}
+			})
+		}
+	})
+	v, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkix.Extension{Id: oidIssuingDistributionPoint, Critical: true, Value: v}
+}
+
+func TestCheckCRLScope(t *testing.T) {
+	leaf, intermediate, _ := newTestChain(t)
+
+	user := *leaf.cert
+	user.CRLDistributionPoints = []string{"http://example.com/a.crl"}
+	ca := *intermediate.cert
+
+	tests := []struct {
+		name    string
+		exts    []pkix.Extension
+		cert    *x509.Certificate
+		wantErr error
+	}{
+		{name: "NoIDP", cert: &user},
+		{name: "DistributionPoint", exts: []pkix.Extension{newTestIDP(t, "http://example.com/a.crl")}, cert: &user},
+		{name: "OtherDistributionPoint", exts: []pkix.Extension{newTestIDP(t, "http://example.com/b.crl")}, cert: &user, wantErr: errCRLScope},
+		{name: "NoCertDistributionPoint", exts: []pkix.Extension{newTestIDP(t, "http://example.com/a.crl")}, cert: &ca, wantErr: errCRLScope},
+		{name: "OnlyUserCerts", exts: []pkix.Extension{newTestIDP(t, "", 1)}, cert: &user},
+		{name: "OnlyUserCertsCA", exts: []pkix.Extension{newTestIDP(t, "", 1)}, cert: &ca, wantErr: errCRLScope},
+		{name: "OnlyCACerts", exts: []pkix.Extension{newTestIDP(t, "", 2)}, cert: &ca},
+		{name: "OnlyCACertsUser", exts: []pkix.Extension{newTestIDP(t, "", 2)}, cert: &user, wantErr: errCRLScope},
+		{name: "OnlySomeReasons", exts: []pkix.Extension{newTestIDP(t, "", 3)}, cert: &user, wantErr: errCRLScope},
+		{name: "IndirectCRL", exts: []pkix.Extension{newTestIDP(t, "", 4)}, cert: &user, wantErr: errCRLScope},
+		{name: "OnlyAttributeCerts", exts: []pkix.Extension{newTestIDP(t, "", 5)}, cert: &user, wantErr: errCRLScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := &x509.RevocationList{
+				Number:          big.NewInt(1),
+				ThisUpdate:      time.Now(),
+				NextUpdate:      time.Now().Add(time.Hour),
+				ExtraExtensions: tt.exts,
+			}
+			der, err := x509.CreateRevocationList(rand.Reader, tmpl, intermediate.cert, intermediate.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			crl, err := x509.ParseRevocationList(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := checkCRLScope(crl, tt.cert), tt.wantErr; !errors.Is(got, want) {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+		})
+	}
+}
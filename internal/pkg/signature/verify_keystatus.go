@@ -0,0 +1,272 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package signature
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var errNotPGPSignature = errors.New("not a PGP signature")
+
+// KeyStatus describes the state of the PGP key that produced a signature, relative to the time
+// the signature was created.
+type KeyStatus string
+
+const (
+	// KeyStatusUnknown indicates that the signature was not produced by a known PGP key.
+	KeyStatusUnknown KeyStatus = ""
+	// KeyStatusValid indicates that the key is neither expired nor revoked.
+	KeyStatusValid KeyStatus = "valid"
+	// KeyStatusExpiredAfterSigning indicates that the key expired after the signature was
+	// created. The signature remains valid.
+	KeyStatusExpiredAfterSigning KeyStatus = "expired-after-signing"
+	// KeyStatusRevokedAfterSigning indicates that the key was retired or superseded after the
+	// signature was created. The signature remains valid.
+	KeyStatusRevokedAfterSigning KeyStatus = "revoked-after-signing"
+	// KeyStatusExpired indicates that the key had expired when the signature was created.
+	KeyStatusExpired KeyStatus = "expired"
+	// KeyStatusRevoked indicates that the key had been revoked when the signature was created,
+	// or that the key was revoked as compromised, or for an unspecified reason.
+	KeyStatusRevoked KeyStatus = "revoked"
+)
+
+// VerifyResult describes the results of an individual signature validation.
+type VerifyResult struct {
+	integrity.VerifyResult
+	keyStatus KeyStatus
+}
+
+// KeyStatus returns the status of the PGP key that produced the signature, relative to the time
+// the signature was created. If the signature was not produced by a known PGP key,
+// KeyStatusUnknown is returned.
+func (r VerifyResult) KeyStatus() KeyStatus {
+	return r.keyStatus
+}
+
+// isHardRevocation returns true if sig invalidates all signatures of the revoked key, including
+// those created before the revocation.
+func isHardRevocation(sig *packet.Signature) bool {
+	return sig.RevocationReason == nil ||
+		*sig.RevocationReason == packet.NoReason ||
+		*sig.RevocationReason == packet.KeyCompromised
+}
+
+// keyStatus returns the status of k, for a signature created at time t. Only revocations of the
+// primary key, and of the signing subkey, are considered. A revoked user ID does not revoke the
+// key.
+func keyStatus(k openpgp.Key, t, now time.Time) KeyStatus {
+	e := k.Entity
+	selfSig, _ := e.PrimarySelfSignature()
+	signedBySubkey := k.PublicKey != e.PrimaryKey
+
+	revocations := e.Revocations
+	if signedBySubkey {
+		revocations = append(revocations[:len(revocations):len(revocations)], k.Revocations...)
+	}
+
+	revokedLater := false
+	for _, r := range revocations {
+		if isHardRevocation(r) || !r.CreationTime.After(t) {
+			return KeyStatusRevoked
+		}
+		if !r.CreationTime.After(now) {
+			revokedLater = true
+		}
+	}
+
+	if selfSig == nil {
+		return KeyStatusUnknown
+	}
+
+	expired := e.PrimaryKey.KeyExpired(selfSig, t)
+	expiredLater := e.PrimaryKey.KeyExpired(selfSig, now)
+	if signedBySubkey && k.SelfSignature != nil {
+		expired = expired || k.PublicKey.KeyExpired(k.SelfSignature, t)
+		expiredLater = expiredLater || k.PublicKey.KeyExpired(k.SelfSignature, now)
+	}
+
+	switch {
+	case expired:
+		return KeyStatusExpired
+	case revokedLater:
+		return KeyStatusRevokedAfterSigning
+	case expiredLater:
+		return KeyStatusExpiredAfterSigning
+	default:
+		return KeyStatusValid
+	}
+}
+
+// withoutLifetime returns a copy of sig that does not limit the lifetime of the key it binds.
+func withoutLifetime(sig *packet.Signature) *packet.Signature {
+	if sig == nil {
+		return nil
+	}
+	c := *sig
+	c.KeyLifetimeSecs = nil
+	return &c
+}
+
+// relaxedKey returns a copy of k, in which user ID revocations have been removed, so that a
+// revoked user ID does not invalidate signatures of the key. If lifetime is true, key revocations
+// and key expiry are also removed. This is used to accept signatures created before a key was
+// retired, superseded, or expired. Only the primary identity of the key is kept, so that it is
+// unchanged by the removal of revocations.
+func relaxedKey(k openpgp.Key, lifetime bool) openpgp.Key {
+	e := *k.Entity
+	if lifetime {
+		e.Revocations = nil
+		e.SelfSignature = withoutLifetime(e.SelfSignature)
+	}
+
+	e.Identities = make(map[string]*openpgp.Identity, 1)
+	if _, id := k.Entity.PrimarySelfSignature(); id != nil {
+		c := *id
+		c.Revocations = nil
+		if lifetime {
+			c.SelfSignature = withoutLifetime(id.SelfSignature)
+		}
+		e.Identities[id.Name] = &c
+	}
+
+	if k.PublicKey == e.PrimaryKey {
+		k.SelfSignature, _ = e.PrimarySelfSignature()
+	} else if lifetime {
+		k.SelfSignature = withoutLifetime(k.SelfSignature)
+	}
+	if lifetime {
+		k.Revocations = nil
+	}
+	k.Entity = &e
+
+	return k
+}
+
+// pgpSignature returns the PGP signature packet held by the clear-signed signature object od.
+func pgpSignature(od sif.Descriptor) (*packet.Signature, error) {
+	b, err := od.GetData()
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := clearsign.Decode(b)
+	if block == nil {
+		return nil, errNotPGPSignature
+	}
+
+	p, err := packet.Read(block.ArmoredSignature.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, ok := p.(*packet.Signature)
+	if !ok || sig.IssuerKeyId == nil {
+		return nil, errNotPGPSignature
+	}
+	return sig, nil
+}
+
+// signingTimeKeyRing wraps a KeyRing, so that keys that were retired, superseded, or expired
+// after creating all of their signatures in an image are accepted when verifying that image.
+// Keys revoked as compromised, or for an unspecified reason, are never accepted.
+type signingTimeKeyRing struct {
+	openpgp.KeyRing
+
+	now     time.Time
+	signed  map[uint64]time.Time   // Creation time of the newest signature, by issuer key ID.
+	relaxed map[uint64]openpgp.Key // Original keys, by ID, for keys that have been relaxed.
+}
+
+// newSigningTimeKeyRing returns a KeyRing that wraps kr, and accepts the keys that produced the
+// PGP signatures of f, if they were valid when all such signatures were created.
+func newSigningTimeKeyRing(kr openpgp.KeyRing, f *sif.FileImage) (*signingTimeKeyRing, error) {
+	ods, err := f.GetDescriptors(sif.WithDataType(sif.DataSignature))
+	if err != nil {
+		return nil, err
+	}
+
+	skr := &signingTimeKeyRing{
+		KeyRing: kr,
+		now:     time.Now(),
+		signed:  make(map[uint64]time.Time),
+		relaxed: make(map[uint64]openpgp.Key),
+	}
+
+	for _, od := range ods {
+		sig, err := pgpSignature(od)
+		if err != nil {
+			continue
+		}
+
+		if t, ok := skr.signed[*sig.IssuerKeyId]; !ok || sig.CreationTime.After(t) {
+			skr.signed[*sig.IssuerKeyId] = sig.CreationTime
+		}
+	}
+
+	return skr, nil
+}
+
+// KeysByIdUsage returns the keys of the wrapped KeyRing with the given id that also meet the key
+// usage given by requiredUsage. Keys that were valid when all signatures they produced were
+// created are stripped of their revocations and expiry, and user ID revocations are ignored.
+func (kr *signingTimeKeyRing) KeysByIdUsage(id uint64, requiredUsage byte) []openpgp.Key {
+	keys := kr.KeyRing.KeysByIdUsage(id, requiredUsage)
+
+	t, ok := kr.signed[id]
+	if !ok {
+		return keys
+	}
+
+	for i, k := range keys {
+		switch s := keyStatus(k, t, kr.now); s {
+		case KeyStatusRevokedAfterSigning, KeyStatusExpiredAfterSigning:
+			if _, ok := kr.relaxed[id]; !ok {
+				sylog.Warningf("Accepting signatures made before PGP key %X was no longer valid (key status: %v)", k.Entity.PrimaryKey.Fingerprint, s)
+			}
+			kr.relaxed[id] = k
+			keys[i] = relaxedKey(k, true)
+		case KeyStatusValid:
+			// A revoked user ID does not revoke the key.
+			if _, pi := k.Entity.PrimarySelfSignature(); pi != nil && len(pi.Revocations) > 0 {
+				kr.relaxed[id] = k
+				keys[i] = relaxedKey(k, false)
+			}
+		}
+	}
+	return keys
+}
+
+// status returns the status of the PGP key that produced the signature of r.
+func (kr *signingTimeKeyRing) status(r integrity.VerifyResult) KeyStatus {
+	e := r.Entity()
+	if kr == nil || e == nil {
+		return KeyStatusUnknown
+	}
+
+	sig, err := pgpSignature(r.Signature())
+	if err != nil {
+		return KeyStatusUnknown
+	}
+
+	k, ok := kr.relaxed[*sig.IssuerKeyId]
+	if !ok {
+		keys := openpgp.EntityList{e}.KeysById(*sig.IssuerKeyId)
+		if len(keys) == 0 {
+			return KeyStatusUnknown
+		}
+		k = keys[0]
+	}
+
+	return keyStatus(k, sig.CreationTime, kr.now)
+}
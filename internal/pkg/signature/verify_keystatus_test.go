@@ -0,0 +1,246 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package signature
+
+import (
+	"context"
+	"crypto"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/sypgp"
+)
+
+// configAt returns a PGP configuration that creates keys and signatures at time t.
+func configAt(t time.Time) *packet.Config {
+	return &packet.Config{
+		Algorithm: packet.PubKeyAlgoEdDSA,
+		Time:      func() time.Time { return t },
+	}
+}
+
+// newTestEntity returns a new PGP entity, created at time created. If subkey is true, a signing
+// subkey is added to the entity, after its encryption subkey.
+func newTestEntity(t *testing.T, created time.Time, subkey bool) *openpgp.Entity {
+	t.Helper()
+
+	e, err := openpgp.NewEntity("test", "", "test@example.com", configAt(created))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if subkey {
+		if err := e.AddSigningSubkey(configAt(created)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return e
+}
+
+// setTestLifetime sets the lifetime of the primary key of e to lifetime.
+func setTestLifetime(t *testing.T, e *openpgp.Entity, lifetime time.Duration) {
+	t.Helper()
+
+	secs := uint32(lifetime / time.Second)
+	for _, id := range e.Identities {
+		id.SelfSignature.KeyLifetimeSecs = &secs
+		if err := id.SelfSignature.SignUserId(id.UserId.Id, e.PrimaryKey, e.PrivateKey, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// signTestImage signs a copy of the image at path with e at time signed, and returns the path
+// of the signed image.
+func signTestImage(t *testing.T, path string, e *openpgp.Entity, signed time.Time) string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(t.TempDir(), "image.sif")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := sif.LoadContainerFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.UnloadContainer()
+
+	s, err := integrity.NewSigner(f,
+		integrity.OptSignWithEntity(sypgp.SigningEntity(e)),
+		integrity.OptSignWithTime(func() time.Time { return signed }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Sign(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyKeyStatus(t *testing.T) {
+	now := time.Now()
+	created := now.Add(-10 * time.Hour)
+	signed := now.Add(-5 * time.Hour)
+
+	tests := []struct {
+		name       string
+		subkey     bool
+		edit       func(*testing.T, *openpgp.Entity)
+		wantStatus KeyStatus
+		wantErr    bool
+	}{
+		{
+			name:       "Valid",
+			wantStatus: KeyStatusValid,
+		},
+		{
+			name: "SupersededAfterSigning",
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				if err := e.RevokeKey(packet.KeySuperseded, "", configAt(now.Add(-time.Hour))); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: KeyStatusRevokedAfterSigning,
+		},
+		{
+			name: "RetiredBeforeSigning",
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				if err := e.RevokeKey(packet.KeyRetired, "", configAt(signed.Add(-time.Hour))); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: KeyStatusRevoked,
+			wantErr:    true,
+		},
+		{
+			name: "CompromisedAfterSigning",
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				if err := e.RevokeKey(packet.KeyCompromised, "", configAt(now.Add(-time.Hour))); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: KeyStatusRevoked,
+			wantErr:    true,
+		},
+		{
+			name: "UnspecifiedAfterSigning",
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				if err := e.RevokeKey(packet.NoReason, "", configAt(now.Add(-time.Hour))); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: KeyStatusRevoked,
+			wantErr:    true,
+		},
+		{
+			name: "ExpiredAfterSigning",
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				setTestLifetime(t, e, 7*time.Hour)
+			},
+			wantStatus: KeyStatusExpiredAfterSigning,
+		},
+		{
+			name: "ExpiredBeforeSigning",
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				setTestLifetime(t, e, 2*time.Hour)
+			},
+			wantStatus: KeyStatusExpired,
+			wantErr:    true,
+		},
+		{
+			name: "UserIDRevoked",
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				reason := packet.NoReason
+				for _, id := range e.Identities {
+					sig := &packet.Signature{
+						Version:          e.PrimaryKey.Version,
+						SigType:          packet.SigTypeCertificationRevocation,
+						PubKeyAlgo:       e.PrimaryKey.PubKeyAlgo,
+						Hash:             crypto.SHA256,
+						CreationTime:     signed.Add(-time.Hour),
+						IssuerKeyId:      &e.PrimaryKey.KeyId,
+						RevocationReason: &reason,
+					}
+					if err := sig.SignUserId(id.UserId.Id, e.PrimaryKey, e.PrivateKey, nil); err != nil {
+						t.Fatal(err)
+					}
+					id.Revocations = append(id.Revocations, sig)
+				}
+			},
+			wantStatus: KeyStatusValid,
+		},
+		{
+			name:       "SubkeyValid",
+			subkey:     true,
+			wantStatus: KeyStatusValid,
+		},
+		{
+			name:   "SubkeySupersededAfterSigning",
+			subkey: true,
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				err := e.RevokeSubkey(&e.Subkeys[len(e.Subkeys)-1], packet.KeySuperseded, "", configAt(now.Add(-time.Hour)))
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: KeyStatusRevokedAfterSigning,
+		},
+		{
+			name:   "SubkeyCompromisedAfterSigning",
+			subkey: true,
+			edit: func(t *testing.T, e *openpgp.Entity) {
+				err := e.RevokeSubkey(&e.Subkeys[len(e.Subkeys)-1], packet.KeyCompromised, "", configAt(now.Add(-time.Hour)))
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantStatus: KeyStatusRevoked,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEntity(t, created, tt.subkey)
+
+			path := signTestImage(t, filepath.Join("..", "..", "..", "test", "images", "one-group.sif"), e, signed)
+
+			if tt.edit != nil {
+				tt.edit(t, e)
+			}
+
+			var status KeyStatus
+			cb := func(_ *sif.FileImage, r VerifyResult) bool {
+				status = r.KeyStatus()
+				return false
+			}
+
+			err := Verify(context.Background(), path,
+				OptVerifyWithKeyRing(openpgp.EntityList{e}),
+				OptVerifyCallback(cb),
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if got, want := status, tt.wantStatus; got != want {
+				t.Errorf("got status %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -10,13 +10,17 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
 	"golang.org/x/crypto/ocsp"
 )
@@ -34,9 +38,53 @@ const (
 	PKIXOCSPNoCheck = "1.3.6.1.5.5.7.48.1.5"
 )
 
-var errOCSP = errors.New("OCSP verification has failed")
+var (
+	errOCSP               = errors.New("OCSP verification has failed")
+	errRevocation         = errors.New("revocation check has failed")
+	errNoRevocationStatus = errors.New("no revocation status available")
+	errUnreliableResponse = errors.New("unreliable OCSP response")
+	errResponseTooOld     = errors.New("OCSP response older than maximum age")
+	errFailedToDecodeOCSP = errors.New("failed to decode OCSP response")
+)
+
+// RevocationOptions describes how the revocation status of the certificates of
+// a chain is determined. For each certificate, the sources of revocation
+// status are consulted in order: pre-fetched OCSP responses, CRLs, CRLs
+// fetched from the distribution points of the certificate and, last, online
+// OCSP queries. The first source that covers the certificate, and is current,
+// decides its status. A source that is not current, or older than MaxAge, is
+// skipped. A certificate not covered by any source fails the check.
+type RevocationOptions struct {
+	OCSP          bool                   // query the OCSP responders of certificates online
+	OCSPResponses [][]byte               // pre-fetched or stapled DER encoded OCSP responses
+	CRLs          []*x509.RevocationList // local CRLs
+	FetchCRLs     bool                   // fetch CRLs from the distribution points of certificates
+	MaxAge        time.Duration          // if non-zero, maximum age of OCSP responses and CRLs
+}
+
+// Enabled returns true if o requests a revocation check.
+func (o RevocationOptions) Enabled() bool {
+	return o.OCSP || len(o.OCSPResponses) > 0 || len(o.CRLs) > 0 || o.FetchCRLs
+}
 
+// onlineOnly returns true if o only requests online OCSP queries.
+func (o RevocationOptions) onlineOnly() bool {
+	return o.OCSP && len(o.OCSPResponses) == 0 && len(o.CRLs) == 0 && !o.FetchCRLs
+}
+
+// OCSPVerify checks the revocation status of the certificates of chain, by
+// querying their OCSP responders online.
 func OCSPVerify(chain ...*x509.Certificate) error {
+	if err := VerifyRevocation(chain, RevocationOptions{OCSP: true}); err != nil {
+		sylog.Warningf("OCSP verification has failed. Err: %s", err)
+		return errOCSP
+	}
+	return nil
+}
+
+// VerifyRevocation checks the revocation status of the certificates of chain,
+// according to o. Self-signed certificates are not checked.
+func VerifyRevocation(chain []*x509.Certificate, o RevocationOptions) error {
 	// use the pool as an index for certificate issuers.
 	// fixme: we can drop this lookup if we assume that certificate N is always signed by certificate N+1.
 	pool := map[string]*x509.Certificate{}
@@ -47,16 +95,78 @@ func OCSPVerify(chain ...*x509.Certificate) error {
 
 	// recursively validate the certificate chain
 	for _, cert := range chain {
-		if err := revocationCheck(cert, pool); err != nil {
-			sylog.Warningf("OCSP verification has failed. Err: %s", err)
-			return errOCSP
+		if err := revocationCheck(cert, pool, o); err != nil {
+			return fmt.Errorf("%w: certificate '%s': %w", errRevocation, cert.Subject, err)
 		}
 	}
 
 	return nil
 }
 
-func revocationCheck(cert *x509.Certificate, pool map[string]*x509.Certificate) error {
+// LoadOCSPResponses returns the OCSP responses read from the file at path or,
+// if path is a directory, from the files it holds. Files hold either a single
+// DER encoded response, or PEM encoded responses. Symbolic links to files are
+// not followed.
+func LoadOCSPResponses(path string) ([][]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{path}
+	if fi.IsDir() {
+		des, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		paths = paths[:0]
+		for _, de := range des {
+			if de.Type().IsRegular() {
+				paths = append(paths, filepath.Join(path, de.Name()))
+			}
+		}
+	}
+
+	var responses [][]byte
+	for _, p := range paths {
+		b, err := fs.ReadFileNoFollow(p)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := decodeOCSPResponses(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		responses = append(responses, rs...)
+	}
+	return responses, nil
+}
+
+// decodeOCSPResponses returns the OCSP responses in b, which holds either a
+// single DER encoded response, or PEM encoded responses.
+func decodeOCSPResponses(b []byte) ([][]byte, error) {
+	var responses [][]byte
+	for rest := bytes.TrimSpace(b); len(rest) > 0; rest = bytes.TrimSpace(rest) {
+		var p *pem.Block
+		if p, rest = pem.Decode(rest); p == nil {
+			break
+		}
+		if p.Type != "OCSP RESPONSE" {
+			return nil, fmt.Errorf("%w: unexpected PEM type %q", errFailedToDecodeOCSP, p.Type)
+		}
+		responses = append(responses, p.Bytes)
+	}
+	if len(responses) > 0 {
+		return responses, nil
+	}
+
+	if _, err := ocsp.ParseResponse(b, nil); err != nil {
+		return nil, fmt.Errorf("%w: %w", errFailedToDecodeOCSP, err)
+	}
+	return [][]byte{b}, nil
+}
+
+func revocationCheck(cert *x509.Certificate, pool map[string]*x509.Certificate, o RevocationOptions) error {
 	if len(cert.AuthorityKeyId) == 0 || string(cert.SubjectKeyId) == string(cert.AuthorityKeyId) {
 		sylog.Infof("skip self-signed certificate (%s)", cert.Subject.String())
 
@@ -71,10 +181,10 @@ func revocationCheck(cert *x509.Certificate, pool map[string]*x509.Certificate)
 
 	sylog.Infof("Validate: cert:%s  issuer:%s", cert.Subject.CommonName, issuer.Subject.CommonName)
 
-	// Ask OCSP for the validity of signer's certificate.
-	ocspCertificate, err := checkOCSPResponse(cert, issuer)
+	// Determine the revocation status of the certificate.
+	ocspCertificate, err := o.status(cert, issuer)
 	if err != nil {
-		return fmt.Errorf("OCSP Query err: %w", err)
+		return err
 	}
 
 	// 4.2.2.2  Authorized Responders
@@ -135,7 +245,7 @@ func revocationCheck(cert *x509.Certificate, pool map[string]*x509.Certificate)
 		// Authority Information Access if the check should be done in some
 		// other way. Details for specifying either of these two mechanisms are
 		// available in [RFC2459].
-		if err := revocationCheck(ocspCertificate, pool); err != nil {
+		if err := revocationCheck(ocspCertificate, pool, o); err != nil {
 			return fmt.Errorf("cannot verify OCSP server's certificate. err: %w", err)
 		}
 
@@ -150,17 +260,109 @@ func revocationCheck(cert *x509.Certificate, pool map[string]*x509.Certificate)
 	return nil
 }
 
-// checkOCSPResponse submit a revocation check request to the OCSP responder.
-// If the certificate is ok to use, it returns nil.
-// If the function cannot perform the check, or if the certificate is not ok for use (revoked or unknown), it returns
-// with an error.
-func checkOCSPResponse(cert, issuer *x509.Certificate) (needsValidation *x509.Certificate, err error) {
+// status determines the revocation status of cert, issued by issuer, from the
+// sources of o. If the certificate is ok to use, it returns nil. If the status
+// is determined by an OCSP response that was signed by a delegated responder,
+// the certificate of the responder is returned, for validation by the caller.
+func (o RevocationOptions) status(cert, issuer *x509.Certificate) (needsValidation *x509.Certificate, err error) {
 	sylog.Debugf("cert:[%s] issuer:[%s]", cert.Subject.String(), issuer.Subject.String())
 
 	if !issuer.IsCA {
 		return nil, fmt.Errorf("signer's certificates can only belong to a CA")
 	}
 
+	// A source that is not current, or too old, is skipped, so that another
+	// source may determine the status. If none does, the reason the last
+	// source was skipped is reported.
+	var skipped error
+
+	// Pre-fetched OCSP responses. A response that does not match the
+	// certificate, or is not signed on behalf of the issuer, is skipped.
+	for _, raw := range o.OCSPResponses {
+		r, err := ocsp.ParseResponseForCert(raw, cert, issuer)
+		if err != nil {
+			continue
+		}
+		needsValidation, err := o.checkOCSPResponse(r)
+		if isStale(err) {
+			sylog.Debugf("Skipping pre-fetched OCSP response for %s: %v", cert.Subject, err)
+			skipped = err
+			continue
+		}
+		sylog.Debugf("Using pre-fetched OCSP response for %s", cert.Subject)
+		return needsValidation, err
+	}
+
+	// CRLs, which must be signed by the issuer.
+	if ok, err := o.crlStatus(o.CRLs, cert, issuer, &skipped); ok {
+		return nil, err
+	}
+
+	if o.FetchCRLs {
+		for _, dp := range cert.CRLDistributionPoints {
+			crls, err := fetchCRLs(dp)
+			if err != nil {
+				sylog.Warningf("Failed to fetch CRL from %s: %v", dp, err)
+				continue
+			}
+			if ok, err := o.crlStatus(crls, cert, issuer, &skipped); ok {
+				return nil, err
+			}
+		}
+	}
+
+	if o.OCSP {
+		raw, err := queryOCSP(cert, issuer)
+		if err != nil {
+			return nil, fmt.Errorf("OCSP Query err: %w", err)
+		}
+		r, err := ocsp.ParseResponseForCert(raw, cert, issuer)
+		if err != nil {
+			return nil, fmt.Errorf("OCSP response err: %w", err)
+		}
+		return o.checkOCSPResponse(r)
+	}
+
+	if skipped != nil {
+		return nil, fmt.Errorf("%w: %w", errNoRevocationStatus, skipped)
+	}
+	return nil, errNoRevocationStatus
+}
+
+// isStale returns true if err indicates that a source of revocation status is not current, or
+// too old.
+func isStale(err error) bool {
+	return errors.Is(err, errUnreliableResponse) || errors.Is(err, errResponseTooOld) ||
+		errors.Is(err, errUnreliableCRL) || errors.Is(err, errCRLTooOld)
+}
+
+// crlStatus determines the revocation status of cert, issued by issuer, from the first of crls
+// that is signed by the issuer, covers cert, and is current. If there is no such CRL, ok is false
+// and, if a CRL was skipped as it is not current or too old, the reason is stored in skipped.
+func (o RevocationOptions) crlStatus(crls []*x509.RevocationList, cert, issuer *x509.Certificate, skipped *error) (ok bool, err error) {
+	for _, crl := range crls {
+		if !bytes.Equal(crl.RawIssuer, issuer.RawSubject) || crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		if err := checkCRLScope(crl, cert); err != nil {
+			sylog.Debugf("Skipping CRL from %s for %s: %v", crl.Issuer, cert.Subject, err)
+			continue
+		}
+		err := o.checkCRL(crl, cert)
+		if isStale(err) {
+			sylog.Debugf("Skipping CRL from %s for %s: %v", crl.Issuer, cert.Subject, err)
+			*skipped = err
+			continue
+		}
+		sylog.Debugf("Using CRL from %s for %s", crl.Issuer, cert.Subject)
+		return true, err
+	}
+	return false, nil
+}
+
+// queryOCSP submits a revocation check request for cert, issued by issuer, to
+// its OCSP responder, and returns the DER encoded response.
+func queryOCSP(cert, issuer *x509.Certificate) ([]byte, error) {
 	// Extract OCSP Server from the certificate in question
 	if len(cert.OCSPServer) == 0 {
 		return nil, fmt.Errorf("certificate does not support OCSP")
@@ -206,12 +408,14 @@ func checkOCSPResponse(cert, issuer *x509.Certificate) (needsValidation *x509.Ce
 	if err != nil {
 		return nil, fmt.Errorf("cannot read response body. err: %w", err)
 	}
+	return output, nil
+}
 
-	ocspResponse, err := ocsp.ParseResponseForCert(output, cert, issuer)
-	if err != nil {
-		return nil, fmt.Errorf("OCSP response err: %w", err)
-	}
-
+// checkOCSPResponse checks that the OCSP response r is current, and states
+// that the certificate is ok to use. If the function cannot perform the check,
+// or if the certificate is not ok for use (revoked or unknown), it returns
+// with an error.
+func (o RevocationOptions) checkOCSPResponse(ocspResponse *ocsp.Response) (needsValidation *x509.Certificate, err error) {
 	// Handle OCSP Response
 
 	// 4.2.2.1  Time
@@ -222,18 +426,25 @@ func checkOCSPResponse(cert, issuer *x509.Certificate) (needsValidation *x509.Ce
 	// the local system time value SHOULD be considered unreliable.
 	// - Responses where the nextUpdate value is not set are equivalent to a CRL
 	// with no time for nextUpdate (see Section 2.4).
-	if ocspResponse.ThisUpdate.After(time.Now()) {
-		return nil, fmt.Errorf("unreliable OCSP response")
+	now := time.Now()
+	if ocspResponse.ThisUpdate.After(now) {
+		return nil, errUnreliableResponse
 	}
 
 	if !ocspResponse.NextUpdate.IsZero() {
-		if ocspResponse.NextUpdate.Before(time.Now()) {
-			return nil, fmt.Errorf("unreliable OCSP response")
+		if ocspResponse.NextUpdate.Before(now) {
+			return nil, errUnreliableResponse
 		}
 	}
 	//   If nextUpdate is not set, the responder is indicating that newer
 	//   revocation information is available all the time.
 
+	// Pre-fetched responses may be required to be recent, whatever their
+	// nextUpdate.
+	if o.MaxAge > 0 && now.Sub(ocspResponse.ThisUpdate) > o.MaxAge {
+		return nil, fmt.Errorf("%w: produced at %s", errResponseTooOld, ocspResponse.ThisUpdate)
+	}
+
 	// The OCSP's certificate is signed by a third-party issuer that we need to verify.
 	if ocspResponse.Certificate != nil {
 		needsValidation = ocspResponse.Certificate
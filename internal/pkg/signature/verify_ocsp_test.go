@@ -0,0 +1,297 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testCA is a certificate, and the key it was issued for.
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// newTestCert issues a certificate with the specified serial number, signed by
+// parent (self-signed if parent is nil).
+func newTestCert(t *testing.T, serial int64, ca bool, parent *testCA) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: big.NewInt(serial).String()},
+		SubjectKeyId: big.NewInt(serial).Bytes(),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	if ca {
+		tmpl.BasicConstraintsValid = true
+		tmpl.IsCA = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		tmpl.ExtKeyUsage = nil
+	}
+
+	issuer := &testCA{cert: tmpl, key: key}
+	if parent != nil {
+		issuer = parent
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer.cert, key.Public(), issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: c, key: key}
+}
+
+// newTestChain returns a leaf certificate, an intermediate CA and a root CA.
+func newTestChain(t *testing.T) (leaf, intermediate, root *testCA) {
+	t.Helper()
+
+	root = newTestCert(t, 1, true, nil)
+	intermediate = newTestCert(t, 2, true, root)
+	leaf = newTestCert(t, 3, false, intermediate)
+	return leaf, intermediate, root
+}
+
+// newTestOCSPResponse returns a DER encoded OCSP response for cert, signed by
+// issuer, with the specified status, produced at thisUpdate.
+func newTestOCSPResponse(t *testing.T, cert *x509.Certificate, issuer *testCA, status int, thisUpdate time.Time) []byte {
+	t.Helper()
+
+	tmpl := ocsp.Response{
+		Status:       status,
+		SerialNumber: cert.SerialNumber,
+		ThisUpdate:   thisUpdate,
+		NextUpdate:   time.Now().Add(time.Hour),
+	}
+	if status == ocsp.Revoked {
+		tmpl.RevokedAt = thisUpdate
+		tmpl.RevocationReason = ocsp.KeyCompromise
+	}
+
+	b, err := ocsp.CreateResponse(issuer.cert, issuer.cert, tmpl, issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// newTestCRL returns a CRL signed by issuer, issued at thisUpdate, that
+// revokes the specified certificates.
+func newTestCRL(t *testing.T, issuer *testCA, thisUpdate time.Time, revoked ...*x509.Certificate) *x509.RevocationList {
+	t.Helper()
+
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: thisUpdate,
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for _, c := range revoked {
+		tmpl.RevokedCertificateEntries = append(tmpl.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   c.SerialNumber,
+			RevocationTime: thisUpdate,
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, issuer.cert, issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crl
+}
+
+func TestVerifyRevocation(t *testing.T) {
+	leaf, intermediate, root := newTestChain(t)
+	chain := []*x509.Certificate{leaf.cert, intermediate.cert, root.cert}
+
+	now := time.Now().Add(-time.Minute)
+	old := time.Now().Add(-48 * time.Hour)
+
+	goodLeaf := newTestOCSPResponse(t, leaf.cert, intermediate, ocsp.Good, now)
+	revokedLeaf := newTestOCSPResponse(t, leaf.cert, intermediate, ocsp.Revoked, now)
+	oldLeaf := newTestOCSPResponse(t, leaf.cert, intermediate, ocsp.Good, old)
+	goodIntermediate := newTestOCSPResponse(t, intermediate.cert, root, ocsp.Good, now)
+
+	tests := []struct {
+		name    string
+		opts    RevocationOptions
+		wantErr bool
+	}{
+		{
+			name:    "NoStatus",
+			opts:    RevocationOptions{CRLs: []*x509.RevocationList{newTestCRL(t, intermediate, now)}},
+			wantErr: true,
+		},
+		{
+			name: "OCSPResponses",
+			opts: RevocationOptions{OCSPResponses: [][]byte{goodLeaf, goodIntermediate}},
+		},
+		{
+			name: "OCSPResponsesRevoked",
+			opts: RevocationOptions{
+				OCSPResponses: [][]byte{revokedLeaf, goodIntermediate},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OCSPResponseMaxAge",
+			opts: RevocationOptions{
+				OCSPResponses: [][]byte{oldLeaf, goodIntermediate},
+				MaxAge:        24 * time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name: "OCSPResponseOld",
+			opts: RevocationOptions{
+				OCSPResponses: [][]byte{oldLeaf, goodIntermediate},
+			},
+		},
+		{
+			name: "CRLs",
+			opts: RevocationOptions{
+				CRLs: []*x509.RevocationList{newTestCRL(t, intermediate, now), newTestCRL(t, root, now)},
+			},
+		},
+		{
+			name: "CRLRevoked",
+			opts: RevocationOptions{
+				CRLs: []*x509.RevocationList{newTestCRL(t, intermediate, now), newTestCRL(t, root, now, intermediate.cert)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "CRLMaxAge",
+			opts: RevocationOptions{
+				CRLs:   []*x509.RevocationList{newTestCRL(t, intermediate, now), newTestCRL(t, root, old)},
+				MaxAge: 24 * time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name: "OCSPResponseMaxAgeFallback",
+			opts: RevocationOptions{
+				OCSPResponses: [][]byte{oldLeaf, goodIntermediate},
+				CRLs:          []*x509.RevocationList{newTestCRL(t, intermediate, now)},
+				MaxAge:        24 * time.Hour,
+			},
+		},
+		{
+			name: "CRLMaxAgeFallback",
+			opts: RevocationOptions{
+				CRLs:   []*x509.RevocationList{newTestCRL(t, intermediate, now), newTestCRL(t, root, old), newTestCRL(t, root, now)},
+				MaxAge: 24 * time.Hour,
+			},
+		},
+		{
+			name: "CRLWrongIssuer",
+			opts: RevocationOptions{
+				CRLs: []*x509.RevocationList{newTestCRL(t, root, now)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Mixed",
+			opts: RevocationOptions{
+				OCSPResponses: [][]byte{goodLeaf},
+				CRLs:          []*x509.RevocationList{newTestCRL(t, root, now)},
+				MaxAge:        time.Hour,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyRevocation(chain, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errRevocation) {
+				t.Errorf("got error %v, want %v", err, errRevocation)
+			}
+		})
+	}
+}
+
+func TestLoadOCSPResponses(t *testing.T) {
+	leaf, intermediate, root := newTestChain(t)
+
+	r1 := newTestOCSPResponse(t, leaf.cert, intermediate, ocsp.Good, time.Now())
+	r2 := newTestOCSPResponse(t, intermediate.cert, root, ocsp.Good, time.Now())
+
+	dir := t.TempDir()
+
+	der := filepath.Join(dir, "leaf.der")
+	if err := os.WriteFile(der, r1, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pemPath := filepath.Join(t.TempDir(), "chain.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "OCSP RESPONSE", Bytes: r1})
+	b = append(b, pem.EncodeToMemory(&pem.Block{Type: "OCSP RESPONSE", Bytes: r2})...)
+	if err := os.WriteFile(pemPath, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	badPEM := filepath.Join(t.TempDir(), "bad.pem")
+	if err := os.WriteFile(badPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: r1}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCount int
+		wantErr   error
+	}{
+		{name: "DER", path: der, wantCount: 1},
+		{name: "PEM", path: pemPath, wantCount: 2},
+		{name: "Directory", path: dir, wantCount: 1},
+		{name: "BadPEM", path: badPEM, wantErr: errFailedToDecodeOCSP},
+		{name: "Missing", path: filepath.Join(dir, "missing"), wantErr: os.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := LoadOCSPResponses(tt.path)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+			if got, want := len(rs), tt.wantCount; got != want {
+				t.Errorf("got %v responses, want %v", got, want)
+			}
+		})
+	}
+}
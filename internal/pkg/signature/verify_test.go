@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2020-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
 // distributed with the sources of this project regarding your rights to use or distribute this
 // software.
@@ -17,6 +17,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
@@ -124,6 +125,8 @@ func Test_newVerifier(t *testing.T) {
 
 	pgpOpts := []client.Option{client.OptBearerToken("token")}
 
+	kr := openpgp.EntityList{}
+
 	tests := []struct {
 		name         string
 		opts         []VerifyOpt
@@ -165,6 +168,28 @@ func Test_newVerifier(t *testing.T) {
 				pgpOpts: pgpOpts,
 			},
 		},
+		{
+			name:         "OptVerifyWithKeyRing",
+			opts:         []VerifyOpt{OptVerifyWithKeyRing(kr)},
+			wantVerifier: verifier{kr: kr},
+		},
+		{
+			name: "OptVerifyWithRevocation",
+			opts: []VerifyOpt{
+				OptVerifyWithOCSP(),
+				OptVerifyWithOCSPResponses([]byte{1}),
+				OptVerifyWithCRLFetch(),
+				OptVerifyWithRevocationMaxAge(time.Hour),
+			},
+			wantVerifier: verifier{
+				revocation: RevocationOptions{
+					OCSP:          true,
+					OCSPResponses: [][]byte{{1}},
+					FetchCRLs:     true,
+					MaxAge:        time.Hour,
+				},
+			},
+		},
 		{
 			name:         "OptVerifyGroup",
 			opts:         []VerifyOpt{OptVerifyGroup(1)},
@@ -223,7 +248,7 @@ func Test_verifier_getOpts(t *testing.T) {
 	}
 	defer oneGroupImage.UnloadContainer()
 
-	cb := func(*sif.FileImage, integrity.VerifyResult) bool { return false }
+	cb := func(*sif.FileImage, VerifyResult) bool { return false }
 
 	tests := []struct {
 		name     string
@@ -289,6 +314,15 @@ func Test_verifier_getOpts(t *testing.T) {
 			f:        oneGroupImage,
 			wantOpts: 2,
 		},
+		{
+			name: "KeyRing",
+			v: verifier{
+				pgp: true,
+				kr:  openpgp.EntityList{},
+			},
+			f:        oneGroupImage,
+			wantOpts: 2,
+		},
 		{
 			name:     "Group1",
 			v:        verifier{groupIDs: []uint32{1}},
@@ -544,7 +578,7 @@ func TestVerify(t *testing.T) { //nolint:maintidx
 		t.Run(tt.name, func(t *testing.T) {
 			i := 0
 
-			cb := func(_ *sif.FileImage, r integrity.VerifyResult) bool {
+			cb := func(_ *sif.FileImage, r VerifyResult) bool {
 				defer func() { i++ }()
 
 				if i >= len(tt.wantVerified) {
@@ -743,7 +777,7 @@ func TestVerifyFingerPrint(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			i := 0
 
-			cb := func(_ *sif.FileImage, r integrity.VerifyResult) bool {
+			cb := func(_ *sif.FileImage, r VerifyResult) bool {
 				defer func() { i++ }()
 
 				if i >= len(tt.wantVerified) {
@@ -0,0 +1,238 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package signature
+
+import (
+	"cmp"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/cosign"
+)
+
+var errBadThreshold = errors.New("threshold must be between 0 and the number of required entities")
+
+// KeyFingerprint returns the fingerprint used to identify a signing entity that is not a PGP key,
+// such as the key of an x.509 certificate or a raw public key. It is the hex encoded SHA-256
+// digest of the PKIX, ASN.1 DER encoding of pub.
+func KeyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := cryptoutils.MarshalPublicKeyToDER(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ObjectSigners lists the entities that produced a valid signature of an object.
+type ObjectSigners struct {
+	ID       uint32       // ID of the object.
+	DataType sif.DataType // Data type of the object.
+	Signers  []string     // Fingerprints of the entities that signed the object.
+}
+
+// ThresholdResult describes the result of a threshold verification.
+type ThresholdResult struct {
+	Threshold int             // Number of required entities that must sign.
+	Signers   []string        // Fingerprints of the required entities that signed all selected objects.
+	Objects   []ObjectSigners // Signers of each object with at least one valid signature.
+}
+
+// signerFingerprints returns the fingerprints of the entities that produced the signature of r.
+func signerFingerprints(r VerifyResult) []string {
+	if e := r.Entity(); e != nil {
+		return []string{hex.EncodeToString(e.PrimaryKey.Fingerprint)}
+	}
+
+	var fps []string
+	for _, pub := range r.Keys() {
+		if fp, err := KeyFingerprint(pub); err == nil {
+			fps = append(fps, fp)
+		}
+	}
+	return fps
+}
+
+// normalizeFingerprints returns the distinct fingerprints, in lower case and without separators.
+func normalizeFingerprints(fingerprints []string) []string {
+	var fps []string
+	for _, fp := range fingerprints {
+		fp = strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(fp))
+		if !slices.Contains(fps, fp) {
+			fps = append(fps, fp)
+		}
+	}
+	return fps
+}
+
+// VerifyThreshold verifies an image and checks it was signed by *at least* threshold of the
+// entities identified by fingerprints, or by all of them if threshold is 0. An entity is counted
+// if it produced a valid signature of each of the selected objects. PGP entities are identified
+// by the fingerprint of their primary key, and other entities by KeyFingerprint.
+//
+// The cosign signatures of an OCI-SIF image are verified with the key material specified with
+// OptVerifyWithVerifier, and an entity is counted if it produced a valid signature of the image.
+//
+// Signatures that cannot be validated, for example because they were produced by an entity for
+// which no key material is available, do not cause verification to fail, but are not counted.
+// Key material must still be provided for each format of signature present in the image.
+// The returned result lists the entities that signed each object, and is returned even if the
+// threshold is not met.
+//
+// Key material is specified as for Verify. Several sources of key material may be specified.
+func VerifyThreshold(ctx context.Context, path string, fingerprints []string, threshold int, opts ...VerifyOpt) (ThresholdResult, error) {
+	var res ThresholdResult
+
+	fps := normalizeFingerprints(fingerprints)
+	if len(fps) == 0 || threshold < 0 || threshold > len(fps) {
+		return res, errBadThreshold
+	}
+	if threshold == 0 {
+		threshold = len(fps)
+	}
+	res.Threshold = threshold
+
+	v, err := newVerifier(opts)
+	if err != nil {
+		return res, err
+	}
+
+	// Load container.
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return res, err
+	}
+	defer f.UnloadContainer()
+
+	// An OCI-SIF image carries cosign signatures, rather than SIF signatures.
+	if _, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex)); err == nil {
+		return verifyCosignThreshold(ctx, path, fps, threshold, &v)
+	}
+
+	type task struct {
+		id      uint32
+		isGroup bool
+	}
+	signedBy := map[task]map[string]bool{}
+	objects := map[uint32]*ObjectSigners{}
+
+	// Record the signers of each selected object, and carry on when a signature is not valid.
+	cb := v.cb
+	v.cb = func(f *sif.FileImage, r VerifyResult) bool {
+		if cb != nil {
+			cb(f, r)
+		}
+
+		id, isGroup := r.Signature().LinkedID()
+		t := task{id, isGroup}
+		if signedBy[t] == nil {
+			signedBy[t] = map[string]bool{}
+		}
+		if r.Error() != nil {
+			return true
+		}
+
+		for _, fp := range signerFingerprints(r) {
+			signedBy[t][fp] = true
+
+			for _, od := range r.Verified() {
+				o, ok := objects[od.ID()]
+				if !ok {
+					o = &ObjectSigners{ID: od.ID(), DataType: od.DataType()}
+					objects[od.ID()] = o
+				}
+				if !slices.Contains(o.Signers, fp) {
+					o.Signers = append(o.Signers, fp)
+				}
+			}
+		}
+		return true
+	}
+
+	// Get options to validate f.
+	vopts, err := v.getOpts(ctx, f)
+	if err != nil {
+		return res, err
+	}
+
+	// Verify signature(s).
+	iv, err := integrity.NewVerifier(f, vopts...)
+	if err != nil {
+		return res, err
+	}
+	if err := iv.Verify(); err != nil {
+		return res, err
+	}
+
+	for _, o := range objects {
+		res.Objects = append(res.Objects, *o)
+	}
+	slices.SortFunc(res.Objects, func(a, b ObjectSigners) int { return cmp.Compare(a.ID, b.ID) })
+
+	// Count the required entities that signed all selected objects.
+	for _, fp := range fps {
+		n := 0
+		for _, signers := range signedBy {
+			if signers[fp] {
+				n++
+			}
+		}
+		if n > 0 && n == len(signedBy) {
+			res.Signers = append(res.Signers, fp)
+		}
+	}
+
+	if len(res.Signers) < threshold {
+		return res, fmt.Errorf("%w: signed by %d of %d, %d required", errNotSignedByRequired, len(res.Signers), len(fps), threshold)
+	}
+	return res, nil
+}
+
+// verifyCosignThreshold checks that the OCI-SIF image at path has cosign signatures by *at least*
+// threshold of the entities identified by fingerprints, using the key material of v.
+func verifyCosignThreshold(ctx context.Context, path string, fps []string, threshold int, v *verifier) (ThresholdResult, error) {
+	res := ThresholdResult{Threshold: threshold}
+
+	if len(v.svs) == 0 {
+		return res, errors.New("cosign signatures must be verified with public keys")
+	}
+	signed, err := cosign.SignedBy(ctx, path, v.svs)
+	if err != nil {
+		return res, err
+	}
+
+	for i, sv := range v.svs {
+		if !signed[i] {
+			continue
+		}
+		pub, err := sv.PublicKey()
+		if err != nil {
+			return res, err
+		}
+		fp, err := KeyFingerprint(pub)
+		if err != nil {
+			return res, err
+		}
+		if slices.Contains(fps, fp) && !slices.Contains(res.Signers, fp) {
+			res.Signers = append(res.Signers, fp)
+		}
+	}
+
+	if len(res.Signers) < threshold {
+		return res, fmt.Errorf("%w: signed by %d of %d, %d required", errNotSignedByRequired, len(res.Signers), len(fps), threshold)
+	}
+	return res, nil
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package signature
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// signTestImageWithSigner adds a signature produced with signer opt to the image at path.
+func signTestImageWithSigner(t *testing.T, path string, opt integrity.SignerOpt) {
+	t.Helper()
+
+	f, err := sif.LoadContainerFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.UnloadContainer()
+
+	s, err := integrity.NewSigner(f, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Sign(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	sv := getTestVerifier(t, "rsa-public.pem")
+
+	pub, err := sv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fp, err := KeyFingerprint(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := hex.DecodeString(fp); err != nil || len(b) != 32 {
+		t.Errorf("got fingerprint %q, want hex encoded SHA-256 digest", fp)
+	}
+
+	c := getCertificate(t, "leaf.pem")
+	if fp, err := KeyFingerprint(c.PublicKey); err != nil || fp == "" {
+		t.Errorf("failed to get certificate fingerprint: %v", err)
+	}
+}
+
+func TestVerifyThreshold(t *testing.T) {
+	now := time.Now()
+
+	e1 := getTestEntity(t)
+	e2 := newTestEntity(t, now.Add(-time.Hour), false)
+	e3 := newTestEntity(t, now.Add(-time.Hour), false)
+
+	fp1 := testFingerPrint
+	fp2 := hex.EncodeToString(e2.PrimaryKey.Fingerprint)
+	fp3 := hex.EncodeToString(e3.PrimaryKey.Fingerprint)
+
+	pub, err := getTestVerifier(t, "rsa-public.pem").PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fpRSA, err := KeyFingerprint(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sign an image with e1, e2, e3 and an RSA key. e3 is not in the keyring used to verify.
+	path := signTestImage(t, filepath.Join("..", "..", "..", "test", "images", "one-group-signed-pgp.sif"), e2, now)
+	signTestImageWithSigner(t, path, integrity.OptSignWithEntity(e3))
+	signTestImageWithSigner(t, path, integrity.OptSignWithSigner(getTestSigner(t, "rsa-private.pem")))
+
+	kr := OptVerifyWithKeyRing(openpgp.EntityList{e1, e2})
+	rsa := OptVerifyWithVerifier(getTestVerifier(t, "rsa-public.pem"))
+
+	tests := []struct {
+		name         string
+		path         string
+		fingerprints []string
+		threshold    int
+		opts         []VerifyOpt
+		wantSigners  []string
+		wantErr      error
+	}{
+		{
+			name:      "NoFingerprints",
+			path:      path,
+			threshold: 0,
+			opts:      []VerifyOpt{kr},
+			wantErr:   errBadThreshold,
+		},
+		{
+			name:         "ThresholdNegative",
+			path:         path,
+			fingerprints: []string{fp1, fp2},
+			threshold:    -1,
+			opts:         []VerifyOpt{kr},
+			wantErr:      errBadThreshold,
+		},
+		{
+			name:         "ThresholdAll",
+			path:         path,
+			fingerprints: []string{fp1, fp2},
+			threshold:    0,
+			opts:         []VerifyOpt{kr, rsa},
+			wantSigners:  []string{strings.ToLower(fp1), fp2},
+		},
+		{
+			name:         "ThresholdTooLarge",
+			path:         path,
+			fingerprints: []string{fp1, fp2, strings.ToLower(fp1)},
+			threshold:    3,
+			opts:         []VerifyOpt{kr},
+			wantErr:      errBadThreshold,
+		},
+		{
+			name:         "SignatureNotFound",
+			path:         filepath.Join("..", "..", "..", "test", "images", "one-group.sif"),
+			fingerprints: []string{fp1, fp2},
+			threshold:    1,
+			opts:         []VerifyOpt{kr},
+			wantErr:      &integrity.SignatureNotFoundError{},
+		},
+		{
+			name:         "TwoOfThree",
+			path:         path,
+			fingerprints: []string{fp1, fp2, invalidFingerPrint},
+			threshold:    2,
+			opts:         []VerifyOpt{kr, rsa},
+			wantSigners:  []string{strings.ToLower(fp1), fp2},
+		},
+		{
+			name:         "UnknownKey",
+			path:         path,
+			fingerprints: []string{fp1, fp2, fp3},
+			threshold:    3,
+			opts:         []VerifyOpt{kr, rsa},
+			wantSigners:  []string{strings.ToLower(fp1), fp2},
+			wantErr:      errNotSignedByRequired,
+		},
+		{
+			name:         "PGPAndKey",
+			path:         path,
+			fingerprints: []string{fp1, fp2, strings.ToUpper(fpRSA)},
+			threshold:    3,
+			opts:         []VerifyOpt{kr, rsa},
+			wantSigners:  []string{strings.ToLower(fp1), fp2, fpRSA},
+		},
+		{
+			name:         "CosignNotSigned",
+			path:         filepath.Join("..", "..", "..", "test", "images", "empty.oci.sif"),
+			fingerprints: []string{fpRSA},
+			threshold:    1,
+			opts:         []VerifyOpt{rsa},
+			wantErr:      errNotSignedByRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := VerifyThreshold(context.Background(), tt.path, tt.fingerprints, tt.threshold, tt.opts...)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if got, want := res.Signers, tt.wantSigners; !slices.Equal(got, want) {
+				t.Errorf("got signers %v, want %v", got, want)
+			}
+
+			// Each signer must be listed against each object of the signed group.
+			if len(tt.wantSigners) > 0 {
+				if got, want := len(res.Objects), 2; got != want {
+					t.Fatalf("got %v objects, want %v", got, want)
+				}
+				for _, o := range res.Objects {
+					for _, fp := range tt.wantSigners {
+						if !slices.Contains(o.Signers, fp) {
+							t.Errorf("object %v: signer %v not listed", o.ID, fp)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package signingentity loads the x509 certificates and cosign public keys
+// that execution control lists and verification policies trust to sign images.
+// All files are read without following a final symbolic link.
+package signingentity
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+)
+
+var (
+	// ErrFailedToDecodePEM is returned when a certificate file does not hold
+	// PEM encoded certificates.
+	ErrFailedToDecodePEM = errors.New("failed to decode PEM")
+	// ErrSubjectNotAllowed is returned when the subject of a certificate is not
+	// one of the allowed subjects.
+	ErrSubjectNotAllowed = errors.New("certificate subject not allowed")
+	// ErrSANNotAllowed is returned when no subject alternative name of a
+	// certificate is one of the allowed names.
+	ErrSANNotAllowed = errors.New("certificate subject alternative name not allowed")
+)
+
+// Entity is a signing entity identified by an x509 certificate or a cosign
+// public key.
+type Entity struct {
+	Name     string             // path of the certificate or public key
+	Pub      crypto.PublicKey   // public key of the entity
+	Verifier signature.Verifier // verifier for signatures made by the entity
+	Err      error              // non-nil if the certificate is not trusted
+}
+
+// Signs returns true if pub is the public key of e.
+func (e Entity) Signs(pub crypto.PublicKey) bool {
+	k, ok := e.Pub.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(pub)
+}
+
+// KeyFingerprint returns the hex encoded SHA-256 digest of the PKIX, ASN.1 DER
+// encoding of the public key of e. It identifies the key, whether it is held in
+// a certificate or a cosign public key file.
+func (e Entity) KeyFingerprint() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(e.Pub)
+	if err != nil {
+		return "", fmt.Errorf("while encoding public key of %s: %w", e.Name, err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// LoadCertificates returns the certificates read from the PEM file at path.
+func LoadCertificates(path string) ([]*x509.Certificate, error) {
+	b, err := fs.ReadFileNoFollow(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for rest := bytes.TrimSpace(b); len(rest) > 0; {
+		var p *pem.Block
+
+		if p, rest = pem.Decode(rest); p == nil {
+			return nil, ErrFailedToDecodePEM
+		}
+
+		c, err := x509.ParseCertificate(p.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+		rest = bytes.TrimSpace(rest)
+	}
+	if len(certs) == 0 {
+		return nil, ErrFailedToDecodePEM
+	}
+	return certs, nil
+}
+
+// LoadCertificatePool returns the pool of certificates read from the PEM file
+// at path.
+func LoadCertificatePool(path string) (*x509.CertPool, error) {
+	certs, err := LoadCertificates(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool, nil
+}
+
+// CheckCertificate verifies that c chains to a certificate in roots, via
+// intermediates, and is valid for code signing. If subjects is not empty, the
+// subject of c must be one of subjects. If sans is not empty, one of the
+// subject alternative names of c must be one of sans. The verified chains are
+// returned.
+func CheckCertificate(c *x509.Certificate, intermediates, roots *x509.CertPool, subjects, sans []string) ([][]*x509.Certificate, error) {
+	opts := x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+		KeyUsages: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageCodeSigning,
+		},
+	}
+	chains, err := c.Verify(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(subjects) > 0 {
+		if !slices.Contains(subjects, c.Subject.CommonName) && !slices.Contains(subjects, c.Subject.String()) {
+			return nil, fmt.Errorf("%w: %s", ErrSubjectNotAllowed, c.Subject)
+		}
+	}
+
+	if len(sans) > 0 {
+		names := slices.Concat(c.DNSNames, c.EmailAddresses)
+		for _, u := range c.URIs {
+			names = append(names, u.String())
+		}
+		if !slices.ContainsFunc(names, func(s string) bool { return slices.Contains(sans, s) }) {
+			return nil, fmt.Errorf("%w: %v", ErrSANNotAllowed, names)
+		}
+	}
+
+	return chains, nil
+}
+
+// LoadCertificate returns the entity identified by the first certificate in
+// the PEM file at path. The Err field of the entity is set to the result of
+// check, called with the certificate.
+func LoadCertificate(path string, check func(*x509.Certificate) error) (Entity, error) {
+	certs, err := LoadCertificates(path)
+	if err != nil {
+		return Entity{}, fmt.Errorf("while loading certificate %s: %w", path, err)
+	}
+	c := certs[0]
+
+	sv, err := signature.LoadVerifier(c.PublicKey, crypto.SHA256)
+	if err != nil {
+		return Entity{}, fmt.Errorf("while loading certificate %s: %w", path, err)
+	}
+
+	return Entity{
+		Name:     path,
+		Pub:      c.PublicKey,
+		Verifier: sv,
+		Err:      check(c),
+	}, nil
+}
+
+// LoadCosignKey returns the entity identified by the PEM encoded cosign public
+// key in the file at path.
+func LoadCosignKey(path string) (Entity, error) {
+	b, err := fs.ReadFileNoFollow(path)
+	if err != nil {
+		return Entity{}, fmt.Errorf("while loading cosign key %s: %w", path, err)
+	}
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(b)
+	if err != nil {
+		return Entity{}, fmt.Errorf("while loading cosign key %s: %w", path, err)
+	}
+	sv, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		return Entity{}, fmt.Errorf("while loading cosign key %s: %w", path, err)
+	}
+	return Entity{Name: path, Pub: pub, Verifier: sv}, nil
+}
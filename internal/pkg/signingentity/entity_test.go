@@ -0,0 +1,110 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package signingentity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed code signing certificate, with
+// common name cn and email address email, to a PEM file, and returns its path.
+func writeTestCertificate(t *testing.T, cn, email string) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		EmailAddresses:        []string{email},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckCertificate(t *testing.T) {
+	path := writeTestCertificate(t, "signer", "signer@example.com")
+	roots, err := LoadCertificatePool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		subjects []string
+		sans     []string
+		wantErr  error
+	}{
+		{name: "NoConstraints"},
+		{name: "Subject", subjects: []string{"signer"}},
+		{name: "SubjectNotAllowed", subjects: []string{"other"}, wantErr: ErrSubjectNotAllowed},
+		{name: "SAN", sans: []string{"signer@example.com"}},
+		{name: "SANNotAllowed", sans: []string{"other@example.com"}, wantErr: ErrSANNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := LoadCertificate(path, func(c *x509.Certificate) error {
+				_, err := CheckCertificate(c, nil, roots, tt.subjects, tt.sans)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !errors.Is(e.Err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", e.Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadCertificates(t *testing.T) {
+	path := writeTestCertificate(t, "signer", "signer@example.com")
+
+	garbage := filepath.Join(t.TempDir(), "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCertificates(garbage); !errors.Is(err, ErrFailedToDecodePEM) {
+		t.Errorf("got error %v, want %v", err, ErrFailedToDecodePEM)
+	}
+
+	// A final symbolic link is not followed.
+	link := filepath.Join(t.TempDir(), "link.pem")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCertificates(link); err == nil {
+		t.Errorf("unexpected success loading through a symbolic link")
+	}
+}
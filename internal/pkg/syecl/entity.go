@@ -0,0 +1,70 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package syecl
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/signingentity"
+)
+
+var (
+	errCertRootsRequired  = errors.New("certificate entities require a certroots file")
+	errCertificateMissing = errors.New("certroots, certintermediates, certsubject and certsan require a certificate")
+)
+
+// loadEntities returns the certificate and cosign key entities of eg, rather
+// than PGP fingerprints. A certificate that is not trusted by eg is returned
+// with its Err field set.
+func (eg *Execgroup) loadEntities() ([]signingentity.Entity, error) {
+	if len(eg.Certificates) == 0 {
+		if eg.CertRoots != "" || eg.CertIntermediates != "" || len(eg.CertSubjects) > 0 || len(eg.CertSANs) > 0 {
+			return nil, errCertificateMissing
+		}
+	} else if eg.CertRoots == "" {
+		return nil, errCertRootsRequired
+	}
+
+	var es []signingentity.Entity
+
+	if len(eg.Certificates) > 0 {
+		roots, err := signingentity.LoadCertificatePool(eg.CertRoots)
+		if err != nil {
+			return nil, fmt.Errorf("while loading root certificates %s: %w", eg.CertRoots, err)
+		}
+
+		var intermediates *x509.CertPool
+		if eg.CertIntermediates != "" {
+			if intermediates, err = signingentity.LoadCertificatePool(eg.CertIntermediates); err != nil {
+				return nil, fmt.Errorf("while loading intermediate certificates %s: %w", eg.CertIntermediates, err)
+			}
+		}
+
+		check := func(c *x509.Certificate) error {
+			_, err := signingentity.CheckCertificate(c, intermediates, roots, eg.CertSubjects, eg.CertSANs)
+			return err
+		}
+		for _, path := range eg.Certificates {
+			e, err := signingentity.LoadCertificate(path, check)
+			if err != nil {
+				return nil, err
+			}
+			es = append(es, e)
+		}
+	}
+
+	for _, path := range eg.CosignKeys {
+		e, err := signingentity.LoadCosignKey(path)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+
+	return es, nil
+}
@@ -18,12 +18,17 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	toml "github.com/pelletier/go-toml/v2"
+	"github.com/sigstore/sigstore/pkg/signature"
 	"github.com/sylabs/sif/v2/pkg/integrity"
 	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/cosign"
+	"github.com/sylabs/singularity/v4/internal/pkg/signingentity"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
 	"golang.org/x/sys/unix"
 )
 
@@ -42,17 +47,36 @@ type EclConfig struct {
 // Execgroup describes an execution group, the main unit of configuration:
 //
 //	TagName: a descriptive identifier
-//	ListMode: whether the execgroup follows a whitelist, whitestrict or blacklist model
-//		whitelist: one or more KeyFP's present and verified,
-//		whitestrict: all KeyFP's present and verified,
-//		blacklist: none of the KeyFP should be present
+//	ListMode: whether the execgroup follows a whitelist, whitestrict, threshold or blacklist model
+//		whitelist: one or more entities present and verified,
+//		whitestrict: all entities present and verified,
+//		threshold: at least Threshold distinct entities present and verified,
+//		blacklist: none of the entities should be present
+//	Threshold: number of entities required by the threshold model
 //	DirPath: containers must be stored in this directory path
 //	KeyFPs: list of Key Fingerprints of entities to verify
+//	Certificates: list of paths to x509 certificates of entities to verify
+//	CertRoots: path to the root certificates that Certificates must chain to
+//	CertIntermediates: path to intermediate certificates used to form chains
+//	CertSubjects: if set, Certificates must have a subject CN or DN in this list
+//	CertSANs: if set, Certificates must have a DNS, email or URI SAN in this list
+//	CosignKeys: list of paths to cosign public keys of entities to verify
+//
+// A certificate that does not chain to CertRoots, or does not satisfy the
+// subject and SAN constraints, never satisfies a whitelist, whitestrict
+// or threshold execgroup. It is still matched by a blacklist execgroup.
 type Execgroup struct {
-	TagName  string   `toml:"tagname"`
-	ListMode string   `toml:"mode"`
-	DirPath  string   `toml:"dirpath"`
-	KeyFPs   []string `toml:"keyfp"`
+	TagName           string   `toml:"tagname"`
+	ListMode          string   `toml:"mode"`
+	Threshold         int      `toml:"threshold,omitempty"`
+	DirPath           string   `toml:"dirpath"`
+	KeyFPs            []string `toml:"keyfp"`
+	Certificates      []string `toml:"certificate,omitempty"`
+	CertRoots         string   `toml:"certroots,omitempty"`
+	CertIntermediates string   `toml:"certintermediates,omitempty"`
+	CertSubjects      []string `toml:"certsubject,omitempty"`
+	CertSANs          []string `toml:"certsan,omitempty"`
+	CosignKeys        []string `toml:"cosignkey,omitempty"`
 }
 
 // LoadConfig opens an ECL config file and unmarshals it into structures
@@ -120,8 +144,11 @@ func (ecl *EclConfig) ValidateConfig() error {
 				return fmt.Errorf("all execgroup dirpath`s should be fully cleaned with symlinks resolved")
 			}
 		}
-		if v.ListMode != "whitelist" && v.ListMode != "whitestrict" && v.ListMode != "blacklist" {
-			return fmt.Errorf("the mode field can only be either: whitelist, whitestrict, blacklist")
+		if v.ListMode != "whitelist" && v.ListMode != "whitestrict" && v.ListMode != "threshold" && v.ListMode != "blacklist" {
+			return fmt.Errorf("the mode field can only be either: whitelist, whitestrict, threshold, blacklist")
+		}
+		if v.ListMode != "threshold" && v.Threshold != 0 {
+			return fmt.Errorf("execgroup %s: the threshold field requires the threshold mode", v.TagName)
 		}
 		for _, k := range v.KeyFPs {
 			decoded, err := hex.DecodeString(k)
@@ -129,129 +156,302 @@ func (ecl *EclConfig) ValidateConfig() error {
 				return fmt.Errorf("expecting a 40 chars hex fingerprint string")
 			}
 		}
+		es, err := v.loadEntities()
+		if err != nil {
+			return fmt.Errorf("execgroup %s: %w", v.TagName, err)
+		}
+		if v.ListMode == "threshold" {
+			keys, err := v.keys(es)
+			if err != nil {
+				return fmt.Errorf("execgroup %s: %w", v.TagName, err)
+			}
+			if n := countKeys(keys); v.Threshold < 1 || v.Threshold > n {
+				return fmt.Errorf("execgroup %s: the threshold field must be between 1 and the number of distinct keys (%d)", v.TagName, n)
+			}
+		}
 	}
 
 	return nil
 }
 
-// checkWhiteList evaluates authorization by requiring at least 1 entity
-func checkWhiteList(v *integrity.Verifier, egroup *Execgroup) (ok bool, err error) {
-	// get signing entities fingerprints that have signed all selected objects
-	keyfps, err := v.AllSignedBy()
-	if err != nil {
-		return
+// signers holds the identities of the entities that signed an image. PGP
+// entities are identified by fingerprint, and other entities by the path to
+// their certificate or public key.
+type signers struct {
+	all []string // entities that have signed all selected objects
+	any []string // entities that have signed any selected object
+}
+
+// ids returns the identities of the entities of egroup.
+func (egroup *Execgroup) ids(es []signingentity.Entity) []string {
+	ids := slices.Clone(egroup.KeyFPs)
+	for _, e := range es {
+		ids = append(ids, e.Name)
 	}
+	return ids
+}
 
-	// were the selected objects signed by an authorized entity?
-	for _, v := range egroup.KeyFPs {
-		for _, u := range keyfps {
-			if strings.EqualFold(v, hex.EncodeToString(u[:])) {
-				ok = true
-			}
+// keys returns the identity of the public key of each entity of egroup, keyed
+// by the identity of the entity. PGP keys are identified by their fingerprint,
+// and certificates and cosign keys by the fingerprint of their public key, so
+// that a key held in more than one file is a single entity.
+func (egroup *Execgroup) keys(es []signingentity.Entity) (map[string]string, error) {
+	keys := make(map[string]string, len(egroup.KeyFPs)+len(es))
+	for _, fp := range egroup.KeyFPs {
+		keys[fp] = strings.ToLower(fp)
+	}
+	for _, e := range es {
+		fp, err := e.KeyFingerprint()
+		if err != nil {
+			return nil, err
 		}
+		keys[e.Name] = fp
 	}
+	return keys, nil
+}
 
-	if !ok {
-		return false, errNotSignedByRequired
+// countKeys returns the number of distinct keys in keys.
+func countKeys(keys map[string]string) int {
+	distinct := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		distinct[k] = true
 	}
-
-	return true, nil
+	return len(distinct)
 }
 
-// checkWhiteStrict evaluates authorization by requiring all entities
-func checkWhiteStrict(v *integrity.Verifier, egroup *Execgroup) (ok bool, err error) {
-	// get signing entities fingerprints that have signed all selected objects
-	keyfps, err := v.AllSignedBy()
-	if err != nil {
-		return
+// checkWhiteList evaluates authorization by requiring at least 1 entity
+func checkWhiteList(s signers, ids []string) (ok bool, err error) {
+	// were the selected objects signed by an authorized entity?
+	for _, v := range ids {
+		if containsFold(s.all, v) {
+			return true, nil
+		}
 	}
 
+	return false, errNotSignedByRequired
+}
+
+// checkWhiteStrict evaluates authorization by requiring all entities
+func checkWhiteStrict(s signers, ids []string) (ok bool, err error) {
 	// were all selected objects signed by all authorized entity?
-	m := map[string]bool{}
-	for _, v := range egroup.KeyFPs {
-		m[v] = false
-		for _, u := range keyfps {
-			if strings.EqualFold(v, hex.EncodeToString(u[:])) {
-				m[v] = true
-			}
+	for _, v := range ids {
+		if !containsFold(s.all, v) {
+			return false, errNotSignedByRequired
 		}
 	}
 
-	for _, v := range m {
-		if !v {
-			return false, errNotSignedByRequired
+	return true, nil
+}
+
+// checkThreshold evaluates authorization by requiring at least n distinct
+// entities. Entities are distinguished by their public key, as identified in
+// keys, so that a key held in more than one file is counted once.
+func checkThreshold(s signers, ids []string, keys map[string]string, n int) (ok bool, err error) {
+	// were all selected objects signed by enough authorized entities?
+	signed := map[string]string{}
+	for _, v := range ids {
+		if containsFold(s.all, v) {
+			signed[v] = keys[v]
 		}
 	}
+	if c := countKeys(signed); c < n {
+		return false, fmt.Errorf("%w: signed by %d entities, %d required", errNotSignedByRequired, c, n)
+	}
 
 	return true, nil
 }
 
 // checkBlackList evaluates authorization by requiring all entities to be absent
-func checkBlackList(v *integrity.Verifier, egroup *Execgroup) (ok bool, err error) {
-	// get all signing entities fingerprints that have signed any selected object
-	keyfps, err := v.AnySignedBy()
-	if err != nil {
-		return
-	}
-
+func checkBlackList(s signers, ids []string) (ok bool, err error) {
 	// was a selected object signed by a forbidden entity?
-	for _, v := range egroup.KeyFPs {
-		for _, u := range keyfps {
-			if strings.EqualFold(v, hex.EncodeToString(u[:])) {
-				return false, errSignedByForbidden
-			}
+	for _, v := range ids {
+		if containsFold(s.any, v) {
+			return false, errSignedByForbidden
 		}
 	}
 
 	return true, nil
 }
 
-func shouldRun(ctx context.Context, ecl *EclConfig, fp *os.File, kr openpgp.KeyRing) (ok bool, err error) {
-	egroup := getExecGroup(ecl, fp)
-	if egroup == nil {
-		return false, fmt.Errorf("%s not part of any execgroup", fp.Name())
+func containsFold(s []string, v string) bool {
+	return slices.ContainsFunc(s, func(u string) bool { return strings.EqualFold(u, v) })
+}
+
+// counts returns the entities of es that may be counted as signers of an image
+// by egroup. Entities that are not trusted are only counted by a blacklist.
+func (egroup *Execgroup) counts(es []signingentity.Entity) []signingentity.Entity {
+	if egroup.ListMode == "blacklist" {
+		return es
 	}
+	return slices.DeleteFunc(slices.Clone(es), func(e signingentity.Entity) bool {
+		if e.Err != nil {
+			sylog.Warningf("Certificate %s not trusted by execgroup %s: %v", e.Name, egroup.TagName, e.Err)
+		}
+		return e.Err != nil
+	})
+}
 
-	f, err := sif.LoadContainer(fp,
-		sif.OptLoadWithFlag(os.O_RDONLY),
-		sif.OptLoadWithCloseOnUnload(false),
-	)
-	if err != nil {
-		return false, err
+// sifSigners verifies the native SIF f, returning the entities that signed it.
+func sifSigners(ctx context.Context, ecl *EclConfig, f *sif.FileImage, kr openpgp.KeyRing, es []signingentity.Entity) (s signers, err error) {
+	type task struct {
+		id      uint32
+		isGroup bool
 	}
-	defer f.UnloadContainer()
+	signedBy := map[task]map[string]bool{}
 
 	opts := []integrity.VerifierOpt{
 		integrity.OptVerifyWithContext(ctx),
 		integrity.OptVerifyWithKeyRing(kr),
+		integrity.OptVerifyCallback(func(r integrity.VerifyResult) bool {
+			if r.Error() != nil {
+				return false
+			}
+			id, isGroup := r.Signature().LinkedID()
+			t := task{id, isGroup}
+			if signedBy[t] == nil {
+				signedBy[t] = map[string]bool{}
+			}
+			for _, pub := range r.Keys() {
+				for _, e := range es {
+					if e.Signs(pub) {
+						signedBy[t][e.Name] = true
+					}
+				}
+			}
+			return false
+		}),
+	}
+	for _, e := range es {
+		opts = append(opts, integrity.OptVerifyWithVerifier(e.Verifier))
 	}
 	if ecl.Legacy {
 		// Legacy behavior is to verify the primary partition only.
 		od, err := f.GetDescriptor(sif.WithPartitionType(sif.PartPrimSys))
 		if err != nil {
-			return false, fmt.Errorf("get primary system partition: %v", err)
+			return s, fmt.Errorf("get primary system partition: %v", err)
 		}
 		opts = append(opts, integrity.OptVerifyLegacy(), integrity.OptVerifyObject(od.ID()))
 	}
 
 	v, err := integrity.NewVerifier(f, opts...)
 	if err != nil {
-		return false, err
+		return s, err
 	}
 
 	// Validate signature.
 	if err := v.Verify(); err != nil {
-		return false, fmt.Errorf("image signature not valid: %v", err)
+		return s, fmt.Errorf("image signature not valid: %v", err)
+	}
+
+	// get signing entities fingerprints that have signed all, or any, selected objects
+	all, err := v.AllSignedBy()
+	if err != nil {
+		return s, err
 	}
+	for _, fp := range all {
+		s.all = append(s.all, hex.EncodeToString(fp))
+	}
+	anyFPs, err := v.AnySignedBy()
+	if err != nil {
+		return s, err
+	}
+	for _, fp := range anyFPs {
+		s.any = append(s.any, hex.EncodeToString(fp))
+	}
+
+	for _, e := range es {
+		n := 0
+		for _, names := range signedBy {
+			if names[e.Name] {
+				n++
+			}
+		}
+		if n > 0 {
+			s.any = append(s.any, e.Name)
+		}
+		if n > 0 && n == len(signedBy) {
+			s.all = append(s.all, e.Name)
+		}
+	}
+
+	return s, nil
+}
+
+// ociSIFSigners verifies the cosign signatures of the OCI-SIF at path,
+// returning the entities that signed it. OCI-SIF images do not carry PGP
+// signatures.
+func ociSIFSigners(ctx context.Context, path string, es []signingentity.Entity) (s signers, err error) {
+	if len(es) == 0 {
+		return s, nil
+	}
+
+	vs := make([]signature.Verifier, 0, len(es))
+	for _, e := range es {
+		vs = append(vs, e.Verifier)
+	}
+	signed, err := cosign.SignedBy(ctx, path, vs)
+	if err != nil {
+		return s, fmt.Errorf("image signature not valid: %v", err)
+	}
+	for i, e := range es {
+		if signed[i] {
+			s.all = append(s.all, e.Name)
+		}
+	}
+	s.any = s.all
+	return s, nil
+}
 
-	// Check fingerprints against policy.
+// isOCISIF returns true if f is an OCI-SIF image.
+func isOCISIF(f *sif.FileImage) bool {
+	_, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex))
+	return err == nil
+}
+
+func shouldRun(ctx context.Context, ecl *EclConfig, fp *os.File, kr openpgp.KeyRing) (ok bool, err error) {
+	egroup := getExecGroup(ecl, fp)
+	if egroup == nil {
+		return false, fmt.Errorf("%s not part of any execgroup", fp.Name())
+	}
+
+	es, err := egroup.loadEntities()
+	if err != nil {
+		return false, err
+	}
+
+	f, err := sif.LoadContainer(fp,
+		sif.OptLoadWithFlag(os.O_RDONLY),
+		sif.OptLoadWithCloseOnUnload(false),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer f.UnloadContainer()
+
+	var s signers
+	if isOCISIF(f) {
+		s, err = ociSIFSigners(ctx, fp.Name(), egroup.counts(es))
+	} else {
+		s, err = sifSigners(ctx, ecl, f, kr, egroup.counts(es))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Check signing entities against policy.
 	switch egroup.ListMode {
 	case "whitelist":
-		return checkWhiteList(v, egroup)
+		return checkWhiteList(s, egroup.ids(es))
 	case "whitestrict":
-		return checkWhiteStrict(v, egroup)
+		return checkWhiteStrict(s, egroup.ids(es))
+	case "threshold":
+		keys, err := egroup.keys(es)
+		if err != nil {
+			return false, err
+		}
+		return checkThreshold(s, egroup.ids(es), keys, egroup.Threshold)
 	case "blacklist":
-		return checkBlackList(v, egroup)
+		return checkBlackList(s, egroup.ids(es))
 	}
 
 	return false, fmt.Errorf("ecl config file invalid")
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
@@ -229,6 +230,13 @@ func TestValidateConfig(t *testing.T) {
 		DirPath:  dirPath,
 		KeyFPs:   []string{KeyFP1},
 	}
+	th := Execgroup{
+		TagName:   "name",
+		ListMode:  "threshold",
+		Threshold: 2,
+		DirPath:   dirPath,
+		KeyFPs:    []string{KeyFP1, KeyFP2},
+	}
 
 	tests := []struct {
 		name    string
@@ -257,6 +265,55 @@ func TestValidateConfig(t *testing.T) {
 			}},
 			wantErr: true,
 		},
+		{
+			name: "ThresholdMissing",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{ListMode: "threshold", KeyFPs: []string{KeyFP1}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "ThresholdTooLarge",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{ListMode: "threshold", Threshold: 3, KeyFPs: []string{KeyFP1, KeyFP2}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "ThresholdDuplicateKey",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{
+					ListMode:          "threshold",
+					Threshold:         2,
+					KeyFPs:            []string{KeyFP1, strings.ToLower(KeyFP1)},
+					Certificates:      []string{testCert("leaf.pem")},
+					CertRoots:         testCert("root.pem"),
+					CertIntermediates: testCert("intermediate.pem"),
+				},
+			}},
+		},
+		{
+			name: "ThresholdDuplicateKeyTooLarge",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{
+					ListMode:          "threshold",
+					Threshold:         3,
+					KeyFPs:            []string{KeyFP1},
+					Certificates:      []string{testCert("leaf.pem")},
+					CertRoots:         testCert("root.pem"),
+					CertIntermediates: testCert("intermediate.pem"),
+					CosignKeys:        []string{testKey("rsa-public.pem")},
+				},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "ThresholdWrongMode",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{ListMode: "whitelist", Threshold: 1, KeyFPs: []string{KeyFP1}},
+			}},
+			wantErr: true,
+		},
 		{
 			name: "BadFingerprint",
 			c: EclConfig{ExecGroups: []Execgroup{
@@ -264,6 +321,34 @@ func TestValidateConfig(t *testing.T) {
 			}},
 			wantErr: true,
 		},
+		{
+			name: "CertRootsMissing",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{ListMode: "whitelist", Certificates: []string{testCert("leaf.pem")}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "CertificateMissing",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{ListMode: "whitelist", CertRoots: testCert("root.pem")},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "BadCertificate",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{ListMode: "whitelist", Certificates: []string{testKey("rsa-public.pem")}, CertRoots: testCert("root.pem")},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "BadCosignKey",
+			c: EclConfig{ExecGroups: []Execgroup{
+				{ListMode: "whitelist", CosignKeys: []string{testCert("missing.pem")}},
+			}},
+			wantErr: true,
+		},
 		{
 			name: "Deactivated",
 			c:    EclConfig{Activated: false},
@@ -304,6 +389,20 @@ func TestValidateConfig(t *testing.T) {
 			name: "BlackListLegacy",
 			c:    EclConfig{Activated: true, Legacy: true, ExecGroups: []Execgroup{bl}},
 		},
+		{
+			name: "Threshold",
+			c:    EclConfig{Activated: true, ExecGroups: []Execgroup{th}},
+		},
+		{
+			name: "Entities",
+			c: EclConfig{Activated: true, ExecGroups: []Execgroup{{
+				ListMode:          "whitelist",
+				Certificates:      []string{testCert("leaf.pem")},
+				CertRoots:         testCert("root.pem"),
+				CertIntermediates: testCert("intermediate.pem"),
+				CosignKeys:        []string{testKey("cosign.pub")},
+			}}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -315,6 +414,16 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+// testCert returns the path to the test certificate file name.
+func testCert(name string) string {
+	return filepath.Join("..", "..", "..", "test", "certs", name)
+}
+
+// testKey returns the path to the test key file name.
+func testKey(name string) string {
+	return filepath.Join("..", "..", "..", "test", "keys", name)
+}
+
 // getTestEntity returns a fixed test PGP entity.
 func getTestEntity(t *testing.T) *openpgp.Entity {
 	t.Helper()
@@ -372,6 +481,18 @@ func TestShouldRun(t *testing.T) {
 		DirPath:  dirPath,
 		KeyFPs:   []string{KeyFP2},
 	}
+	th1 := Execgroup{
+		ListMode:  "threshold",
+		Threshold: 1,
+		DirPath:   dirPath,
+		KeyFPs:    []string{KeyFP1, KeyFP2},
+	}
+	th2 := Execgroup{
+		ListMode:  "threshold",
+		Threshold: 2,
+		DirPath:   dirPath,
+		KeyFPs:    []string{KeyFP1, strings.ToLower(KeyFP1), KeyFP2},
+	}
 	bl1 := Execgroup{
 		ListMode: "blacklist",
 		DirPath:  dirPath,
@@ -404,6 +525,8 @@ func TestShouldRun(t *testing.T) {
 		{"WhitelistError", true, false, wl2, signed, true},
 		{"WhitestrictOK", true, false, ws1, signed, false},
 		{"WhitestrictError", true, false, ws2, signed, true},
+		{"ThresholdOK", true, false, th1, signed, false},
+		{"ThresholdError", true, false, th2, signed, true},
 		{"BlacklistOK", true, false, bl2, signed, false},
 		{"BlacklistError", true, false, bl1, signed, true},
 		{"LegacyDeactivated", false, true, Execgroup{}, unsigned, false},
@@ -413,6 +536,8 @@ func TestShouldRun(t *testing.T) {
 		{"LegacyWhitelistError", true, true, wl2, legacySigned, true},
 		{"LegacyWhitestrictOK", true, true, ws1, legacySigned, false},
 		{"LegacyWhitestrictError", true, true, ws2, legacySigned, true},
+		{"LegacyThresholdOK", true, true, th1, legacySigned, false},
+		{"LegacyThresholdError", true, true, th2, legacySigned, true},
 		{"LegacyBlacklistOK", true, true, bl2, legacySigned, false},
 		{"LegacyBlacklistError", true, true, bl1, legacySigned, true},
 	}
@@ -455,3 +580,112 @@ func TestShouldRun(t *testing.T) {
 		})
 	}
 }
+
+func TestShouldRunEntities(t *testing.T) {
+	dirPath, err := filepath.Abs(filepath.Join("..", "..", "..", "test", "images"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The DSSE image is signed with the ed25519 and rsa test keys. The leaf
+	// certificate holds the rsa public key.
+	signed := filepath.Join(dirPath, "one-group-signed-dsse.sif")
+
+	leaf := Execgroup{
+		DirPath:           dirPath,
+		Certificates:      []string{testCert("leaf.pem")},
+		CertRoots:         testCert("root.pem"),
+		CertIntermediates: testCert("intermediate.pem"),
+	}
+	withMode := func(eg Execgroup, mode string) Execgroup {
+		eg.ListMode = mode
+		return eg
+	}
+	with := func(eg Execgroup, f func(eg *Execgroup)) Execgroup {
+		f(&eg)
+		return eg
+	}
+
+	tests := []struct {
+		name    string
+		eg      Execgroup
+		wantErr bool
+	}{
+		{"CertificateWhitelistOK", withMode(leaf, "whitelist"), false},
+		{"CertificateWhitestrictOK", withMode(leaf, "whitestrict"), false},
+		{"CertificateBlacklistError", withMode(leaf, "blacklist"), true},
+		{"CertificateSubjectOK", with(withMode(leaf, "whitelist"), func(eg *Execgroup) {
+			eg.CertSubjects = []string{"leaf"}
+		}), false},
+		{"CertificateSubjectError", with(withMode(leaf, "whitelist"), func(eg *Execgroup) {
+			eg.CertSubjects = []string{"other"}
+		}), true},
+		{"CertificateSANError", with(withMode(leaf, "whitelist"), func(eg *Execgroup) {
+			eg.CertSANs = []string{"signer@example.com"}
+		}), true},
+		{"CertificateUntrustedWhitelistError", with(withMode(leaf, "whitelist"), func(eg *Execgroup) {
+			eg.CertIntermediates = ""
+		}), true},
+		{"CertificateUntrustedBlacklistError", with(withMode(leaf, "blacklist"), func(eg *Execgroup) {
+			eg.CertIntermediates = ""
+		}), true},
+		{"CosignKeyWhitelistOK", Execgroup{
+			ListMode:   "whitelist",
+			DirPath:    dirPath,
+			CosignKeys: []string{testKey("ed25519-public.pem")},
+		}, false},
+		{"CosignKeyWhitestrictError", with(withMode(leaf, "whitestrict"), func(eg *Execgroup) {
+			eg.CosignKeys = []string{testKey("ed25519-public.pem"), testKey("ecdsa-public.pem")}
+		}), true},
+		{"ThresholdOK", with(withMode(leaf, "threshold"), func(eg *Execgroup) {
+			eg.Threshold = 2
+			eg.CosignKeys = []string{testKey("ed25519-public.pem"), testKey("ecdsa-public.pem")}
+		}), false},
+		{"ThresholdError", with(withMode(leaf, "threshold"), func(eg *Execgroup) {
+			eg.Threshold = 3
+			eg.CosignKeys = []string{testKey("ed25519-public.pem"), testKey("ecdsa-public.pem")}
+		}), true},
+		// The leaf certificate holds the rsa public key, so the two are a
+		// single entity.
+		{"ThresholdDuplicateKeyError", with(withMode(leaf, "threshold"), func(eg *Execgroup) {
+			eg.Threshold = 2
+			eg.CosignKeys = []string{testKey("rsa-public.pem")}
+		}), true},
+		{"ThresholdUntrustedError", with(withMode(leaf, "threshold"), func(eg *Execgroup) {
+			eg.Threshold = 2
+			eg.CertIntermediates = ""
+			eg.CosignKeys = []string{testKey("ed25519-public.pem"), testKey("ecdsa-public.pem")}
+		}), true},
+		{"CosignKeyBlacklistError", Execgroup{
+			ListMode:   "blacklist",
+			DirPath:    dirPath,
+			CosignKeys: []string{testKey("ed25519-public.pem")},
+		}, true},
+		// All signatures must be verified, so a blacklist of an entity that did
+		// not sign the image cannot verify the image.
+		{"CosignKeyBlacklistUnverified", Execgroup{
+			ListMode:   "blacklist",
+			DirPath:    dirPath,
+			CosignKeys: []string{testKey("ecdsa-public.pem")},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := EclConfig{
+				Activated:  true,
+				ExecGroups: []Execgroup{tt.eg},
+			}
+
+			got, err := c.ShouldRun(t.Context(), signed, openpgp.EntityList{getTestEntity(t)})
+
+			if want := !tt.wantErr; got != want {
+				t.Errorf("got run %v, want %v", got, want)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got err %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sypgp
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var (
+	errKeyNotFound    = errors.New("no key matching given fingerprint found")
+	errSubkeyNotFound = errors.New("no subkey matching given key ID or fingerprint found")
+	errBadExpiry      = errors.New("key expiry must be after the creation of the key")
+	errBadReason      = errors.New("revocation reason must be one of unspecified, superseded, retired, compromised")
+	errNotRevocation  = errors.New("not a key revocation certificate")
+)
+
+// PassphraseFunc returns the passphrase protecting a private key. It is only
+// called if the key is encrypted.
+type PassphraseFunc func() (string, error)
+
+// RevocationReasons maps the revocation reasons accepted by ParseRevocationReason to their
+// OpenPGP reason codes.
+var RevocationReasons = map[string]packet.ReasonForRevocation{
+	"unspecified": packet.NoReason,
+	"superseded":  packet.KeySuperseded,
+	"compromised": packet.KeyCompromised,
+	"retired":     packet.KeyRetired,
+}
+
+// ParseRevocationReason returns the OpenPGP reason code corresponding to s.
+func ParseRevocationReason(s string) (packet.ReasonForRevocation, error) {
+	r, ok := RevocationReasons[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", errBadReason, s)
+	}
+	return r, nil
+}
+
+// lifetimeSecs returns the lifetime in seconds of a key created at created,
+// that expires at expiry. A zero expiry indicates a key that does not
+// expire.
+func lifetimeSecs(created, expiry time.Time) (uint32, error) {
+	if expiry.IsZero() {
+		return 0, nil
+	}
+
+	secs := expiry.Unix() - created.Unix()
+	if secs <= 0 || secs > math.MaxUint32 {
+		return 0, errBadExpiry
+	}
+	return uint32(secs), nil
+}
+
+// unlockPrivKey returns the private keyring, and the private key in it
+// matching fingerprint. If the private key is encrypted, it is decrypted with
+// the passphrase returned by pass, which is also returned.
+func (keyring *Handle) unlockPrivKey(fingerprint string, pass PassphraseFunc) (openpgp.EntityList, *openpgp.Entity, []byte, error) {
+	if keyring.global {
+		return nil, nil, nil, fmt.Errorf("global keyring only holds public keys")
+	}
+
+	el, err := keyring.LoadPrivKeyring()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to list local keyring: %v", err)
+	}
+
+	e := findKeyByFingerprint(el, fingerprint)
+	if e == nil {
+		return nil, nil, nil, errKeyNotFound
+	}
+
+	var passphrase []byte
+	if e.PrivateKey.Encrypted {
+		p, err := pass()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		passphrase = []byte(p)
+
+		if err := e.DecryptPrivateKeys(passphrase); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return el, e, passphrase, nil
+}
+
+// editPrivKey applies edit to the private key matching fingerprint, and
+// stores the result in both the private and public keyrings. If the private
+// key is encrypted, it is decrypted with the passphrase returned by pass
+// before edit is called, and encrypted again afterwards.
+func (keyring *Handle) editPrivKey(fingerprint string, pass PassphraseFunc, edit func(*openpgp.Entity) error) (*openpgp.Entity, error) {
+	el, e, passphrase, err := keyring.unlockPrivKey(fingerprint, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := edit(e); err != nil {
+		return nil, err
+	}
+
+	if passphrase != nil {
+		if err := e.EncryptPrivateKeys(passphrase, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	sylog.Verbosef("Updating local keyring: %v", keyring.SecretPath())
+
+	if err := keyring.storePrivKeyring(el); err != nil {
+		return nil, err
+	}
+
+	// Replace the public key, if present, so that the change is seen when
+	// verifying.
+	pl, err := keyring.LoadPubKeyring()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list local keyring: %v", err)
+	}
+	for i, pe := range pl {
+		if compareKeyEntity(pe, fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)) {
+			pl[i] = e
+
+			sylog.Verbosef("Updating local keyring: %v", keyring.PublicPath())
+
+			if err := keyring.storePubKeyring(pl); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	return e, nil
+}
+
+// SetKeyExpiry sets the private key matching fingerprint, and its subkeys, to
+// expire at expiry. A zero expiry indicates a key that does not expire. The
+// passphrase of the key is obtained from pass, if needed.
+func (keyring *Handle) SetKeyExpiry(fingerprint string, expiry time.Time, pass PassphraseFunc) (*openpgp.Entity, error) {
+	return keyring.editPrivKey(fingerprint, pass, func(e *openpgp.Entity) error {
+		secs, err := lifetimeSecs(e.PrimaryKey.CreationTime, expiry)
+		if err != nil {
+			return err
+		}
+		return setExpiry(e, secs, nil)
+	})
+}
+
+// setExpiry replaces the self-signature of each identity of e with one that
+// sets the key lifetime to secs, and the binding signature of each subkey of
+// e with one that sets the subkey to expire with the primary key.
+func setExpiry(e *openpgp.Entity, secs uint32, config *packet.Config) error {
+	for _, id := range e.Identities {
+		if id.SelfSignature == nil {
+			continue
+		}
+
+		sig := *id.SelfSignature
+		sig.CreationTime = config.Now()
+		sig.KeyLifetimeSecs = &secs
+		if err := sig.SignUserId(id.UserId.Id, e.PrimaryKey, e.PrivateKey, config); err != nil {
+			return err
+		}
+
+		// Drop the superseded self-signature, so that the new one is
+		// selected even if both were created in the same second.
+		sigs := make([]*packet.Signature, 0, len(id.Signatures))
+		for _, s := range id.Signatures {
+			if s != id.SelfSignature {
+				sigs = append(sigs, s)
+			}
+		}
+		id.Signatures = append(sigs, &sig)
+		id.SelfSignature = &sig
+	}
+
+	var expiry time.Time
+	if secs != 0 {
+		expiry = e.PrimaryKey.CreationTime.Add(time.Duration(secs) * time.Second)
+	}
+
+	for i := range e.Subkeys {
+		sk := &e.Subkeys[i]
+		if sk.Sig == nil {
+			continue
+		}
+
+		skSecs, err := lifetimeSecs(sk.PublicKey.CreationTime, expiry)
+		if err != nil {
+			return fmt.Errorf("subkey %016X: %w", sk.PublicKey.KeyId, err)
+		}
+
+		// The embedded signature of a signing subkey binds the subkey to
+		// the primary key, and is carried over unchanged.
+		sig := *sk.Sig
+		sig.CreationTime = config.Now()
+		sig.KeyLifetimeSecs = &skSecs
+		if err := sig.SignKey(sk.PublicKey, e.PrivateKey, config); err != nil {
+			return err
+		}
+		sk.Sig = &sig
+	}
+
+	return nil
+}
+
+// AddSigningSubkey generates a new signing subkey for the private key
+// matching fingerprint, that expires at expiry. A zero expiry indicates a
+// subkey that does not expire. As the newest signing subkey, it is used for
+// subsequent signatures. The passphrase of the key is obtained from pass, if
+// needed.
+func (keyring *Handle) AddSigningSubkey(fingerprint string, expiry time.Time, pass PassphraseFunc) (*openpgp.Entity, error) {
+	now := time.Now()
+	secs, err := lifetimeSecs(now, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring.editPrivKey(fingerprint, pass, func(e *openpgp.Entity) error {
+		config := &packet.Config{
+			DefaultHash:     crypto.SHA384,
+			KeyLifetimeSecs: secs,
+			Time:            func() time.Time { return now },
+		}
+
+		// Match the strength of the primary key, where possible.
+		if e.PrimaryKey.PubKeyAlgo == packet.PubKeyAlgoRSA {
+			bits, err := e.PrimaryKey.BitLength()
+			if err != nil {
+				return err
+			}
+			config.Algorithm = packet.PubKeyAlgoRSA
+			config.RSABits = int(bits)
+		}
+
+		return e.AddSigningSubkey(config)
+	})
+}
+
+// findSubkey returns the subkey of e matching the key ID (16 characters) or
+// fingerprint (40 characters) id.
+func findSubkey(e *openpgp.Entity, id string) (*openpgp.Subkey, error) {
+	id = strings.ToUpper(strings.TrimPrefix(id, "0x"))
+	for i, sk := range e.Subkeys {
+		if fmt.Sprintf("%X", sk.PublicKey.Fingerprint) == id || fmt.Sprintf("%016X", sk.PublicKey.KeyId) == id {
+			return &e.Subkeys[i], nil
+		}
+	}
+	return nil, errSubkeyNotFound
+}
+
+// RevokeKey revokes the private key matching fingerprint for reason, with
+// an optional explanation text. If subkey is not empty, only the subkey of
+// the key with that key ID or fingerprint is revoked. The passphrase of the
+// key is obtained from pass, if needed.
+//
+// Signatures created before the revocation remain valid, unless reason is
+// packet.KeyCompromised or packet.NoReason.
+func (keyring *Handle) RevokeKey(fingerprint, subkey string, reason packet.ReasonForRevocation, text string, pass PassphraseFunc) (*openpgp.Entity, error) {
+	return keyring.editPrivKey(fingerprint, pass, func(e *openpgp.Entity) error {
+		if subkey == "" {
+			return e.RevokeKey(reason, text, nil)
+		}
+
+		sk, err := findSubkey(e, subkey)
+		if err != nil {
+			return err
+		}
+		return e.RevokeSubkey(sk, reason, text, nil)
+	})
+}
+
+// RevocationCertificate returns an armored revocation certificate for the
+// private key matching fingerprint, for reason, with an optional explanation
+// text. The keyring is not modified, so that the certificate can be stored
+// safely, and imported later to revoke the key, for example if the private
+// key is lost. The passphrase of the key is obtained from pass, if needed.
+func (keyring *Handle) RevocationCertificate(fingerprint string, reason packet.ReasonForRevocation, text string, pass PassphraseFunc) ([]byte, error) {
+	_, e, _, err := keyring.unlockPrivKey(fingerprint, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.RevokeKey(reason, text, nil); err != nil {
+		return nil, err
+	}
+	sig := e.Revocations[len(e.Revocations)-1]
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, map[string]string{
+		"Comment": fmt.Sprintf("Revocation certificate for %X", e.PrimaryKey.Fingerprint),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := sig.Serialize(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// importRevocationCertificate adds the key revocation signature held in the
+// revocation certificate at path, as written by RevocationCertificate, to the
+// matching key in the public keyring, and in the private keyring if present.
+// The revoked public key is returned.
+func (keyring *Handle) importRevocationCertificate(path string) (*openpgp.Entity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	block, err := armor.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotRevocation, err)
+	}
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotRevocation, err)
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok || sig.SigType != packet.SigTypeKeyRevocation || sig.IssuerKeyId == nil {
+		return nil, errNotRevocation
+	}
+
+	// revoke adds sig to the key of el it was issued by, if it is a valid
+	// revocation of that key.
+	revoke := func(el openpgp.EntityList) (*openpgp.Entity, error) {
+		for _, e := range el {
+			if e.PrimaryKey.KeyId != *sig.IssuerKeyId {
+				continue
+			}
+			if err := e.PrimaryKey.VerifyRevocationSignature(sig); err != nil {
+				return nil, fmt.Errorf("invalid revocation certificate: %w", err)
+			}
+			e.Revocations = append(e.Revocations, sig)
+			return e, nil
+		}
+		return nil, errKeyNotFound
+	}
+
+	pl, err := keyring.LoadPubKeyring()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list local keyring: %v", err)
+	}
+	e, err := revoke(pl)
+	if err != nil {
+		return nil, err
+	}
+
+	if !keyring.global {
+		el, err := keyring.LoadPrivKeyring()
+		if err != nil {
+			return nil, fmt.Errorf("unable to list local keyring: %v", err)
+		}
+		if _, err := revoke(el); err == nil {
+			sylog.Verbosef("Updating local keyring: %v", keyring.SecretPath())
+
+			if err := keyring.storePrivKeyring(el); err != nil {
+				return nil, err
+			}
+		} else if !errors.Is(err, errKeyNotFound) {
+			return nil, err
+		}
+	}
+
+	sylog.Verbosef("Updating local keyring: %v", keyring.PublicPath())
+
+	if err := keyring.storePubKeyring(pl); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SigningEntity returns an entity that produces signatures with the newest
+// valid signing subkey of e, if there is one. Otherwise, e is returned.
+func SigningEntity(e *openpgp.Entity) *openpgp.Entity {
+	k, ok := e.SigningKey(time.Now())
+	if !ok || k.PrivateKey == nil || k.PrivateKey == e.PrivateKey {
+		return e
+	}
+
+	se := *e
+	se.PrivateKey = k.PrivateKey
+	return &se
+}
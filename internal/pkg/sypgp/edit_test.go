@@ -0,0 +1,289 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sypgp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// newTestKeyring returns a keyring in a temporary directory, holding a key
+// pair protected by password, and the fingerprint of the key pair.
+func newTestKeyring(t *testing.T, password string) (*Handle, string) {
+	t.Helper()
+
+	keyring := NewHandle(t.TempDir())
+
+	e, err := keyring.GenKeyPair(GenKeyPairOptions{
+		Name:      "test",
+		Email:     "test@test.com",
+		Password:  password,
+		KeyLength: 2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring, fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+}
+
+// loadTestKeys returns the private and public keys matching fingerprint
+// from keyring.
+func loadTestKeys(t *testing.T, keyring *Handle, fingerprint string) (priv, pub *openpgp.Entity) {
+	t.Helper()
+
+	el, err := keyring.LoadPrivKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priv = findKeyByFingerprint(el, fingerprint); priv == nil || priv.PrivateKey == nil {
+		t.Fatalf("private key %v not found", fingerprint)
+	}
+
+	el, err = keyring.LoadPubKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub = findKeyByFingerprint(el, fingerprint); pub == nil {
+		t.Fatalf("public key %v not found", fingerprint)
+	}
+
+	return priv, pub
+}
+
+func TestSetKeyExpiry(t *testing.T) {
+	keyring, fp := newTestKeyring(t, "1234")
+
+	pass := func() (string, error) { return "1234", nil }
+	badPass := func() (string, error) { return "5678", nil }
+
+	expiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	if _, err := keyring.SetKeyExpiry(fp, expiry, badPass); err == nil {
+		t.Errorf("unexpected success with wrong passphrase")
+	}
+
+	if _, err := keyring.SetKeyExpiry("0123456789ABCDEF0123456789ABCDEF01234567", expiry, pass); !errors.Is(err, errKeyNotFound) {
+		t.Errorf("got error %v, want %v", err, errKeyNotFound)
+	}
+
+	if _, err := keyring.SetKeyExpiry(fp, time.Unix(0, 0), pass); !errors.Is(err, errBadExpiry) {
+		t.Errorf("got error %v, want %v", err, errBadExpiry)
+	}
+
+	if _, err := keyring.SetKeyExpiry(fp, expiry, pass); err != nil {
+		t.Fatal(err)
+	}
+
+	priv, pub := loadTestKeys(t, keyring, fp)
+	if !priv.PrivateKey.Encrypted {
+		t.Errorf("private key not encrypted")
+	}
+
+	for _, e := range []*openpgp.Entity{priv, pub} {
+		sig, _ := e.PrimarySelfSignature()
+		if e.PrimaryKey.KeyExpired(sig, expiry.Add(-time.Second)) {
+			t.Errorf("key expired before %v", expiry)
+		}
+		if !e.PrimaryKey.KeyExpired(sig, expiry.Add(time.Second)) {
+			t.Errorf("key not expired after %v", expiry)
+		}
+
+		// Subkeys expire with the primary key.
+		if len(e.Subkeys) == 0 {
+			t.Fatalf("no subkeys")
+		}
+		for _, sk := range e.Subkeys {
+			if err := e.PrimaryKey.VerifyKeySignature(sk.PublicKey, sk.Sig); err != nil {
+				t.Errorf("invalid subkey binding signature: %v", err)
+			}
+			if sk.PublicKey.KeyExpired(sk.Sig, expiry.Add(-time.Second)) {
+				t.Errorf("subkey expired before %v", expiry)
+			}
+			if !sk.PublicKey.KeyExpired(sk.Sig, expiry.Add(time.Second)) {
+				t.Errorf("subkey not expired after %v", expiry)
+			}
+		}
+	}
+
+	// A zero expiry removes the expiry.
+	if _, err := keyring.SetKeyExpiry(fp, time.Time{}, pass); err != nil {
+		t.Fatal(err)
+	}
+
+	_, pub = loadTestKeys(t, keyring, fp)
+	if sig, _ := pub.PrimarySelfSignature(); pub.PrimaryKey.KeyExpired(sig, expiry.AddDate(10, 0, 0)) {
+		t.Errorf("key expired")
+	}
+	for _, sk := range pub.Subkeys {
+		if sk.PublicKey.KeyExpired(sk.Sig, expiry.AddDate(10, 0, 0)) {
+			t.Errorf("subkey expired")
+		}
+	}
+}
+
+func TestAddSigningSubkey(t *testing.T) {
+	keyring, fp := newTestKeyring(t, "")
+
+	pass := func() (string, error) { return "", errors.New("unexpected passphrase prompt") }
+
+	if _, err := keyring.AddSigningSubkey(fp, time.Now().Add(-time.Hour), pass); !errors.Is(err, errBadExpiry) {
+		t.Errorf("got error %v, want %v", err, errBadExpiry)
+	}
+
+	e, err := keyring.AddSigningSubkey(fp, time.Now().AddDate(1, 0, 0), pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := e.Subkeys[len(e.Subkeys)-1].PublicKey.KeyId
+
+	priv, pub := loadTestKeys(t, keyring, fp)
+
+	for _, e := range []*openpgp.Entity{priv, pub} {
+		k, ok := e.SigningKey(time.Now())
+		if !ok {
+			t.Fatalf("no signing key")
+		}
+		if got, want := k.PublicKey.KeyId, id; got != want {
+			t.Errorf("got signing key %X, want %X", got, want)
+		}
+	}
+
+	if got, want := SigningEntity(priv).PrivateKey.KeyId, id; got != want {
+		t.Errorf("got signing entity key %X, want %X", got, want)
+	}
+}
+
+func TestRevokeKey(t *testing.T) {
+	pass := func() (string, error) { return "", errors.New("unexpected passphrase prompt") }
+
+	t.Run("Key", func(t *testing.T) {
+		keyring, fp := newTestKeyring(t, "")
+
+		if _, err := keyring.RevokeKey(fp, "", packet.KeySuperseded, "replaced", pass); err != nil {
+			t.Fatal(err)
+		}
+
+		priv, pub := loadTestKeys(t, keyring, fp)
+		for _, e := range []*openpgp.Entity{priv, pub} {
+			if got, want := len(e.Revocations), 1; got != want {
+				t.Fatalf("got %v revocations, want %v", got, want)
+			}
+			if got, want := *e.Revocations[0].RevocationReason, packet.KeySuperseded; got != want {
+				t.Errorf("got reason %v, want %v", got, want)
+			}
+			if got, want := e.Revocations[0].RevocationReasonText, "replaced"; got != want {
+				t.Errorf("got reason text %q, want %q", got, want)
+			}
+		}
+	})
+
+	t.Run("Subkey", func(t *testing.T) {
+		keyring, fp := newTestKeyring(t, "")
+
+		e, err := keyring.AddSigningSubkey(fp, time.Time{}, pass)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := fmt.Sprintf("%016X", e.Subkeys[len(e.Subkeys)-1].PublicKey.KeyId)
+
+		if _, err := keyring.RevokeKey(fp, "0123456789ABCDEF", packet.KeyRetired, "", pass); !errors.Is(err, errSubkeyNotFound) {
+			t.Errorf("got error %v, want %v", err, errSubkeyNotFound)
+		}
+
+		if _, err := keyring.RevokeKey(fp, id, packet.KeyRetired, "", pass); err != nil {
+			t.Fatal(err)
+		}
+
+		_, pub := loadTestKeys(t, keyring, fp)
+		if len(pub.Revocations) != 0 {
+			t.Errorf("unexpected revocation of primary key")
+		}
+		sk := pub.Subkeys[len(pub.Subkeys)-1]
+		if !sk.Revoked(time.Now()) {
+			t.Errorf("subkey %v not revoked", id)
+		}
+
+		// With the subkey revoked, the primary key is used for signing.
+		if k, ok := pub.SigningKey(time.Now()); !ok || k.PublicKey != pub.PrimaryKey {
+			t.Errorf("primary key not used for signing")
+		}
+	})
+}
+
+func TestRevocationCertificate(t *testing.T) {
+	keyring, fp := newTestKeyring(t, "1234")
+
+	pass := func() (string, error) { return "1234", nil }
+
+	b, err := keyring.RevocationCertificate(fp, packet.KeyCompromised, "lost", pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The keyring is not modified.
+	priv, pub := loadTestKeys(t, keyring, fp)
+	if len(priv.Revocations) != 0 || len(pub.Revocations) != 0 {
+		t.Errorf("unexpected revocation of key")
+	}
+
+	path := filepath.Join(t.TempDir(), "revoke.asc")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A revocation certificate cannot be applied to another key.
+	other, _ := newTestKeyring(t, "")
+	if err := other.ImportKey(path, false); !errors.Is(err, errKeyNotFound) {
+		t.Errorf("got error %v, want %v", err, errKeyNotFound)
+	}
+
+	if err := keyring.ImportKey(path, false); err != nil {
+		t.Fatal(err)
+	}
+
+	priv, pub = loadTestKeys(t, keyring, fp)
+	for _, e := range []*openpgp.Entity{priv, pub} {
+		if !e.Revoked(time.Now()) {
+			t.Fatalf("key not revoked")
+		}
+		if got, want := *e.Revocations[0].RevocationReason, packet.KeyCompromised; got != want {
+			t.Errorf("got reason %v, want %v", got, want)
+		}
+	}
+}
+
+func TestParseRevocationReason(t *testing.T) {
+	tests := []struct {
+		s          string
+		wantReason packet.ReasonForRevocation
+		wantErr    error
+	}{
+		{s: "unspecified", wantReason: packet.NoReason},
+		{s: "Superseded", wantReason: packet.KeySuperseded},
+		{s: "compromised", wantReason: packet.KeyCompromised},
+		{s: "retired", wantReason: packet.KeyRetired},
+		{s: "lost", wantErr: errBadReason},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			r, err := ParseRevocationReason(tt.s)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+			if got, want := r, tt.wantReason; got != want {
+				t.Errorf("got reason %v, want %v", got, want)
+			}
+		})
+	}
+}
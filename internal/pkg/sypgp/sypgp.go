@@ -389,10 +389,8 @@ func (keyring *Handle) storePrivKeyring(keys openpgp.EntityList) error {
 	}
 	defer f.Close()
 
-	for _, k := range keys {
-		if err := k.Serialize(f); err != nil {
-			return fmt.Errorf("could not store private key: %s", err)
-		}
+	if err := storePrivKeys(f, keys); err != nil {
+		return fmt.Errorf("could not store private key: %s", err)
 	}
 
 	return nil
@@ -1062,12 +1060,21 @@ func (keyring *Handle) importPublicKey(entity *openpgp.Entity) error {
 
 // ImportKey imports one or more keys from the specified file. The keys
 // can be either a public or private keys, and the file can be either in
-// binary or ascii-armored format.
+// binary or ascii-armored format. A revocation certificate, as written by
+// RevocationCertificate, is applied to the key it revokes.
 func (keyring *Handle) ImportKey(kpath string, setNewPassword bool) error {
 	// Load the private key as an entitylist
 	pathEntityList, err := loadKeysFromFile(kpath)
 	if err != nil {
-		return fmt.Errorf("unable to get entity from: %s: %v", kpath, err)
+		e, rerr := keyring.importRevocationCertificate(kpath)
+		if errors.Is(rerr, errNotRevocation) {
+			return fmt.Errorf("unable to get entity from: %s: %v", kpath, err)
+		} else if rerr != nil {
+			return rerr
+		}
+
+		fmt.Printf("Key with fingerprint %X successfully revoked\n", e.PrimaryKey.Fingerprint)
+		return nil
 	}
 
 	for _, pathEntity := range pathEntityList {
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2019-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -55,6 +55,9 @@ func FindBin(name string) (path string, err error) {
 	// tar to squashfs tools for OCI-mode image conversion
 	case "tar2sqfs", "sqfstar":
 		return findOnPath(name)
+	// erofs tools for OCI-SIF erofs layers
+	case "mkfs.erofs", "erofsfuse":
+		return findOnPath(name)
 	default:
 		return "", fmt.Errorf("executable name %q is not known to FindBin", name)
 	}
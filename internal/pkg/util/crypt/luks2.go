@@ -0,0 +1,511 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/pbkdf2"
+	"crypto/sha1" //nolint:gosec // Permitted hash for LUKS2 anti-forensic split and digests.
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/ccoveille/go-safecast/v2"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/xts"
+)
+
+const (
+	luks2BinaryHeaderSize = 4096
+	luks2SectorSize       = 512
+	luks2MaxSectorSize    = 4096
+	// luks2MaxKeySize and luks2MaxStripes bound the key material of a
+	// keyslot, as with cryptsetup.
+	luks2MaxKeySize = 512
+	luks2MaxStripes = 4000
+	// luks2MaxKDFMemory bounds the memory cost of an argon2 keyslot, in KiB,
+	// as with cryptsetup.
+	luks2MaxKDFMemory = 4 * 1024 * 1024
+)
+
+var (
+	luks2Magic          = []byte{'L', 'U', 'K', 'S', 0xba, 0xbe}
+	luks2SecondaryMagic = []byte{'S', 'K', 'U', 'L', 0xba, 0xbe}
+)
+
+var (
+	// errUnsupportedLUKS2 is returned for LUKS2 volumes that use features that
+	// cannot be read in-process.
+	errUnsupportedLUKS2 = errors.New("unsupported LUKS2 volume")
+	// errLUKS2ReadOnly is returned when writing to a LUKS2 volume that was not
+	// opened for writing.
+	errLUKS2ReadOnly = errors.New("LUKS2 volume is read-only")
+	// errLUKS2Size is returned when writing past the end of a LUKS2 volume.
+	errLUKS2Size = errors.New("write past the end of LUKS2 volume")
+	// errInvalidKeyslot is returned for a keyslot with inconsistent metadata.
+	errInvalidKeyslot = errors.New("invalid LUKS2 keyslot")
+)
+
+type luks2Keyslot struct {
+	Type    string `json:"type"`
+	KeySize int    `json:"key_size"`
+	AF      struct {
+		Type    string `json:"type"`
+		Stripes int    `json:"stripes"`
+		Hash    string `json:"hash"`
+	} `json:"af"`
+	Area struct {
+		Type       string `json:"type"`
+		Offset     string `json:"offset"`
+		Size       string `json:"size"`
+		Encryption string `json:"encryption"`
+		KeySize    int    `json:"key_size"`
+	} `json:"area"`
+	KDF struct {
+		Type       string `json:"type"`
+		Hash       string `json:"hash,omitempty"`
+		Iterations int    `json:"iterations,omitempty"`
+		Time       uint32 `json:"time,omitempty"`
+		Memory     uint32 `json:"memory,omitempty"`
+		CPUs       uint8  `json:"cpus,omitempty"`
+		Salt       []byte `json:"salt"`
+	} `json:"kdf"`
+}
+
+type luks2Segment struct {
+	Type       string `json:"type"`
+	Offset     string `json:"offset"`
+	Size       string `json:"size"`
+	IVTweak    string `json:"iv_tweak"`
+	Encryption string `json:"encryption"`
+	SectorSize int64  `json:"sector_size"`
+}
+
+type luks2Digest struct {
+	Type       string   `json:"type"`
+	Keyslots   []string `json:"keyslots"`
+	Segments   []string `json:"segments"`
+	Hash       string   `json:"hash"`
+	Iterations int      `json:"iterations"`
+	Salt       []byte   `json:"salt"`
+	Digest     []byte   `json:"digest"`
+}
+
+type luks2Config struct {
+	JSONSize     string `json:"json_size"`
+	KeyslotsSize string `json:"keyslots_size"`
+}
+
+type luks2Metadata struct {
+	Keyslots map[string]luks2Keyslot    `json:"keyslots"`
+	Tokens   map[string]json.RawMessage `json:"tokens"`
+	Segments map[string]luks2Segment    `json:"segments"`
+	Digests  map[string]luks2Digest     `json:"digests"`
+	Config   luks2Config                `json:"config"`
+}
+
+// LUKS2Reader reads the decrypted content of a LUKS2 volume, in-process,
+// without activation of a dm-crypt device. Content may also be written, if
+// the volume was opened with NewLUKS2ReadWriter.
+type LUKS2Reader struct {
+	r          io.ReaderAt
+	w          io.WriterAt
+	offset     int64
+	size       int64
+	sectorSize int64
+	ivTweak    uint64
+	cipher     *xts.Cipher
+	// mu is held for writing while sectors are re-encrypted by WriteAt, so
+	// that ReadAt never decrypts a partially written sector.
+	mu sync.RWMutex
+}
+
+// NewLUKS2Reader unlocks the LUKS2 volume held in r, of the specified size,
+// with key. Only the aes-xts-plain64 cipher, used by default by cryptsetup,
+// is supported. If no keyslot can be unlocked with key, ErrInvalidPassphrase
+// is returned.
+func NewLUKS2Reader(r io.ReaderAt, size int64, key []byte) (*LUKS2Reader, error) {
+	md, err := readLUKS2Metadata(r)
+	if err != nil {
+		return nil, err
+	}
+
+	_, volumeKey, did, err := unlockLUKS2(r, md, key)
+	if err != nil {
+		return nil, err
+	}
+	d := md.Digests[did]
+	seg, found := md.Segments[d.Segments[0]]
+	if !found {
+		return nil, fmt.Errorf("segment %s not found", d.Segments[0])
+	}
+	return newLUKS2SegmentReader(r, size, seg, volumeKey)
+}
+
+// unlockLUKS2 returns the ID of the first keyslot of md that is unlocked by
+// key, the volume key that it holds, and the ID of the digest that the volume
+// key was checked against. If no keyslot can be unlocked with key,
+// ErrInvalidPassphrase is returned.
+func unlockLUKS2(r io.ReaderAt, md *luks2Metadata, key []byte) (string, []byte, string, error) {
+	// Keyslots that cannot be used in-process are skipped, as another keyslot
+	// may unlock the volume. If none can be used, the reason is returned.
+	var errSkipped error
+	tried := false
+	for _, id := range slices.Sorted(maps.Keys(md.Keyslots)) {
+		volumeKey, err := unlockLUKS2Keyslot(r, md.Keyslots[id], key)
+		if errors.Is(err, errUnsupportedLUKS2) || errors.Is(err, errInvalidKeyslot) {
+			sylog.Debugf("Skipping LUKS2 keyslot %s: %v", id, err)
+			errSkipped = fmt.Errorf("keyslot %s: %w", id, err)
+			continue
+		}
+		if err != nil {
+			return "", nil, "", fmt.Errorf("keyslot %s: %w", id, err)
+		}
+		tried = true
+		for _, did := range slices.Sorted(maps.Keys(md.Digests)) {
+			d := md.Digests[did]
+			if !slices.Contains(d.Keyslots, id) || len(d.Segments) == 0 {
+				continue
+			}
+			ok, err := checkLUKS2Digest(d, volumeKey)
+			if errors.Is(err, errUnsupportedLUKS2) {
+				sylog.Debugf("Skipping LUKS2 digest for keyslot %s: %v", id, err)
+				continue
+			}
+			if err != nil {
+				return "", nil, "", err
+			}
+			if ok {
+				return id, volumeKey, did, nil
+			}
+		}
+	}
+	if !tried && errSkipped != nil {
+		return "", nil, "", errSkipped
+	}
+	return "", nil, "", ErrInvalidPassphrase
+}
+
+// ReadAt reads len(b) bytes of decrypted content, from offset off.
+func (l *LUKS2Reader) ReadAt(b []byte, off int64) (int, error) {
+	if off >= l.size {
+		return 0, io.EOF
+	}
+	want := min(int64(len(b)), l.size-off)
+
+	start := off / l.sectorSize * l.sectorSize
+	end := (off + want + l.sectorSize - 1) / l.sectorSize * l.sectorSize
+	buf := make([]byte, end-start)
+	l.mu.RLock()
+	_, err := l.r.ReadAt(buf, l.offset+start)
+	l.mu.RUnlock()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	shift := uint64(l.sectorSize / luks2SectorSize)
+	for s := int64(0); s < int64(len(buf)); s += l.sectorSize {
+		sector := (uint64(start+s)/luks2SectorSize + l.ivTweak) / shift
+		l.cipher.Decrypt(buf[s:s+l.sectorSize], buf[s:s+l.sectorSize], sector)
+	}
+
+	n := copy(b, buf[off-start:off-start+want])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Size returns the size of the decrypted content.
+func (l *LUKS2Reader) Size() int64 {
+	return l.size
+}
+
+// WriteAt encrypts len(b) bytes to the volume, at offset off of the decrypted
+// content. Sectors that are partially written are read and re-encrypted. The
+// size of the volume is fixed, so writes past its end fail.
+func (l *LUKS2Reader) WriteAt(b []byte, off int64) (int, error) {
+	if l.w == nil {
+		return 0, errLUKS2ReadOnly
+	}
+	if off < 0 || off+int64(len(b)) > l.size {
+		return 0, fmt.Errorf("write of %d bytes at offset %d: %w", len(b), off, errLUKS2Size)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start := off / l.sectorSize * l.sectorSize
+	end := (off + int64(len(b)) + l.sectorSize - 1) / l.sectorSize * l.sectorSize
+	buf := make([]byte, end-start)
+
+	// Only the first and last sectors may be partially written.
+	shift := uint64(l.sectorSize / luks2SectorSize)
+	sectorOf := func(s int64) uint64 {
+		return (uint64(start+s)/luks2SectorSize + l.ivTweak) / shift
+	}
+	edges := []int64{0}
+	if last := int64(len(buf)) - l.sectorSize; last > 0 {
+		edges = append(edges, last)
+	}
+	for _, s := range edges {
+		if start+s >= off && start+s+l.sectorSize <= off+int64(len(b)) {
+			continue
+		}
+		sector := buf[s : s+l.sectorSize]
+		if _, err := l.r.ReadAt(sector, l.offset+start+s); err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		l.cipher.Decrypt(sector, sector, sectorOf(s))
+	}
+
+	copy(buf[off-start:], b)
+	for s := int64(0); s < int64(len(buf)); s += l.sectorSize {
+		l.cipher.Encrypt(buf[s:s+l.sectorSize], buf[s:s+l.sectorSize], sectorOf(s))
+	}
+	if _, err := l.w.WriteAt(buf, l.offset+start); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// readLUKS2Metadata reads the binary header and JSON metadata of a LUKS2
+// volume.
+func readLUKS2Metadata(r io.ReaderAt) (*luks2Metadata, error) {
+	js, _, err := readLUKS2JSON(r)
+	if err != nil {
+		return nil, err
+	}
+	var md luks2Metadata
+	if err := json.Unmarshal(js, &md); err != nil {
+		return nil, fmt.Errorf("while parsing LUKS2 metadata: %w", err)
+	}
+	return &md, nil
+}
+
+// readLUKS2JSON checks the binary header of a LUKS2 volume, and returns the
+// JSON metadata that follows it, and the size of the header and metadata.
+func readLUKS2JSON(r io.ReaderAt) ([]byte, int64, error) {
+	hdr := make([]byte, luks2BinaryHeaderSize)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, 0, fmt.Errorf("while reading LUKS2 header: %w", err)
+	}
+	if !bytes.Equal(hdr[:6], luks2Magic) {
+		return nil, 0, fmt.Errorf("not a LUKS volume")
+	}
+	if v := binary.BigEndian.Uint16(hdr[6:]); v != 2 {
+		return nil, 0, fmt.Errorf("%w: LUKS version %d", errUnsupportedLUKS2, v)
+	}
+	hdrSize := binary.BigEndian.Uint64(hdr[8:])
+	if hdrSize <= luks2BinaryHeaderSize || hdrSize > 4*1024*1024 {
+		return nil, 0, fmt.Errorf("invalid LUKS2 header size %d", hdrSize)
+	}
+
+	js := make([]byte, hdrSize-luks2BinaryHeaderSize)
+	if _, err := r.ReadAt(js, luks2BinaryHeaderSize); err != nil {
+		return nil, 0, fmt.Errorf("while reading LUKS2 metadata: %w", err)
+	}
+	return bytes.TrimRight(js, "\x00"), int64(hdrSize), nil //nolint:gosec // Checked above.
+}
+
+// luks2Hash returns the hash function named h.
+func luks2Hash(h string) (func() hash.Hash, error) {
+	switch h {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("%w: hash %q", errUnsupportedLUKS2, h)
+	}
+}
+
+// luks2Cipher returns a cipher for the LUKS2 encryption named enc.
+func luks2Cipher(enc string, key []byte) (*xts.Cipher, error) {
+	if enc != "aes-xts-plain64" {
+		return nil, fmt.Errorf("%w: encryption %q", errUnsupportedLUKS2, enc)
+	}
+	return xts.NewCipher(aes.NewCipher, key)
+}
+
+// unlockLUKS2Keyslot returns the candidate volume key held in keyslot ks, as
+// decrypted with key. The candidate must be checked against a digest.
+func unlockLUKS2Keyslot(r io.ReaderAt, ks luks2Keyslot, key []byte) ([]byte, error) {
+	if ks.Type != "luks2" || ks.AF.Type != "luks1" || ks.Area.Type != "raw" {
+		return nil, fmt.Errorf("%w: keyslot type", errUnsupportedLUKS2)
+	}
+	if ks.KeySize <= 0 || ks.KeySize > luks2MaxKeySize || ks.AF.Stripes <= 0 || ks.AF.Stripes > luks2MaxStripes {
+		return nil, fmt.Errorf("%w: key size %d, %d stripes", errInvalidKeyslot, ks.KeySize, ks.AF.Stripes)
+	}
+	if ks.KeySize != ks.Area.KeySize {
+		return nil, fmt.Errorf("%w: key size %d differs from area key size %d", errUnsupportedLUKS2, ks.KeySize, ks.Area.KeySize)
+	}
+	keySize, err := safecast.Convert[uint32](ks.Area.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := strconv.ParseInt(ks.Area.Offset, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: offset: %v", errInvalidKeyslot, err)
+	}
+	areaSize, err := strconv.ParseInt(ks.Area.Size, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: size: %v", errInvalidKeyslot, err)
+	}
+	afSize := ks.KeySize * ks.AF.Stripes
+	if int64(afSize) > areaSize {
+		return nil, fmt.Errorf("%w: %d bytes of key material exceed area of %d bytes", errInvalidKeyslot, afSize, areaSize)
+	}
+
+	switch ks.KDF.Type {
+	case "pbkdf2":
+		if ks.KDF.Iterations < 1 {
+			return nil, fmt.Errorf("%w: %d pbkdf2 iterations", errInvalidKeyslot, ks.KDF.Iterations)
+		}
+	case "argon2i", "argon2id":
+		if ks.KDF.Time < 1 || ks.KDF.CPUs < 1 || ks.KDF.Memory > luks2MaxKDFMemory {
+			return nil, fmt.Errorf("%w: %s time %d, memory %d KiB, %d cpus", errInvalidKeyslot, ks.KDF.Type, ks.KDF.Time, ks.KDF.Memory, ks.KDF.CPUs)
+		}
+	}
+
+	var derived []byte
+	switch ks.KDF.Type {
+	case "pbkdf2":
+		h, err := luks2Hash(ks.KDF.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if derived, err = pbkdf2.Key(h, string(key), ks.KDF.Salt, ks.KDF.Iterations, ks.Area.KeySize); err != nil {
+			return nil, err
+		}
+	case "argon2i":
+		derived = argon2.Key(key, ks.KDF.Salt, ks.KDF.Time, ks.KDF.Memory, ks.KDF.CPUs, keySize)
+	case "argon2id":
+		derived = argon2.IDKey(key, ks.KDF.Salt, ks.KDF.Time, ks.KDF.Memory, ks.KDF.CPUs, keySize)
+	default:
+		return nil, fmt.Errorf("%w: kdf %q", errUnsupportedLUKS2, ks.KDF.Type)
+	}
+
+	c, err := luks2Cipher(ks.Area.Encryption, derived)
+	if err != nil {
+		return nil, err
+	}
+	area := make([]byte, (afSize+luks2SectorSize-1)/luks2SectorSize*luks2SectorSize)
+	if _, err := r.ReadAt(area, offset); err != nil {
+		return nil, fmt.Errorf("while reading keyslot area: %w", err)
+	}
+	for s := 0; s < len(area); s += luks2SectorSize {
+		c.Decrypt(area[s:s+luks2SectorSize], area[s:s+luks2SectorSize], uint64(s/luks2SectorSize))
+	}
+
+	h, err := luks2Hash(ks.AF.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return afMerge(area[:afSize], ks.KeySize, ks.AF.Stripes, h), nil
+}
+
+// afMerge recovers a key of keySize bytes from material that was split into
+// stripes with the LUKS anti-forensic splitter.
+func afMerge(material []byte, keySize, stripes int, h func() hash.Hash) []byte {
+	d := make([]byte, keySize)
+	for i := range stripes - 1 {
+		subtle.XORBytes(d, d, material[i*keySize:(i+1)*keySize])
+		d = afDiffuse(d, h)
+	}
+	subtle.XORBytes(d, d, material[(stripes-1)*keySize:])
+	return d
+}
+
+// afDiffuse applies the diffusion function of the LUKS anti-forensic
+// splitter to b.
+func afDiffuse(b []byte, h func() hash.Hash) []byte {
+	out := make([]byte, len(b))
+	size := h().Size()
+	for i := 0; i*size < len(b); i++ {
+		hh := h()
+		var iv [4]byte
+		binary.BigEndian.PutUint32(iv[:], uint32(i)) //nolint:gosec // Block index of a key, far below overflow.
+		hh.Write(iv[:])
+		hh.Write(b[i*size : min((i+1)*size, len(b))])
+		copy(out[i*size:], hh.Sum(nil))
+	}
+	return out
+}
+
+// checkLUKS2Digest returns whether volumeKey matches digest d.
+func checkLUKS2Digest(d luks2Digest, volumeKey []byte) (bool, error) {
+	if d.Type != "pbkdf2" {
+		return false, fmt.Errorf("%w: digest type %q", errUnsupportedLUKS2, d.Type)
+	}
+	if d.Iterations < 1 {
+		return false, fmt.Errorf("invalid LUKS2 digest: %d pbkdf2 iterations", d.Iterations)
+	}
+	h, err := luks2Hash(d.Hash)
+	if err != nil {
+		return false, err
+	}
+	sum, err := pbkdf2.Key(h, string(volumeKey), d.Salt, d.Iterations, len(d.Digest))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(sum, d.Digest) == 1, nil
+}
+
+// newLUKS2SegmentReader returns a reader for segment seg, decrypted with
+// volumeKey.
+func newLUKS2SegmentReader(r io.ReaderAt, size int64, seg luks2Segment, volumeKey []byte) (*LUKS2Reader, error) {
+	if seg.Type != "crypt" {
+		return nil, fmt.Errorf("%w: segment type %q", errUnsupportedLUKS2, seg.Type)
+	}
+	c, err := luks2Cipher(seg.Encryption, volumeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &LUKS2Reader{
+		r:          r,
+		sectorSize: seg.SectorSize,
+		cipher:     c,
+	}
+	if w, ok := r.(*fileSection); ok {
+		l.w = w
+	}
+	if l.sectorSize == 0 {
+		l.sectorSize = luks2SectorSize
+	}
+	if l.sectorSize < luks2SectorSize || l.sectorSize > luks2MaxSectorSize || l.sectorSize&(l.sectorSize-1) != 0 {
+		return nil, fmt.Errorf("invalid sector size %d", l.sectorSize)
+	}
+	if l.offset, err = strconv.ParseInt(seg.Offset, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid segment offset: %w", err)
+	}
+	if seg.IVTweak != "" {
+		if l.ivTweak, err = strconv.ParseUint(seg.IVTweak, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid segment iv_tweak: %w", err)
+		}
+	}
+	l.size = size - l.offset
+	if seg.Size != "dynamic" {
+		if l.size, err = strconv.ParseInt(seg.Size, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid segment size: %w", err)
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/xts"
+)
+
+const (
+	// luks2HeaderSize is the size of each of the two copies of the binary
+	// header and JSON metadata.
+	luks2HeaderSize = 16384
+	// luks2DataOffset is the offset of the encrypted data, following the
+	// headers and keyslots area.
+	luks2DataOffset = 1024 * 1024
+	// luks2DataSectorSize is the sector size of the encrypted data.
+	luks2DataSectorSize = 4096
+	// luks2KeySize is the size of the aes-xts-plain64 volume key.
+	luks2KeySize = 64
+	// luks2Stripes is the number of anti-forensic stripes of a keyslot.
+	luks2Stripes = 4000
+	// luks2DigestIterations is the number of PBKDF2 iterations of the volume
+	// key digest.
+	luks2DigestIterations = 1000
+)
+
+// Argon2id parameters of the keyslot of volumes written by EncryptLUKS2.
+// Variables so that tests may use cheaper parameters.
+var (
+	luks2KDFTime   uint32 = 4
+	luks2KDFMemory uint32 = 256 * 1024
+	luks2KDFCPUs   uint8  = 4
+)
+
+// randomBytes returns n random bytes.
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// newLUKS2Keyslot returns a keyslot, and the content of its keyslot area,
+// holding volumeKey protected by key.
+func newLUKS2Keyslot(volumeKey, key []byte) (luks2Keyslot, []byte, error) {
+	salt, err := randomBytes(32)
+	if err != nil {
+		return luks2Keyslot{}, nil, err
+	}
+
+	keySize := len(volumeKey)
+
+	var ks luks2Keyslot
+	ks.Type = "luks2"
+	ks.KeySize = keySize
+	ks.AF.Type = "luks1"
+	ks.AF.Stripes = luks2Stripes
+	ks.AF.Hash = "sha256"
+	ks.Area.Type = "raw"
+	ks.Area.Offset = strconv.Itoa(2 * luks2HeaderSize)
+	ks.Area.Encryption = "aes-xts-plain64"
+	ks.Area.KeySize = keySize
+	ks.KDF.Type = "argon2id"
+	ks.KDF.Time = luks2KDFTime
+	ks.KDF.Memory = luks2KDFMemory
+	ks.KDF.CPUs = luks2KDFCPUs
+	ks.KDF.Salt = salt
+
+	// Anti-forensic split of the volume key, so that it can only be
+	// recovered from the complete keyslot area.
+	material := make([]byte, keySize*luks2Stripes)
+	if _, err := rand.Read(material[:keySize*(luks2Stripes-1)]); err != nil {
+		return luks2Keyslot{}, nil, err
+	}
+	d := make([]byte, keySize)
+	for i := range luks2Stripes - 1 {
+		subtle.XORBytes(d, d, material[i*keySize:(i+1)*keySize])
+		d = afDiffuse(d, sha256.New)
+	}
+	subtle.XORBytes(material[(luks2Stripes-1)*keySize:], d, volumeKey)
+
+	// The keyslot area is aligned to 4096 bytes, as by cryptsetup.
+	area := make([]byte, (len(material)+4095)/4096*4096)
+	copy(area, material)
+	derived := argon2.IDKey(key, salt, ks.KDF.Time, ks.KDF.Memory, ks.KDF.CPUs, uint32(keySize)) //nolint:gosec // Size of a volume key.
+	c, err := xts.NewCipher(aes.NewCipher, derived)
+	if err != nil {
+		return luks2Keyslot{}, nil, err
+	}
+	for s := 0; s < len(area); s += luks2SectorSize {
+		c.Encrypt(area[s:s+luks2SectorSize], area[s:s+luks2SectorSize], uint64(s/luks2SectorSize))
+	}
+	ks.Area.Size = strconv.Itoa(len(area))
+
+	return ks, area, nil
+}
+
+// newLUKS2Digest returns a digest of volumeKey.
+func newLUKS2Digest(volumeKey []byte) (luks2Digest, error) {
+	salt, err := randomBytes(32)
+	if err != nil {
+		return luks2Digest{}, err
+	}
+	digest, err := pbkdf2.Key(sha256.New, string(volumeKey), salt, luks2DigestIterations, 32)
+	if err != nil {
+		return luks2Digest{}, err
+	}
+	return luks2Digest{
+		Type:       "pbkdf2",
+		Keyslots:   []string{"0"},
+		Segments:   []string{"0"},
+		Hash:       "sha256",
+		Iterations: luks2DigestIterations,
+		Salt:       salt,
+		Digest:     digest,
+	}, nil
+}
+
+// newLUKS2Header returns the primary and secondary binary headers, each
+// followed by the JSON metadata js, with their checksums.
+func newLUKS2Header(js []byte) ([]byte, error) {
+	if len(js) >= luks2HeaderSize-luks2BinaryHeaderSize {
+		return nil, fmt.Errorf("LUKS2 metadata of %d bytes is too large", len(js))
+	}
+	u := uuid.NewString()
+
+	hdr := make([]byte, 2*luks2HeaderSize)
+	for i, magic := range [][]byte{luks2Magic, luks2SecondaryMagic} {
+		h := hdr[i*luks2HeaderSize : (i+1)*luks2HeaderSize]
+		salt, err := randomBytes(64)
+		if err != nil {
+			return nil, err
+		}
+		copy(h, magic)
+		binary.BigEndian.PutUint16(h[6:], 2)
+		binary.BigEndian.PutUint64(h[8:], luks2HeaderSize)
+		binary.BigEndian.PutUint64(h[16:], 1)
+		copy(h[72:104], "sha256")
+		copy(h[104:168], salt)
+		copy(h[168:208], u)
+		binary.BigEndian.PutUint64(h[256:], uint64(i*luks2HeaderSize)) //nolint:gosec // Index of a header copy.
+		copy(h[luks2BinaryHeaderSize:], js)
+
+		// The checksum covers the binary header, with a zeroed checksum
+		// field, and the JSON area.
+		sum := sha256.Sum256(h)
+		copy(h[448:512], sum[:])
+	}
+	return hdr, nil
+}
+
+// EncryptLUKS2 writes a LUKS2 volume to w, holding the content read from r,
+// padded to a whole number of sectors. The content is encrypted with a new
+// aes-xts-plain64 volume key, held in a single argon2id keyslot that is
+// unlocked by key. The volume can be read with NewLUKS2Reader, or opened with
+// cryptsetup.
+func EncryptLUKS2(w io.Writer, r io.Reader, key []byte) error {
+	if len(key) == 0 {
+		return errors.New("cannot encrypt LUKS2 volume with an empty key")
+	}
+	volumeKey, err := randomBytes(luks2KeySize)
+	if err != nil {
+		return err
+	}
+
+	ks, area, err := newLUKS2Keyslot(volumeKey, key)
+	if err != nil {
+		return err
+	}
+	digest, err := newLUKS2Digest(volumeKey)
+	if err != nil {
+		return err
+	}
+
+	md := luks2Metadata{
+		Keyslots: map[string]luks2Keyslot{"0": ks},
+		Tokens:   map[string]json.RawMessage{},
+		Segments: map[string]luks2Segment{"0": {
+			Type:       "crypt",
+			Offset:     strconv.Itoa(luks2DataOffset),
+			Size:       "dynamic",
+			IVTweak:    "0",
+			Encryption: "aes-xts-plain64",
+			SectorSize: luks2DataSectorSize,
+		}},
+		Digests: map[string]luks2Digest{"0": digest},
+		Config: luks2Config{
+			JSONSize:     strconv.Itoa(luks2HeaderSize - luks2BinaryHeaderSize),
+			KeyslotsSize: strconv.Itoa(luks2DataOffset - 2*luks2HeaderSize),
+		},
+	}
+	js, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+	hdr, err := newLUKS2Header(js)
+	if err != nil {
+		return err
+	}
+
+	meta := make([]byte, luks2DataOffset)
+	copy(meta, hdr)
+	copy(meta[2*luks2HeaderSize:], area)
+	if _, err := w.Write(meta); err != nil {
+		return err
+	}
+
+	c, err := xts.NewCipher(aes.NewCipher, volumeKey)
+	if err != nil {
+		return err
+	}
+	const sectorsPerChunk = 256
+	buf := make([]byte, sectorsPerChunk*luks2DataSectorSize)
+	var sector uint64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+
+		// Pad the final sector with zeros.
+		padded := (n + luks2DataSectorSize - 1) / luks2DataSectorSize * luks2DataSectorSize
+		clear(buf[n:padded])
+		for s := 0; s < padded; s += luks2DataSectorSize {
+			c.Encrypt(buf[s:s+luks2DataSectorSize], buf[s:s+luks2DataSectorSize], sector)
+			sector++
+		}
+		if _, err := w.Write(buf[:padded]); err != nil {
+			return err
+		}
+		if n < len(buf) {
+			return nil
+		}
+	}
+}
+
+// IsLUKS2 returns whether b starts with the binary header of a LUKS2 volume.
+func IsLUKS2(b []byte) bool {
+	return bytes.HasPrefix(b, luks2Magic) && len(b) >= 8 && binary.BigEndian.Uint16(b[6:]) == 2
+}
+
+// fileSection is the section of a file, from offset and of the specified
+// size, that holds a LUKS2 volume.
+type fileSection struct {
+	f interface {
+		io.ReaderAt
+		io.WriterAt
+		Sync() error
+	}
+	offset int64
+	size   int64
+}
+
+func (s *fileSection) ReadAt(b []byte, off int64) (int, error) {
+	if off >= s.size {
+		return 0, io.EOF
+	}
+	if remain := s.size - off; int64(len(b)) > remain {
+		n, err := s.f.ReadAt(b[:remain], s.offset+off)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return s.f.ReadAt(b, s.offset+off)
+}
+
+func (s *fileSection) WriteAt(b []byte, off int64) (int, error) {
+	if off+int64(len(b)) > s.size {
+		return 0, errLUKS2Size
+	}
+	return s.f.WriteAt(b, s.offset+off)
+}
+
+// NewLUKS2ReadWriter unlocks the LUKS2 volume held in f, from offset and of the
+// specified size, with key. If f was opened for writing, the decrypted content
+// of the volume may also be written with WriteAt.
+func NewLUKS2ReadWriter(f *os.File, offset, size int64, key []byte) (*LUKS2Reader, error) {
+	return NewLUKS2Reader(&fileSection{f: f, offset: offset, size: size}, size, key)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package crypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"syscall"
+
+	"github.com/ccoveille/go-safecast/v2"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// luks2Root is the root directory of a FUSE filesystem exposing the decrypted
+// content of LUKS2 volumes.
+type luks2Root struct {
+	fs.Inode
+	volumes  []*LUKS2Reader
+	writable bool
+}
+
+var _ fs.NodeOnAdder = (*luks2Root)(nil)
+
+// OnAdd populates the root directory with a file for each volume, named by its
+// index.
+func (r *luks2Root) OnAdd(ctx context.Context) {
+	for i, v := range r.volumes {
+		ch := r.NewPersistentInode(ctx, &luks2File{volume: v, writable: r.writable}, fs.StableAttr{Mode: syscall.S_IFREG})
+		r.AddChild(strconv.Itoa(i), ch, false)
+	}
+}
+
+// luks2File is a file of fixed size, with content decrypted from, and
+// encrypted to, a LUKS2 volume on demand.
+type luks2File struct {
+	fs.Inode
+	volume   *LUKS2Reader
+	writable bool
+}
+
+var (
+	_ fs.NodeGetattrer = (*luks2File)(nil)
+	_ fs.NodeOpener    = (*luks2File)(nil)
+	_ fs.NodeReader    = (*luks2File)(nil)
+	_ fs.NodeWriter    = (*luks2File)(nil)
+	_ fs.NodeFsyncer   = (*luks2File)(nil)
+)
+
+func (f *luks2File) Getattr(_ context.Context, _ fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	size, err := safecast.Convert[uint64](f.volume.Size())
+	if err != nil {
+		return syscall.EIO
+	}
+	out.Mode = 0o444
+	if f.writable {
+		out.Mode = 0o644
+	}
+	out.Size = size
+	return 0
+}
+
+func (f *luks2File) Open(_ context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if !f.writable && flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	// Content only changes through this filesystem, so the kernel page cache
+	// can be retained.
+	return nil, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (f *luks2File) Read(_ context.Context, _ fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	n, err := f.volume.ReadAt(dest, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		sylog.Errorf("While reading encrypted volume: %v", err)
+		return nil, syscall.EIO
+	}
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+func (f *luks2File) Write(_ context.Context, _ fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	if !f.writable {
+		return 0, syscall.EROFS
+	}
+	n, err := f.volume.WriteAt(data, off)
+	if errors.Is(err, errLUKS2Size) {
+		return 0, syscall.ENOSPC
+	}
+	if err != nil {
+		sylog.Errorf("While writing encrypted volume: %v", err)
+		return 0, syscall.EIO
+	}
+	written, err := safecast.Convert[uint32](n)
+	if err != nil {
+		return 0, syscall.EIO
+	}
+	return written, 0
+}
+
+func (f *luks2File) Fsync(_ context.Context, _ fs.FileHandle, _ uint32) syscall.Errno {
+	if s, ok := f.volume.w.(*fileSection); ok {
+		if err := s.f.Sync(); err != nil {
+			sylog.Errorf("While syncing encrypted volume: %v", err)
+			return syscall.EIO
+		}
+	}
+	return 0
+}
+
+// MountLUKS2 serves the decrypted content of volumes, as files named by index,
+// from a FUSE filesystem mounted at dir. If writable is set, the files may be
+// written, provided the volumes were opened with NewLUKS2ReadWriter. The
+// returned server must be unmounted once the filesystem is no longer required.
+func MountLUKS2(dir string, volumes []*LUKS2Reader, writable bool) (*fuse.Server, error) {
+	root := &luks2Root{volumes: volumes, writable: writable}
+	server, err := fs.Mount(dir, root, &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName: "singularity-luks2",
+			Name:   "luks2",
+			// Mount directly where permitted, falling back to fusermount.
+			DirectMount: true,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while mounting encrypted volumes: %w", err)
+	}
+	return server, nil
+}
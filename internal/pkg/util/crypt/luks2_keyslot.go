@@ -0,0 +1,269 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package crypt
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// errNoKeyslotSpace is returned when the keyslots area of a LUKS2 volume has
+// no space for a new keyslot.
+var errNoKeyslotSpace = errors.New("no space for a new keyslot in LUKS2 volume")
+
+// AddLUKS2Key adds a keyslot unlocked by newKey to the LUKS2 volume held in f,
+// from offset and of the specified size, holding the volume key of the
+// keyslot unlocked by key. The content of the volume is not re-encrypted.
+func AddLUKS2Key(f *os.File, offset, size int64, key, newKey []byte) error {
+	if len(newKey) == 0 {
+		return errors.New("cannot add an empty key to LUKS2 volume")
+	}
+	s := &fileSection{f: f, offset: offset, size: size}
+
+	js, hdrSize, err := readLUKS2JSON(s)
+	if err != nil {
+		return err
+	}
+	var md luks2Metadata
+	if err := json.Unmarshal(js, &md); err != nil {
+		return fmt.Errorf("while parsing LUKS2 metadata: %w", err)
+	}
+
+	_, volumeKey, did, err := unlockLUKS2(s, &md, key)
+	if err != nil {
+		return err
+	}
+	ks, area, err := newLUKS2Keyslot(volumeKey, newKey)
+	if err != nil {
+		return err
+	}
+	areaOffset, err := freeLUKS2Area(&md, hdrSize, int64(len(area)))
+	if err != nil {
+		return err
+	}
+	ks.Area.Offset = strconv.FormatInt(areaOffset, 10)
+
+	id := 0
+	for ; ; id++ {
+		if _, ok := md.Keyslots[strconv.Itoa(id)]; !ok {
+			break
+		}
+	}
+
+	// The keyslot area is written before the metadata that references it, so
+	// that the volume remains valid if interrupted.
+	if _, err := s.WriteAt(area, areaOffset); err != nil {
+		return fmt.Errorf("while writing LUKS2 keyslot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	js, err = editLUKS2JSON(js, func(keyslots map[string]json.RawMessage, digests map[string][]string) error {
+		b, err := json.Marshal(ks)
+		if err != nil {
+			return err
+		}
+		keyslots[strconv.Itoa(id)] = b
+		digests[did] = append(digests[did], strconv.Itoa(id))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeLUKS2JSON(s, hdrSize, js)
+}
+
+// RemoveLUKS2Key removes the keyslot unlocked by key from the LUKS2 volume held
+// in f, from offset and of the specified size. The keyslot area is wiped, so
+// that key no longer unlocks the volume, even from a copy of its metadata. The
+// last keyslot of a volume cannot be removed.
+func RemoveLUKS2Key(f *os.File, offset, size int64, key []byte) error {
+	s := &fileSection{f: f, offset: offset, size: size}
+
+	js, hdrSize, err := readLUKS2JSON(s)
+	if err != nil {
+		return err
+	}
+	var md luks2Metadata
+	if err := json.Unmarshal(js, &md); err != nil {
+		return fmt.Errorf("while parsing LUKS2 metadata: %w", err)
+	}
+
+	id, _, _, err := unlockLUKS2(s, &md, key)
+	if err != nil {
+		return err
+	}
+	if len(md.Keyslots) < 2 {
+		return errors.New("cannot remove the last keyslot of LUKS2 volume")
+	}
+
+	ks := md.Keyslots[id]
+	areaOffset, err := strconv.ParseInt(ks.Area.Offset, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: area offset: %w", errInvalidKeyslot, err)
+	}
+	areaSize, err := strconv.ParseInt(ks.Area.Size, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: area size: %w", errInvalidKeyslot, err)
+	}
+	if areaOffset < 2*hdrSize || areaSize < 0 || areaOffset+areaSize > size {
+		return fmt.Errorf("%w: area of %d bytes at offset %d", errInvalidKeyslot, areaSize, areaOffset)
+	}
+	if _, err := s.WriteAt(make([]byte, areaSize), areaOffset); err != nil {
+		return fmt.Errorf("while wiping LUKS2 keyslot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	js, err = editLUKS2JSON(js, func(keyslots map[string]json.RawMessage, digests map[string][]string) error {
+		delete(keyslots, id)
+		for did, ids := range digests {
+			digests[did] = slices.DeleteFunc(ids, func(k string) bool { return k == id })
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeLUKS2JSON(s, hdrSize, js)
+}
+
+// freeLUKS2Area returns the offset of a free region of size bytes in the
+// keyslots area of a LUKS2 volume with metadata md, that follows the two
+// copies of the header and metadata, each of hdrSize bytes.
+func freeLUKS2Area(md *luks2Metadata, hdrSize, size int64) (int64, error) {
+	areasSize, err := strconv.ParseInt(md.Config.KeyslotsSize, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LUKS2 keyslots size: %w", err)
+	}
+	start, end := 2*hdrSize, 2*hdrSize+areasSize
+
+	type region struct{ offset, end int64 }
+	used := make([]region, 0, len(md.Keyslots))
+	for id, ks := range md.Keyslots {
+		o, err := strconv.ParseInt(ks.Area.Offset, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("keyslot %s: %w: area offset: %w", id, errInvalidKeyslot, err)
+		}
+		n, err := strconv.ParseInt(ks.Area.Size, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("keyslot %s: %w: area size: %w", id, errInvalidKeyslot, err)
+		}
+		used = append(used, region{o, o + n})
+	}
+	slices.SortFunc(used, func(a, b region) int { return cmp.Compare(a.offset, b.offset) })
+
+	// Keyslot areas are aligned to 4096 bytes, as by cryptsetup.
+	offset := start
+	for _, r := range used {
+		if r.offset-offset >= size {
+			break
+		}
+		offset = max(offset, (r.end+4095)/4096*4096)
+	}
+	if offset+size > end {
+		return 0, errNoKeyslotSpace
+	}
+	return offset, nil
+}
+
+// editLUKS2JSON applies edit to the JSON metadata js of a LUKS2 volume. edit
+// is passed the keyslots, and the IDs of the keyslots of each digest, which it
+// may modify. Other metadata, that may not be known to this package, is
+// preserved.
+func editLUKS2JSON(js []byte, edit func(keyslots map[string]json.RawMessage, digests map[string][]string) error) ([]byte, error) {
+	var md map[string]json.RawMessage
+	if err := json.Unmarshal(js, &md); err != nil {
+		return nil, fmt.Errorf("while parsing LUKS2 metadata: %w", err)
+	}
+	var keyslots map[string]json.RawMessage
+	if err := json.Unmarshal(md["keyslots"], &keyslots); err != nil {
+		return nil, fmt.Errorf("while parsing LUKS2 keyslots: %w", err)
+	}
+	var digests map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(md["digests"], &digests); err != nil {
+		return nil, fmt.Errorf("while parsing LUKS2 digests: %w", err)
+	}
+	digestKeyslots := make(map[string][]string, len(digests))
+	for id, d := range digests {
+		var ids []string
+		if err := json.Unmarshal(d["keyslots"], &ids); err != nil {
+			return nil, fmt.Errorf("while parsing LUKS2 digest %s: %w", id, err)
+		}
+		digestKeyslots[id] = ids
+	}
+
+	if err := edit(keyslots, digestKeyslots); err != nil {
+		return nil, err
+	}
+
+	var err error
+	for id, ids := range digestKeyslots {
+		if digests[id]["keyslots"], err = json.Marshal(ids); err != nil {
+			return nil, err
+		}
+	}
+	if md["keyslots"], err = json.Marshal(keyslots); err != nil {
+		return nil, err
+	}
+	if md["digests"], err = json.Marshal(digests); err != nil {
+		return nil, err
+	}
+	return json.Marshal(md)
+}
+
+// writeLUKS2JSON writes the JSON metadata js to both copies of the header of
+// the LUKS2 volume held in s, each of hdrSize bytes, with an incremented
+// sequence ID and updated checksum. The primary copy is written first, so that
+// cryptsetup can recover from the secondary copy if interrupted.
+func writeLUKS2JSON(s *fileSection, hdrSize int64, js []byte) error {
+	if int64(len(js)) >= hdrSize-luks2BinaryHeaderSize {
+		return fmt.Errorf("LUKS2 metadata of %d bytes is too large", len(js))
+	}
+
+	var seqID uint64
+	for i, magic := range [][]byte{luks2Magic, luks2SecondaryMagic} {
+		h := make([]byte, hdrSize)
+		if _, err := s.ReadAt(h[:luks2BinaryHeaderSize], int64(i)*hdrSize); err != nil {
+			return fmt.Errorf("while reading LUKS2 header: %w", err)
+		}
+		if !bytes.HasPrefix(h, magic) {
+			return fmt.Errorf("invalid LUKS2 header at offset %d", int64(i)*hdrSize)
+		}
+		if alg := string(bytes.TrimRight(h[72:104], "\x00")); alg != "sha256" {
+			return fmt.Errorf("%w: header checksum %q", errUnsupportedLUKS2, alg)
+		}
+		if i == 0 {
+			seqID = binary.BigEndian.Uint64(h[16:]) + 1
+		}
+		binary.BigEndian.PutUint64(h[16:], seqID)
+		copy(h[luks2BinaryHeaderSize:], js)
+
+		// The checksum covers the binary header, with a zeroed checksum
+		// field, and the JSON area.
+		clear(h[448:512])
+		sum := sha256.Sum256(h)
+		copy(h[448:512], sum[:])
+
+		if _, err := s.WriteAt(h, int64(i)*hdrSize); err != nil {
+			return fmt.Errorf("while writing LUKS2 header: %w", err)
+		}
+		if err := s.f.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,744 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/xts"
+)
+
+const (
+	testKeySize    = 64
+	testStripes    = 4000
+	testAreaOffset = 32768
+	testDataOffset = 16 * 1024 * 1024
+)
+
+// makeLUKS2 returns a LUKS2 volume holding data, with a single keyslot
+// unlocked by key, in the layout written by cryptsetup.
+func makeLUKS2(t *testing.T, key, data []byte, kdf string, sectorSize int64) []byte {
+	t.Helper()
+
+	volumeKey := make([]byte, testKeySize)
+	salt := make([]byte, 32)
+	digestSalt := make([]byte, 32)
+	for _, b := range [][]byte{volumeKey, salt, digestSalt} {
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ks luks2Keyslot
+	ks.Type = "luks2"
+	ks.KeySize = testKeySize
+	ks.AF.Type = "luks1"
+	ks.AF.Stripes = testStripes
+	ks.AF.Hash = "sha256"
+	ks.Area.Type = "raw"
+	ks.Area.Offset = strconv.Itoa(testAreaOffset)
+	ks.Area.Encryption = "aes-xts-plain64"
+	ks.Area.KeySize = testKeySize
+	ks.KDF.Type = kdf
+	ks.KDF.Salt = salt
+
+	var derived []byte
+	switch kdf {
+	case "pbkdf2":
+		ks.KDF.Hash = "sha256"
+		ks.KDF.Iterations = 1000
+		var err error
+		if derived, err = pbkdf2.Key(sha256.New, string(key), salt, 1000, testKeySize); err != nil {
+			t.Fatal(err)
+		}
+	case "argon2id":
+		ks.KDF.Time, ks.KDF.Memory, ks.KDF.CPUs = 1, 64, 1
+		derived = argon2.IDKey(key, salt, 1, 64, 1, testKeySize)
+	}
+
+	// Anti-forensic split of the volume key.
+	material := make([]byte, testKeySize*testStripes)
+	if _, err := rand.Read(material[:testKeySize*(testStripes-1)]); err != nil {
+		t.Fatal(err)
+	}
+	d := make([]byte, testKeySize)
+	for i := range testStripes - 1 {
+		subtle.XORBytes(d, d, material[i*testKeySize:(i+1)*testKeySize])
+		d = afDiffuse(d, sha256.New)
+	}
+	subtle.XORBytes(material[(testStripes-1)*testKeySize:], d, volumeKey)
+
+	area := make([]byte, (len(material)+luks2SectorSize-1)/luks2SectorSize*luks2SectorSize)
+	copy(area, material)
+	c, err := xts.NewCipher(aes.NewCipher, derived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s := 0; s < len(area); s += luks2SectorSize {
+		c.Encrypt(area[s:s+luks2SectorSize], area[s:s+luks2SectorSize], uint64(s/luks2SectorSize))
+	}
+	ks.Area.Size = strconv.Itoa(len(area))
+
+	digest, err := pbkdf2.Key(sha256.New, string(volumeKey), digestSalt, 1000, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md := luks2Metadata{
+		Keyslots: map[string]luks2Keyslot{"0": ks},
+		Segments: map[string]luks2Segment{"0": {
+			Type:       "crypt",
+			Offset:     strconv.Itoa(testDataOffset),
+			Size:       "dynamic",
+			IVTweak:    "0",
+			Encryption: "aes-xts-plain64",
+			SectorSize: sectorSize,
+		}},
+		Digests: map[string]luks2Digest{"0": {
+			Type:       "pbkdf2",
+			Keyslots:   []string{"0"},
+			Segments:   []string{"0"},
+			Hash:       "sha256",
+			Iterations: 1000,
+			Salt:       digestSalt,
+			Digest:     digest,
+		}},
+	}
+	js, err := json.Marshal(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const hdrSize = 16384
+	vol := make([]byte, testDataOffset+len(data))
+	copy(vol, luks2Magic)
+	binary.BigEndian.PutUint16(vol[6:], 2)
+	binary.BigEndian.PutUint64(vol[8:], hdrSize)
+	copy(vol[luks2BinaryHeaderSize:hdrSize], js)
+	copy(vol[testAreaOffset:], area)
+
+	dc, err := xts.NewCipher(aes.NewCipher, volumeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := vol[testDataOffset:]
+	copy(enc, data)
+	for s := int64(0); s < int64(len(enc)); s += sectorSize {
+		dc.Encrypt(enc[s:s+sectorSize], enc[s:s+sectorSize], uint64(s/sectorSize))
+	}
+	return vol
+}
+
+func TestLUKS2Reader(t *testing.T) {
+	key := []byte("passphrase")
+	data := make([]byte, 64*1024)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		kdf        string
+		sectorSize int64
+	}{
+		{"pbkdf2", "pbkdf2", 512},
+		{"argon2id", "argon2id", 512},
+		{"sector4k", "pbkdf2", 4096},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vol := makeLUKS2(t, key, data, tt.kdf, tt.sectorSize)
+			r := bytes.NewReader(vol)
+
+			if _, err := NewLUKS2Reader(r, int64(len(vol)), []byte("wrong")); !errors.Is(err, ErrInvalidPassphrase) {
+				t.Errorf("got error %v with wrong key, want %v", err, ErrInvalidPassphrase)
+			}
+
+			l, err := NewLUKS2Reader(r, int64(len(vol)), key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Size() != int64(len(data)) {
+				t.Fatalf("got size %d, want %d", l.Size(), len(data))
+			}
+
+			got, err := io.ReadAll(io.NewSectionReader(l, 0, l.Size()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("decrypted content differs")
+			}
+
+			// Unaligned read spanning sectors.
+			b := make([]byte, 5000)
+			if _, err := l.ReadAt(b, 1234); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, data[1234:6234]) {
+				t.Errorf("unaligned read differs")
+			}
+
+			if n, err := l.ReadAt(b, l.Size()-10); n != 10 || err != io.EOF {
+				t.Errorf("got %d, %v reading past end, want 10, EOF", n, err)
+			}
+		})
+	}
+}
+
+// editLUKS2Metadata applies edit to the JSON metadata of the volume vol, as
+// written by makeLUKS2.
+func editLUKS2Metadata(t *testing.T, vol []byte, edit func(md *luks2Metadata)) {
+	t.Helper()
+
+	md, err := readLUKS2Metadata(bytes.NewReader(vol))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit(md)
+	js, err := json.Marshal(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr := vol[luks2BinaryHeaderSize:16384]
+	clear(hdr)
+	copy(hdr, js)
+}
+
+func TestLUKS2ReaderMetadata(t *testing.T) {
+	key := []byte("passphrase")
+	data := make([]byte, 4096)
+
+	tests := []struct {
+		name    string
+		edit    func(md *luks2Metadata)
+		wantErr error
+	}{
+		{
+			name: "UnsupportedKeyslotSkipped",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				md.Keyslots["1"] = ks
+				ks.KDF.Type = "scrypt"
+				md.Keyslots["0"] = ks
+				d := md.Digests["0"]
+				d.Keyslots = []string{"1"}
+				md.Digests["0"] = d
+			},
+		},
+		{
+			name: "InvalidKeyslotSkipped",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				md.Keyslots["1"] = ks
+				ks.AF.Stripes = 0
+				md.Keyslots["0"] = ks
+				d := md.Digests["0"]
+				d.Keyslots = []string{"1"}
+				md.Digests["0"] = d
+			},
+		},
+		{
+			name: "ZeroKeySize",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				ks.KeySize = 0
+				md.Keyslots["0"] = ks
+			},
+			wantErr: errInvalidKeyslot,
+		},
+		{
+			name: "ZeroStripes",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				ks.AF.Stripes = 0
+				md.Keyslots["0"] = ks
+			},
+			wantErr: errInvalidKeyslot,
+		},
+		{
+			name: "AreaTooSmall",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				ks.Area.Size = "4096"
+				md.Keyslots["0"] = ks
+			},
+			wantErr: errInvalidKeyslot,
+		},
+		{
+			name: "ZeroIterations",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				ks.KDF.Iterations = 0
+				md.Keyslots["0"] = ks
+			},
+			wantErr: errInvalidKeyslot,
+		},
+		{
+			name: "KeySizeMismatch",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				ks.Area.KeySize = 32
+				md.Keyslots["0"] = ks
+			},
+			wantErr: errUnsupportedLUKS2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vol := makeLUKS2(t, key, data, "pbkdf2", 512)
+			editLUKS2Metadata(t, vol, tt.edit)
+
+			l, err := NewLUKS2Reader(bytes.NewReader(vol), int64(len(vol)), key)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(io.NewSectionReader(l, 0, l.Size()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("decrypted content differs")
+			}
+		})
+	}
+
+	argon2Tests := []struct {
+		name string
+		edit func(md *luks2Metadata)
+	}{
+		{
+			name: "ZeroTime",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				ks.KDF.Time = 0
+				md.Keyslots["0"] = ks
+			},
+		},
+		{
+			name: "ZeroCPUs",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				ks.KDF.CPUs = 0
+				md.Keyslots["0"] = ks
+			},
+		},
+		{
+			name: "ExcessiveMemory",
+			edit: func(md *luks2Metadata) {
+				ks := md.Keyslots["0"]
+				ks.KDF.Memory = luks2MaxKDFMemory + 1
+				md.Keyslots["0"] = ks
+			},
+		},
+	}
+	for _, tt := range argon2Tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vol := makeLUKS2(t, key, data, "argon2id", 512)
+			editLUKS2Metadata(t, vol, tt.edit)
+			if _, err := NewLUKS2Reader(bytes.NewReader(vol), int64(len(vol)), key); !errors.Is(err, errInvalidKeyslot) {
+				t.Errorf("got error %v, want %v", err, errInvalidKeyslot)
+			}
+		})
+	}
+
+	for _, size := range []int64{256, 1536, 8192} {
+		t.Run("SectorSize"+strconv.FormatInt(size, 10), func(t *testing.T) {
+			vol := makeLUKS2(t, key, data, "pbkdf2", 512)
+			editLUKS2Metadata(t, vol, func(md *luks2Metadata) {
+				seg := md.Segments["0"]
+				seg.SectorSize = size
+				md.Segments["0"] = seg
+			})
+			if _, err := NewLUKS2Reader(bytes.NewReader(vol), int64(len(vol)), key); err == nil {
+				t.Errorf("unexpected success with sector size %d", size)
+			}
+		})
+	}
+}
+
+// useCheapKDF lowers the argon2id parameters of volumes written by
+// EncryptLUKS2 for the duration of the test.
+func useCheapKDF(t *testing.T) {
+	t.Helper()
+
+	time, memory, cpus := luks2KDFTime, luks2KDFMemory, luks2KDFCPUs
+	luks2KDFTime, luks2KDFMemory, luks2KDFCPUs = 1, 1024, 1
+	t.Cleanup(func() {
+		luks2KDFTime, luks2KDFMemory, luks2KDFCPUs = time, memory, cpus
+	})
+}
+
+func TestEncryptLUKS2(t *testing.T) {
+	useCheapKDF(t)
+
+	key := []byte("passphrase")
+
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"Empty", 0},
+		{"Aligned", 2 * luks2DataSectorSize},
+		{"Unaligned", 3*luks2DataSectorSize + 123},
+		{"MultipleChunks", 300*luks2DataSectorSize + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			if _, err := rand.Read(data); err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := EncryptLUKS2(&buf, bytes.NewReader(data), key); err != nil {
+				t.Fatal(err)
+			}
+			vol := buf.Bytes()
+			if !IsLUKS2(vol) {
+				t.Fatal("volume has no LUKS2 header")
+			}
+			if len(data) > 0 && bytes.Contains(vol, data[:min(len(data), 64)]) {
+				t.Error("volume holds plaintext content")
+			}
+
+			// Primary and secondary headers must hold valid checksums.
+			for _, off := range []int{0, luks2HeaderSize} {
+				h := bytes.Clone(vol[off : off+luks2HeaderSize])
+				want := bytes.Clone(h[448:480])
+				clear(h[448:512])
+				if got := sha256.Sum256(h); !bytes.Equal(got[:], want) {
+					t.Errorf("header at %d has invalid checksum", off)
+				}
+			}
+
+			if _, err := NewLUKS2Reader(bytes.NewReader(vol), int64(len(vol)), []byte("wrong")); !errors.Is(err, ErrInvalidPassphrase) {
+				t.Errorf("got error %v with wrong key, want %v", err, ErrInvalidPassphrase)
+			}
+
+			l, err := NewLUKS2Reader(bytes.NewReader(vol), int64(len(vol)), key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			padded := (tt.size + luks2DataSectorSize - 1) / luks2DataSectorSize * luks2DataSectorSize
+			if l.Size() != int64(padded) {
+				t.Fatalf("got size %d, want %d", l.Size(), padded)
+			}
+
+			got, err := io.ReadAll(io.NewSectionReader(l, 0, l.Size()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got[:tt.size], data) {
+				t.Errorf("decrypted content differs")
+			}
+			if !bytes.Equal(got[tt.size:], make([]byte, padded-tt.size)) {
+				t.Errorf("padding is not zeroed")
+			}
+		})
+	}
+}
+
+func TestLUKS2ReadWriter(t *testing.T) {
+	useCheapKDF(t)
+
+	key := []byte("passphrase")
+	data := make([]byte, 8*luks2DataSectorSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := EncryptLUKS2(&buf, bytes.NewReader(data), key); err != nil {
+		t.Fatal(err)
+	}
+
+	// Place the volume within a file, between unrelated content.
+	const offset = 4096
+	f, err := os.Create(filepath.Join(t.TempDir(), "image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	prefix := bytes.Repeat([]byte{0xaa}, offset)
+	suffix := bytes.Repeat([]byte{0x55}, 4096)
+	for _, b := range [][]byte{prefix, buf.Bytes(), suffix} {
+		if _, err := f.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	size := int64(buf.Len())
+
+	tests := []struct {
+		name string
+		off  int64
+		size int
+	}{
+		{"Aligned", luks2DataSectorSize, luks2DataSectorSize},
+		{"WithinSector", 100, 200},
+		{"Unaligned", 3000, 3 * luks2DataSectorSize},
+		{"LastSector", 7*luks2DataSectorSize + 10, luks2DataSectorSize - 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLUKS2ReadWriter(f, offset, size, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := make([]byte, tt.size)
+			if _, err := rand.Read(b); err != nil {
+				t.Fatal(err)
+			}
+			if n, err := l.WriteAt(b, tt.off); err != nil || n != len(b) {
+				t.Fatalf("got %d, %v writing, want %d, nil", n, err, len(b))
+			}
+			copy(data[tt.off:], b)
+
+			// Re-open the volume to read the written content from the file.
+			l, err = NewLUKS2ReadWriter(f, offset, size, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(io.NewSectionReader(l, 0, l.Size()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("decrypted content differs")
+			}
+		})
+	}
+
+	l, err := NewLUKS2ReadWriter(f, offset, size, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.WriteAt([]byte("x"), l.Size()); !errors.Is(err, errLUKS2Size) {
+		t.Errorf("got error %v writing past end, want %v", err, errLUKS2Size)
+	}
+
+	got := make([]byte, offset)
+	if _, err := f.ReadAt(got, 0); err != nil || !bytes.Equal(got, prefix) {
+		t.Errorf("content before volume modified")
+	}
+	got = make([]byte, len(suffix))
+	if _, err := f.ReadAt(got, offset+size); err != nil || !bytes.Equal(got, suffix) {
+		t.Errorf("content after volume modified")
+	}
+
+	r, err := NewLUKS2Reader(bytes.NewReader(buf.Bytes()), size, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.WriteAt([]byte("x"), 0); !errors.Is(err, errLUKS2ReadOnly) {
+		t.Errorf("got error %v writing read-only volume, want %v", err, errLUKS2ReadOnly)
+	}
+}
+
+// piecewiseFile writes to a file in small pieces, so that concurrent reads
+// observe partial writes unless they are serialized.
+type piecewiseFile struct {
+	*os.File
+}
+
+func (f piecewiseFile) WriteAt(b []byte, off int64) (int, error) {
+	n := 0
+	for n < len(b) {
+		m, err := f.File.WriteAt(b[n:min(n+64, len(b))], off+int64(n))
+		n += m
+		if err != nil {
+			return n, err
+		}
+		runtime.Gosched()
+	}
+	return n, nil
+}
+
+func TestLUKS2ReadWriterConcurrent(t *testing.T) {
+	useCheapKDF(t)
+
+	key := []byte("passphrase")
+	var buf bytes.Buffer
+	if err := EncryptLUKS2(&buf, bytes.NewReader(make([]byte, 4*luks2DataSectorSize)), key); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "image")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	volSize := int64(buf.Len())
+	l, err := NewLUKS2Reader(&fileSection{f: piecewiseFile{f}, size: volSize}, volSize, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes span sectors, so a read concurrent with a write must see either
+	// all of the old content, or all of the new.
+	const off, size = 100, 2 * luks2DataSectorSize
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 200 {
+			if _, err := l.WriteAt(bytes.Repeat([]byte{byte(i%2 + 1)}, size), off); err != nil {
+				t.Error(err)
+				break
+			}
+		}
+		close(done)
+	}()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := make([]byte, size)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := l.ReadAt(b, off); err != nil {
+					t.Error(err)
+					return
+				}
+				if bytes.Count(b, b[:1]) != len(b) {
+					t.Error("read partially written content")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLUKS2ChangeKey(t *testing.T) {
+	useCheapKDF(t)
+
+	key := []byte("passphrase")
+	newKey := []byte("new passphrase")
+	data := make([]byte, 2*luks2DataSectorSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := EncryptLUKS2(&buf, bytes.NewReader(data), key); err != nil {
+		t.Fatal(err)
+	}
+
+	// Place the volume within a file, following unrelated content.
+	const offset = 4096
+	path := filepath.Join(t.TempDir(), "image")
+	if err := os.WriteFile(path, append(bytes.Repeat([]byte{0xaa}, offset), buf.Bytes()...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	size := int64(buf.Len())
+
+	// checkKey checks whether key unlocks the volume, and the content that
+	// it reads.
+	checkKey := func(t *testing.T, key []byte, want bool) {
+		t.Helper()
+
+		l, err := NewLUKS2ReadWriter(f, offset, size, key)
+		if !want {
+			if !errors.Is(err, ErrInvalidPassphrase) {
+				t.Errorf("got error %v, want %v", err, ErrInvalidPassphrase)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, len(data))
+		if _, err := l.ReadAt(got, 0); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("decrypted content differs")
+		}
+	}
+
+	if err := AddLUKS2Key(f, offset, size, []byte("bad"), newKey); !errors.Is(err, ErrInvalidPassphrase) {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidPassphrase)
+	}
+
+	if err := AddLUKS2Key(f, offset, size, key, newKey); err != nil {
+		t.Fatal(err)
+	}
+	checkKey(t, key, true)
+	checkKey(t, newKey, true)
+
+	if err := RemoveLUKS2Key(f, offset, size, key); err != nil {
+		t.Fatal(err)
+	}
+	checkKey(t, key, false)
+	checkKey(t, newKey, true)
+
+	if err := RemoveLUKS2Key(f, offset, size, newKey); err == nil {
+		t.Error("unexpected success removing last keyslot")
+	}
+
+	// Both copies of the header must hold the updated metadata, with the
+	// same sequence ID.
+	hdr := make([]byte, 2*luks2HeaderSize)
+	if _, err := f.ReadAt(hdr, offset); err != nil {
+		t.Fatal(err)
+	}
+	primary, secondary := hdr[:luks2HeaderSize], hdr[luks2HeaderSize:]
+	if !bytes.Equal(primary[luks2BinaryHeaderSize:], secondary[luks2BinaryHeaderSize:]) {
+		t.Errorf("header copies hold different metadata")
+	}
+	if got, want := binary.BigEndian.Uint64(secondary[16:]), binary.BigEndian.Uint64(primary[16:]); got != want || got != 3 {
+		t.Errorf("got sequence IDs %d and %d, want 3", want, got)
+	}
+	for i, h := range [][]byte{primary, secondary} {
+		c := bytes.Clone(h)
+		clear(c[448:512])
+		if sum := sha256.Sum256(c); !bytes.Equal(h[448:480], sum[:]) {
+			t.Errorf("header %d checksum mismatch", i)
+		}
+	}
+}
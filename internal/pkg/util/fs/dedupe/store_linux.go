@@ -0,0 +1,381 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package dedupe implements a content-addressed store, used to share the
+// content of identical files between unpacked container root filesystems.
+//
+// Files share data extents with an object in the store through reflinks, while
+// keeping their own metadata. Modifying a reflinked file does not affect any
+// other file. Only filesystems that support reflinks (XFS, btrfs etc.) are
+// deduplicated, as hardlinks would share modifications made in place.
+package dedupe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ccoveille/go-safecast/v2"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// objectsDir holds objects, named by content digest.
+	objectsDir = "objects"
+	// tmpDir holds objects while they are being created.
+	tmpDir = "tmp"
+	// savingsFile records the space saved by reflinking files to objects,
+	// which cannot be determined from the objects themselves.
+	savingsFile = "reflink-savings"
+	// dedupeRangeMax is the largest range passed to a single FIDEDUPERANGE
+	// call. The kernel may process less than this in one call.
+	dedupeRangeMax = 16 << 20
+)
+
+var (
+	// errCrossDevice is returned when files cannot be shared with the store,
+	// because they are on a different filesystem.
+	errCrossDevice = errors.New("content store is on a different filesystem")
+	// ErrReflinkUnsupported is returned by Dedupe when reflinks are not
+	// supported.
+	ErrReflinkUnsupported = errors.New("filesystem does not support reflinks")
+)
+
+// Store is a content-addressed store of file content.
+type Store struct {
+	dir string
+}
+
+// Stats describes the result of deduplicating a directory tree.
+type Stats struct {
+	// Files is the number of files that now share content with an existing
+	// object in the store.
+	Files int
+	// Saved is the total size of those files.
+	Saved int64
+}
+
+// Usage describes the content of a Store.
+type Usage struct {
+	// Objects is the number of objects in the store.
+	Objects int
+	// Size is the total size of the objects.
+	Size int64
+	// Saved is the space saved by files sharing content with the objects.
+	// Savings are recorded when files are deduplicated, and are not reduced
+	// when the files are later removed.
+	Saved int64
+}
+
+// New returns a Store held in dir, which is created if necessary.
+func New(dir string) (*Store, error) {
+	for _, d := range []string{objectsDir, tmpDir} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o700); err != nil {
+			return nil, fmt.Errorf("while creating content store: %w", err)
+		}
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Dedupe shares the content of regular files under root with objects in the
+// store, adding objects for content that is not already held. If the
+// filesystem does not support reflinks, ErrReflinkUnsupported is returned.
+func (s *Store) Dedupe(root string) (Stats, error) {
+	d := deduper{store: s}
+	err := filepath.WalkDir(root, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !de.Type().IsRegular() {
+			return nil
+		}
+		return d.file(path)
+	})
+	if d.stats.Saved > 0 {
+		if err := s.addReflinkSavings(d.stats.Saved); err != nil {
+			sylog.Warningf("Could not record content store savings: %v", err)
+		}
+	}
+	if err != nil {
+		return d.stats, fmt.Errorf("while deduplicating %s: %w", root, err)
+	}
+	return d.stats, nil
+}
+
+// Clean removes the objects that have not been shared with a file for the
+// specified number of days, and returns their number and total size. Files
+// that share content with a removed object keep their data extents. If dryRun
+// is true, the objects are only counted.
+func (s *Store) Clean(days int, dryRun bool) (int, int64, error) {
+	var n int
+	var size int64
+	err := filepath.WalkDir(filepath.Join(s.dir, objectsDir), func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !de.Type().IsRegular() {
+			return nil
+		}
+		fi, err := de.Info()
+		if err != nil {
+			return err
+		}
+		if time.Since(fi.ModTime()) < time.Duration(days)*24*time.Hour {
+			return nil
+		}
+		sylog.Debugf("Removing content store object %s, not shared for %d days", de.Name(), days)
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		n++
+		size += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return n, size, fmt.Errorf("while removing content store objects: %w", err)
+	}
+	return n, size, nil
+}
+
+// Usage returns the number and size of objects in the store, and the space
+// saved by files sharing them.
+func (s *Store) Usage() (Usage, error) {
+	var u Usage
+	err := filepath.WalkDir(filepath.Join(s.dir, objectsDir), func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !de.Type().IsRegular() {
+			return nil
+		}
+		fi, err := de.Info()
+		if err != nil {
+			return err
+		}
+		u.Objects++
+		u.Size += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return u, fmt.Errorf("while reading content store: %w", err)
+	}
+
+	u.Saved, err = s.reflinkSavings()
+	return u, err
+}
+
+// deduper holds the state of a single Dedupe operation.
+type deduper struct {
+	store *Store
+	stats Stats
+}
+
+func (d *deduper) file(path string) error {
+	var st unix.Stat_t
+	if err := unix.Lstat(path, &st); err != nil {
+		return err
+	}
+	// Files that are hardlinked within the tree already share their content.
+	if st.Size == 0 || st.Nlink > 1 {
+		return nil
+	}
+
+	digest, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+
+	err = d.reflink(path, digest, st.Size)
+	if isUnsupported(err) {
+		return fmt.Errorf("%w: %v", ErrReflinkUnsupported, err)
+	}
+	return err
+}
+
+// reflink shares the content of the file at path with the object for digest,
+// creating the object if it does not exist. The modification time of an
+// existing object is updated, as it is used by Clean.
+func (d *deduper) reflink(path, digest string, size int64) error {
+	obj := d.store.objectPath(digest)
+	if _, err := os.Stat(obj); errors.Is(err, os.ErrNotExist) {
+		return d.store.cloneObject(path, obj)
+	}
+
+	same, err := dedupeRange(obj, path, size)
+	if err != nil || !same {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(obj, now, now); err != nil {
+		sylog.Debugf("Could not update modification time of %s: %v", obj, err)
+	}
+	d.stats.Files++
+	d.stats.Saved += size
+	return nil
+}
+
+// objectPath returns the path of the object for digest.
+func (s *Store) objectPath(digest string) string {
+	return filepath.Join(s.dir, objectsDir, digest[:2], digest)
+}
+
+// cloneObject creates the object obj as a reflink of the file at path.
+func (s *Store) cloneObject(path, obj string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, tmpDir), "object-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	cloneErr := unix.IoctlFileClone(int(tmp.Fd()), int(src.Fd()))
+	if err := tmp.Close(); err != nil && cloneErr == nil {
+		cloneErr = err
+	}
+	if cloneErr != nil {
+		return cloneErr
+	}
+
+	if err := os.MkdirAll(filepath.Dir(obj), 0o700); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), obj)
+}
+
+// dedupeRange shares the extents of obj with the file at path. The kernel
+// verifies that content is identical, and reports false if it is not.
+func dedupeRange(obj, path string, size int64) (bool, error) {
+	src, err := os.Open(obj)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+
+	// The owner of a file may deduplicate it through a read-only descriptor.
+	dst, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer dst.Close()
+
+	length, err := safecast.Convert[uint64](size)
+	if err != nil {
+		return false, err
+	}
+
+	var off uint64
+	for off < length {
+		r := unix.FileDedupeRange{
+			Src_offset: off,
+			Src_length: min(length-off, dedupeRangeMax),
+			Info: []unix.FileDedupeRangeInfo{
+				{Dest_fd: int64(dst.Fd()), Dest_offset: off},
+			},
+		}
+		if err := unix.IoctlFileDedupeRange(int(src.Fd()), &r); err != nil {
+			return false, err
+		}
+		info := r.Info[0]
+		switch {
+		case info.Status == unix.FILE_DEDUPE_RANGE_DIFFERS:
+			sylog.Debugf("Content of %s differs from %s, not deduplicating", path, obj)
+			return false, nil
+		case info.Status < 0:
+			return false, unix.Errno(-info.Status)
+		case info.Bytes_deduped == 0:
+			return false, fmt.Errorf("no progress deduplicating %s", path)
+		}
+		off += info.Bytes_deduped
+	}
+	return true, nil
+}
+
+// isUnsupported returns true if err indicates that reflinks are not supported
+// between the store and a file.
+func isUnsupported(err error) bool {
+	for _, e := range []error{unix.EOPNOTSUPP, unix.ENOTTY, unix.EINVAL, unix.EXDEV, unix.ENOSYS} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// addReflinkSavings adds n bytes to the recorded reflink savings.
+func (s *Store) addReflinkSavings(n int64) error {
+	f, err := os.OpenFile(filepath.Join(s.dir, savingsFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		return err
+	}
+	defer unix.Flock(int(f.Fd()), unix.LOCK_UN) //nolint:errcheck
+
+	saved, err := readSavings(f)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt([]byte(strconv.FormatInt(saved+n, 10)), 0)
+	return err
+}
+
+func (s *Store) reflinkSavings() (int64, error) {
+	f, err := os.Open(filepath.Join(s.dir, savingsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return readSavings(f)
+}
+
+func readSavings(r io.Reader) (int64, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+}
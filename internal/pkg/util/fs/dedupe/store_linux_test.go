@@ -0,0 +1,174 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package dedupe
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testFiles = map[string]string{
+	"bin/a":       "content a",
+	"bin/b":       "content b",
+	"etc/a-copy":  "content a",
+	"etc/empty":   "",
+	"lib/x/large": string(make([]byte, 100000)),
+}
+
+func writeTestRoot(t *testing.T, root string) {
+	t.Helper()
+
+	mtime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, content := range testFiles {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkTestRoot(t *testing.T, root string) {
+	t.Helper()
+
+	for name, content := range testFiles {
+		b, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("content of %s changed", name)
+		}
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := New(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root1 := filepath.Join(tmpDir, "root1")
+	writeTestRoot(t, root1)
+	stats, err := s.Dedupe(root1)
+	if errors.Is(err, ErrReflinkUnsupported) {
+		// The tree must be left as it was.
+		checkTestRoot(t, root1)
+		t.Skipf("reflinks not supported: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTestRoot(t, root1)
+
+	// bin/a and etc/a-copy share content within the first root.
+	if stats.Files != 1 || stats.Saved != int64(len(testFiles["bin/a"])) {
+		t.Errorf("first root: got %d files, %d bytes saved, want 1 file, %d bytes", stats.Files, stats.Saved, len(testFiles["bin/a"]))
+	}
+
+	root2 := filepath.Join(tmpDir, "root2")
+	writeTestRoot(t, root2)
+	stats, err = s.Dedupe(root2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTestRoot(t, root2)
+
+	var wantSaved int64
+	for _, c := range testFiles {
+		wantSaved += int64(len(c))
+	}
+	if stats.Files != len(testFiles)-1 || stats.Saved != wantSaved {
+		t.Errorf("second root: got %d files, %d bytes saved, want %d files, %d bytes", stats.Files, stats.Saved, len(testFiles)-1, wantSaved)
+	}
+
+	u, err := s.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Objects for bin/a, bin/b, lib/x/large.
+	if u.Objects != 3 {
+		t.Errorf("got %d objects, want 3", u.Objects)
+	}
+	if want := wantSaved + int64(len(testFiles["bin/a"])); u.Saved != want {
+		t.Errorf("got %d bytes saved, want %d", u.Saved, want)
+	}
+
+	// A reflinked file modified in place must not modify the other files.
+	if err := os.WriteFile(filepath.Join(root1, "bin/a"), []byte("modified"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checkTestRoot(t, root2)
+
+	// A further dedupe of a deduplicated root must not fail, or change content.
+	if _, err := s.Dedupe(root2); err != nil {
+		t.Fatal(err)
+	}
+	checkTestRoot(t, root2)
+}
+
+func TestClean(t *testing.T) {
+	tmpDir := t.TempDir()
+	s, err := New(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	objects := map[string]time.Time{
+		fmt.Sprintf("%x", sha256.Sum256([]byte("old"))):    old,
+		fmt.Sprintf("%x", sha256.Sum256([]byte("recent"))): time.Now(),
+	}
+	for digest, mtime := range objects {
+		path := s.objectPath(digest)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(digest), 0o444); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, size, err := s.Clean(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || size != 64 {
+		t.Errorf("dry run: got %d objects, %d bytes, want 1 object, 64 bytes", n, size)
+	}
+	u, err := s.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Objects != 2 {
+		t.Errorf("got %d objects after dry run, want 2", u.Objects)
+	}
+
+	if _, _, err := s.Clean(1, false); err != nil {
+		t.Fatal(err)
+	}
+	u, err = s.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Objects != 1 {
+		t.Errorf("got %d objects after clean, want 1", u.Objects)
+	}
+}
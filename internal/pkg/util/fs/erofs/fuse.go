@@ -0,0 +1,58 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package erofs mounts erofs filesystems with the kernel erofs driver, or
+// erofsfuse.
+package erofs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/v4/internal/pkg/util/bin"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/fuse"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// FUSEMount mounts the erofs filesystem found at offset in the file at path,
+// read-only on mountPath, with erofsfuse.
+func FUSEMount(ctx context.Context, offset uint64, path, mountPath string, allowOther bool) error {
+	erofsfuse, err := bin.FindBin("erofsfuse")
+	if err != nil {
+		return fmt.Errorf("mounting erofs requires erofsfuse to be installed: %w", err)
+	}
+	// We shouldn't perform the mount unless we can eventually unmount it.
+	if _, err := bin.FindBin("fusermount"); err != nil {
+		return fmt.Errorf("mounting erofs requires fusermount to be installed: %w", err)
+	}
+
+	opts := []string{"ro", "nosuid", "nodev"}
+	if allowOther {
+		opts = append(opts, "allow_other")
+	}
+	args := []string{
+		fmt.Sprintf("--offset=%d", offset),
+		"-o", strings.Join(opts, ","),
+		filepath.Clean(path),
+		filepath.Clean(mountPath),
+	}
+
+	sylog.Debugf("Executing FUSE mount command: %s %s", erofsfuse, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, erofsfuse, args...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("encountered error while trying to mount erofs image %q with FUSE at %s: %w", path, mountPath, err)
+	}
+	return nil
+}
+
+// FUSEUnmount unmounts an erofs filesystem mounted by FUSEMount.
+func FUSEUnmount(ctx context.Context, mountPath string) error {
+	return fuse.UnmountWithFuse(ctx, mountPath)
+}
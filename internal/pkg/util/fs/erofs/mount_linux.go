@@ -0,0 +1,96 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package erofs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"github.com/sylabs/singularity/v4/pkg/util/loop"
+	"golang.org/x/sys/unix"
+)
+
+// KernelSupported returns whether the running kernel can mount erofs
+// filesystems.
+func KernelSupported() bool {
+	f, err := os.Open("/proc/filesystems")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) > 0 && fields[len(fields)-1] == "erofs" {
+			return true
+		}
+	}
+	return false
+}
+
+// KernelMount mounts the erofs filesystem found at offset, and of size bytes,
+// in the file at path, read-only on mountPath, with the kernel erofs driver. A
+// size of 0 extends to the end of the file. The filesystem is mounted through
+// a loop device, that is detached when it is unmounted, so CAP_SYS_ADMIN is
+// required.
+func KernelMount(offset, size uint64, path, mountPath string) error {
+	maxLoopDev, err := loop.GetMaxLoopDevices()
+	if err != nil {
+		return err
+	}
+	loopDev := &loop.Device{
+		MaxLoopDevices: maxLoopDev,
+		Info: &unix.LoopInfo64{
+			Sizelimit: size,
+			Offset:    offset,
+			Flags:     unix.LO_FLAGS_AUTOCLEAR | unix.LO_FLAGS_READ_ONLY,
+		},
+	}
+	idx := 0
+	if err := loopDev.AttachFromPath(path, os.O_RDONLY, &idx); err != nil {
+		return fmt.Errorf("failed to attach image %s: %w", path, err)
+	}
+	defer loopDev.Close()
+
+	dev := fmt.Sprintf("/dev/loop%d", idx)
+	sylog.Debugf("Mounting erofs from %s to %s", dev, mountPath)
+	if err := unix.Mount(dev, mountPath, "erofs", unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("while mounting erofs image %q at %s: %w", path, mountPath, err)
+	}
+	return nil
+}
+
+// Mount mounts the erofs filesystem found at offset, and of size bytes, in
+// the file at path, read-only on mountPath. The kernel erofs driver is used
+// where it is available, and the caller is root, with erofsfuse used otherwise.
+// It returns whether the kernel driver was used, which must be passed to
+// Unmount.
+func Mount(ctx context.Context, offset, size uint64, path, mountPath string) (kernel bool, err error) {
+	if os.Geteuid() == 0 && KernelSupported() {
+		err := KernelMount(offset, size, path, mountPath)
+		if err == nil {
+			return true, nil
+		}
+		sylog.Debugf("Falling back to erofsfuse: %v", err)
+	}
+	return false, FUSEMount(ctx, offset, path, mountPath, false)
+}
+
+// Unmount unmounts an erofs filesystem mounted by Mount.
+func Unmount(ctx context.Context, mountPath string, kernel bool) error {
+	if !kernel {
+		return FUSEUnmount(ctx, mountPath)
+	}
+	if err := unix.Unmount(mountPath, unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("while unmounting %s: %w", mountPath, err)
+	}
+	return nil
+}
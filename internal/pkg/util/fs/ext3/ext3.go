@@ -0,0 +1,339 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package ext3 provides read-only, in-process access to the content of ext2,
+// ext3 and ext4 filesystem images, such as those used for Singularity image
+// partitions and overlays.
+package ext3
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	superblockOffset = 1024
+	superblockMagic  = 0xef53
+	rootInode        = 2
+
+	incompatFiletype   = 0x2
+	incompatRecover    = 0x4
+	incompatExtents    = 0x40
+	incompat64Bit      = 0x80
+	incompatMMP        = 0x100
+	incompatFlexBG     = 0x200
+	incompatCsumSeed   = 0x2000
+	incompatLargeDir   = 0x4000
+	incompatCasefold   = 0x20000
+	supportedIncompats = incompatFiletype | incompatRecover | incompatExtents |
+		incompat64Bit | incompatMMP | incompatFlexBG | incompatCsumSeed |
+		incompatLargeDir | incompatCasefold
+)
+
+// ErrUnsupported is returned when a filesystem uses features that cannot be
+// read.
+var ErrUnsupported = errors.New("unsupported filesystem features")
+
+// superblock holds the fields of the ext superblock used to read the
+// filesystem.
+type superblock struct {
+	blocksCount     uint64
+	firstDataBlock  uint32
+	logBlockSize    uint32
+	blocksPerGroup  uint32
+	inodesPerGroup  uint32
+	inodeSize       uint16
+	featureIncompat uint32
+	descSize        uint16
+}
+
+// FS is a read-only view of an ext filesystem. It implements fs.FS,
+// fs.ReadDirFS, fs.StatFS and fs.ReadLinkFS. Symbolic links are never
+// followed, so a path must not traverse a symbolic link.
+type FS struct {
+	r          io.ReaderAt
+	sb         superblock
+	blockSize  int64
+	inodeTable []uint64
+}
+
+var (
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadLinkFS = (*FS)(nil)
+)
+
+// New returns a read-only view of the ext filesystem held in r.
+func New(r io.ReaderAt) (*FS, error) {
+	b := make([]byte, 1024)
+	if _, err := r.ReadAt(b, superblockOffset); err != nil {
+		return nil, fmt.Errorf("while reading superblock: %w", err)
+	}
+	le := binary.LittleEndian
+	if le.Uint16(b[56:]) != superblockMagic {
+		return nil, fmt.Errorf("not an ext filesystem")
+	}
+
+	sb := superblock{
+		blocksCount:     uint64(le.Uint32(b[4:])),
+		firstDataBlock:  le.Uint32(b[20:]),
+		logBlockSize:    le.Uint32(b[24:]),
+		blocksPerGroup:  le.Uint32(b[32:]),
+		inodesPerGroup:  le.Uint32(b[40:]),
+		inodeSize:       128,
+		featureIncompat: le.Uint32(b[96:]),
+		descSize:        32,
+	}
+	if le.Uint32(b[76:]) > 0 {
+		sb.inodeSize = le.Uint16(b[88:])
+	}
+	if sb.featureIncompat&^supportedIncompats != 0 {
+		return nil, fmt.Errorf("%w: %#x", ErrUnsupported, sb.featureIncompat&^supportedIncompats)
+	}
+	if sb.featureIncompat&incompat64Bit != 0 {
+		sb.blocksCount |= uint64(le.Uint32(b[336:])) << 32
+		if ds := le.Uint16(b[254:]); ds != 0 {
+			sb.descSize = ds
+		}
+	}
+	if sb.logBlockSize > 6 || sb.blocksPerGroup == 0 || sb.inodesPerGroup == 0 {
+		return nil, fmt.Errorf("invalid superblock")
+	}
+
+	f := &FS{
+		r:         r,
+		sb:        sb,
+		blockSize: 1024 << sb.logBlockSize,
+	}
+	if err := f.readGroupDescriptors(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// readGroupDescriptors records the location of the inode table of each block
+// group.
+func (f *FS) readGroupDescriptors() error {
+	groups := (f.sb.blocksCount - uint64(f.sb.firstDataBlock) + uint64(f.sb.blocksPerGroup) - 1) / uint64(f.sb.blocksPerGroup)
+	b := make([]byte, groups*uint64(f.sb.descSize))
+	off := (int64(f.sb.firstDataBlock) + 1) * f.blockSize
+	if _, err := f.r.ReadAt(b, off); err != nil {
+		return fmt.Errorf("while reading group descriptors: %w", err)
+	}
+
+	le := binary.LittleEndian
+	f.inodeTable = make([]uint64, groups)
+	for i := range f.inodeTable {
+		d := b[uint64(i)*uint64(f.sb.descSize):]
+		f.inodeTable[i] = uint64(le.Uint32(d[8:]))
+		if f.sb.descSize >= 64 {
+			f.inodeTable[i] |= uint64(le.Uint32(d[40:])) << 32
+		}
+	}
+	return nil
+}
+
+// lookup returns the inode at the slash-separated path name, relative to the
+// root directory.
+func (f *FS) lookup(op, name string) (*inode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	ino, err := f.inode(rootInode)
+	if err != nil {
+		return nil, err
+	}
+	if name == "." {
+		return ino, nil
+	}
+	for elem := range strings.SplitSeq(name, "/") {
+		if !ino.isDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		entries, err := f.readDir(ino)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		var found *dirent
+		for i := range entries {
+			if entries[i].name == elem {
+				found = &entries[i]
+				break
+			}
+		}
+		if found == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		if ino, err = f.inode(found.ino); err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+	}
+	return ino, nil
+}
+
+// Open opens the named file. A symbolic link is opened as itself.
+func (f *FS) Open(name string) (fs.File, error) {
+	ino, err := f.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &file{fs: f, ino: ino, info: fileInfo{name: path.Base(name), ino: ino}}, nil
+}
+
+// Stat returns a FileInfo describing the named file. A symbolic link is
+// described as itself.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	ino, err := f.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{name: path.Base(name), ino: ino}, nil
+}
+
+// Lstat returns a FileInfo describing the named file.
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	return f.Stat(name)
+}
+
+// ReadLink returns the destination of the named symbolic link.
+func (f *FS) ReadLink(name string) (string, error) {
+	ino, err := f.lookup("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if ino.mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := f.readLink(ino)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return target, nil
+}
+
+// ReadDir reads the named directory, returning its entries sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	d, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	dir, ok := d.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// fileInfo describes an inode.
+type fileInfo struct {
+	name string
+	ino  *inode
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.ino.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.ino.mode() }
+func (fi fileInfo) ModTime() time.Time { return time.Unix(int64(fi.ino.mtime), 0) }
+func (fi fileInfo) IsDir() bool        { return fi.ino.isDir() }
+func (fi fileInfo) Sys() any           { return nil }
+
+// dirEntry is an entry read from a directory.
+type dirEntry struct {
+	fs   *FS
+	name string
+	ino  uint32
+}
+
+func (d dirEntry) Name() string { return d.name }
+
+func (d dirEntry) IsDir() bool {
+	fi, err := d.Info()
+	return err == nil && fi.IsDir()
+}
+
+func (d dirEntry) Type() fs.FileMode {
+	fi, err := d.Info()
+	if err != nil {
+		return 0
+	}
+	return fi.Mode().Type()
+}
+
+func (d dirEntry) Info() (fs.FileInfo, error) {
+	ino, err := d.fs.inode(d.ino)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{name: d.name, ino: ino}, nil
+}
+
+// file is an open file or directory.
+type file struct {
+	fs     *FS
+	ino    *inode
+	info   fileInfo
+	offset int64
+	// entries holds the remaining entries of a directory being read.
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *file) Close() error { return nil }
+
+func (f *file) Read(b []byte) (int, error) {
+	if !f.ino.mode().IsRegular() {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: errors.New("not a regular file")}
+	}
+	n, err := f.ReadAt(b, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadAt reads len(b) bytes from the file, starting at byte offset off.
+func (f *file) ReadAt(b []byte, off int64) (int, error) {
+	return f.fs.readAt(f.ino, b, off)
+}
+
+func (f *file) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.ino.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.info.name, Err: errors.New("not a directory")}
+	}
+	if !f.read {
+		dirents, err := f.fs.readDir(f.ino)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range dirents {
+			f.entries = append(f.entries, dirEntry{fs: f.fs, name: d.name, ino: d.ino})
+		}
+		f.read = true
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
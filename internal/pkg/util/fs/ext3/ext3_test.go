@@ -0,0 +1,154 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ext3
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// writeSource populates dir with the content used to create test filesystems,
+// returning the content of regular files by path.
+func writeSource(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+
+	rnd := rand.New(rand.NewSource(1))
+	big := make([]byte, 3*1024*1024+123)
+	rnd.Read(big)
+
+	files := map[string][]byte{
+		"hello":        []byte("hello world\n"),
+		"empty":        {},
+		"dir/sub/file": []byte("nested"),
+		"big":          big,
+	}
+	for i := range 300 {
+		files[fmt.Sprintf("many/file-%03d", i)] = []byte(fmt.Sprint(i))
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A sparse file, with a hole before and after its content.
+	sparse, err := os.Create(filepath.Join(dir, "sparse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sparse.WriteAt([]byte("data"), 1024*1024); err != nil {
+		t.Fatal(err)
+	}
+	if err := sparse.Truncate(2 * 1024 * 1024); err != nil {
+		t.Fatal(err)
+	}
+	sparse.Close()
+	sparseContent := make([]byte, 2*1024*1024)
+	copy(sparseContent[1024*1024:], "data")
+	files["sparse"] = sparseContent
+
+	if err := os.Chmod(filepath.Join(dir, "hello"), fs.ModeSetuid|0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("dir/sub/file", filepath.Join(dir, "short-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(strings.Repeat("long/", 30)+"target", filepath.Join(dir, "long-link")); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestFS(t *testing.T) {
+	tests := []struct {
+		name string
+		mkfs string
+		args []string
+	}{
+		{"ext3-1k", "mkfs.ext3", []string{"-b", "1024"}},
+		{"ext3-4k", "mkfs.ext3", []string{"-b", "4096"}},
+		{"ext4", "mkfs.ext4", nil},
+	}
+
+	src := t.TempDir()
+	files := writeSource(t, src)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mkfs, err := exec.LookPath(tt.mkfs)
+			if err != nil {
+				t.Skipf("%s not found", tt.mkfs)
+			}
+			img := filepath.Join(t.TempDir(), "image")
+			args := append([]string{"-q", "-F", "-d", src}, tt.args...)
+			args = append(args, img, "64M")
+			if out, err := exec.Command(mkfs, args...).CombinedOutput(); err != nil {
+				t.Fatalf("%s failed: %v: %s", tt.mkfs, err, out)
+			}
+
+			f, err := os.Open(img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			fsys, err := New(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := fstest.TestFS(fsys, "hello", "dir/sub/file", "many/file-299", "short-link", "long-link"); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range files {
+				got, err := fs.ReadFile(fsys, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s: content differs", name)
+				}
+			}
+
+			fi, err := fs.Stat(fsys, "hello")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := fs.ModeSetuid | 0o755; fi.Mode() != want {
+				t.Errorf("got mode %v, want %v", fi.Mode(), want)
+			}
+
+			for name, want := range map[string]string{
+				"short-link": "dir/sub/file",
+				"long-link":  strings.Repeat("long/", 30) + "target",
+			} {
+				got, err := fs.ReadLink(fsys, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("%s: got target %q, want %q", name, got, want)
+				}
+			}
+
+			if _, err := fsys.Open("hello/child"); err == nil {
+				t.Errorf("unexpected success opening child of a file")
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package ext3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	inodeFlagExtents    = 0x80000
+	inodeFlagInlineData = 0x10000000
+
+	extentMagic      = 0xf30a
+	extentInitMaxLen = 32768
+
+	// fastSymlinkSize is the size of the i_block area, which holds the
+	// target of short symbolic links.
+	fastSymlinkSize = 60
+)
+
+// inode holds the fields of an inode used to read its content.
+type inode struct {
+	num   uint32
+	raw   uint16
+	size  int64
+	mtime uint32
+	flags uint32
+	block [fastSymlinkSize]byte
+	// extents maps the content of the inode to disk blocks, once read.
+	extents []extent
+	mapped  bool
+}
+
+// extent is a run of contiguous blocks of an inode.
+type extent struct {
+	logical  uint64
+	physical uint64
+	length   uint64
+	// uninit is set for preallocated extents, which read as zeros.
+	uninit bool
+}
+
+func (i *inode) isDir() bool {
+	return i.raw&0xf000 == 0x4000
+}
+
+// mode converts the unix mode of the inode to an fs.FileMode.
+func (i *inode) mode() fs.FileMode {
+	m := fs.FileMode(i.raw & 0o777)
+	switch i.raw & 0xf000 {
+	case 0x1000:
+		m |= fs.ModeNamedPipe
+	case 0x2000:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	case 0x4000:
+		m |= fs.ModeDir
+	case 0x6000:
+		m |= fs.ModeDevice
+	case 0xa000:
+		m |= fs.ModeSymlink
+	case 0xc000:
+		m |= fs.ModeSocket
+	}
+	if i.raw&0o4000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if i.raw&0o2000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if i.raw&0o1000 != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
+
+// inode reads inode number n.
+func (f *FS) inode(n uint32) (*inode, error) {
+	if n == 0 {
+		return nil, fmt.Errorf("invalid inode 0")
+	}
+	group := (n - 1) / f.sb.inodesPerGroup
+	index := (n - 1) % f.sb.inodesPerGroup
+	if int(group) >= len(f.inodeTable) {
+		return nil, fmt.Errorf("invalid inode %d", n)
+	}
+
+	b := make([]byte, 128)
+	off := int64(f.inodeTable[group])*f.blockSize + int64(index)*int64(f.sb.inodeSize)
+	if _, err := f.r.ReadAt(b, off); err != nil {
+		return nil, fmt.Errorf("while reading inode %d: %w", n, err)
+	}
+
+	le := binary.LittleEndian
+	ino := &inode{
+		num:   n,
+		raw:   le.Uint16(b[0:]),
+		size:  int64(uint64(le.Uint32(b[4:])) | uint64(le.Uint32(b[108:]))<<32),
+		mtime: le.Uint32(b[16:]),
+		flags: le.Uint32(b[32:]),
+	}
+	copy(ino.block[:], b[40:100])
+	if ino.flags&inodeFlagInlineData != 0 {
+		return nil, fmt.Errorf("inode %d: %w: inline data", n, ErrUnsupported)
+	}
+	return ino, nil
+}
+
+// readBlock reads block n of the filesystem.
+func (f *FS) readBlock(n uint64) ([]byte, error) {
+	b := make([]byte, f.blockSize)
+	if _, err := f.r.ReadAt(b, int64(n)*f.blockSize); err != nil {
+		return nil, fmt.Errorf("while reading block %d: %w", n, err)
+	}
+	return b, nil
+}
+
+// mapInode records the extents of the content of ino.
+func (f *FS) mapInode(ino *inode) error {
+	if ino.mapped {
+		return nil
+	}
+	var err error
+	if ino.flags&inodeFlagExtents != 0 {
+		err = f.extentTree(ino.block[:], &ino.extents)
+	} else {
+		err = f.blockMap(ino)
+	}
+	if err != nil {
+		return fmt.Errorf("inode %d: %w", ino.num, err)
+	}
+	ino.mapped = true
+	return nil
+}
+
+// extentTree appends the extents held in the extent tree node b, and its
+// children, to ext.
+func (f *FS) extentTree(b []byte, ext *[]extent) error {
+	le := binary.LittleEndian
+	if le.Uint16(b[0:]) != extentMagic {
+		return fmt.Errorf("invalid extent header")
+	}
+	entries := int(le.Uint16(b[2:]))
+	depth := le.Uint16(b[6:])
+	if 12+entries*12 > len(b) {
+		return fmt.Errorf("invalid extent header")
+	}
+
+	for i := range entries {
+		e := b[12+i*12:]
+		if depth == 0 {
+			length := uint64(le.Uint16(e[4:]))
+			uninit := length > extentInitMaxLen
+			if uninit {
+				length -= extentInitMaxLen
+			}
+			*ext = append(*ext, extent{
+				logical:  uint64(le.Uint32(e[0:])),
+				physical: uint64(le.Uint16(e[6:]))<<32 | uint64(le.Uint32(e[8:])),
+				length:   length,
+				uninit:   uninit,
+			})
+			continue
+		}
+		child, err := f.readBlock(uint64(le.Uint16(e[8:]))<<32 | uint64(le.Uint32(e[4:])))
+		if err != nil {
+			return err
+		}
+		if err := f.extentTree(child, ext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockMap records the extents of an inode that uses direct and indirect
+// block maps, as in ext2 and ext3.
+func (f *FS) blockMap(ino *inode) error {
+	nblocks := uint64((ino.size + f.blockSize - 1) / f.blockSize)
+	add := func(logical uint64, physical uint32) {
+		if physical == 0 {
+			return
+		}
+		if n := len(ino.extents); n > 0 {
+			last := &ino.extents[n-1]
+			if last.logical+last.length == logical && last.physical+last.length == uint64(physical) {
+				last.length++
+				return
+			}
+		}
+		ino.extents = append(ino.extents, extent{logical: logical, physical: uint64(physical), length: 1})
+	}
+
+	le := binary.LittleEndian
+	var logical uint64
+	for i := 0; i < 12 && logical < nblocks; i++ {
+		add(logical, le.Uint32(ino.block[i*4:]))
+		logical++
+	}
+	for level := 1; level <= 3 && logical < nblocks; level++ {
+		var err error
+		logical, err = f.indirect(le.Uint32(ino.block[(11+level)*4:]), level, logical, nblocks, add)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indirect maps the blocks referenced by an indirect block of the given
+// level, the first of which has index logical in the file. It returns the
+// index of the block that follows them.
+func (f *FS) indirect(ptr uint32, level int, logical, nblocks uint64, add func(uint64, uint32)) (uint64, error) {
+	per := uint64(f.blockSize / 4)
+	if ptr == 0 {
+		span := per
+		for range level - 1 {
+			span *= per
+		}
+		return logical + span, nil
+	}
+
+	b, err := f.readBlock(uint64(ptr))
+	if err != nil {
+		return 0, err
+	}
+	le := binary.LittleEndian
+	for i := uint64(0); i < per && logical < nblocks; i++ {
+		p := le.Uint32(b[i*4:])
+		if level == 1 {
+			add(logical, p)
+			logical++
+			continue
+		}
+		if logical, err = f.indirect(p, level-1, logical, nblocks, add); err != nil {
+			return 0, err
+		}
+	}
+	return logical, nil
+}
+
+// readAt reads len(b) bytes of the content of ino, starting at offset off.
+func (f *FS) readAt(ino *inode, b []byte, off int64) (int, error) {
+	if off >= ino.size {
+		return 0, io.EOF
+	}
+	if err := f.mapInode(ino); err != nil {
+		return 0, err
+	}
+
+	want := min(int64(len(b)), ino.size-off)
+	n := int64(0)
+	for n < want {
+		logical := uint64((off + n) / f.blockSize)
+		inBlock := (off + n) % f.blockSize
+
+		i := sort.Search(len(ino.extents), func(i int) bool {
+			return ino.extents[i].logical+ino.extents[i].length > logical
+		})
+
+		var e *extent
+		if i < len(ino.extents) && ino.extents[i].logical <= logical {
+			e = &ino.extents[i]
+		}
+
+		// Read to the end of the extent, or the start of the next extent if
+		// this block is a hole.
+		var runEnd uint64
+		switch {
+		case e != nil:
+			runEnd = e.logical + e.length
+		case i < len(ino.extents):
+			runEnd = ino.extents[i].logical
+		default:
+			runEnd = logical + 1 + uint64((want-n)/f.blockSize)
+		}
+		count := min(int64(runEnd-logical)*f.blockSize-inBlock, want-n)
+		dst := b[n : n+count]
+
+		if e == nil || e.uninit {
+			clear(dst)
+		} else {
+			pos := int64(e.physical+logical-e.logical)*f.blockSize + inBlock
+			if _, err := f.r.ReadAt(dst, pos); err != nil {
+				return int(n), fmt.Errorf("inode %d: %w", ino.num, err)
+			}
+		}
+		n += count
+	}
+
+	if n < int64(len(b)) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// dirent is an entry of a directory.
+type dirent struct {
+	name string
+	ino  uint32
+}
+
+// readDir reads the entries of directory ino, excluding "." and "..".
+func (f *FS) readDir(ino *inode) ([]dirent, error) {
+	b := make([]byte, ino.size)
+	if _, err := f.readAt(ino, b, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	le := binary.LittleEndian
+	var entries []dirent
+	for off := 0; off+8 <= len(b); {
+		recLen := int(le.Uint16(b[off+4:]))
+		if recLen < 8 || off+recLen > len(b) {
+			return nil, fmt.Errorf("inode %d: invalid directory entry", ino.num)
+		}
+		n := le.Uint32(b[off:])
+		nameLen := int(b[off+6])
+		if f.sb.featureIncompat&incompatFiletype == 0 {
+			nameLen = int(le.Uint16(b[off+6:]))
+		}
+		if n != 0 && 8+nameLen <= recLen {
+			name := string(b[off+8 : off+8+nameLen])
+			if name != "." && name != ".." {
+				entries = append(entries, dirent{name: name, ino: n})
+			}
+		}
+		off += recLen
+	}
+	return entries, nil
+}
+
+// readLink returns the target of symbolic link ino.
+func (f *FS) readLink(ino *inode) (string, error) {
+	if ino.size < fastSymlinkSize && ino.flags&inodeFlagExtents == 0 {
+		return string(ino.block[:ino.size]), nil
+	}
+	b := make([]byte, ino.size)
+	if _, err := f.readAt(ino, b, 0); err != nil && err != io.EOF {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// sortEntries sorts directory entries by name.
+func sortEntries(entries []fs.DirEntry) {
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+}
@@ -793,3 +793,14 @@ func WriteFileNoFollow(path string, data []byte, mode os.FileMode) error {
 	_, err = f.Write(data)
 	return err
 }
+
+// ReadFileNoFollow mimics os.ReadFile but with O_NOFOLLOW to avoid following
+// symlinks in the last part of the path.
+func ReadFileNoFollow(path string) ([]byte, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// Copyright (c) 2023-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -34,3 +34,28 @@ func GetMacro(name string) (value string, err error) {
 	}
 	return eval, nil
 }
+
+// Packages returns the version of each package in the rpm database at dbPath,
+// keyed by package name and architecture.
+func Packages(dbPath string) (map[string]string, error) {
+	rpm, err := exec.LookPath("rpm")
+	if err != nil {
+		return nil, fmt.Errorf("rpm command not found: %w", err)
+	}
+
+	qf := "%{NAME}.%{ARCH} %|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\\n"
+	cmd := exec.Command(rpm, "--dbpath", dbPath, "--query", "--all", "--queryformat", qf)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("while querying rpm database: %w", err)
+	}
+
+	pkgs := make(map[string]string)
+	for line := range strings.Lines(string(out)) {
+		name, version, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok {
+			pkgs[name] = version
+		}
+	}
+	return pkgs, nil
+}
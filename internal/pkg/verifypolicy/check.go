@@ -0,0 +1,258 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package verifypolicy
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/cosign"
+	sifsignature "github.com/sylabs/singularity/v4/internal/pkg/signature"
+	"github.com/sylabs/singularity/v4/internal/pkg/signingentity"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+var (
+	errUnsigned      = errors.New("image format does not support signatures")
+	errNoTrustedKeys = errors.New("no trusted key material in scope")
+	errBadMaxAge     = errors.New("revocationmaxage must be a duration, e.g. 72h")
+)
+
+// revocationOptions returns the revocation checks that the chain of cert is
+// subject to.
+func (cert *Certificate) revocationOptions() (sifsignature.RevocationOptions, error) {
+	o := sifsignature.RevocationOptions{
+		OCSP:      cert.OCSP,
+		FetchCRLs: cert.CRLFetch,
+	}
+
+	if cert.OCSPResponses != "" {
+		rs, err := sifsignature.LoadOCSPResponses(cert.OCSPResponses)
+		if err != nil {
+			return o, fmt.Errorf("while loading OCSP responses %s: %w", cert.OCSPResponses, err)
+		}
+		o.OCSPResponses = rs
+	}
+
+	for _, path := range cert.CRLs {
+		crls, err := sifsignature.LoadCRLs(path)
+		if err != nil {
+			return o, fmt.Errorf("while loading CRL %s: %w", path, err)
+		}
+		o.CRLs = append(o.CRLs, crls...)
+	}
+
+	if cert.RevocationMaxAge != "" {
+		d, err := time.ParseDuration(cert.RevocationMaxAge)
+		if err != nil || d < 0 {
+			return o, fmt.Errorf("%w: %s", errBadMaxAge, cert.RevocationMaxAge)
+		}
+		o.MaxAge = d
+	}
+
+	return o, nil
+}
+
+// check verifies that the certificate c chains to a certificate in roots, via
+// intermediates, is valid for code signing, satisfies the subject and subject
+// alternative name constraints of cert, and passes the revocation checks ro.
+func (cert *Certificate) check(c *x509.Certificate, intermediates, roots *x509.CertPool, ro sifsignature.RevocationOptions) error {
+	chains, err := signingentity.CheckCertificate(c, intermediates, roots, cert.Subjects, cert.SANs)
+	if err != nil {
+		return err
+	}
+
+	if ro.Enabled() {
+		return sifsignature.VerifyRevocation(chains[0], ro)
+	}
+	return nil
+}
+
+// load returns the entity described by cert.
+func (cert *Certificate) load() (signingentity.Entity, error) {
+	if cert.Path == "" {
+		return signingentity.Entity{}, errCertificateMissing
+	}
+	if cert.Roots == "" {
+		return signingentity.Entity{}, errCertRootsRequired
+	}
+
+	roots, err := signingentity.LoadCertificatePool(cert.Roots)
+	if err != nil {
+		return signingentity.Entity{}, fmt.Errorf("while loading root certificates %s: %w", cert.Roots, err)
+	}
+
+	var intermediates *x509.CertPool
+	if cert.Intermediates != "" {
+		if intermediates, err = signingentity.LoadCertificatePool(cert.Intermediates); err != nil {
+			return signingentity.Entity{}, fmt.Errorf("while loading intermediate certificates %s: %w", cert.Intermediates, err)
+		}
+	}
+
+	ro, err := cert.revocationOptions()
+	if err != nil {
+		return signingentity.Entity{}, err
+	}
+
+	return signingentity.LoadCertificate(cert.Path, func(c *x509.Certificate) error {
+		return cert.check(c, intermediates, roots, ro)
+	})
+}
+
+// loadEntities returns the certificate and cosign key entities of s. A
+// certificate that is not trusted by s is returned with its Err field set.
+func (s *Scope) loadEntities() ([]signingentity.Entity, error) {
+	var es []signingentity.Entity
+
+	for i := range s.Certificates {
+		e, err := s.Certificates[i].load()
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+
+	for _, path := range s.CosignKeys {
+		e, err := signingentity.LoadCosignKey(path)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+
+	return es, nil
+}
+
+// trustedKeyRing is a keyring holding the keys of an underlying keyring that
+// belong to one of a set of trusted primary key fingerprints. The type
+// satisfies the openpgp.KeyRing interface.
+type trustedKeyRing struct {
+	kr  openpgp.KeyRing
+	fps []string
+}
+
+// filter returns the keys of keys that belong to a trusted entity.
+func (t trustedKeyRing) filter(keys []openpgp.Key) []openpgp.Key {
+	return slices.DeleteFunc(slices.Clone(keys), func(k openpgp.Key) bool {
+		fp := hex.EncodeToString(k.Entity.PrimaryKey.Fingerprint)
+		return !slices.ContainsFunc(t.fps, func(v string) bool { return strings.EqualFold(v, fp) })
+	})
+}
+
+// KeysById returns the set of trusted keys that have the given key id.
+//
+//nolint:revive  // golang/x/crypto uses Id instead of ID so we have to too
+func (t trustedKeyRing) KeysById(id uint64) []openpgp.Key {
+	return t.filter(t.kr.KeysById(id))
+}
+
+// KeysByIdUsage returns the set of trusted keys with the given id that also
+// meet the key usage given by requiredUsage.
+//
+//nolint:revive  // golang/x/crypto uses Id instead of ID so we have to too
+func (t trustedKeyRing) KeysByIdUsage(id uint64, requiredUsage byte) []openpgp.Key {
+	return t.filter(t.kr.KeysByIdUsage(id, requiredUsage))
+}
+
+// DecryptionKeys returns all trusted private keys that are valid for
+// decryption.
+func (t trustedKeyRing) DecryptionKeys() []openpgp.Key {
+	return t.filter(t.kr.DecryptionKeys())
+}
+
+// Check checks the image at path, that was retrieved from source, against p.
+// PGP keys are looked up in kr. Either of path and source may be empty, if
+// unknown. The scope that applies to the image is returned.
+func (p *Policy) Check(ctx context.Context, path, source string, kr openpgp.KeyRing) (*Scope, error) {
+	s, err := p.Scope(path, source)
+	if err != nil {
+		return nil, err
+	}
+	return s, s.Check(ctx, path, kr)
+}
+
+// Check checks the image at path against s. PGP keys are looked up in kr. If
+// path is empty, the image is considered not to be signed.
+//
+// A native SIF image must carry signatures that all verify with the key
+// material of s, for all object groups, as with signature.Verify. An OCI-SIF
+// image must carry at least one cosign signature that verifies with a
+// certificate or cosign key of s.
+func (s *Scope) Check(ctx context.Context, path string, kr openpgp.KeyRing) error {
+	switch s.mode() {
+	case ModeAccept:
+		return nil
+	case ModeReject:
+		return fmt.Errorf("%w: scope %s", ErrRejected, s.Name)
+	}
+
+	if path == "" {
+		return errUnsigned
+	}
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUnsigned, err)
+	}
+	_, err = f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex))
+	ociSIF := err == nil
+	f.UnloadContainer()
+
+	es, err := s.loadEntities()
+	if err != nil {
+		return err
+	}
+	vs := make([]signature.Verifier, 0, len(es))
+	for _, e := range es {
+		if e.Err != nil {
+			sylog.Warningf("Certificate %s not trusted by scope %s: %v", e.Name, s.Name, e.Err)
+			continue
+		}
+		vs = append(vs, e.Verifier)
+	}
+
+	if ociSIF {
+		if len(vs) == 0 {
+			return errNoTrustedKeys
+		}
+		signed, err := cosign.SignedBy(ctx, path, vs)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(signed, true) {
+			return cosign.ErrNoValidSignatures
+		}
+		return nil
+	}
+
+	if len(vs) == 0 && len(s.KeyFPs) == 0 {
+		return errNoTrustedKeys
+	}
+
+	var opts []sifsignature.VerifyOpt
+	for _, sv := range vs {
+		opts = append(opts, sifsignature.OptVerifyWithVerifier(sv))
+	}
+	if len(s.KeyFPs) > 0 {
+		if kr == nil {
+			kr = openpgp.EntityList{}
+		}
+		opts = append(opts, sifsignature.OptVerifyWithKeyRing(trustedKeyRing{kr: kr, fps: s.KeyFPs}))
+	}
+	if s.Legacy {
+		opts = append(opts, sifsignature.OptVerifyLegacy())
+	}
+	return sifsignature.Verify(ctx, path, opts...)
+}
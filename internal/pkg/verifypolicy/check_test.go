@@ -0,0 +1,170 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package verifypolicy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// getTestEntity returns a fixed test PGP entity.
+func getTestEntity(t *testing.T) *openpgp.Entity {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("..", "..", "..", "test", "keys", "pgp-public.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	el, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(el), 1; got != want {
+		t.Fatalf("got %v entities, want %v", got, want)
+	}
+	return el[0]
+}
+
+func TestCheck(t *testing.T) {
+	dirPath, err := filepath.Abs(filepath.Join("..", "..", "..", "test", "images"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsigned := filepath.Join(dirPath, "one-group.sif")
+	signed := filepath.Join(dirPath, "one-group-signed-pgp.sif")
+	legacySigned := filepath.Join(dirPath, "one-group-signed-legacy.sif")
+	// The DSSE image is signed with the ed25519 and rsa test keys. The leaf
+	// certificate holds the rsa public key.
+	dsseSigned := filepath.Join(dirPath, "one-group-signed-dsse.sif")
+	ociSIF := filepath.Join(dirPath, "empty.oci.sif")
+
+	leaf := Certificate{
+		Path:          testCert("leaf.pem"),
+		Roots:         testCert("root.pem"),
+		Intermediates: testCert("intermediate.pem"),
+	}
+	with := func(c Certificate, f func(c *Certificate)) Certificate {
+		f(&c)
+		return c
+	}
+
+	var errAny error = errors.New("any error")
+
+	tests := []struct {
+		name    string
+		scope   Scope
+		path    string
+		wantErr error
+	}{
+		{"Accept", Scope{Mode: ModeAccept}, unsigned, nil},
+		{"AcceptNoPath", Scope{Mode: ModeAccept}, "", nil},
+		{"Reject", Scope{Mode: ModeReject}, signed, ErrRejected},
+		{"NoPath", Scope{KeyFPs: []string{keyFP1}}, "", errUnsigned},
+		{"NotSIF", Scope{KeyFPs: []string{keyFP1}}, filepath.Join(dirPath, "squashfs-for-overlay.img"), errUnsigned},
+		{"PGP", Scope{KeyFPs: []string{keyFP1}}, signed, nil},
+		{"PGPUntrusted", Scope{KeyFPs: []string{keyFP2}}, signed, errAny},
+		{"PGPUnsigned", Scope{KeyFPs: []string{keyFP1}}, unsigned, errAny},
+		{"PGPLegacy", Scope{KeyFPs: []string{keyFP1}, Legacy: true}, legacySigned, nil},
+		{"PGPLegacyNotEnabled", Scope{KeyFPs: []string{keyFP1}}, legacySigned, errAny},
+		{"Certificate", Scope{Certificates: []Certificate{leaf}}, dsseSigned, nil},
+		{"CertificateSubject", Scope{Certificates: []Certificate{with(leaf, func(c *Certificate) {
+			c.Subjects = []string{"leaf"}
+		})}}, dsseSigned, nil},
+		{"CertificateSubjectNotAllowed", Scope{Certificates: []Certificate{with(leaf, func(c *Certificate) {
+			c.Subjects = []string{"other"}
+		})}}, dsseSigned, errNoTrustedKeys},
+		{"CertificateSANNotAllowed", Scope{Certificates: []Certificate{with(leaf, func(c *Certificate) {
+			c.SANs = []string{"signer@example.com"}
+		})}}, dsseSigned, errNoTrustedKeys},
+		{"CertificateUntrusted", Scope{Certificates: []Certificate{with(leaf, func(c *Certificate) {
+			c.Intermediates = ""
+		})}}, dsseSigned, errNoTrustedKeys},
+		{"CosignKey", Scope{CosignKeys: []string{testKey("ed25519-public.pem")}}, dsseSigned, nil},
+		{"CosignKeyUntrusted", Scope{CosignKeys: []string{testKey("ecdsa-public.pem")}}, dsseSigned, errAny},
+		// A PGP signature cannot be verified with certificates and keys only.
+		{"CosignKeyPGPSigned", Scope{CosignKeys: []string{testKey("ed25519-public.pem")}}, signed, errAny},
+		{"OCISIFPGP", Scope{KeyFPs: []string{keyFP1}}, ociSIF, errNoTrustedKeys},
+		{"OCISIFUnsigned", Scope{CosignKeys: []string{testKey("cosign.pub")}}, ociSIF, errAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scope.Check(t.Context(), tt.path, openpgp.EntityList{getTestEntity(t)})
+
+			switch {
+			case tt.wantErr == errAny:
+				if err == nil {
+					t.Errorf("unexpected success")
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	dirPath, err := filepath.Abs(filepath.Join("..", "..", "..", "test", "images"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := filepath.Join(dirPath, "one-group-signed-pgp.sif")
+
+	p := Policy{
+		Scopes: []Scope{
+			{Name: "images", Paths: []string{dirPath}, KeyFPs: []string{keyFP2}},
+			{Name: "registry", Registries: []string{"library://example/"}, KeyFPs: []string{keyFP1}},
+		},
+	}
+	kr := openpgp.EntityList{getTestEntity(t)}
+
+	// The image path selects a scope that does not trust the signer.
+	s, err := p.Check(t.Context(), signed, "", kr)
+	if err == nil {
+		t.Errorf("unexpected success")
+	}
+	if s == nil || s.Name != "images" {
+		t.Errorf("got scope %+v, want images", s)
+	}
+
+	// A symbolic link is resolved before selecting a scope.
+	link := filepath.Join(t.TempDir(), "image.sif")
+	if err := os.Symlink(signed, link); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := p.Scope(link, "library://example/image"); err != nil || s.Name != "images" {
+		t.Errorf("got scope %+v (%v), want images", s, err)
+	}
+
+	// A path outside of any scope is selected by its source.
+	other := filepath.Join(t.TempDir(), "image.sif")
+	b, err := os.ReadFile(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err = p.Check(t.Context(), other, "library://example/image", kr)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if s == nil || s.Name != "registry" {
+		t.Errorf("got scope %+v, want registry", s)
+	}
+
+	if _, err := p.Check(t.Context(), other, "", kr); !errors.Is(err, ErrNoScope) {
+		t.Errorf("got error %v, want %v", err, ErrNoScope)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package verifypolicy implements the loading and evaluation of verification
+// policy files. A policy is a TOML file holding an ordered list of scopes.
+// Each scope selects images by path or by source URI, and describes the key
+// material that must have signed them.
+package verifypolicy
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"github.com/sylabs/singularity/v4/pkg/syfs"
+	"golang.org/x/sys/unix"
+)
+
+// FileName is the name of a verification policy file, in the system-wide
+// configuration directory or in the configuration directory of a user.
+const FileName = "verify-policy.toml"
+
+// Modes of a scope.
+const (
+	ModeSigned = "signed" // images must be signed with key material of the scope
+	ModeAccept = "accept" // images are accepted without verification
+	ModeReject = "reject" // images are rejected
+)
+
+var (
+	ErrNoScope  = errors.New("image not part of any verification policy scope")
+	ErrRejected = errors.New("image rejected by verification policy")
+
+	errBadMode            = errors.New("the mode field can only be either: signed, accept, reject")
+	errBadFingerprint     = errors.New("expecting a 40 chars hex fingerprint string")
+	errRelativePath       = errors.New("scope paths must be absolute")
+	errNoKeyMaterial      = errors.New("signed scope requires a keyfp, certificate or cosignkey")
+	errCertificateMissing = errors.New("certificate entries require a path")
+	errCertRootsRequired  = errors.New("certificate entries require a roots file")
+)
+
+// Policy describes the structure of a verification policy file. The first
+// scope that matches an image applies to it. An image that matches no scope
+// is rejected.
+type Policy struct {
+	Scopes []Scope `toml:"scope,omitempty"`
+}
+
+// Scope describes a set of images, and how they are verified:
+//
+//	Name: a descriptive identifier
+//	Mode: whether images must be signed, are accepted, or are rejected
+//	Paths: absolute directories or glob patterns that images are matched against
+//	Registries: URI prefixes that the source of images is matched against
+//	KeyFPs: fingerprints of trusted PGP keys
+//	Certificates: trusted x509 certificates, with their chain and identity constraints
+//	CosignKeys: paths to trusted cosign (PEM) public keys
+//	Legacy: whether legacy (insecure) signatures of the primary partition are verified
+//
+// A scope with neither Paths nor Registries matches all images.
+type Scope struct {
+	Name         string        `toml:"name"`
+	Mode         string        `toml:"mode,omitempty"`
+	Paths        []string      `toml:"paths,omitempty"`
+	Registries   []string      `toml:"registries,omitempty"`
+	KeyFPs       []string      `toml:"keyfp,omitempty"`
+	Certificates []Certificate `toml:"certificate,omitempty"`
+	CosignKeys   []string      `toml:"cosignkey,omitempty"`
+	Legacy       bool          `toml:"legacyinsecure,omitempty"`
+}
+
+// Certificate describes a trusted x509 certificate:
+//
+//	Path: path to the certificate
+//	Roots: path to the root certificates that the certificate must chain to
+//	Intermediates: path to intermediate certificates used to form chains
+//	Subjects: if set, the certificate must have a subject CN or DN in this list
+//	SANs: if set, the certificate must have a DNS, email or URI SAN in this list
+//	OCSP: whether the chain is subject to an online revocation check
+//	OCSPResponses: path to a file or directory of pre-fetched OCSP responses for the chain
+//	CRLs: paths to certificate revocation lists for the chain
+//	CRLFetch: whether CRLs are fetched from the distribution points of the chain
+//	RevocationMaxAge: if set, the maximum age (e.g. "72h") of OCSP responses and CRLs
+type Certificate struct {
+	Path             string   `toml:"path"`
+	Roots            string   `toml:"roots"`
+	Intermediates    string   `toml:"intermediates,omitempty"`
+	Subjects         []string `toml:"subject,omitempty"`
+	SANs             []string `toml:"san,omitempty"`
+	OCSP             bool     `toml:"ocsp,omitempty"`
+	OCSPResponses    string   `toml:"ocspresponses,omitempty"`
+	CRLs             []string `toml:"crl,omitempty"`
+	CRLFetch         bool     `toml:"crlfetch,omitempty"`
+	RevocationMaxAge string   `toml:"revocationmaxage,omitempty"`
+}
+
+// UserPath returns the path of the verification policy file of the current
+// user.
+func UserPath() string {
+	return filepath.Join(syfs.ConfigDir(), FileName)
+}
+
+// Load opens the verification policy file at path, without following a final
+// symbolic link, and unmarshals it.
+func Load(path string) (*Policy, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadFile(f)
+}
+
+// LoadFile unmarshals a verification policy from an open file. Unknown fields
+// are rejected, so that a misspelled constraint is not silently ignored.
+func LoadFile(f *os.File) (*Policy, error) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policy
+	d := toml.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&p); err != nil {
+		return nil, fmt.Errorf("while parsing %s: %w", f.Name(), err)
+	}
+	return &p, nil
+}
+
+// Validate checks that the scopes of p are logically correct, and that their
+// key material can be loaded.
+func (p *Policy) Validate() error {
+	for _, s := range p.Scopes {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("scope %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// mode returns the mode of s, which defaults to ModeSigned.
+func (s *Scope) mode() string {
+	if s.Mode == "" {
+		return ModeSigned
+	}
+	return s.Mode
+}
+
+func (s *Scope) validate() error {
+	switch s.mode() {
+	case ModeSigned, ModeAccept, ModeReject:
+	default:
+		return errBadMode
+	}
+
+	for _, path := range s.Paths {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("%w: %s", errRelativePath, path)
+		}
+		if _, err := filepath.Match(path, ""); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	for _, k := range s.KeyFPs {
+		decoded, err := hex.DecodeString(k)
+		if err != nil || len(decoded) != 20 {
+			return errBadFingerprint
+		}
+	}
+
+	if s.mode() == ModeSigned && len(s.KeyFPs) == 0 && len(s.Certificates) == 0 && len(s.CosignKeys) == 0 {
+		return errNoKeyMaterial
+	}
+
+	_, err := s.loadEntities()
+	return err
+}
+
+// matchPath returns true if path is, or is below, one of the directories in
+// patterns, or matches one of the glob patterns in patterns.
+func matchPath(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		dir := filepath.Clean(pattern)
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) || dir == "/" {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSource returns true if source starts with one of prefixes.
+func matchSource(prefixes []string, source string) bool {
+	if source == "" {
+		return false
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope returns the first scope of p that matches the image at path, that was
+// retrieved from source. Either of path and source may be empty, if unknown.
+func (p *Policy) Scope(path, source string) (*Scope, error) {
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+	}
+	return p.scope(path, source)
+}
+
+// ScopeForFd returns the first scope of p that matches the image opened as
+// file descriptor fd, that was retrieved from source. The path of the image is
+// read from /proc/self/fd, so that the scope applies to the file that is
+// opened, even if its path has since been replaced. Source may be empty, if
+// unknown or untrusted.
+func (p *Policy) ScopeForFd(fd uintptr, source string) (*Scope, error) {
+	path, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+	if err != nil {
+		return nil, fmt.Errorf("while reading image path: %w", err)
+	}
+	return p.scope(path, source)
+}
+
+// scope returns the first scope of p that matches the image at the absolute,
+// resolved path, that was retrieved from source.
+func (p *Policy) scope(path, source string) (*Scope, error) {
+	for i := range p.Scopes {
+		s := &p.Scopes[i]
+		if len(s.Paths) == 0 && len(s.Registries) == 0 {
+			return s, nil
+		}
+		if path != "" && matchPath(s.Paths, path) {
+			return s, nil
+		}
+		if matchSource(s.Registries, source) {
+			return s, nil
+		}
+	}
+	return nil, ErrNoScope
+}
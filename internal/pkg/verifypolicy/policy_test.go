@@ -0,0 +1,262 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package verifypolicy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	keyFP1 = "F34371D0ACD5D09EB9BD853A80600A5FA11BBD29"
+	keyFP2 = "7064B1D6EFF01B1262FED3F03581D99FE87EAFD1"
+)
+
+func testCert(name string) string {
+	return filepath.Join("..", "..", "..", "test", "certs", name)
+}
+
+func testKey(name string) string {
+	return filepath.Join("..", "..", "..", "test", "keys", name)
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantPolicy *Policy
+		wantErr    bool
+	}{
+		{
+			name:       "Empty",
+			wantPolicy: &Policy{},
+		},
+		{
+			name: "Scopes",
+			content: `
+[[scope]]
+  name = "site"
+  paths = ["/opt/containers"]
+  keyfp = ["` + keyFP1 + `"]
+
+[[scope]]
+  name = "builds"
+  registries = ["docker://registry.example.com/"]
+  cosignkey = ["/etc/cosign.pub"]
+
+  [[scope.certificate]]
+    path = "/etc/signer.pem"
+    roots = "/etc/root.pem"
+    subject = ["leaf"]
+
+[[scope]]
+  name = "default"
+  mode = "reject"
+`,
+			wantPolicy: &Policy{
+				Scopes: []Scope{
+					{
+						Name:   "site",
+						Paths:  []string{"/opt/containers"},
+						KeyFPs: []string{keyFP1},
+					},
+					{
+						Name:       "builds",
+						Registries: []string{"docker://registry.example.com/"},
+						CosignKeys: []string{"/etc/cosign.pub"},
+						Certificates: []Certificate{
+							{Path: "/etc/signer.pem", Roots: "/etc/root.pem", Subjects: []string{"leaf"}},
+						},
+					},
+					{
+						Name: "default",
+						Mode: ModeReject,
+					},
+				},
+			},
+		},
+		{
+			name: "UnknownField",
+			content: `
+[[scope]]
+  name = "site"
+  keyfps = ["` + keyFP1 + `"]
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(p, tt.wantPolicy) {
+				t.Errorf("got policy %+v, want %+v", p, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestLoadSymlink(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.toml")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, FileName)
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(link); err == nil {
+		t.Errorf("unexpected success loading policy through symlink")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cert := Certificate{
+		Path:          testCert("leaf.pem"),
+		Roots:         testCert("root.pem"),
+		Intermediates: testCert("intermediate.pem"),
+	}
+
+	crlMissing := cert
+	crlMissing.CRLs = []string{testCert("missing.crl")}
+
+	badMaxAge := cert
+	badMaxAge.CRLFetch = true
+	badMaxAge.RevocationMaxAge = "3 days"
+
+	tests := []struct {
+		name    string
+		scope   Scope
+		wantErr error
+	}{
+		{"PGP", Scope{KeyFPs: []string{keyFP1}}, nil},
+		{"Certificate", Scope{Certificates: []Certificate{cert}}, nil},
+		{"CosignKey", Scope{CosignKeys: []string{testKey("cosign.pub")}}, nil},
+		{"Accept", Scope{Mode: ModeAccept}, nil},
+		{"Reject", Scope{Mode: ModeReject}, nil},
+		{"BadMode", Scope{Mode: "whitelist"}, errBadMode},
+		{"BadFingerprint", Scope{KeyFPs: []string{"A11BBD29"}}, errBadFingerprint},
+		{"RelativePath", Scope{Mode: ModeAccept, Paths: []string{"containers"}}, errRelativePath},
+		{"NoKeyMaterial", Scope{Paths: []string{"/opt/containers"}}, errNoKeyMaterial},
+		{"CertificateMissing", Scope{Certificates: []Certificate{{Roots: testCert("root.pem")}}}, errCertificateMissing},
+		{"CertRootsRequired", Scope{Certificates: []Certificate{{Path: testCert("leaf.pem")}}}, errCertRootsRequired},
+		{"CosignKeyMissing", Scope{CosignKeys: []string{testKey("missing.pub")}}, os.ErrNotExist},
+		{"CRLMissing", Scope{Certificates: []Certificate{crlMissing}}, os.ErrNotExist},
+		{"BadMaxAge", Scope{Certificates: []Certificate{badMaxAge}}, errBadMaxAge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{Scopes: []Scope{tt.scope}}
+
+			if err := p.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScope(t *testing.T) {
+	p := Policy{
+		Scopes: []Scope{
+			{Name: "dir", Paths: []string{"/opt/containers"}},
+			{Name: "glob", Paths: []string{"/scratch/*/containers/*.sif"}},
+			{Name: "registry", Registries: []string{"docker://registry.example.com/builds/", "library://example/"}},
+		},
+	}
+	withDefault := Policy{Scopes: append(p.Scopes, Scope{Name: "default"})}
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		path      string
+		source    string
+		wantScope string
+		wantErr   error
+	}{
+		{"Dir", p, "/opt/containers/image.sif", "", "dir", nil},
+		{"SubDir", p, "/opt/containers/a/b/image.sif", "", "dir", nil},
+		{"DirPrefix", p, "/opt/containers2/image.sif", "", "", ErrNoScope},
+		{"Glob", p, "/scratch/user/containers/image.sif", "", "glob", nil},
+		{"GlobMismatch", p, "/scratch/user/containers/image.img", "", "", ErrNoScope},
+		{"Registry", p, "/home/user/.singularity/cache/image", "docker://registry.example.com/builds/app:1", "registry", nil},
+		{"RegistryOnly", p, "", "library://example/app", "registry", nil},
+		{"RegistryMismatch", p, "", "docker://registry.example.com/other/app:1", "", ErrNoScope},
+		{"PathFirst", p, "/opt/containers/image.sif", "library://example/app", "dir", nil},
+		{"NoMatch", p, "/tmp/image.sif", "", "", ErrNoScope},
+		{"Default", withDefault, "/tmp/image.sif", "", "default", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := tt.policy.Scope(tt.path, tt.source)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got, want := s.Name, tt.wantScope; got != want {
+				t.Errorf("got scope %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestScopeForFd(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	path := filepath.Join(dir1, "image.sif")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := Policy{
+		Scopes: []Scope{
+			{Name: "one", Paths: []string{dir1}},
+			{Name: "two", Paths: []string{dir2}},
+			{Name: "registry", Registries: []string{"library://example/"}},
+		},
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if s, err := p.ScopeForFd(f.Fd(), ""); err != nil || s.Name != "one" {
+		t.Errorf("got scope %+v (%v), want one", s, err)
+	}
+
+	// The scope follows the opened file, rather than its original path.
+	if err := os.Rename(path, filepath.Join(dir2, "image.sif")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := p.ScopeForFd(f.Fd(), "library://example/app"); err != nil || s.Name != "two" {
+		t.Errorf("got scope %+v (%v), want two", s, err)
+	}
+}
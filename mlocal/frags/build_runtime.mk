@@ -73,6 +73,18 @@ $(syecl_config_INSTALL): $(syecl_config)
 INSTALLFILES += $(syecl_config_INSTALL)
 
 
+# verification policy file
+verifypolicy_config := $(SOURCEDIR)/internal/pkg/verifypolicy/verify-policy.toml.example
+
+verifypolicy_config_INSTALL := $(DESTDIR)$(SYSCONFDIR)/singularity/verify-policy.toml
+$(verifypolicy_config_INSTALL): $(verifypolicy_config)
+	@echo " INSTALL" $@
+	$(V)umask 0022 && mkdir -p $(@D)
+	$(V)install -m 0644 $< $@
+
+INSTALLFILES += $(verifypolicy_config_INSTALL)
+
+
 # seccomp profile
 seccomp_profile := $(SOURCEDIR)/etc/seccomp-profiles/default.json
 
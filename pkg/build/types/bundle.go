@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2022, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -35,6 +35,10 @@ type Bundle struct {
 	Rootfs     *os.Root `json:"-"`          // rooted handle to RootfsPath for confined build modifications
 	TmpDir     string   `json:"tmpPath"`    // where temp files required during build will appear
 
+	// SourceDigest is the digest of the bootstrap source, in the form
+	// "<algorithm>:<hex>", where it can be resolved by the conveyor.
+	SourceDigest string `json:"sourceDigest"`
+
 	parentPath string // parent directory for RootfsPath
 }
 
@@ -87,6 +91,21 @@ type Options struct {
 	Platform ggcrv1.Platform
 	// Authentication file for registry credentials
 	DockerAuthFile string
+	// Secrets are made available to the %post and %test sections, but are not
+	// stored in the image.
+	Secrets []Secret `json:"-"`
+	// Network selects the network used by the %post and %test sections.
+	// "none" runs them in an isolated network namespace, while "default" or
+	// an empty value uses the host network.
+	Network string `json:"network"`
+	// CPUs limits the number of CPUs available to the %post and %test sections.
+	CPUs string `json:"cpus"`
+	// Memory limits the memory available to the %post and %test sections.
+	Memory string `json:"memory"`
+	// ContextDir, if set, holds the files uploaded to a build server. Sources
+	// of files copied from the host are resolved inside it, and may not refer
+	// to any other host path.
+	ContextDir string `json:"contextDir"`
 }
 
 // NewEncryptedBundle creates an Encrypted Bundle environment.
@@ -99,6 +118,36 @@ func NewBundle(parentPath, tempDir string) (b *Bundle, err error) {
 	return newBundle(parentPath, tempDir, nil)
 }
 
+// OpenBundle re-opens the Bundle with root filesystem at rootfsPath and
+// temporary directory tmpDir, left in place by an earlier, failed build. The
+// rootfs must be named "rootfs", inside its own parent directory, as created by
+// NewBundle.
+func OpenBundle(rootfsPath, tmpDir string) (*Bundle, error) {
+	if filepath.Base(rootfsPath) != "rootfs" {
+		return nil, fmt.Errorf("%q is not a bundle root filesystem", rootfsPath)
+	}
+	for _, dir := range []string{rootfsPath, tmpDir} {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not open bundle: %v", err)
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("could not open bundle: %q is not a directory", dir)
+		}
+	}
+
+	b := &Bundle{
+		parentPath:  filepath.Dir(rootfsPath),
+		RootfsPath:  rootfsPath,
+		TmpDir:      tmpDir,
+		JSONObjects: make(map[string][]byte),
+	}
+	if err := b.ReopenRootfs(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
 // RunSection iterates through the sections specified in a bundle
 // and returns true if the given string, s, is a section of the
 // definition that should be executed during the build process.
@@ -1,4 +1,4 @@
-// Copyright (c) 2019, Sylabs Inc. All rights reserved.
+// Copyright (c) 2019-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -129,3 +129,29 @@ func TestBundle_RunSections(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenBundle(t *testing.T) {
+	b, err := NewBundle(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	defer b.Remove()
+
+	ob, err := OpenBundle(b.RootfsPath, b.TmpDir)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	if ob.parentPath != b.parentPath {
+		t.Errorf("got parent path %q, want %q", ob.parentPath, b.parentPath)
+	}
+	if err := ob.Rootfs.WriteFile("test", []byte("test"), 0o644); err != nil {
+		t.Errorf("could not write to opened rootfs: %v", err)
+	}
+
+	if _, err := OpenBundle(b.TmpDir, b.TmpDir); err == nil {
+		t.Errorf("unexpected success opening bundle without rootfs")
+	}
+	if _, err := OpenBundle(filepath.Join(t.TempDir(), "rootfs"), b.TmpDir); err == nil {
+		t.Errorf("unexpected success opening missing bundle")
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2026, Sylabs Inc. All rights reserved.
 // Copyright (c) Contributors to the Apptainer project, established as
 //   Apptainer a Series of LF Projects LLC.
 // This software is licensed under a 3-clause BSD license. Please consult the
@@ -13,7 +13,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -206,8 +208,8 @@ func writeFilesIfExists(w io.Writer, f []Files) {
 func writeLabelsIfExists(w io.Writer, l map[string]string) {
 	if len(l) > 0 {
 		fmt.Fprintln(w, "%labels")
-		for k, v := range l {
-			fmt.Fprintf(w, "\t%s %s\n", k, v)
+		for _, k := range slices.Sorted(maps.Keys(l)) {
+			fmt.Fprintf(w, "\t%s %s\n", k, l[k])
 		}
 		fmt.Fprintln(w)
 	}
@@ -221,13 +223,13 @@ func populateRaw(d *Definition, w io.Writer) {
 		fmt.Fprintf(w, "%s: %s\n", "bootstrap", v)
 	}
 
-	for k, v := range d.Header {
+	for _, k := range slices.Sorted(maps.Keys(d.Header)) {
 		// filter out bootstrap parameter since it should already be added
 		if k == "bootstrap" {
 			continue
 		}
 
-		fmt.Fprintf(w, "%s: %s\n", k, v)
+		fmt.Fprintf(w, "%s: %s\n", k, d.Header[k])
 	}
 	fmt.Fprintln(w)
 
@@ -367,6 +367,12 @@ func doHeader(h string, d *types.Definition) error {
 			continue
 		}
 
+		// skip template actions on their own line, e.g. '{{ if .gpu }}', which
+		// are only evaluated once build-args are known
+		if isTemplateAction(line) {
+			continue
+		}
+
 		// trim any comments on header lines
 		trimLine := strings.Split(line, "#")[0]
 		if len(valCont) == 0 {
@@ -410,6 +416,11 @@ func doHeader(h string, d *types.Definition) error {
 	return nil
 }
 
+// isTemplateAction returns whether line consists solely of a template action.
+func isTemplateAction(line string) bool {
+	return strings.HasPrefix(line, "{{") && strings.HasSuffix(line, "}}") && strings.Count(line, "{{") == 1
+}
+
 // ParseDefinitionFile receives a reader from a definition file
 // and parse it into a Definition struct or return error if
 // the definition file has a bad section.
@@ -510,7 +521,12 @@ func IsValidDefinition(source string) (valid bool, err error) {
 		return false, nil
 	}
 
-	_, err = ParseDefinitionFile(defFile)
+	raw, err := ReadDefinitionFile(source)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = ParseDefinitionFile(bytes.NewReader(raw))
 	if err != nil {
 		return false, err
 	}
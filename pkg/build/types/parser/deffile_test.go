@@ -326,6 +326,11 @@ func TestDoHeader(t *testing.T) {
 			t.Fatal("Test succeeded while supposed to fail")
 		}
 	}
+
+	templated := "Bootstrap: docker\n{{ if .gpu }}\nFrom: nvidia/cuda\n{{ else }}\nFrom: ubuntu\n{{ end }}\n"
+	if err := doHeader(templated, myData); err != nil {
+		t.Fatalf("unexpected error with template actions in header: %v", err)
+	}
 }
 
 func TestIsValidDefinition(t *testing.T) {
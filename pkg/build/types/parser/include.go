@@ -0,0 +1,124 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// includeDirective is the directive used to include a fragment into a
+// definition file.
+const includeDirective = "%include"
+
+// ReadDefinitionFile reads the definition file at path, and returns its
+// content with all %include directives resolved. See ResolveIncludes.
+func ReadDefinitionFile(path string) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	return resolveIncludes(raw, filepath.Dir(abs), []string{abs})
+}
+
+// ResolveIncludes replaces each line of the form:
+//
+//	%include path/to/fragment.def
+//
+// in raw with the content of the named fragment. Relative paths are resolved
+// against dir for the top-level content, and against the directory holding
+// the including fragment for nested includes. Fragments may contain complete
+// sections, or lines to be placed into the enclosing section, and may use
+// build-args, which are substituted once the definition is expanded. An
+// include cycle is an error.
+func ResolveIncludes(raw []byte, dir string) ([]byte, error) {
+	return resolveIncludes(raw, dir, nil)
+}
+
+// resolveIncludes resolves includes in raw, where stack holds the absolute
+// paths of the files currently being included, outermost first.
+func resolveIncludes(raw []byte, dir string, stack []string) ([]byte, error) {
+	// Fast path - nothing to resolve.
+	if !bytes.Contains(bytes.ToLower(raw), []byte(includeDirective)) {
+		return raw, nil
+	}
+
+	var out bytes.Buffer
+	s := bufio.NewScanner(bytes.NewReader(raw))
+	s.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(raw)+1)
+	for s.Scan() {
+		line := s.Text()
+		target, ok, err := includeTarget(line)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			out.WriteString(line)
+			out.WriteString("\n")
+			continue
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		target = filepath.Clean(target)
+
+		if slices.Contains(stack, target) {
+			return nil, fmt.Errorf("include cycle detected: %s -> %s", strings.Join(stack, " -> "), target)
+		}
+
+		fragment, err := os.ReadFile(target)
+		if err != nil {
+			return nil, fmt.Errorf("while including %s: %w", target, err)
+		}
+		fragment, err = resolveIncludes(fragment, filepath.Dir(target), append(slices.Clone(stack), target))
+		if err != nil {
+			return nil, err
+		}
+
+		out.Write(fragment)
+		if len(fragment) > 0 && fragment[len(fragment)-1] != '\n' {
+			out.WriteString("\n")
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("while resolving includes: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// includeTarget returns the path named by an %include directive on line, and
+// whether line is an %include directive at all. The path may be quoted, and
+// may be followed by a comment.
+func includeTarget(line string) (string, bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], includeDirective) {
+		return "", false, nil
+	}
+
+	arg := strings.TrimSpace(strings.TrimSpace(line)[len(includeDirective):])
+	if i := strings.Index(arg, "#"); i >= 0 {
+		arg = strings.TrimSpace(arg[:i])
+	}
+	if len(arg) >= 2 && (arg[0] == '"' || arg[0] == '\'') && arg[len(arg)-1] == arg[0] {
+		arg = arg[1 : len(arg)-1]
+	}
+	if arg == "" {
+		return "", true, fmt.Errorf("%s directive requires a path", includeDirective)
+	}
+
+	return arg, true, nil
+}
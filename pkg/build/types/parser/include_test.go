@@ -0,0 +1,92 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadDefinitionFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "Nested",
+			path: "testdata_include/main.def",
+			want: `Bootstrap: docker
+From: alpine:{{ VERSION }}
+
+%arguments
+	VERSION=3.20
+
+%labels
+	Team infra
+
+%post
+	export http_proxy={{ PROXY }}
+	export LC_ALL=C
+	apk add --no-cache curl
+`,
+		},
+		{
+			name:    "Cycle",
+			path:    "testdata_include/cycle.def",
+			wantErr: "include cycle detected",
+		},
+		{
+			name:    "Missing",
+			path:    "testdata_include/missing.def",
+			wantErr: "while including",
+		},
+		{
+			name:    "NoPath",
+			path:    "testdata_include/empty.def",
+			wantErr: "requires a path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadDefinitionFile(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			d, err := ParseDefinitionFile(bytes.NewReader(got))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Labels["Team"] != "infra" {
+				t.Errorf("included label not parsed: %v", d.Labels)
+			}
+		})
+	}
+}
+
+func TestResolveIncludesNoDirective(t *testing.T) {
+	raw := []byte("Bootstrap: scratch\n\n%post\n\techo %included\n")
+	got, err := ResolveIncludes(raw, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Errorf("got %q, want %q", got, raw)
+	}
+}
@@ -0,0 +1 @@
+	export LC_ALL=C
\ No newline at end of file
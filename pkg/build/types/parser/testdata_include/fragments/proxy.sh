@@ -0,0 +1,2 @@
+	export http_proxy={{ PROXY }}
+	%include "locale.sh" # nested
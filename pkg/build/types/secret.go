@@ -0,0 +1,89 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package types
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var secretIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Secret is a build secret, made available to the %post and %test sections at
+// /run/secrets/<ID>, but never stored in the resulting image.
+type Secret struct {
+	// ID is the name of the secret, and of the file holding it during the build.
+	ID string
+	// Src is the path of a file holding the secret value.
+	Src string
+	// Env is the name of an environment variable holding the secret value.
+	// Only one of Src and Env is set.
+	Env string
+}
+
+// ParseSecret parses a secret specification of the form id=<id>,src=<path>
+// or id=<id>,env=<variable>. A leading ~/ in a path is expanded to the home
+// directory of the current user.
+func ParseSecret(spec string) (Secret, error) {
+	var s Secret
+	for opt := range strings.SplitSeq(spec, ",") {
+		k, v, ok := strings.Cut(opt, "=")
+		if !ok {
+			return s, fmt.Errorf("invalid secret option %q: must be key=value", opt)
+		}
+		switch k {
+		case "id":
+			s.ID = v
+		case "src", "source":
+			s.Src = v
+		case "env":
+			s.Env = v
+		default:
+			return s, fmt.Errorf("unknown secret option %q", k)
+		}
+	}
+
+	if !secretIDRegexp.MatchString(s.ID) {
+		return s, fmt.Errorf("secret %q: id must be set, and contain only letters, digits, '_', '.' or '-'", spec)
+	}
+	if s.ID == "." || s.ID == ".." {
+		return s, fmt.Errorf("secret %q: invalid id %q", spec, s.ID)
+	}
+	if (s.Src == "") == (s.Env == "") {
+		return s, fmt.Errorf("secret %q: exactly one of src or env must be set", spec)
+	}
+
+	if rest, ok := strings.CutPrefix(s.Src, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return s, fmt.Errorf("secret %q: while expanding ~: %w", spec, err)
+		}
+		s.Src = filepath.Join(home, rest)
+	}
+
+	return s, nil
+}
+
+// Value returns the value of the secret, read from its source file or
+// environment variable.
+func (s Secret) Value() ([]byte, error) {
+	if s.Env != "" {
+		v, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return nil, fmt.Errorf("secret %s: environment variable %s is not set", s.ID, s.Env)
+		}
+		return []byte(v), nil
+	}
+
+	b, err := os.ReadFile(s.Src)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", s.ID, err)
+	}
+	return b, nil
+}
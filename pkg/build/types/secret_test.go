@@ -0,0 +1,109 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSecret(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    Secret
+		wantErr bool
+	}{
+		{
+			name: "Src",
+			spec: "id=pip,src=/etc/pip.conf",
+			want: Secret{ID: "pip", Src: "/etc/pip.conf"},
+		},
+		{
+			name: "SourceHome",
+			spec: "source=~/.netrc,id=netrc",
+			want: Secret{ID: "netrc", Src: filepath.Join(home, ".netrc")},
+		},
+		{
+			name: "Env",
+			spec: "id=token,env=GIT_TOKEN",
+			want: Secret{ID: "token", Env: "GIT_TOKEN"},
+		},
+		{
+			name:    "NoID",
+			spec:    "src=/etc/pip.conf",
+			wantErr: true,
+		},
+		{
+			name:    "BadID",
+			spec:    "id=../pip,src=/etc/pip.conf",
+			wantErr: true,
+		},
+		{
+			name:    "DotID",
+			spec:    "id=..,src=/etc/pip.conf",
+			wantErr: true,
+		},
+		{
+			name:    "NoSource",
+			spec:    "id=pip",
+			wantErr: true,
+		},
+		{
+			name:    "SrcAndEnv",
+			spec:    "id=pip,src=/etc/pip.conf,env=PIP",
+			wantErr: true,
+		},
+		{
+			name:    "UnknownOption",
+			spec:    "id=pip,src=/etc/pip.conf,mode=0600",
+			wantErr: true,
+		},
+		{
+			name:    "NotKeyValue",
+			spec:    "pip",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSecret(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretValue(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(src, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET_VALUE", "t0k3n")
+
+	v, err := Secret{ID: "file", Src: src}.Value()
+	if err != nil || string(v) != "s3cr3t\n" {
+		t.Errorf("got %q, %v from file secret", v, err)
+	}
+	v, err = Secret{ID: "env", Env: "TEST_SECRET_VALUE"}.Value()
+	if err != nil || string(v) != "t0k3n" {
+		t.Errorf("got %q, %v from env secret", v, err)
+	}
+	if _, err := (Secret{ID: "missing", Env: "TEST_SECRET_UNSET"}).Value(); err == nil {
+		t.Errorf("unexpected success with unset environment variable")
+	}
+}
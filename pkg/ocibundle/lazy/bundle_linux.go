@@ -0,0 +1,301 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package lazy provides an OCI bundle with a rootfs assembled from the layers
+// of an image in a registry, which are fetched on demand as they are accessed.
+package lazy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sylabs/singularity/v4/internal/pkg/cache"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociimage"
+	"github.com/sylabs/singularity/v4/internal/pkg/ociimage/lazy"
+	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
+	"github.com/sylabs/singularity/v4/internal/pkg/runtime/engine/config/oci/generate"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/erofs"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/overlay"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/squashfs"
+	"github.com/sylabs/singularity/v4/pkg/ocibundle"
+	ocisifbundle "github.com/sylabs/singularity/v4/pkg/ocibundle/ocisif"
+	"github.com/sylabs/singularity/v4/pkg/ocibundle/tools"
+	"github.com/sylabs/singularity/v4/pkg/sylog"
+)
+
+// Bundle is an OCI bundle, created from a registry imageRef, with layers that
+// are fetched on demand.
+type Bundle struct {
+	// imageRef is the reference to the OCI image source, e.g. docker://alpine
+	imageRef string
+	// transportOptions provides auth / platform etc. configuration for
+	// interactions with the registry.
+	transportOptions *ociimage.TransportOptions
+	// imgCache is a Singularity image cache, which fetched chunks are stored in.
+	imgCache *cache.Handle
+	// img is the image, if already opened by the caller.
+	img *lazy.Image
+	// imageSpec is the OCI image information, CMD, ENTRYPOINT, etc.
+	imageSpec *imgspecv1.Image
+	// bundlePath is the location where the OCI bundle will be created.
+	bundlePath string
+	// blobServer serves the on-demand layer blobs.
+	blobServer *fuse.Server
+	// layers that have been mounted
+	mountedLayers []mountedLayer
+	// assembled rootfs, from overlay mount of mountedLayers
+	rootfsOverlaySet overlay.Set
+	// Has the image been mounted onto the bundle rootfs?
+	rootfsMounted bool
+	// Generic bundle properties
+	ocibundle.Bundle
+}
+
+// mountedLayer is a layer filesystem mounted from a blob.
+type mountedLayer struct {
+	path  string
+	erofs bool
+	// kernel is set if an erofs layer was mounted with the kernel driver.
+	kernel bool
+}
+
+type Option func(b *Bundle) error
+
+// OptBundlePath sets the path that the bundle will be created at.
+func OptBundlePath(bp string) Option {
+	return func(b *Bundle) error {
+		var err error
+		b.bundlePath, err = filepath.Abs(bp)
+		if err != nil {
+			return fmt.Errorf("failed to determine bundle path: %s", err)
+		}
+		return nil
+	}
+}
+
+// OptImageRef sets the image source reference, from which the bundle will be created.
+func OptImageRef(ref string) Option {
+	return func(b *Bundle) error {
+		b.imageRef = ref
+		return nil
+	}
+}
+
+// OptTransportOptions sets configuration for interaction with the registry.
+func OptTransportOptions(tOpts *ociimage.TransportOptions) Option {
+	return func(b *Bundle) error {
+		b.transportOptions = tOpts
+		return nil
+	}
+}
+
+// OptImgCache sets the Singularity image cache that fetched chunks are stored in.
+func OptImgCache(ic *cache.Handle) Option {
+	return func(b *Bundle) error {
+		b.imgCache = ic
+		return nil
+	}
+}
+
+// OptImage sets an image that has already been opened with lazy.Open, so that
+// it is not resolved and verified again when the bundle is created. The image
+// ref must still be set with OptImageRef.
+func OptImage(img *lazy.Image) Option {
+	return func(b *Bundle) error {
+		b.img = img
+		return nil
+	}
+}
+
+// New returns a bundle interface to create/delete an OCI bundle from a registry image ref.
+func New(opts ...Option) (ocibundle.Bundle, error) {
+	b := Bundle{
+		imageRef: "",
+	}
+
+	for _, opt := range opts {
+		if err := opt(&b); err != nil {
+			return nil, fmt.Errorf("while initializing bundle: %w", err)
+		}
+	}
+
+	return &b, nil
+}
+
+// Delete erases OCI bundle created from a registry image ref.
+func (b *Bundle) Delete(ctx context.Context) error {
+	sylog.Debugf("Deleting lazy bundle at %s", b.bundlePath)
+
+	if b.rootfsMounted {
+		rootfsPath := tools.RootFs(b.bundlePath).Path()
+		sylog.Debugf("Unmounting rootfs overlay from %q", rootfsPath)
+		if err := b.rootfsOverlaySet.Unmount(ctx, rootfsPath); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range b.mountedLayers {
+		sylog.Debugf("Unmounting layer fs from %q", l.path)
+		unmount := squashfs.FUSEUnmount
+		if l.erofs {
+			unmount = func(ctx context.Context, path string) error {
+				return erofs.Unmount(ctx, path, l.kernel)
+			}
+		}
+		if err := unmount(ctx, l.path); err != nil {
+			return err
+		}
+	}
+
+	if b.blobServer != nil {
+		sylog.Debugf("Unmounting layer blobs from %q", b.blobsPath())
+		if err := b.blobServer.Unmount(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(tools.Layers(b.bundlePath).Path()); err != nil {
+		return fmt.Errorf("failed to delete layers directory: %s", err)
+	}
+	return tools.DeleteBundle(b.bundlePath)
+}
+
+// Create sets up the on-disk structure for an OCI runtime bundle, with rootfs
+// assembled from layers that are fetched from the registry on demand.
+func (b *Bundle) Create(ctx context.Context, ociConfig *specs.Spec) error {
+	img := b.img
+	if img == nil {
+		var err error
+		img, err = lazy.Open(ctx, b.transportOptions, b.imgCache, b.imageRef, tools.Layers(b.bundlePath).Path())
+		if err != nil {
+			return err
+		}
+	}
+
+	rawConf, err := img.RawConfigFile()
+	if err != nil {
+		return fmt.Errorf("while retrieving image config: %w", err)
+	}
+	var imageSpec imgspecv1.Image
+	if err := json.Unmarshal(rawConf, &imageSpec); err != nil {
+		return fmt.Errorf("while parsing image spec: %w", err)
+	}
+	b.imageSpec = &imageSpec
+
+	// Generate OCI bundle directory and config
+	g, err := tools.GenerateBundleConfig(b.bundlePath, ociConfig)
+	if err != nil {
+		if errCleanup := b.Delete(ctx); errCleanup != nil {
+			sylog.Errorf("While removing temporary bundle: %v", errCleanup)
+		}
+		return fmt.Errorf("failed to generate OCI bundle/config: %s", err)
+	}
+
+	// Mount layers from image
+	sylog.Debugf("Mounting on-demand layers from %q to %q", b.imageRef, b.bundlePath)
+	if err := b.mountLayers(ctx, img); err != nil {
+		if errCleanup := b.Delete(ctx); errCleanup != nil {
+			sylog.Errorf("While removing temporary bundle: %v", errCleanup)
+		}
+		return ocisifbundle.UnavailableError{Underlying: fmt.Errorf("while mounting layers: %w", err)}
+	}
+
+	// Assemble rootfs overlay mount
+	sylog.Debugf("Mounting rootfs overlay to %q", tools.RootFs(b.bundlePath).Path())
+	if err := b.mountRootfs(ctx); err != nil {
+		if errCleanup := b.Delete(ctx); errCleanup != nil {
+			sylog.Errorf("While removing temporary bundle: %v", errCleanup)
+		}
+		return fmt.Errorf("while mounting rootfs overlay: %w", err)
+	}
+
+	return b.writeConfig(g)
+}
+
+func (b *Bundle) blobsPath() string {
+	return filepath.Join(tools.Layers(b.bundlePath).Path(), "blobs")
+}
+
+func (b *Bundle) mountLayers(ctx context.Context, img *lazy.Image) error {
+	if err := fs.MkdirAt(tools.Layers(b.bundlePath).Path(), "blobs", 0o755); err != nil {
+		return fmt.Errorf("while creating blobs directory: %w", err)
+	}
+	server, err := img.Mount(b.blobsPath())
+	if err != nil {
+		return err
+	}
+	b.blobServer = server
+
+	for i, mt := range img.MediaTypes {
+		blobPath := filepath.Join(b.blobsPath(), strconv.Itoa(i))
+		layerPath := filepath.Join(tools.Layers(b.bundlePath).Path(), strconv.Itoa(i))
+		sylog.Debugf("Mounting layer %d fs from %q to %q", i, blobPath, layerPath)
+		if err := fs.MkdirAt(tools.Layers(b.bundlePath).Path(), strconv.Itoa(i), 0o755); err != nil {
+			return fmt.Errorf("while creating layer directory: %w", err)
+		}
+
+		ml := mountedLayer{path: layerPath, erofs: mt == ocisif.ErofsLayerMediaType}
+		if ml.erofs {
+			if ml.kernel, err = erofs.Mount(ctx, 0, 0, blobPath, layerPath); err != nil {
+				return fmt.Errorf("while mounting erofs layer: %w", err)
+			}
+		} else if _, err := squashfs.FUSEMount(ctx, 0, blobPath, layerPath, false); err != nil {
+			return fmt.Errorf("while mounting squashfs layer: %w", err)
+		}
+		b.mountedLayers = append(b.mountedLayers, ml)
+	}
+	return nil
+}
+
+func (b *Bundle) mountRootfs(ctx context.Context) error {
+	for i := len(b.mountedLayers) - 1; i >= 0; i-- {
+		item, err := overlay.NewItemFromString(b.mountedLayers[i].path)
+		if err != nil {
+			return err
+		}
+		item.Readonly = true
+		item.SetParentDir(b.bundlePath)
+		b.rootfsOverlaySet.ReadonlyOverlays = append(b.rootfsOverlaySet.ReadonlyOverlays, item)
+	}
+
+	rootFsDir := tools.RootFs(b.bundlePath).Path()
+	if err := b.rootfsOverlaySet.Mount(ctx, rootFsDir); err != nil {
+		return fmt.Errorf("while mounting rootfs overlay: %w", err)
+	}
+	b.rootfsMounted = true
+	return nil
+}
+
+// Update will update the OCI config for the OCI bundle, so that it is ready for execution.
+func (b *Bundle) Update(_ context.Context, ociConfig *specs.Spec) error {
+	// generate OCI bundle directory and config
+	g, err := tools.GenerateBundleConfig(b.bundlePath, ociConfig)
+	if err != nil {
+		return fmt.Errorf("failed to generate OCI bundle/config: %s", err)
+	}
+	return b.writeConfig(g)
+}
+
+// ImageSpec returns the OCI image spec associated with the bundle.
+func (b *Bundle) ImageSpec() (imgSpec *imgspecv1.Image) {
+	return b.imageSpec
+}
+
+// Path returns the bundle's path on disk.
+func (b *Bundle) Path() string {
+	return b.bundlePath
+}
+
+func (b *Bundle) writeConfig(g *generate.Generator) error {
+	return tools.SaveBundleConfig(b.bundlePath, g)
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2023-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2023-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -16,18 +16,23 @@ import (
 
 	"github.com/ccoveille/go-safecast/v2"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	gofuse "github.com/hanwen/go-fuse/v2/fuse"
 	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	ocitsif "github.com/sylabs/oci-tools/pkg/sif"
 	"github.com/sylabs/sif/v2/pkg/sif"
 	"github.com/sylabs/singularity/v4/internal/pkg/ocisif"
 	"github.com/sylabs/singularity/v4/internal/pkg/runtime/engine/config/oci/generate"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/crypt"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/erofs"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/overlay"
 	"github.com/sylabs/singularity/v4/internal/pkg/util/fs/squashfs"
 	"github.com/sylabs/singularity/v4/pkg/ocibundle"
 	"github.com/sylabs/singularity/v4/pkg/ocibundle/tools"
 	"github.com/sylabs/singularity/v4/pkg/sylog"
+	"github.com/sylabs/singularity/v4/pkg/util/cryptkey"
 )
 
 // UnavailableError is used to wrap an Underlying error, while indicating that
@@ -48,12 +53,22 @@ func (e UnavailableError) Error() string {
 type Bundle struct {
 	// imageRef is the reference to the OCI image source, e.g. oci-sif:alpine.sif
 	imageRef string
+	// platform, if set, selects the image to run from a multi-platform oci-sif.
+	platform *v1.Platform
+	// keyInfo holds the key material used to access encrypted layers.
+	keyInfo *cryptkey.KeyInfo
+	// key unlocks the encrypted layers of the image.
+	key []byte
 	// imageSpec is the OCI image information, CMD, ENTRYPOINT, etc.
 	imageSpec *imgspecv1.Image
 	// bundlePath is the location where the OCI bundle will be created.
 	bundlePath string
-	// paths to squashfs layers that have been mounted
-	mountedLayers []string
+	// layer filesystems that have been mounted
+	mountedLayers []mountedLayer
+	// luksServer serves the decrypted content of encrypted layers.
+	luksServer *gofuse.Server
+	// luksFile is the image file that encrypted layers are read from.
+	luksFile *os.File
 	// assembled rootfs, from overlay mount of mountedLayers
 	rootfsOverlaySet overlay.Set
 	// Has the image been mounted onto the bundle rootfs?
@@ -62,6 +77,13 @@ type Bundle struct {
 	ocibundle.Bundle
 }
 
+// mountedLayer is a layer filesystem mounted from the image.
+type mountedLayer struct {
+	path string
+	// kernel is set if an erofs layer was mounted with the kernel driver.
+	kernel bool
+}
+
 type Option func(b *Bundle) error
 
 // OptBundlePath sets the path that the bundle will be created at.
@@ -84,6 +106,24 @@ func OptImageRef(ref string) Option {
 	}
 }
 
+// OptPlatform sets the platform that the image must satisfy. In a
+// multi-platform oci-sif, the image for this platform is used.
+func OptPlatform(p v1.Platform) Option {
+	return func(b *Bundle) error {
+		b.platform = &p
+		return nil
+	}
+}
+
+// OptKeyInfo sets the key material used to access the encrypted layers of an
+// image.
+func OptKeyInfo(ki *cryptkey.KeyInfo) Option {
+	return func(b *Bundle) error {
+		b.keyInfo = ki
+		return nil
+	}
+}
+
 // New returns a bundle interface to create/delete an OCI bundle from an oci-sif image ref.
 func New(opts ...Option) (ocibundle.Bundle, error) {
 	b := Bundle{
@@ -111,11 +151,29 @@ func (b *Bundle) Delete(ctx context.Context) error {
 		}
 	}
 
-	for _, layerPath := range b.mountedLayers {
-		sylog.Debugf("Unmounting layer fs from %q", layerPath)
-		if err := squashfs.FUSEUnmount(ctx, layerPath); err != nil {
+	for _, l := range b.mountedLayers {
+		sylog.Debugf("Unmounting layer fs from %q", l.path)
+		unmount := squashfs.FUSEUnmount
+		if l.kernel {
+			unmount = func(ctx context.Context, path string) error {
+				return erofs.Unmount(ctx, path, true)
+			}
+		}
+		if err := unmount(ctx, l.path); err != nil {
+			return err
+		}
+	}
+
+	if b.luksServer != nil {
+		sylog.Debugf("Unmounting decrypted layers from %q", b.luksPath())
+		if err := b.luksServer.Unmount(); err != nil {
 			return err
 		}
+		b.luksServer = nil
+	}
+	if b.luksFile != nil {
+		b.luksFile.Close()
+		b.luksFile = nil
 	}
 
 	return tools.DeleteBundle(b.bundlePath)
@@ -130,13 +188,18 @@ func (b *Bundle) Create(ctx context.Context, ociConfig *specs.Spec) error {
 	}
 
 	// Retrieve and check the index manifest, which lists the images in the oci-sif file.
-	// We currently only support oci-sif files containing exactly 1 image.
+	// Without a platform, we only support oci-sif files containing exactly 1 image.
 	fi, err := sif.LoadContainerFromPath(imgFile, sif.OptLoadWithFlag(os.O_RDONLY))
 	if err != nil {
 		return fmt.Errorf("while loading SIF: %w", err)
 	}
 
-	img, err := ocisif.GetSingleImage(fi)
+	var img v1.Image
+	if b.platform != nil {
+		img, err = ocisif.GetPlatformImage(fi, *b.platform)
+	} else {
+		img, err = ocisif.GetSingleImage(fi)
+	}
 	if err != nil {
 		return fmt.Errorf("while initializing image: %w", err)
 	}
@@ -153,6 +216,21 @@ func (b *Bundle) Create(ctx context.Context, ociConfig *specs.Spec) error {
 	}
 	b.imageSpec = &imageSpec
 
+	encrypted, err := ocisif.HasEncryptedLayers(img)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		sylog.Debugf("Encrypted image layers detected")
+		if b.keyInfo == nil {
+			return fmt.Errorf("no key was provided, cannot access encrypted container")
+		}
+		if b.key, err = cryptkey.PlaintextKey(*b.keyInfo, imgFile); err != nil {
+			sylog.Errorf("Please check you are providing the correct key for decryption")
+			return fmt.Errorf("cannot decrypt %s: %w", imgFile, err)
+		}
+	}
+
 	// Generate OCI bundle directory and config
 	g, err := tools.GenerateBundleConfig(b.bundlePath, ociConfig)
 	if err != nil {
@@ -163,7 +241,7 @@ func (b *Bundle) Create(ctx context.Context, ociConfig *specs.Spec) error {
 	}
 
 	// Mount layers from image
-	sylog.Debugf("Mounting squashfs layers from %q to %q", imgFile, b.bundlePath)
+	sylog.Debugf("Mounting layers from %q to %q", imgFile, b.bundlePath)
 	if err := b.mountLayers(ctx, img, imgFile); err != nil {
 		if errCleanup := b.Delete(ctx); errCleanup != nil {
 			sylog.Errorf("While removing temporary bundle: %v", errCleanup)
@@ -189,16 +267,28 @@ func (b *Bundle) mountLayers(ctx context.Context, img v1.Image, imgFile string)
 		return fmt.Errorf("while obtaining layers: %s", err)
 	}
 
+	// Each layer fs is mounted from the image file, or from the decrypted
+	// content of an encrypted layer, served from a FUSE filesystem.
+	type layerSource struct {
+		path   string
+		offset int64
+		size   int64
+		mt     types.MediaType
+	}
+	sources := make([]layerSource, 0, len(layers))
+	var volumes []*crypt.LUKS2Reader
+
 	for i, l := range layers {
 		mt, err := l.MediaType()
 		if err != nil {
 			return fmt.Errorf("while checking layer: %w", err)
 		}
 		// An ext3 final layer is an overlay, and handled separately from the rootfs assembly.
-		if mt == ocisif.Ext3LayerMediaType && i == len(layers)-1 {
+		if ocisif.DecryptedMediaType(mt) == ocisif.Ext3LayerMediaType && i == len(layers)-1 {
 			continue
 		}
-		if mt != ocisif.SquashfsLayerMediaType {
+		decryptedMT := ocisif.DecryptedMediaType(mt)
+		if !ocisif.IsSquashfsLayer(decryptedMT) && decryptedMT != ocisif.ErofsLayerMediaType {
 			return fmt.Errorf("unsupported layer mediaType %q", mt)
 		}
 		ol, ok := l.(*ocitsif.Layer)
@@ -210,27 +300,77 @@ func (b *Bundle) mountLayers(ctx context.Context, img v1.Image, imgFile string)
 			return fmt.Errorf("while finding layer offset: %w", err)
 		}
 
+		size, err := l.Size()
+		if err != nil {
+			return fmt.Errorf("while finding layer size: %w", err)
+		}
+
+		if !ocisif.IsEncryptedLayer(mt) {
+			sources = append(sources, layerSource{imgFile, offset, size, mt})
+			continue
+		}
+
+		if b.luksFile == nil {
+			if b.luksFile, err = os.Open(imgFile); err != nil {
+				return err
+			}
+		}
+		v, err := crypt.NewLUKS2ReadWriter(b.luksFile, offset, size, b.key)
+		if err != nil {
+			return fmt.Errorf("while unlocking layer %d: %w", i, err)
+		}
+		path := filepath.Join(b.luksPath(), strconv.Itoa(len(volumes)))
+		sources = append(sources, layerSource{path, 0, 0, decryptedMT})
+		volumes = append(volumes, v)
+	}
+
+	if len(volumes) > 0 {
+		sylog.Debugf("Mounting decrypted layers to %q", b.luksPath())
+		if err := os.Mkdir(b.luksPath(), 0o700); err != nil {
+			return fmt.Errorf("while creating decrypted layers directory: %w", err)
+		}
+		if b.luksServer, err = crypt.MountLUKS2(b.luksPath(), volumes, false); err != nil {
+			return UnavailableError{Underlying: err}
+		}
+	}
+
+	for i, src := range sources {
 		layerPath := filepath.Join(tools.Layers(b.bundlePath).Path(), strconv.Itoa(i))
-		sylog.Debugf("Mounting layer %d fs from %q to %q", i, imgFile, layerPath)
+		sylog.Debugf("Mounting layer %d fs from %q to %q", i, src.path, layerPath)
 		if err := fs.MkdirAt(tools.Layers(b.bundlePath).Path(), strconv.Itoa(i), 0o755); err != nil {
 			return fmt.Errorf("while creating layer directory: %w", err)
 		}
 
-		fuseOffset, err := safecast.Convert[uint64](offset)
+		offset, err := safecast.Convert[uint64](src.offset)
+		if err != nil {
+			return err
+		}
+		size, err := safecast.Convert[uint64](src.size)
 		if err != nil {
 			return err
 		}
-		if _, err := squashfs.FUSEMount(ctx, fuseOffset, imgFile, layerPath, false); err != nil {
+		ml := mountedLayer{path: layerPath}
+		if src.mt == ocisif.ErofsLayerMediaType {
+			if ml.kernel, err = erofs.Mount(ctx, offset, size, src.path, layerPath); err != nil {
+				return UnavailableError{Underlying: fmt.Errorf("while mounting erofs layer: %w", err)}
+			}
+		} else if _, err := squashfs.FUSEMount(ctx, offset, src.path, layerPath, false); err != nil {
 			return UnavailableError{Underlying: fmt.Errorf("while mounting squashfs layer: %w", err)}
 		}
-		b.mountedLayers = append(b.mountedLayers, layerPath)
+		b.mountedLayers = append(b.mountedLayers, ml)
 	}
 	return nil
 }
 
+// luksPath returns the directory that the decrypted content of encrypted
+// layers is served at.
+func (b *Bundle) luksPath() string {
+	return filepath.Join(tools.Layers(b.bundlePath).Path(), "luks")
+}
+
 func (b *Bundle) mountRootfs(ctx context.Context) error {
 	for i := len(b.mountedLayers) - 1; i >= 0; i-- {
-		item, err := overlay.NewItemFromString(b.mountedLayers[i])
+		item, err := overlay.NewItemFromString(b.mountedLayers[i].path)
 		if err != nil {
 			return err
 		}
@@ -88,6 +88,7 @@ type JSONConfig struct {
 	OpenFd                []int             `json:"openFd,omitempty"`
 	TargetGID             []int             `json:"targetGID,omitempty"`
 	Image                 string            `json:"image"`
+	ImageSource           string            `json:"imageSource,omitempty"`
 	Workdir               string            `json:"workdir,omitempty"`
 	CgroupsJSON           string            `json:"cgroupsJSON,omitempty"`
 	HomeSource            string            `json:"homedir,omitempty"`
@@ -154,6 +155,17 @@ func (e *EngineConfig) GetImage() string {
 	return e.JSON.Image
 }
 
+// SetImageSource sets the URI that the container image was retrieved from.
+func (e *EngineConfig) SetImageSource(uri string) {
+	e.JSON.ImageSource = uri
+}
+
+// GetImageSource retrieves the URI that the container image was retrieved
+// from, if any.
+func (e *EngineConfig) GetImageSource() string {
+	return e.JSON.ImageSource
+}
+
 // SetEncryptionKey sets the key for the image's system partition.
 func (e *EngineConfig) SetEncryptionKey(key []byte) {
 	e.JSON.EncryptionKey = key
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2025, Sylabs Inc. All rights reserved.
+// Copyright (c) 2019-2026, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,16 +6,12 @@
 package cryptkey
 
 import (
-	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
 	"crypto/x509"
-	"encoding/asn1"
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/sylabs/sif/v2/pkg/sif"
@@ -47,6 +43,9 @@ type KeyInfo struct {
 	Format   int
 	Material string
 	Path     string
+	// Recipients holds the paths of additional RSA or PGP public keys that
+	// the key of a PEM encrypted container is encrypted for.
+	Recipients []string
 }
 
 func getRandomBytes(size int) ([]byte, error) {
@@ -75,26 +74,31 @@ func NewPlaintextKey(k KeyInfo) ([]byte, error) {
 	}
 }
 
+// EncryptKey returns plaintext encrypted for the key material in k. With PEM
+// key material, plaintext is encrypted for the public key at k.Path, if set,
+// and each of k.Recipients. Each public key may be a PEM formatted RSA key, or
+// an ASCII armored PGP key.
 func EncryptKey(k KeyInfo, plaintext []byte) ([]byte, error) {
 	switch k.Format {
 	case PEM:
-		pubKey, err := LoadPEMPublicKey(k.Path)
-		if err != nil {
-			return nil, fmt.Errorf("loading public key for key encryption: %v", err)
+		paths := k.Recipients
+		if k.Path != "" {
+			paths = append([]string{k.Path}, paths...)
 		}
-
-		ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pubKey, plaintext, nil)
-		if err != nil {
-			return nil, fmt.Errorf("encrypting key: %v", err)
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("encrypting key: %v", ErrNoPEMData)
 		}
 
-		var buf bytes.Buffer
-
-		if err := savePEMMessage(&buf, ciphertext); err != nil {
-			return nil, fmt.Errorf("serializing encrypted key: %v", err)
+		blocks := make([]*pem.Block, 0, len(paths))
+		for _, path := range paths {
+			block, err := encryptForRecipient(path, plaintext)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block)
 		}
 
-		return buf.Bytes(), nil
+		return encodeMessages(blocks)
 
 	case Passphrase:
 		return nil, nil
@@ -104,12 +108,16 @@ func EncryptKey(k KeyInfo, plaintext []byte) ([]byte, error) {
 	}
 }
 
+// PlaintextKey returns the key of the encrypted container image, using the key
+// material in k. With PEM key material, the private key at k.Path may be a PEM
+// formatted RSA key, or an ASCII armored PGP key, decrypted with k.Material if
+// it is protected by a passphrase.
 func PlaintextKey(k KeyInfo, image string) ([]byte, error) {
 	switch k.Format {
 	case PEM:
-		privateKey, err := LoadPEMPrivateKey(k.Path)
+		privateKey, err := loadPrivateKey(k.Path, k.Material)
 		if err != nil {
-			return nil, fmt.Errorf("could not load PEM private key: %v", err)
+			return nil, err
 		}
 
 		pemKey, err := getEncryptionKeyFromImage(image)
@@ -117,16 +125,14 @@ func PlaintextKey(k KeyInfo, image string) ([]byte, error) {
 			return nil, fmt.Errorf("could not get encryption information from SIF: %v", err)
 		}
 
-		pemBuf := bytes.NewReader(pemKey)
-
-		encKey, err := loadPEMMessage(pemBuf)
+		blocks, err := decodeMessages(pemKey)
 		if err != nil {
 			return nil, fmt.Errorf("could not unpack LUKS PEM from SIF: %v", err)
 		}
 
-		plaintext, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, encKey, nil)
+		plaintext, _, err := privateKey.decrypt(blocks)
 		if err != nil {
-			return nil, fmt.Errorf("could not decrypt LUKS key: %v", err)
+			return nil, err
 		}
 
 		return plaintext, nil
@@ -167,80 +173,60 @@ func LoadPEMPublicKey(fn string) (*rsa.PublicKey, error) {
 	return x509.ParsePKCS1PublicKey(block.Bytes)
 }
 
-func loadPEMMessage(r io.Reader) ([]byte, error) {
-	b, err := io.ReadAll(r)
+func getEncryptionKeyFromImage(fn string) ([]byte, error) {
+	img, err := sif.LoadContainerFromPath(fn, sif.OptLoadWithFlag(os.O_RDONLY))
 	if err != nil {
-		return nil, err
-	}
-
-	block, _ := pem.Decode(b)
-	if block == nil {
-		return nil, fmt.Errorf("could not load decode PEM key %s: %v", r, ErrNoPEMData)
-	}
-
-	var buf []byte
-	if _, err := asn1.Unmarshal(block.Bytes, &buf); err != nil {
-		return nil, fmt.Errorf("could not unmarshal key asn1 data: %v", err)
+		return nil, fmt.Errorf("could not load container: %w", err)
 	}
+	defer img.UnloadContainer()
 
-	return buf, nil
-}
-
-func savePEMMessage(w io.Writer, msg []byte) error {
-	asn1Bytes, err := asn1.Marshal(msg)
+	d, err := getEncryptionKeyDescriptor(img, fn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	b := &pem.Block{
-		Type:  "MESSAGE",
-		Bytes: asn1Bytes,
+	key, err := d.GetData()
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve LUKS key data from %s: %w", fn, err)
 	}
 
-	return pem.Encode(w, b)
+	return key, nil
 }
 
-func getEncryptionKeyFromImage(fn string) ([]byte, error) {
-	img, err := sif.LoadContainerFromPath(fn, sif.OptLoadWithFlag(os.O_RDONLY))
-	if err != nil {
-		return nil, fmt.Errorf("could not load container: %w", err)
-	}
-	defer img.UnloadContainer()
+// getEncryptionKeyDescriptor returns the descriptor of the object holding the
+// encrypted LUKS key of img, loaded from fn. In a native SIF image, the key is
+// linked to the primary system partition. An OCI-SIF image has no primary
+// system partition, and holds a single key for all of its encrypted layers.
+func getEncryptionKeyDescriptor(img *sif.FileImage, fn string) (sif.Descriptor, error) {
+	fns := []sif.DescriptorSelectorFunc{sif.WithDataType(sif.DataCryptoMessage)}
 
 	primDescr, err := img.GetDescriptor(sif.WithPartitionType(sif.PartPrimSys))
-	if err != nil {
-		return nil, fmt.Errorf("could not retrieve primary system partition from '%s': %w", fn, err)
+	if err == nil {
+		fns = append(fns, sif.WithLinkedID(primDescr.ID()))
+	} else if !errors.Is(err, sif.ErrObjectNotFound) {
+		return sif.Descriptor{}, fmt.Errorf("could not retrieve primary system partition from '%s': %w", fn, err)
 	}
 
-	descr, err := img.GetDescriptors(
-		sif.WithLinkedID(primDescr.ID()),
-		sif.WithDataType(sif.DataCryptoMessage),
-	)
+	descr, err := img.GetDescriptors(fns...)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve linked descriptors for primary system partition from %s: %w", fn, err)
+		return sif.Descriptor{}, fmt.Errorf("could not retrieve linked descriptors for primary system partition from %s: %w", fn, err)
 	}
 
 	for _, d := range descr {
 		format, message, err := d.CryptoMessageMetadata()
 		if err != nil {
-			return nil, fmt.Errorf("could not get crypto message metadata: %w", err)
+			return sif.Descriptor{}, fmt.Errorf("could not get crypto message metadata: %w", err)
 		}
 
-		// currently only support one type of message
-		if format != sif.FormatPEM || message != sif.MessageRSAOAEP {
+		if format != sif.FormatPEM || (message != sif.MessageRSAOAEP && message != MessageRecipients) {
 			continue
 		}
 
 		// TODO(ian): For now, assume the first linked message is what we
 		// are looking for. We should consider what we want to do in the
 		// case of multiple linked messages
-		key, err := d.GetData()
-		if err != nil {
-			return nil, fmt.Errorf("could not retrieve LUKS key data from %s: %w", fn, err)
-		}
-
-		return key, nil
+		return d, nil
 	}
 
-	return nil, fmt.Errorf("could not read LUKS key from %s: %v", fn, ErrEncryptedKeyNotFound)
+	return sif.Descriptor{}, fmt.Errorf("could not read LUKS key from %s: %w", fn, ErrEncryptedKeyNotFound)
 }
@@ -0,0 +1,305 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cryptkey
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+var (
+	// ErrNoPGPData indicates there is no ASCII armored PGP key data.
+	ErrNoPGPData = errors.New("no PGP key data")
+	// ErrNotRecipient indicates the key is not a recipient of the encrypted key.
+	ErrNotRecipient = errors.New("key is not a recipient of the encrypted key")
+)
+
+const (
+	// rsaMessageType is the PEM block type of the key encrypted for an RSA
+	// recipient.
+	rsaMessageType = "MESSAGE"
+	// pgpMessageType is the PEM block type of the key encrypted for a PGP
+	// recipient, as a binary OpenPGP message.
+	pgpMessageType = "OPENPGP MESSAGE"
+	// recipientHeader is the PEM header identifying the recipient of the
+	// encrypted key.
+	recipientHeader = "Recipient"
+)
+
+// MessageRecipients is the SIF message type of an encrypted key that is held
+// in PEM blocks for multiple recipients, one or more of which is a PGP key.
+// Such a key is not an RSA-OAEP message, and is not understood by versions of
+// SingularityCE that only read a single RSA-OAEP encrypted key.
+const MessageRecipients sif.MessageType = 0x201
+
+// MessageType returns the SIF message type of the encrypted key msg, as
+// returned by EncryptKey.
+func MessageType(msg []byte) (sif.MessageType, error) {
+	blocks, err := decodeMessages(msg)
+	if err != nil {
+		return 0, err
+	}
+	for _, block := range blocks {
+		if block.Type != rsaMessageType {
+			return MessageRecipients, nil
+		}
+	}
+	return sif.MessageRSAOAEP, nil
+}
+
+// isPGPKeyFile returns true if b holds an ASCII armored PGP key.
+func isPGPKeyFile(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN PGP "))
+}
+
+// LoadPGPKey loads the ASCII armored PGP key in file fn. If fn does not hold
+// a PGP key, ErrNoPGPData is returned.
+func LoadPGPKey(fn string) (*openpgp.Entity, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	if !isPGPKeyFile(b) {
+		return nil, fmt.Errorf("could not read %s: %w", fn, ErrNoPGPData)
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", fn, err)
+	}
+	if len(el) != 1 {
+		return nil, fmt.Errorf("could not read %s: expected a single PGP key, found %d", fn, len(el))
+	}
+	return el[0], nil
+}
+
+// rsaRecipientID returns the recipient ID of pub, the hex encoded SHA-256
+// digest of its PKIX, ASN.1 DER encoding.
+func rsaRecipientID(pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// pgpRecipientID returns the recipient ID of e, the hex encoded fingerprint of
+// its primary key.
+func pgpRecipientID(e *openpgp.Entity) string {
+	return hex.EncodeToString(e.PrimaryKey.Fingerprint)
+}
+
+// RecipientID returns the ID of the recipient holding the public or private
+// key in file fn, which may be a PEM formatted RSA key, or an ASCII armored
+// PGP key. The ID of an RSA key is the hex encoded SHA-256 digest of the DER
+// encoding of its public key, and the ID of a PGP key is its fingerprint.
+func RecipientID(fn string) (string, error) {
+	if e, err := LoadPGPKey(fn); err == nil {
+		return pgpRecipientID(e), nil
+	} else if !errors.Is(err, ErrNoPGPData) {
+		return "", err
+	}
+
+	pub, err := loadRSAPublicKey(fn)
+	if err != nil {
+		return "", err
+	}
+	return rsaRecipientID(pub)
+}
+
+// loadRSAPublicKey loads the RSA public key in file fn, which may hold a PEM
+// formatted public key, or the private key that it is the public half of.
+func loadRSAPublicKey(fn string) (*rsa.PublicKey, error) {
+	pub, err := LoadPEMPublicKey(fn)
+	if err != nil {
+		priv, perr := LoadPEMPrivateKey(fn)
+		if perr != nil {
+			return nil, err
+		}
+		pub = &priv.PublicKey
+	}
+	return pub, nil
+}
+
+// encryptForRecipient returns a PEM block holding plaintext, encrypted for the
+// RSA or PGP public key in file fn. If fn holds a private key, plaintext is
+// encrypted for its public half.
+func encryptForRecipient(fn string, plaintext []byte) (*pem.Block, error) {
+	if e, err := LoadPGPKey(fn); err == nil {
+		var buf bytes.Buffer
+
+		w, err := openpgp.Encrypt(&buf, []*openpgp.Entity{e}, nil, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("encrypting key: %v", err)
+		}
+		if _, err := w.Write(plaintext); err != nil {
+			return nil, fmt.Errorf("encrypting key: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("encrypting key: %v", err)
+		}
+
+		return &pem.Block{
+			Type:    pgpMessageType,
+			Headers: map[string]string{recipientHeader: pgpRecipientID(e)},
+			Bytes:   buf.Bytes(),
+		}, nil
+	} else if !errors.Is(err, ErrNoPGPData) {
+		return nil, fmt.Errorf("loading public key for key encryption: %v", err)
+	}
+
+	pubKey, err := loadRSAPublicKey(fn)
+	if err != nil {
+		return nil, fmt.Errorf("loading public key for key encryption: %v", err)
+	}
+
+	id, err := rsaRecipientID(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("loading public key for key encryption: %v", err)
+	}
+
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pubKey, plaintext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting key: %v", err)
+	}
+
+	asn1Bytes, err := asn1.Marshal(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("serializing encrypted key: %v", err)
+	}
+
+	return &pem.Block{
+		Type:    rsaMessageType,
+		Headers: map[string]string{recipientHeader: id},
+		Bytes:   asn1Bytes,
+	}, nil
+}
+
+// decodeMessages returns the PEM blocks holding the key encrypted for each
+// recipient.
+func decodeMessages(b []byte) ([]*pem.Block, error) {
+	var blocks []*pem.Block
+	for rest := bytes.TrimSpace(b); len(rest) > 0; rest = bytes.TrimSpace(rest) {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return nil, fmt.Errorf("could not decode encrypted key: %v", ErrNoPEMData)
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("could not decode encrypted key: %v", ErrNoPEMData)
+	}
+	return blocks, nil
+}
+
+// encodeMessages returns the PEM encoding of blocks.
+func encodeMessages(blocks []*pem.Block) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, block := range blocks {
+		if err := pem.Encode(&buf, block); err != nil {
+			return nil, fmt.Errorf("serializing encrypted key: %v", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// forRecipient returns true if block may hold the key encrypted for the
+// recipient with ID id. Blocks without a recipient header, written before
+// images could be encrypted for multiple recipients, may be for any recipient.
+func forRecipient(block *pem.Block, id string) bool {
+	r, ok := block.Headers[recipientHeader]
+	return !ok || strings.EqualFold(r, id)
+}
+
+// privateKey is the private key of a recipient, used to decrypt the key
+// encrypted for the recipient.
+type privateKey struct {
+	id  string
+	pgp *openpgp.Entity
+	rsa *rsa.PrivateKey
+}
+
+// loadPrivateKey loads the PEM formatted RSA private key, or ASCII armored PGP
+// private key, in file fn. If the key is an encrypted PGP key, passphrase is
+// used to decrypt it.
+func loadPrivateKey(fn, passphrase string) (privateKey, error) {
+	if b, err := os.ReadFile(fn); err == nil && isPGPKeyFile(b) {
+		e, err := LoadPGPKey(fn)
+		if err != nil {
+			return privateKey{}, fmt.Errorf("could not load PGP private key: %v", err)
+		}
+		if e.PrivateKey == nil {
+			return privateKey{}, fmt.Errorf("could not load PGP private key: %s holds a public key", fn)
+		}
+		if e.PrivateKey.Encrypted {
+			if err := e.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+				return privateKey{}, fmt.Errorf("could not decrypt PGP private key: %v", err)
+			}
+		}
+		return privateKey{id: pgpRecipientID(e), pgp: e}, nil
+	}
+
+	k, err := LoadPEMPrivateKey(fn)
+	if err != nil {
+		return privateKey{}, fmt.Errorf("could not load PEM private key: %v", err)
+	}
+	id, err := rsaRecipientID(&k.PublicKey)
+	if err != nil {
+		return privateKey{}, fmt.Errorf("could not load PEM private key: %v", err)
+	}
+	return privateKey{id: id, rsa: k}, nil
+}
+
+// decrypt returns the key held by the first of blocks that was encrypted for
+// k, and that block.
+func (k privateKey) decrypt(blocks []*pem.Block) ([]byte, *pem.Block, error) {
+	for _, block := range blocks {
+		if !forRecipient(block, k.id) {
+			continue
+		}
+
+		switch {
+		case k.pgp != nil && block.Type == pgpMessageType:
+			md, err := openpgp.ReadMessage(bytes.NewReader(block.Bytes), openpgp.EntityList{k.pgp}, nil, nil)
+			if err != nil {
+				continue
+			}
+			plaintext, err := io.ReadAll(md.UnverifiedBody)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not decrypt LUKS key: %v", err)
+			}
+			return plaintext, block, nil
+
+		case k.rsa != nil && block.Type == rsaMessageType:
+			var encKey []byte
+			if _, err := asn1.Unmarshal(block.Bytes, &encKey); err != nil {
+				return nil, nil, fmt.Errorf("could not unmarshal key asn1 data: %v", err)
+			}
+
+			plaintext, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, k.rsa, encKey, nil)
+			if err != nil {
+				continue
+			}
+			return plaintext, block, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("could not decrypt LUKS key: %w", ErrNotRecipient)
+}
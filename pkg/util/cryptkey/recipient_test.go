@@ -0,0 +1,470 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cryptkey
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/crypt"
+)
+
+// writePGPKeys writes the ASCII armored public and private keys of a new PGP
+// entity to files, encrypting the private key with passphrase if it is not
+// empty. The paths of the public and private key files are returned.
+func writePGPKeys(t *testing.T, passphrase string) (string, string) {
+	t.Helper()
+
+	e, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	pub := filepath.Join(dir, "public.asc")
+	priv := filepath.Join(dir, "private.asc")
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pub, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	if w, err = armor.Encode(&buf, openpgp.PrivateKeyType, nil); err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "" {
+		if err := e.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(priv, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return pub, priv
+}
+
+// writeRSAKeys writes the PEM encoded public and private keys of a new RSA key
+// to files. The paths of the public and private key files are returned.
+func writeRSAKeys(t *testing.T) (string, string) {
+	t.Helper()
+
+	k, err := GenerateRSAKey(DefaultKeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	pub := filepath.Join(dir, "public.pem")
+	priv := filepath.Join(dir, "private.pem")
+
+	if err := SavePublicPEM(pub, k); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePrivatePEM(priv, k); err != nil {
+		t.Fatal(err)
+	}
+
+	return pub, priv
+}
+
+var (
+	luks2VolumesMu sync.Mutex
+	luks2Volumes   = make(map[string][]byte)
+)
+
+// luks2Volume returns a LUKS2 volume unlocked by key. Volumes are shared
+// between tests, as the key derivation of each keyslot is costly.
+func luks2Volume(t *testing.T, key []byte) []byte {
+	t.Helper()
+
+	luks2VolumesMu.Lock()
+	defer luks2VolumesMu.Unlock()
+
+	if vol, ok := luks2Volumes[string(key)]; ok {
+		return vol
+	}
+	var vol bytes.Buffer
+	if err := crypt.EncryptLUKS2(&vol, strings.NewReader("partition"), key); err != nil {
+		t.Fatal(err)
+	}
+	luks2Volumes[string(key)] = vol.Bytes()
+	return vol.Bytes()
+}
+
+// createEncryptedImage creates an image holding a primary system partition,
+// a LUKS2 volume unlocked by plaintext, and the LUKS key encrypted with k.
+func createEncryptedImage(t *testing.T, k KeyInfo, plaintext []byte) string {
+	t.Helper()
+
+	ciphertext, err := EncryptKey(k, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mt, err := MessageType(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(luks2Volume(t, plaintext)),
+		sif.OptPartitionMetadata(sif.FsEncryptedSquashfs, sif.PartPrimSys, "amd64"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := sif.NewDescriptorInput(sif.DataCryptoMessage, bytes.NewReader(ciphertext),
+		sif.OptLinkedID(1),
+		sif.OptCryptoMessageMetadata(sif.FormatPEM, mt),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "image.sif")
+	f, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(part, msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.UnloadContainer(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// unlocksPartition returns whether key unlocks the primary system partition of
+// the image at path.
+func unlocksPartition(t *testing.T, path string, key []byte) bool {
+	t.Helper()
+
+	fi, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.UnloadContainer()
+
+	d, err := fi.GetDescriptor(sif.WithPartitionType(sif.PartPrimSys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = crypt.NewLUKS2Reader(io.NewSectionReader(f, d.Offset(), d.Size()), d.Size(), key)
+	if err != nil && !errors.Is(err, crypt.ErrInvalidPassphrase) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestRecipientID(t *testing.T) {
+	rsaPub, rsaPriv := writeRSAKeys(t)
+	pgpPub, pgpPriv := writePGPKeys(t, testPassphrase)
+
+	tests := []struct {
+		name    string
+		a, b    string
+		wantErr bool
+	}{
+		{name: "RSA", a: rsaPub, b: rsaPriv},
+		{name: "PGP", a: pgpPub, b: pgpPriv},
+		{name: "Invalid", a: invalidPemPath, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := RecipientID(tt.a)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			b, err := RecipientID(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a != b {
+				t.Errorf("got IDs %v and %v, want equal IDs for public and private key", a, b)
+			}
+		})
+	}
+}
+
+func TestEncryptKeyRecipients(t *testing.T) {
+	rsaPub, rsaPriv := writeRSAKeys(t)
+	pgpPub, pgpPriv := writePGPKeys(t, testPassphrase)
+	otherPub, otherPriv := writeRSAKeys(t)
+	_, strangerPriv := writeRSAKeys(t)
+
+	plaintext := []byte("luks key")
+
+	path := createEncryptedImage(t, KeyInfo{
+		Format:     PEM,
+		Path:       rsaPub,
+		Recipients: []string{pgpPub, otherPub},
+	}, plaintext)
+
+	ids, err := Recipients(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(ids), 3; got != want {
+		t.Fatalf("got %v recipients, want %v", got, want)
+	}
+
+	tests := []struct {
+		name    string
+		keyInfo KeyInfo
+		wantErr error
+	}{
+		{name: "RSA", keyInfo: KeyInfo{Format: PEM, Path: rsaPriv}},
+		{name: "RSARecipient", keyInfo: KeyInfo{Format: PEM, Path: otherPriv}},
+		{name: "PGPRecipient", keyInfo: KeyInfo{Format: PEM, Path: pgpPriv, Material: testPassphrase}},
+		{name: "NotRecipient", keyInfo: KeyInfo{Format: PEM, Path: strangerPriv}, wantErr: ErrNotRecipient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlaintextKey(tt.keyInfo, path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !bytes.Equal(got, plaintext) {
+				t.Errorf("got key %q, want %q", got, plaintext)
+			}
+		})
+	}
+
+	t.Run("PGPBadPassphrase", func(t *testing.T) {
+		if _, err := PlaintextKey(KeyInfo{Format: PEM, Path: pgpPriv, Material: "bad"}, path); err == nil {
+			t.Error("unexpected success")
+		}
+	})
+}
+
+func TestRekey(t *testing.T) {
+	rsaPub, rsaPriv := writeRSAKeys(t)
+	pgpPub, pgpPriv := writePGPKeys(t, "")
+	otherPub, otherPriv := writeRSAKeys(t)
+
+	plaintext := []byte("luks key")
+
+	rsaID, err := RecipientID(rsaPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgpID, err := RecipientID(pgpPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherID, err := RecipientID(otherPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		recipients  []string
+		keyInfo     *KeyInfo
+		add         []string
+		remove      []string
+		wantIDs     []string
+		wantErr     error
+		wantMessage sif.MessageType
+		wantRotated bool
+		decrypts    []string
+	}{
+		{
+			name:        "Add",
+			keyInfo:     &KeyInfo{Format: PEM, Path: rsaPriv},
+			add:         []string{pgpPub, otherPub},
+			wantIDs:     []string{rsaID, pgpID, otherID},
+			wantMessage: MessageRecipients,
+			decrypts:    []string{rsaPriv, pgpPriv, otherPriv},
+		},
+		{
+			name:        "AddRSA",
+			keyInfo:     &KeyInfo{Format: PEM, Path: rsaPriv},
+			add:         []string{otherPub},
+			wantIDs:     []string{rsaID, otherID},
+			wantMessage: sif.MessageRSAOAEP,
+			decrypts:    []string{rsaPriv, otherPriv},
+		},
+		{
+			name:        "AddExisting",
+			keyInfo:     &KeyInfo{Format: PEM, Path: rsaPriv},
+			add:         []string{rsaPub},
+			wantIDs:     []string{rsaID},
+			wantMessage: sif.MessageRSAOAEP,
+			decrypts:    []string{rsaPriv},
+		},
+		{
+			name:    "AddWithoutKey",
+			add:     []string{pgpPub},
+			wantErr: ErrUnsupportedKeyURI,
+		},
+		{
+			name:    "AddNotRecipient",
+			keyInfo: &KeyInfo{Format: PEM, Path: otherPriv},
+			add:     []string{pgpPub},
+			wantErr: ErrNotRecipient,
+		},
+		{
+			name:        "AddRemove",
+			keyInfo:     &KeyInfo{Format: PEM, Path: rsaPriv},
+			add:         []string{otherPub},
+			remove:      []string{rsaID},
+			wantIDs:     []string{otherID},
+			wantMessage: sif.MessageRSAOAEP,
+			wantRotated: true,
+			decrypts:    []string{otherPriv},
+		},
+		{
+			name:        "RemoveByPath",
+			keyInfo:     &KeyInfo{Format: PEM, Path: rsaPriv},
+			add:         []string{otherPub},
+			remove:      []string{rsaPub},
+			wantIDs:     []string{otherID},
+			wantMessage: sif.MessageRSAOAEP,
+			wantRotated: true,
+			decrypts:    []string{otherPriv},
+		},
+		{
+			name:        "RemoveRemaining",
+			recipients:  []string{pgpPub, otherPub},
+			keyInfo:     &KeyInfo{Format: PEM, Path: rsaPriv},
+			add:         []string{otherPub},
+			remove:      []string{pgpID},
+			wantIDs:     []string{rsaID, otherID},
+			wantMessage: sif.MessageRSAOAEP,
+			wantRotated: true,
+			decrypts:    []string{rsaPriv, otherPriv},
+		},
+		{
+			name:       "RemoveWithoutRecipientKey",
+			recipients: []string{pgpPub, otherPub},
+			keyInfo:    &KeyInfo{Format: PEM, Path: rsaPriv},
+			remove:     []string{pgpID},
+			wantErr:    ErrRecipientKeyRequired,
+		},
+		{
+			name:    "RemoveWithoutKey",
+			remove:  []string{rsaID},
+			wantErr: ErrUnsupportedKeyURI,
+		},
+		{
+			name:    "RemoveNotFound",
+			keyInfo: &KeyInfo{Format: PEM, Path: rsaPriv},
+			remove:  []string{otherID},
+			wantErr: ErrRecipientNotFound,
+		},
+		{
+			name:    "RemoveAll",
+			keyInfo: &KeyInfo{Format: PEM, Path: rsaPriv},
+			remove:  []string{rsaID},
+			wantErr: ErrNoRecipients,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createEncryptedImage(t, KeyInfo{Format: PEM, Path: rsaPub, Recipients: tt.recipients}, plaintext)
+
+			ids, err := Rekey(path, tt.keyInfo, tt.add, tt.remove, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got, want := ids, tt.wantIDs; !slices.Equal(got, want) {
+				t.Errorf("got recipients %v, want %v", got, want)
+			}
+
+			if tt.wantErr != nil {
+				if len(tt.remove) > 0 && !unlocksPartition(t, path, plaintext) {
+					t.Errorf("key of image changed on failure")
+				}
+				return
+			}
+
+			if got, err := Recipients(path); err != nil {
+				t.Fatal(err)
+			} else if !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("got recipients %v in image, want %v", got, tt.wantIDs)
+			}
+
+			fi, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ds, err := fi.GetDescriptors(sif.WithDataType(sif.DataCryptoMessage))
+			fi.UnloadContainer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ds) != 1 {
+				t.Fatalf("got %d encrypted keys, want 1", len(ds))
+			}
+			if _, mt, err := ds[0].CryptoMessageMetadata(); err != nil {
+				t.Fatal(err)
+			} else if mt != tt.wantMessage {
+				t.Errorf("got message type %v, want %v", mt, tt.wantMessage)
+			}
+
+			var key []byte
+			for _, fn := range tt.decrypts {
+				got, err := PlaintextKey(KeyInfo{Format: PEM, Path: fn}, path)
+				if err != nil {
+					t.Fatalf("failed to decrypt with %v: %v", fn, err)
+				}
+				if key != nil && !bytes.Equal(got, key) {
+					t.Errorf("recipients decrypt different keys")
+				}
+				key = got
+			}
+			if rotated := !bytes.Equal(key, plaintext); rotated != tt.wantRotated {
+				t.Errorf("got rotated key %v, want %v", rotated, tt.wantRotated)
+			}
+			if !unlocksPartition(t, path, key) {
+				t.Errorf("key does not unlock partition")
+			}
+			if tt.wantRotated && unlocksPartition(t, path, plaintext) {
+				t.Errorf("old key unlocks partition")
+			}
+		})
+	}
+}
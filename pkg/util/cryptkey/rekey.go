@@ -0,0 +1,280 @@
+// Copyright (c) 2026, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cryptkey
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/v4/internal/pkg/util/crypt"
+)
+
+var (
+	// ErrRecipientNotFound indicates the recipient to remove is not found.
+	ErrRecipientNotFound = errors.New("recipient not found")
+	// ErrNoRecipients indicates the key would not be encrypted for any recipient.
+	ErrNoRecipients = errors.New("cannot remove all recipients")
+	// ErrRecipientKeyRequired indicates the public key of a remaining recipient
+	// is needed to encrypt a new key for it.
+	ErrRecipientKeyRequired = errors.New("public key of remaining recipient required")
+)
+
+// recipientIDs returns the ID of the recipient of each of blocks. The recipient
+// of a block without a recipient header is not known, and is returned as an
+// empty ID.
+func recipientIDs(blocks []*pem.Block) []string {
+	ids := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		ids = append(ids, strings.ToLower(block.Headers[recipientHeader]))
+	}
+	return ids
+}
+
+// Recipients returns the IDs of the recipients that the LUKS key of the
+// encrypted container image is encrypted for. The recipient of a key encrypted
+// before images could be encrypted for multiple recipients is not known, and
+// is returned as an empty ID.
+func Recipients(image string) ([]string, error) {
+	pemKey, err := getEncryptionKeyFromImage(image)
+	if err != nil {
+		return nil, fmt.Errorf("could not get encryption information from SIF: %v", err)
+	}
+
+	blocks, err := decodeMessages(pemKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not unpack LUKS PEM from SIF: %v", err)
+	}
+
+	return recipientIDs(blocks), nil
+}
+
+// RotateFunc re-encrypts the encrypted container image, so that it is
+// unlocked by newKey rather than key, and replaces its encrypted key with msg.
+type RotateFunc func(image string, key, newKey, msg []byte) error
+
+// Rekey updates the recipients that the LUKS key of the encrypted container
+// image is encrypted for, without re-encrypting its file system. The RSA or
+// PGP public keys in files add are added to the recipients, and the recipients
+// in remove, identified by ID or by the path of their public key file, are
+// removed. The LUKS key is first decrypted with the PEM key material in k. The
+// IDs of the resulting recipients are returned.
+//
+// When a recipient is removed, the LUKS key is replaced by a new key, so that
+// a removed recipient that kept a copy of the old key is not able to decrypt
+// the image. The new key is encrypted for each remaining recipient, so the
+// public key of each must be provided in add, or be the key in k. The image is
+// re-encrypted with rotate, or if rotate is nil, by changing the key of the
+// LUKS2 volume of the primary system partition of a native SIF image in place.
+func Rekey(image string, k *KeyInfo, add, remove []string, rotate RotateFunc) ([]string, error) {
+	if k == nil || k.Format != PEM {
+		return nil, fmt.Errorf("updating recipients requires the PEM private key of a recipient: %w", ErrUnsupportedKeyURI)
+	}
+
+	pemKey, err := getEncryptionKeyFromImage(image)
+	if err != nil {
+		return nil, fmt.Errorf("could not get encryption information from SIF: %v", err)
+	}
+
+	blocks, err := decodeMessages(pemKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not unpack LUKS PEM from SIF: %v", err)
+	}
+
+	privateKey, err := loadPrivateKey(k.Path, k.Material)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, block, err := privateKey.decrypt(blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify the recipient of a key encrypted without a recipient header.
+	if _, ok := block.Headers[recipientHeader]; !ok {
+		if block.Headers == nil {
+			block.Headers = make(map[string]string)
+		}
+		block.Headers[recipientHeader] = privateKey.id
+	}
+
+	for _, r := range remove {
+		id := r
+		if fi, err := os.Stat(r); err == nil && fi.Mode().IsRegular() {
+			if id, err = RecipientID(r); err != nil {
+				return nil, err
+			}
+		}
+
+		n := len(blocks)
+		blocks = slices.DeleteFunc(blocks, func(block *pem.Block) bool {
+			return strings.EqualFold(block.Headers[recipientHeader], id)
+		})
+		if len(blocks) == n {
+			return nil, fmt.Errorf("%w: %s", ErrRecipientNotFound, r)
+		}
+	}
+
+	if len(remove) == 0 {
+		blocks, err = addRecipients(blocks, add, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		data, err := encodeMessages(blocks)
+		if err != nil {
+			return nil, err
+		}
+		if err := replaceKeyMessage(image, data); err != nil {
+			return nil, err
+		}
+		return recipientIDs(blocks), nil
+	}
+
+	// Encrypt a new key for the remaining recipients, and those added, from
+	// their public keys.
+	newKey, err := getRandomBytes(len(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]string)
+	for _, path := range append([]string{k.Path}, add...) {
+		id, err := RecipientID(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading public key for key encryption: %v", err)
+		}
+		paths[id] = path
+	}
+	newBlocks := make([]*pem.Block, 0, len(blocks))
+	for _, block := range blocks {
+		id := strings.ToLower(block.Headers[recipientHeader])
+		path, ok := paths[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrRecipientKeyRequired, id)
+		}
+		b, err := encryptForRecipient(path, newKey)
+		if err != nil {
+			return nil, err
+		}
+		newBlocks = append(newBlocks, b)
+	}
+	if newBlocks, err = addRecipients(newBlocks, add, newKey); err != nil {
+		return nil, err
+	}
+	if len(newBlocks) == 0 {
+		return nil, ErrNoRecipients
+	}
+	data, err := encodeMessages(newBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotate == nil {
+		rotate = rotatePrimaryPartition
+	}
+	if err := rotate(image, plaintext, newKey, data); err != nil {
+		return nil, fmt.Errorf("could not change LUKS key of %s: %w", image, err)
+	}
+	return recipientIDs(newBlocks), nil
+}
+
+// addRecipients returns blocks, with plaintext encrypted for each of the RSA
+// or PGP public keys in files add that is not already a recipient.
+func addRecipients(blocks []*pem.Block, add []string, plaintext []byte) ([]*pem.Block, error) {
+	for _, path := range add {
+		id, err := RecipientID(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading public key for key encryption: %v", err)
+		}
+		if slices.Contains(recipientIDs(blocks), id) {
+			continue
+		}
+
+		block, err := encryptForRecipient(path, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// replaceKeyMessage replaces the object holding the encrypted LUKS key of the
+// encrypted container image with msg. The new object is added before the old
+// object is removed, so that the image always holds an encrypted key.
+func replaceKeyMessage(image string, msg []byte) error {
+	img, err := sif.LoadContainerFromPath(image)
+	if err != nil {
+		return fmt.Errorf("could not load container: %w", err)
+	}
+	defer img.UnloadContainer()
+
+	d, err := getEncryptionKeyDescriptor(img, image)
+	if err != nil {
+		return fmt.Errorf("could not get encryption information from SIF: %v", err)
+	}
+
+	mt, err := MessageType(msg)
+	if err != nil {
+		return err
+	}
+	linkedID, _ := d.LinkedID()
+	di, err := sif.NewDescriptorInput(sif.DataCryptoMessage, bytes.NewReader(msg),
+		sif.OptLinkedID(linkedID),
+		sif.OptCryptoMessageMetadata(sif.FormatPEM, mt),
+	)
+	if err != nil {
+		return err
+	}
+	if err := img.AddObject(di); err != nil {
+		return fmt.Errorf("could not add LUKS key to %s: %w", image, err)
+	}
+	if err := img.DeleteObject(d.ID(), sif.OptDeleteZero(true)); err != nil {
+		return fmt.Errorf("could not remove LUKS key from %s: %w", image, err)
+	}
+	return nil
+}
+
+// rotatePrimaryPartition changes the key of the LUKS2 volume of the primary
+// system partition of the native SIF image from key to newKey, and replaces
+// its encrypted key with msg. A keyslot for newKey is added before msg is
+// stored, and the keyslot for key is removed after, so that the image can be
+// decrypted if interrupted.
+func rotatePrimaryPartition(image string, key, newKey, msg []byte) error {
+	img, err := sif.LoadContainerFromPath(image, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return fmt.Errorf("could not load container: %w", err)
+	}
+	d, err := img.GetDescriptor(sif.WithPartitionType(sif.PartPrimSys))
+	img.UnloadContainer()
+	if err != nil {
+		return fmt.Errorf("could not retrieve primary system partition: %w", err)
+	}
+	if fs, _, _, err := d.PartitionMetadata(); err != nil {
+		return err
+	} else if fs != sif.FsEncryptedSquashfs {
+		return fmt.Errorf("primary system partition is not encrypted")
+	}
+
+	f, err := os.OpenFile(image, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := crypt.AddLUKS2Key(f, d.Offset(), d.Size(), key, newKey); err != nil {
+		return err
+	}
+	if err := replaceKeyMessage(image, msg); err != nil {
+		return err
+	}
+	return crypt.RemoveLUKS2Key(f, d.Offset(), d.Size(), key)
+}
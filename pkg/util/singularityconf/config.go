@@ -45,6 +45,7 @@ type File struct {
 	AllowContainerSquashfs  bool     `default:"yes" authorized:"yes,no" directive:"allow container squashfs"`
 	AllowContainerExtfs     bool     `default:"yes" authorized:"yes,no" directive:"allow container extfs"`
 	AllowContainerDir       bool     `default:"yes" authorized:"yes,no" directive:"allow container dir"`
+	EnforceVerifyPolicy     bool     `default:"no" authorized:"yes,no" directive:"enforce verify policy"`
 	AllowKernelSquashfs     bool     `default:"yes" authorized:"yes,no" directive:"allow kernel squashfs"`
 	AllowKernelExtfs        bool     `default:"yes" authorized:"yes,no" directive:"allow kernel extfs"`
 	AlwaysUseNv             bool     `default:"no" authorized:"yes,no" directive:"always use nv"`
@@ -356,6 +357,14 @@ allow container squashfs = {{ if eq .AllowContainerSquashfs true }}yes{{ else }}
 allow container extfs = {{ if eq .AllowContainerExtfs true }}yes{{ else }}no{{ end }}
 allow container dir = {{ if eq .AllowContainerDir true }}yes{{ else }}no{{ end }}
 
+# ENFORCE VERIFY POLICY: [BOOL]
+# DEFAULT: no
+# If set to yes, images must satisfy the system-wide verification policy
+# (verify-policy.toml, in the same directory as this file) before any action
+# command runs them. Images that cannot be signed, such as sandbox and ext3
+# images, are only allowed by a policy scope with mode = "accept".
+enforce verify policy = {{ if eq .EnforceVerifyPolicy true }}yes{{ else }}no{{ end }}
+
 # ALLOW KERNEL SQUASHFS: [BOOL]
 # DEFAULT: yes
 # If set to no, Singularity will not perform any kernel mounts of squashfs filesystems.
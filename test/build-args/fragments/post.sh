@@ -0,0 +1 @@
+    export http_proxy={{ PROXY }}